@@ -8,16 +8,21 @@ import (
 
 	"github.com/kk-code-lab/seglake/internal/admin"
 	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/ops"
 	"github.com/kk-code-lab/seglake/internal/s3"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
 )
 
-func runBuckets(action, metaPath, bucket, versioning string, force bool, jsonOut bool) error {
+func runBuckets(action, metaPath, bucket, key, versionID, versioning string, force bool, jsonOut bool) error {
 	if client, ok, err := adminClientIfRunning(filepath.Dir(metaPath)); err != nil {
 		return err
 	} else if ok {
 		req := admin.BucketsRequest{
 			Action:     action,
 			Bucket:     bucket,
+			Key:        key,
+			VersionID:  versionID,
 			Versioning: versioning,
 			Force:      force,
 		}
@@ -34,6 +39,12 @@ func runBuckets(action, metaPath, bucket, versioning string, force bool, jsonOut
 				return err
 			}
 			return formatBucketExists(resp, jsonOut)
+		case "delete":
+			var resp admin.BucketDeleteResponse
+			if err := client.postJSON("/admin/buckets", req, &resp); err != nil {
+				return err
+			}
+			return formatBucketDelete(resp, force, jsonOut)
 		default:
 			var resp map[string]string
 			if err := client.postJSON("/admin/buckets", req, &resp); err != nil {
@@ -91,17 +102,25 @@ func runBuckets(action, metaPath, bucket, versioning string, force bool, jsonOut
 		if err != nil {
 			return err
 		}
+		resp := admin.BucketDeleteResponse{Status: "ok"}
 		if !exists {
-			if jsonOut {
-				return writeJSON(map[string]string{"status": "ok"})
-			}
-			fmt.Println("ok")
-			return nil
+			return formatBucketDelete(resp, force, jsonOut)
 		}
+		var segmentIDs []string
 		if force {
-			if err := deleteBucketObjects(context.Background(), store, bucket); err != nil {
+			// Purging only drops meta rows; the segments are reclaimed below.
+			segmentIDs, err = ops.BucketSegments(context.Background(), store, bucket)
+			if err != nil {
+				return err
+			}
+			purged, err := store.PurgeBucketObjects(context.Background(), bucket)
+			if err != nil {
 				return err
 			}
+			resp.Objects = purged.Objects
+			resp.Versions = purged.Versions
+			resp.DeleteMarkers = purged.DeleteMarkers
+			resp.Bytes = purged.Bytes
 		} else {
 			hasObjects, err := store.BucketHasObjects(context.Background(), bucket)
 			if err != nil {
@@ -114,11 +133,16 @@ func runBuckets(action, metaPath, bucket, versioning string, force bool, jsonOut
 		if err := store.DeleteBucket(context.Background(), bucket); err != nil {
 			return err
 		}
-		if jsonOut {
-			return writeJSON(map[string]string{"status": "ok"})
+		if len(segmentIDs) > 0 {
+			layout := fs.NewLayout(filepath.Join(filepath.Dir(metaPath), "objects"))
+			report, err := ops.ReclaimSegments(context.Background(), layout, store, segmentIDs, nil)
+			if err != nil {
+				return fmt.Errorf("bucket deleted, reclaiming its segments failed (run gc-rewrite): %w", err)
+			}
+			resp.ReclaimedSegments = report.Deleted
+			resp.ReclaimedBytes = report.Reclaimed
 		}
-		fmt.Println("ok")
-		return nil
+		return formatBucketDelete(resp, force, jsonOut)
 	case "exists":
 		if bucket == "" {
 			return ErrBucketRequired
@@ -128,6 +152,28 @@ func runBuckets(action, metaPath, bucket, versioning string, force bool, jsonOut
 			return err
 		}
 		return formatBucketExists(map[string]bool{"exists": exists}, jsonOut)
+	case "restore":
+		if bucket == "" {
+			return ErrBucketRequired
+		}
+		if key == "" || versionID == "" {
+			return fmt.Errorf("restore requires -object-key and -version-id")
+		}
+		eng, err := engine.New(engine.Options{
+			Layout:    fs.NewLayout(filepath.Join(filepath.Dir(metaPath), "objects")),
+			MetaStore: store,
+		})
+		if err != nil {
+			return err
+		}
+		if err := eng.RestoreObjectVersion(context.Background(), bucket, key, versionID); err != nil {
+			return err
+		}
+		if jsonOut {
+			return writeJSON(map[string]string{"status": "ok"})
+		}
+		fmt.Println("ok")
+		return nil
 	default:
 		return fmt.Errorf("unknown bucket-action %q", action)
 	}
@@ -158,34 +204,14 @@ func formatBucketExists(resp map[string]bool, jsonOut bool) error {
 	return nil
 }
 
-func deleteBucketObjects(ctx context.Context, store *meta.Store, bucket string) error {
-	versioningState, err := store.GetBucketVersioningState(ctx, bucket)
-	if err != nil {
-		return err
+func formatBucketDelete(resp admin.BucketDeleteResponse, force bool, jsonOut bool) error {
+	if jsonOut {
+		return writeJSON(resp)
 	}
-	afterKey := ""
-	afterVersion := ""
-	for {
-		objects, err := store.ListObjects(ctx, bucket, "", afterKey, afterVersion, 1000)
-		if err != nil {
-			return err
-		}
-		if len(objects) == 0 {
-			return nil
-		}
-		for _, obj := range objects {
-			if versioningState == meta.BucketVersioningDisabled {
-				if _, err := store.DeleteObjectUnversioned(ctx, bucket, obj.Key); err != nil {
-					return err
-				}
-			} else {
-				if _, err := store.DeleteObject(ctx, bucket, obj.Key); err != nil {
-					return err
-				}
-			}
-		}
-		last := objects[len(objects)-1]
-		afterKey = last.Key
-		afterVersion = last.VersionID
+	if !force {
+		fmt.Println("ok")
+		return nil
 	}
+	fmt.Printf("ok objects=%d versions=%d delete_markers=%d bytes=%d reclaimed_segments=%d reclaimed_bytes=%d\n", resp.Objects, resp.Versions, resp.DeleteMarkers, resp.Bytes, resp.ReclaimedSegments, resp.ReclaimedBytes)
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
 )
@@ -20,7 +21,7 @@ func TestRunBucketsDeleteWithoutForceFails(t *testing.T) {
 		t.Fatalf("RecordPut: %v", err)
 	}
 
-	if err := runBuckets("delete", metaPath, "bucket", "", false, false); err == nil {
+	if err := runBuckets("delete", metaPath, "bucket", "", "", "", false, false); err == nil {
 		t.Fatalf("expected error for non-empty bucket delete without force")
 	}
 }
@@ -37,7 +38,7 @@ func TestRunBucketsForceDeleteDisabledVersioning(t *testing.T) {
 		t.Fatalf("RecordPut: %v", err)
 	}
 
-	if err := runBuckets("delete", metaPath, "bucket", "", true, false); err != nil {
+	if err := runBuckets("delete", metaPath, "bucket", "", "", "", true, false); err != nil {
 		t.Fatalf("runBuckets force delete: %v", err)
 	}
 	exists, err := store.BucketExists(ctx, "bucket")
@@ -47,14 +48,14 @@ func TestRunBucketsForceDeleteDisabledVersioning(t *testing.T) {
 	if exists {
 		t.Fatalf("expected bucket deleted")
 	}
-	objects, err := store.ListObjects(ctx, "bucket", "", "", "", 1000)
+	objects, err := store.ListObjects(ctx, "bucket", "", "", "", 1000, time.Time{})
 	if err != nil {
 		t.Fatalf("ListObjects: %v", err)
 	}
 	if len(objects) != 0 {
 		t.Fatalf("expected no live objects, got %d", len(objects))
 	}
-	versions, err := store.ListObjectVersions(ctx, "bucket", "", "", "", 1000)
+	versions, err := store.ListObjectVersions(ctx, "bucket", "", "", "", 1000, time.Time{})
 	if err != nil {
 		t.Fatalf("ListObjectVersions: %v", err)
 	}
@@ -74,8 +75,11 @@ func TestRunBucketsForceDeleteEnabledVersioning(t *testing.T) {
 	if err := store.RecordPut(ctx, "bucket", "key", "v1", "etag", 1, "", "text/plain"); err != nil {
 		t.Fatalf("RecordPut: %v", err)
 	}
+	if err := store.RecordPut(ctx, "bucket", "key", "v2", "etag", 1, "", "text/plain"); err != nil {
+		t.Fatalf("RecordPut v2: %v", err)
+	}
 
-	if err := runBuckets("delete", metaPath, "bucket", "", true, false); err != nil {
+	if err := runBuckets("delete", metaPath, "bucket", "", "", "", true, false); err != nil {
 		t.Fatalf("runBuckets force delete: %v", err)
 	}
 	exists, err := store.BucketExists(ctx, "bucket")
@@ -85,13 +89,20 @@ func TestRunBucketsForceDeleteEnabledVersioning(t *testing.T) {
 	if exists {
 		t.Fatalf("expected bucket deleted")
 	}
-	objects, err := store.ListObjects(ctx, "bucket", "", "", "", 1000)
+	objects, err := store.ListObjects(ctx, "bucket", "", "", "", 1000, time.Time{})
 	if err != nil {
 		t.Fatalf("ListObjects: %v", err)
 	}
 	if len(objects) != 0 {
 		t.Fatalf("expected no live objects, got %d", len(objects))
 	}
+	versions, err := store.ListObjectVersions(ctx, "bucket", "", "", "", 1000, time.Time{})
+	if err != nil {
+		t.Fatalf("ListObjectVersions: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Fatalf("expected noncurrent versions purged, got %d", len(versions))
+	}
 	live, err := store.BucketHasLiveObjects(ctx, "bucket")
 	if err != nil {
 		t.Fatalf("BucketHasLiveObjects: %v", err)
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kk-code-lab/seglake/internal/ops"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func runETagFix(opts *etagFixOptions) error {
+	if opts == nil {
+		return fmt.Errorf("etag-fix options required")
+	}
+	layout := fs.NewLayout(filepath.Join(opts.dataDir, "objects"))
+	metaPath := resolveMetaPath(opts.dataDir, opts.rebuildMeta)
+	report, err := ops.ETagFix(layout, metaPath, ops.ETagFixOptions{
+		Bucket: opts.bucket,
+		Prefix: opts.prefix,
+		DryRun: opts.dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	if opts.jsonOut {
+		return writeJSONReport(report)
+	}
+	fmt.Printf("%s\n", formatReport(report))
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kk-code-lab/seglake/internal/ops"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func runExport(opts *exportOptions) error {
+	if opts == nil {
+		return fmt.Errorf("export options required")
+	}
+	layout := fs.NewLayout(filepath.Join(opts.dataDir, "objects"))
+	metaPath := resolveMetaPath(opts.dataDir, opts.rebuildMeta)
+	report, err := ops.Export(layout, metaPath, ops.ExportOptions{
+		Bucket: opts.bucket,
+		Prefix: opts.prefix,
+		Out:    opts.out,
+		Format: opts.format,
+		Resume: opts.resume,
+	})
+	if err != nil {
+		return err
+	}
+	if opts.jsonOut {
+		return writeJSONReport(report)
+	}
+	fmt.Printf("%s\n", formatReport(report))
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kk-code-lab/seglake/internal/ops"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func runImport(opts *importOptions) error {
+	if opts == nil {
+		return fmt.Errorf("import options required")
+	}
+	layout := fs.NewLayout(filepath.Join(opts.dataDir, "objects"))
+	metaPath := resolveMetaPath(opts.dataDir, opts.rebuildMeta)
+	report, err := ops.Import(layout, metaPath, ops.ImportOptions{
+		Bucket:   opts.bucket,
+		Prefix:   opts.prefix,
+		Src:      opts.src,
+		Metadata: opts.metadata,
+		Workers:  opts.workers,
+		SiteID:   opts.siteID,
+	})
+	if err != nil {
+		return err
+	}
+	if opts.jsonOut {
+		return writeJSONReport(report)
+	}
+	fmt.Printf("%s\n", formatReport(report))
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kk-code-lab/seglake/internal/ops"
+)
+
+func runInventory(opts *inventoryOptions) error {
+	if opts == nil {
+		return fmt.Errorf("inventory options required")
+	}
+	metaPath := resolveMetaPath(opts.dataDir, opts.rebuildMeta)
+	report, err := ops.Inventory(metaPath, ops.InventoryOptions{
+		Bucket:      opts.bucket,
+		Prefix:      opts.prefix,
+		Out:         opts.out,
+		Format:      opts.format,
+		PartObjects: opts.partObjects,
+		Resume:      opts.resume,
+	})
+	if err != nil {
+		return err
+	}
+	if opts.jsonOut {
+		return writeJSONReport(report)
+	}
+	fmt.Printf("%s\n", formatReport(report))
+	return nil
+}
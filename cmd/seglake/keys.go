@@ -6,13 +6,14 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/admin"
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/s3"
 )
 
-func runKeys(action, metaPath, accessKey, secretKey, policy, bucket string, enabled bool, inflight int64, jsonOut bool) error {
+func runKeys(action, metaPath, accessKey, secretKey, policy, bucket string, enabled bool, inflight int64, overlap time.Duration, jsonOut bool) error {
 	if client, ok, err := adminClientIfRunning(filepath.Dir(metaPath)); err != nil {
 		return err
 	} else if ok {
@@ -23,6 +24,7 @@ func runKeys(action, metaPath, accessKey, secretKey, policy, bucket string, enab
 			Policy:    policy,
 			Bucket:    bucket,
 			Inflight:  inflight,
+			Overlap:   int64(overlap),
 		}
 		if action == "create" {
 			req.Enabled = &enabled
@@ -89,6 +91,18 @@ func runKeys(action, metaPath, accessKey, secretKey, policy, bucket string, enab
 		}
 		fmt.Println("ok")
 		return nil
+	case "rotate":
+		if accessKey == "" || secretKey == "" {
+			return ErrKeyAccessSecretNeeded
+		}
+		if err := store.RotateAPIKeySecret(context.Background(), accessKey, secretKey, overlap); err != nil {
+			return err
+		}
+		if jsonOut {
+			return writeJSON(map[string]string{"status": "ok"})
+		}
+		fmt.Println("ok")
+		return nil
 	case "allow-bucket":
 		if accessKey == "" || bucket == "" {
 			return ErrKeyAccessBucketNeeded
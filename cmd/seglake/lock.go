@@ -186,7 +186,7 @@ func formatLockConflict(status heartbeatStatus) error {
 
 func isUnsafeLiveMode(mode string) bool {
 	switch mode {
-	case "rebuild-index", "gc-run", "gc-rewrite", "gc-rewrite-run", "mpu-gc-run", "repl-pull", "repl-push", "repl-bootstrap", "db-integrity-check", "db-reindex":
+	case "rebuild-index", "import", "gc-run", "gc-rewrite", "gc-rewrite-run", "mpu-gc-run", "repl-pull", "repl-push", "repl-bootstrap", "db-integrity-check", "db-reindex", "bucket-force-delete":
 		return true
 	default:
 		return false
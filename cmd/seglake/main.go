@@ -21,6 +21,7 @@ import (
 	"github.com/kk-code-lab/seglake/internal/app"
 	"github.com/kk-code-lab/seglake/internal/clock"
 	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/ops"
 	"github.com/kk-code-lab/seglake/internal/s3"
 	"github.com/kk-code-lab/seglake/internal/storage/engine"
 	"github.com/kk-code-lab/seglake/internal/storage/fs"
@@ -56,6 +57,25 @@ func bucketSet(names []string) map[string]struct{} {
 	return out
 }
 
+// parseBucketLimits parses comma-separated bucket=N pairs.
+func parseBucketLimits(value string) (map[string]int, error) {
+	pairs := splitComma(value)
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]int, len(pairs))
+	for _, pair := range pairs {
+		bucket, raw, ok := strings.Cut(pair, "=")
+		bucket = strings.TrimSpace(bucket)
+		limit, err := strconv.Atoi(strings.TrimSpace(raw))
+		if !ok || bucket == "" || err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid bucket limit %q (want bucket=N)", pair)
+		}
+		out[bucket] = limit
+	}
+	return out, nil
+}
+
 func envOrDefault(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
@@ -82,6 +102,22 @@ func envBoolOrDefault(key string, fallback bool) bool {
 	return parsed
 }
 
+func envDurationOrDefault(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		if secretValue, ok := secretEnv[key]; ok {
+			value = secretValue
+		} else {
+			return fallback
+		}
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
 type globalArgs struct {
 	mode        string
 	modeHelp    bool
@@ -94,31 +130,83 @@ type globalArgs struct {
 type serverOptions struct {
 	addr              string
 	dataDir           string
+	readOnly          bool
 	accessKey         string
 	secretKey         string
 	region            string
+	allowedRegions    string
+	presignMaxExpiry  time.Duration
 	publicBuckets     string
 	virtualHosted     bool
 	logRequests       bool
+	logFormat         string
+	slowRequest       time.Duration
+	logSampleRate     float64
 	allowUnsigned     bool
 	tlsEnable         bool
 	tlsCert           string
 	tlsKey            string
+	tlsMinVersion     string
+	tlsCiphers        string
+	tlsSNICerts       string
+	tlsClientCA       string
 	trustedProxies    string
+	defaultOwner      string
 	siteID            string
 	syncInterval      time.Duration
 	syncBytes         int64
+	syncMode          string
+	stagingDir        string
+	durability        string
+	durabilityWindow  time.Duration
+	currentCheck      bool
+	readParallelism   int
+	readAheadChunks   int
+	compactInterval   time.Duration
+	gcMinAge          time.Duration
+	gcLiveThreshold   float64
+	gcRewriteBps      int64
+	gcRewriteReads    int
+	gcPauseFile       string
 	maxObjectSize     int64
+	maxBodyBytes      int64
+	maxListKeys       int
+	listPrefixIndex   bool
 	corsOrigins       string
 	corsMethods       string
 	corsHeaders       string
 	corsMaxAge        int
+	corsExpose        string
+	corsCredentials   bool
 	replayTTL         time.Duration
+	idempotencyTTL    time.Duration
 	replayBlock       bool
 	replayMaxEntries  int
 	requireIfMatch    string
+	sniffTypes        string
 	requireMD5        bool
+	rejectAmbiguous   bool
+	rejectUnknownSub  bool
+	trailerChecksums  bool
 	mpuCompleteLimit  int
+	inflightGlobal    int64
+	inflightShare     float64
+	mpuAllowGaps      bool
+	mpuCompleteBatch  int
+	mpuMaxParts       int
+	mpuMinPartSize    int64
+	mpuMaxUploads     int
+	mpuMaxUploadsBkts string
+	deleteConfirm     string
+	replLagThreshold  time.Duration
+	replApplyTarget   time.Duration
+	copyURLHosts      string
+	copyURLTimeout    time.Duration
+	notifyHosts       string
+	notifyQueue       int
+	notifyRetries     int
+	notifyTimeout     time.Duration
+	requestTimeout    time.Duration
 	maxHeaderBytes    int
 	maxURLLength      int
 	readHeaderTimeout time.Duration
@@ -129,31 +217,39 @@ type serverOptions struct {
 }
 
 type opsOptions struct {
-	dataDir           string
-	snapshotDir       string
-	rebuildMeta       string
-	replCompareDir    string
-	fsckAllManifests  bool
-	scrubAllManifests bool
-	gcMinAge          time.Duration
-	gcForce           bool
-	gcWarnSegments    int
-	gcWarnReclaim     int64
-	gcMaxSegments     int
-	gcMaxReclaim      int64
-	gcLiveThreshold   float64
-	gcRewritePlanFile string
-	gcRewriteFromPlan string
-	gcRewriteBps      int64
-	gcPauseFile       string
-	mpuTTL            time.Duration
-	mpuForce          bool
-	mpuWarnUploads    int
-	mpuWarnReclaim    int64
-	mpuMaxUploads     int
-	mpuMaxReclaim     int64
-	dbReindexTable    string
-	jsonOut           bool
+	dataDir            string
+	stagingDir         string
+	snapshotDir        string
+	rebuildMeta        string
+	replCompareDir     string
+	fsckAllManifests   bool
+	fsckDryRun         bool
+	fsckRepair         bool
+	currentCheckDryRun bool
+	scrubAllManifests  bool
+	scrubBps           int64
+	scrubWindow        string
+	scrubResume        bool
+	gcMinAge           time.Duration
+	gcForce            bool
+	gcWarnSegments     int
+	gcWarnReclaim      int64
+	gcMaxSegments      int
+	gcMaxReclaim       int64
+	gcLiveThreshold    float64
+	gcRewritePlanFile  string
+	gcRewriteFromPlan  string
+	gcRewriteBps       int64
+	gcRewriteReads     int
+	gcPauseFile        string
+	mpuTTL             time.Duration
+	mpuForce           bool
+	mpuWarnUploads     int
+	mpuWarnReclaim     int64
+	mpuMaxUploads      int
+	mpuMaxReclaim      int64
+	dbReindexTable     string
+	jsonOut            bool
 }
 
 type keysOptions struct {
@@ -166,6 +262,7 @@ type keysOptions struct {
 	enabled     bool
 	inflight    int64
 	bucket      string
+	overlap     time.Duration
 	jsonOut     bool
 }
 
@@ -184,27 +281,75 @@ type bucketsOptions struct {
 	rebuildMeta string
 	action      string
 	bucket      string
+	key         string
+	versionID   string
 	versioning  string
 	force       bool
 	jsonOut     bool
 }
 
+type exportOptions struct {
+	dataDir     string
+	rebuildMeta string
+	bucket      string
+	prefix      string
+	out         string
+	format      string
+	resume      bool
+	jsonOut     bool
+}
+
+type inventoryOptions struct {
+	dataDir     string
+	rebuildMeta string
+	bucket      string
+	prefix      string
+	out         string
+	format      string
+	partObjects int
+	resume      bool
+	jsonOut     bool
+}
+
+type etagFixOptions struct {
+	dataDir     string
+	rebuildMeta string
+	bucket      string
+	prefix      string
+	dryRun      bool
+	jsonOut     bool
+}
+
+type importOptions struct {
+	dataDir     string
+	rebuildMeta string
+	siteID      string
+	bucket      string
+	prefix      string
+	src         string
+	metadata    string
+	workers     int
+	jsonOut     bool
+}
+
 type replPullOptions struct {
-	dataDir      string
-	siteID       string
-	remote       string
-	since        string
-	limit        int
-	fetchData    bool
-	watch        bool
-	interval     time.Duration
-	backoffMax   time.Duration
-	retryTimeout time.Duration
-	accessKey    string
-	secretKey    string
-	region       string
-	syncInterval time.Duration
-	syncBytes    int64
+	dataDir          string
+	siteID           string
+	remote           string
+	since            string
+	limit            int
+	applyChunk       int
+	fetchConcurrency int
+	fetchData        bool
+	watch            bool
+	interval         time.Duration
+	backoffMax       time.Duration
+	retryTimeout     time.Duration
+	accessKey        string
+	secretKey        string
+	region           string
+	syncInterval     time.Duration
+	syncBytes        int64
 }
 
 type replPushOptions struct {
@@ -213,6 +358,7 @@ type replPushOptions struct {
 	remote     string
 	since      string
 	limit      int
+	minBatch   int
 	watch      bool
 	interval   time.Duration
 	backoffMax time.Duration
@@ -221,8 +367,22 @@ type replPushOptions struct {
 	region     string
 }
 
+type replValidateSampleOptions struct {
+	dataDir       string
+	siteID        string
+	remote        string
+	samplePercent float64
+	limit         int
+	ratePerSecond float64
+	accessKey     string
+	secretKey     string
+	region        string
+	jsonOut       bool
+}
+
 type replBootstrapOptions struct {
 	dataDir   string
+	siteID    string
 	remote    string
 	accessKey string
 	secretKey string
@@ -318,6 +478,21 @@ func main() {
 		if err := runReplPushMode(opts); err != nil {
 			exitError("repl push", err)
 		}
+	case global.mode == "repl-validate-sample":
+		fs, opts := newReplValidateSampleFlagSet()
+		if global.modeHelp {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if help, err := parseModeFlags(fs, remaining); err != nil {
+			exitParseError(err)
+		} else if help {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if err := runReplValidateSampleMode(opts); err != nil {
+			exitError("repl validate sample", err)
+		}
 	case global.mode == "repl-bootstrap":
 		fs, opts := newReplBootstrapFlagSet()
 		if global.modeHelp {
@@ -347,7 +522,7 @@ func main() {
 			if err := client.postJSON("/admin/repl/bootstrap", req, &resp); err != nil {
 				exitError("repl bootstrap", err)
 			}
-		} else if err := runReplBootstrap(opts.remote, opts.accessKey, opts.secretKey, opts.region, opts.dataDir, opts.force); err != nil {
+		} else if err := runReplBootstrap(opts.remote, opts.accessKey, opts.secretKey, opts.region, opts.dataDir, opts.siteID, opts.force); err != nil {
 			exitError("repl bootstrap", err)
 		}
 	case global.mode == "keys":
@@ -368,7 +543,7 @@ func main() {
 			}
 		}
 		metaPath := resolveMetaPath(opts.dataDir, opts.rebuildMeta)
-		if err := runKeys(opts.action, metaPath, opts.accessKey, opts.secretKey, opts.policy, opts.bucket, opts.enabled, opts.inflight, opts.jsonOut); err != nil {
+		if err := runKeys(opts.action, metaPath, opts.accessKey, opts.secretKey, opts.policy, opts.bucket, opts.enabled, opts.inflight, opts.overlap, opts.jsonOut); err != nil {
 			exitError("keys", err)
 		}
 	case global.mode == "bucket-policy":
@@ -409,10 +584,92 @@ func main() {
 				exitError("data dir", err)
 			}
 		}
+		if opts.force && opts.action == "delete" {
+			if err := confirmLiveMode(opts.dataDir, "bucket-force-delete", global.assumeYes); err != nil {
+				exitError("buckets", err)
+			}
+		}
 		metaPath := resolveMetaPath(opts.dataDir, opts.rebuildMeta)
-		if err := runBuckets(opts.action, metaPath, opts.bucket, opts.versioning, opts.force, opts.jsonOut); err != nil {
+		if err := runBuckets(opts.action, metaPath, opts.bucket, opts.key, opts.versionID, opts.versioning, opts.force, opts.jsonOut); err != nil {
 			exitError("buckets", err)
 		}
+	case global.mode == "export":
+		fs, opts := newExportFlagSet()
+		if global.modeHelp {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if help, err := parseModeFlags(fs, remaining); err != nil {
+			exitParseError(err)
+		} else if help {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if err := requireDataDir(opts.dataDir); err != nil {
+			exitError("data dir", err)
+		}
+		if err := runExport(opts); err != nil {
+			exitError("export", err)
+		}
+	case global.mode == "inventory":
+		fs, opts := newInventoryFlagSet()
+		if global.modeHelp {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if help, err := parseModeFlags(fs, remaining); err != nil {
+			exitParseError(err)
+		} else if help {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if err := requireDataDir(opts.dataDir); err != nil {
+			exitError("data dir", err)
+		}
+		if err := runInventory(opts); err != nil {
+			exitError("inventory", err)
+		}
+	case global.mode == "etag-fix":
+		fs, opts := newETagFixFlagSet()
+		if global.modeHelp {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if help, err := parseModeFlags(fs, remaining); err != nil {
+			exitParseError(err)
+		} else if help {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if opts.rebuildMeta == "" {
+			if err := requireDataDir(opts.dataDir); err != nil {
+				exitError("data dir", err)
+			}
+		}
+		if err := runETagFix(opts); err != nil {
+			exitError("etag-fix", err)
+		}
+	case global.mode == "import":
+		fs, opts := newImportFlagSet()
+		if global.modeHelp {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if help, err := parseModeFlags(fs, remaining); err != nil {
+			exitParseError(err)
+		} else if help {
+			printModeHelp(global.mode, fs)
+			return
+		}
+		if err := requireDataDir(opts.dataDir); err != nil {
+			exitError("data dir", err)
+		}
+		if err := confirmLiveMode(opts.dataDir, global.mode, global.assumeYes); err != nil {
+			exitError("import", err)
+		}
+		if err := runImport(opts); err != nil {
+			exitError("import", err)
+		}
 	case global.mode == "maintenance":
 		fs, opts := newMaintenanceFlagSet()
 		if global.modeHelp {
@@ -626,28 +883,80 @@ func newServerFlagSet() (*flag.FlagSet, *serverOptions) {
 	fs.StringVar(&opts.accessKey, "access-key", envOrDefault("SEGLAKE_ACCESS_KEY", ""), "S3 access key (enables SigV4, env SEGLAKE_ACCESS_KEY)")
 	fs.StringVar(&opts.secretKey, "secret-key", envOrDefault("SEGLAKE_SECRET_KEY", ""), "S3 secret key (enables SigV4, env SEGLAKE_SECRET_KEY)")
 	fs.StringVar(&opts.region, "region", envOrDefault("SEGLAKE_REGION", "us-east-1"), "S3 region (env SEGLAKE_REGION)")
+	fs.StringVar(&opts.allowedRegions, "allowed-regions", envOrDefault("SEGLAKE_ALLOWED_REGIONS", ""), "Comma-separated extra regions accepted in SigV4 credential scopes, * for any (env SEGLAKE_ALLOWED_REGIONS)")
+	fs.DurationVar(&opts.presignMaxExpiry, "presign-max-expiry", envDurationOrDefault("SEGLAKE_PRESIGN_MAX_EXPIRY", 7*24*time.Hour), "Longest X-Amz-Expires accepted on presigned URLs (1s..168h, env SEGLAKE_PRESIGN_MAX_EXPIRY)")
 	fs.StringVar(&opts.publicBuckets, "public-buckets", envOrDefault("SEGLAKE_PUBLIC_BUCKETS", ""), "Comma-separated bucket names allowing unsigned requests (requires bucket policy, env SEGLAKE_PUBLIC_BUCKETS)")
 	fs.BoolVar(&opts.virtualHosted, "virtual-hosted", envBoolOrDefault("SEGLAKE_VIRTUAL_HOSTED", true), "Enable virtual-hosted-style bucket routing (env SEGLAKE_VIRTUAL_HOSTED)")
-	fs.BoolVar(&opts.logRequests, "log-requests", true, "Log HTTP requests")
+	fs.BoolVar(&opts.logRequests, "log-requests", true, "Log HTTP requests (when false, only buckets enabled via /v1/ops/request-logging are logged)")
+	fs.StringVar(&opts.logFormat, "log-format", s3.LogFormatText, "Access log format: text (key=value) or json (one object per line)")
+	fs.DurationVar(&opts.slowRequest, "slow-request-threshold", 0, "Log requests slower than this with a slow_request marker (0 disables)")
+	fs.Float64Var(&opts.logSampleRate, "request-log-sample-rate", 0, "Fraction (0..1) of other requests logged as request_sample with timing details")
 	fs.BoolVar(&opts.allowUnsigned, "allow-unsigned-payload", true, "Allow SigV4 UNSIGNED-PAYLOAD")
 	fs.BoolVar(&opts.tlsEnable, "tls", envBoolOrDefault("SEGLAKE_TLS", false), "Enable HTTPS listener with TLS (env SEGLAKE_TLS)")
 	fs.StringVar(&opts.tlsCert, "tls-cert", envOrDefault("SEGLAKE_TLS_CERT", ""), "TLS certificate path (PEM, env SEGLAKE_TLS_CERT)")
 	fs.StringVar(&opts.tlsKey, "tls-key", envOrDefault("SEGLAKE_TLS_KEY", ""), "TLS private key path (PEM, env SEGLAKE_TLS_KEY)")
-	fs.StringVar(&opts.trustedProxies, "trusted-proxies", envOrDefault("SEGLAKE_TRUSTED_PROXIES", ""), "Comma-separated CIDR ranges trusted for X-Forwarded-For (env SEGLAKE_TRUSTED_PROXIES)")
+	fs.StringVar(&opts.tlsMinVersion, "tls-min-version", envOrDefault("SEGLAKE_TLS_MIN_VERSION", "1.2"), "Minimum TLS version: 1.2 or 1.3 (env SEGLAKE_TLS_MIN_VERSION)")
+	fs.StringVar(&opts.tlsCiphers, "tls-ciphers", envOrDefault("SEGLAKE_TLS_CIPHERS", ""), "Comma-separated TLS 1.2 cipher suite allowlist by Go name (empty = ECDHE AES-GCM/ChaCha20, env SEGLAKE_TLS_CIPHERS)")
+	fs.StringVar(&opts.tlsSNICerts, "tls-sni-certs", envOrDefault("SEGLAKE_TLS_SNI_CERTS", ""), "Comma-separated extra cert.pem:key.pem pairs selected by SNI hostname (env SEGLAKE_TLS_SNI_CERTS)")
+	fs.StringVar(&opts.tlsClientCA, "tls-client-ca", envOrDefault("SEGLAKE_TLS_CLIENT_CA", ""), "CA bundle (PEM) for client certificates; when set, /v1/ops/* and /v1/replication/* require a verified client cert (env SEGLAKE_TLS_CLIENT_CA)")
+	fs.StringVar(&opts.trustedProxies, "trusted-proxies", envOrDefault("SEGLAKE_TRUSTED_PROXIES", ""), "Comma-separated CIDR ranges trusted for X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host (env SEGLAKE_TRUSTED_PROXIES)")
+	fs.BoolVar(&opts.readOnly, "read-only", envBoolOrDefault("SEGLAKE_READ_ONLY", false), "Serve GET/HEAD/LIST only from a read-only meta.db and layout (e.g. a frozen snapshot); mutations get 405 (env SEGLAKE_READ_ONLY)")
+	fs.StringVar(&opts.defaultOwner, "default-owner", "seglake", "Bucket owner reported by ListBuckets for requests without an access key")
 	fs.StringVar(&opts.siteID, "site-id", "local", "Site identifier for replication (HLC/oplog)")
 	fs.DurationVar(&opts.syncInterval, "sync-interval", 100*time.Millisecond, "Write barrier interval")
 	fs.Int64Var(&opts.syncBytes, "sync-bytes", 128<<20, "Write barrier byte threshold")
+	fs.StringVar(&opts.stagingDir, "staging-dir", "", "Directory for open segments (e.g. a fast SSD); sealed segments move into the data dir (empty = write in the data dir)")
+	fs.StringVar(&opts.syncMode, "sync-mode", engine.SyncPerWrite, "Segment/manifest fsync policy: write (fsync per object before it joins the barrier) or barrier (one segment fsync and batched manifest fsyncs per barrier flush)")
+	fs.StringVar(&opts.durability, "durability", meta.DurabilityFull, "Metadata durability: full (fsync WAL on every barrier flush; no acknowledged write lost on power loss) or balanced (synchronous=NORMAL; faster small writes, but power loss may drop commits acknowledged within -durability-window)")
+	fs.DurationVar(&opts.durabilityWindow, "durability-window", time.Second, "Max unsynced window in balanced durability (must be >= -sync-interval)")
+	fs.BoolVar(&opts.currentCheck, "current-check", false, "Repair objects_current rows pointing at a missing or DELETED version before serving (see -mode current-check)")
+	fs.IntVar(&opts.readParallelism, "read-parallelism", 1, "Concurrent chunk reads per GET (1 = serial)")
+	fs.IntVar(&opts.readAheadChunks, "read-ahead-chunks", 0, "Max chunks prefetched ahead of the client (0 = 2x read-parallelism)")
+	fs.DurationVar(&opts.compactInterval, "compact-interval", 0, "Background segment compaction interval while idle (0 disables)")
+	fs.DurationVar(&opts.gcMinAge, "gc-min-age", 24*time.Hour, "Compaction minimum segment age")
+	fs.Float64Var(&opts.gcLiveThreshold, "gc-live-threshold", 0.5, "Compaction live-bytes ratio threshold (<= value)")
+	fs.Int64Var(&opts.gcRewriteBps, "gc-rewrite-bps", 0, "Compaction max bytes per second (0 = unlimited)")
+	fs.IntVar(&opts.gcRewriteReads, "gc-rewrite-max-reads", 1, "Compaction max concurrent segment reads")
+	fs.StringVar(&opts.gcPauseFile, "gc-pause-file", "", "Compaction pause while file exists")
 	fs.Int64Var(&opts.maxObjectSize, "max-object-size", 5<<30, "Max object size in bytes (0 = unlimited)")
+	fs.Int64Var(&opts.maxBodyBytes, "max-body-bytes", 1<<20, "Max control-plane request body in bytes (policy, XML configuration, CompleteMultipartUpload)")
+	fs.IntVar(&opts.maxListKeys, "list-max-keys", 1000, "Ceiling for max-keys on listings; larger requests are clamped")
+	fs.BoolVar(&opts.listPrefixIndex, "list-prefix-index", true, "Serve \"/\" delimiter listings from the meta prefix index (false = scan keys)")
 	fs.StringVar(&opts.corsOrigins, "cors-origins", "*", "Comma-separated CORS allowed origins (* for all)")
 	fs.StringVar(&opts.corsMethods, "cors-methods", "GET,PUT,HEAD,DELETE", "Comma-separated CORS allowed methods")
 	fs.StringVar(&opts.corsHeaders, "cors-headers", "authorization,content-md5,content-type,x-amz-date,x-amz-content-sha256", "Comma-separated CORS allowed headers")
 	fs.IntVar(&opts.corsMaxAge, "cors-max-age", 86400, "CORS preflight max age in seconds")
+	fs.StringVar(&opts.corsExpose, "cors-expose-headers", "ETag,x-amz-version-id", "Comma-separated response headers exposed via Access-Control-Expose-Headers")
+	fs.BoolVar(&opts.corsCredentials, "cors-allow-credentials", false, "Send Access-Control-Allow-Credentials and echo the request origin instead of *")
 	fs.DurationVar(&opts.replayTTL, "replay-ttl", 0, "Replay protection TTL (0 disables)")
+	fs.DurationVar(&opts.idempotencyTTL, "idempotency-ttl", time.Hour, "How long PUT results are replayed for a repeated x-seglake-idempotency-key")
 	fs.BoolVar(&opts.replayBlock, "replay-block", false, "Block requests on replay detection (default logs only)")
 	fs.IntVar(&opts.replayMaxEntries, "replay-cache-max", 0, "Replay cache max entries (0 = default)")
 	fs.StringVar(&opts.requireIfMatch, "require-if-match-buckets", "", "Comma-separated buckets requiring If-Match on overwrite (* for all)")
+	fs.StringVar(&opts.deleteConfirm, "delete-confirm-secret", envOrDefault("SEGLAKE_DELETE_CONFIRM_SECRET", ""), "x-seglake-confirm value required for permanent deletes in buckets with delete protection (env SEGLAKE_DELETE_CONFIRM_SECRET)")
+	fs.StringVar(&opts.sniffTypes, "sniff-content-type-buckets", "", "Comma-separated buckets where PUT without Content-Type infers it from the first 512 bytes (* for all)")
 	fs.BoolVar(&opts.requireMD5, "require-content-md5", false, "Require Content-MD5 on PUT/UploadPart")
+	fs.BoolVar(&opts.rejectAmbiguous, "reject-ambiguous-keys", false, "Reject writes to keys with empty, . or .. path segments (InvalidArgument)")
+	fs.BoolVar(&opts.rejectUnknownSub, "reject-unknown-subresources", false, "Reject requests with an unknown value-less query key such as ?acll (InvalidArgument)")
+	fs.BoolVar(&opts.trailerChecksums, "trailer-checksums", true, "Validate and store x-amz-checksum-* trailers on aws-chunked uploads")
 	fs.IntVar(&opts.mpuCompleteLimit, "mpu-complete-limit", 4, "Max concurrent CompleteMultipartUpload operations (0 disables)")
+	fs.Int64Var(&opts.inflightGlobal, "inflight-global", 0, "Max inflight requests across all access keys (0 disables)")
+	fs.Float64Var(&opts.inflightShare, "inflight-fair-share", 0, "Fraction (0..1) of -inflight-global one access key may hold while other keys are active (0 disables)")
+	fs.BoolVar(&opts.mpuAllowGaps, "mpu-allow-part-gaps", false, "Accept non-contiguous part numbers on CompleteMultipartUpload")
+	fs.IntVar(&opts.mpuCompleteBatch, "mpu-complete-max-buffered-parts", 1000, "Max part rows buffered per CompleteMultipartUpload batch")
+	fs.IntVar(&opts.mpuMaxParts, "mpu-max-parts", 10000, "Max parts stored per multipart upload, enforced on UploadPart (max 10000)")
+	fs.Int64Var(&opts.mpuMinPartSize, "mpu-min-part-size", 5<<20, "Min size of every part but the last on CompleteMultipartUpload")
+	fs.IntVar(&opts.mpuMaxUploads, "mpu-max-uploads", 10000, "Max active multipart uploads per bucket; more initiates get 503 SlowDown (0 = no cap)")
+	fs.StringVar(&opts.mpuMaxUploadsBkts, "mpu-max-uploads-buckets", "", "Comma-separated bucket=N overrides of -mpu-max-uploads (0 = no cap)")
+	fs.StringVar(&opts.copyURLHosts, "copy-source-url-hosts", "", "Comma-separated hosts allowed for PUT with x-seglake-copy-source-url (*.suffix wildcards; empty disables)")
+	fs.DurationVar(&opts.copyURLTimeout, "copy-source-url-timeout", 5*time.Minute, "Timeout for fetching an x-seglake-copy-source-url")
+	fs.StringVar(&opts.notifyHosts, "notify-hosts", "", "Comma-separated webhook hosts allowed in bucket notification configs (*.suffix wildcards; empty disables)")
+	fs.IntVar(&opts.notifyQueue, "notify-queue", 1000, "Bucket notification events buffered for delivery; more are dropped")
+	fs.IntVar(&opts.notifyRetries, "notify-retries", 3, "Redeliveries of a bucket notification after a failed webhook POST")
+	fs.DurationVar(&opts.notifyTimeout, "notify-timeout", 5*time.Second, "Timeout for one bucket notification webhook POST")
+	fs.DurationVar(&opts.requestTimeout, "request-timeout", 0, "Deadline for read requests (GET/HEAD, listings); clients may lower it with x-seglake-timeout (0 = none)")
+	fs.DurationVar(&opts.replLagThreshold, "repl-lag-threshold", time.Minute, "Replication lag above which /v1/replication/status reports a remote unhealthy")
+	fs.DurationVar(&opts.replApplyTarget, "repl-apply-target", time.Second, "Oplog apply latency that repl-push batch sizes are steered toward")
 	fs.IntVar(&opts.maxHeaderBytes, "max-header-bytes", defaultMaxHeaderBytes, "Max request header bytes (0 = Go default)")
 	fs.IntVar(&opts.maxURLLength, "max-url-length", defaultMaxURLLength, "Max request URI length in bytes (0 disables)")
 	fs.DurationVar(&opts.readHeaderTimeout, "read-header-timeout", defaultReadHeaderTimeout, "HTTP read header timeout")
@@ -662,11 +971,18 @@ func newOpsFlagSet() (*flag.FlagSet, *opsOptions) {
 	fs := flag.NewFlagSet("ops", flag.ContinueOnError)
 	opts := &opsOptions{}
 	fs.StringVar(&opts.dataDir, "data-dir", envOrDefault("SEGLAKE_DATA_DIR", "./data"), "Data directory (env SEGLAKE_DATA_DIR)")
+	fs.StringVar(&opts.stagingDir, "staging-dir", "", "Server -staging-dir, so segments not yet moved into the data dir are found")
 	fs.StringVar(&opts.snapshotDir, "snapshot-dir", "", "Snapshot output directory")
 	fs.StringVar(&opts.rebuildMeta, "rebuild-meta", "", "Path to meta.db for rebuild-index")
 	fs.StringVar(&opts.replCompareDir, "repl-compare-dir", "", "Replication validation compare data dir")
 	fs.BoolVar(&opts.fsckAllManifests, "fsck-all-manifests", false, "Fsck scan all manifests instead of live set from meta")
+	fs.BoolVar(&opts.fsckDryRun, "fsck-dry-run", false, "fsck-segments: report only, open meta.db read-only")
+	fs.BoolVar(&opts.currentCheckDryRun, "current-check-dry-run", false, "current-check: report dangling objects_current pointers without repairing them")
+	fs.BoolVar(&opts.fsckRepair, "fsck-repair", false, "fsck-segments: re-point bad chunks to a verified dedup copy before marking versions DAMAGED")
 	fs.BoolVar(&opts.scrubAllManifests, "scrub-all-manifests", false, "Scrub scan all manifests instead of live set from meta")
+	fs.Int64Var(&opts.scrubBps, "scrub-bps", 0, "Scrub max bytes per second read (0 = unlimited)")
+	fs.StringVar(&opts.scrubWindow, "scrub-window", "", "Scrub only within this daily UTC window, HH:MM-HH:MM (may wrap midnight); stops when it closes")
+	fs.BoolVar(&opts.scrubResume, "scrub-resume", false, "Scrub resume an unfinished pass from the cursor in meta.db")
 	fs.DurationVar(&opts.gcMinAge, "gc-min-age", 24*time.Hour, "GC minimum segment age")
 	fs.BoolVar(&opts.gcForce, "gc-force", false, "GC delete segments (required for gc-run)")
 	fs.IntVar(&opts.gcWarnSegments, "gc-warn-segments", 100, "GC warn when candidates exceed this count (0 disables)")
@@ -676,7 +992,8 @@ func newOpsFlagSet() (*flag.FlagSet, *opsOptions) {
 	fs.Float64Var(&opts.gcLiveThreshold, "gc-live-threshold", 0.5, "GC rewrite live-bytes ratio threshold (<= value)")
 	fs.StringVar(&opts.gcRewritePlanFile, "gc-rewrite-plan", "", "GC rewrite plan output file")
 	fs.StringVar(&opts.gcRewriteFromPlan, "gc-rewrite-from-plan", "", "GC rewrite plan input file")
-	fs.Int64Var(&opts.gcRewriteBps, "gc-rewrite-bps", 0, "GC rewrite max bytes per second (0 = unlimited)")
+	fs.Int64Var(&opts.gcRewriteBps, "gc-rewrite-bps", 0, "GC rewrite max bytes per second across all reads (0 = unlimited)")
+	fs.IntVar(&opts.gcRewriteReads, "gc-rewrite-max-reads", 1, "GC rewrite max concurrent segment reads")
 	fs.StringVar(&opts.gcPauseFile, "gc-pause-file", "", "GC pause while file exists")
 	fs.DurationVar(&opts.mpuTTL, "mpu-ttl", 7*24*time.Hour, "Multipart upload TTL for cleanup")
 	fs.BoolVar(&opts.mpuForce, "mpu-force", false, "Multipart GC delete uploads (required for mpu-gc-run)")
@@ -694,13 +1011,14 @@ func newKeysFlagSet() (*flag.FlagSet, *keysOptions) {
 	opts := &keysOptions{}
 	fs.StringVar(&opts.dataDir, "data-dir", envOrDefault("SEGLAKE_DATA_DIR", "./data"), "Data directory (env SEGLAKE_DATA_DIR)")
 	fs.StringVar(&opts.rebuildMeta, "rebuild-meta", "", "Path to meta.db")
-	fs.StringVar(&opts.action, "keys-action", "list", "Keys action: list|create|allow-bucket|disallow-bucket|list-buckets|list-buckets-all|enable|disable|delete|set-policy")
+	fs.StringVar(&opts.action, "keys-action", "list", "Keys action: list|create|rotate|allow-bucket|disallow-bucket|list-buckets|list-buckets-all|enable|disable|delete|set-policy")
 	fs.StringVar(&opts.accessKey, "key-access", "", "API access key for keys-action")
 	fs.StringVar(&opts.secretKey, "key-secret", "", "API secret key for keys-action")
 	fs.StringVar(&opts.policy, "key-policy", "rw", "API key policy: rw|ro|read-only")
 	fs.BoolVar(&opts.enabled, "key-enabled", true, "API key enabled flag")
 	fs.Int64Var(&opts.inflight, "key-inflight", 0, "API key inflight limit (0=default)")
 	fs.StringVar(&opts.bucket, "key-bucket", "", "Bucket name for keys-action allow-bucket")
+	fs.DurationVar(&opts.overlap, "key-overlap", 0, "How long the old secret keeps validating after keys-action rotate (0 = revoke at once)")
 	fs.BoolVar(&opts.jsonOut, "json", false, "Output ops report as JSON")
 	return fs, opts
 }
@@ -723,10 +1041,68 @@ func newBucketsFlagSet() (*flag.FlagSet, *bucketsOptions) {
 	opts := &bucketsOptions{}
 	fs.StringVar(&opts.dataDir, "data-dir", envOrDefault("SEGLAKE_DATA_DIR", "./data"), "Data directory (env SEGLAKE_DATA_DIR)")
 	fs.StringVar(&opts.rebuildMeta, "rebuild-meta", "", "Path to meta.db")
-	fs.StringVar(&opts.action, "bucket-action", "list", "Bucket action: list|create|delete|exists")
+	fs.StringVar(&opts.action, "bucket-action", "list", "Bucket action: list|create|delete|exists|restore")
 	fs.StringVar(&opts.bucket, "bucket", "", "Bucket name for bucket-action")
+	fs.StringVar(&opts.key, "object-key", "", "Object key for bucket-action restore")
+	fs.StringVar(&opts.versionID, "version-id", "", "DELETED version id for bucket-action restore")
 	fs.StringVar(&opts.versioning, "bucket-versioning", "", "Bucket versioning for create: enabled|suspended|disabled|unversioned")
-	fs.BoolVar(&opts.force, "bucket-force", false, "Force delete bucket by deleting live objects first")
+	fs.BoolVar(&opts.force, "bucket-force", false, "Force delete bucket by purging all object versions and delete markers first")
+	fs.BoolVar(&opts.jsonOut, "json", false, "Output ops report as JSON")
+	return fs, opts
+}
+
+func newExportFlagSet() (*flag.FlagSet, *exportOptions) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	opts := &exportOptions{}
+	fs.StringVar(&opts.dataDir, "data-dir", envOrDefault("SEGLAKE_DATA_DIR", "./data"), "Data directory (env SEGLAKE_DATA_DIR)")
+	fs.StringVar(&opts.rebuildMeta, "rebuild-meta", "", "Path to meta.db")
+	fs.StringVar(&opts.bucket, "bucket", "", "Bucket to export")
+	fs.StringVar(&opts.prefix, "prefix", "", "Export only keys with this prefix")
+	fs.StringVar(&opts.out, "export-out", "", "Output tar file or directory (metadata sidecar: <out>.export.jsonl)")
+	fs.StringVar(&opts.format, "export-format", "tar", "Export format: tar|dir")
+	fs.BoolVar(&opts.resume, "export-resume", false, "Resume an interrupted export, skipping keys already in the sidecar")
+	fs.BoolVar(&opts.jsonOut, "json", false, "Output ops report as JSON")
+	return fs, opts
+}
+
+func newInventoryFlagSet() (*flag.FlagSet, *inventoryOptions) {
+	fs := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	opts := &inventoryOptions{}
+	fs.StringVar(&opts.dataDir, "data-dir", envOrDefault("SEGLAKE_DATA_DIR", "./data"), "Data directory (env SEGLAKE_DATA_DIR)")
+	fs.StringVar(&opts.rebuildMeta, "rebuild-meta", "", "Path to meta.db")
+	fs.StringVar(&opts.bucket, "bucket", "", "Bucket to inventory")
+	fs.StringVar(&opts.prefix, "prefix", "", "Inventory only keys with this prefix")
+	fs.StringVar(&opts.out, "inventory-out", "", "Output directory for part files and manifest.json")
+	fs.StringVar(&opts.format, "inventory-format", "csv", "Inventory format: csv|jsonl")
+	fs.IntVar(&opts.partObjects, "inventory-part-objects", 100000, "Max objects per part file")
+	fs.BoolVar(&opts.resume, "inventory-resume", false, "Resume an interrupted inventory after its last complete part")
+	fs.BoolVar(&opts.jsonOut, "json", false, "Output ops report as JSON")
+	return fs, opts
+}
+
+func newETagFixFlagSet() (*flag.FlagSet, *etagFixOptions) {
+	fs := flag.NewFlagSet("etag-fix", flag.ContinueOnError)
+	opts := &etagFixOptions{}
+	fs.StringVar(&opts.dataDir, "data-dir", envOrDefault("SEGLAKE_DATA_DIR", "./data"), "Data directory (env SEGLAKE_DATA_DIR)")
+	fs.StringVar(&opts.rebuildMeta, "rebuild-meta", "", "Path to meta.db")
+	fs.StringVar(&opts.bucket, "bucket", "", "Bucket to check")
+	fs.StringVar(&opts.prefix, "prefix", "", "Check only keys with this prefix")
+	fs.BoolVar(&opts.dryRun, "etag-dry-run", false, "Report wrong ETags without updating them")
+	fs.BoolVar(&opts.jsonOut, "json", false, "Output ops report as JSON")
+	return fs, opts
+}
+
+func newImportFlagSet() (*flag.FlagSet, *importOptions) {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	opts := &importOptions{}
+	fs.StringVar(&opts.dataDir, "data-dir", envOrDefault("SEGLAKE_DATA_DIR", "./data"), "Data directory (env SEGLAKE_DATA_DIR)")
+	fs.StringVar(&opts.rebuildMeta, "rebuild-meta", "", "Path to meta.db")
+	fs.StringVar(&opts.siteID, "site-id", "local", "Site identifier for replication (HLC/oplog)")
+	fs.StringVar(&opts.bucket, "bucket", "", "Target bucket (must exist)")
+	fs.StringVar(&opts.prefix, "prefix", "", "Key prefix prepended to each file path")
+	fs.StringVar(&opts.src, "import-src", "", "Source directory to import")
+	fs.StringVar(&opts.metadata, "import-meta", "", "Optional JSON-lines metadata sidecar (export format, keys relative to -import-src)")
+	fs.IntVar(&opts.workers, "import-workers", 4, "Number of parallel import workers")
 	fs.BoolVar(&opts.jsonOut, "json", false, "Output ops report as JSON")
 	return fs, opts
 }
@@ -739,7 +1115,9 @@ func newReplPullFlagSet() (*flag.FlagSet, *replPullOptions) {
 	fs.StringVar(&opts.remote, "repl-remote", "", "Replication remote base URL (e.g. http://host:9000)")
 	fs.StringVar(&opts.since, "repl-since", "", "Replication oplog HLC watermark")
 	fs.IntVar(&opts.limit, "repl-limit", 1000, "Replication oplog batch size")
+	fs.IntVar(&opts.applyChunk, "repl-apply-chunk", 0, "Oplog entries applied per transaction; the watermark advances after each (0 = whole batch)")
 	fs.BoolVar(&opts.fetchData, "repl-fetch-data", true, "Fetch missing manifests/chunks after oplog apply")
+	fs.IntVar(&opts.fetchConcurrency, "repl-fetch-concurrency", 4, "Parallel manifest/chunk downloads when fetching data")
 	fs.BoolVar(&opts.watch, "repl-watch", false, "Continuously poll replication oplog")
 	fs.DurationVar(&opts.interval, "repl-interval", 5*time.Second, "Replication poll interval")
 	fs.DurationVar(&opts.backoffMax, "repl-backoff-max", time.Minute, "Replication max backoff on errors")
@@ -759,7 +1137,8 @@ func newReplPushFlagSet() (*flag.FlagSet, *replPushOptions) {
 	fs.StringVar(&opts.siteID, "site-id", "local", "Site identifier for replication (HLC/oplog)")
 	fs.StringVar(&opts.remote, "repl-remote", "", "Replication remote base URL (e.g. http://host:9000)")
 	fs.StringVar(&opts.since, "repl-push-since", "", "Replication push start HLC (optional)")
-	fs.IntVar(&opts.limit, "repl-push-limit", 1000, "Replication push batch size")
+	fs.IntVar(&opts.limit, "repl-push-limit", 1000, "Replication push max batch size (the batch adapts to the peer's apply latency)")
+	fs.IntVar(&opts.minBatch, "repl-push-min-batch", 10, "Replication push min batch size")
 	fs.BoolVar(&opts.watch, "repl-push-watch", false, "Continuously push local oplog")
 	fs.DurationVar(&opts.interval, "repl-push-interval", 5*time.Second, "Replication push interval")
 	fs.DurationVar(&opts.backoffMax, "repl-push-backoff-max", time.Minute, "Replication push max backoff on errors")
@@ -769,10 +1148,27 @@ func newReplPushFlagSet() (*flag.FlagSet, *replPushOptions) {
 	return fs, opts
 }
 
+func newReplValidateSampleFlagSet() (*flag.FlagSet, *replValidateSampleOptions) {
+	fs := flag.NewFlagSet("repl-validate-sample", flag.ContinueOnError)
+	opts := &replValidateSampleOptions{}
+	fs.StringVar(&opts.dataDir, "data-dir", "./data", "Data directory")
+	fs.StringVar(&opts.siteID, "site-id", "local", "Site identifier for replication (HLC/oplog)")
+	fs.StringVar(&opts.remote, "repl-remote", "", "Replication remote base URL (e.g. http://host:9000)")
+	fs.Float64Var(&opts.samplePercent, "repl-sample-percent", 1, "Percent of scanned keys compared with the remote (0-100]")
+	fs.IntVar(&opts.limit, "repl-validate-limit", 10000, "Max keys scanned per run (resumes from the last cursor)")
+	fs.Float64Var(&opts.ratePerSecond, "repl-validate-rps", 10, "Max remote lookups per second (0 = unlimited)")
+	fs.StringVar(&opts.accessKey, "repl-access-key", "", "Replication access key for SigV4 presign")
+	fs.StringVar(&opts.secretKey, "repl-secret-key", "", "Replication secret key for SigV4 presign")
+	fs.StringVar(&opts.region, "repl-region", "us-east-1", "Replication SigV4 region")
+	fs.BoolVar(&opts.jsonOut, "json", false, "Output ops report as JSON")
+	return fs, opts
+}
+
 func newReplBootstrapFlagSet() (*flag.FlagSet, *replBootstrapOptions) {
 	fs := flag.NewFlagSet("repl-bootstrap", flag.ContinueOnError)
 	opts := &replBootstrapOptions{}
 	fs.StringVar(&opts.dataDir, "data-dir", "./data", "Data directory")
+	fs.StringVar(&opts.siteID, "site-id", "local", "Site identifier for replication (HLC/oplog)")
 	fs.StringVar(&opts.remote, "repl-remote", "", "Replication remote base URL (e.g. http://host:9000)")
 	fs.StringVar(&opts.accessKey, "repl-access-key", "", "Replication access key for SigV4 presign")
 	fs.StringVar(&opts.secretKey, "repl-secret-key", "", "Replication secret key for SigV4 presign")
@@ -783,7 +1179,7 @@ func newReplBootstrapFlagSet() (*flag.FlagSet, *replBootstrapOptions) {
 
 func isOpsMode(mode string) bool {
 	switch mode {
-	case "status", "fsck", "scrub", "snapshot", "rebuild-index", "gc-plan", "gc-run", "gc-rewrite", "gc-rewrite-plan", "gc-rewrite-run", "mpu-gc-plan", "mpu-gc-run", "support-bundle", "repl-validate", "db-integrity-check", "db-reindex":
+	case "status", "fsck", "fsck-segments", "scrub", "snapshot", "rebuild-index", "gc-plan", "gc-run", "gc-rewrite", "gc-rewrite-plan", "gc-rewrite-run", "mpu-gc-plan", "mpu-gc-run", "support-bundle", "repl-validate", "db-integrity-check", "db-reindex", "current-check":
 		return true
 	default:
 		return false
@@ -822,23 +1218,95 @@ func resolveMetaPath(dataDir, override string) string {
 }
 
 func runServer(opts *serverOptions) error {
+	// Built before anything is opened so a bad cert, version or cipher name
+	// fails the start right away.
+	var tlsCfg *tls.Config
+	if opts.tlsEnable || (opts.tlsCert != "" || opts.tlsKey != "") {
+		cfg, err := newTLSConfig(tlsOptions{
+			certPath:     opts.tlsCert,
+			keyPath:      opts.tlsKey,
+			sniCerts:     splitComma(opts.tlsSNICerts),
+			minVersion:   opts.tlsMinVersion,
+			cipherSuites: splitComma(opts.tlsCiphers),
+			clientCA:     opts.tlsClientCA,
+		})
+		if err != nil {
+			return err
+		}
+		tlsCfg = cfg
+	} else if opts.tlsClientCA != "" {
+		return errors.New("-tls-client-ca requires -tls")
+	}
+	if opts.presignMaxExpiry < time.Second || opts.presignMaxExpiry > 7*24*time.Hour {
+		return fmt.Errorf("-presign-max-expiry must be between 1s and 168h, got %s", opts.presignMaxExpiry)
+	}
+	logFormat, err := s3.ParseLogFormat(opts.logFormat)
+	if err != nil {
+		return fmt.Errorf("-log-format: %w", err)
+	}
+	mpuMaxUploadsBuckets, err := parseBucketLimits(opts.mpuMaxUploadsBkts)
+	if err != nil {
+		return fmt.Errorf("-mpu-max-uploads-buckets: %w", err)
+	}
 	adminSocketPath := defaultAdminSocketPath(opts.dataDir)
 	adminTokenPath := defaultAdminTokenPath(opts.dataDir)
 	lock, err := acquireServerLock(opts.dataDir, opts.addr, adminSocketPath, adminTokenPath)
 	if err != nil {
-		return err
+		// A read-only mount cannot hold the heartbeat; nothing can write
+		// there either, so serve without it.
+		if !opts.readOnly || !errors.Is(err, syscall.EROFS) {
+			return err
+		}
+		fmt.Printf("seglake: data dir is read-only, running without server lock\n")
 	}
 	defer lock.Release()
 
-	store, err := openStore(opts.dataDir, opts.siteID)
+	var store *meta.Store
+	if opts.readOnly {
+		if err := requireDataDir(opts.dataDir); err != nil {
+			return err
+		}
+		store, err = meta.OpenReadOnly(filepath.Join(opts.dataDir, "meta.db"))
+		if err == nil {
+			store.SetSiteID(opts.siteID)
+		}
+	} else {
+		store, err = openStoreWithOptions(opts.dataDir, opts.siteID, meta.OpenOptions{
+			Durability: opts.durability,
+			SyncWindow: opts.durabilityWindow,
+		})
+		if errors.Is(err, meta.ErrReadOnly) {
+			err = fmt.Errorf("%w (use -read-only to serve it without writes)", err)
+		}
+	}
 	if err != nil {
 		return err
 	}
 	defer func() { _ = store.Close() }()
-	eng, err := openEngine(opts.dataDir, store, opts.syncInterval, opts.syncBytes)
+	if opts.currentCheck && !opts.readOnly {
+		report, err := ops.CurrentCheckStore(context.Background(), store, ops.CurrentCheckOptions{
+			Logf: func(format string, args ...any) { fmt.Printf("seglake: "+format+"\n", args...) },
+		})
+		if err != nil {
+			return fmt.Errorf("current-check: %w", err)
+		}
+		fmt.Printf("seglake: %s\n", formatReport(report))
+	}
+	metrics := s3.NewMetrics()
+	store.SetBusyRetryHook(metrics.IncMetaBusyRetry)
+	eng, err := openEngine(opts.dataDir, store, engine.Options{
+		BarrierInterval: opts.syncInterval,
+		BarrierMaxBytes: opts.syncBytes,
+		SyncMode:        opts.syncMode,
+		ReadParallelism: opts.readParallelism,
+		ReadAheadChunks: opts.readAheadChunks,
+		ReadOnly:        opts.readOnly,
+		StagingDir:      opts.stagingDir,
+	})
 	if err != nil {
 		return err
 	}
+	defer func() { _ = eng.Close() }()
 
 	fmt.Printf("seglake %s (commit %s)\n", app.Version, app.BuildCommit)
 	clk := clock.RealClock{}
@@ -849,9 +1317,9 @@ func runServer(opts *serverOptions) error {
 		MaxSkew:              5 * time.Minute,
 		AllowUnsignedPayload: opts.allowUnsigned,
 		Clock:                clk,
-		SecretLookup: func(ctx context.Context, accessKey string) (string, bool, error) {
-			return store.LookupAPISecret(ctx, accessKey)
-		},
+		AllowedRegions:       splitComma(opts.allowedRegions),
+		MaxPresignExpiry:     opts.presignMaxExpiry,
+		SecretsLookup:        store.LookupAPISecrets,
 	}
 	authLimiter := s3.NewAuthLimiter()
 	authLimiter.Clock = clk
@@ -859,7 +1327,7 @@ func runServer(opts *serverOptions) error {
 		Engine:                eng,
 		Meta:                  store,
 		Auth:                  authCfg,
-		Metrics:               s3.NewMetrics(),
+		Metrics:               metrics,
 		Clock:                 clk,
 		AuthLimiter:           authLimiter,
 		InflightLimiter:       s3.NewInflightLimiter(32),
@@ -867,51 +1335,97 @@ func runServer(opts *serverOptions) error {
 		VirtualHosted:         opts.virtualHosted,
 		PublicBuckets:         bucketSet(splitComma(opts.publicBuckets)),
 		MaxObjectSize:         opts.maxObjectSize,
+		MaxBodyBytes:          opts.maxBodyBytes,
+		MaxListKeys:           opts.maxListKeys,
 		CORSAllowOrigins:      splitComma(opts.corsOrigins),
 		CORSAllowMethods:      splitComma(opts.corsMethods),
 		CORSAllowHeaders:      splitComma(opts.corsHeaders),
 		CORSMaxAge:            opts.corsMaxAge,
+		CORSExposeHeaders:     splitComma(opts.corsExpose),
+		CORSAllowCredentials:  opts.corsCredentials,
 		ReplayCacheTTL:        opts.replayTTL,
 		ReplayBlock:           opts.replayBlock,
 		ReplayCacheMaxEntries: opts.replayMaxEntries,
 		RequireIfMatchBuckets: bucketSet(splitComma(opts.requireIfMatch)),
+		SniffContentTypes:     bucketSet(splitComma(opts.sniffTypes)),
 		RequireContentMD5:     opts.requireMD5,
+		RejectAmbiguousKeys:   opts.rejectAmbiguous,
 		MaxURLLength:          opts.maxURLLength,
 		DataDir:               opts.dataDir,
+		DefaultOwner:          opts.defaultOwner,
+		MPUAllowPartGaps:      opts.mpuAllowGaps,
+		MPUMaxBufferedParts:   opts.mpuCompleteBatch,
+		MPUMaxParts:           opts.mpuMaxParts,
+		MPUMinPartSize:        opts.mpuMinPartSize,
+		MPUMaxUploads:         opts.mpuMaxUploads,
+		MPUMaxUploadsBuckets:  mpuMaxUploadsBuckets,
+		DeleteConfirmSecret:   opts.deleteConfirm,
+		CompactInterval:       opts.compactInterval,
+		CompactMinAge:         opts.gcMinAge,
+		CompactLiveThreshold:  opts.gcLiveThreshold,
+		CompactBps:            opts.gcRewriteBps,
+		CompactReads:          opts.gcRewriteReads,
+		CompactPauseFile:      opts.gcPauseFile,
+		ReplLagThreshold:      opts.replLagThreshold,
+		ReplApplyTarget:       opts.replApplyTarget,
+		CopySourceURLHosts:    splitComma(opts.copyURLHosts),
+		CopySourceURLTimeout:  opts.copyURLTimeout,
+		IdempotencyTTL:        opts.idempotencyTTL,
+		RequestTimeout:        opts.requestTimeout,
+		ReadOnly:              opts.readOnly,
+		RequireOpsClientCert:  tlsCfg != nil && opts.tlsClientCA != "",
 	}
 	if opts.trustedProxies != "" {
 		h.TrustedProxies = splitComma(opts.trustedProxies)
 	}
-	var handler http.Handler = h
-	if opts.logRequests {
-		handler = s3.LoggingMiddleware(handler, h.Clock)
-	}
-	adminCtx, adminCancel := context.WithCancel(context.Background())
-	defer adminCancel()
-	_, _, socketPath, tokenPath, err := startAdminServer(adminCtx, opts.dataDir, opts.addr, store, eng, h.WriteInflight)
-	if err != nil {
-		return err
+	h.DisableTrailerChecksums = !opts.trailerChecksums
+	h.DisableListPrefixIndex = !opts.listPrefixIndex
+	h.SlowRequestThreshold = opts.slowRequest
+	h.RequestLogSampleRate = opts.logSampleRate
+	h.InflightLimiter.SetFairShare(opts.inflightGlobal, opts.inflightShare)
+	h.RejectUnknownSubresources = opts.rejectUnknownSub
+	if hosts := splitComma(opts.notifyHosts); len(hosts) > 0 && !opts.readOnly {
+		h.Notifier = s3.NewNotifier(opts.notifyQueue)
+		h.Notifier.Hosts = hosts
+		h.Notifier.Retries = opts.notifyRetries
+		h.Notifier.Timeout = opts.notifyTimeout
+		h.Notifier.Metrics = h.Metrics
 	}
-	defer func() {
-		_ = os.Remove(socketPath)
-		_ = os.Remove(tokenPath)
-	}()
-	server := newHTTPServer(opts, handler)
-	srvErr := make(chan error, 1)
-	maintCtx, maintCancel := context.WithCancel(context.Background())
-	defer maintCancel()
-	go h.RunMaintenanceLoop(maintCtx, 250*time.Millisecond)
-	if opts.tlsEnable || (opts.tlsCert != "" || opts.tlsKey != "") {
-		cfg, err := newTLSConfig(opts.tlsCert, opts.tlsKey)
+	var handler http.Handler = s3.LoggingMiddleware(h, h.Clock, s3.LoggingOptions{
+		Bucket:      h.RequestLogBucket,
+		BucketsOnly: !opts.logRequests,
+		Format:      logFormat,
+		Describe:    h.DescribeRequest,
+	})
+	// Admin commands and the maintenance loop (compaction, lifecycle,
+	// expiry) all write, so a read-only server runs neither.
+	if !opts.readOnly {
+		adminCtx, adminCancel := context.WithCancel(context.Background())
+		defer adminCancel()
+		_, _, socketPath, tokenPath, err := startAdminServer(adminCtx, opts.dataDir, opts.addr, store, eng, h.WriteInflight)
 		if err != nil {
 			return err
 		}
-		server.TLSConfig = cfg
+		defer func() {
+			_ = os.Remove(socketPath)
+			_ = os.Remove(tokenPath)
+		}()
+		maintCtx, maintCancel := context.WithCancel(context.Background())
+		defer maintCancel()
+		go h.RunMaintenanceLoop(maintCtx, 250*time.Millisecond)
+		if h.Notifier != nil {
+			go h.Notifier.Run(maintCtx, 0)
+		}
+	}
+	server := newHTTPServer(opts, handler)
+	srvErr := make(chan error, 1)
+	if tlsCfg != nil {
+		server.TLSConfig = tlsCfg
 		ln, err := net.Listen("tcp", opts.addr)
 		if err != nil {
 			return err
 		}
-		tlsLn := tls.NewListener(ln, cfg)
+		tlsLn := tls.NewListener(ln, tlsCfg)
 		go func() {
 			srvErr <- server.Serve(tlsLn)
 		}()
@@ -989,6 +1503,8 @@ func runReplPullMode(opts *replPullOptions) error {
 			Remote:            opts.remote,
 			Since:             opts.since,
 			Limit:             opts.limit,
+			ApplyChunk:        opts.applyChunk,
+			FetchConcurrency:  opts.fetchConcurrency,
 			FetchData:         opts.fetchData,
 			Watch:             opts.watch,
 			IntervalNanos:     int64(opts.interval),
@@ -1006,11 +1522,14 @@ func runReplPullMode(opts *replPullOptions) error {
 		return err
 	}
 	defer func() { _ = store.Close() }()
-	eng, err := openEngine(opts.dataDir, store, opts.syncInterval, opts.syncBytes)
+	eng, err := openEngine(opts.dataDir, store, engine.Options{
+		BarrierInterval: opts.syncInterval,
+		BarrierMaxBytes: opts.syncBytes,
+	})
 	if err != nil {
 		return err
 	}
-	return runReplPull(opts.remote, opts.since, opts.limit, opts.fetchData, opts.watch, opts.interval, opts.backoffMax, opts.retryTimeout, opts.accessKey, opts.secretKey, opts.region, store, eng)
+	return runReplPull(opts.remote, opts.since, opts.limit, opts.applyChunk, opts.fetchConcurrency, opts.fetchData, opts.watch, opts.interval, opts.backoffMax, opts.retryTimeout, opts.accessKey, opts.secretKey, opts.region, store, eng)
 }
 
 func runReplPushMode(opts *replPushOptions) error {
@@ -1021,6 +1540,7 @@ func runReplPushMode(opts *replPushOptions) error {
 			Remote:          opts.remote,
 			Since:           opts.since,
 			Limit:           opts.limit,
+			MinBatch:        opts.minBatch,
 			Watch:           opts.watch,
 			IntervalNanos:   int64(opts.interval),
 			BackoffMaxNanos: int64(opts.backoffMax),
@@ -1036,15 +1556,19 @@ func runReplPushMode(opts *replPushOptions) error {
 		return err
 	}
 	defer func() { _ = store.Close() }()
-	return runReplPush(opts.remote, opts.since, opts.limit, opts.watch, opts.interval, opts.backoffMax, opts.accessKey, opts.secretKey, opts.region, store)
+	return runReplPush(opts.remote, opts.since, opts.limit, opts.minBatch, opts.watch, opts.interval, opts.backoffMax, opts.accessKey, opts.secretKey, opts.region, store)
 }
 
 func openStore(dataDir, siteID string) (*meta.Store, error) {
+	return openStoreWithOptions(dataDir, siteID, meta.OpenOptions{})
+}
+
+func openStoreWithOptions(dataDir, siteID string, opts meta.OpenOptions) (*meta.Store, error) {
 	if err := requireDataDir(dataDir); err != nil {
 		return nil, err
 	}
 	metaPath := filepath.Join(dataDir, "meta.db")
-	store, err := meta.Open(metaPath)
+	store, err := meta.OpenWithOptions(metaPath, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1052,13 +1576,10 @@ func openStore(dataDir, siteID string) (*meta.Store, error) {
 	return store, nil
 }
 
-func openEngine(dataDir string, store *meta.Store, syncInterval time.Duration, syncBytes int64) (*engine.Engine, error) {
-	return engine.New(engine.Options{
-		Layout:          fs.NewLayout(filepath.Join(dataDir, "objects")),
-		MetaStore:       store,
-		BarrierInterval: syncInterval,
-		BarrierMaxBytes: syncBytes,
-	})
+func openEngine(dataDir string, store *meta.Store, opts engine.Options) (*engine.Engine, error) {
+	opts.Layout = fs.NewLayout(filepath.Join(dataDir, "objects"))
+	opts.MetaStore = store
+	return engine.New(opts)
 }
 
 func printGlobalHelp() {
@@ -1069,6 +1590,7 @@ func printGlobalHelp() {
 		"server",
 		"status",
 		"fsck",
+		"fsck-segments",
 		"scrub",
 		"snapshot",
 		"rebuild-index",
@@ -1080,13 +1602,19 @@ func printGlobalHelp() {
 		"mpu-gc-plan",
 		"mpu-gc-run",
 		"support-bundle",
+		"current-check",
 		"keys",
 		"bucket-policy",
 		"buckets",
 		"maintenance",
+		"export",
+		"inventory",
+		"etag-fix",
+		"import",
 		"repl-pull",
 		"repl-push",
 		"repl-validate",
+		"repl-validate-sample",
 		"repl-bootstrap",
 	} {
 		fmt.Printf("  %s\n", mode)
@@ -193,7 +193,7 @@ func maintenanceStats(addr string) (maintenanceStatsResponse, error) {
 	region := envOrDefault("SEGLAKE_REGION", "us-east-1")
 	if access != "" || secret != "" {
 		auth := &s3.AuthConfig{AccessKey: access, SecretKey: secret, Region: region}
-		signed, err := auth.Presign(http.MethodGet, url, 5*time.Minute)
+		signed, err := auth.Presign(http.MethodGet, url, "", 5*time.Minute)
 		if err != nil {
 			return maintenanceStatsResponse{}, err
 		}
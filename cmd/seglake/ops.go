@@ -22,30 +22,37 @@ func runOpsWithMode(mode string, opts *opsOptions) error {
 		return err
 	} else if ok {
 		req := admin.OpsRunRequest{
-			Mode:              mode,
-			SnapshotDir:       opts.snapshotDir,
-			RebuildMeta:       opts.rebuildMeta,
-			ReplCompareDir:    opts.replCompareDir,
-			DBReindexTable:    opts.dbReindexTable,
-			FsckAllManifests:  opts.fsckAllManifests,
-			ScrubAllManifests: opts.scrubAllManifests,
-			GCMinAgeNanos:     int64(opts.gcMinAge),
-			GCForce:           opts.gcForce,
-			GCWarnSegments:    opts.gcWarnSegments,
-			GCWarnReclaim:     opts.gcWarnReclaim,
-			GCMaxSegments:     opts.gcMaxSegments,
-			GCMaxReclaim:      opts.gcMaxReclaim,
-			GCLiveThreshold:   opts.gcLiveThreshold,
-			GCRewritePlanFile: opts.gcRewritePlanFile,
-			GCRewriteFromPlan: opts.gcRewriteFromPlan,
-			GCRewriteBps:      opts.gcRewriteBps,
-			GCPauseFile:       opts.gcPauseFile,
-			MPUTTLNanos:       int64(opts.mpuTTL),
-			MPUForce:          opts.mpuForce,
-			MPUWarnUploads:    opts.mpuWarnUploads,
-			MPUWarnReclaim:    opts.mpuWarnReclaim,
-			MPUMaxUploads:     opts.mpuMaxUploads,
-			MPUMaxReclaim:     opts.mpuMaxReclaim,
+			Mode:               mode,
+			SnapshotDir:        opts.snapshotDir,
+			RebuildMeta:        opts.rebuildMeta,
+			ReplCompareDir:     opts.replCompareDir,
+			DBReindexTable:     opts.dbReindexTable,
+			FsckAllManifests:   opts.fsckAllManifests,
+			FsckDryRun:         opts.fsckDryRun,
+			FsckRepair:         opts.fsckRepair,
+			CurrentCheckDryRun: opts.currentCheckDryRun,
+			ScrubAllManifests:  opts.scrubAllManifests,
+			ScrubBps:           opts.scrubBps,
+			ScrubWindow:        opts.scrubWindow,
+			ScrubResume:        opts.scrubResume,
+			GCMinAgeNanos:      int64(opts.gcMinAge),
+			GCForce:            opts.gcForce,
+			GCWarnSegments:     opts.gcWarnSegments,
+			GCWarnReclaim:      opts.gcWarnReclaim,
+			GCMaxSegments:      opts.gcMaxSegments,
+			GCMaxReclaim:       opts.gcMaxReclaim,
+			GCLiveThreshold:    opts.gcLiveThreshold,
+			GCRewritePlanFile:  opts.gcRewritePlanFile,
+			GCRewriteFromPlan:  opts.gcRewriteFromPlan,
+			GCRewriteBps:       opts.gcRewriteBps,
+			GCRewriteReads:     opts.gcRewriteReads,
+			GCPauseFile:        opts.gcPauseFile,
+			MPUTTLNanos:        int64(opts.mpuTTL),
+			MPUForce:           opts.mpuForce,
+			MPUWarnUploads:     opts.mpuWarnUploads,
+			MPUWarnReclaim:     opts.mpuWarnReclaim,
+			MPUMaxUploads:      opts.mpuMaxUploads,
+			MPUMaxReclaim:      opts.mpuMaxReclaim,
 		}
 		var report ops.Report
 		if err := client.postJSON("/admin/ops/run", req, &report); err != nil {
@@ -70,11 +77,23 @@ func runOpsWithMode(mode string, opts *opsOptions) error {
 		MaxUploads:         opts.mpuMaxUploads,
 		MaxReclaimedBytes:  opts.mpuMaxReclaim,
 	}
-	return runOps(mode, opts.dataDir, metaPath, opts.snapshotDir, opts.replCompareDir, opts.fsckAllManifests, opts.scrubAllManifests, opts.gcMinAge, opts.gcForce, opts.gcLiveThreshold, opts.gcRewritePlanFile, opts.gcRewriteFromPlan, opts.gcRewriteBps, opts.gcPauseFile, opts.mpuTTL, opts.mpuForce, gcGuard, mpuGuard, opts.dbReindexTable, opts.jsonOut)
+	fsckSegments := ops.FsckSegmentsOptions{DryRun: opts.fsckDryRun, Repair: opts.fsckRepair}
+	currentCheck := ops.CurrentCheckOptions{DryRun: opts.currentCheckDryRun}
+	scrub := ops.ScrubOptions{
+		AllManifests: opts.scrubAllManifests,
+		BytesPerSec:  opts.scrubBps,
+		Window:       opts.scrubWindow,
+		Resume:       opts.scrubResume,
+	}
+	if !opts.jsonOut {
+		currentCheck.Logf = func(format string, args ...any) { fmt.Printf(format+"\n", args...) }
+	}
+	return runOps(mode, opts.dataDir, opts.stagingDir, metaPath, opts.snapshotDir, opts.replCompareDir, opts.fsckAllManifests, scrub, opts.gcMinAge, opts.gcForce, opts.gcLiveThreshold, opts.gcRewritePlanFile, opts.gcRewriteFromPlan, opts.gcRewriteBps, opts.gcRewriteReads, opts.gcPauseFile, opts.mpuTTL, opts.mpuForce, gcGuard, mpuGuard, fsckSegments, currentCheck, opts.dbReindexTable, opts.jsonOut)
 }
 
-func runOps(mode, dataDir, metaPath, snapshotDir, replCompareDir string, fsckAllManifests, scrubAllManifests bool, gcMinAge time.Duration, gcForce bool, gcLiveThreshold float64, gcRewritePlanFile, gcRewriteFromPlan string, gcRewriteBps int64, gcPauseFile string, mpuTTL time.Duration, mpuForce bool, gcGuardrails ops.GCGuardrails, mpuGuardrails ops.MPUGCGuardrails, dbReindexTable string, jsonOut bool) error {
+func runOps(mode, dataDir, stagingDir, metaPath, snapshotDir, replCompareDir string, fsckAllManifests bool, scrub ops.ScrubOptions, gcMinAge time.Duration, gcForce bool, gcLiveThreshold float64, gcRewritePlanFile, gcRewriteFromPlan string, gcRewriteBps int64, gcRewriteReads int, gcPauseFile string, mpuTTL time.Duration, mpuForce bool, gcGuardrails ops.GCGuardrails, mpuGuardrails ops.MPUGCGuardrails, fsckSegments ops.FsckSegmentsOptions, currentCheck ops.CurrentCheckOptions, dbReindexTable string, jsonOut bool) error {
 	layout := fs.NewLayout(filepath.Join(dataDir, "objects"))
+	layout.StagingDir = stagingDir
 	var (
 		report *ops.Report
 		err    error
@@ -84,8 +103,10 @@ func runOps(mode, dataDir, metaPath, snapshotDir, replCompareDir string, fsckAll
 		report, err = ops.Status(layout)
 	case "fsck":
 		report, err = ops.Fsck(layout, metaPath, !fsckAllManifests)
+	case "fsck-segments":
+		report, err = ops.FsckSegments(layout, metaPath, fsckSegments)
 	case "scrub":
-		report, err = ops.Scrub(layout, metaPath, !scrubAllManifests)
+		report, err = ops.Scrub(layout, metaPath, scrub)
 	case "snapshot":
 		if snapshotDir == "" {
 			snapshotDir = filepath.Join(dataDir, "snapshots", "snapshot-"+fmtTime())
@@ -108,7 +129,7 @@ func runOps(mode, dataDir, metaPath, snapshotDir, replCompareDir string, fsckAll
 	case "gc-run":
 		report, err = ops.GCRun(layout, metaPath, gcMinAge, gcForce, gcGuardrails)
 	case "gc-rewrite":
-		report, err = ops.GCRewrite(layout, metaPath, gcMinAge, gcLiveThreshold, gcForce, gcRewriteBps, gcPauseFile)
+		report, err = ops.GCRewrite(layout, metaPath, gcMinAge, gcLiveThreshold, gcForce, gcRewriteBps, gcRewriteReads, gcPauseFile)
 	case "gc-rewrite-plan":
 		var plan *ops.GCRewritePlan
 		plan, report, err = ops.GCRewritePlanBuild(layout, metaPath, gcMinAge, gcLiveThreshold)
@@ -124,7 +145,7 @@ func runOps(mode, dataDir, metaPath, snapshotDir, replCompareDir string, fsckAll
 		var plan *ops.GCRewritePlan
 		plan, err = ops.ReadGCRewritePlan(gcRewriteFromPlan)
 		if err == nil {
-			report, err = ops.GCRewriteFromPlan(layout, metaPath, plan, gcForce, gcRewriteBps, gcPauseFile)
+			report, err = ops.GCRewriteFromPlan(layout, metaPath, plan, gcForce, gcRewriteBps, gcRewriteReads, gcPauseFile)
 		}
 	case "mpu-gc-plan":
 		var uploads []meta.MultipartUpload
@@ -147,6 +168,8 @@ func runOps(mode, dataDir, metaPath, snapshotDir, replCompareDir string, fsckAll
 		report, err = ops.DBIntegrityCheck(metaPath)
 	case "db-reindex":
 		report, err = ops.DBReindex(metaPath, dbReindexTable)
+	case "current-check":
+		report, err = ops.CurrentCheck(metaPath, currentCheck)
 	default:
 		return fmt.Errorf("unknown mode %q", mode)
 	}
@@ -184,6 +207,65 @@ func formatReport(report *ops.Report) string {
 	if report == nil {
 		return ""
 	}
+	if report.Mode == "repl-validate-sample" {
+		return fmt.Sprintf("mode=%s scanned=%d sampled=%d missing=%d mismatch=%d errors=%d",
+			report.Mode,
+			report.CompareScanned,
+			report.CompareSampled,
+			report.CompareSampleMissing,
+			report.CompareSampleMismatch,
+			report.Errors,
+		)
+	}
+	if report.Mode == "import" {
+		return fmt.Sprintf("mode=%s imported=%d bytes=%d skipped=%d errors=%d warnings=%d", report.Mode, report.Imported, report.ImportedBytes, report.Skipped, report.Errors, report.Warnings)
+	}
+	if report.Mode == "inventory" {
+		return fmt.Sprintf("mode=%s objects=%d parts=%d skipped=%d errors=%d warnings=%d", report.Mode, report.InventoryObjects, report.InventoryParts, report.Skipped, report.Errors, report.Warnings)
+	}
+	if report.Mode == "etag-fix" {
+		return fmt.Sprintf("mode=%s checked=%d wrong=%d fixed=%d skipped=%d errors=%d warnings=%d", report.Mode, report.ETagsChecked, report.Candidates, report.ETagsFixed, report.Skipped, report.Errors, report.Warnings)
+	}
+	if report.Mode == "current-check" {
+		return fmt.Sprintf("mode=%s dangling=%d repointed=%d removed=%d errors=%d warnings=%d", report.Mode, report.Candidates, report.CurrentRepointed, report.CurrentRemoved, report.Errors, report.Warnings)
+	}
+	if report.Mode == "export" {
+		return fmt.Sprintf("mode=%s exported=%d bytes=%d skipped=%d errors=%d warnings=%d", report.Mode, report.Exported, report.ExportedBytes, report.Skipped, report.Errors, report.Warnings)
+	}
+	if report.Mode == "scrub" {
+		line := fmt.Sprintf("mode=%s manifests=%d skipped=%d bytes=%d errors=%d warnings=%d", report.Mode, report.Manifests, report.Skipped, report.ScrubbedBytes, report.Errors, report.Warnings)
+		if report.ScrubCursor != "" {
+			line += " cursor=" + report.ScrubCursor
+		}
+		return line
+	}
+	if report.Mode == "fsck-segments" {
+		return fmt.Sprintf("mode=%s manifests=%d segments=%d out_of_bounds=%d footer_mismatches=%d missing_segments=%d damaged_versions=%d repointed_chunks=%d errors=%d",
+			report.Mode,
+			report.Manifests,
+			report.Segments,
+			report.OutOfBoundsChunks,
+			report.FooterMismatches,
+			report.MissingSegments,
+			report.DamagedVersions,
+			report.RepointedChunks,
+			report.Errors,
+		)
+	}
+	if report.Mode == "gc-rewrite" {
+		return fmt.Sprintf("mode=%s candidates=%d rewritten_bytes=%d rewrite_bps=%d new_segments=%d deleted=%d errors=%d",
+			report.Mode,
+			report.Candidates,
+			report.RewrittenBytes,
+			report.RewriteBytesPerSec,
+			report.NewSegments,
+			report.Deleted,
+			report.Errors,
+		)
+	}
+	if report.Mode == "support-bundle" {
+		return fmt.Sprintf("mode=%s bundle=%s warnings=%d", report.Mode, report.BundlePath, report.Warnings)
+	}
 	if report.Mode == "repl-validate" {
 		return fmt.Sprintf("mode=%s local_manifests=%d remote_manifests=%d local_live=%d remote_live=%d errors=%d",
 			report.Mode,
@@ -221,8 +303,10 @@ func printModeHelp(mode string, fs *flag.FlagSet) {
 		fmt.Println("Mode status: counts manifests and segments.")
 	case "fsck":
 		fmt.Println("Mode fsck: validates segment headers/footers and chunk bounds.")
+	case "fsck-segments":
+		fmt.Println("Mode fsck-segments: cross-checks live manifests against recorded segment sizes and footer checksums; marks affected versions DAMAGED.")
 	case "scrub":
-		fmt.Println("Mode scrub: verifies chunk hashes against stored data.")
+		fmt.Println("Mode scrub: verifies chunk hashes against stored data; -scrub-bps, -scrub-window and -scrub-resume spread a pass over several runs.")
 	case "snapshot":
 		fmt.Println("Mode snapshot: copies meta.db (+wal/shm) and writes snapshot.json.")
 	case "rebuild-index":
@@ -242,11 +326,13 @@ func printModeHelp(mode string, fs *flag.FlagSet) {
 	case "mpu-gc-run":
 		fmt.Println("Mode mpu-gc-run: deletes stale multipart uploads and parts.")
 	case "support-bundle":
-		fmt.Println("Mode support-bundle: creates snapshot + fsck/scrub reports.")
+		fmt.Println("Mode support-bundle: writes a tar.gz with fsck/scrub reports, schema version, pragmas, redacted API keys, recent ops runs and replication state.")
 	case "db-integrity-check":
 		fmt.Println("Mode db-integrity-check: runs PRAGMA integrity_check on meta.db.")
 	case "db-reindex":
 		fmt.Println("Mode db-reindex: rebuilds SQLite indices in meta.db.")
+	case "current-check":
+		fmt.Println("Mode current-check: repoints objects_current rows at a missing or DELETED version to the newest remaining version (or removes them); meta.db only, safe while the server runs.")
 	case "keys":
 		fmt.Println("Mode keys: manage API keys and bucket allowlists.")
 	case "bucket-policy":
@@ -255,12 +341,22 @@ func printModeHelp(mode string, fs *flag.FlagSet) {
 		fmt.Println("Mode buckets: manage bucket entries (admin).")
 	case "maintenance":
 		fmt.Println("Mode maintenance: toggle read-only maintenance mode.")
+	case "import":
+		fmt.Println("Mode import: store files of a directory as objects (parallel, skips unchanged files, writes oplog entries).")
+	case "export":
+		fmt.Println("Mode export: copy current objects of a bucket into a tar or directory (read-only, safe while the server runs).")
+	case "inventory":
+		fmt.Println("Mode inventory: write CSV or JSON-lines part files (key, size, ETag, last-modified, storage class) of current objects plus manifest.json (read-only, resumable).")
+	case "etag-fix":
+		fmt.Println("Mode etag-fix: re-read current objects of a bucket/prefix, recompute ETags and fix wrong ones (single-row updates, safe while the server runs; skips DAMAGED).")
 	case "repl-pull":
 		fmt.Println("Mode repl-pull: pull oplog from remote and apply locally.")
 	case "repl-push":
 		fmt.Println("Mode repl-push: push local oplog to remote.")
 	case "repl-validate":
 		fmt.Println("Mode repl-validate: compare manifests and live versions between data dirs.")
+	case "repl-validate-sample":
+		fmt.Println("Mode repl-validate-sample: compare a random sample of keys with a live remote (resumable).")
 	case "repl-bootstrap":
 		fmt.Println("Mode repl-bootstrap: download snapshot and catch up oplog.")
 	default:
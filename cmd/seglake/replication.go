@@ -1,21 +1,65 @@
 package main
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/kk-code-lab/seglake/internal/admin"
 	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/ops"
 	"github.com/kk-code-lab/seglake/internal/repl"
 	"github.com/kk-code-lab/seglake/internal/storage/engine"
 )
 
-func runReplBootstrap(remote, accessKey, secretKey, region, dataDir string, force bool) error {
-	return repl.RunBootstrap(remote, accessKey, secretKey, region, dataDir, force)
+func runReplBootstrap(remote, accessKey, secretKey, region, dataDir, siteID string, force bool) error {
+	return repl.RunBootstrap(remote, accessKey, secretKey, region, dataDir, siteID, force)
 }
 
-func runReplPull(remote, since string, limit int, fetchData, watch bool, interval, backoffMax, retryTimeout time.Duration, accessKey, secretKey, region string, store *meta.Store, eng *engine.Engine) error {
-	return repl.RunPull(remote, since, limit, fetchData, watch, interval, backoffMax, retryTimeout, accessKey, secretKey, region, store, eng)
+func runReplPull(remote, since string, limit, applyChunk, fetchConcurrency int, fetchData, watch bool, interval, backoffMax, retryTimeout time.Duration, accessKey, secretKey, region string, store *meta.Store, eng *engine.Engine) error {
+	return repl.RunPull(remote, since, limit, applyChunk, fetchConcurrency, fetchData, watch, interval, backoffMax, retryTimeout, accessKey, secretKey, region, store, eng)
 }
 
-func runReplPush(remote, since string, limit int, watch bool, interval, backoffMax time.Duration, accessKey, secretKey, region string, store *meta.Store) error {
-	return repl.RunPush(remote, since, limit, watch, interval, backoffMax, accessKey, secretKey, region, store)
+func runReplPush(remote, since string, limit, minBatch int, watch bool, interval, backoffMax time.Duration, accessKey, secretKey, region string, store *meta.Store) error {
+	return repl.RunPush(remote, since, limit, minBatch, watch, interval, backoffMax, accessKey, secretKey, region, store)
+}
+
+func runReplValidateSample(remote, accessKey, secretKey, region string, samplePercent float64, limit int, ratePerSecond float64, store *meta.Store) (*ops.Report, error) {
+	return repl.RunValidateSample(remote, accessKey, secretKey, region, samplePercent, limit, ratePerSecond, store)
+}
+
+func runReplValidateSampleMode(opts *replValidateSampleOptions) error {
+	var report *ops.Report
+	if client, ok, err := adminClientIfRunning(opts.dataDir); err != nil {
+		return err
+	} else if ok {
+		req := admin.ReplValidateRequest{
+			Remote:        opts.remote,
+			SamplePercent: opts.samplePercent,
+			Limit:         opts.limit,
+			RatePerSecond: opts.ratePerSecond,
+			AccessKey:     opts.accessKey,
+			SecretKey:     opts.secretKey,
+			Region:        opts.region,
+		}
+		var resp ops.Report
+		if err := client.postJSON("/admin/repl/validate", req, &resp); err != nil {
+			return err
+		}
+		report = &resp
+	} else {
+		store, err := openStore(opts.dataDir, opts.siteID)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = store.Close() }()
+		report, err = runReplValidateSample(opts.remote, opts.accessKey, opts.secretKey, opts.region, opts.samplePercent, opts.limit, opts.ratePerSecond, store)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.jsonOut {
+		return writeJSONReport(report)
+	}
+	fmt.Println(formatReport(report))
+	return nil
 }
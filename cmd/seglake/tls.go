@@ -2,13 +2,40 @@ package main
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
 
+// tlsOptions configures the HTTPS listener.
+type tlsOptions struct {
+	certPath string
+	keyPath  string
+	// sniCerts are extra "cert:key" PEM path pairs, picked by SNI hostname.
+	sniCerts []string
+	// minVersion is "1.2" or "1.3" ("" = 1.2).
+	minVersion string
+	// cipherSuites is an allowlist of TLS 1.2 suite names (empty = defaultCipherSuites).
+	cipherSuites []string
+	// clientCA is a PEM bundle used to verify optional client certificates.
+	clientCA string
+}
+
+// defaultCipherSuites are the TLS 1.2 suites used without -tls-ciphers:
+// forward-secret AEAD suites only. TLS 1.3 suites are not configurable.
+var defaultCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
 type certReloader struct {
 	certPath string
 	keyPath  string
@@ -19,21 +46,124 @@ type certReloader struct {
 	keyMtime  time.Time
 }
 
-func newTLSConfig(certPath, keyPath string) (*tls.Config, error) {
-	if certPath == "" || keyPath == "" {
+// certSet serves the first certificate valid for the client's SNI name,
+// falling back to the primary (first) one.
+type certSet []*certReloader
+
+func newTLSConfig(opts tlsOptions) (*tls.Config, error) {
+	if opts.certPath == "" || opts.keyPath == "" {
 		return nil, errors.New("tls cert and key required")
 	}
-	loader := &certReloader{certPath: certPath, keyPath: keyPath}
-	if err := loader.load(); err != nil {
+	minVersion, err := parseTLSVersion(opts.minVersion)
+	if err != nil {
+		return nil, err
+	}
+	suites, err := parseCipherSuites(opts.cipherSuites)
+	if err != nil {
 		return nil, err
 	}
-	return &tls.Config{
-		MinVersion:     tls.VersionTLS12,
-		GetCertificate: loader.getCertificate,
-	}, nil
+	if minVersion == tls.VersionTLS13 && len(opts.cipherSuites) > 0 {
+		return nil, errors.New("tls cipher suites only apply to TLS 1.2; drop -tls-ciphers or use -tls-min-version 1.2")
+	}
+	certs := certSet{{certPath: opts.certPath, keyPath: opts.keyPath}}
+	for _, pair := range opts.sniCerts {
+		certPath, keyPath, ok := strings.Cut(pair, ":")
+		if !ok || certPath == "" || keyPath == "" {
+			return nil, fmt.Errorf("invalid tls sni cert %q (want cert.pem:key.pem)", pair)
+		}
+		certs = append(certs, &certReloader{certPath: certPath, keyPath: keyPath})
+	}
+	for _, loader := range certs {
+		if err := loader.load(); err != nil {
+			return nil, fmt.Errorf("tls cert %s: %w", loader.certPath, err)
+		}
+	}
+	cfg := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   suites,
+		GetCertificate: certs.getCertificate,
+	}
+	if opts.clientCA != "" {
+		pool, err := loadClientCAs(opts.clientCA)
+		if err != nil {
+			return nil, err
+		}
+		// Client certs stay optional at the handshake; the handler requires a
+		// verified chain only on the ops and replication endpoints.
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
+}
+
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("tls client ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("tls client ca %s: no certificates found", path)
+	}
+	return pool, nil
+}
+
+func parseTLSVersion(raw string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "tls") {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls min version %q (want 1.2 or 1.3)", raw)
+	}
+}
+
+// parseCipherSuites maps Go cipher suite names (e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256) to ids. Insecure suites are refused.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return defaultCipherSuites, nil
+	}
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	insecure := make(map[string]struct{})
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = struct{}{}
+	}
+	out := make([]uint16, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if _, ok := insecure[name]; ok {
+			return nil, fmt.Errorf("insecure tls cipher suite %q", name)
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tls cipher suite %q", name)
+		}
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+func (s certSet) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if len(s) > 1 && hello != nil && hello.ServerName != "" {
+		for _, loader := range s[1:] {
+			cert, err := loader.current()
+			if err != nil {
+				continue
+			}
+			if hello.SupportsCertificate(cert) == nil {
+				return cert, nil
+			}
+		}
+	}
+	return s[0].current()
 }
 
-func (r *certReloader) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+func (r *certReloader) current() (*tls.Certificate, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if r.needsReload() {
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed ECDSA cert/key pair for dnsName and
+// returns their paths.
+func writeTestCert(t *testing.T, dir, dnsName string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+	certPath := filepath.Join(dir, dnsName+".crt")
+	keyPath := filepath.Join(dir, dnsName+".key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certPath, keyPath
+}
+
+func TestNewTLSConfigValidatesVersionAndCiphers(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCert(t, dir, "a.example")
+	base := tlsOptions{certPath: certPath, keyPath: keyPath}
+
+	cfg, err := newTLSConfig(base)
+	if err != nil {
+		t.Fatalf("newTLSConfig: %v", err)
+	}
+	if cfg.MinVersion != tls.VersionTLS12 || len(cfg.CipherSuites) != len(defaultCipherSuites) {
+		t.Fatalf("unexpected defaults: min=%x suites=%v", cfg.MinVersion, cfg.CipherSuites)
+	}
+
+	cfg, err = newTLSConfig(tlsOptions{certPath: certPath, keyPath: keyPath, clientCA: certPath})
+	if err != nil {
+		t.Fatalf("newTLSConfig client ca: %v", err)
+	}
+	if cfg.ClientAuth != tls.VerifyClientCertIfGiven || cfg.ClientCAs == nil {
+		t.Fatalf("unexpected client auth: %v", cfg.ClientAuth)
+	}
+
+	tests := []struct {
+		name    string
+		opts    tlsOptions
+		wantErr string
+	}{
+		{name: "tls13", opts: tlsOptions{minVersion: "1.3"}},
+		{name: "allowlist", opts: tlsOptions{cipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}}},
+		{name: "bad version", opts: tlsOptions{minVersion: "1.1"}, wantErr: "unsupported tls min version"},
+		{name: "unknown cipher", opts: tlsOptions{cipherSuites: []string{"TLS_NOPE"}}, wantErr: `unknown tls cipher suite "TLS_NOPE"`},
+		{name: "insecure cipher", opts: tlsOptions{cipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}, wantErr: "insecure tls cipher suite"},
+		{name: "ciphers with tls13", opts: tlsOptions{minVersion: "1.3", cipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}}, wantErr: "only apply to TLS 1.2"},
+		{name: "bad sni pair", opts: tlsOptions{sniCerts: []string{"only-cert.pem"}}, wantErr: "invalid tls sni cert"},
+		{name: "client ca", opts: tlsOptions{clientCA: certPath}},
+		{name: "missing client ca", opts: tlsOptions{clientCA: filepath.Join(dir, "missing.pem")}, wantErr: "tls client ca"},
+		{name: "client ca without certs", opts: tlsOptions{clientCA: keyPath}, wantErr: "no certificates found"},
+	}
+	for _, tc := range tests {
+		opts := tc.opts
+		opts.certPath, opts.keyPath = certPath, keyPath
+		_, err := newTLSConfig(opts)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Fatalf("%s: expected %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+func TestNewTLSConfigSelectsSNICertificate(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCert(t, dir, "a.example")
+	sniCert, sniKey := writeTestCert(t, dir, "b.example")
+	cfg, err := newTLSConfig(tlsOptions{certPath: certPath, keyPath: keyPath, sniCerts: []string{sniCert + ":" + sniKey}})
+	if err != nil {
+		t.Fatalf("newTLSConfig: %v", err)
+	}
+	for serverName, want := range map[string]string{"b.example": "b.example", "a.example": "a.example", "other.example": "a.example"} {
+		serverConn, clientConn := net.Pipe()
+		go func() { _ = tls.Server(serverConn, cfg).Handshake() }()
+		client := tls.Client(clientConn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+		err := client.Handshake()
+		// Closing the pipe also unblocks the server's post-handshake writes.
+		_ = clientConn.Close()
+		_ = serverConn.Close()
+		if err != nil {
+			t.Fatalf("%s: handshake: %v", serverName, err)
+		}
+		if got := client.ConnectionState().PeerCertificates[0].Subject.CommonName; got != want {
+			t.Fatalf("%s: served %s, want %s", serverName, got, want)
+		}
+	}
+}
@@ -1,30 +1,37 @@
 package admin
 
 type OpsRunRequest struct {
-	Mode              string  `json:"mode"`
-	SnapshotDir       string  `json:"snapshot_dir,omitempty"`
-	RebuildMeta       string  `json:"rebuild_meta,omitempty"`
-	ReplCompareDir    string  `json:"repl_compare_dir,omitempty"`
-	DBReindexTable    string  `json:"db_reindex_table,omitempty"`
-	FsckAllManifests  bool    `json:"fsck_all_manifests,omitempty"`
-	ScrubAllManifests bool    `json:"scrub_all_manifests,omitempty"`
-	GCMinAgeNanos     int64   `json:"gc_min_age_nanos,omitempty"`
-	GCForce           bool    `json:"gc_force,omitempty"`
-	GCWarnSegments    int     `json:"gc_warn_segments,omitempty"`
-	GCWarnReclaim     int64   `json:"gc_warn_reclaim_bytes,omitempty"`
-	GCMaxSegments     int     `json:"gc_max_segments,omitempty"`
-	GCMaxReclaim      int64   `json:"gc_max_reclaim_bytes,omitempty"`
-	GCLiveThreshold   float64 `json:"gc_live_threshold,omitempty"`
-	GCRewritePlanFile string  `json:"gc_rewrite_plan,omitempty"`
-	GCRewriteFromPlan string  `json:"gc_rewrite_from_plan,omitempty"`
-	GCRewriteBps      int64   `json:"gc_rewrite_bps,omitempty"`
-	GCPauseFile       string  `json:"gc_pause_file,omitempty"`
-	MPUTTLNanos       int64   `json:"mpu_ttl_nanos,omitempty"`
-	MPUForce          bool    `json:"mpu_force,omitempty"`
-	MPUWarnUploads    int     `json:"mpu_warn_uploads,omitempty"`
-	MPUWarnReclaim    int64   `json:"mpu_warn_reclaim_bytes,omitempty"`
-	MPUMaxUploads     int     `json:"mpu_max_uploads,omitempty"`
-	MPUMaxReclaim     int64   `json:"mpu_max_reclaim_bytes,omitempty"`
+	Mode               string  `json:"mode"`
+	SnapshotDir        string  `json:"snapshot_dir,omitempty"`
+	RebuildMeta        string  `json:"rebuild_meta,omitempty"`
+	ReplCompareDir     string  `json:"repl_compare_dir,omitempty"`
+	DBReindexTable     string  `json:"db_reindex_table,omitempty"`
+	FsckAllManifests   bool    `json:"fsck_all_manifests,omitempty"`
+	FsckDryRun         bool    `json:"fsck_dry_run,omitempty"`
+	FsckRepair         bool    `json:"fsck_repair,omitempty"`
+	CurrentCheckDryRun bool    `json:"current_check_dry_run,omitempty"`
+	ScrubAllManifests  bool    `json:"scrub_all_manifests,omitempty"`
+	ScrubBps           int64   `json:"scrub_bps,omitempty"`
+	ScrubWindow        string  `json:"scrub_window,omitempty"`
+	ScrubResume        bool    `json:"scrub_resume,omitempty"`
+	GCMinAgeNanos      int64   `json:"gc_min_age_nanos,omitempty"`
+	GCForce            bool    `json:"gc_force,omitempty"`
+	GCWarnSegments     int     `json:"gc_warn_segments,omitempty"`
+	GCWarnReclaim      int64   `json:"gc_warn_reclaim_bytes,omitempty"`
+	GCMaxSegments      int     `json:"gc_max_segments,omitempty"`
+	GCMaxReclaim       int64   `json:"gc_max_reclaim_bytes,omitempty"`
+	GCLiveThreshold    float64 `json:"gc_live_threshold,omitempty"`
+	GCRewritePlanFile  string  `json:"gc_rewrite_plan,omitempty"`
+	GCRewriteFromPlan  string  `json:"gc_rewrite_from_plan,omitempty"`
+	GCRewriteBps       int64   `json:"gc_rewrite_bps,omitempty"`
+	GCRewriteReads     int     `json:"gc_rewrite_max_reads,omitempty"`
+	GCPauseFile        string  `json:"gc_pause_file,omitempty"`
+	MPUTTLNanos        int64   `json:"mpu_ttl_nanos,omitempty"`
+	MPUForce           bool    `json:"mpu_force,omitempty"`
+	MPUWarnUploads     int     `json:"mpu_warn_uploads,omitempty"`
+	MPUWarnReclaim     int64   `json:"mpu_warn_reclaim_bytes,omitempty"`
+	MPUMaxUploads      int     `json:"mpu_max_uploads,omitempty"`
+	MPUMaxReclaim      int64   `json:"mpu_max_reclaim_bytes,omitempty"`
 }
 
 type KeysRequest struct {
@@ -35,6 +42,8 @@ type KeysRequest struct {
 	Bucket    string `json:"bucket,omitempty"`
 	Enabled   *bool  `json:"enabled,omitempty"`
 	Inflight  int64  `json:"inflight,omitempty"`
+	// Overlap (nanoseconds) keeps the old secret valid after "rotate".
+	Overlap int64 `json:"overlap_nanos,omitempty"`
 }
 
 type BucketPolicyRequest struct {
@@ -46,10 +55,26 @@ type BucketPolicyRequest struct {
 type BucketsRequest struct {
 	Action     string `json:"action"`
 	Bucket     string `json:"bucket,omitempty"`
+	Key        string `json:"key,omitempty"`
+	VersionID  string `json:"version_id,omitempty"`
 	Versioning string `json:"versioning,omitempty"`
 	Force      bool   `json:"force,omitempty"`
 }
 
+// BucketDeleteResponse carries what a forced delete purged before removing
+// the bucket; the counts are zero for a plain delete.
+type BucketDeleteResponse struct {
+	Status        string `json:"status"`
+	Objects       int64  `json:"objects"`
+	Versions      int64  `json:"versions"`
+	DeleteMarkers int64  `json:"delete_markers"`
+	Bytes         int64  `json:"bytes"`
+	// ReclaimedSegments and ReclaimedBytes count the segments of the purged
+	// data that were compacted away right after the delete.
+	ReclaimedSegments int   `json:"reclaimed_segments"`
+	ReclaimedBytes    int64 `json:"reclaimed_bytes"`
+}
+
 type MaintenanceRequest struct {
 	Action string `json:"action"`
 	NoWait bool   `json:"no_wait,omitempty"`
@@ -65,10 +90,29 @@ type MaintenanceResponse struct {
 	ServerStateUpdatedAt string `json:"server_state_updated,omitempty"`
 }
 
+type BarrierRequest struct {
+	Action        string `json:"action"`
+	IntervalNanos int64  `json:"interval_nanos,omitempty"`
+	MaxBytes      int64  `json:"max_bytes,omitempty"`
+}
+
+type BarrierResponse struct {
+	IntervalNanos int64  `json:"interval_nanos"`
+	MaxBytes      int64  `json:"max_bytes"`
+	Barriers      int64  `json:"barriers_total"`
+	Ops           int64  `json:"ops_total"`
+	Bytes         int64  `json:"bytes_total"`
+	LastBytes     int64  `json:"last_barrier_bytes"`
+	LastOps       int64  `json:"last_barrier_ops"`
+	LastBarrierAt string `json:"last_barrier_at,omitempty"`
+}
+
 type ReplPullRequest struct {
 	Remote            string `json:"remote"`
 	Since             string `json:"since,omitempty"`
 	Limit             int    `json:"limit,omitempty"`
+	ApplyChunk        int    `json:"apply_chunk,omitempty"`
+	FetchConcurrency  int    `json:"fetch_concurrency,omitempty"`
 	FetchData         bool   `json:"fetch_data,omitempty"`
 	Watch             bool   `json:"watch,omitempty"`
 	IntervalNanos     int64  `json:"interval_nanos,omitempty"`
@@ -83,6 +127,7 @@ type ReplPushRequest struct {
 	Remote          string `json:"remote"`
 	Since           string `json:"since,omitempty"`
 	Limit           int    `json:"limit,omitempty"`
+	MinBatch        int    `json:"min_batch,omitempty"`
 	Watch           bool   `json:"watch,omitempty"`
 	IntervalNanos   int64  `json:"interval_nanos,omitempty"`
 	BackoffMaxNanos int64  `json:"backoff_max_nanos,omitempty"`
@@ -91,8 +136,19 @@ type ReplPushRequest struct {
 	Region          string `json:"region,omitempty"`
 }
 
+type ReplValidateRequest struct {
+	Remote        string  `json:"remote"`
+	SamplePercent float64 `json:"sample_percent,omitempty"`
+	Limit         int     `json:"limit,omitempty"`
+	RatePerSecond float64 `json:"rate_per_second,omitempty"`
+	AccessKey     string  `json:"access_key,omitempty"`
+	SecretKey     string  `json:"secret_key,omitempty"`
+	Region        string  `json:"region,omitempty"`
+}
+
 type ReplBootstrapRequest struct {
 	Remote    string `json:"remote"`
+	SiteID    string `json:"site_id,omitempty"`
 	Force     bool   `json:"force,omitempty"`
 	AccessKey string `json:"access_key,omitempty"`
 	SecretKey string `json:"secret_key,omitempty"`
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"path/filepath"
 	"strings"
@@ -59,12 +60,16 @@ func (h *Handler) dispatch(w http.ResponseWriter, r *http.Request) {
 		h.handleBuckets(w, r)
 	case "/admin/maintenance":
 		h.handleMaintenance(w, r)
+	case "/admin/barrier":
+		h.handleBarrier(w, r)
 	case "/admin/repl/pull":
 		h.handleReplPull(w, r)
 	case "/admin/repl/push":
 		h.handleReplPush(w, r)
 	case "/admin/repl/bootstrap":
 		h.handleReplBootstrap(w, r)
+	case "/admin/repl/validate":
+		h.handleReplValidate(w, r)
 	default:
 		writeAdminError(w, http.StatusNotFound, "unknown admin endpoint")
 	}
@@ -88,7 +93,8 @@ func (h *Handler) handleOpsRun(w http.ResponseWriter, r *http.Request) {
 		writeAdminError(w, http.StatusBadRequest, "unknown ops mode")
 		return
 	}
-	if requiresQuiescedOps(req.Mode) {
+	// Re-pointing manifests must not race with GC or readers pinning segments.
+	if requiresQuiescedOps(req.Mode) || (req.Mode == "fsck-segments" && req.FsckRepair) {
 		state, err := h.Meta.MaintenanceState(context.Background())
 		if err != nil {
 			writeAdminError(w, http.StatusInternalServerError, err.Error())
@@ -109,6 +115,10 @@ func (h *Handler) handleOpsRun(w http.ResponseWriter, r *http.Request) {
 		metaPath = req.RebuildMeta
 	}
 	layout := fs.NewLayout(filepath.Join(dataDir, "objects"))
+	if h.Engine != nil {
+		// The engine layout also covers segments still in the staging dir.
+		layout = h.Engine.Layout()
+	}
 	gcGuard := ops.GCGuardrails{
 		WarnCandidates:     req.GCWarnSegments,
 		WarnReclaimedBytes: req.GCWarnReclaim,
@@ -121,9 +131,15 @@ func (h *Handler) handleOpsRun(w http.ResponseWriter, r *http.Request) {
 		MaxUploads:         req.MPUMaxUploads,
 		MaxReclaimedBytes:  req.MPUMaxReclaim,
 	}
+	scrub := ops.ScrubOptions{
+		AllManifests: req.ScrubAllManifests,
+		BytesPerSec:  req.ScrubBps,
+		Window:       req.ScrubWindow,
+		Resume:       req.ScrubResume,
+	}
 	gcMinAge := time.Duration(req.GCMinAgeNanos)
 	mpuTTL := time.Duration(req.MPUTTLNanos)
-	report, err := runOpsRequest(req.Mode, layout, metaPath, req.SnapshotDir, req.ReplCompareDir, req.FsckAllManifests, req.ScrubAllManifests, gcMinAge, req.GCForce, req.GCLiveThreshold, req.GCRewritePlanFile, req.GCRewriteFromPlan, req.GCRewriteBps, req.GCPauseFile, mpuTTL, req.MPUForce, gcGuard, mpuGuard, req.DBReindexTable)
+	report, err := runOpsRequest(req.Mode, layout, metaPath, req.SnapshotDir, req.ReplCompareDir, req.FsckAllManifests, scrub, gcMinAge, req.GCForce, req.GCLiveThreshold, req.GCRewritePlanFile, req.GCRewriteFromPlan, req.GCRewriteBps, req.GCRewriteReads, req.GCPauseFile, mpuTTL, req.MPUForce, gcGuard, mpuGuard, ops.FsckSegmentsOptions{DryRun: req.FsckDryRun, Repair: req.FsckRepair}, ops.CurrentCheckOptions{DryRun: req.CurrentCheckDryRun, Logf: log.Printf}, req.DBReindexTable)
 	if err != nil {
 		writeAdminError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -166,6 +182,20 @@ func (h *Handler) handleKeys(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		writeAdminJSON(w, map[string]string{"status": "ok"})
+	case "rotate":
+		if req.AccessKey == "" || req.SecretKey == "" {
+			writeAdminError(w, http.StatusBadRequest, "access_key and secret_key required")
+			return
+		}
+		if err := h.Meta.RotateAPIKeySecret(context.Background(), req.AccessKey, req.SecretKey, time.Duration(req.Overlap)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeAdminError(w, http.StatusNotFound, "access key not found")
+				return
+			}
+			writeAdminError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeAdminJSON(w, map[string]string{"status": "ok"})
 	case "allow-bucket":
 		if req.AccessKey == "" || req.Bucket == "" {
 			writeAdminError(w, http.StatusBadRequest, "access_key and bucket required")
@@ -370,12 +400,25 @@ func (h *Handler) handleBuckets(w http.ResponseWriter, r *http.Request) {
 			writeAdminError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		resp := BucketDeleteResponse{Status: "ok"}
+		var segmentIDs []string
 		if exists {
 			if req.Force {
-				if err := deleteBucketObjects(context.Background(), h.Meta, req.Bucket); err != nil {
+				// Purging only drops meta rows; the segments are reclaimed below.
+				segmentIDs, err = ops.BucketSegments(context.Background(), h.Meta, req.Bucket)
+				if err != nil {
+					writeAdminError(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+				purged, err := h.Meta.PurgeBucketObjects(context.Background(), req.Bucket)
+				if err != nil {
 					writeAdminError(w, http.StatusInternalServerError, err.Error())
 					return
 				}
+				resp.Objects = purged.Objects
+				resp.Versions = purged.Versions
+				resp.DeleteMarkers = purged.DeleteMarkers
+				resp.Bytes = purged.Bytes
 			} else {
 				hasObjects, err := h.Meta.BucketHasObjects(context.Background(), req.Bucket)
 				if err != nil {
@@ -392,7 +435,16 @@ func (h *Handler) handleBuckets(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		}
-		writeAdminJSON(w, map[string]string{"status": "ok"})
+		if len(segmentIDs) > 0 && h.Engine != nil {
+			report, err := ops.ReclaimSegments(context.Background(), h.Engine.Layout(), h.Meta, segmentIDs, h.Engine)
+			if err != nil {
+				writeAdminError(w, http.StatusInternalServerError, "bucket deleted, reclaiming its segments failed (run gc-rewrite): "+err.Error())
+				return
+			}
+			resp.ReclaimedSegments = report.Deleted
+			resp.ReclaimedBytes = report.Reclaimed
+		}
+		writeAdminJSON(w, resp)
 	case "exists":
 		if req.Bucket == "" {
 			writeAdminError(w, http.StatusBadRequest, "bucket required")
@@ -404,6 +456,20 @@ func (h *Handler) handleBuckets(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		writeAdminJSON(w, map[string]bool{"exists": exists})
+	case "restore":
+		if req.Bucket == "" || req.Key == "" || req.VersionID == "" {
+			writeAdminError(w, http.StatusBadRequest, "bucket, key and version_id required")
+			return
+		}
+		if err := h.Engine.RestoreObjectVersion(context.Background(), req.Bucket, req.Key, req.VersionID); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, meta.ErrNotRestorable) {
+				status = http.StatusConflict
+			}
+			writeAdminError(w, status, err.Error())
+			return
+		}
+		writeAdminJSON(w, map[string]string{"status": "ok"})
 	default:
 		writeAdminError(w, http.StatusBadRequest, "unknown bucket action")
 	}
@@ -461,6 +527,39 @@ func (h *Handler) handleMaintenance(w http.ResponseWriter, r *http.Request) {
 	writeAdminJSON(w, resp)
 }
 
+func (h *Handler) handleBarrier(w http.ResponseWriter, r *http.Request) {
+	var req BarrierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	switch strings.ToLower(strings.TrimSpace(req.Action)) {
+	case "", "status", "get", "show":
+	case "set":
+		if err := h.Engine.SetBarrierThresholds(time.Duration(req.IntervalNanos), req.MaxBytes); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	default:
+		writeAdminError(w, http.StatusBadRequest, "barrier action must be status|set")
+		return
+	}
+	stats := h.Engine.BarrierStats()
+	resp := BarrierResponse{
+		IntervalNanos: int64(stats.Interval),
+		MaxBytes:      stats.MaxBytes,
+		Barriers:      stats.Barriers,
+		Ops:           stats.Ops,
+		Bytes:         stats.Bytes,
+		LastBytes:     stats.LastBytes,
+		LastOps:       stats.LastOps,
+	}
+	if !stats.LastBarrierAt.IsZero() {
+		resp.LastBarrierAt = stats.LastBarrierAt.Format(time.RFC3339Nano)
+	}
+	writeAdminJSON(w, resp)
+}
+
 func writeAdminJSON(w http.ResponseWriter, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(payload)
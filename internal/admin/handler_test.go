@@ -3,10 +3,12 @@ package admin
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/storage/engine"
@@ -96,3 +98,33 @@ func newTestHandler(t *testing.T) *Handler {
 		AuthToken: "admin-token",
 	}
 }
+
+func TestBarrierSetAndStatus(t *testing.T) {
+	h := newTestHandler(t)
+	reqBody := []byte(`{"action":"set","interval_nanos":20000000,"max_bytes":4096}`)
+	req := httptest.NewRequest(http.MethodPost, "/admin/barrier", bytes.NewReader(reqBody))
+	req.Header.Set(TokenHeader(), h.AuthToken)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("set status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp BarrierResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.IntervalNanos != int64(20*time.Millisecond) || resp.MaxBytes != 4096 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if stats := h.Engine.BarrierStats(); stats.Interval != 20*time.Millisecond || stats.MaxBytes != 4096 {
+		t.Fatalf("engine thresholds not applied: %+v", stats)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/barrier", bytes.NewReader([]byte(`{"action":"set"}`)))
+	req.Header.Set(TokenHeader(), h.AuthToken)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("empty set status=%d", w.Code)
+	}
+}
@@ -13,7 +13,7 @@ import (
 
 func isOpsMode(mode string) bool {
 	switch mode {
-	case "status", "fsck", "scrub", "snapshot", "rebuild-index", "gc-plan", "gc-run", "gc-rewrite", "gc-rewrite-plan", "gc-rewrite-run", "mpu-gc-plan", "mpu-gc-run", "support-bundle", "repl-validate", "db-integrity-check", "db-reindex":
+	case "status", "fsck", "fsck-segments", "scrub", "snapshot", "rebuild-index", "gc-plan", "gc-run", "gc-rewrite", "gc-rewrite-plan", "gc-rewrite-run", "mpu-gc-plan", "mpu-gc-run", "support-bundle", "repl-validate", "db-integrity-check", "db-reindex", "current-check":
 		return true
 	default:
 		return false
@@ -29,7 +29,7 @@ func requiresQuiescedOps(mode string) bool {
 	}
 }
 
-func runOpsRequest(mode string, layout fs.Layout, metaPath, snapshotDir, replCompareDir string, fsckAllManifests, scrubAllManifests bool, gcMinAge time.Duration, gcForce bool, gcLiveThreshold float64, gcRewritePlanFile, gcRewriteFromPlan string, gcRewriteBps int64, gcPauseFile string, mpuTTL time.Duration, mpuForce bool, gcGuardrails ops.GCGuardrails, mpuGuardrails ops.MPUGCGuardrails, dbReindexTable string) (*ops.Report, error) {
+func runOpsRequest(mode string, layout fs.Layout, metaPath, snapshotDir, replCompareDir string, fsckAllManifests bool, scrub ops.ScrubOptions, gcMinAge time.Duration, gcForce bool, gcLiveThreshold float64, gcRewritePlanFile, gcRewriteFromPlan string, gcRewriteBps int64, gcRewriteReads int, gcPauseFile string, mpuTTL time.Duration, mpuForce bool, gcGuardrails ops.GCGuardrails, mpuGuardrails ops.MPUGCGuardrails, fsckSegments ops.FsckSegmentsOptions, currentCheck ops.CurrentCheckOptions, dbReindexTable string) (*ops.Report, error) {
 	var (
 		report *ops.Report
 		err    error
@@ -39,8 +39,10 @@ func runOpsRequest(mode string, layout fs.Layout, metaPath, snapshotDir, replCom
 		report, err = ops.Status(layout)
 	case "fsck":
 		report, err = ops.Fsck(layout, metaPath, !fsckAllManifests)
+	case "fsck-segments":
+		report, err = ops.FsckSegments(layout, metaPath, fsckSegments)
 	case "scrub":
-		report, err = ops.Scrub(layout, metaPath, !scrubAllManifests)
+		report, err = ops.Scrub(layout, metaPath, scrub)
 	case "snapshot":
 		if snapshotDir == "" {
 			snapshotDir = filepath.Join(filepath.Dir(layout.Root), "snapshots", "snapshot-"+fmtTime())
@@ -63,7 +65,7 @@ func runOpsRequest(mode string, layout fs.Layout, metaPath, snapshotDir, replCom
 	case "gc-run":
 		report, err = ops.GCRun(layout, metaPath, gcMinAge, gcForce, gcGuardrails)
 	case "gc-rewrite":
-		report, err = ops.GCRewrite(layout, metaPath, gcMinAge, gcLiveThreshold, gcForce, gcRewriteBps, gcPauseFile)
+		report, err = ops.GCRewrite(layout, metaPath, gcMinAge, gcLiveThreshold, gcForce, gcRewriteBps, gcRewriteReads, gcPauseFile)
 	case "gc-rewrite-plan":
 		var plan *ops.GCRewritePlan
 		plan, report, err = ops.GCRewritePlanBuild(layout, metaPath, gcMinAge, gcLiveThreshold)
@@ -79,7 +81,7 @@ func runOpsRequest(mode string, layout fs.Layout, metaPath, snapshotDir, replCom
 		var plan *ops.GCRewritePlan
 		plan, err = ops.ReadGCRewritePlan(gcRewriteFromPlan)
 		if err == nil {
-			report, err = ops.GCRewriteFromPlan(layout, metaPath, plan, gcForce, gcRewriteBps, gcPauseFile)
+			report, err = ops.GCRewriteFromPlan(layout, metaPath, plan, gcForce, gcRewriteBps, gcRewriteReads, gcPauseFile)
 		}
 	case "mpu-gc-plan":
 		var uploads []meta.MultipartUpload
@@ -102,6 +104,8 @@ func runOpsRequest(mode string, layout fs.Layout, metaPath, snapshotDir, replCom
 		report, err = ops.DBIntegrityCheck(metaPath)
 	case "db-reindex":
 		report, err = ops.DBReindex(metaPath, dbReindexTable)
+	case "current-check":
+		report, err = ops.CurrentCheck(metaPath, currentCheck)
 	default:
 		return nil, fmt.Errorf("unknown mode %q", mode)
 	}
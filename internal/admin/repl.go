@@ -18,7 +18,7 @@ func (h *Handler) handleReplPull(w http.ResponseWriter, r *http.Request) {
 	interval := time.Duration(req.IntervalNanos)
 	backoffMax := time.Duration(req.BackoffMaxNanos)
 	retryTimeout := time.Duration(req.RetryTimeoutNanos)
-	err := repl.RunPull(req.Remote, req.Since, req.Limit, req.FetchData, req.Watch, interval, backoffMax, retryTimeout, req.AccessKey, req.SecretKey, req.Region, h.Meta, h.Engine)
+	err := repl.RunPull(req.Remote, req.Since, req.Limit, req.ApplyChunk, req.FetchConcurrency, req.FetchData, req.Watch, interval, backoffMax, retryTimeout, req.AccessKey, req.SecretKey, req.Region, h.Meta, h.Engine)
 	if err != nil {
 		writeAdminError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -34,7 +34,7 @@ func (h *Handler) handleReplPush(w http.ResponseWriter, r *http.Request) {
 	}
 	interval := time.Duration(req.IntervalNanos)
 	backoffMax := time.Duration(req.BackoffMaxNanos)
-	err := repl.RunPush(req.Remote, req.Since, req.Limit, req.Watch, interval, backoffMax, req.AccessKey, req.SecretKey, req.Region, h.Meta)
+	err := repl.RunPush(req.Remote, req.Since, req.Limit, req.MinBatch, req.Watch, interval, backoffMax, req.AccessKey, req.SecretKey, req.Region, h.Meta)
 	if err != nil {
 		writeAdminError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -42,6 +42,20 @@ func (h *Handler) handleReplPush(w http.ResponseWriter, r *http.Request) {
 	writeAdminJSON(w, map[string]string{"status": "ok"})
 }
 
+func (h *Handler) handleReplValidate(w http.ResponseWriter, r *http.Request) {
+	var req ReplValidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	report, err := repl.RunValidateSample(req.Remote, req.AccessKey, req.SecretKey, req.Region, req.SamplePercent, req.Limit, req.RatePerSecond, h.Meta)
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAdminJSON(w, report)
+}
+
 func (h *Handler) handleReplBootstrap(w http.ResponseWriter, r *http.Request) {
 	var req ReplBootstrapRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -53,7 +67,11 @@ func (h *Handler) handleReplBootstrap(w http.ResponseWriter, r *http.Request) {
 		layout := h.Engine.Layout()
 		dataDir = filepath.Dir(layout.Root)
 	}
-	err := repl.RunBootstrap(req.Remote, req.AccessKey, req.SecretKey, req.Region, dataDir, req.Force)
+	siteID := req.SiteID
+	if siteID == "" && h.Meta != nil {
+		siteID = h.Meta.SiteID()
+	}
+	err := repl.RunBootstrap(req.Remote, req.AccessKey, req.SecretKey, req.Region, dataDir, siteID, req.Force)
 	if err != nil {
 		writeAdminError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -1,29 +1,53 @@
 package clock
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
 
+// DefaultMaxDrift bounds how far a wall-clock reading may move away from the
+// monotonic elapsed time since the previous reading before it is clamped.
+const DefaultMaxDrift = time.Second
+
+var processStart = time.Now()
+
 // HLC implements a simple hybrid logical clock for ordering local events.
+//
+// Wall-clock readings are checked against the monotonic clock: a step of
+// more than maxDrift (NTP correction, manual change, VM resume) is clamped,
+// so a large jump is absorbed at most maxDrift per reading.
 type HLC struct {
 	mu           sync.Mutex
 	lastPhysical int64
 	logical      uint32
+
+	maxDrift int64
+	wallNow  func() int64
+	monoNow  func() int64
+	anchored bool
+	refWall  int64
+	refMono  int64
+	clamping bool
+	clamps   uint64
 }
 
 // New returns a new HLC instance.
 func New() *HLC {
-	return &HLC{}
+	return &HLC{
+		maxDrift: int64(DefaultMaxDrift),
+		wallNow:  func() int64 { return time.Now().UTC().UnixNano() },
+		monoNow:  func() int64 { return int64(time.Since(processStart)) },
+	}
 }
 
 // Next returns the next HLC timestamp string (lexicographically sortable).
 func (h *HLC) Next() string {
-	now := time.Now().UTC().UnixNano()
 	h.mu.Lock()
+	now := h.physicalNowLocked()
 	if now > h.lastPhysical {
 		h.lastPhysical = now
 		h.logical = 0
@@ -36,6 +60,48 @@ func (h *HLC) Next() string {
 	return format(physical, logical)
 }
 
+// Clamps returns how many wall-clock readings were clamped.
+func (h *HLC) Clamps() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.clamps
+}
+
+// physicalNowLocked reads the wall clock and clamps it to the previous
+// reading plus the monotonic elapsed time, give or take maxDrift.
+func (h *HLC) physicalNowLocked() int64 {
+	if h.wallNow == nil {
+		return time.Now().UTC().UnixNano()
+	}
+	wall := h.wallNow()
+	mono := h.monoNow()
+	if !h.anchored {
+		h.anchored = true
+		h.refWall, h.refMono = wall, mono
+		return wall
+	}
+	expected := h.refWall + (mono - h.refMono)
+	used := wall
+	direction := ""
+	switch {
+	case wall > expected+h.maxDrift:
+		used = expected + h.maxDrift
+		direction = "forward"
+	case wall < expected-h.maxDrift:
+		used = expected - h.maxDrift
+		direction = "backward"
+	}
+	if direction != "" {
+		h.clamps++
+		if !h.clamping {
+			log.Printf("level=warn hlc_clamped direction=%s skew_ms=%d max_drift_ms=%d", direction, (wall-expected)/int64(time.Millisecond), h.maxDrift/int64(time.Millisecond))
+		}
+	}
+	h.clamping = direction != ""
+	h.refWall, h.refMono = used, mono
+	return used
+}
+
 // Update advances the clock if the provided timestamp is ahead.
 func (h *HLC) Update(ts string) bool {
 	physical, logical, ok := parse(ts)
@@ -57,22 +123,64 @@ func (h *HLC) Update(ts string) bool {
 	return updated
 }
 
-func parse(ts string) (int64, uint32, bool) {
-	parts := strings.SplitN(ts, "-", 2)
-	if len(parts) != 2 {
-		return 0, 0, false
+// HLC timestamps are serialized as "<physical>-<logical>": the physical part
+// is Unix nanoseconds (UTC), zero-padded to 19 digits, and the logical
+// counter is zero-padded to 10 digits. Both fields have a fixed width and
+// the physical time is never negative, so comparing two timestamps as
+// strings (Go, SQLite ORDER BY and > on TEXT) gives the same order as
+// comparing (physical, logical) numerically.
+const (
+	hlcPhysicalDigits = 19
+	hlcLogicalDigits  = 10
+	// HLCLength is the length of every valid HLC timestamp.
+	HLCLength = hlcPhysicalDigits + 1 + hlcLogicalDigits
+)
+
+// ErrInvalidHLC is returned by ParseHLC for strings that are not in the
+// fixed-width HLC format.
+var ErrInvalidHLC = errors.New("clock: invalid hlc timestamp")
+
+// FormatHLC serializes an HLC timestamp. physical must not be negative.
+func FormatHLC(physical int64, logical uint32) string {
+	return fmt.Sprintf("%0*d-%0*d", hlcPhysicalDigits, physical, hlcLogicalDigits, logical)
+}
+
+// ParseHLC parses a timestamp produced by FormatHLC. Anything else (other
+// widths, signs, spaces, a logical counter above MaxUint32) is rejected, since
+// it would not sort correctly against valid timestamps.
+func ParseHLC(ts string) (physical int64, logical uint32, err error) {
+	if len(ts) != HLCLength || ts[hlcPhysicalDigits] != '-' {
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidHLC, ts)
+	}
+	physicalPart, logicalPart := ts[:hlcPhysicalDigits], ts[hlcPhysicalDigits+1:]
+	if !allDigits(physicalPart) || !allDigits(logicalPart) {
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidHLC, ts)
 	}
-	physical, err := strconv.ParseInt(parts[0], 10, 64)
+	physical, err = strconv.ParseInt(physicalPart, 10, 64)
 	if err != nil {
-		return 0, 0, false
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidHLC, ts)
 	}
-	logical64, err := strconv.ParseUint(parts[1], 10, 32)
+	logical64, err := strconv.ParseUint(logicalPart, 10, 32)
 	if err != nil {
-		return 0, 0, false
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidHLC, ts)
 	}
-	return physical, uint32(logical64), true
+	return physical, uint32(logical64), nil
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func parse(ts string) (int64, uint32, bool) {
+	physical, logical, err := ParseHLC(ts)
+	return physical, logical, err == nil
 }
 
 func format(physical int64, logical uint32) string {
-	return fmt.Sprintf("%019d-%010d", physical, logical)
+	return FormatHLC(physical, logical)
 }
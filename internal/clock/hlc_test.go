@@ -0,0 +1,166 @@
+package clock
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"testing"
+	"time"
+)
+
+type fakeClocks struct {
+	wall int64
+	mono int64
+}
+
+func newTestHLC(c *fakeClocks) *HLC {
+	h := New()
+	h.wallNow = func() int64 { return c.wall }
+	h.monoNow = func() int64 { return c.mono }
+	return h
+}
+
+func mustParse(t *testing.T, ts string) (int64, uint32) {
+	t.Helper()
+	physical, logical, ok := parse(ts)
+	if !ok {
+		t.Fatalf("parse %q failed", ts)
+	}
+	return physical, logical
+}
+
+func TestHLCBackwardJumpStaysMonotonic(t *testing.T) {
+	c := &fakeClocks{wall: int64(1000 * time.Second), mono: 0}
+	h := newTestHLC(c)
+	prev := h.Next()
+
+	c.wall -= int64(time.Hour)
+	c.mono += int64(time.Millisecond)
+	for i := 0; i < 5; i++ {
+		next := h.Next()
+		if next <= prev {
+			t.Fatalf("expected %s > %s after backward jump", next, prev)
+		}
+		prev = next
+		c.mono += int64(time.Millisecond)
+	}
+	if h.Clamps() == 0 {
+		t.Fatalf("expected backward jump to be clamped")
+	}
+	physical, _ := mustParse(t, prev)
+	if physical != int64(1000*time.Second) {
+		t.Fatalf("expected physical to hold at last value, got %d", physical)
+	}
+}
+
+func TestHLCForwardJumpIsCapped(t *testing.T) {
+	c := &fakeClocks{wall: int64(1000 * time.Second), mono: 0}
+	h := newTestHLC(c)
+	start, _ := mustParse(t, h.Next())
+
+	c.wall += int64(time.Hour)
+	c.mono += int64(10 * time.Millisecond)
+	physical, _ := mustParse(t, h.Next())
+	limit := start + int64(10*time.Millisecond) + int64(DefaultMaxDrift)
+	if physical != limit {
+		t.Fatalf("expected physical capped at %d, got %d", limit, physical)
+	}
+	if h.Clamps() != 1 {
+		t.Fatalf("expected 1 clamp, got %d", h.Clamps())
+	}
+
+	// Small steps within the drift bound are taken as-is.
+	c.wall = physical + int64(500*time.Millisecond)
+	c.mono += int64(time.Millisecond)
+	next, _ := mustParse(t, h.Next())
+	if next != c.wall {
+		t.Fatalf("expected unclamped wall %d, got %d", c.wall, next)
+	}
+}
+
+func TestHLCNextAfterUpdate(t *testing.T) {
+	c := &fakeClocks{wall: int64(1000 * time.Second), mono: 0}
+	h := newTestHLC(c)
+	remote := format(int64(2000*time.Second), 7)
+	if !h.Update(remote) {
+		t.Fatalf("expected update to advance clock")
+	}
+	c.mono += int64(time.Millisecond)
+	c.wall += int64(time.Millisecond)
+	if next := h.Next(); next <= remote {
+		t.Fatalf("expected %s > %s", next, remote)
+	}
+}
+
+func TestFormatHLCSortsLikeNumbers(t *testing.T) {
+	type ts struct {
+		physical int64
+		logical  uint32
+	}
+	sec := int64(time.Second)
+	values := []ts{
+		{0, 0},
+		{0, 1},
+		{0, math.MaxUint32},
+		{1, 0},
+		{sec - 1, math.MaxUint32},
+		{sec, 0},
+		{sec, 9},
+		{sec, 10},
+		{sec + 1, 0},
+		{10*sec - 1, 0},
+		{10 * sec, 0},
+		{1_700_000_000*sec + 999_999_999, 2},
+		{1_700_000_001 * sec, 0},
+		{math.MaxInt64, math.MaxUint32},
+	}
+	for i := 1; i < len(values); i++ {
+		prev, next := values[i-1], values[i]
+		a, b := FormatHLC(prev.physical, prev.logical), FormatHLC(next.physical, next.logical)
+		if len(a) != HLCLength || len(b) != HLCLength {
+			t.Fatalf("unexpected length: %q %q", a, b)
+		}
+		if a >= b {
+			t.Fatalf("%q must sort before %q", a, b)
+		}
+		physical, logical, err := ParseHLC(b)
+		if err != nil || physical != next.physical || logical != next.logical {
+			t.Fatalf("round trip %q: %d %d %v", b, physical, logical, err)
+		}
+	}
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[len(values)-1-i] = FormatHLC(v.physical, v.logical)
+	}
+	sort.Strings(strs)
+	for i, v := range values {
+		if strs[i] != FormatHLC(v.physical, v.logical) {
+			t.Fatalf("sorted position %d: got %q", i, strs[i])
+		}
+	}
+}
+
+func TestParseHLCRejectsMalformed(t *testing.T) {
+	for _, ts := range []string{
+		"",
+		"1",
+		"1-1",
+		"0000000000000000001-1",
+		"000000000000000001-00000000001",
+		"0000000000000000001_0000000001",
+		"-000000000000000001-0000000001",
+		"+000000000000000001-0000000001",
+		" 000000000000000001-0000000001",
+		"0000000000000000001-000000000a",
+		"9999999999999999999-0000000000",
+		"0000000000000000001-9999999999",
+		"0000000000000000001-0000000001-0",
+	} {
+		if _, _, err := ParseHLC(ts); !errors.Is(err, ErrInvalidHLC) {
+			t.Fatalf("ParseHLC(%q): expected ErrInvalidHLC, got %v", ts, err)
+		}
+		if New().Update(ts) {
+			t.Fatalf("Update(%q) must ignore malformed timestamps", ts)
+		}
+	}
+}
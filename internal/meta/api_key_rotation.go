@@ -0,0 +1,91 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RotateAPIKeySecret replaces the secret of an existing API key, keeping its
+// policy, enabled state, inflight limit and bucket allowlist. With overlap > 0
+// the old secret keeps validating for that long so clients can roll over;
+// otherwise it stops at once. Returns sql.ErrNoRows for an unknown key.
+func (s *Store) RotateAPIKeySecret(ctx context.Context, accessKey, newSecret string, overlap time.Duration) (err error) {
+	if accessKey == "" || newSecret == "" {
+		return fmt.Errorf("meta: access key and secret required")
+	}
+	if overlap < 0 {
+		return errors.New("meta: overlap must not be negative")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	key, err := getAPIKeyTx(tx, accessKey)
+	if err != nil {
+		return err
+	}
+	if key.SecretKey == newSecret {
+		return errors.New("meta: new secret matches the current secret")
+	}
+	now := s.now().UTC()
+	previous, expiresAt := "", ""
+	if overlap > 0 {
+		previous = key.SecretKey
+		expiresAt = now.Add(overlap).Format(time.RFC3339Nano)
+	}
+	if _, err = tx.ExecContext(ctx, `
+UPDATE api_keys
+SET secret_key=?, secret_hash=?, previous_secret=NULLIF(?, ''), previous_secret_expires_at=NULLIF(?, '')
+WHERE access_key=?`, newSecret, newSecret, previous, expiresAt, accessKey); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(oplogAPIKeyPayload{
+		AccessKey:               key.AccessKey,
+		SecretKey:               newSecret,
+		Enabled:                 key.Enabled,
+		Policy:                  key.Policy,
+		InflightLimit:           key.InflightLimit,
+		PreviousSecretKey:       previous,
+		PreviousSecretExpiresAt: expiresAt,
+		UpdatedAt:               now.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+	hlcTS, _ := s.nextHLC()
+	if err := s.recordOplogTx(tx, hlcTS, "api_key", metaOplogBucket, accessKey, "", string(payload)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LookupAPISecrets returns the current secret, the secret replaced by
+// RotateAPIKeySecret while its overlap window is open ("" otherwise) and the
+// enabled state of an access key, all from one query.
+func (s *Store) LookupAPISecrets(ctx context.Context, accessKey string) (string, string, bool, error) {
+	key, err := s.GetAPIKey(ctx, accessKey)
+	if err != nil {
+		return "", "", false, err
+	}
+	return key.SecretKey, key.openPreviousSecret(s.now()), key.Enabled, nil
+}
+
+// openPreviousSecret returns PreviousSecretKey while its overlap is open at now.
+func (k *APIKey) openPreviousSecret(now time.Time) string {
+	if k.PreviousSecretKey == "" {
+		return ""
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, k.PreviousSecretExpiresAt)
+	if err != nil || !now.Before(expiresAt) {
+		return ""
+	}
+	return k.PreviousSecretKey
+}
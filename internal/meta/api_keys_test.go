@@ -2,8 +2,13 @@ package meta
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/clock"
 )
 
 func TestAPIKeyLifecycleAndBuckets(t *testing.T) {
@@ -78,3 +83,80 @@ func TestAPIKeyLifecycleAndBuckets(t *testing.T) {
 		t.Fatalf("expected key deleted")
 	}
 }
+
+func TestRotateAPIKeySecretOverlap(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.clock = clock.FixedClock{T: start}
+
+	if err := store.RotateAPIKeySecret(ctx, "missing", "s2", time.Hour); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected ErrNoRows for unknown key, got %v", err)
+	}
+	if err := store.UpsertAPIKey(ctx, "k1", "s1", "ro", false, 7); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	if err := store.SetAPIKeyEnabled(ctx, "k1", true); err != nil {
+		t.Fatalf("SetAPIKeyEnabled: %v", err)
+	}
+	if err := store.AllowBucketForKey(ctx, "k1", "b1"); err != nil {
+		t.Fatalf("AllowBucketForKey: %v", err)
+	}
+	if err := store.RotateAPIKeySecret(ctx, "k1", "s1", time.Hour); err == nil {
+		t.Fatalf("expected error rotating to the current secret")
+	}
+	if err := store.RotateAPIKeySecret(ctx, "k1", "s2", time.Hour); err != nil {
+		t.Fatalf("RotateAPIKeySecret: %v", err)
+	}
+	// A policy change during the overlap must not end it.
+	if err := store.UpdateAPIKeyPolicy(ctx, "k1", "rw"); err != nil {
+		t.Fatalf("UpdateAPIKeyPolicy: %v", err)
+	}
+
+	target, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("Open target: %v", err)
+	}
+	defer func() { _ = target.Close() }()
+	target.clock = store.clock
+	entries, err := store.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+
+	for name, s := range map[string]*Store{"source": store, "target": target} {
+		secret, enabled, err := s.LookupAPISecret(ctx, "k1")
+		if err != nil || secret != "s2" || !enabled {
+			t.Fatalf("%s: current secret %q enabled=%v (%v)", name, secret, enabled, err)
+		}
+		key, err := s.GetAPIKey(ctx, "k1")
+		if err != nil || key.Policy != "rw" || key.InflightLimit != 7 {
+			t.Fatalf("%s: unexpected key %+v (%v)", name, key, err)
+		}
+		if buckets, err := s.ListAllowedBuckets(ctx, "k1"); err != nil || len(buckets) != 1 || buckets[0] != "b1" {
+			t.Fatalf("%s: allowlist %v (%v)", name, buckets, err)
+		}
+		if _, previous, _, err := s.LookupAPISecrets(ctx, "k1"); err != nil || previous != "s1" {
+			t.Fatalf("%s: expected previous secret during overlap, got %q (%v)", name, previous, err)
+		}
+		s.clock = clock.FixedClock{T: start.Add(time.Hour)}
+		if _, previous, _, err := s.LookupAPISecrets(ctx, "k1"); err != nil || previous != "" {
+			t.Fatalf("%s: expected previous secret expired, got %q (%v)", name, previous, err)
+		}
+	}
+
+	if err := store.RotateAPIKeySecret(ctx, "k1", "s3", 0); err != nil {
+		t.Fatalf("RotateAPIKeySecret without overlap: %v", err)
+	}
+	store.clock = clock.FixedClock{T: start}
+	if secret, previous, _, err := store.LookupAPISecrets(ctx, "k1"); err != nil || secret != "s3" || previous != "" {
+		t.Fatalf("expected s3 and no previous secret without overlap, got %q/%q (%v)", secret, previous, err)
+	}
+}
@@ -0,0 +1,85 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type oplogBucketContentTypePayload struct {
+	Bucket string `json:"bucket"`
+	// ContentType is empty when the default was removed.
+	ContentType string `json:"content_type,omitempty"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// SetBucketDefaultContentType sets the Content-Type stored for objects PUT to
+// an existing bucket without one; "" removes it. Recorded in the oplog as
+// "bucket_content_type" so it replicates. Returns sql.ErrNoRows for an
+// unknown bucket.
+func (s *Store) SetBucketDefaultContentType(ctx context.Context, bucket, contentType string) error {
+	if bucket == "" {
+		return errors.New("meta: bucket required")
+	}
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	return s.WithTx(func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT 1 FROM buckets WHERE bucket=?", bucket).Scan(&exists); err != nil {
+			return err
+		}
+		payload := oplogBucketContentTypePayload{Bucket: bucket, ContentType: contentType, UpdatedAt: now}
+		if err := setBucketDefaultContentTypeTx(tx, payload); err != nil {
+			return err
+		}
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		hlcTS, _ := s.nextHLC()
+		return s.recordOplogTx(tx, hlcTS, "bucket_content_type", bucket, bucket, "", string(raw))
+	})
+}
+
+// GetBucketDefaultContentType returns the default Content-Type of a bucket,
+// or "" when none is set.
+func (s *Store) GetBucketDefaultContentType(ctx context.Context, bucket string) (string, error) {
+	if bucket == "" {
+		return "", errors.New("meta: bucket required")
+	}
+	var contentType string
+	err := s.db.QueryRowContext(ctx, "SELECT content_type FROM bucket_content_types WHERE bucket=?", bucket).Scan(&contentType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return contentType, err
+}
+
+func setBucketDefaultContentTypeTx(tx *sql.Tx, payload oplogBucketContentTypePayload) error {
+	if payload.ContentType == "" {
+		_, err := tx.Exec("DELETE FROM bucket_content_types WHERE bucket=?", payload.Bucket)
+		return err
+	}
+	_, err := tx.Exec(`
+INSERT INTO bucket_content_types(bucket, content_type, updated_at)
+VALUES(?, ?, ?)
+ON CONFLICT(bucket) DO UPDATE SET content_type=excluded.content_type, updated_at=excluded.updated_at`,
+		payload.Bucket, payload.ContentType, payload.UpdatedAt)
+	return err
+}
+
+func applyBucketContentTypeTx(tx *sql.Tx, entry OplogEntry) error {
+	var payload oplogBucketContentTypePayload
+	if entry.Payload == "" {
+		return fmt.Errorf("meta: bucket_content_type payload required")
+	}
+	if err := json.Unmarshal([]byte(entry.Payload), &payload); err != nil {
+		return err
+	}
+	if payload.Bucket == "" {
+		payload.Bucket = entry.Bucket
+	}
+	return setBucketDefaultContentTypeTx(tx, payload)
+}
@@ -0,0 +1,118 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BucketTag is one cost-allocation tag of a bucket.
+type BucketTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type oplogBucketTagsPayload struct {
+	Bucket string `json:"bucket"`
+	// Tags is empty when the tag set was deleted.
+	Tags      []BucketTag `json:"tags,omitempty"`
+	UpdatedAt string      `json:"updated_at"`
+}
+
+// SetBucketTags replaces the tag set of an existing bucket; an empty set
+// deletes it. Recorded in the oplog as "bucket_tags" so it replicates.
+// Returns sql.ErrNoRows for an unknown bucket.
+func (s *Store) SetBucketTags(ctx context.Context, bucket string, tags []BucketTag) error {
+	if bucket == "" {
+		return errors.New("meta: bucket required")
+	}
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	return s.WithTx(func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT 1 FROM buckets WHERE bucket=?", bucket).Scan(&exists); err != nil {
+			return err
+		}
+		payload := oplogBucketTagsPayload{Bucket: bucket, Tags: tags, UpdatedAt: now}
+		if err := setBucketTagsTx(tx, payload); err != nil {
+			return err
+		}
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		hlcTS, _ := s.nextHLC()
+		return s.recordOplogTx(tx, hlcTS, "bucket_tags", bucket, bucket, "", string(raw))
+	})
+}
+
+// DeleteBucketTags removes the tag set of an existing bucket.
+func (s *Store) DeleteBucketTags(ctx context.Context, bucket string) error {
+	return s.SetBucketTags(ctx, bucket, nil)
+}
+
+// GetBucketTags returns the tags of a bucket ordered by key (none = empty).
+func (s *Store) GetBucketTags(ctx context.Context, bucket string) (out []BucketTag, err error) {
+	if bucket == "" {
+		return nil, errors.New("meta: bucket required")
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT tag_key, tag_value FROM bucket_tags WHERE bucket=? ORDER BY tag_key", bucket)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var tag BucketTag
+		if err := scan(&tag.Key, &tag.Value); err != nil {
+			return err
+		}
+		out = append(out, tag)
+		return nil
+	})
+}
+
+// ListBucketTags returns the tags of every tagged bucket, ordered by key.
+func (s *Store) ListBucketTags(ctx context.Context) (map[string][]BucketTag, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT bucket, tag_key, tag_value FROM bucket_tags ORDER BY bucket, tag_key")
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]BucketTag)
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var bucket string
+		var tag BucketTag
+		if err := scan(&bucket, &tag.Key, &tag.Value); err != nil {
+			return err
+		}
+		out[bucket] = append(out[bucket], tag)
+		return nil
+	})
+}
+
+func setBucketTagsTx(tx *sql.Tx, payload oplogBucketTagsPayload) error {
+	if _, err := tx.Exec("DELETE FROM bucket_tags WHERE bucket=?", payload.Bucket); err != nil {
+		return err
+	}
+	for _, tag := range payload.Tags {
+		if _, err := tx.Exec("INSERT INTO bucket_tags(bucket, tag_key, tag_value, updated_at) VALUES(?, ?, ?, ?)",
+			payload.Bucket, tag.Key, tag.Value, payload.UpdatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyBucketTagsTx(tx *sql.Tx, entry OplogEntry) error {
+	var payload oplogBucketTagsPayload
+	if entry.Payload == "" {
+		return fmt.Errorf("meta: bucket_tags payload required")
+	}
+	if err := json.Unmarshal([]byte(entry.Payload), &payload); err != nil {
+		return err
+	}
+	if payload.Bucket == "" {
+		payload.Bucket = entry.Bucket
+	}
+	return setBucketTagsTx(tx, payload)
+}
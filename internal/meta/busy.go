@@ -0,0 +1,67 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrBusy wraps a SQLite BUSY/LOCKED error that persisted through busy_timeout
+// and every retry; callers should back off rather than report an internal error.
+var ErrBusy = errors.New("meta: database busy")
+
+const (
+	busyRetryAttempts = 4
+	busyRetryBase     = 10 * time.Millisecond
+)
+
+// IsBusy reports whether err is SQLite lock contention (SQLITE_BUSY or
+// SQLITE_LOCKED, including their extended codes) or already wraps ErrBusy.
+func IsBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrBusy) {
+		return true
+	}
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	}
+	return false
+}
+
+// SetBusyRetryHook registers fn to be called each time a transaction is
+// retried after SQLite reported BUSY/LOCKED (e.g. to count it in metrics).
+// Call it before the store is shared.
+func (s *Store) SetBusyRetryHook(fn func()) {
+	s.busyRetryHook = fn
+}
+
+// withBusyRetry runs fn, re-running it with jittered exponential backoff while
+// it fails with a busy error. fn must roll back its own transaction on error
+// so a retry starts clean. A busy error that survives every attempt is
+// wrapped in ErrBusy.
+func (s *Store) withBusyRetry(fn func() error) error {
+	err := fn()
+	for attempt := 0; attempt < busyRetryAttempts && IsBusy(err); attempt++ {
+		if s.busyRetryHook != nil {
+			s.busyRetryHook()
+		}
+		backoff := busyRetryBase << attempt
+		time.Sleep(backoff/2 + rand.N(backoff/2+1))
+		err = fn()
+	}
+	if IsBusy(err) && !errors.Is(err, ErrBusy) {
+		return fmt.Errorf("%w: %w", ErrBusy, err)
+	}
+	return err
+}
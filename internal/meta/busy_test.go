@@ -0,0 +1,79 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithTxRetriesBusy(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "meta.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	// One connection without busy_timeout, so contention surfaces at once.
+	store.db.SetMaxOpenConns(1)
+	if _, err := store.db.ExecContext(ctx, "PRAGMA busy_timeout=0"); err != nil {
+		t.Fatalf("busy_timeout: %v", err)
+	}
+	if _, err := store.db.ExecContext(ctx, "CREATE TABLE busy_probe(n INTEGER)"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var retries int
+	store.SetBusyRetryHook(func() { retries++ })
+
+	other, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = other.Close() })
+	locker, err := other.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn: %v", err)
+	}
+	t.Cleanup(func() { _ = locker.Close() })
+	insert := func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO busy_probe(n) VALUES (1)")
+		return err
+	}
+
+	if _, err := locker.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("BEGIN IMMEDIATE: %v", err)
+	}
+	err = store.WithTx(insert)
+	if !errors.Is(err, ErrBusy) || !IsBusy(err) {
+		t.Fatalf("expected ErrBusy while the lock is held, got %v", err)
+	}
+	if retries != busyRetryAttempts {
+		t.Fatalf("expected %d retries, got %d", busyRetryAttempts, retries)
+	}
+
+	retries = 0
+	released := make(chan error, 1)
+	time.AfterFunc(15*time.Millisecond, func() {
+		_, err := locker.ExecContext(ctx, "COMMIT")
+		released <- err
+	})
+	if err := store.WithTx(insert); err != nil {
+		t.Fatalf("WithTx after release: %v", err)
+	}
+	if err := <-released; err != nil {
+		t.Fatalf("COMMIT: %v", err)
+	}
+	if retries == 0 {
+		t.Fatalf("expected the first attempt to hit the held lock")
+	}
+	var rows int
+	if err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM busy_probe").Scan(&rows); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if rows != 1 {
+		t.Fatalf("expected one committed row, got %d", rows)
+	}
+}
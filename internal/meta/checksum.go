@@ -0,0 +1,37 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// SetVersionChecksumTx stores the validated additional checksum of a version
+// (algorithm such as "crc32c", base64 value as sent by the client).
+func (s *Store) SetVersionChecksumTx(tx *sql.Tx, versionID, algorithm, value string) error {
+	if tx == nil {
+		return errors.New("meta: tx required")
+	}
+	if versionID == "" || algorithm == "" || value == "" {
+		return errors.New("meta: version id, checksum algorithm and value required")
+	}
+	_, err := tx.Exec("UPDATE versions SET checksum_algorithm=?, checksum_value=? WHERE version_id=?", algorithm, value, versionID)
+	return err
+}
+
+// VersionChecksum returns the stored checksum of a version; empty strings
+// mean none was recorded.
+func (s *Store) VersionChecksum(ctx context.Context, versionID string) (string, string, error) {
+	if versionID == "" {
+		return "", "", errors.New("meta: version id required")
+	}
+	var algorithm, value string
+	err := s.db.QueryRowContext(ctx, "SELECT checksum_algorithm, checksum_value FROM versions WHERE version_id=?", versionID).Scan(&algorithm, &value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	return algorithm, value, nil
+}
@@ -0,0 +1,104 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// CurrentRepair describes an objects_current row that referenced a missing or
+// DELETED version. NewVersionID is empty when the pointer was removed.
+type CurrentRepair struct {
+	Bucket       string
+	Key          string
+	VersionID    string
+	NewVersionID string
+}
+
+// Removed reports whether the repair dropped the pointer instead of
+// repointing it.
+func (r CurrentRepair) Removed() bool {
+	return r.NewVersionID == ""
+}
+
+// ListDanglingCurrent returns objects_current rows whose version is missing,
+// belongs to another key or is DELETED. Delete markers and DAMAGED versions
+// are valid current versions and are not reported.
+func (s *Store) ListDanglingCurrent(ctx context.Context) (out []CurrentRepair, err error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("meta: db not initialized")
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT o.bucket, o.key, o.version_id
+FROM objects_current o
+LEFT JOIN versions v ON v.version_id=o.version_id AND v.bucket=o.bucket AND v.key=o.key
+WHERE v.version_id IS NULL OR v.state='DELETED'
+ORDER BY o.bucket, o.key`)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var item CurrentRepair
+		if err := scan(&item.Bucket, &item.Key, &item.VersionID); err != nil {
+			return err
+		}
+		out = append(out, item)
+		return nil
+	})
+}
+
+// RepairCurrent repoints a dangling objects_current row at the newest
+// non-DELETED version of the key (the rule DeleteObjectVersionTx uses), or
+// removes it when none is left. The row is re-checked in the transaction, so
+// ok is false when a concurrent write already fixed it. Repairs are local and
+// not written to the oplog; every site derives its own pointer.
+func (s *Store) RepairCurrent(ctx context.Context, item CurrentRepair) (repair CurrentRepair, ok bool, err error) {
+	if item.Bucket == "" || item.Key == "" {
+		return item, false, errors.New("meta: bucket and key required")
+	}
+	repair = item
+	err = s.WithTx(func(tx *sql.Tx) error {
+		var current string
+		var state sql.NullString
+		err := tx.QueryRowContext(ctx, `
+SELECT o.version_id, v.state
+FROM objects_current o
+LEFT JOIN versions v ON v.version_id=o.version_id AND v.bucket=o.bucket AND v.key=o.key
+WHERE o.bucket=? AND o.key=?`, item.Bucket, item.Key).Scan(&current, &state)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		if current != item.VersionID || (state.Valid && state.String != "DELETED") {
+			return nil
+		}
+		var next string
+		err = tx.QueryRowContext(ctx, `
+SELECT version_id
+FROM versions
+WHERE bucket=? AND key=? AND state<>'DELETED'
+ORDER BY hlc_ts DESC, site_id DESC
+LIMIT 1`, item.Bucket, item.Key).Scan(&next)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if _, err := tx.ExecContext(ctx, "DELETE FROM objects_current WHERE bucket=? AND key=?", item.Bucket, item.Key); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if _, err := tx.ExecContext(ctx, "UPDATE objects_current SET version_id=? WHERE bucket=? AND key=?", next, item.Bucket, item.Key); err != nil {
+				return err
+			}
+		}
+		repair.NewVersionID = next
+		ok = true
+		return nil
+	})
+	if err != nil {
+		return item, false, err
+	}
+	return repair, ok, nil
+}
@@ -0,0 +1,131 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Durability levels for the metadata store.
+const (
+	// DurabilityFull runs synchronous=FULL and checkpoints the WAL on every
+	// barrier flush: acknowledged writes survive power loss.
+	DurabilityFull = "full"
+	// DurabilityBalanced runs synchronous=NORMAL and checkpoints the WAL at
+	// most once per sync window: power loss may drop commits acknowledged
+	// within the last window, but never leaves the database inconsistent.
+	DurabilityBalanced = "balanced"
+)
+
+const defaultSyncWindow = time.Second
+
+// OpenOptions configures OpenWithOptions.
+type OpenOptions struct {
+	// Durability selects DurabilityFull (default) or DurabilityBalanced.
+	Durability string
+	// SyncWindow bounds how long balanced commits may stay unsynced (0 = 1s).
+	SyncWindow time.Duration
+}
+
+// ParseDurability validates a durability level name.
+func ParseDurability(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", DurabilityFull:
+		return DurabilityFull, nil
+	case DurabilityBalanced:
+		return DurabilityBalanced, nil
+	default:
+		return "", fmt.Errorf("meta: unknown durability %q (want full or balanced)", value)
+	}
+}
+
+// ValidateDurability checks that the balanced sync window is not shorter
+// than the write barrier interval; a shorter window would checkpoint on
+// every flush and report a loss bound the barrier cannot provide.
+func ValidateDurability(durability string, syncWindow, barrierInterval time.Duration) error {
+	if durability != DurabilityBalanced {
+		return nil
+	}
+	if syncWindow <= 0 {
+		syncWindow = defaultSyncWindow
+	}
+	if barrierInterval > 0 && syncWindow < barrierInterval {
+		return fmt.Errorf("meta: durability window %s is shorter than barrier interval %s", syncWindow, barrierInterval)
+	}
+	return nil
+}
+
+// Durability returns the store's durability level.
+func (s *Store) Durability() string {
+	if s == nil || s.durability == "" {
+		return DurabilityFull
+	}
+	return s.durability
+}
+
+// SyncWindow returns how long balanced commits may stay unsynced.
+func (s *Store) SyncWindow() time.Duration {
+	if s == nil || s.durability != DurabilityBalanced {
+		return 0
+	}
+	return s.syncWindow
+}
+
+func synchronousPragma(durability string) string {
+	if durability == DurabilityBalanced {
+		return "NORMAL"
+	}
+	return "FULL"
+}
+
+// flushAfterCommit checkpoints the WAL after a barrier commit. In balanced
+// mode the checkpoint is deferred until the sync window has elapsed.
+func (s *Store) flushAfterCommit() error {
+	if s.Durability() != DurabilityBalanced {
+		return s.Flush()
+	}
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	if s.closed {
+		return errors.New("meta: store closed")
+	}
+	elapsed := s.now().Sub(s.lastSync)
+	if elapsed >= s.syncWindow {
+		if err := s.Flush(); err != nil {
+			return err
+		}
+		s.lastSync = s.now()
+		return nil
+	}
+	if s.syncTimer == nil {
+		s.syncTimer = time.AfterFunc(s.syncWindow-elapsed, s.deferredSync)
+	}
+	return nil
+}
+
+func (s *Store) deferredSync() {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	s.syncTimer = nil
+	if s.closed {
+		return
+	}
+	if err := s.Flush(); err == nil {
+		s.lastSync = s.now()
+	}
+}
+
+// stopSync flushes pending balanced commits before the store closes.
+func (s *Store) stopSync() {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	if s.syncTimer != nil {
+		s.syncTimer.Stop()
+		s.syncTimer = nil
+	}
+	if !s.closed && s.Durability() == DurabilityBalanced {
+		_ = s.Flush()
+	}
+	s.closed = true
+}
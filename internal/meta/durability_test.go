@@ -0,0 +1,83 @@
+package meta
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBalancedDurabilityDefersCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "meta.db")
+	store, err := OpenWithOptions(path, OpenOptions{Durability: DurabilityBalanced, SyncWindow: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("OpenWithOptions: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	var sync int
+	if err := store.db.QueryRow("PRAGMA synchronous").Scan(&sync); err != nil {
+		t.Fatalf("PRAGMA synchronous: %v", err)
+	}
+	if sync != 1 {
+		t.Fatalf("expected synchronous=NORMAL (1), got %d", sync)
+	}
+
+	put := func(key string) []func(tx *sql.Tx) error {
+		return []func(tx *sql.Tx) error{func(tx *sql.Tx) error {
+			return store.RecordPutTx(tx, "b1", key, "v-"+key, "etag", 1, "/tmp/m", "")
+		}}
+	}
+	// The first flush checkpoints immediately; the second falls inside the window.
+	if err := store.FlushWith(put("k1")); err != nil {
+		t.Fatalf("FlushWith: %v", err)
+	}
+	if err := store.FlushWith(put("k2")); err != nil {
+		t.Fatalf("FlushWith: %v", err)
+	}
+	walPath := path + "-wal"
+	if info, err := os.Stat(walPath); err != nil || info.Size() == 0 {
+		t.Fatalf("expected pending WAL after deferred flush (err=%v)", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info, err := os.Stat(walPath)
+		if err != nil {
+			t.Fatalf("stat wal: %v", err)
+		}
+		if info.Size() == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("WAL not checkpointed after sync window (size=%d)", info.Size())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestValidateDurability(t *testing.T) {
+	cases := []struct {
+		name       string
+		durability string
+		window     time.Duration
+		interval   time.Duration
+		wantErr    bool
+	}{
+		{name: "full ignores window", durability: DurabilityFull, window: time.Millisecond, interval: time.Second},
+		{name: "balanced window covers interval", durability: DurabilityBalanced, window: time.Second, interval: 100 * time.Millisecond},
+		{name: "balanced window shorter than interval", durability: DurabilityBalanced, window: 50 * time.Millisecond, interval: 100 * time.Millisecond, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDurability(tc.durability, tc.window, tc.interval)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err=%v wantErr=%v", err, tc.wantErr)
+			}
+		})
+	}
+	if _, err := ParseDurability("fast"); err == nil {
+		t.Fatalf("expected unknown durability error")
+	}
+}
@@ -0,0 +1,118 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ExpiryTimeFormat is the fixed-width layout of versions.expires_at, so the
+// column orders chronologically as text.
+const ExpiryTimeFormat = "2006-01-02T15:04:05Z"
+
+// ExpiredVersion identifies a version whose expiry has passed.
+type ExpiredVersion struct {
+	Bucket    string
+	Key       string
+	VersionID string
+	ExpiresAt string
+}
+
+// SetVersionExpiryTx records the expiry of a version written in the same
+// transaction and adds it to the version's put oplog entry, so replicas
+// expire the version at the same time.
+func (s *Store) SetVersionExpiryTx(tx *sql.Tx, versionID string, expiresAt time.Time) error {
+	if tx == nil {
+		return errors.New("meta: tx required")
+	}
+	if versionID == "" || expiresAt.IsZero() {
+		return errors.New("meta: version id and expiry required")
+	}
+	value := expiresAt.UTC().Format(ExpiryTimeFormat)
+	if _, err := tx.Exec("UPDATE versions SET expires_at=? WHERE version_id=?", value, versionID); err != nil {
+		return err
+	}
+	return patchPutOplogPayloadTx(tx, versionID, func(payload *oplogPutPayload) {
+		payload.ExpiresAt = value
+	})
+}
+
+// patchPutOplogPayloadTx applies patch to the payload of the version's put
+// oplog entry written in the same transaction, so attributes set after the
+// put replicate with it. Versions without a put entry are left alone.
+func patchPutOplogPayloadTx(tx *sql.Tx, versionID string, patch func(*oplogPutPayload)) error {
+	var id int64
+	var raw string
+	err := tx.QueryRow("SELECT id, payload FROM oplog WHERE op_type='put' AND version_id=? ORDER BY id DESC LIMIT 1", versionID).Scan(&id, &raw)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	var payload oplogPutPayload
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+			return err
+		}
+	}
+	patch(&payload)
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE oplog SET payload=? WHERE id=?", string(data), id)
+	return err
+}
+
+// ListExpiredVersions returns up to limit versions whose expiry is at or
+// before now, oldest expiry first. Delete markers and deleted versions are
+// skipped.
+func (s *Store) ListExpiredVersions(ctx context.Context, now time.Time, limit int) (out []ExpiredVersion, err error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("meta: db not initialized")
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT bucket, key, version_id, expires_at
+FROM versions
+WHERE expires_at<>'' AND expires_at<=? AND state NOT IN ('DELETED', 'DELETE_MARKER')
+ORDER BY expires_at, version_id
+LIMIT ?`, now.UTC().Format(ExpiryTimeFormat), limit)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var item ExpiredVersion
+		if err := scan(&item.Bucket, &item.Key, &item.VersionID, &item.ExpiresAt); err != nil {
+			return err
+		}
+		out = append(out, item)
+		return nil
+	})
+}
+
+// Expired reports whether the version's expiry is at or before now.
+func (m *ObjectMeta) Expired(now time.Time) bool {
+	if m == nil || m.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(ExpiryTimeFormat, m.ExpiresAt)
+	if err != nil {
+		return false
+	}
+	return !now.Before(expiresAt)
+}
+
+// expiryCutoff is the expires_at bound of listings at now: rows with a later
+// expiry are still visible. A zero now yields "", which every expiry passes.
+func expiryCutoff(now time.Time) string {
+	if now.IsZero() {
+		return ""
+	}
+	return now.UTC().Format(ExpiryTimeFormat)
+}
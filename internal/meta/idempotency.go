@@ -0,0 +1,84 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// IdempotentPut is the recorded result of a PUT sent with an idempotency key.
+type IdempotentPut struct {
+	VersionID string
+	ETag      string
+	CreatedAt time.Time
+}
+
+// LookupIdempotencyKey returns the PUT recorded for bucket/key under idemKey
+// within ttl, or sql.ErrNoRows when there is none or it has expired.
+func (s *Store) LookupIdempotencyKey(ctx context.Context, bucket, key, idemKey string, ttl time.Duration) (*IdempotentPut, error) {
+	return s.lookupIdempotencyKey(ctx, s.db, bucket, key, idemKey, ttl)
+}
+
+// LookupIdempotencyKeyTx is LookupIdempotencyKey within the provided transaction.
+func (s *Store) LookupIdempotencyKeyTx(ctx context.Context, tx *sql.Tx, bucket, key, idemKey string, ttl time.Duration) (*IdempotentPut, error) {
+	if tx == nil {
+		return nil, errors.New("meta: tx required")
+	}
+	return s.lookupIdempotencyKey(ctx, tx, bucket, key, idemKey, ttl)
+}
+
+func (s *Store) lookupIdempotencyKey(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, bucket, key, idemKey string, ttl time.Duration) (*IdempotentPut, error) {
+	if bucket == "" || key == "" || idemKey == "" {
+		return nil, errors.New("meta: bucket, key, and idempotency key required")
+	}
+	var out IdempotentPut
+	var createdAt string
+	err := q.QueryRowContext(ctx, `
+SELECT version_id, etag, created_at
+FROM idempotency_keys
+WHERE bucket=? AND key=? AND idempotency_key=?`, bucket, key, idemKey).Scan(&out.VersionID, &out.ETag, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	out.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 && s.now().Sub(out.CreatedAt) >= ttl {
+		return nil, sql.ErrNoRows
+	}
+	return &out, nil
+}
+
+// RecordIdempotencyKeyTx maps bucket/key/idemKey to the version written in
+// the same transaction, replacing an expired mapping.
+func (s *Store) RecordIdempotencyKeyTx(ctx context.Context, tx *sql.Tx, bucket, key, idemKey, versionID, etag string) error {
+	if tx == nil {
+		return errors.New("meta: tx required")
+	}
+	if bucket == "" || key == "" || idemKey == "" || versionID == "" {
+		return errors.New("meta: bucket, key, idempotency key, and version id required")
+	}
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO idempotency_keys(bucket, key, idempotency_key, version_id, etag, created_at)
+VALUES(?, ?, ?, ?, ?, ?)
+ON CONFLICT(bucket, key, idempotency_key) DO UPDATE SET
+	version_id=excluded.version_id,
+	etag=excluded.etag,
+	created_at=excluded.created_at`,
+		bucket, key, idemKey, versionID, etag, s.now().UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// ExpireIdempotencyKeys deletes mappings recorded before the cutoff and
+// returns how many were removed.
+func (s *Store) ExpireIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE created_at < ?", before.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,53 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/clock"
+)
+
+func TestIdempotencyKeyLookupAndExpiry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newTestStore(t)
+	start := time.Date(2025, 12, 22, 12, 0, 0, 0, time.UTC)
+	store.clock = clock.FixedClock{T: start}
+
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	if err := store.RecordIdempotencyKeyTx(ctx, tx, "bucket", "key", "idem", "v1", "etag"); err != nil {
+		t.Fatalf("RecordIdempotencyKeyTx: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	rec, err := store.LookupIdempotencyKey(ctx, "bucket", "key", "idem", time.Hour)
+	if err != nil {
+		t.Fatalf("LookupIdempotencyKey: %v", err)
+	}
+	if rec.VersionID != "v1" || rec.ETag != "etag" || !rec.CreatedAt.Equal(start) {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+	if _, err := store.LookupIdempotencyKey(ctx, "bucket", "other", "idem", time.Hour); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected ErrNoRows for other key, got %v", err)
+	}
+
+	store.clock = clock.FixedClock{T: start.Add(2 * time.Hour)}
+	if _, err := store.LookupIdempotencyKey(ctx, "bucket", "key", "idem", time.Hour); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected expired mapping to be ignored, got %v", err)
+	}
+	removed, err := store.ExpireIdempotencyKeys(ctx, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ExpireIdempotencyKeys: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("expected 1 removed, got %d", removed)
+	}
+}
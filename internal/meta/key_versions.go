@@ -0,0 +1,76 @@
+package meta
+
+import (
+	"context"
+	"errors"
+)
+
+// KeyVersionStats aggregates the stored versions of one key.
+type KeyVersionStats struct {
+	Key string
+	// VersionCount counts versions and delete markers still stored
+	// (DELETED versions are not).
+	VersionCount int64
+	TotalBytes   int64
+	// LatestVersion is the current version id ("" when the key has none).
+	LatestVersion string
+}
+
+// ListKeyVersionStats returns per-key version counts and sizes for keys of
+// bucket starting with prefix, ordered by key and resuming after afterKey.
+func (s *Store) ListKeyVersionStats(ctx context.Context, bucket, prefix, afterKey string, limit int) (out []KeyVersionStats, err error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("meta: db not initialized")
+	}
+	if bucket == "" {
+		return nil, errors.New("meta: bucket required")
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+	// Grouping in versions_bucket_key_idx order needs no sort, so a page
+	// reads only the versions of the keys it returns.
+	where := "bucket=? AND key>=? AND key>? AND state<>'DELETED'"
+	args := []any{bucket, prefix, afterKey}
+	if upper := prefixUpperBound(prefix); upper != "" {
+		where += " AND key<?"
+		args = append(args, upper)
+	}
+	args = append(args, limit, bucket)
+	rows, err := s.db.QueryContext(ctx, `
+SELECT g.key, g.versions, g.bytes, COALESCE(o.version_id, '')
+FROM (
+	SELECT key, COUNT(*) AS versions, COALESCE(SUM(size), 0) AS bytes
+	FROM versions
+	WHERE `+where+`
+	GROUP BY key
+	ORDER BY key
+	LIMIT ?
+) g
+LEFT JOIN objects_current o ON o.bucket=? AND o.key=g.key
+ORDER BY g.key`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var stats KeyVersionStats
+		if err := scan(&stats.Key, &stats.VersionCount, &stats.TotalBytes, &stats.LatestVersion); err != nil {
+			return err
+		}
+		out = append(out, stats)
+		return nil
+	})
+}
+
+// prefixUpperBound returns the smallest string greater than every string
+// starting with prefix under byte-wise comparison ("" = unbounded).
+func prefixUpperBound(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package meta
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListKeyVersionStats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	for _, put := range []struct {
+		bucket, key, version string
+		size                 int64
+	}{
+		{"bucket", "logs/a", "v1", 10},
+		{"bucket", "logs/a", "v2", 20},
+		{"bucket", "logs/a", "v3", 30},
+		{"bucket", "logs/b", "v4", 5},
+		{"bucket", "logs0", "v5", 1},
+		{"bucket", "other", "v6", 7},
+		{"elsewhere", "logs/a", "v7", 100},
+	} {
+		if err := store.RecordPut(ctx, put.bucket, put.key, put.version, "etag", put.size, "", ""); err != nil {
+			t.Fatalf("RecordPut %s: %v", put.version, err)
+		}
+	}
+	if _, err := store.DeleteObjectVersion(ctx, "bucket", "logs/a", "v1"); err != nil {
+		t.Fatalf("DeleteObjectVersion: %v", err)
+	}
+	marker, err := store.CreateDeleteMarker(ctx, "bucket", "logs/b")
+	if err != nil {
+		t.Fatalf("CreateDeleteMarker: %v", err)
+	}
+
+	got, err := store.ListKeyVersionStats(ctx, "bucket", "logs/", "", 1)
+	if err != nil {
+		t.Fatalf("ListKeyVersionStats: %v", err)
+	}
+	if want := []KeyVersionStats{{Key: "logs/a", VersionCount: 2, TotalBytes: 50, LatestVersion: "v3"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("page 1 = %+v, want %+v", got, want)
+	}
+	got, err = store.ListKeyVersionStats(ctx, "bucket", "logs/", got[0].Key, 1)
+	if err != nil {
+		t.Fatalf("ListKeyVersionStats page 2: %v", err)
+	}
+	if want := []KeyVersionStats{{Key: "logs/b", VersionCount: 2, TotalBytes: 5, LatestVersion: marker}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("page 2 = %+v, want %+v", got, want)
+	}
+	if got, err = store.ListKeyVersionStats(ctx, "bucket", "logs/", got[0].Key, 1); err != nil || len(got) != 0 {
+		t.Fatalf("expected last page empty, got %+v (%v)", got, err)
+	}
+	got, err = store.ListKeyVersionStats(ctx, "bucket", "", "", 0)
+	if err != nil {
+		t.Fatalf("ListKeyVersionStats all: %v", err)
+	}
+	var keys []string
+	for _, stats := range got {
+		keys = append(keys, stats.Key)
+	}
+	if strings.Join(keys, ",") != "logs/a,logs/b,logs0,other" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	if _, err := store.ListKeyVersionStats(ctx, "", "", "", 10); err == nil {
+		t.Fatalf("expected error without bucket")
+	}
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	for prefix, want := range map[string]string{
+		"":          "",
+		"logs/":     "logs0",
+		"a\xff":     "b",
+		"\xff\xff":  "",
+		"tenant-1/": "tenant-10",
+	} {
+		if got := prefixUpperBound(prefix); got != want {
+			t.Fatalf("prefixUpperBound(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
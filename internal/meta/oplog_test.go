@@ -2,12 +2,18 @@ package meta
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/clock"
 )
 
 func TestOplogPutDelete(t *testing.T) {
@@ -59,6 +65,53 @@ func TestOplogPutDelete(t *testing.T) {
 	}
 }
 
+func TestCreateDeleteMarkerReplicatesMarkerVersion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	source, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = source.Close() })
+	source.SetSiteID("site-a")
+	target, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = target.Close() })
+	target.SetSiteID("site-b")
+
+	if err := source.RecordPut(ctx, "bucket", "key", "v1", "etag", 123, "", ""); err != nil {
+		t.Fatalf("RecordPut: %v", err)
+	}
+	marker, err := source.CreateDeleteMarker(ctx, "bucket", "key")
+	if err != nil {
+		t.Fatalf("CreateDeleteMarker: %v", err)
+	}
+	if marker == "" || marker == "v1" {
+		t.Fatalf("expected new marker version, got %q", marker)
+	}
+	entries, err := source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if last := entries[len(entries)-1]; last.OpType != "delete" || last.VersionID != marker {
+		t.Fatalf("expected delete entry for %s, got %+v", marker, last)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	for name, store := range map[string]*Store{"source": source, "target": target} {
+		obj, err := store.GetObjectMeta(ctx, "bucket", "key")
+		if err != nil {
+			t.Fatalf("%s GetObjectMeta: %v", name, err)
+		}
+		if obj.VersionID != marker || obj.State != VersionStateDeleteMarker {
+			t.Fatalf("%s current = %s/%s, want marker %s", name, obj.VersionID, obj.State, marker)
+		}
+	}
+}
+
 func TestOplogSinceLimit(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -254,6 +307,49 @@ func TestApplyOplogPutTieBreakSite(t *testing.T) {
 	}
 }
 
+func TestApplyOplogLWWIgnoresVersionIDOrder(t *testing.T) {
+	t.Parallel()
+	putPayload, err := json.Marshal(oplogPutPayload{
+		ETag:         "etag",
+		Size:         1,
+		LastModified: "2025-12-22T12:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	entry := func(site, hlc, versionID string) OplogEntry {
+		return OplogEntry{SiteID: site, HLCTS: hlc, OpType: "put", Bucket: "bucket", Key: "key", VersionID: versionID, Payload: string(putPayload)}
+	}
+	// Version ids sort opposite to HLC/site order, as deterministic
+	// generators may produce.
+	cases := []struct {
+		name    string
+		entries []OplogEntry
+		want    string
+	}{
+		{"newer hlc applied first", []OplogEntry{entry("site-a", "0000000000000000200-0000000001", "a-new"), entry("site-a", "0000000000000000100-0000000001", "z-old")}, "a-new"},
+		{"newer hlc applied last", []OplogEntry{entry("site-a", "0000000000000000100-0000000001", "z-old"), entry("site-a", "0000000000000000200-0000000001", "a-new")}, "a-new"},
+		{"site tie-break", []OplogEntry{entry("site-z", "0000000000000000100-0000000001", "a-site-z"), entry("site-a", "0000000000000000100-0000000001", "z-site-a")}, "a-site-z"},
+	}
+	for _, tc := range cases {
+		store, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		if _, err := store.ApplyOplogEntries(context.Background(), tc.entries); err != nil {
+			t.Fatalf("%s: ApplyOplogEntries: %v", tc.name, err)
+		}
+		metaObj, err := store.GetObjectMeta(context.Background(), "bucket", "key")
+		_ = store.Close()
+		if err != nil {
+			t.Fatalf("%s: GetObjectMeta: %v", tc.name, err)
+		}
+		if metaObj.VersionID != tc.want {
+			t.Fatalf("%s: current=%s want %s", tc.name, metaObj.VersionID, tc.want)
+		}
+	}
+}
+
 func TestApplyOplogDeleteConflicts(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -552,6 +648,163 @@ func TestApplyOplogBucketPolicy(t *testing.T) {
 	}
 }
 
+func TestApplyOplogBucketNotification(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	config := `{"webhooks":[{"id":"hook","url":"https://hooks.example.com/","events":["s3:ObjectCreated:*"]}]}`
+	payload, err := json.Marshal(oplogBucketNotificationPayload{
+		Bucket:    "demo",
+		Config:    config,
+		UpdatedAt: "2025-12-22T12:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	entries := []OplogEntry{
+		{
+			SiteID:  "site-a",
+			HLCTS:   "0000000000000000200-0000000001",
+			OpType:  "bucket_notification",
+			Bucket:  "demo",
+			Key:     "demo",
+			Payload: string(payload),
+		},
+		{
+			SiteID: "site-a",
+			HLCTS:  "0000000000000000201-0000000001",
+			OpType: "bucket_notification_delete",
+			Bucket: "demo",
+			Key:    "demo",
+		},
+	}
+	if _, err := store.ApplyOplogEntries(context.Background(), entries[:1]); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	got, err := store.GetBucketNotification(context.Background(), "demo")
+	if err != nil {
+		t.Fatalf("GetBucketNotification: %v", err)
+	}
+	if got != config {
+		t.Fatalf("unexpected config: %s", got)
+	}
+	if _, err := store.ApplyOplogEntries(context.Background(), entries[1:]); err != nil {
+		t.Fatalf("ApplyOplogEntries delete: %v", err)
+	}
+	if _, err := store.GetBucketNotification(context.Background(), "demo"); err == nil {
+		t.Fatalf("expected notification to be deleted")
+	}
+}
+
+func TestBucketDefaultContentTypeReplicates(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	source, err := Open(filepath.Join(dir, "source.db"))
+	if err != nil {
+		t.Fatalf("Open source: %v", err)
+	}
+	t.Cleanup(func() { _ = source.Close() })
+	target, err := Open(filepath.Join(dir, "target.db"))
+	if err != nil {
+		t.Fatalf("Open target: %v", err)
+	}
+	t.Cleanup(func() { _ = target.Close() })
+
+	ctx := context.Background()
+	if err := source.SetBucketDefaultContentType(ctx, "missing", "image/png"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for unknown bucket, got %v", err)
+	}
+	if err := source.CreateBucket(ctx, "media"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if err := source.SetBucketDefaultContentType(ctx, "media", "image/png"); err != nil {
+		t.Fatalf("SetBucketDefaultContentType: %v", err)
+	}
+	entries, err := source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	if got, err := target.GetBucketDefaultContentType(ctx, "media"); err != nil || got != "image/png" {
+		t.Fatalf("target default=%q err=%v", got, err)
+	}
+
+	if err := source.SetBucketDefaultContentType(ctx, "media", ""); err != nil {
+		t.Fatalf("clear default: %v", err)
+	}
+	entries, err = source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries[len(entries)-1:]); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	for name, store := range map[string]*Store{"source": source, "target": target} {
+		if got, err := store.GetBucketDefaultContentType(ctx, "media"); err != nil || got != "" {
+			t.Fatalf("%s default after clear=%q err=%v", name, got, err)
+		}
+	}
+}
+
+func TestBucketDeleteProtectionReplicates(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	source, err := Open(filepath.Join(dir, "source.db"))
+	if err != nil {
+		t.Fatalf("Open source: %v", err)
+	}
+	t.Cleanup(func() { _ = source.Close() })
+	target, err := Open(filepath.Join(dir, "target.db"))
+	if err != nil {
+		t.Fatalf("Open target: %v", err)
+	}
+	t.Cleanup(func() { _ = target.Close() })
+
+	ctx := context.Background()
+	if err := source.SetBucketDeleteProtection(ctx, "missing", true); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for unknown bucket, got %v", err)
+	}
+	if err := source.CreateBucket(ctx, "vault"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if err := source.SetBucketDeleteProtection(ctx, "vault", true); err != nil {
+		t.Fatalf("SetBucketDeleteProtection: %v", err)
+	}
+	entries, err := source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	if protected, err := target.BucketDeleteProtected(ctx, "vault"); err != nil || !protected {
+		t.Fatalf("target protected=%v err=%v", protected, err)
+	}
+
+	if err := source.SetBucketDeleteProtection(ctx, "vault", false); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	entries, err = source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries[len(entries)-1:]); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	for name, store := range map[string]*Store{"source": source, "target": target} {
+		if protected, err := store.BucketDeleteProtected(ctx, "vault"); err != nil || protected {
+			t.Fatalf("%s protected after disable=%v err=%v", name, protected, err)
+		}
+	}
+}
+
 func TestApplyOplogIdempotent(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -907,3 +1160,293 @@ func makeDeleteEntry(siteID, hlc, bucket, key, versionID string) OplogEntry {
 		Payload:   string(payload),
 	}
 }
+
+func TestApplyOplogAdvancesLocalHLC(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	store.SetSiteID("site-b")
+
+	// A remote entry stamped far ahead of the local wall clock.
+	remote := OplogEntry{
+		SiteID:    "site-a",
+		HLCTS:     "9000000000000000000-0000000003",
+		OpType:    "put",
+		Bucket:    "bucket",
+		Key:       "remote",
+		VersionID: "v-remote",
+	}
+	if _, err := store.ApplyOplogEntries(context.Background(), []OplogEntry{remote}); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	if err := store.RecordPut(context.Background(), "bucket", "local", "v-local", "etag", 1, "", ""); err != nil {
+		t.Fatalf("RecordPut: %v", err)
+	}
+	entries, err := store.ListOplog(context.Background())
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	var local string
+	for _, entry := range entries {
+		if entry.VersionID == "v-local" {
+			local = entry.HLCTS
+		}
+	}
+	if local <= remote.HLCTS {
+		t.Fatalf("expected local hlc %q after remote %q", local, remote.HLCTS)
+	}
+}
+
+func TestVersionExpiryReplicatesInPutPayload(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	source, err := Open(filepath.Join(dir, "source.db"))
+	if err != nil {
+		t.Fatalf("Open source: %v", err)
+	}
+	t.Cleanup(func() { _ = source.Close() })
+	target, err := Open(filepath.Join(dir, "target.db"))
+	if err != nil {
+		t.Fatalf("Open target: %v", err)
+	}
+	t.Cleanup(func() { _ = target.Close() })
+	source.SetSiteID("site-a")
+
+	ctx := context.Background()
+	expiresAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	if err := source.WithTx(func(tx *sql.Tx) error {
+		if err := source.RecordPutTx(tx, "bucket", "key", "v1", "etag", 5, "", ""); err != nil {
+			return err
+		}
+		return source.SetVersionExpiryTx(tx, "v1", expiresAt)
+	}); err != nil {
+		t.Fatalf("put with expiry: %v", err)
+	}
+	entries, err := source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if len(entries) != 1 || !strings.Contains(entries[0].Payload, `"expires_at":"2026-03-04T05:06:07Z"`) {
+		t.Fatalf("expected expiry in put payload, got %+v", entries)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	for name, store := range map[string]*Store{"source": source, "target": target} {
+		objMeta, err := store.GetObjectMeta(ctx, "bucket", "key")
+		if err != nil {
+			t.Fatalf("%s GetObjectMeta: %v", name, err)
+		}
+		if objMeta.Expired(expiresAt.Add(-time.Second)) || !objMeta.Expired(expiresAt) {
+			t.Fatalf("%s: unexpected expiry %q", name, objMeta.ExpiresAt)
+		}
+		before, err := store.ListExpiredVersions(ctx, expiresAt.Add(-time.Second), 10)
+		if err != nil {
+			t.Fatalf("%s ListExpiredVersions: %v", name, err)
+		}
+		after, err := store.ListExpiredVersions(ctx, expiresAt, 10)
+		if err != nil {
+			t.Fatalf("%s ListExpiredVersions: %v", name, err)
+		}
+		if len(before) != 0 || len(after) != 1 || after[0].VersionID != "v1" {
+			t.Fatalf("%s: unexpected expired versions before=%+v after=%+v", name, before, after)
+		}
+	}
+}
+
+func TestVersionStorageClassReplicatesInPutPayload(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	source, err := Open(filepath.Join(dir, "source.db"))
+	if err != nil {
+		t.Fatalf("Open source: %v", err)
+	}
+	t.Cleanup(func() { _ = source.Close() })
+	target, err := Open(filepath.Join(dir, "target.db"))
+	if err != nil {
+		t.Fatalf("Open target: %v", err)
+	}
+	t.Cleanup(func() { _ = target.Close() })
+	source.SetSiteID("site-a")
+
+	ctx := context.Background()
+	if err := source.WithTx(func(tx *sql.Tx) error {
+		if err := source.RecordPutTx(tx, "bucket", "cold", "v1", "etag", 5, "", ""); err != nil {
+			return err
+		}
+		return source.SetVersionStorageClassTx(tx, "v1", "GLACIER")
+	}); err != nil {
+		t.Fatalf("put with storage class: %v", err)
+	}
+	if err := source.RecordPut(ctx, "bucket", "plain", "v2", "etag", 5, "", ""); err != nil {
+		t.Fatalf("RecordPut: %v", err)
+	}
+	entries, err := source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if len(entries) != 2 || !strings.Contains(entries[0].Payload, `"storage_class":"GLACIER"`) || strings.Contains(entries[1].Payload, "storage_class") {
+		t.Fatalf("unexpected put payloads: %+v", entries)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	for name, store := range map[string]*Store{"source": source, "target": target} {
+		for key, want := range map[string]string{"cold": "GLACIER", "plain": StorageClassStandard} {
+			objMeta, err := store.GetObjectMeta(ctx, "bucket", key)
+			if err != nil {
+				t.Fatalf("%s GetObjectMeta %s: %v", name, key, err)
+			}
+			if objMeta.StorageClass != want {
+				t.Fatalf("%s %s: storage class %q, want %q", name, key, objMeta.StorageClass, want)
+			}
+		}
+	}
+}
+
+func TestVersionMetadataReplicatesInPutPayload(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	source, err := Open(filepath.Join(dir, "source.db"))
+	if err != nil {
+		t.Fatalf("Open source: %v", err)
+	}
+	t.Cleanup(func() { _ = source.Close() })
+	target, err := Open(filepath.Join(dir, "target.db"))
+	if err != nil {
+		t.Fatalf("Open target: %v", err)
+	}
+	t.Cleanup(func() { _ = target.Close() })
+	source.SetSiteID("site-a")
+
+	ctx := context.Background()
+	metadata := map[string]string{"owner": "alice", "project": "x"}
+	tags := map[string]string{"env": "prod"}
+	if err := source.WithTx(func(tx *sql.Tx) error {
+		if err := source.RecordPutTx(tx, "bucket", "key", "v1", "etag", 5, "", ""); err != nil {
+			return err
+		}
+		return source.SetVersionMetadataTx(tx, "v1", metadata, tags)
+	}); err != nil {
+		t.Fatalf("put with metadata: %v", err)
+	}
+	entries, err := source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if len(entries) != 1 || !strings.Contains(entries[0].Payload, `"metadata":{"owner":"alice","project":"x"}`) || !strings.Contains(entries[0].Payload, `"tags":{"env":"prod"}`) {
+		t.Fatalf("expected metadata and tags in put payload, got %+v", entries)
+	}
+	// An entry from a site without metadata support applies as before.
+	legacy := entries[0]
+	legacy.VersionID = "v2"
+	legacy.Key = "legacy"
+	legacy.HLCTS = "0000000000000000001-0000000000"
+	legacy.Payload = `{"etag":"etag","size":5}`
+	if _, err := target.ApplyOplogEntries(ctx, append(entries, legacy)); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	for name, store := range map[string]*Store{"source": source, "target": target} {
+		gotMetadata, gotTags, err := store.VersionMetadata(ctx, "v1")
+		if err != nil {
+			t.Fatalf("%s VersionMetadata: %v", name, err)
+		}
+		if !reflect.DeepEqual(gotMetadata, metadata) || !reflect.DeepEqual(gotTags, tags) {
+			t.Fatalf("%s: metadata %v tags %v", name, gotMetadata, gotTags)
+		}
+	}
+	gotMetadata, gotTags, err := target.VersionMetadata(ctx, "v2")
+	if err != nil || gotMetadata != nil || gotTags != nil {
+		t.Fatalf("legacy entry: metadata %v tags %v (%v)", gotMetadata, gotTags, err)
+	}
+	if objMeta, err := target.GetObjectMeta(ctx, "bucket", "legacy"); err != nil || objMeta.VersionID != "v2" {
+		t.Fatalf("legacy entry not applied: %+v (%v)", objMeta, err)
+	}
+}
+
+func TestApplyOplogRejectsMalformedHLC(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	for _, hlc := range []string{"1", "0000000000000000001-1", "000000000000000001a-0000000001"} {
+		_, err := store.ApplyOplogEntries(context.Background(), []OplogEntry{{
+			SiteID:    "site-a",
+			HLCTS:     hlc,
+			OpType:    "put",
+			Bucket:    "bucket",
+			Key:       "key",
+			VersionID: "v1",
+		}})
+		if !errors.Is(err, clock.ErrInvalidHLC) {
+			t.Fatalf("hlc %q: expected ErrInvalidHLC, got %v", hlc, err)
+		}
+	}
+	if _, err := store.GetObjectMeta(context.Background(), "bucket", "key"); err == nil {
+		t.Fatalf("malformed entries must not be applied")
+	}
+}
+
+func TestBucketTagsReplicate(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	source, err := Open(filepath.Join(dir, "source.db"))
+	if err != nil {
+		t.Fatalf("Open source: %v", err)
+	}
+	t.Cleanup(func() { _ = source.Close() })
+	target, err := Open(filepath.Join(dir, "target.db"))
+	if err != nil {
+		t.Fatalf("Open target: %v", err)
+	}
+	t.Cleanup(func() { _ = target.Close() })
+
+	ctx := context.Background()
+	if err := source.SetBucketTags(ctx, "missing", []BucketTag{{Key: "team", Value: "core"}}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for unknown bucket, got %v", err)
+	}
+	if err := source.CreateBucket(ctx, "billing"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	if err := source.SetBucketTags(ctx, "billing", []BucketTag{{Key: "team", Value: "core"}, {Key: "cost-center", Value: "42"}}); err != nil {
+		t.Fatalf("SetBucketTags: %v", err)
+	}
+	if err := source.SetBucketTags(ctx, "billing", []BucketTag{{Key: "team", Value: "data"}, {Key: "env", Value: "prod"}}); err != nil {
+		t.Fatalf("SetBucketTags replace: %v", err)
+	}
+	entries, err := source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	want := []BucketTag{{Key: "env", Value: "prod"}, {Key: "team", Value: "data"}}
+	for name, store := range map[string]*Store{"source": source, "target": target} {
+		tags, err := store.GetBucketTags(ctx, "billing")
+		if err != nil || !reflect.DeepEqual(tags, want) {
+			t.Fatalf("%s tags=%+v err=%v", name, tags, err)
+		}
+	}
+
+	if err := source.DeleteBucketTags(ctx, "billing"); err != nil {
+		t.Fatalf("DeleteBucketTags: %v", err)
+	}
+	entries, err = source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if _, err := target.ApplyOplogEntries(ctx, entries[len(entries)-1:]); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	for name, store := range map[string]*Store{"source": source, "target": target} {
+		if tags, err := store.GetBucketTags(ctx, "billing"); err != nil || len(tags) != 0 {
+			t.Fatalf("%s tags after delete=%+v err=%v", name, tags, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PrefixIndexDelimiter is the only delimiter the prefix index tracks: it
+// holds the first-level "/" prefix of every current key (see applyV27).
+const PrefixIndexDelimiter = "/"
+
+// HasPrefixIndex reports whether the database carries the bucket_prefixes
+// table. Databases opened read-only below schema v27 do not, and listings
+// must fall back to scanning keys.
+func (s *Store) HasPrefixIndex() bool {
+	return s != nil && s.prefixIndex
+}
+
+func tableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var name string
+	err := db.QueryRowContext(ctx, "SELECT name FROM sqlite_master WHERE type='table' AND name=?", table).Scan(&name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListFirstLevelPrefixes returns up to limit indexed "/" prefixes of the
+// bucket in [from, before), in key order ("" before means unbounded). Only
+// prefixes still holding a key that is not a delete marker and has not
+// expired at now are returned.
+func (s *Store) ListFirstLevelPrefixes(ctx context.Context, bucket, from, before string, limit int, now time.Time) (out []string, err error) {
+	if !s.HasPrefixIndex() {
+		return nil, errors.New("meta: prefix index not available")
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+	// The live check probes the key range [prefix, prefix with "/" -> "0"),
+	// which holds exactly the keys under the prefix.
+	query := `
+SELECT p.prefix
+FROM bucket_prefixes p
+WHERE p.bucket=? AND p.prefix >= ?`
+	args := []any{bucket, from}
+	if before != "" {
+		// A literal upper bound lets SQLite stop the index range scan there.
+		query += " AND p.prefix < ?"
+		args = append(args, before)
+	}
+	query += `
+AND EXISTS (
+	SELECT 1
+	FROM objects_current o
+	JOIN versions v ON v.version_id = o.version_id
+	WHERE o.bucket=p.bucket AND o.key >= p.prefix AND o.key < substr(p.prefix, 1, length(p.prefix)-1) || '0'
+	AND v.state<>'DELETE_MARKER' AND (v.expires_at='' OR v.expires_at>?)
+)
+ORDER BY p.prefix
+LIMIT ?`
+	rows, err := s.db.QueryContext(ctx, query, append(args, expiryCutoff(now), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var prefix string
+		if err := scan(&prefix); err != nil {
+			return err
+		}
+		out = append(out, prefix)
+		return nil
+	})
+}
+
+// ListObjectsInRange returns current objects (no delete markers) with keys in
+// [from, before), in key order ("" before means unbounded). Bounds compare
+// bytewise, unlike the LIKE prefix match of ListObjects; expiry at now is
+// filtered the same way.
+func (s *Store) ListObjectsInRange(ctx context.Context, bucket, from, before string, limit int, now time.Time) (out []ObjectMeta, err error) {
+	if limit <= 0 {
+		limit = 1000
+	}
+	query := `
+SELECT o.key, v.version_id, v.etag, v.size, v.content_type, v.last_modified_utc, v.state, v.storage_class
+FROM objects_current o
+JOIN versions v ON v.version_id = o.version_id
+WHERE o.bucket=? AND o.key >= ? AND v.state<>'DELETE_MARKER' AND (v.expires_at='' OR v.expires_at>?)`
+	args := []any{bucket, from, expiryCutoff(now)}
+	if before != "" {
+		query += " AND o.key < ?"
+		args = append(args, before)
+	}
+	query += " ORDER BY o.key LIMIT ?"
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var meta ObjectMeta
+		if err := scan(&meta.Key, &meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.StorageClass); err != nil {
+			return err
+		}
+		out = append(out, meta)
+		return nil
+	})
+}
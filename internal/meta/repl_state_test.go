@@ -147,3 +147,57 @@ func newTestStore(t *testing.T) *Store {
 	t.Cleanup(func() { _ = store.Close() })
 	return store
 }
+
+func TestReplValidateCursor(t *testing.T) {
+	t.Parallel()
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	bucket, key, err := store.GetReplValidateCursor(ctx, "http://peer-a:9000")
+	if err != nil {
+		t.Fatalf("GetReplValidateCursor: %v", err)
+	}
+	if bucket != "" || key != "" {
+		t.Fatalf("expected empty cursor, got %q/%q", bucket, key)
+	}
+	if err := store.SetReplValidateCursor(ctx, "http://peer-a:9000", "b", "dir/key\nwith newline"); err != nil {
+		t.Fatalf("SetReplValidateCursor: %v", err)
+	}
+	bucket, key, err = store.GetReplValidateCursor(ctx, "http://peer-a:9000")
+	if err != nil {
+		t.Fatalf("GetReplValidateCursor: %v", err)
+	}
+	if bucket != "b" || key != "dir/key\nwith newline" {
+		t.Fatalf("unexpected cursor %q/%q", bucket, key)
+	}
+}
+
+func TestListCurrentObjectsAfter(t *testing.T) {
+	t.Parallel()
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	for _, obj := range []struct{ bucket, key, version string }{
+		{"a", "k2", "v1"},
+		{"a", "k1", "v2"},
+		{"b", "k1", "v3"},
+	} {
+		if err := store.RecordPut(ctx, obj.bucket, obj.key, obj.version, "etag-"+obj.version, 1, filepath.Join("m", obj.version), ""); err != nil {
+			t.Fatalf("RecordPut: %v", err)
+		}
+	}
+	got, err := store.ListCurrentObjectsAfter(ctx, "", "", 2)
+	if err != nil {
+		t.Fatalf("ListCurrentObjectsAfter: %v", err)
+	}
+	if len(got) != 2 || got[0].Key != "k1" || got[1].Key != "k2" || got[0].Bucket != "a" {
+		t.Fatalf("unexpected first page: %+v", got)
+	}
+	got, err = store.ListCurrentObjectsAfter(ctx, got[1].Bucket, got[1].Key, 2)
+	if err != nil {
+		t.Fatalf("ListCurrentObjectsAfter: %v", err)
+	}
+	if len(got) != 1 || got[0].Bucket != "b" || got[0].VersionID != "v3" || got[0].ETag != "etag-v3" {
+		t.Fatalf("unexpected second page: %+v", got)
+	}
+}
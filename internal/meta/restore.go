@@ -0,0 +1,149 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNotRestorable reports a version that cannot be brought back: it does not
+// exist, is not DELETED, or its data is gone.
+var ErrNotRestorable = errors.New("meta: version not restorable")
+
+// RestoreObjectVersion flips a DELETED version back to ACTIVE and makes it the
+// current version when it is the latest by HLC. The restore takes a new HLC
+// and is recorded in the oplog as "restore", so it replicates.
+func (s *Store) RestoreObjectVersion(ctx context.Context, bucket, key, versionID string) error {
+	if bucket == "" || key == "" || versionID == "" {
+		return errors.New("meta: bucket, key, and version id required")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = s.RestoreObjectVersionTx(ctx, tx, bucket, key, versionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RestoreObjectVersionTx restores a DELETED version within the provided transaction.
+func (s *Store) RestoreObjectVersionTx(ctx context.Context, tx *sql.Tx, bucket, key, versionID string) error {
+	if bucket == "" || key == "" || versionID == "" {
+		return errors.New("meta: bucket, key, and version id required")
+	}
+	if tx == nil {
+		return errors.New("meta: tx required")
+	}
+	var state string
+	var isNull bool
+	err := tx.QueryRowContext(ctx, `
+SELECT state, is_null
+FROM versions
+WHERE bucket=? AND key=? AND version_id=?`, bucket, key, versionID).Scan(&state, &isNull)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: version %s not found", ErrNotRestorable, versionID)
+		}
+		return err
+	}
+	if state != VersionStateDeleted {
+		return fmt.Errorf("%w: version %s is %s, not DELETED", ErrNotRestorable, versionID, state)
+	}
+	if isNull {
+		var other string
+		err := tx.QueryRowContext(ctx, `
+SELECT version_id
+FROM versions
+WHERE bucket=? AND key=? AND is_null=1 AND state<>'DELETED' AND version_id<>?
+LIMIT 1`, bucket, key, versionID).Scan(&other)
+		if err == nil {
+			return fmt.Errorf("%w: null version %s was replaced by %s", ErrNotRestorable, versionID, other)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+	var manifestPath string
+	if err := tx.QueryRowContext(ctx, "SELECT path FROM manifests WHERE version_id=?", versionID).Scan(&manifestPath); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: version %s has no manifest", ErrNotRestorable, versionID)
+		}
+		return err
+	}
+	if _, err := os.Stat(manifestPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: manifest for version %s is gone (garbage collected)", ErrNotRestorable, versionID)
+		}
+		return err
+	}
+	hlcTS, siteID := s.nextHLC()
+	if err := withVersionUsageTx(tx, versionID, func() error {
+		_, err := tx.ExecContext(ctx, "UPDATE versions SET state='ACTIVE', hlc_ts=?, site_id=? WHERE version_id=?", hlcTS, siteID, versionID)
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := repointCurrentTx(tx, bucket, key); err != nil {
+		return err
+	}
+	return s.recordOplogTx(tx, hlcTS, "restore", bucket, key, versionID, "")
+}
+
+// applyRestoreTx applies a replicated restore. A version deleted again after
+// the restore (newer HLC) stays DELETED.
+func applyRestoreTx(tx *sql.Tx, entry OplogEntry) error {
+	if entry.VersionID == "" {
+		return fmt.Errorf("meta: restore entry requires version id")
+	}
+	var state, hlcTS, siteID string
+	err := tx.QueryRow("SELECT state, hlc_ts, site_id FROM versions WHERE version_id=?", entry.VersionID).Scan(&state, &hlcTS, &siteID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if state != VersionStateDeleted || compareHLC(entry.HLCTS, entry.SiteID, hlcTS, siteID) <= 0 {
+		return nil
+	}
+	if err := withVersionUsageTx(tx, entry.VersionID, func() error {
+		_, err := tx.Exec("UPDATE versions SET state='ACTIVE', hlc_ts=?, site_id=? WHERE version_id=?", entry.HLCTS, entry.SiteID, entry.VersionID)
+		return err
+	}); err != nil {
+		return err
+	}
+	return repointCurrentTx(tx, entry.Bucket, entry.Key)
+}
+
+// repointCurrentTx points objects_current at the latest non-DELETED version
+// of a key, or removes the row when none is left.
+func repointCurrentTx(tx *sql.Tx, bucket, key string) error {
+	var latest string
+	err := tx.QueryRow(`
+SELECT version_id
+FROM versions
+WHERE bucket=? AND key=? AND state<>'DELETED'
+ORDER BY hlc_ts DESC, site_id DESC
+LIMIT 1`, bucket, key).Scan(&latest)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = tx.Exec("DELETE FROM objects_current WHERE bucket=? AND key=?", bucket, key)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+INSERT INTO objects_current(bucket, key, version_id)
+VALUES(?, ?, ?)
+ON CONFLICT(bucket, key) DO UPDATE SET version_id=excluded.version_id`,
+		bucket, key, latest)
+	return err
+}
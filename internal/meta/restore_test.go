@@ -0,0 +1,128 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestoreObjectVersion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newTestStore(t)
+	store.SetSiteID("site-a")
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "v1.manifest")
+	if err := os.WriteFile(manifestPath, []byte("manifest"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := store.RecordPut(ctx, "bucket", "key", "v1", "etag", 10, manifestPath, ""); err != nil {
+		t.Fatalf("RecordPut: %v", err)
+	}
+	if _, err := store.DeleteObjectVersion(ctx, "bucket", "key", "v1"); err != nil {
+		t.Fatalf("DeleteObjectVersion: %v", err)
+	}
+	if _, err := store.GetObjectMeta(ctx, "bucket", "key"); err == nil {
+		t.Fatalf("expected no current object after delete")
+	}
+
+	if err := store.RestoreObjectVersion(ctx, "bucket", "key", "v1"); err != nil {
+		t.Fatalf("RestoreObjectVersion: %v", err)
+	}
+	current, err := store.GetObjectMeta(ctx, "bucket", "key")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if current.VersionID != "v1" || current.State != VersionStateActive {
+		t.Fatalf("unexpected current: %+v", current)
+	}
+	usage, err := store.GetBucketUsage(ctx, "bucket")
+	if err != nil {
+		t.Fatalf("GetBucketUsage: %v", err)
+	}
+	if usage.Objects != 1 || usage.Bytes != 10 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	entries, err := store.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	last := entries[len(entries)-1]
+	if last.OpType != "restore" || last.VersionID != "v1" || last.SiteID != "site-a" {
+		t.Fatalf("unexpected restore entry: %+v", last)
+	}
+
+	if err := store.RestoreObjectVersion(ctx, "bucket", "key", "v1"); !errors.Is(err, ErrNotRestorable) {
+		t.Fatalf("expected ErrNotRestorable for active version, got %v", err)
+	}
+	if err := store.RestoreObjectVersion(ctx, "bucket", "key", "missing"); !errors.Is(err, ErrNotRestorable) {
+		t.Fatalf("expected ErrNotRestorable for unknown version, got %v", err)
+	}
+
+	if _, err := store.DeleteObjectVersion(ctx, "bucket", "key", "v1"); err != nil {
+		t.Fatalf("DeleteObjectVersion: %v", err)
+	}
+	if err := os.Remove(manifestPath); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := store.RestoreObjectVersion(ctx, "bucket", "key", "v1"); !errors.Is(err, ErrNotRestorable) {
+		t.Fatalf("expected ErrNotRestorable for missing manifest, got %v", err)
+	}
+}
+
+func TestRestoreObjectVersionKeepsNewerCurrent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := newTestStore(t)
+	manifestPath := filepath.Join(t.TempDir(), "v1.manifest")
+	if err := os.WriteFile(manifestPath, []byte("manifest"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := store.RecordPut(ctx, "bucket", "key", "v1", "etag1", 1, manifestPath, ""); err != nil {
+		t.Fatalf("RecordPut v1: %v", err)
+	}
+	if _, err := store.DeleteObjectVersion(ctx, "bucket", "key", "v1"); err != nil {
+		t.Fatalf("DeleteObjectVersion: %v", err)
+	}
+	if err := store.RestoreObjectVersion(ctx, "bucket", "key", "v1"); err != nil {
+		t.Fatalf("RestoreObjectVersion: %v", err)
+	}
+	if err := store.RecordPut(ctx, "bucket", "key", "v2", "etag2", 2, "", ""); err != nil {
+		t.Fatalf("RecordPut v2: %v", err)
+	}
+
+	// Replaying the oplog elsewhere must converge on the same current version.
+	entries, err := store.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	replica := newTestStore(t)
+	if _, err := replica.ApplyOplogEntries(ctx, entries); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	for _, s := range []*Store{store, replica} {
+		current, err := s.GetObjectMeta(ctx, "bucket", "key")
+		if err != nil {
+			t.Fatalf("GetObjectMeta: %v", err)
+		}
+		if current.VersionID != "v2" {
+			t.Fatalf("expected v2 current, got %s", current.VersionID)
+		}
+	}
+	versions, err := replica.ListObjectVersions(ctx, "bucket", "", "", "", 10, time.Time{})
+	if err != nil {
+		t.Fatalf("ListObjectVersions: %v", err)
+	}
+	restored := false
+	for _, v := range versions {
+		if v.VersionID == "v1" {
+			restored = v.State == VersionStateActive
+		}
+	}
+	if !restored {
+		t.Fatalf("expected replicated v1 restored: %+v", versions)
+	}
+}
@@ -0,0 +1,62 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ScrubState is the progress of the current (or last) scrub pass. A pass can
+// span several runs: each run resumes after Cursor until the pass completes.
+type ScrubState struct {
+	// Cursor is the last manifest verified, relative to the manifests dir
+	// ("" = pass not started).
+	Cursor        string `json:"cursor,omitempty"`
+	StartedAt     string `json:"started_at,omitempty"`
+	UpdatedAt     string `json:"updated_at,omitempty"`
+	CompletedAt   string `json:"completed_at,omitempty"`
+	Manifests     int    `json:"manifests"`
+	BytesScrubbed int64  `json:"bytes_scrubbed"`
+	Errors        int    `json:"errors"`
+}
+
+// GetScrubState returns the persisted scrub progress; ok is false before the
+// first scrub.
+func (s *Store) GetScrubState(ctx context.Context) (ScrubState, bool, error) {
+	if s == nil || s.db == nil {
+		return ScrubState{}, false, errors.New("meta: store not initialized")
+	}
+	var state ScrubState
+	err := s.db.QueryRowContext(ctx, `
+SELECT cursor, started_at, updated_at, COALESCE(completed_at, ''), manifests, bytes_scrubbed, errors
+FROM scrub_state
+WHERE id=1`).Scan(&state.Cursor, &state.StartedAt, &state.UpdatedAt, &state.CompletedAt, &state.Manifests, &state.BytesScrubbed, &state.Errors)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ScrubState{}, false, nil
+		}
+		return ScrubState{}, false, err
+	}
+	return state, true, nil
+}
+
+// SaveScrubState persists scrub progress; UpdatedAt is set to now.
+func (s *Store) SaveScrubState(ctx context.Context, state ScrubState) error {
+	if s == nil || s.db == nil {
+		return errors.New("meta: store not initialized")
+	}
+	updatedAt := s.now().UTC().Format(time.RFC3339Nano)
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO scrub_state(id, cursor, started_at, updated_at, completed_at, manifests, bytes_scrubbed, errors)
+VALUES(1, ?, ?, ?, NULLIF(?, ''), ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+	cursor=excluded.cursor,
+	started_at=excluded.started_at,
+	updated_at=excluded.updated_at,
+	completed_at=excluded.completed_at,
+	manifests=excluded.manifests,
+	bytes_scrubbed=excluded.bytes_scrubbed,
+	errors=excluded.errors`, state.Cursor, state.StartedAt, updatedAt, state.CompletedAt, state.Manifests, state.BytesScrubbed, state.Errors)
+	return err
+}
@@ -0,0 +1,98 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"math/bits"
+)
+
+// SizeHistogramBucket counts ACTIVE versions whose size falls in
+// [MinBytes, MaxBytes]. Buckets are powers of two; empty objects get their own.
+type SizeHistogramBucket struct {
+	MinBytes int64 `json:"min_bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+	Objects  int64 `json:"objects"`
+	Bytes    int64 `json:"bytes"`
+}
+
+// sizeClass maps a size to its histogram bucket: 0 for empty objects and
+// n for sizes in [2^(n-1), 2^n-1].
+func sizeClass(size int64) int {
+	if size <= 0 {
+		return 0
+	}
+	return bits.Len64(uint64(size))
+}
+
+func sizeClassBounds(class int) (int64, int64) {
+	if class <= 0 {
+		return 0, 0
+	}
+	return 1 << (class - 1), 1<<class - 1
+}
+
+// SizeHistogram returns the object size distribution of ACTIVE versions.
+// It reads counters maintained on every write, so it does not scan versions.
+func (s *Store) SizeHistogram(ctx context.Context) (out []SizeHistogramBucket, err error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT size_class, objects, bytes
+FROM size_histogram
+WHERE objects > 0
+ORDER BY size_class`)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var class int
+		var bucket SizeHistogramBucket
+		if err := scan(&class, &bucket.Objects, &bucket.Bytes); err != nil {
+			return err
+		}
+		bucket.MinBytes, bucket.MaxBytes = sizeClassBounds(class)
+		out = append(out, bucket)
+		return nil
+	})
+}
+
+// adjustSizeHistogramTx moves objects versions of the given size in or out of
+// the size histogram.
+func adjustSizeHistogramTx(tx *sql.Tx, size, objects int64) error {
+	if objects == 0 {
+		return nil
+	}
+	_, err := tx.Exec(`
+INSERT INTO size_histogram(size_class, objects, bytes)
+VALUES(?, ?, ?)
+ON CONFLICT(size_class) DO UPDATE SET objects=objects+excluded.objects, bytes=bytes+excluded.bytes`,
+		sizeClass(size), objects, objects*size)
+	return err
+}
+
+// backfillSizeHistogramTx rebuilds the size histogram from ACTIVE versions.
+func backfillSizeHistogramTx(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+SELECT size, COUNT(*)
+FROM versions
+WHERE state='ACTIVE'
+GROUP BY size`)
+	if err != nil {
+		return err
+	}
+	counts := make(map[int64]int64)
+	if err := scanRows(rows, func(scan func(dest ...any) error) error {
+		var size, objects int64
+		if err := scan(&size, &objects); err != nil {
+			return err
+		}
+		counts[size] = objects
+		return nil
+	}); err != nil {
+		return err
+	}
+	for size, objects := range counts {
+		if err := adjustSizeHistogramTx(tx, size, objects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package meta
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSizeHistogramTracksWrites(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	assertHistogram := func(want []SizeHistogramBucket) {
+		t.Helper()
+		got, err := store.SizeHistogram(ctx)
+		if err != nil {
+			t.Fatalf("SizeHistogram: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("histogram=%+v want %+v", got, want)
+		}
+	}
+
+	if err := store.RecordPut(ctx, "bucket", "key", "v1", "etag1", 100, "", ""); err != nil {
+		t.Fatalf("RecordPut v1: %v", err)
+	}
+	if err := store.RecordPut(ctx, "bucket", "key", "v2", "etag2", 50, "", ""); err != nil {
+		t.Fatalf("RecordPut v2: %v", err)
+	}
+	if err := store.RecordPut(ctx, "bucket", "empty", "v3", "etag3", 0, "", ""); err != nil {
+		t.Fatalf("RecordPut v3: %v", err)
+	}
+	assertHistogram([]SizeHistogramBucket{
+		{MinBytes: 0, MaxBytes: 0, Objects: 1, Bytes: 0},
+		{MinBytes: 32, MaxBytes: 63, Objects: 1, Bytes: 50},
+		{MinBytes: 64, MaxBytes: 127, Objects: 1, Bytes: 100},
+	})
+	if _, err := store.DeleteObjectVersion(ctx, "bucket", "key", "v1"); err != nil {
+		t.Fatalf("DeleteObjectVersion: %v", err)
+	}
+	assertHistogram([]SizeHistogramBucket{
+		{MinBytes: 0, MaxBytes: 0, Objects: 1, Bytes: 0},
+		{MinBytes: 32, MaxBytes: 63, Objects: 1, Bytes: 50},
+	})
+}
+
+func TestSizeClassBounds(t *testing.T) {
+	for _, size := range []int64{0, 1, 2, 3, 4, 1023, 1024, 5 << 30, 1<<63 - 1} {
+		minBytes, maxBytes := sizeClassBounds(sizeClass(size))
+		if size < minBytes || size > maxBytes {
+			t.Fatalf("size %d outside [%d, %d]", size, minBytes, maxBytes)
+		}
+	}
+}
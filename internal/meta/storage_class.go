@@ -0,0 +1,37 @@
+package meta
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// StorageClassStandard is the storage class of versions written without
+// x-amz-storage-class. Seglake stores every class alike; the class is only
+// recorded and reported back.
+const StorageClassStandard = "STANDARD"
+
+// SetVersionStorageClassTx records the storage class of a version written in
+// the same transaction and adds it to the version's put oplog entry, so
+// replicas report the same class.
+func (s *Store) SetVersionStorageClassTx(tx *sql.Tx, versionID, class string) error {
+	if tx == nil {
+		return errors.New("meta: tx required")
+	}
+	if versionID == "" || class == "" {
+		return errors.New("meta: version id and storage class required")
+	}
+	if _, err := tx.Exec("UPDATE versions SET storage_class=? WHERE version_id=?", class, versionID); err != nil {
+		return err
+	}
+	return patchPutOplogPayloadTx(tx, versionID, func(payload *oplogPutPayload) {
+		payload.StorageClass = class
+	})
+}
+
+// StorageClassOrStandard reports an unset class as STANDARD.
+func StorageClassOrStandard(class string) string {
+	if class == "" {
+		return StorageClassStandard
+	}
+	return class
+}
@@ -2,14 +2,15 @@ package meta
 
 import (
 	"context"
-	"crypto/rand"
 	"database/sql"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kk-code-lab/seglake/internal/clock"
@@ -18,10 +19,23 @@ import (
 
 // Store wraps the SQLite metadata database.
 type Store struct {
-	db     *sql.DB
-	hlc    *clock.HLC
-	siteID string
-	clock  clock.Clock
+	db         *sql.DB
+	hlc        *clock.HLC
+	siteID     string
+	clock      clock.Clock
+	durability string
+	syncWindow time.Duration
+
+	syncMu    sync.Mutex
+	syncTimer *time.Timer
+	lastSync  time.Time
+	closed    bool
+
+	versionIDs VersionIDGenerator
+	// prefixIndex is set when bucket_prefixes exists (schema v27+).
+	prefixIndex bool
+
+	busyRetryHook func()
 }
 
 const (
@@ -59,14 +73,6 @@ func boolToInt(v bool) int {
 	return 0
 }
 
-func newVersionID() (string, error) {
-	var buf [16]byte
-	if _, err := rand.Read(buf[:]); err != nil {
-		return "", err
-	}
-	return hex.EncodeToString(buf[:]), nil
-}
-
 // APIKey describes stored credentials and policy metadata.
 type APIKey struct {
 	AccessKey     string
@@ -77,6 +83,25 @@ type APIKey struct {
 	LastUsedAt    string
 	Policy        string
 	InflightLimit int64
+	// PreviousSecretKey still validates until PreviousSecretExpiresAt
+	// (RFC 3339) after RotateAPIKeySecret with an overlap.
+	PreviousSecretKey       string
+	PreviousSecretExpiresAt string
+}
+
+// BucketInfo describes a bucket entry.
+type BucketInfo struct {
+	Name      string
+	CreatedAt string
+}
+
+// BucketUsage describes the ACTIVE versions stored in a bucket.
+type BucketUsage struct {
+	Bucket  string `json:"bucket"`
+	Objects int64  `json:"objects"`
+	Bytes   int64  `json:"bytes"`
+	// Tags are the bucket's cost-allocation tags (ListBucketUsage only).
+	Tags []BucketTag `json:"tags,omitempty"`
 }
 
 // OplogEntry describes a single replication log entry.
@@ -97,6 +122,12 @@ type oplogPutPayload struct {
 	Size         int64  `json:"size"`
 	LastModified string `json:"last_modified_utc"`
 	ContentType  string `json:"content_type,omitempty"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+	StorageClass string `json:"storage_class,omitempty"`
+	// Metadata and Tags are absent from entries written before schema v36;
+	// such entries apply without them.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
 }
 
 type oplogDeletePayload struct {
@@ -124,14 +155,23 @@ type oplogBucketPolicyPayload struct {
 	UpdatedAt string `json:"updated_at"`
 }
 
+type oplogBucketNotificationPayload struct {
+	Bucket    string `json:"bucket"`
+	Config    string `json:"config"`
+	UpdatedAt string `json:"updated_at"`
+}
+
 type oplogAPIKeyPayload struct {
 	AccessKey     string `json:"access_key"`
 	SecretKey     string `json:"secret_key,omitempty"`
 	Enabled       bool   `json:"enabled"`
 	Policy        string `json:"policy"`
 	InflightLimit int64  `json:"inflight_limit"`
-	Deleted       bool   `json:"deleted,omitempty"`
-	UpdatedAt     string `json:"updated_at"`
+	// PreviousSecretKey and PreviousSecretExpiresAt carry a rotation overlap.
+	PreviousSecretKey       string `json:"previous_secret_key,omitempty"`
+	PreviousSecretExpiresAt string `json:"previous_secret_expires_at,omitempty"`
+	Deleted                 bool   `json:"deleted,omitempty"`
+	UpdatedAt               string `json:"updated_at"`
 }
 
 type oplogAPIKeyBucketPayload struct {
@@ -143,6 +183,7 @@ type oplogAPIKeyBucketPayload struct {
 
 const metaOplogBucket = "_meta"
 const maintenanceSettingKey = "maintenance_mode"
+const replValidateCursorPrefix = "repl_validate_cursor:"
 
 const (
 	maintenanceStateOff      = "off"
@@ -159,22 +200,45 @@ type MaintenanceState struct {
 
 // Open opens or creates the metadata database at the given path.
 func Open(path string) (*Store, error) {
+	return OpenWithOptions(path, OpenOptions{})
+}
+
+// OpenWithOptions opens or creates the metadata database with the given durability.
+func OpenWithOptions(path string, opts OpenOptions) (*Store, error) {
 	if path == "" {
 		return nil, errors.New("meta: db path required")
 	}
-	db, err := sql.Open("sqlite", path)
+	durability, err := ParseDurability(opts.Durability)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SyncWindow <= 0 {
+		opts.SyncWindow = defaultSyncWindow
+	}
+	dsn := path
+	if !strings.Contains(path, "?") {
+		// Apply synchronous and busy_timeout to every pooled connection, not
+		// just the first.
+		dsn = path + "?_pragma=synchronous(" + synchronousPragma(durability) + ")&_pragma=busy_timeout(5000)"
+	}
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, err
 	}
-	store := &Store{db: db, hlc: clock.New(), siteID: "local", clock: clock.RealClock{}}
+	store := &Store{db: db, hlc: clock.New(), siteID: "local", clock: clock.RealClock{}, durability: durability, syncWindow: opts.SyncWindow}
 	if err := store.applyPragmas(context.Background()); err != nil {
 		_ = db.Close()
-		return nil, err
+		return nil, readOnlyError(path, err)
+	}
+	if err := store.checkWritable(context.Background()); err != nil {
+		_ = db.Close()
+		return nil, readOnlyError(path, err)
 	}
 	if err := store.migrate(context.Background()); err != nil {
 		_ = db.Close()
-		return nil, err
+		return nil, readOnlyError(path, err)
 	}
+	store.prefixIndex = true
 	if err := store.initHLC(context.Background()); err != nil {
 		_ = db.Close()
 		return nil, err
@@ -182,6 +246,72 @@ func Open(path string) (*Store, error) {
 	return store, nil
 }
 
+// ErrReadOnly is returned by Open when meta.db (or its directory) cannot be
+// written, e.g. on a read-only mount. OpenReadOnly can still serve it.
+var ErrReadOnly = errors.New("meta: database is read-only")
+
+// readOnlyError wraps err in ErrReadOnly when SQLite refused a write.
+func readOnlyError(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "readonly database") || strings.Contains(msg, "read-only file system") {
+		return fmt.Errorf("%w: %s: %v", ErrReadOnly, path, err)
+	}
+	return err
+}
+
+// checkWritable issues a write that changes nothing, so a read-only
+// database fails at Open rather than on the first mutation.
+func (s *Store) checkWritable(ctx context.Context) error {
+	var version int64
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, "PRAGMA user_version="+strconv.FormatInt(version, 10))
+	return err
+}
+
+// OpenReadOnly opens an existing metadata database without migrating or
+// writing to it, so it can be read next to a running server. When the WAL
+// index cannot be created (read-only mount) the file is opened as immutable.
+func OpenReadOnly(path string) (*Store, error) {
+	if path == "" {
+		return nil, errors.New("meta: db path required")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, prefixIndex, err := openReadOnlyDB(path, "mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		var immutableErr error
+		db, prefixIndex, immutableErr = openReadOnlyDB(path, "mode=ro&immutable=1")
+		if immutableErr != nil {
+			return nil, err
+		}
+	}
+	return &Store{db: db, hlc: clock.New(), siteID: "local", clock: clock.RealClock{}, durability: DurabilityFull, syncWindow: defaultSyncWindow, prefixIndex: prefixIndex}, nil
+}
+
+func openReadOnlyDB(path, query string) (*sql.DB, bool, error) {
+	dsn := (&url.URL{Scheme: "file", Path: path, RawQuery: query}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := db.PingContext(context.Background()); err != nil {
+		_ = db.Close()
+		return nil, false, err
+	}
+	prefixIndex, err := tableExists(context.Background(), db, "bucket_prefixes")
+	if err != nil {
+		_ = db.Close()
+		return nil, false, err
+	}
+	return db, prefixIndex, nil
+}
+
 func (s *Store) now() time.Time {
 	if s != nil && s.clock != nil {
 		return s.clock.Now()
@@ -197,6 +327,14 @@ func (s *Store) SetSiteID(siteID string) {
 	s.siteID = siteID
 }
 
+// SiteID returns the local site identifier used in oplog entries.
+func (s *Store) SiteID() string {
+	if s == nil || s.siteID == "" {
+		return "local"
+	}
+	return s.siteID
+}
+
 func (s *Store) nextHLC() (string, string) {
 	if s == nil {
 		return "", ""
@@ -271,6 +409,7 @@ func (s *Store) Close() error {
 	if s == nil || s.db == nil {
 		return nil
 	}
+	s.stopSync()
 	return s.db.Close()
 }
 
@@ -285,35 +424,44 @@ func (s *Store) Begin() (*sql.Tx, error) {
 	return s.db.Begin()
 }
 
-// FlushWith executes commits within a transaction and flushes WAL.
+// FlushWith executes commits within a transaction and flushes WAL
+// (deferred up to the sync window in balanced durability). If SQLite stays
+// busy past busy_timeout the transaction is rolled back and retried, so
+// commits may run more than once and must not leak state from a failed run.
 func (s *Store) FlushWith(commits []func(tx *sql.Tx) error) error {
-	tx, err := s.Begin()
-	if err != nil {
-		return err
-	}
-	for _, commit := range commits {
-		if err := commit(tx); err != nil {
-			_ = tx.Rollback()
+	err := s.withBusyRetry(func() error {
+		tx, err := s.Begin()
+		if err != nil {
 			return err
 		}
-	}
-	if err := tx.Commit(); err != nil {
+		for _, commit := range commits {
+			if err := commit(tx); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	if err != nil {
 		return err
 	}
-	return s.Flush()
+	return s.flushAfterCommit()
 }
 
-// WithTx runs fn in a single transaction.
+// WithTx runs fn in a single transaction, retrying it like FlushWith when
+// SQLite stays busy.
 func (s *Store) WithTx(fn func(tx *sql.Tx) error) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	if err := fn(tx); err != nil {
-		_ = tx.Rollback()
-		return err
-	}
-	return tx.Commit()
+	return s.withBusyRetry(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
 }
 
 // Flush forces a WAL checkpoint to durably persist changes.
@@ -376,9 +524,18 @@ func (s *Store) applyPragmas(ctx context.Context) error {
 	if _, err := s.db.ExecContext(ctx, "PRAGMA journal_mode=WAL"); err != nil {
 		return err
 	}
-	if _, err := s.db.ExecContext(ctx, "PRAGMA synchronous=FULL"); err != nil {
+	if _, err := s.db.ExecContext(ctx, "PRAGMA synchronous="+synchronousPragma(s.Durability())); err != nil {
 		return err
 	}
+	if s.Durability() == DurabilityBalanced {
+		var mode string
+		if err := s.db.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode); err != nil {
+			return err
+		}
+		if !strings.EqualFold(mode, "wal") {
+			return fmt.Errorf("meta: balanced durability requires WAL (journal_mode=%s)", mode)
+		}
+	}
 	if _, err := s.db.ExecContext(ctx, "PRAGMA foreign_keys=ON"); err != nil {
 		return err
 	}
@@ -563,6 +720,182 @@ CREATE TABLE IF NOT EXISTS schema_migrations (
 			return err
 		}
 	}
+	if version < 20 {
+		if err = applyV20(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(20, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 21 {
+		if err = applyV21(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(21, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 22 {
+		if err = applyV22(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(22, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 23 {
+		if err = applyV23(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(23, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 24 {
+		if err = applyV24(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(24, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 25 {
+		if err = applyV25(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(25, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 26 {
+		if err = applyV26(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(26, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 27 {
+		if err = applyV27(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(27, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 28 {
+		if err = applyV28(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(28, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 29 {
+		if err = applyV29(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(29, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 30 {
+		if err = applyV30(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(30, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 31 {
+		if err = applyV31(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(31, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 32 {
+		if err = applyV32(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(32, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 33 {
+		if err = applyV33(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(33, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 34 {
+		if err = applyV34(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(34, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 35 {
+		if err = applyV35(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(35, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 36 {
+		if err = applyV36(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(36, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 37 {
+		if err = applyV37(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(37, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 38 {
+		if err = applyV38(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(38, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 39 {
+		if err = applyV39(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(39, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 40 {
+		if err = applyV40(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(40, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	if version < 41 {
+		if err = applyV41(ctx, tx); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations(version, applied_at) VALUES(41, ?)", s.now().UTC().Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
 	return tx.Commit()
 }
 
@@ -1056,6 +1389,309 @@ func applyV19(ctx context.Context, tx *sql.Tx) error {
 	return nil
 }
 
+func applyV20(ctx context.Context, tx *sql.Tx) error {
+	hasOwner, err := columnExists(ctx, tx, "buckets", "owner")
+	if err != nil {
+		return err
+	}
+	if !hasOwner {
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE buckets ADD COLUMN owner TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyV21(ctx context.Context, tx *sql.Tx) error {
+	ddl := []string{
+		`CREATE TABLE IF NOT EXISTS bucket_usage (
+			bucket TEXT PRIMARY KEY,
+			objects INTEGER NOT NULL DEFAULT 0,
+			bytes INTEGER NOT NULL DEFAULT 0
+		)`,
+		`INSERT OR REPLACE INTO bucket_usage(bucket, objects, bytes)
+		SELECT bucket, COUNT(*), COALESCE(SUM(size),0)
+		FROM versions
+		WHERE state='ACTIVE'
+		GROUP BY bucket`,
+	}
+	for _, stmt := range ddl {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyV22(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS bucket_lifecycle (
+			bucket TEXT PRIMARY KEY,
+			abort_mpu_days INTEGER NOT NULL,
+			updated_at TEXT NOT NULL
+		)`)
+	return err
+}
+
+func applyV23(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS size_histogram (
+			size_class INTEGER PRIMARY KEY,
+			objects INTEGER NOT NULL DEFAULT 0,
+			bytes INTEGER NOT NULL DEFAULT 0
+		)`); err != nil {
+		return err
+	}
+	return backfillSizeHistogramTx(ctx, tx)
+}
+
+func applyV24(ctx context.Context, tx *sql.Tx) error {
+	for _, column := range []string{"checksum_algorithm", "checksum_value"} {
+		exists, err := columnExists(ctx, tx, "versions", column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE versions ADD COLUMN "+column+" TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyV25(ctx context.Context, tx *sql.Tx) error {
+	ddl := []string{
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+			bucket TEXT NOT NULL,
+			key TEXT NOT NULL,
+			idempotency_key TEXT NOT NULL,
+			version_id TEXT NOT NULL,
+			etag TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY(bucket, key, idempotency_key)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idempotency_keys_created_idx ON idempotency_keys(created_at)`,
+	}
+	for _, stmt := range ddl {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyV26(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS bucket_website (
+			bucket TEXT PRIMARY KEY,
+			redirect_base_url TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)`)
+	return err
+}
+
+// applyV27 adds the first-level prefix index used by "/" delimiter listings.
+// Triggers keep it in step with objects_current on every write path; keys
+// counts current rows (delete markers included) and rows at zero are dropped.
+func applyV27(ctx context.Context, tx *sql.Tx) error {
+	ddl := []string{
+		`CREATE TABLE IF NOT EXISTS bucket_prefixes (
+			bucket TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			keys INTEGER NOT NULL,
+			PRIMARY KEY(bucket, prefix)
+		) WITHOUT ROWID`,
+		`INSERT OR IGNORE INTO bucket_prefixes(bucket, prefix, keys)
+			SELECT bucket, substr(key, 1, instr(key, '/')), COUNT(*)
+			FROM objects_current
+			WHERE instr(key, '/') > 0
+			GROUP BY bucket, substr(key, 1, instr(key, '/'))`,
+		`CREATE TRIGGER IF NOT EXISTS objects_current_prefix_insert
+			AFTER INSERT ON objects_current
+			WHEN instr(NEW.key, '/') > 0
+			BEGIN
+				INSERT INTO bucket_prefixes(bucket, prefix, keys)
+				VALUES(NEW.bucket, substr(NEW.key, 1, instr(NEW.key, '/')), 1)
+				ON CONFLICT(bucket, prefix) DO UPDATE SET keys=keys+1;
+			END`,
+		`CREATE TRIGGER IF NOT EXISTS objects_current_prefix_delete
+			AFTER DELETE ON objects_current
+			WHEN instr(OLD.key, '/') > 0
+			BEGIN
+				UPDATE bucket_prefixes SET keys=keys-1
+				WHERE bucket=OLD.bucket AND prefix=substr(OLD.key, 1, instr(OLD.key, '/'));
+				DELETE FROM bucket_prefixes
+				WHERE bucket=OLD.bucket AND prefix=substr(OLD.key, 1, instr(OLD.key, '/')) AND keys<=0;
+			END`,
+	}
+	for _, stmt := range ddl {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyV28 indexes DAMAGED versions so ListDamagedVersions pages without
+// scanning every version.
+func applyV28(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS versions_damaged_idx ON versions(bucket, key, version_id) WHERE state='DAMAGED'`)
+	return err
+}
+
+// applyV29 adds the per-version expiry set by x-seglake-ttl and indexes the
+// versions that carry one.
+func applyV29(ctx context.Context, tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE versions ADD COLUMN expires_at TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS versions_expires_idx ON versions(expires_at) WHERE expires_at<>''`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyV30 indexes oplog entries by version for per-version lookups.
+func applyV30(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS oplog_version_idx ON oplog(version_id, op_type)`)
+	return err
+}
+
+// applyV31 adds per-bucket notification configurations (JSON, owned by the
+// s3 package).
+func applyV31(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS bucket_notifications (
+			bucket TEXT PRIMARY KEY,
+			config TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)`)
+	return err
+}
+
+// applyV32 adds the buckets with detailed request logging enabled.
+func applyV32(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS bucket_request_logging (
+			bucket TEXT PRIMARY KEY,
+			updated_at TEXT NOT NULL
+		)`)
+	return err
+}
+
+// applyV33 records the version and ETag a multipart upload completed to, so
+// a retried CompleteMultipartUpload can return the original result.
+func applyV33(ctx context.Context, tx *sql.Tx) error {
+	ddl := []string{
+		`ALTER TABLE multipart_uploads ADD COLUMN version_id TEXT`,
+		`ALTER TABLE multipart_uploads ADD COLUMN etag TEXT`,
+	}
+	for _, stmt := range ddl {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyV34 records the storage class of versions and multipart uploads
+// (x-amz-storage-class), reported back on reads and listings.
+func applyV34(ctx context.Context, tx *sql.Tx) error {
+	ddl := []string{
+		`ALTER TABLE versions ADD COLUMN storage_class TEXT NOT NULL DEFAULT 'STANDARD'`,
+		`ALTER TABLE multipart_uploads ADD COLUMN storage_class TEXT NOT NULL DEFAULT 'STANDARD'`,
+	}
+	for _, stmt := range ddl {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyV35 adds the resumable scrub cursor and progress (single row).
+func applyV35(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS scrub_state (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			cursor TEXT NOT NULL,
+			started_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			completed_at TEXT,
+			manifests INTEGER NOT NULL DEFAULT 0,
+			bytes_scrubbed INTEGER NOT NULL DEFAULT 0,
+			errors INTEGER NOT NULL DEFAULT 0
+		)`)
+	return err
+}
+
+// applyV36 adds per-version user metadata and tags (JSON objects, NULL when
+// none), replicated in the put oplog payload.
+func applyV36(ctx context.Context, tx *sql.Tx) error {
+	ddl := []string{
+		`ALTER TABLE versions ADD COLUMN user_metadata TEXT`,
+		`ALTER TABLE versions ADD COLUMN tags TEXT`,
+	}
+	for _, stmt := range ddl {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyV37 keeps the previous secret of a rotated API key and when it stops
+// validating (RotateAPIKeySecret).
+func applyV37(ctx context.Context, tx *sql.Tx) error {
+	ddl := []string{
+		`ALTER TABLE api_keys ADD COLUMN previous_secret TEXT`,
+		`ALTER TABLE api_keys ADD COLUMN previous_secret_expires_at TEXT`,
+	}
+	for _, stmt := range ddl {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyV38 adds the per-bucket default Content-Type for PUTs without one.
+func applyV38(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS bucket_content_types (
+			bucket TEXT PRIMARY KEY,
+			content_type TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)`)
+	return err
+}
+
+// applyV39 indexes multipart uploads by bucket and state for the per-bucket
+// active upload cap.
+func applyV39(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS multipart_uploads_bucket_state_idx ON multipart_uploads(bucket, state)`)
+	return err
+}
+
+// applyV40 adds per-bucket delete protection (a row means enabled).
+func applyV40(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS bucket_delete_protection (
+			bucket TEXT PRIMARY KEY,
+			updated_at TEXT NOT NULL
+		)`)
+	return err
+}
+
+// applyV41 adds bucket tags (?tagging on a bucket) for cost allocation.
+func applyV41(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS bucket_tags (
+			bucket TEXT NOT NULL,
+			tag_key TEXT NOT NULL,
+			tag_value TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			PRIMARY KEY(bucket, tag_key)
+		)`)
+	return err
+}
+
 // UpsertAPIKey inserts or updates an API key entry.
 func (s *Store) UpsertAPIKey(ctx context.Context, accessKey, secretKey, policy string, enabled bool, inflightLimit int64) (err error) {
 	if accessKey == "" || secretKey == "" {
@@ -1087,7 +1723,9 @@ ON CONFLICT(access_key) DO UPDATE SET
 	enabled=excluded.enabled,
 	secret_key=excluded.secret_key,
 	policy=excluded.policy,
-	inflight_limit=excluded.inflight_limit`,
+	inflight_limit=excluded.inflight_limit,
+	previous_secret=NULL,
+	previous_secret_expires_at=NULL`,
 		accessKey, secretKey, enabledInt, now, secretKey, policy, inflightLimit)
 	if err != nil {
 		return err
@@ -1139,12 +1777,14 @@ func (s *Store) UpdateAPIKeyPolicy(ctx context.Context, accessKey, policy string
 	}
 	now := s.now().UTC().Format(time.RFC3339Nano)
 	payload, err := json.Marshal(oplogAPIKeyPayload{
-		AccessKey:     key.AccessKey,
-		SecretKey:     key.SecretKey,
-		Enabled:       key.Enabled,
-		Policy:        policy,
-		InflightLimit: key.InflightLimit,
-		UpdatedAt:     now,
+		AccessKey:               key.AccessKey,
+		SecretKey:               key.SecretKey,
+		Enabled:                 key.Enabled,
+		Policy:                  policy,
+		InflightLimit:           key.InflightLimit,
+		PreviousSecretKey:       key.PreviousSecretKey,
+		PreviousSecretExpiresAt: key.PreviousSecretExpiresAt,
+		UpdatedAt:               now,
 	})
 	if err != nil {
 		return err
@@ -1156,7 +1796,8 @@ func (s *Store) UpdateAPIKeyPolicy(ctx context.Context, accessKey, policy string
 	return tx.Commit()
 }
 
-// AllowBucketForKey adds a bucket allow entry for the given access key.
+// AllowBucketForKey adds a bucket allow entry for the given access key. The
+// entry is a bucket name or a "bucket/prefix/*" pattern.
 func (s *Store) AllowBucketForKey(ctx context.Context, accessKey, bucket string) error {
 	return s.updateAPIKeyBucketAccess(ctx, accessKey, bucket, true)
 }
@@ -1279,7 +1920,7 @@ func (s *Store) GetAPIKey(ctx context.Context, accessKey string) (*APIKey, error
 		return nil, errors.New("meta: access key required")
 	}
 	row := s.db.QueryRowContext(ctx, `
-SELECT access_key, COALESCE(secret_key,''), secret_hash, enabled, created_at, COALESCE(label,''), COALESCE(last_used_at,''), COALESCE(policy,''), COALESCE(inflight_limit,0)
+SELECT access_key, COALESCE(secret_key,''), secret_hash, enabled, created_at, COALESCE(label,''), COALESCE(last_used_at,''), COALESCE(policy,''), COALESCE(inflight_limit,0), COALESCE(previous_secret,''), COALESCE(previous_secret_expires_at,'')
 FROM api_keys
 WHERE access_key=?`, accessKey)
 	return scanAPIKeyRow(row)
@@ -1293,7 +1934,7 @@ func getAPIKeyTx(tx *sql.Tx, accessKey string) (*APIKey, error) {
 		return nil, errors.New("meta: access key required")
 	}
 	row := tx.QueryRow(`
-SELECT access_key, COALESCE(secret_key,''), secret_hash, enabled, created_at, COALESCE(label,''), COALESCE(last_used_at,''), COALESCE(policy,''), COALESCE(inflight_limit,0)
+SELECT access_key, COALESCE(secret_key,''), secret_hash, enabled, created_at, COALESCE(label,''), COALESCE(last_used_at,''), COALESCE(policy,''), COALESCE(inflight_limit,0), COALESCE(previous_secret,''), COALESCE(previous_secret_expires_at,'')
 FROM api_keys
 WHERE access_key=?`, accessKey)
 	return scanAPIKeyRow(row)
@@ -1304,7 +1945,7 @@ func scanAPIKeyRow(row *sql.Row) (*APIKey, error) {
 	var secretKey string
 	var secretHash string
 	var enabledInt int
-	if err := row.Scan(&key.AccessKey, &secretKey, &secretHash, &enabledInt, &key.CreatedAt, &key.Label, &key.LastUsedAt, &key.Policy, &key.InflightLimit); err != nil {
+	if err := row.Scan(&key.AccessKey, &secretKey, &secretHash, &enabledInt, &key.CreatedAt, &key.Label, &key.LastUsedAt, &key.Policy, &key.InflightLimit, &key.PreviousSecretKey, &key.PreviousSecretExpiresAt); err != nil {
 		return nil, err
 	}
 	if secretKey == "" {
@@ -1337,7 +1978,8 @@ func (s *Store) HasAPIKeys(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
-// IsBucketAllowed checks whether the access key can access the bucket.
+// IsBucketAllowed checks whether the access key can access the bucket, either
+// whole or through a "bucket/prefix/*" entry.
 func (s *Store) IsBucketAllowed(ctx context.Context, accessKey, bucket string) (bool, error) {
 	if accessKey == "" || bucket == "" {
 		return true, nil
@@ -1350,7 +1992,7 @@ func (s *Store) IsBucketAllowed(ctx context.Context, accessKey, bucket string) (
 	if count == 0 {
 		return true, nil
 	}
-	row = s.db.QueryRowContext(ctx, "SELECT 1 FROM api_key_bucket_allow WHERE access_key=? AND bucket=? LIMIT 1", accessKey, bucket)
+	row = s.db.QueryRowContext(ctx, "SELECT 1 FROM api_key_bucket_allow WHERE access_key=? AND (bucket=? OR substr(bucket, 1, ?)=?) LIMIT 1", accessKey, bucket, len(bucket)+1, bucket+"/")
 	var allowed int
 	if err := row.Scan(&allowed); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -1414,12 +2056,14 @@ func (s *Store) SetAPIKeyEnabled(ctx context.Context, accessKey string, enabled
 	}
 	now := s.now().UTC().Format(time.RFC3339Nano)
 	payload, err := json.Marshal(oplogAPIKeyPayload{
-		AccessKey:     key.AccessKey,
-		SecretKey:     key.SecretKey,
-		Enabled:       enabled,
-		Policy:        key.Policy,
-		InflightLimit: key.InflightLimit,
-		UpdatedAt:     now,
+		AccessKey:               key.AccessKey,
+		SecretKey:               key.SecretKey,
+		Enabled:                 enabled,
+		Policy:                  key.Policy,
+		InflightLimit:           key.InflightLimit,
+		PreviousSecretKey:       key.PreviousSecretKey,
+		PreviousSecretExpiresAt: key.PreviousSecretExpiresAt,
+		UpdatedAt:               now,
 	})
 	if err != nil {
 		return err
@@ -1470,7 +2114,7 @@ func (s *Store) DeleteAPIKey(ctx context.Context, accessKey string) (err error)
 // ListAPIKeys returns all API keys ordered by access key.
 func (s *Store) ListAPIKeys(ctx context.Context) (out []APIKey, err error) {
 	rows, err := s.db.QueryContext(ctx, `
-SELECT access_key, COALESCE(secret_key,''), secret_hash, enabled, created_at, COALESCE(label,''), COALESCE(last_used_at,''), COALESCE(policy,''), COALESCE(inflight_limit,0)
+SELECT access_key, COALESCE(secret_key,''), secret_hash, enabled, created_at, COALESCE(label,''), COALESCE(last_used_at,''), COALESCE(policy,''), COALESCE(inflight_limit,0), COALESCE(previous_secret,''), COALESCE(previous_secret_expires_at,'')
 FROM api_keys
 ORDER BY access_key`)
 	if err != nil {
@@ -1481,7 +2125,7 @@ ORDER BY access_key`)
 		var secretKey string
 		var secretHash string
 		var enabledInt int
-		if err := scan(&key.AccessKey, &secretKey, &secretHash, &enabledInt, &key.CreatedAt, &key.Label, &key.LastUsedAt, &key.Policy, &key.InflightLimit); err != nil {
+		if err := scan(&key.AccessKey, &secretKey, &secretHash, &enabledInt, &key.CreatedAt, &key.Label, &key.LastUsedAt, &key.Policy, &key.InflightLimit, &key.PreviousSecretKey, &key.PreviousSecretExpiresAt); err != nil {
 			return err
 		}
 		if secretKey == "" {
@@ -1532,65 +2176,291 @@ ON CONFLICT(bucket) DO UPDATE SET
 	return tx.Commit()
 }
 
-// GetBucketPolicy returns a policy string for the bucket.
-func (s *Store) GetBucketPolicy(ctx context.Context, bucket string) (string, error) {
+// GetBucketPolicy returns a policy string for the bucket.
+func (s *Store) GetBucketPolicy(ctx context.Context, bucket string) (string, error) {
+	if bucket == "" {
+		return "", errors.New("meta: bucket required")
+	}
+	row := s.db.QueryRowContext(ctx, "SELECT policy FROM bucket_policies WHERE bucket=?", bucket)
+	var policy string
+	if err := row.Scan(&policy); err != nil {
+		return "", err
+	}
+	return policy, nil
+}
+
+// ListBucketPolicies returns bucket policy JSON by bucket name.
+func (s *Store) ListBucketPolicies(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT bucket, policy
+FROM bucket_policies
+ORDER BY bucket`)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	if err := scanRows(rows, func(scan func(dest ...any) error) error {
+		var bucket string
+		var policy string
+		if err := scan(&bucket, &policy); err != nil {
+			return err
+		}
+		out[bucket] = policy
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteBucketPolicy removes a bucket policy.
+func (s *Store) DeleteBucketPolicy(ctx context.Context, bucket string) (err error) {
+	if bucket == "" {
+		return fmt.Errorf("meta: bucket required")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.ExecContext(ctx, "DELETE FROM bucket_policies WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	hlcTS, _ := s.nextHLC()
+	if err := s.recordOplogTx(tx, hlcTS, "bucket_policy_delete", bucket, bucket, "", ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// BucketLifecycle holds the supported lifecycle rules for a bucket.
+type BucketLifecycle struct {
+	Bucket string
+	// AbortMPUDays aborts multipart uploads this many days after initiation.
+	AbortMPUDays int
+}
+
+// SetBucketLifecycle stores lifecycle rules for an existing bucket.
+func (s *Store) SetBucketLifecycle(ctx context.Context, bucket string, abortMPUDays int) error {
+	if bucket == "" {
+		return errors.New("meta: bucket required")
+	}
+	if abortMPUDays <= 0 {
+		return errors.New("meta: abort days must be > 0")
+	}
+	return s.WithTx(func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT 1 FROM buckets WHERE bucket=?", bucket).Scan(&exists); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO bucket_lifecycle(bucket, abort_mpu_days, updated_at)
+VALUES(?, ?, ?)
+ON CONFLICT(bucket) DO UPDATE SET
+	abort_mpu_days=excluded.abort_mpu_days,
+	updated_at=excluded.updated_at`, bucket, abortMPUDays, s.now().UTC().Format(time.RFC3339Nano))
+		return err
+	})
+}
+
+// GetBucketLifecycle returns lifecycle rules for a bucket (sql.ErrNoRows if none).
+func (s *Store) GetBucketLifecycle(ctx context.Context, bucket string) (*BucketLifecycle, error) {
+	if bucket == "" {
+		return nil, errors.New("meta: bucket required")
+	}
+	out := &BucketLifecycle{Bucket: bucket}
+	if err := s.db.QueryRowContext(ctx, "SELECT abort_mpu_days FROM bucket_lifecycle WHERE bucket=?", bucket).Scan(&out.AbortMPUDays); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListBucketLifecycles returns lifecycle rules for all buckets.
+func (s *Store) ListBucketLifecycles(ctx context.Context) ([]BucketLifecycle, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT bucket, abort_mpu_days
+FROM bucket_lifecycle
+ORDER BY bucket`)
+	if err != nil {
+		return nil, err
+	}
+	var out []BucketLifecycle
+	if err := scanRows(rows, func(scan func(dest ...any) error) error {
+		var rule BucketLifecycle
+		if err := scan(&rule.Bucket, &rule.AbortMPUDays); err != nil {
+			return err
+		}
+		out = append(out, rule)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteBucketLifecycle removes lifecycle rules for a bucket.
+func (s *Store) DeleteBucketLifecycle(ctx context.Context, bucket string) error {
+	if bucket == "" {
+		return errors.New("meta: bucket required")
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM bucket_lifecycle WHERE bucket=?", bucket)
+	return err
+}
+
+// BucketWebsite holds the redirect configuration for a bucket.
+type BucketWebsite struct {
+	Bucket string
+	// RedirectBaseURL receives anonymous GETs as RedirectBaseURL + "/" + key.
+	RedirectBaseURL string
+}
+
+// SetBucketWebsite stores the redirect base URL for an existing bucket.
+func (s *Store) SetBucketWebsite(ctx context.Context, bucket, redirectBaseURL string) error {
+	if bucket == "" {
+		return errors.New("meta: bucket required")
+	}
+	if redirectBaseURL == "" {
+		return errors.New("meta: redirect base url required")
+	}
+	return s.WithTx(func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT 1 FROM buckets WHERE bucket=?", bucket).Scan(&exists); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO bucket_website(bucket, redirect_base_url, updated_at)
+VALUES(?, ?, ?)
+ON CONFLICT(bucket) DO UPDATE SET
+	redirect_base_url=excluded.redirect_base_url,
+	updated_at=excluded.updated_at`, bucket, redirectBaseURL, s.now().UTC().Format(time.RFC3339Nano))
+		return err
+	})
+}
+
+// GetBucketWebsite returns the redirect configuration for a bucket (sql.ErrNoRows if none).
+func (s *Store) GetBucketWebsite(ctx context.Context, bucket string) (*BucketWebsite, error) {
+	if bucket == "" {
+		return nil, errors.New("meta: bucket required")
+	}
+	out := &BucketWebsite{Bucket: bucket}
+	if err := s.db.QueryRowContext(ctx, "SELECT redirect_base_url FROM bucket_website WHERE bucket=?", bucket).Scan(&out.RedirectBaseURL); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteBucketWebsite removes the redirect configuration for a bucket.
+func (s *Store) DeleteBucketWebsite(ctx context.Context, bucket string) error {
+	if bucket == "" {
+		return errors.New("meta: bucket required")
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM bucket_website WHERE bucket=?", bucket)
+	return err
+}
+
+// SetBucketNotification stores the notification configuration of an
+// existing bucket and records it in the oplog so it replicates.
+func (s *Store) SetBucketNotification(ctx context.Context, bucket, config string) error {
+	if bucket == "" || config == "" {
+		return errors.New("meta: bucket and config required")
+	}
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	return s.WithTx(func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT 1 FROM buckets WHERE bucket=?", bucket).Scan(&exists); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO bucket_notifications(bucket, config, updated_at)
+VALUES(?, ?, ?)
+ON CONFLICT(bucket) DO UPDATE SET
+	config=excluded.config,
+	updated_at=excluded.updated_at`, bucket, config, now); err != nil {
+			return err
+		}
+		payload, err := json.Marshal(oplogBucketNotificationPayload{
+			Bucket:    bucket,
+			Config:    config,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+		hlcTS, _ := s.nextHLC()
+		return s.recordOplogTx(tx, hlcTS, "bucket_notification", bucket, bucket, "", string(payload))
+	})
+}
+
+// GetBucketNotification returns the notification configuration of a bucket (sql.ErrNoRows if none).
+func (s *Store) GetBucketNotification(ctx context.Context, bucket string) (string, error) {
 	if bucket == "" {
 		return "", errors.New("meta: bucket required")
 	}
-	row := s.db.QueryRowContext(ctx, "SELECT policy FROM bucket_policies WHERE bucket=?", bucket)
-	var policy string
-	if err := row.Scan(&policy); err != nil {
+	var config string
+	if err := s.db.QueryRowContext(ctx, "SELECT config FROM bucket_notifications WHERE bucket=?", bucket).Scan(&config); err != nil {
 		return "", err
 	}
-	return policy, nil
+	return config, nil
 }
 
-// ListBucketPolicies returns bucket policy JSON by bucket name.
-func (s *Store) ListBucketPolicies(ctx context.Context) (map[string]string, error) {
-	rows, err := s.db.QueryContext(ctx, `
-SELECT bucket, policy
-FROM bucket_policies
-ORDER BY bucket`)
-	if err != nil {
-		return nil, err
+// DeleteBucketNotification removes the notification configuration of a
+// bucket and records the removal in the oplog.
+func (s *Store) DeleteBucketNotification(ctx context.Context, bucket string) error {
+	if bucket == "" {
+		return errors.New("meta: bucket required")
 	}
-	out := make(map[string]string)
-	if err := scanRows(rows, func(scan func(dest ...any) error) error {
-		var bucket string
-		var policy string
-		if err := scan(&bucket, &policy); err != nil {
+	return s.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM bucket_notifications WHERE bucket=?", bucket); err != nil {
 			return err
 		}
-		out[bucket] = policy
-		return nil
-	}); err != nil {
-		return nil, err
-	}
-	return out, nil
+		hlcTS, _ := s.nextHLC()
+		return s.recordOplogTx(tx, hlcTS, "bucket_notification_delete", bucket, bucket, "", "")
+	})
 }
 
-// DeleteBucketPolicy removes a bucket policy.
-func (s *Store) DeleteBucketPolicy(ctx context.Context, bucket string) (err error) {
+// SetBucketRequestLogging enables or disables detailed request logging for an
+// existing bucket. The setting is local to this site and not replicated.
+func (s *Store) SetBucketRequestLogging(ctx context.Context, bucket string, enabled bool) error {
 	if bucket == "" {
-		return fmt.Errorf("meta: bucket required")
+		return errors.New("meta: bucket required")
 	}
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
+	if !enabled {
+		_, err := s.db.ExecContext(ctx, "DELETE FROM bucket_request_logging WHERE bucket=?", bucket)
 		return err
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	return s.WithTx(func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT 1 FROM buckets WHERE bucket=?", bucket).Scan(&exists); err != nil {
+			return err
 		}
-	}()
-	if _, err = tx.ExecContext(ctx, "DELETE FROM bucket_policies WHERE bucket=?", bucket); err != nil {
-		return err
-	}
-	hlcTS, _ := s.nextHLC()
-	if err := s.recordOplogTx(tx, hlcTS, "bucket_policy_delete", bucket, bucket, "", ""); err != nil {
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO bucket_request_logging(bucket, updated_at)
+VALUES(?, ?)
+ON CONFLICT(bucket) DO UPDATE SET updated_at=excluded.updated_at`, bucket, now)
 		return err
+	})
+}
+
+// ListRequestLoggingBuckets returns the buckets with detailed request logging
+// enabled, sorted by name.
+func (s *Store) ListRequestLoggingBuckets(ctx context.Context) (out []string, err error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT bucket FROM bucket_request_logging ORDER BY bucket")
+	if err != nil {
+		return nil, err
 	}
-	return tx.Commit()
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var bucket string
+		if err := scan(&bucket); err != nil {
+			return err
+		}
+		out = append(out, bucket)
+		return nil
+	})
 }
 
 // Segment holds segment metadata.
@@ -1631,6 +2501,16 @@ ON CONFLICT(segment_id) DO UPDATE SET
 	return err
 }
 
+// SetSegmentPath updates the recorded path of a segment that was moved, e.g.
+// out of the staging directory. Unknown segments are ignored.
+func (s *Store) SetSegmentPath(ctx context.Context, segmentID, path string) error {
+	if segmentID == "" || path == "" {
+		return fmt.Errorf("meta: segment id and path required")
+	}
+	_, err := s.db.ExecContext(ctx, "UPDATE segments SET path=? WHERE segment_id=?", path, segmentID)
+	return err
+}
+
 // RecordSegmentTx inserts or updates segment metadata within a transaction.
 func (s *Store) RecordSegmentTx(tx *sql.Tx, segmentID, path, state string, size int64, footerChecksum []byte) error {
 	if segmentID == "" || path == "" {
@@ -1716,9 +2596,38 @@ func (s *Store) RecordPutWithHLC(tx *sql.Tx, hlcTS, siteID, bucket, key, version
 	}
 	isNull := versioningState == BucketVersioningSuspended || versioningState == BucketVersioningDisabled
 	if isNull {
+		rows, err := tx.Query(`
+SELECT size
+FROM versions
+WHERE bucket=? AND key=? AND is_null=1 AND state='ACTIVE'`, bucket, key)
+		if err != nil {
+			return err
+		}
+		var replacedSizes []int64
+		if err := scanRows(rows, func(scan func(dest ...any) error) error {
+			var size int64
+			if err := scan(&size); err != nil {
+				return err
+			}
+			replacedSizes = append(replacedSizes, size)
+			return nil
+		}); err != nil {
+			return err
+		}
+		var replacedObjects, replacedBytes int64
+		for _, size := range replacedSizes {
+			replacedObjects++
+			replacedBytes += size
+			if err := adjustSizeHistogramTx(tx, size, -1); err != nil {
+				return err
+			}
+		}
 		if _, err := tx.Exec("UPDATE versions SET state='DELETED' WHERE bucket=? AND key=? AND is_null=1 AND state<>'DELETED'", bucket, key); err != nil {
 			return err
 		}
+		if err := adjustBucketUsageTx(tx, bucket, -replacedObjects, -replacedBytes); err != nil {
+			return err
+		}
 	}
 	if _, err := tx.Exec(`
 INSERT INTO versions(version_id, bucket, key, etag, size, content_type, last_modified_utc, hlc_ts, site_id, is_null, state)
@@ -1726,6 +2635,12 @@ VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'ACTIVE')`,
 		versionID, bucket, key, etag, size, contentType, lastModified, hlcTS, siteID, boolToInt(isNull)); err != nil {
 		return err
 	}
+	if err := adjustBucketUsageTx(tx, bucket, 1, size); err != nil {
+		return err
+	}
+	if err := adjustSizeHistogramTx(tx, size, 1); err != nil {
+		return err
+	}
 	if _, err := tx.Exec(`
 INSERT INTO objects_current(bucket, key, version_id)
 VALUES(?, ?, ?)
@@ -1782,10 +2697,13 @@ func (s *Store) RecordMPUComplete(ctx context.Context, bucket, key, versionID, e
 	if err != nil {
 		return err
 	}
-	if _, err := tx.Exec(`
+	if err := withVersionUsageTx(tx, versionID, func() error {
+		_, err := tx.Exec(`
 UPDATE versions
 SET etag=?, size=?, last_modified_utc=?
-WHERE version_id=?`, etag, size, lastModified, versionID); err != nil {
+WHERE version_id=?`, etag, size, lastModified, versionID)
+		return err
+	}); err != nil {
 		return err
 	}
 	if err := s.recordOplogTx(tx, hlcTS, "mpu_complete", bucket, key, versionID, string(payload)); err != nil {
@@ -1812,10 +2730,13 @@ func (s *Store) RecordMPUCompleteTx(ctx context.Context, tx *sql.Tx, bucket, key
 	if err != nil {
 		return err
 	}
-	if _, err := tx.Exec(`
+	if err := withVersionUsageTx(tx, versionID, func() error {
+		_, err := tx.Exec(`
 UPDATE versions
 SET etag=?, size=?, last_modified_utc=?
-WHERE version_id=?`, etag, size, lastModified, versionID); err != nil {
+WHERE version_id=?`, etag, size, lastModified, versionID)
+		return err
+	}); err != nil {
 		return err
 	}
 	if err := s.recordOplogTx(tx, hlcTS, "mpu_complete", bucket, key, versionID, string(payload)); err != nil {
@@ -1943,9 +2864,6 @@ VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 	if err := s.updateHLCStateTx(tx, entry.HLCTS); err != nil {
 		return false, err
 	}
-	if s.hlc != nil {
-		_ = s.hlc.Update(entry.HLCTS)
-	}
 	return true, nil
 }
 
@@ -2002,6 +2920,16 @@ func (s *Store) ApplyOplogEntries(ctx context.Context, entries []OplogEntry) (in
 			if entry.SiteID == "" || entry.HLCTS == "" || entry.OpType == "" || entry.Bucket == "" || entry.Key == "" {
 				return fmt.Errorf("meta: invalid oplog entry")
 			}
+			// HLCs order versions and oplog pulls as strings, so a
+			// malformed one would corrupt ordering rather than fail.
+			if _, _, err := clock.ParseHLC(entry.HLCTS); err != nil {
+				return fmt.Errorf("meta: invalid oplog entry: %w", err)
+			}
+			// Advance the local clock past every remote entry, including
+			// duplicates, so later local writes sort after applied ones.
+			if s.hlc != nil {
+				_ = s.hlc.Update(entry.HLCTS)
+			}
 			inserted, err := s.insertOplogEntryTx(tx, entry)
 			if err != nil {
 				return err
@@ -2047,8 +2975,9 @@ func (s *Store) ApplyOplogEntries(ctx context.Context, entries []OplogEntry) (in
 				if lastModified == "" {
 					lastModified = s.now().UTC().Format(time.RFC3339Nano)
 				}
-				if entry.OpType == "mpu_complete" {
-					if _, err := tx.Exec(`
+				if err := withVersionUsageTx(tx, entry.VersionID, func() error {
+					if entry.OpType == "mpu_complete" {
+						_, err := tx.Exec(`
 INSERT INTO versions(version_id, bucket, key, etag, size, content_type, last_modified_utc, hlc_ts, site_id, is_null, state)
 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'ACTIVE')
 ON CONFLICT(version_id) DO UPDATE SET
@@ -2059,16 +2988,24 @@ ON CONFLICT(version_id) DO UPDATE SET
 	hlc_ts=excluded.hlc_ts,
 	site_id=excluded.site_id,
 	state='ACTIVE'`,
-						entry.VersionID, entry.Bucket, entry.Key, payload.ETag, payload.Size, payload.ContentType, lastModified, entry.HLCTS, entry.SiteID, boolToInt(isNull)); err != nil {
+							entry.VersionID, entry.Bucket, entry.Key, payload.ETag, payload.Size, payload.ContentType, lastModified, entry.HLCTS, entry.SiteID, boolToInt(isNull))
 						return err
 					}
-				} else {
-					if _, err := tx.Exec(`
-INSERT OR IGNORE INTO versions(version_id, bucket, key, etag, size, content_type, last_modified_utc, hlc_ts, site_id, is_null, state)
-VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'ACTIVE')`,
-						entry.VersionID, entry.Bucket, entry.Key, payload.ETag, payload.Size, payload.ContentType, lastModified, entry.HLCTS, entry.SiteID, boolToInt(isNull)); err != nil {
+					metadataJSON, err := encodeStringMap(payload.Metadata)
+					if err != nil {
 						return err
 					}
+					tagsJSON, err := encodeStringMap(payload.Tags)
+					if err != nil {
+						return err
+					}
+					_, err = tx.Exec(`
+INSERT OR IGNORE INTO versions(version_id, bucket, key, etag, size, content_type, last_modified_utc, hlc_ts, site_id, is_null, state, expires_at, storage_class, user_metadata, tags)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'ACTIVE', ?, ?, ?, ?)`,
+						entry.VersionID, entry.Bucket, entry.Key, payload.ETag, payload.Size, payload.ContentType, lastModified, entry.HLCTS, entry.SiteID, boolToInt(isNull), payload.ExpiresAt, StorageClassOrStandard(payload.StorageClass), metadataJSON, tagsJSON)
+					return err
+				}); err != nil {
+					return err
 				}
 				var currentVersion string
 				var currentHLC string
@@ -2123,7 +3060,8 @@ ON CONFLICT(bucket, key) DO UPDATE SET version_id=excluded.version_id`,
 					lastModified = s.now().UTC().Format(time.RFC3339Nano)
 				}
 				if payload.DeleteMarker {
-					if _, err := tx.Exec(`
+					if err := withVersionUsageTx(tx, entry.VersionID, func() error {
+						_, err := tx.Exec(`
 INSERT INTO versions(version_id, bucket, key, etag, size, content_type, last_modified_utc, hlc_ts, site_id, state)
 VALUES(?, ?, ?, '', 0, '', ?, ?, ?, 'DELETE_MARKER')
 ON CONFLICT(version_id) DO UPDATE SET
@@ -2131,7 +3069,9 @@ ON CONFLICT(version_id) DO UPDATE SET
 	hlc_ts=excluded.hlc_ts,
 	site_id=excluded.site_id,
 	state='DELETE_MARKER'`,
-						entry.VersionID, entry.Bucket, entry.Key, lastModified, entry.HLCTS, entry.SiteID); err != nil {
+							entry.VersionID, entry.Bucket, entry.Key, lastModified, entry.HLCTS, entry.SiteID)
+						return err
+					}); err != nil {
 						return err
 					}
 					var currentVersion string
@@ -2174,13 +3114,19 @@ ON CONFLICT(bucket, key) DO UPDATE SET version_id=excluded.version_id`,
 					}
 					break
 				}
-				res, err := tx.Exec(`
+				var affected int64
+				if err := withVersionUsageTx(tx, entry.VersionID, func() error {
+					res, err := tx.Exec(`
 UPDATE versions SET state='DELETED', hlc_ts=?, site_id=? WHERE version_id=?`,
-					entry.HLCTS, entry.SiteID, entry.VersionID)
-				if err != nil {
+						entry.HLCTS, entry.SiteID, entry.VersionID)
+					if err != nil {
+						return err
+					}
+					affected, _ = res.RowsAffected()
+					return nil
+				}); err != nil {
 					return err
 				}
-				affected, _ := res.RowsAffected()
 				if affected == 0 {
 					if _, err := tx.Exec(`
 INSERT INTO versions(version_id, bucket, key, etag, size, content_type, last_modified_utc, hlc_ts, site_id, state)
@@ -2222,6 +3168,22 @@ LIMIT 1`, entry.Bucket, entry.Key).Scan(&nextVersion)
 						}
 					}
 				}
+			case "restore":
+				if err := applyRestoreTx(tx, entry); err != nil {
+					return err
+				}
+			case "bucket_content_type":
+				if err := applyBucketContentTypeTx(tx, entry); err != nil {
+					return err
+				}
+			case "bucket_delete_protection":
+				if err := applyBucketDeleteProtectionTx(tx, entry); err != nil {
+					return err
+				}
+			case "bucket_tags":
+				if err := applyBucketTagsTx(tx, entry); err != nil {
+					return err
+				}
 			case "bucket_policy":
 				var payload oplogBucketPolicyPayload
 				if entry.Payload == "" {
@@ -2249,6 +3211,33 @@ ON CONFLICT(bucket) DO UPDATE SET policy=excluded.policy, updated_at=excluded.up
 				if err != nil {
 					return err
 				}
+			case "bucket_notification":
+				var payload oplogBucketNotificationPayload
+				if entry.Payload == "" {
+					return fmt.Errorf("meta: bucket_notification payload required")
+				}
+				if err := json.Unmarshal([]byte(entry.Payload), &payload); err != nil {
+					return err
+				}
+				if payload.Bucket == "" {
+					payload.Bucket = entry.Bucket
+				}
+				_, err := tx.Exec(`
+INSERT INTO bucket_notifications(bucket, config, updated_at)
+VALUES(?, ?, ?)
+ON CONFLICT(bucket) DO UPDATE SET config=excluded.config, updated_at=excluded.updated_at`,
+					payload.Bucket, payload.Config, payload.UpdatedAt)
+				if err != nil {
+					return err
+				}
+			case "bucket_notification_delete":
+				if entry.Bucket == "" {
+					return fmt.Errorf("meta: bucket required")
+				}
+				_, err := tx.Exec(`DELETE FROM bucket_notifications WHERE bucket=?`, entry.Bucket)
+				if err != nil {
+					return err
+				}
 			case "api_key":
 				var payload oplogAPIKeyPayload
 				if entry.Payload == "" {
@@ -2274,16 +3263,18 @@ ON CONFLICT(bucket) DO UPDATE SET policy=excluded.policy, updated_at=excluded.up
 					enabledInt = 1
 				}
 				_, err := tx.Exec(`
-INSERT INTO api_keys(access_key, secret_hash, salt, enabled, created_at, label, last_used_at, secret_key, policy, inflight_limit)
-VALUES(?, ?, '', ?, ?, '', '', ?, ?, ?)
+INSERT INTO api_keys(access_key, secret_hash, salt, enabled, created_at, label, last_used_at, secret_key, policy, inflight_limit, previous_secret, previous_secret_expires_at)
+VALUES(?, ?, '', ?, ?, '', '', ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
 ON CONFLICT(access_key) DO UPDATE SET
 	secret_hash=excluded.secret_hash,
 	salt=excluded.salt,
 	enabled=excluded.enabled,
 	secret_key=excluded.secret_key,
 	policy=excluded.policy,
-	inflight_limit=excluded.inflight_limit`,
-					payload.AccessKey, payload.SecretKey, enabledInt, payload.UpdatedAt, payload.SecretKey, payload.Policy, payload.InflightLimit)
+	inflight_limit=excluded.inflight_limit,
+	previous_secret=excluded.previous_secret,
+	previous_secret_expires_at=excluded.previous_secret_expires_at`,
+					payload.AccessKey, payload.SecretKey, enabledInt, payload.UpdatedAt, payload.SecretKey, payload.Policy, payload.InflightLimit, payload.PreviousSecretKey, payload.PreviousSecretExpiresAt)
 				if err != nil {
 					return err
 				}
@@ -2355,8 +3346,10 @@ func markVersionConflictTx(tx *sql.Tx, versionID string) error {
 	if tx == nil || versionID == "" {
 		return nil
 	}
-	_, err := tx.Exec(`UPDATE versions SET state='CONFLICT' WHERE version_id=?`, versionID)
-	return err
+	return withVersionUsageTx(tx, versionID, func() error {
+		_, err := tx.Exec(`UPDATE versions SET state='CONFLICT' WHERE version_id=?`, versionID)
+		return err
+	})
 }
 
 // ListOplogSince returns oplog entries with hlc_ts greater than the provided value.
@@ -2598,14 +3591,96 @@ ON CONFLICT(remote) DO UPDATE SET updated_at=excluded.updated_at, last_push_hlc=
 	return err
 }
 
+type replValidateCursor struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+// GetReplValidateCursor returns the last bucket/key checked by sampled validation against a remote.
+func (s *Store) GetReplValidateCursor(ctx context.Context, remote string) (string, string, error) {
+	if s == nil || s.db == nil {
+		return "", "", errors.New("meta: db not initialized")
+	}
+	if remote == "" {
+		return "", "", errors.New("meta: remote required")
+	}
+	var value string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM settings WHERE name=?", replValidateCursorPrefix+remote).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	var cursor replValidateCursor
+	if err := json.Unmarshal([]byte(value), &cursor); err != nil {
+		return "", "", fmt.Errorf("meta: invalid repl validate cursor: %w", err)
+	}
+	return cursor.Bucket, cursor.Key, nil
+}
+
+// SetReplValidateCursor stores the sampled validation cursor for a remote (empty bucket resets it).
+func (s *Store) SetReplValidateCursor(ctx context.Context, remote, bucket, key string) error {
+	if s == nil || s.db == nil {
+		return errors.New("meta: db not initialized")
+	}
+	if remote == "" {
+		return errors.New("meta: remote required")
+	}
+	value, err := json.Marshal(replValidateCursor{Bucket: bucket, Key: key})
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO settings(name, value, updated_at)
+VALUES(?, ?, ?)
+ON CONFLICT(name) DO UPDATE SET value=excluded.value, updated_at=excluded.updated_at`, replValidateCursorPrefix+remote, string(value), now)
+	return err
+}
+
 // MarkDamaged sets version state to DAMAGED.
 func (s *Store) MarkDamaged(ctx context.Context, versionID string) error {
 	if versionID == "" {
 		return fmt.Errorf("meta: version id required")
 	}
-	_, err := s.db.ExecContext(ctx, `
+	return s.WithTx(func(tx *sql.Tx) error {
+		return withVersionUsageTx(tx, versionID, func() error {
+			_, err := tx.ExecContext(ctx, `
 UPDATE versions SET state='DAMAGED' WHERE version_id=?`, versionID)
-	return err
+			return err
+		})
+	})
+}
+
+// UpdateVersionETag replaces the ETag of a version if it is still oldETag and
+// reports whether the row changed. Only the local versions row is updated.
+func (s *Store) UpdateVersionETag(ctx context.Context, versionID, oldETag, newETag string) (bool, error) {
+	if versionID == "" || newETag == "" {
+		return false, fmt.Errorf("meta: version id and etag required")
+	}
+	res, err := s.db.ExecContext(ctx, "UPDATE versions SET etag=? WHERE version_id=? AND IFNULL(etag, '')=?", newETag, versionID, oldETag)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// HasOplogOp reports whether the oplog holds an entry of opType for a version.
+func (s *Store) HasOplogOp(ctx context.Context, versionID, opType string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, "SELECT 1 FROM oplog WHERE version_id=? AND op_type=? LIMIT 1", versionID, opType).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // CurrentVersion returns the current version id for a key.
@@ -2639,6 +3714,11 @@ type MultipartUpload struct {
 	CreatedAt   string
 	State       string
 	ContentType string
+	// VersionID and ETag are the completed object's, set once the upload is
+	// COMPLETED (empty for uploads completed before schema v33).
+	VersionID    string
+	ETag         string
+	StorageClass string
 }
 
 // MultipartPart holds part metadata.
@@ -2664,7 +3744,8 @@ VALUES(?, ?, ?, ?, 'ACTIVE', ?)`, uploadID, bucket, key, now, contentType)
 }
 
 // CreateMultipartUploadTx creates an upload within the provided transaction.
-func (s *Store) CreateMultipartUploadTx(ctx context.Context, tx *sql.Tx, bucket, key, uploadID, contentType string) error {
+// An empty storageClass means STANDARD.
+func (s *Store) CreateMultipartUploadTx(ctx context.Context, tx *sql.Tx, bucket, key, uploadID, contentType, storageClass string) error {
 	if bucket == "" || key == "" || uploadID == "" {
 		return fmt.Errorf("meta: bucket, key, and upload id required")
 	}
@@ -2673,8 +3754,8 @@ func (s *Store) CreateMultipartUploadTx(ctx context.Context, tx *sql.Tx, bucket,
 	}
 	now := s.now().UTC().Format(time.RFC3339Nano)
 	_, err := tx.ExecContext(ctx, `
-INSERT INTO multipart_uploads(upload_id, bucket, key, created_at, state, content_type)
-VALUES(?, ?, ?, ?, 'ACTIVE', ?)`, uploadID, bucket, key, now, contentType)
+INSERT INTO multipart_uploads(upload_id, bucket, key, created_at, state, content_type, storage_class)
+VALUES(?, ?, ?, ?, 'ACTIVE', ?, ?)`, uploadID, bucket, key, now, contentType, StorageClassOrStandard(storageClass))
 	return err
 }
 
@@ -2689,7 +3770,7 @@ func (s *Store) ListMultipartUploads(ctx context.Context, bucket, prefix, keyMar
 SELECT upload_id, bucket, key, created_at, state, content_type
 FROM multipart_uploads
 WHERE bucket=? AND key LIKE ? ESCAPE '\' AND state='ACTIVE'`,
-		"key", "upload_id", bucket, pattern, keyMarker, uploadIDMarker, limit,
+		"key", "upload_id", []any{bucket, pattern}, keyMarker, uploadIDMarker, limit,
 	)
 	if err != nil {
 		return nil, err
@@ -2707,11 +3788,11 @@ WHERE bucket=? AND key LIKE ? ESCAPE '\' AND state='ACTIVE'`,
 // GetMultipartUpload returns upload metadata.
 func (s *Store) GetMultipartUpload(ctx context.Context, uploadID string) (*MultipartUpload, error) {
 	row := s.db.QueryRowContext(ctx, `
-SELECT upload_id, bucket, key, created_at, state, content_type
+SELECT upload_id, bucket, key, created_at, state, content_type, COALESCE(version_id, ''), COALESCE(etag, ''), storage_class
 FROM multipart_uploads
 WHERE upload_id=?`, uploadID)
 	var up MultipartUpload
-	if err := row.Scan(&up.UploadID, &up.Bucket, &up.Key, &up.CreatedAt, &up.State, &up.ContentType); err != nil {
+	if err := row.Scan(&up.UploadID, &up.Bucket, &up.Key, &up.CreatedAt, &up.State, &up.ContentType, &up.VersionID, &up.ETag, &up.StorageClass); err != nil {
 		return nil, err
 	}
 	return &up, nil
@@ -2757,8 +3838,9 @@ func (s *Store) AbortMultipartUploadTx(ctx context.Context, tx *sql.Tx, uploadID
 	return nil
 }
 
-// CompleteMultipartUpload marks an upload as completed and clears its parts.
-func (s *Store) CompleteMultipartUpload(ctx context.Context, uploadID string) error {
+// CompleteMultipartUpload marks an upload as completed to versionID/etag and
+// clears its parts.
+func (s *Store) CompleteMultipartUpload(ctx context.Context, uploadID, versionID, etag string) error {
 	if uploadID == "" {
 		return fmt.Errorf("meta: upload id required")
 	}
@@ -2772,7 +3854,7 @@ func (s *Store) CompleteMultipartUpload(ctx context.Context, uploadID string) er
 		}
 	}()
 	if _, err = tx.ExecContext(ctx, `
-UPDATE multipart_uploads SET state='COMPLETED' WHERE upload_id=?`, uploadID); err != nil {
+UPDATE multipart_uploads SET state='COMPLETED', version_id=?, etag=? WHERE upload_id=?`, versionID, etag, uploadID); err != nil {
 		return err
 	}
 	if _, err = tx.ExecContext(ctx, "DELETE FROM multipart_parts WHERE upload_id=?", uploadID); err != nil {
@@ -2781,8 +3863,8 @@ UPDATE multipart_uploads SET state='COMPLETED' WHERE upload_id=?`, uploadID); er
 	return tx.Commit()
 }
 
-// CompleteMultipartUploadTx marks an upload as completed and clears its parts within the provided transaction.
-func (s *Store) CompleteMultipartUploadTx(ctx context.Context, tx *sql.Tx, uploadID string) error {
+// CompleteMultipartUploadTx marks an upload as completed to versionID/etag and clears its parts within the provided transaction.
+func (s *Store) CompleteMultipartUploadTx(ctx context.Context, tx *sql.Tx, uploadID, versionID, etag string) error {
 	if uploadID == "" {
 		return fmt.Errorf("meta: upload id required")
 	}
@@ -2790,7 +3872,7 @@ func (s *Store) CompleteMultipartUploadTx(ctx context.Context, tx *sql.Tx, uploa
 		return fmt.Errorf("meta: tx required")
 	}
 	if _, err := tx.ExecContext(ctx, `
-UPDATE multipart_uploads SET state='COMPLETED' WHERE upload_id=?`, uploadID); err != nil {
+UPDATE multipart_uploads SET state='COMPLETED', version_id=?, etag=? WHERE upload_id=?`, versionID, etag, uploadID); err != nil {
 		return err
 	}
 	if _, err := tx.ExecContext(ctx, "DELETE FROM multipart_parts WHERE upload_id=?", uploadID); err != nil {
@@ -2866,6 +3948,68 @@ ORDER BY part_number`, uploadID)
 	return out, nil
 }
 
+// CountMultipartParts returns the number of parts stored for an upload,
+// not counting exceptPart (the part being uploaded or replaced).
+func (s *Store) CountMultipartParts(ctx context.Context, uploadID string, exceptPart int) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM multipart_parts WHERE upload_id=? AND part_number<>?", uploadID, exceptPart).Scan(&count)
+	return count, err
+}
+
+// CountMultipartPartsTx is CountMultipartParts within a transaction.
+func (s *Store) CountMultipartPartsTx(ctx context.Context, tx *sql.Tx, uploadID string, exceptPart int) (int, error) {
+	if tx == nil {
+		return 0, fmt.Errorf("meta: tx required")
+	}
+	var count int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM multipart_parts WHERE upload_id=? AND part_number<>?", uploadID, exceptPart).Scan(&count)
+	return count, err
+}
+
+// CountActiveMultipartUploadsTx counts the ACTIVE multipart uploads of a
+// bucket within a transaction.
+func (s *Store) CountActiveMultipartUploadsTx(ctx context.Context, tx *sql.Tx, bucket string) (int, error) {
+	if tx == nil {
+		return 0, fmt.Errorf("meta: tx required")
+	}
+	var count int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM multipart_uploads WHERE bucket=? AND state='ACTIVE'", bucket).Scan(&count)
+	return count, err
+}
+
+// ListMultipartPartsAfter returns up to limit parts with part numbers above
+// afterPart, ordered by part number.
+func (s *Store) ListMultipartPartsAfter(ctx context.Context, uploadID string, afterPart, limit int) (out []MultipartPart, err error) {
+	if limit <= 0 {
+		return nil, errors.New("meta: limit must be positive")
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT upload_id, part_number, version_id, etag, size, last_modified_utc
+FROM multipart_parts
+WHERE upload_id=? AND part_number>?
+ORDER BY part_number
+LIMIT ?`, uploadID, afterPart, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); err == nil && cerr != nil {
+			err = cerr
+		}
+	}()
+	for rows.Next() {
+		var part MultipartPart
+		if err := rows.Scan(&part.UploadID, &part.PartNumber, &part.VersionID, &part.ETag, &part.Size, &part.LastModified); err != nil {
+			return nil, err
+		}
+		out = append(out, part)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ListMultipartUploadsBefore returns active uploads created before cutoff.
 func (s *Store) ListMultipartUploadsBefore(ctx context.Context, cutoff time.Time) (out []MultipartUpload, err error) {
 	ts := cutoff.UTC().Format(time.RFC3339Nano)
@@ -2980,6 +4124,21 @@ type ObjectMeta struct {
 	LastModified string
 	State        string
 	IsNull       bool
+	// ExpiresAt is the RFC 3339 expiry set by x-seglake-ttl ("" = none).
+	ExpiresAt string
+	// StorageClass is the x-amz-storage-class the version was written with.
+	StorageClass string
+}
+
+// CurrentObject describes the current version of a key across buckets.
+type CurrentObject struct {
+	Bucket       string
+	Key          string
+	VersionID    string
+	ETag         string
+	Size         int64
+	LastModified string
+	State        string
 }
 
 // ConflictMeta describes a conflicting object version.
@@ -2992,21 +4151,112 @@ type ConflictMeta struct {
 	LastModified string
 }
 
+// DamagedVersion describes a version fsck or scrub marked DAMAGED.
+type DamagedVersion struct {
+	Bucket       string
+	Key          string
+	VersionID    string
+	LastModified string
+	ManifestPath string
+}
+
 // GetObjectMeta returns metadata for the current object version.
 func (s *Store) GetObjectMeta(ctx context.Context, bucket, key string) (*ObjectMeta, error) {
 	row := s.db.QueryRowContext(ctx, `
-SELECT v.version_id, v.etag, v.size, v.content_type, v.last_modified_utc, v.state, v.is_null
+SELECT v.version_id, v.etag, v.size, v.content_type, v.last_modified_utc, v.state, v.is_null, expires_at, v.storage_class
 FROM objects_current o
 JOIN versions v ON v.version_id = o.version_id
 WHERE o.bucket=? AND o.key=?`, bucket, key)
 	var meta ObjectMeta
 	meta.Key = key
-	if err := row.Scan(&meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.IsNull); err != nil {
+	if err := row.Scan(&meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.IsNull, &meta.ExpiresAt, &meta.StorageClass); err != nil {
 		return nil, err
 	}
 	return &meta, nil
 }
 
+// GetObjectMetaTx returns the current version metadata within the provided transaction.
+func (s *Store) GetObjectMetaTx(ctx context.Context, tx *sql.Tx, bucket, key string) (*ObjectMeta, error) {
+	if tx == nil {
+		return nil, errors.New("meta: tx required")
+	}
+	row := tx.QueryRowContext(ctx, `
+SELECT v.version_id, v.etag, v.size, v.content_type, v.last_modified_utc, v.state, v.is_null, expires_at, v.storage_class
+FROM objects_current o
+JOIN versions v ON v.version_id = o.version_id
+WHERE o.bucket=? AND o.key=?`, bucket, key)
+	var meta ObjectMeta
+	meta.Key = key
+	if err := row.Scan(&meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.IsNull, &meta.ExpiresAt, &meta.StorageClass); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// ListCurrentObjectsAfter returns current versions ordered by bucket/key, starting after the given position.
+func (s *Store) ListCurrentObjectsAfter(ctx context.Context, afterBucket, afterKey string, limit int) (out []CurrentObject, err error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("meta: db not initialized")
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT o.bucket, o.key, v.version_id, v.etag, v.size, v.last_modified_utc, v.state
+FROM objects_current o
+JOIN versions v ON v.version_id = o.version_id
+WHERE o.bucket > ? OR (o.bucket = ? AND o.key > ?)
+ORDER BY o.bucket, o.key
+LIMIT ?`, afterBucket, afterBucket, afterKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var obj CurrentObject
+		if err := scan(&obj.Bucket, &obj.Key, &obj.VersionID, &obj.ETag, &obj.Size, &obj.LastModified, &obj.State); err != nil {
+			return err
+		}
+		out = append(out, obj)
+		return nil
+	})
+}
+
+// ListDamagedVersions returns DAMAGED versions ordered by bucket, key and
+// version id, resuming after the given position. ManifestPath is empty when
+// the manifest row is gone.
+func (s *Store) ListDamagedVersions(ctx context.Context, afterBucket, afterKey, afterVersion string, limit int) (out []DamagedVersion, err error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("meta: db not initialized")
+	}
+	if limit <= 0 {
+		limit = 1000
+	}
+	query := `
+SELECT v.bucket, v.key, v.version_id, v.last_modified_utc, COALESCE(m.path, '')
+FROM versions v
+LEFT JOIN manifests m ON m.version_id = v.version_id
+WHERE v.state='DAMAGED'`
+	args := make([]any, 0, 8)
+	if afterBucket != "" {
+		query += " AND (v.bucket > ? OR (v.bucket = ? AND (v.key > ? OR (v.key = ? AND v.version_id > ?))))"
+		args = append(args, afterBucket, afterBucket, afterKey, afterKey, afterVersion)
+	}
+	query += " ORDER BY v.bucket, v.key, v.version_id LIMIT ?"
+	args = append(args, limit)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var item DamagedVersion
+		if err := scan(&item.Bucket, &item.Key, &item.VersionID, &item.LastModified, &item.ManifestPath); err != nil {
+			return err
+		}
+		out = append(out, item)
+		return nil
+	})
+}
+
 // ListConflicts returns conflicting object versions with optional filters and pagination.
 func (s *Store) ListConflicts(ctx context.Context, bucket, prefix, afterBucket, afterKey, afterVersion string, limit int) (out []ConflictMeta, err error) {
 	if s == nil || s.db == nil {
@@ -3022,7 +4272,7 @@ func (s *Store) ListConflicts(ctx context.Context, bucket, prefix, afterBucket,
 SELECT key, version_id, etag, size, last_modified_utc
 FROM versions
 WHERE bucket=? AND key LIKE ? ESCAPE '\' AND state='CONFLICT'`,
-			"key", "version_id", bucket, pattern, afterKey, afterVersion, limit,
+			"key", "version_id", []any{bucket, pattern}, afterKey, afterVersion, limit,
 		)
 		if err != nil {
 			return nil, err
@@ -3079,12 +4329,12 @@ func (s *Store) GetObjectVersion(ctx context.Context, bucket, key, versionID str
 		return nil, errors.New("meta: bucket, key, and version id required")
 	}
 	row := s.db.QueryRowContext(ctx, `
-SELECT version_id, etag, size, content_type, last_modified_utc, state, is_null
+SELECT version_id, etag, size, content_type, last_modified_utc, state, is_null, expires_at, storage_class
 FROM versions
 WHERE bucket=? AND key=? AND version_id=?`, bucket, key, versionID)
 	var meta ObjectMeta
 	meta.Key = key
-	if err := row.Scan(&meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.IsNull); err != nil {
+	if err := row.Scan(&meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.IsNull, &meta.ExpiresAt, &meta.StorageClass); err != nil {
 		return nil, err
 	}
 	return &meta, nil
@@ -3096,39 +4346,41 @@ func (s *Store) GetNullObjectVersion(ctx context.Context, bucket, key string) (*
 		return nil, errors.New("meta: bucket and key required")
 	}
 	row := s.db.QueryRowContext(ctx, `
-SELECT version_id, etag, size, content_type, last_modified_utc, state, is_null
+SELECT version_id, etag, size, content_type, last_modified_utc, state, is_null, expires_at, storage_class
 FROM versions
 WHERE bucket=? AND key=? AND is_null=1 AND state<>'DELETED'
 ORDER BY hlc_ts DESC, site_id DESC
 LIMIT 1`, bucket, key)
 	var meta ObjectMeta
 	meta.Key = key
-	if err := row.Scan(&meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.IsNull); err != nil {
+	if err := row.Scan(&meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.IsNull, &meta.ExpiresAt, &meta.StorageClass); err != nil {
 		return nil, err
 	}
 	return &meta, nil
 }
 
-// ListObjects returns current objects for a bucket with optional prefix and continuation key/version.
-func (s *Store) ListObjects(ctx context.Context, bucket, prefix, afterKey, afterVersion string, limit int) (out []ObjectMeta, err error) {
+// ListObjects returns current objects for a bucket with optional prefix and
+// continuation key/version. Objects whose x-seglake-ttl expiry is at or
+// before now are skipped; a zero now lists them too.
+func (s *Store) ListObjects(ctx context.Context, bucket, prefix, afterKey, afterVersion string, limit int, now time.Time) (out []ObjectMeta, err error) {
 	if limit <= 0 {
 		limit = 1000
 	}
 	pattern := escapeLike(prefix) + "%"
 	rows, err := queryWithMarkers(ctx, s.db,
 		`
-SELECT o.key, v.version_id, v.etag, v.size, v.last_modified_utc
+SELECT o.key, v.version_id, v.etag, v.size, v.content_type, v.last_modified_utc, v.state, v.storage_class
 FROM objects_current o
 JOIN versions v ON v.version_id = o.version_id
-WHERE o.bucket=? AND o.key LIKE ? ESCAPE '\' AND v.state<>'DELETE_MARKER'`,
-		"o.key", "v.version_id", bucket, pattern, afterKey, afterVersion, limit,
+WHERE o.bucket=? AND o.key LIKE ? ESCAPE '\' AND v.state<>'DELETE_MARKER' AND (v.expires_at='' OR v.expires_at>?)`,
+		"o.key", "v.version_id", []any{bucket, pattern, expiryCutoff(now)}, afterKey, afterVersion, limit,
 	)
 	if err != nil {
 		return nil, err
 	}
 	return out, scanRows(rows, func(scan func(dest ...any) error) error {
 		var meta ObjectMeta
-		if err := scan(&meta.Key, &meta.VersionID, &meta.ETag, &meta.Size, &meta.LastModified); err != nil {
+		if err := scan(&meta.Key, &meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.StorageClass); err != nil {
 			return err
 		}
 		out = append(out, meta)
@@ -3136,18 +4388,20 @@ WHERE o.bucket=? AND o.key LIKE ? ESCAPE '\' AND v.state<>'DELETE_MARKER'`,
 	})
 }
 
-// ListObjectVersions returns all versions (including delete markers) for a bucket with optional prefix and markers.
-func (s *Store) ListObjectVersions(ctx context.Context, bucket, prefix, keyMarker, versionIDMarker string, limit int) (out []ObjectMeta, err error) {
+// ListObjectVersions returns all versions (including delete markers) for a
+// bucket with optional prefix and markers. Versions expired at now are
+// skipped as in ListObjects; a zero now lists them too.
+func (s *Store) ListObjectVersions(ctx context.Context, bucket, prefix, keyMarker, versionIDMarker string, limit int, now time.Time) (out []ObjectMeta, err error) {
 	if limit <= 0 {
 		limit = 1000
 	}
 	pattern := escapeLike(prefix) + "%"
 	baseQuery := `
-SELECT key, version_id, etag, size, content_type, last_modified_utc, state, is_null, hlc_ts, site_id
+SELECT key, version_id, etag, size, content_type, last_modified_utc, state, is_null, hlc_ts, site_id, storage_class
 FROM versions
-WHERE bucket=? AND key LIKE ? ESCAPE '\' AND state<>'DELETED'`
+WHERE bucket=? AND key LIKE ? ESCAPE '\' AND state<>'DELETED' AND (expires_at='' OR expires_at>?)`
 	orderClause := " ORDER BY key ASC, hlc_ts DESC, site_id DESC, version_id DESC LIMIT ?"
-	args := []any{bucket, pattern}
+	args := []any{bucket, pattern, expiryCutoff(now)}
 	query := baseQuery + orderClause
 
 	if keyMarker != "" && versionIDMarker != "" {
@@ -3178,7 +4432,7 @@ WHERE bucket=? AND key LIKE ? ESCAPE '\' AND state<>'DELETED'`
 		var meta ObjectMeta
 		var hlcTS string
 		var siteID string
-		if err := scan(&meta.Key, &meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.IsNull, &hlcTS, &siteID); err != nil {
+		if err := scan(&meta.Key, &meta.VersionID, &meta.ETag, &meta.Size, &meta.ContentType, &meta.LastModified, &meta.State, &meta.IsNull, &hlcTS, &siteID, &meta.StorageClass); err != nil {
 			return err
 		}
 		out = append(out, meta)
@@ -3217,20 +4471,22 @@ func escapeLike(s string) string {
 	return b.String()
 }
 
-func queryWithMarkers(ctx context.Context, db *sql.DB, baseQuery, primary, secondary string, bucket, pattern, keyMarker, secondaryMarker string, limit int) (*sql.Rows, error) {
+// queryWithMarkers runs baseQuery, whose placeholders args binds, from the
+// key/secondary markers in primary, secondary order.
+func queryWithMarkers(ctx context.Context, db *sql.DB, baseQuery, primary, secondary string, args []any, keyMarker, secondaryMarker string, limit int) (*sql.Rows, error) {
 	if db == nil {
 		return nil, errors.New("meta: db not initialized")
 	}
 	if keyMarker != "" && secondaryMarker != "" {
 		query := baseQuery + " AND (" + primary + " > ? OR (" + primary + " = ? AND " + secondary + " > ?)) ORDER BY " + primary + ", " + secondary + " LIMIT ?"
-		return db.QueryContext(ctx, query, bucket, pattern, keyMarker, keyMarker, secondaryMarker, limit)
+		return db.QueryContext(ctx, query, append(args, keyMarker, keyMarker, secondaryMarker, limit)...)
 	}
 	if keyMarker != "" {
 		query := baseQuery + " AND " + primary + " > ? ORDER BY " + primary + ", " + secondary + " LIMIT ?"
-		return db.QueryContext(ctx, query, bucket, pattern, keyMarker, limit)
+		return db.QueryContext(ctx, query, append(args, keyMarker, limit)...)
 	}
 	query := baseQuery + " ORDER BY " + primary + ", " + secondary + " LIMIT ?"
-	return db.QueryContext(ctx, query, bucket, pattern, limit)
+	return db.QueryContext(ctx, query, append(args, limit)...)
 }
 
 func scanRows(rows *sql.Rows, scanFn func(scan func(dest ...any) error) error) (err error) {
@@ -3307,6 +4563,75 @@ VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 	return err
 }
 
+// OpsRun describes a recorded ops run.
+type OpsRun struct {
+	ID                int64  `json:"id"`
+	Mode              string `json:"mode"`
+	FinishedAt        string `json:"finished_at"`
+	Errors            int    `json:"errors"`
+	Warnings          int    `json:"warnings,omitempty"`
+	Candidates        int    `json:"candidates,omitempty"`
+	CandidateBytes    int64  `json:"candidate_bytes,omitempty"`
+	Deleted           int    `json:"deleted,omitempty"`
+	ReclaimedBytes    int64  `json:"reclaimed_bytes,omitempty"`
+	RewrittenSegments int    `json:"rewritten_segments,omitempty"`
+	RewrittenBytes    int64  `json:"rewritten_bytes,omitempty"`
+	NewSegments       int    `json:"new_segments,omitempty"`
+}
+
+// ListOpsRuns returns the most recent ops runs, newest first.
+func (s *Store) ListOpsRuns(ctx context.Context, limit int) (out []OpsRun, err error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, mode, finished_at, errors, COALESCE(warnings,0), COALESCE(candidates,0), COALESCE(candidate_bytes,0),
+	COALESCE(deleted,0), COALESCE(reclaimed_bytes,0), COALESCE(rewritten_segments,0), COALESCE(rewritten_bytes,0), COALESCE(new_segments,0)
+FROM ops_runs
+ORDER BY id DESC
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var run OpsRun
+		if err := scan(&run.ID, &run.Mode, &run.FinishedAt, &run.Errors, &run.Warnings, &run.Candidates, &run.CandidateBytes,
+			&run.Deleted, &run.ReclaimedBytes, &run.RewrittenSegments, &run.RewrittenBytes, &run.NewSegments); err != nil {
+			return err
+		}
+		out = append(out, run)
+		return nil
+	})
+}
+
+// SchemaVersion returns the highest applied schema migration.
+func (s *Store) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Pragmas returns the effective SQLite settings of a pooled connection.
+func (s *Store) Pragmas(ctx context.Context) (map[string]string, error) {
+	names := []string{"journal_mode", "synchronous", "foreign_keys", "busy_timeout", "page_size", "page_count", "freelist_count", "cache_size", "auto_vacuum", "wal_autocheckpoint"}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+	out := make(map[string]string, len(names))
+	for _, name := range names {
+		var value string
+		if err := conn.QueryRowContext(ctx, "PRAGMA "+name).Scan(&value); err != nil {
+			return nil, err
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
 // ReportOps is a slimmed view of ops.Report for storage.
 type ReportOps struct {
 	FinishedAt        string
@@ -3501,15 +4826,11 @@ func lagSeconds(hlc string) float64 {
 }
 
 func parseHLCPhysical(hlc string) (int64, bool) {
-	parts := strings.SplitN(hlc, "-", 2)
-	if len(parts) == 0 || parts[0] == "" {
-		return 0, false
-	}
-	val, err := strconv.ParseInt(parts[0], 10, 64)
+	physical, _, err := clock.ParseHLC(hlc)
 	if err != nil {
 		return 0, false
 	}
-	return val, true
+	return physical, true
 }
 
 func (s *Store) countOplogSince(ctx context.Context, since string) (int64, error) {
@@ -3648,6 +4969,34 @@ JOIN manifests m ON m.version_id = v.version_id`)
 	return out, nil
 }
 
+// ListBucketManifestPaths returns manifest paths for all versions in bucket.
+func (s *Store) ListBucketManifestPaths(ctx context.Context, bucket string) (out []string, err error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT m.path
+FROM versions v
+JOIN manifests m ON m.version_id = v.version_id
+WHERE v.bucket = ?`, bucket)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := rows.Close(); err == nil && cerr != nil {
+			err = cerr
+		}
+	}()
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		out = append(out, path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ListBuckets returns bucket names in lexical order.
 func (s *Store) ListBuckets(ctx context.Context) (out []string, err error) {
 	rows, err := s.db.QueryContext(ctx, `SELECT bucket FROM buckets ORDER BY bucket`)
@@ -3672,6 +5021,22 @@ func (s *Store) ListBuckets(ctx context.Context) (out []string, err error) {
 	return out, nil
 }
 
+// ListBucketInfos returns buckets with their creation time in lexical order.
+func (s *Store) ListBucketInfos(ctx context.Context) (out []BucketInfo, err error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT bucket, created_at FROM buckets ORDER BY bucket`)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var info BucketInfo
+		if err := scan(&info.Name, &info.CreatedAt); err != nil {
+			return err
+		}
+		out = append(out, info)
+		return nil
+	})
+}
+
 // BucketExists checks whether a bucket exists.
 func (s *Store) BucketExists(ctx context.Context, bucket string) (bool, error) {
 	if bucket == "" {
@@ -3752,6 +5117,46 @@ func (s *Store) CreateBucketWithVersioningTx(ctx context.Context, tx *sql.Tx, bu
 	return err
 }
 
+// CreateBucketOwnedTx inserts a bucket entry recording the creating access key.
+// It reports false when the bucket already exists.
+func (s *Store) CreateBucketOwnedTx(ctx context.Context, tx *sql.Tx, bucket, versioningState, owner string) (bool, error) {
+	if bucket == "" {
+		return false, fmt.Errorf("meta: bucket required")
+	}
+	if tx == nil {
+		return false, fmt.Errorf("meta: tx required")
+	}
+	if versioningState == "" {
+		versioningState = BucketVersioningEnabled
+	}
+	state, ok := normalizeBucketVersioningState(versioningState)
+	if !ok {
+		return false, fmt.Errorf("meta: invalid versioning state")
+	}
+	now := s.now().UTC().Format(time.RFC3339Nano)
+	res, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO buckets(bucket, created_at, versioning_state, owner) VALUES(?, ?, ?, ?)", bucket, now, state, owner)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// GetBucketOwner returns the access key that created the bucket ("" when unknown).
+func (s *Store) GetBucketOwner(ctx context.Context, bucket string) (string, error) {
+	if bucket == "" {
+		return "", errors.New("meta: bucket required")
+	}
+	var owner string
+	if err := s.db.QueryRowContext(ctx, "SELECT owner FROM buckets WHERE bucket=?", bucket).Scan(&owner); err != nil {
+		return "", err
+	}
+	return owner, nil
+}
+
 // GetBucketVersioningState returns the versioning state for a bucket.
 func (s *Store) GetBucketVersioningState(ctx context.Context, bucket string) (string, error) {
 	if bucket == "" {
@@ -3854,6 +5259,27 @@ func (s *Store) DeleteBucket(ctx context.Context, bucket string) error {
 	if bucket == "" {
 		return fmt.Errorf("meta: bucket required")
 	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM bucket_lifecycle WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM bucket_website WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM bucket_notifications WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM bucket_request_logging WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM bucket_content_types WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM bucket_delete_protection WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM bucket_tags WHERE bucket=?", bucket); err != nil {
+		return err
+	}
 	_, err := s.db.ExecContext(ctx, "DELETE FROM buckets WHERE bucket=?", bucket)
 	return err
 }
@@ -3866,12 +5292,88 @@ func (s *Store) DeleteBucketTx(ctx context.Context, tx *sql.Tx, bucket string) e
 	if tx == nil {
 		return fmt.Errorf("meta: tx required")
 	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM bucket_lifecycle WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM bucket_website WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM bucket_notifications WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM bucket_request_logging WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM bucket_content_types WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM bucket_delete_protection WHERE bucket=?", bucket); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM bucket_tags WHERE bucket=?", bucket); err != nil {
+		return err
+	}
 	_, err := tx.ExecContext(ctx, "DELETE FROM buckets WHERE bucket=?", bucket)
 	return err
 }
 
-// DeleteObject creates a delete marker for the key and updates objects_current.
+// BucketPurge reports what PurgeBucketObjects removed.
+type BucketPurge struct {
+	Objects       int64 `json:"objects"`
+	Versions      int64 `json:"versions"`
+	DeleteMarkers int64 `json:"delete_markers"`
+	Bytes         int64 `json:"bytes"`
+}
+
+// PurgeBucketObjects deletes every version and delete marker in the bucket,
+// recording a "delete" oplog entry for each. The purged manifests are no
+// longer live, so gc-run reclaims their chunks. Objects counts distinct keys.
+func (s *Store) PurgeBucketObjects(ctx context.Context, bucket string) (BucketPurge, error) {
+	var out BucketPurge
+	if bucket == "" {
+		return out, errors.New("meta: bucket required")
+	}
+	lastKey := ""
+	for {
+		// Purged versions drop out of the listing, so each pass starts over.
+		versions, err := s.ListObjectVersions(ctx, bucket, "", "", "", 1000, time.Time{})
+		if err != nil {
+			return out, err
+		}
+		if len(versions) == 0 {
+			return out, nil
+		}
+		for _, v := range versions {
+			deleted, err := s.DeleteObjectVersion(ctx, bucket, v.Key, v.VersionID)
+			if err != nil {
+				return out, err
+			}
+			if !deleted {
+				continue
+			}
+			if v.Key != lastKey {
+				out.Objects++
+				lastKey = v.Key
+			}
+			if strings.EqualFold(v.State, VersionStateDeleteMarker) {
+				out.DeleteMarkers++
+				continue
+			}
+			out.Versions++
+			out.Bytes += v.Size
+		}
+	}
+}
+
+// DeleteObject creates a delete marker for the key; see CreateDeleteMarker.
 func (s *Store) DeleteObject(ctx context.Context, bucket, key string) (string, error) {
+	return s.CreateDeleteMarker(ctx, bucket, key)
+}
+
+// CreateDeleteMarker generates a delete-marker version for the key, makes it
+// current, and records a "delete" oplog entry carrying the marker version id.
+// It returns the generated marker version id.
+func (s *Store) CreateDeleteMarker(ctx context.Context, bucket, key string) (string, error) {
 	if bucket == "" || key == "" {
 		return "", errors.New("meta: bucket and key required")
 	}
@@ -3884,7 +5386,7 @@ func (s *Store) DeleteObject(ctx context.Context, bucket, key string) (string, e
 			_ = tx.Rollback()
 		}
 	}()
-	versionID, err := s.DeleteObjectTx(ctx, tx, bucket, key)
+	versionID, err := s.CreateDeleteMarkerTx(ctx, tx, bucket, key)
 	if err != nil {
 		return "", err
 	}
@@ -3953,7 +5455,8 @@ func (s *Store) recordDeleteMarkerTx(ctx context.Context, tx *sql.Tx, bucket, ke
 		lastModified = s.now().UTC().Format(time.RFC3339Nano)
 	}
 	hlcTS, siteID := s.nextHLC()
-	if _, err := tx.ExecContext(ctx, `
+	if err := withVersionUsageTx(tx, versionID, func() error {
+		_, err := tx.ExecContext(ctx, `
 INSERT INTO versions(version_id, bucket, key, etag, size, content_type, last_modified_utc, hlc_ts, site_id, state)
 VALUES(?, ?, ?, '', 0, '', ?, ?, ?, 'DELETE_MARKER')
 ON CONFLICT(version_id) DO UPDATE SET
@@ -3961,7 +5464,9 @@ ON CONFLICT(version_id) DO UPDATE SET
 	hlc_ts=excluded.hlc_ts,
 	site_id=excluded.site_id,
 	state='DELETE_MARKER'`,
-		versionID, bucket, key, lastModified, hlcTS, siteID); err != nil {
+			versionID, bucket, key, lastModified, hlcTS, siteID)
+		return err
+	}); err != nil {
 		return err
 	}
 	if _, err := tx.ExecContext(ctx, `
@@ -3986,15 +5491,21 @@ ON CONFLICT(bucket, key) DO UPDATE SET version_id=excluded.version_id`,
 	return nil
 }
 
-// DeleteObjectTx creates a delete marker for the key within the provided transaction.
+// DeleteObjectTx creates a delete marker for the key; see CreateDeleteMarkerTx.
 func (s *Store) DeleteObjectTx(ctx context.Context, tx *sql.Tx, bucket, key string) (string, error) {
+	return s.CreateDeleteMarkerTx(ctx, tx, bucket, key)
+}
+
+// CreateDeleteMarkerTx creates a delete marker within the provided transaction
+// and returns its generated version id.
+func (s *Store) CreateDeleteMarkerTx(ctx context.Context, tx *sql.Tx, bucket, key string) (string, error) {
 	if bucket == "" || key == "" {
 		return "", errors.New("meta: bucket and key required")
 	}
 	if tx == nil {
 		return "", errors.New("meta: tx required")
 	}
-	versionID, err := newVersionID()
+	versionID, err := s.NextVersionID(bucket, key)
 	if err != nil {
 		return "", err
 	}
@@ -4024,7 +5535,10 @@ func (s *Store) DeleteObjectUnversionedTx(ctx context.Context, tx *sql.Tx, bucke
 	if err != nil {
 		return "", err
 	}
-	if _, err := tx.ExecContext(ctx, "UPDATE versions SET state='DELETED', hlc_ts=?, site_id=? WHERE version_id=?", hlcTS, siteID, versionID); err != nil {
+	if err := withVersionUsageTx(tx, versionID, func() error {
+		_, err := tx.ExecContext(ctx, "UPDATE versions SET state='DELETED', hlc_ts=?, site_id=? WHERE version_id=?", hlcTS, siteID, versionID)
+		return err
+	}); err != nil {
 		return "", err
 	}
 	if _, err := tx.ExecContext(ctx, "DELETE FROM objects_current WHERE bucket=? AND key=?", bucket, key); err != nil {
@@ -4036,6 +5550,21 @@ func (s *Store) DeleteObjectUnversionedTx(ctx context.Context, tx *sql.Tx, bucke
 	return versionID, nil
 }
 
+// MoveObjectTx retires the source key of a move whose destination version was
+// already recorded in tx, so the oplog carries the put at the new key before
+// the delete at the old one. With keepVersions it writes a delete marker and
+// returns its version id; otherwise the current version is deleted outright.
+// The caller verifies in tx that the source has not changed.
+func (s *Store) MoveObjectTx(ctx context.Context, tx *sql.Tx, srcBucket, srcKey string, keepVersions bool) (string, error) {
+	if keepVersions {
+		return s.CreateDeleteMarkerTx(ctx, tx, srcBucket, srcKey)
+	}
+	if _, err := s.DeleteObjectUnversionedTx(ctx, tx, srcBucket, srcKey); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
 // DeleteObjectVersionTx marks a specific version as deleted and updates objects_current if needed within the provided transaction.
 func (s *Store) DeleteObjectVersionTx(ctx context.Context, tx *sql.Tx, bucket, key, versionID string) (bool, error) {
 	if bucket == "" || key == "" || versionID == "" {
@@ -4060,7 +5589,10 @@ WHERE bucket=? AND key=? AND version_id=?`, bucket, key, versionID).Scan(&state)
 	if err != nil {
 		return false, err
 	}
-	if _, err = tx.ExecContext(ctx, "UPDATE versions SET state='DELETED', hlc_ts=?, site_id=? WHERE version_id=?", hlcTS, siteID, versionID); err != nil {
+	if err = withVersionUsageTx(tx, versionID, func() error {
+		_, err := tx.ExecContext(ctx, "UPDATE versions SET state='DELETED', hlc_ts=?, site_id=? WHERE version_id=?", hlcTS, siteID, versionID)
+		return err
+	}); err != nil {
 		return false, err
 	}
 	var currentVersion string
@@ -4096,6 +5628,102 @@ LIMIT 1`, bucket, key).Scan(&nextVersion)
 	return true, nil
 }
 
+// ListBucketUsage returns per-bucket object counts and bytes of ACTIVE
+// versions, with each bucket's tags.
+func (s *Store) ListBucketUsage(ctx context.Context) (out []BucketUsage, err error) {
+	tags, err := s.ListBucketTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT bucket, objects, bytes
+FROM bucket_usage
+ORDER BY bucket`)
+	if err != nil {
+		return nil, err
+	}
+	return out, scanRows(rows, func(scan func(dest ...any) error) error {
+		var usage BucketUsage
+		if err := scan(&usage.Bucket, &usage.Objects, &usage.Bytes); err != nil {
+			return err
+		}
+		usage.Tags = tags[usage.Bucket]
+		out = append(out, usage)
+		return nil
+	})
+}
+
+// GetBucketUsage returns the object count and bytes of ACTIVE versions in a bucket.
+func (s *Store) GetBucketUsage(ctx context.Context, bucket string) (*BucketUsage, error) {
+	if bucket == "" {
+		return nil, errors.New("meta: bucket required")
+	}
+	usage := &BucketUsage{Bucket: bucket}
+	err := s.db.QueryRowContext(ctx, "SELECT objects, bytes FROM bucket_usage WHERE bucket=?", bucket).Scan(&usage.Objects, &usage.Bytes)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// adjustBucketUsageTx applies an increment to the bucket_usage counters.
+func adjustBucketUsageTx(tx *sql.Tx, bucket string, objects, bytes int64) error {
+	if objects == 0 && bytes == 0 {
+		return nil
+	}
+	_, err := tx.Exec(`
+INSERT INTO bucket_usage(bucket, objects, bytes)
+VALUES(?, ?, ?)
+ON CONFLICT(bucket) DO UPDATE SET objects=objects+excluded.objects, bytes=bytes+excluded.bytes`,
+		bucket, objects, bytes)
+	return err
+}
+
+// versionUsageTx returns what a version contributes to bucket_usage: one
+// object and its size while ACTIVE, nothing otherwise.
+func versionUsageTx(tx *sql.Tx, versionID string) (string, int64, int64, error) {
+	var bucket, state string
+	var size int64
+	err := tx.QueryRow("SELECT bucket, state, size FROM versions WHERE version_id=?", versionID).Scan(&bucket, &state, &size)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, 0, nil
+		}
+		return "", 0, 0, err
+	}
+	if state != "ACTIVE" {
+		return bucket, 0, 0, nil
+	}
+	return bucket, 1, size, nil
+}
+
+// withVersionUsageTx runs fn, which writes a single version row, and moves
+// the version's contribution to bucket_usage and size_histogram by the
+// resulting difference.
+func withVersionUsageTx(tx *sql.Tx, versionID string, fn func() error) error {
+	beforeBucket, beforeObjects, beforeBytes, err := versionUsageTx(tx, versionID)
+	if err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	afterBucket, afterObjects, afterBytes, err := versionUsageTx(tx, versionID)
+	if err != nil {
+		return err
+	}
+	if err := adjustBucketUsageTx(tx, beforeBucket, -beforeObjects, -beforeBytes); err != nil {
+		return err
+	}
+	if err := adjustBucketUsageTx(tx, afterBucket, afterObjects, afterBytes); err != nil {
+		return err
+	}
+	if err := adjustSizeHistogramTx(tx, beforeBytes, -beforeObjects); err != nil {
+		return err
+	}
+	return adjustSizeHistogramTx(tx, afterBytes, afterObjects)
+}
+
 // DeleteSegment removes a segment row.
 func (s *Store) DeleteSegment(ctx context.Context, segmentID string) error {
 	_, err := s.db.ExecContext(ctx, "DELETE FROM segments WHERE segment_id=?", segmentID)
@@ -3,6 +3,7 @@ package meta
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"testing"
@@ -87,6 +88,51 @@ func TestMarkDamaged(t *testing.T) {
 	}
 }
 
+func TestListDamagedVersionsPaginates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "meta.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	for _, put := range []struct{ bucket, key, version string }{
+		{"b1", "k1", "v1"},
+		{"b1", "k2", "v2"},
+		{"b1", "k3", "v3"},
+		{"b2", "k1", "v4"},
+	} {
+		if err := store.RecordPut(ctx, put.bucket, put.key, put.version, "etag", 12, "/tmp/"+put.version, ""); err != nil {
+			t.Fatalf("RecordPut: %v", err)
+		}
+	}
+	for _, version := range []string{"v1", "v3", "v4"} {
+		if err := store.MarkDamaged(ctx, version); err != nil {
+			t.Fatalf("MarkDamaged: %v", err)
+		}
+	}
+	first, err := store.ListDamagedVersions(ctx, "", "", "", 2)
+	if err != nil {
+		t.Fatalf("ListDamagedVersions: %v", err)
+	}
+	if len(first) != 2 || first[0].VersionID != "v1" || first[1].VersionID != "v3" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	if first[0].Bucket != "b1" || first[0].Key != "k1" || first[0].ManifestPath != "/tmp/v1" || first[0].LastModified == "" {
+		t.Fatalf("unexpected item: %+v", first[0])
+	}
+	last := first[len(first)-1]
+	second, err := store.ListDamagedVersions(ctx, last.Bucket, last.Key, last.VersionID, 2)
+	if err != nil {
+		t.Fatalf("ListDamagedVersions: %v", err)
+	}
+	if len(second) != 1 || second[0].Bucket != "b2" || second[0].VersionID != "v4" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+}
+
 func TestRecordMPUCompleteUpdatesETag(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "meta.db")
@@ -412,6 +458,71 @@ func TestDeleteObjectVersionPromotesPrevious(t *testing.T) {
 	}
 }
 
+func TestPurgeBucketObjectsDeletesAllVersions(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	if err := store.CreateBucketWithVersioning(ctx, "b", BucketVersioningEnabled); err != nil {
+		t.Fatalf("CreateBucketWithVersioning: %v", err)
+	}
+	if err := store.RecordPut(ctx, "b", "k1", "v1", "etag1", 3, "", ""); err != nil {
+		t.Fatalf("RecordPut v1: %v", err)
+	}
+	if err := store.RecordPut(ctx, "b", "k1", "v2", "etag2", 5, "", ""); err != nil {
+		t.Fatalf("RecordPut v2: %v", err)
+	}
+	if err := store.RecordPut(ctx, "b", "k2", "v3", "etag3", 7, "", ""); err != nil {
+		t.Fatalf("RecordPut v3: %v", err)
+	}
+	if _, err := store.CreateDeleteMarker(ctx, "b", "k2"); err != nil {
+		t.Fatalf("CreateDeleteMarker: %v", err)
+	}
+	before, err := store.ListOplogSince(ctx, "", 100)
+	if err != nil {
+		t.Fatalf("ListOplogSince: %v", err)
+	}
+
+	purged, err := store.PurgeBucketObjects(ctx, "b")
+	if err != nil {
+		t.Fatalf("PurgeBucketObjects: %v", err)
+	}
+	want := BucketPurge{Objects: 2, Versions: 3, DeleteMarkers: 1, Bytes: 15}
+	if purged != want {
+		t.Fatalf("purged=%+v want %+v", purged, want)
+	}
+	versions, err := store.ListObjectVersions(ctx, "b", "", "", "", 100, time.Time{})
+	if err != nil {
+		t.Fatalf("ListObjectVersions: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Fatalf("versions left: %+v", versions)
+	}
+	hasObjects, err := store.BucketHasObjects(ctx, "b")
+	if err != nil {
+		t.Fatalf("BucketHasObjects: %v", err)
+	}
+	if hasObjects {
+		t.Fatalf("expected no current objects")
+	}
+	after, err := store.ListOplogSince(ctx, before[len(before)-1].HLCTS, 100)
+	if err != nil {
+		t.Fatalf("ListOplogSince: %v", err)
+	}
+	if len(after) != 4 {
+		t.Fatalf("oplog entries=%d want 4", len(after))
+	}
+	for _, entry := range after {
+		if entry.OpType != "delete" {
+			t.Fatalf("unexpected oplog entry: %+v", entry)
+		}
+	}
+}
+
 func TestMigrationV18AddsDeleteMarkersForOrphans(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "meta.db")
@@ -515,3 +626,94 @@ VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, 'ACTIVE')`, "v2", "b", "k2", "etag2", 1, "", n
 		t.Fatalf("expected delete marker for k2, got %s", meta2.State)
 	}
 }
+
+func TestBucketUsageTracksWrites(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	source, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = source.Close() })
+	source.SetSiteID("site-a")
+
+	assertUsage := func(store *Store, objects, bytes int64) {
+		t.Helper()
+		usage, err := store.GetBucketUsage(ctx, "bucket")
+		if err != nil {
+			t.Fatalf("GetBucketUsage: %v", err)
+		}
+		if usage.Objects != objects || usage.Bytes != bytes {
+			t.Fatalf("usage=%+v want objects=%d bytes=%d", usage, objects, bytes)
+		}
+	}
+
+	if err := source.RecordPut(ctx, "bucket", "key", "v1", "etag1", 100, "", ""); err != nil {
+		t.Fatalf("RecordPut v1: %v", err)
+	}
+	if err := source.RecordPut(ctx, "bucket", "key", "v2", "etag2", 50, "", ""); err != nil {
+		t.Fatalf("RecordPut v2: %v", err)
+	}
+	assertUsage(source, 2, 150)
+	if _, err := source.DeleteObject(ctx, "bucket", "key"); err != nil {
+		t.Fatalf("DeleteObject: %v", err)
+	}
+	assertUsage(source, 2, 150)
+	if _, err := source.DeleteObjectVersion(ctx, "bucket", "key", "v1"); err != nil {
+		t.Fatalf("DeleteObjectVersion: %v", err)
+	}
+	assertUsage(source, 1, 50)
+	if _, err := source.DeleteObjectVersion(ctx, "bucket", "key", "v1"); err != nil {
+		t.Fatalf("DeleteObjectVersion again: %v", err)
+	}
+	assertUsage(source, 1, 50)
+
+	entries, err := source.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	replica, err := Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("Open replica: %v", err)
+	}
+	t.Cleanup(func() { _ = replica.Close() })
+	replica.SetSiteID("site-b")
+	for i := 0; i < 2; i++ {
+		if _, err := replica.ApplyOplogEntries(ctx, entries); err != nil {
+			t.Fatalf("ApplyOplogEntries: %v", err)
+		}
+	}
+	assertUsage(replica, 1, 50)
+
+	list, err := replica.ListBucketUsage(ctx)
+	if err != nil {
+		t.Fatalf("ListBucketUsage: %v", err)
+	}
+	if len(list) != 1 || list[0].Bucket != "bucket" || list[0].Bytes != 50 {
+		t.Fatalf("unexpected usage list: %+v", list)
+	}
+}
+
+func TestOpenReportsReadOnlyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.CreateBucket(context.Background(), "demo"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	_ = store.Close()
+
+	if _, err := Open("file:" + path + "?mode=ro"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+	ro, err := OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("OpenReadOnly: %v", err)
+	}
+	defer func() { _ = ro.Close() }()
+	if ok, err := ro.BucketExists(context.Background(), "demo"); err != nil || !ok {
+		t.Fatalf("BucketExists: %v %v", ok, err)
+	}
+}
@@ -0,0 +1,98 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// SetVersionMetadataTx stores the user metadata (x-amz-meta-* names without
+// the prefix) and tag set of a version written in the same transaction and
+// adds them to the version's put oplog entry, so replicas carry them too.
+// Nil or empty maps clear the stored value.
+func (s *Store) SetVersionMetadataTx(tx *sql.Tx, versionID string, metadata, tags map[string]string) error {
+	if tx == nil {
+		return errors.New("meta: tx required")
+	}
+	if versionID == "" {
+		return errors.New("meta: version id required")
+	}
+	metadataJSON, err := encodeStringMap(metadata)
+	if err != nil {
+		return err
+	}
+	tagsJSON, err := encodeStringMap(tags)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE versions SET user_metadata=?, tags=? WHERE version_id=?", metadataJSON, tagsJSON, versionID); err != nil {
+		return err
+	}
+	return patchPutOplogPayloadTx(tx, versionID, func(payload *oplogPutPayload) {
+		payload.Metadata = metadata
+		payload.Tags = tags
+	})
+}
+
+// VersionMetadata returns the user metadata and tags of a version; nil maps
+// mean none were recorded.
+func (s *Store) VersionMetadata(ctx context.Context, versionID string) (map[string]string, map[string]string, error) {
+	if versionID == "" {
+		return nil, nil, errors.New("meta: version id required")
+	}
+	return scanVersionMetadata(s.db.QueryRowContext(ctx, "SELECT user_metadata, tags FROM versions WHERE version_id=?", versionID))
+}
+
+// VersionMetadataTx is VersionMetadata within the provided transaction.
+func (s *Store) VersionMetadataTx(ctx context.Context, tx *sql.Tx, versionID string) (map[string]string, map[string]string, error) {
+	if tx == nil {
+		return nil, nil, errors.New("meta: tx required")
+	}
+	if versionID == "" {
+		return nil, nil, errors.New("meta: version id required")
+	}
+	return scanVersionMetadata(tx.QueryRowContext(ctx, "SELECT user_metadata, tags FROM versions WHERE version_id=?", versionID))
+}
+
+func scanVersionMetadata(row *sql.Row) (map[string]string, map[string]string, error) {
+	var metadataJSON, tagsJSON sql.NullString
+	if err := row.Scan(&metadataJSON, &tagsJSON); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	metadata, err := decodeStringMap(metadataJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, err := decodeStringMap(tagsJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	return metadata, tags, nil
+}
+
+// encodeStringMap stores an empty map as NULL.
+func encodeStringMap(m map[string]string) (any, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func decodeStringMap(raw sql.NullString) (map[string]string, error) {
+	if !raw.Valid || raw.String == "" {
+		return nil, nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(raw.String), &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
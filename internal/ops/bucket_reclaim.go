@@ -0,0 +1,79 @@
+package ops
+
+import (
+	"context"
+	"os"
+	"sort"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+	"github.com/kk-code-lab/seglake/internal/storage/segment"
+)
+
+// BucketSegments returns the segments holding chunks of any version in
+// bucket. Collect them before PurgeBucketObjects drops the versions, then
+// pass them to ReclaimSegments.
+func BucketSegments(ctx context.Context, store *meta.Store, bucket string) ([]string, error) {
+	paths, err := store.ListBucketManifestPaths(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		man, err := (&manifest.BinaryCodec{}).Decode(file)
+		_ = file.Close()
+		if err != nil {
+			continue
+		}
+		for _, ch := range man.Chunks {
+			if !ch.IsHole() {
+				seen[ch.SegmentID] = struct{}{}
+			}
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// ReclaimSegments compacts the sealed segments among ids right away,
+// regardless of their age and live ratio: chunks still referenced are
+// rewritten into new segments and the old segments are removed. Open segments
+// are left for gc once sealed. guard is the engine of a running server, or
+// nil offline.
+func ReclaimSegments(ctx context.Context, layout fs.Layout, store *meta.Store, ids []string, guard SegmentGuard) (*Report, error) {
+	report := newReport("segment-reclaim")
+	want := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		want[id] = struct{}{}
+	}
+	segments, err := store.ListSegments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan := &GCRewritePlan{GeneratedAt: now().UTC(), LiveThreshold: 1}
+	for _, seg := range segments {
+		if _, ok := want[seg.ID]; !ok || seg.State != string(segment.StateSealed) {
+			continue
+		}
+		plan.Candidates = append(plan.Candidates, GCRewriteCandidate{ID: seg.ID, Path: seg.Path, Size: seg.Size})
+	}
+	report.Candidates = len(plan.Candidates)
+	if len(plan.Candidates) == 0 {
+		report.FinishedAt = now().UTC()
+		return report, nil
+	}
+	if err := runGCRewrite(ctx, layout, store, plan, report, 0, 1, "", guard); err != nil {
+		return report, err
+	}
+	_ = store.RecordOpsRun(ctx, report.Mode, reportOpsFrom(report))
+	return report, nil
+}
@@ -0,0 +1,70 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+// CurrentCheckOptions controls the objects_current consistency pass.
+type CurrentCheckOptions struct {
+	// DryRun reports dangling pointers (as candidates) without repairing them.
+	DryRun bool
+	// Logf, when set, receives one line per dangling pointer and repair.
+	Logf func(format string, args ...any)
+}
+
+// CurrentCheck verifies that every objects_current row points at an existing,
+// non-DELETED version of its key and repairs the rest: the pointer moves to
+// the newest remaining version (a delete marker hides the key as before) or
+// is removed when no version is left. Unlike fsck it reads meta.db only, so
+// it is fast enough to run at startup. Repairs are local (no oplog entries).
+func CurrentCheck(metaPath string, opts CurrentCheckOptions) (*Report, error) {
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = store.Close() }()
+	return CurrentCheckStore(context.Background(), store, opts)
+}
+
+// CurrentCheckStore runs CurrentCheck against an open store.
+func CurrentCheckStore(ctx context.Context, store *meta.Store, opts CurrentCheckOptions) (*Report, error) {
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	report := newReport("current-check")
+	dangling, err := store.ListDanglingCurrent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range dangling {
+		report.Candidates++
+		report.CandidateIDs = append(report.CandidateIDs, item.Bucket+"/"+item.Key)
+		if opts.DryRun {
+			logf("current-check: %s/%s points at missing or deleted version %s", item.Bucket, item.Key, item.VersionID)
+			continue
+		}
+		repair, ok, err := store.RepairCurrent(ctx, item)
+		if err != nil {
+			report.addError(fmt.Errorf("current-check: %s/%s: %w", item.Bucket, item.Key, err))
+			continue
+		}
+		if !ok {
+			report.addWarning(fmt.Sprintf("current-check: %s/%s: changed during check", item.Bucket, item.Key))
+			continue
+		}
+		if repair.Removed() {
+			report.CurrentRemoved++
+			logf("current-check: %s/%s: removed pointer to missing or deleted version %s", item.Bucket, item.Key, item.VersionID)
+			continue
+		}
+		report.CurrentRepointed++
+		logf("current-check: %s/%s: repointed %s -> %s", item.Bucket, item.Key, item.VersionID, repair.NewVersionID)
+	}
+	report.FinishedAt = now().UTC()
+	_ = store.RecordOpsRun(ctx, report.Mode, reportOpsFrom(report))
+	return report, nil
+}
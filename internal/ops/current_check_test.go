@@ -0,0 +1,84 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+func TestCurrentCheckRepairsDanglingPointers(t *testing.T) {
+	dir := t.TempDir()
+	metaPath := filepath.Join(dir, "meta.db")
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	if err := store.CreateBucket(ctx, "bucket"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	for _, put := range []struct{ key, version string }{
+		{"repoint", "v1"},
+		{"repoint", "v2"},
+		{"remove", "v3"},
+		{"ok", "v4"},
+	} {
+		if err := store.RecordPut(ctx, "bucket", put.key, put.version, "etag", 1, "", ""); err != nil {
+			t.Fatalf("RecordPut %s: %v", put.version, err)
+		}
+	}
+	// Simulate a crash that left objects_current behind the versions table.
+	db, err := sql.Open("sqlite", metaPath)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if _, err := db.Exec("UPDATE versions SET state='DELETED' WHERE version_id='v2'"); err != nil {
+		t.Fatalf("mark deleted: %v", err)
+	}
+	if _, err := db.Exec("DELETE FROM versions WHERE version_id='v3'"); err != nil {
+		t.Fatalf("delete version: %v", err)
+	}
+
+	report, err := CurrentCheck(metaPath, CurrentCheckOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("CurrentCheck dry run: %v", err)
+	}
+	if report.Candidates != 2 || report.CurrentRepointed != 0 || report.CurrentRemoved != 0 {
+		t.Fatalf("unexpected dry-run report: %+v", report)
+	}
+	var current string
+	if err := db.QueryRow("SELECT version_id FROM objects_current WHERE bucket='bucket' AND key='remove'").Scan(&current); err != nil || current != "v3" {
+		t.Fatalf("dry run must not repair, got %q (%v)", current, err)
+	}
+
+	var logged int
+	report, err = CurrentCheck(metaPath, CurrentCheckOptions{Logf: func(string, ...any) { logged++ }})
+	if err != nil {
+		t.Fatalf("CurrentCheck: %v", err)
+	}
+	if report.Candidates != 2 || report.CurrentRepointed != 1 || report.CurrentRemoved != 1 || report.Errors != 0 || logged != 2 {
+		t.Fatalf("unexpected report: %+v (logged %d)", report, logged)
+	}
+	if len(report.CandidateIDs) != 2 || report.CandidateIDs[0] != "bucket/remove" || report.CandidateIDs[1] != "bucket/repoint" {
+		t.Fatalf("unexpected candidates: %v", report.CandidateIDs)
+	}
+	if err := db.QueryRow("SELECT version_id FROM objects_current WHERE bucket='bucket' AND key='repoint'").Scan(&current); err != nil || current != "v1" {
+		t.Fatalf("expected repoint -> v1, got %q (%v)", current, err)
+	}
+	if err := db.QueryRow("SELECT version_id FROM objects_current WHERE bucket='bucket' AND key='remove'").Scan(&current); err != sql.ErrNoRows {
+		t.Fatalf("expected remove pointer dropped, got %q (%v)", current, err)
+	}
+
+	report, err = CurrentCheck(metaPath, CurrentCheckOptions{})
+	if err != nil {
+		t.Fatalf("CurrentCheck rerun: %v", err)
+	}
+	if report.Candidates != 0 {
+		t.Fatalf("expected clean rerun, got %+v", report)
+	}
+}
@@ -0,0 +1,253 @@
+package ops
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+)
+
+const etagFixPageSize = 500
+
+// ETagFixOptions selects the objects whose ETags are recomputed.
+type ETagFixOptions struct {
+	Bucket string
+	Prefix string
+	// DryRun reports wrong ETags (as candidates) without updating them.
+	DryRun bool
+}
+
+// ETagFix re-reads the current objects of a bucket (optionally under a
+// prefix), recomputes their ETags and rewrites versions.etag where it differs.
+// Objects are only read and each fix is a single-row compare-and-set, so it
+// can run next to a server. DAMAGED objects are skipped. Fixes are local:
+// the oplog is not touched, so run it on every site.
+func ETagFix(layout fs.Layout, metaPath string, opts ETagFixOptions) (*Report, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("ops: etag-fix bucket required")
+	}
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = store.Close() }()
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	report := newReport("etag-fix")
+	ctx := context.Background()
+	afterKey, afterVersion := "", ""
+	for {
+		objs, err := store.ListObjects(ctx, opts.Bucket, opts.Prefix, afterKey, afterVersion, etagFixPageSize, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			afterKey, afterVersion = obj.Key, obj.VersionID
+			// LIKE matches ASCII case-insensitively; drop keys outside the prefix.
+			if !strings.HasPrefix(obj.Key, opts.Prefix) {
+				continue
+			}
+			if strings.EqualFold(obj.State, meta.VersionStateDamaged) {
+				report.Skipped++
+				continue
+			}
+			report.ETagsChecked++
+			want, err := recomputeETag(ctx, eng, store, obj)
+			if err != nil {
+				report.addError(fmt.Errorf("etag-fix: %s: %w", obj.Key, err))
+				continue
+			}
+			if want == "" {
+				report.Skipped++
+				report.addWarning(fmt.Sprintf("etag-fix: %s: etag %s cannot be recomputed (unknown multipart part boundaries or origin)", obj.Key, obj.ETag))
+				continue
+			}
+			if want == obj.ETag {
+				continue
+			}
+			report.Candidates++
+			report.CandidateIDs = append(report.CandidateIDs, obj.VersionID)
+			if opts.DryRun {
+				continue
+			}
+			updated, err := store.UpdateVersionETag(ctx, obj.VersionID, obj.ETag, want)
+			if err != nil {
+				report.addError(fmt.Errorf("etag-fix: %s: %w", obj.Key, err))
+				continue
+			}
+			if !updated {
+				report.addWarning(fmt.Sprintf("etag-fix: %s: version changed during check", obj.Key))
+				continue
+			}
+			report.ETagsFixed++
+		}
+		if len(objs) < etagFixPageSize {
+			break
+		}
+	}
+	report.FinishedAt = now().UTC()
+	_ = store.RecordOpsRun(ctx, report.Mode, reportOpsFrom(report))
+	return report, nil
+}
+
+// recomputeETag returns the ETag obj should have, or "" when it cannot be
+// derived. Multipart ETags ("-N") need the part sizes, which are recovered
+// from the chunk layout; a stored ETag that matches the composite or the
+// append chain over the same parts is kept. The oplog tells plain PUTs from
+// completed multipart uploads, so a "-N" ETag on a plain PUT becomes an MD5;
+// when the chunk layout yields no part sizes the object is skipped instead.
+func recomputeETag(ctx context.Context, eng *engine.Engine, store *meta.Store, obj meta.ObjectMeta) (string, error) {
+	completed, err := store.HasOplogOp(ctx, obj.VersionID, "mpu_complete")
+	if err != nil {
+		return "", err
+	}
+	parts := etagParts(obj.ETag)
+	reader, man, err := eng.Get(ctx, obj.VersionID)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = reader.Close() }()
+	var sizes []int64
+	if parts > 0 {
+		sizes = partSizes(man.Chunks, parts)
+	}
+	if parts > 0 && sizes == nil {
+		// Without the part boundaries the stored ETag cannot be checked; an
+		// MD5 over the whole object could replace a valid composite.
+		return "", nil
+	}
+	whole, composite, chain, err := digestParts(reader, sizes)
+	if err != nil {
+		return "", err
+	}
+	if parts == 0 {
+		return whole, nil
+	}
+	suffix := "-" + strconv.Itoa(parts)
+	if obj.ETag == composite+suffix || (parts > 1 && obj.ETag == chain+suffix) {
+		return obj.ETag, nil
+	}
+	if completed {
+		return composite + suffix, nil
+	}
+	// Not a completed upload: only a plain PUT is known to carry an MD5.
+	put, err := store.HasOplogOp(ctx, obj.VersionID, "put")
+	if err != nil || !put {
+		return "", err
+	}
+	return whole, nil
+}
+
+// etagParts returns N for a multipart-style "<md5>-N" ETag, or 0.
+func etagParts(etag string) int {
+	_, count, ok := strings.Cut(etag, "-")
+	if !ok {
+		return 0
+	}
+	parts, err := strconv.Atoi(count)
+	if err != nil || parts < 1 {
+		return 0
+	}
+	return parts
+}
+
+// digestParts reads r once and returns the MD5 of the whole stream and, when
+// sizes is set, the multipart composite (MD5 of the part MD5s) and the append
+// chain (each part MD5 folded into the previous digest), all hex encoded.
+func digestParts(r io.Reader, sizes []int64) (whole, composite, chain string, err error) {
+	wholeSum := md5.New()
+	r = io.TeeReader(r, wholeSum)
+	compositeSum := md5.New()
+	var link []byte
+	for i, size := range sizes {
+		sum := md5.New()
+		if _, err := io.CopyN(sum, r, size); err != nil {
+			return "", "", "", err
+		}
+		partSum := sum.Sum(nil)
+		compositeSum.Write(partSum)
+		if i == 0 {
+			link = partSum
+			continue
+		}
+		next := md5.New()
+		next.Write(link)
+		next.Write(partSum)
+		link = next.Sum(nil)
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return "", "", "", err
+	}
+	whole = hex.EncodeToString(wholeSum.Sum(nil))
+	if sizes != nil {
+		composite = hex.EncodeToString(compositeSum.Sum(nil))
+		chain = hex.EncodeToString(link)
+	}
+	return whole, composite, chain, nil
+}
+
+// partSizes recovers the part sizes of a multipart object. Every part is
+// chunked on its own, so a chunk shorter than the largest one ends a part.
+// Parts that are an exact multiple of the chunk size leave no trace; then
+// equal parts are assumed, which must leave a single possible chunks-per-part
+// count. It returns nil when the layout is ambiguous.
+func partSizes(chunks []manifest.ChunkRef, parts int) []int64 {
+	if len(chunks) == 0 || parts > len(chunks) {
+		return nil
+	}
+	var full uint32
+	for _, ch := range chunks {
+		full = max(full, ch.Len)
+	}
+	var sizes []int64
+	var size int64
+	for i, ch := range chunks {
+		size += int64(ch.Len)
+		if ch.Len < full || i == len(chunks)-1 {
+			sizes = append(sizes, size)
+			size = 0
+		}
+	}
+	if len(sizes) == parts {
+		return sizes
+	}
+	if len(sizes) != 1 {
+		return nil
+	}
+	perPart := 0
+	for n := 1; n <= len(chunks); n++ {
+		if (len(chunks)+n-1)/n != parts {
+			continue
+		}
+		if perPart != 0 {
+			return nil
+		}
+		perPart = n
+	}
+	if perPart == 0 {
+		return nil
+	}
+	sizes = sizes[:0]
+	size = 0
+	for i, ch := range chunks {
+		size += int64(ch.Len)
+		if (i+1)%perPart == 0 || i == len(chunks)-1 {
+			sizes = append(sizes, size)
+			size = 0
+		}
+	}
+	return sizes
+}
@@ -0,0 +1,178 @@
+package ops
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/chunk"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+)
+
+func TestETagFixRecomputesSingleAndMultipart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "objects"))
+	metaPath := filepath.Join(dir, "meta.db")
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	// 4-byte chunks keep part boundaries visible in small test objects.
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store, Splitter: chunk.NewFixedSplitter(4)})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+
+	_, single, err := eng.PutObject(ctx, "bucket", "logs/single", "text/plain", strings.NewReader("hello world"))
+	if err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	// Parts "abcdef" and "ghi": chunks 4+2 and 3, so the short chunk ends part 1.
+	var chunks []manifest.ChunkRef
+	composite := md5.New()
+	for i, body := range []string{"abcdef", "ghi"} {
+		man, part, err := eng.PutObject(ctx, "bucket", "parts/"+string(rune('a'+i)), "", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("PutObject part: %v", err)
+		}
+		sum, _ := hex.DecodeString(part.ETag)
+		composite.Write(sum)
+		for _, ch := range man.Chunks {
+			ch.Index = len(chunks)
+			chunks = append(chunks, ch)
+		}
+	}
+	mpuETag := hex.EncodeToString(composite.Sum(nil)) + "-2"
+	_, mpu, err := eng.PutManifestWithCommit(ctx, "bucket", "logs/mpu", "", 9, mpuETag, chunks, func(tx *sql.Tx, result *engine.PutResult, _ string) error {
+		return store.RecordMPUCompleteTx(ctx, tx, "bucket", "logs/mpu", result.VersionID, mpuETag, result.Size)
+	})
+	if err != nil {
+		t.Fatalf("PutManifestWithCommit: %v", err)
+	}
+	_, damaged, err := eng.PutObject(ctx, "bucket", "logs/damaged", "", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	if err := store.MarkDamaged(ctx, damaged.VersionID); err != nil {
+		t.Fatalf("MarkDamaged: %v", err)
+	}
+	for _, v := range []struct{ id, etag string }{{single.VersionID, single.ETag}, {mpu.VersionID, mpuETag}, {damaged.VersionID, damaged.ETag}} {
+		if ok, err := store.UpdateVersionETag(ctx, v.id, v.etag, "0123456789abcdef0123456789abcdef-2"); err != nil || !ok {
+			t.Fatalf("corrupt etag: %v %v", ok, err)
+		}
+	}
+
+	report, err := ETagFix(layout, metaPath, ETagFixOptions{Bucket: "bucket", Prefix: "logs/", DryRun: true})
+	if err != nil {
+		t.Fatalf("ETagFix dry run: %v", err)
+	}
+	if report.Candidates != 2 || report.ETagsFixed != 0 {
+		t.Fatalf("unexpected dry run report: %+v", report)
+	}
+
+	report, err = ETagFix(layout, metaPath, ETagFixOptions{Bucket: "bucket", Prefix: "logs/"})
+	if err != nil {
+		t.Fatalf("ETagFix: %v", err)
+	}
+	if report.ETagsChecked != 2 || report.ETagsFixed != 2 || report.Skipped != 1 || report.Errors != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for key, want := range map[string]string{"logs/single": single.ETag, "logs/mpu": mpuETag} {
+		obj, err := store.GetObjectMeta(ctx, "bucket", key)
+		if err != nil {
+			t.Fatalf("GetObjectMeta %s: %v", key, err)
+		}
+		if obj.ETag != want {
+			t.Fatalf("%s etag %s, want %s", key, obj.ETag, want)
+		}
+	}
+
+	report, err = ETagFix(layout, metaPath, ETagFixOptions{Bucket: "bucket", Prefix: "logs/"})
+	if err != nil {
+		t.Fatalf("ETagFix rerun: %v", err)
+	}
+	if report.Candidates != 0 || report.ETagsFixed != 0 {
+		t.Fatalf("rerun changed etags: %+v", report)
+	}
+}
+
+func TestPartSizesAssumesEqualPartsWhenUnambiguous(t *testing.T) {
+	refs := func(lens ...uint32) []manifest.ChunkRef {
+		out := make([]manifest.ChunkRef, len(lens))
+		for i, n := range lens {
+			out[i] = manifest.ChunkRef{Index: i, Len: n}
+		}
+		return out
+	}
+	cases := []struct {
+		name  string
+		lens  []uint32
+		parts int
+		want  []int64
+	}{
+		{name: "short chunks", lens: []uint32{4, 2, 4, 4, 1}, parts: 2, want: []int64{6, 9}},
+		{name: "equal parts", lens: []uint32{4, 4, 4, 4, 4, 4, 3}, parts: 4, want: []int64{8, 8, 8, 3}},
+		{name: "ambiguous", lens: []uint32{4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4}, parts: 3},
+		{name: "too many parts", lens: []uint32{4, 1}, parts: 3},
+	}
+	for _, tc := range cases {
+		got := partSizes(refs(tc.lens...), tc.parts)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestETagFixSkipsMultipartETagWithoutPartSizes(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "objects"))
+	metaPath := filepath.Join(dir, "meta.db")
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store, Splitter: chunk.NewFixedSplitter(4)})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	// One chunk cannot hold three parts, so the sizes are unknown.
+	_, res, err := eng.PutObject(ctx, "bucket", "logs/short", "", strings.NewReader("hi"))
+	if err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	const stored = "0123456789abcdef0123456789abcdef-3"
+	if ok, err := store.UpdateVersionETag(ctx, res.VersionID, res.ETag, stored); err != nil || !ok {
+		t.Fatalf("set etag: %v %v", ok, err)
+	}
+
+	report, err := ETagFix(layout, metaPath, ETagFixOptions{Bucket: "bucket"})
+	if err != nil {
+		t.Fatalf("ETagFix: %v", err)
+	}
+	if report.Skipped != 1 || report.Candidates != 0 || report.ETagsFixed != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	obj, err := store.GetObjectMeta(ctx, "bucket", "logs/short")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if obj.ETag != stored {
+		t.Fatalf("etag rewritten to %s", obj.ETag)
+	}
+}
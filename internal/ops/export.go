@@ -0,0 +1,359 @@
+package ops
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+const (
+	ExportFormatTar = "tar"
+	ExportFormatDir = "dir"
+
+	// exportManifestSuffix names the sidecar written next to the export output.
+	exportManifestSuffix = ".export.jsonl"
+	exportPageSize       = 1000
+)
+
+// ExportOptions configures a bucket export.
+type ExportOptions struct {
+	Bucket string
+	Prefix string
+	// Out is a tar file (ExportFormatTar) or a directory (ExportFormatDir).
+	Out    string
+	Format string
+	// Resume continues an interrupted export, skipping keys already in the sidecar.
+	Resume bool
+}
+
+// ExportEntry is one line of the export sidecar (<out>.export.jsonl).
+type ExportEntry struct {
+	Key          string `json:"key"`
+	VersionID    string `json:"version_id"`
+	ETag         string `json:"etag"`
+	Size         int64  `json:"size"`
+	ContentType  string `json:"content_type,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// TarOffset is the archive length after this entry; resume truncates to it.
+	TarOffset int64 `json:"tar_offset,omitempty"`
+}
+
+// ExportManifestPath returns the sidecar path for an export output.
+func ExportManifestPath(out string) string {
+	return strings.TrimSuffix(out, string(filepath.Separator)) + exportManifestSuffix
+}
+
+// Export streams the current objects of a bucket into a tar archive or a
+// directory tree and records their metadata in a JSON-lines sidecar. It opens
+// meta.db and the object layout read-only, so it can run next to a server.
+func Export(layout fs.Layout, metaPath string, opts ExportOptions) (*Report, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("ops: export bucket required")
+	}
+	if opts.Out == "" {
+		return nil, errors.New("ops: export output required")
+	}
+	if opts.Format == "" {
+		opts.Format = ExportFormatTar
+	}
+	if opts.Format != ExportFormatTar && opts.Format != ExportFormatDir {
+		return nil, fmt.Errorf("ops: invalid export format %q", opts.Format)
+	}
+	store, err := meta.OpenReadOnly(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = store.Close() }()
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	report := newReport("export")
+	manifestPath := ExportManifestPath(opts.Out)
+	done, tarOffset, err := loadExportManifest(manifestPath, opts.Resume)
+	if err != nil {
+		return nil, err
+	}
+	report.Skipped = len(done)
+	manifestFile, err := os.OpenFile(manifestPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = manifestFile.Close() }()
+
+	var sink exportSink
+	if opts.Format == ExportFormatTar {
+		sink, err = openTarSink(opts.Out, tarOffset, opts.Resume)
+	} else {
+		sink, err = openDirSink(opts.Out, opts.Resume)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	afterKey, afterVersion := "", ""
+	for {
+		objs, err := store.ListObjects(ctx, opts.Bucket, opts.Prefix, afterKey, afterVersion, exportPageSize, now())
+		if err != nil {
+			_ = sink.Close()
+			return nil, err
+		}
+		for _, obj := range objs {
+			afterKey, afterVersion = obj.Key, obj.VersionID
+			if _, ok := done[obj.Key]; ok {
+				continue
+			}
+			if !exportableKey(obj.Key) {
+				report.addWarning(fmt.Sprintf("export: skipped key %q (not a safe relative path)", obj.Key))
+				continue
+			}
+			if strings.EqualFold(obj.State, meta.VersionStateDamaged) {
+				report.addError(fmt.Errorf("export: %s: object damaged", obj.Key))
+				continue
+			}
+			entry := ExportEntry{
+				Key:          obj.Key,
+				VersionID:    obj.VersionID,
+				ETag:         obj.ETag,
+				Size:         obj.Size,
+				ContentType:  obj.ContentType,
+				LastModified: obj.LastModified,
+			}
+			reader, _, err := eng.Get(ctx, obj.VersionID)
+			if err != nil {
+				report.addError(fmt.Errorf("export: %s: %w", obj.Key, err))
+				continue
+			}
+			// A failed write leaves the output mid-entry, so stop here; resume
+			// rewinds to the last recorded entry.
+			offset, err := sink.Write(entry, reader)
+			_ = reader.Close()
+			if err != nil {
+				_ = sink.Close()
+				return nil, fmt.Errorf("ops: export %s: %w", obj.Key, err)
+			}
+			entry.TarOffset = offset
+			line, err := json.Marshal(entry)
+			if err != nil {
+				_ = sink.Close()
+				return nil, err
+			}
+			if _, err := manifestFile.Write(append(line, '\n')); err != nil {
+				_ = sink.Close()
+				return nil, err
+			}
+			report.Exported++
+			report.ExportedBytes += obj.Size
+		}
+		if len(objs) < exportPageSize {
+			break
+		}
+	}
+	if err := sink.Close(); err != nil {
+		return nil, err
+	}
+	if err := manifestFile.Sync(); err != nil {
+		return nil, err
+	}
+	report.FinishedAt = now().UTC()
+	return report, nil
+}
+
+// exportableKey rejects keys that cannot be written as a relative path
+// without escaping the output (absolute, "..", empty or folder markers).
+func exportableKey(key string) bool {
+	if key == "" || strings.HasSuffix(key, "/") {
+		return false
+	}
+	return filepath.IsLocal(key) && filepath.ToSlash(filepath.Clean(key)) == key
+}
+
+// loadExportManifest returns keys already exported and the archive length to
+// resume from. A torn last line from an interrupted run is discarded.
+func loadExportManifest(path string, resume bool) (map[string]struct{}, int64, error) {
+	done := make(map[string]struct{})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return done, 0, nil
+		}
+		return nil, 0, err
+	}
+	if !resume {
+		return nil, 0, fmt.Errorf("ops: export sidecar %s exists (use resume)", path)
+	}
+	var tarOffset int64
+	valid := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var entry ExportEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Key == "" {
+			break
+		}
+		done[entry.Key] = struct{}{}
+		tarOffset = entry.TarOffset
+		valid += len(scanner.Bytes()) + 1
+	}
+	if valid < len(data) {
+		if err := os.Truncate(path, int64(valid)); err != nil {
+			return nil, 0, err
+		}
+	}
+	return done, tarOffset, nil
+}
+
+type exportSink interface {
+	// Write stores one object and returns the archive length after it (0 for directories).
+	Write(entry ExportEntry, r io.Reader) (int64, error)
+	Close() error
+}
+
+type tarSink struct {
+	file   *os.File
+	tw     *tar.Writer
+	offset *countingWriter
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func openTarSink(path string, offset int64, resume bool) (*tarSink, error) {
+	flags := os.O_CREATE | os.O_RDWR
+	if !resume {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if info.Size() < offset {
+		_ = file.Close()
+		return nil, fmt.Errorf("ops: export archive %s is shorter than its sidecar", path)
+	}
+	// Drop the trailer and any entry written after the last recorded one.
+	if err := file.Truncate(offset); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	counter := &countingWriter{w: file, n: offset}
+	return &tarSink{file: file, tw: tar.NewWriter(counter), offset: counter}, nil
+}
+
+func (s *tarSink) Write(entry ExportEntry, r io.Reader) (int64, error) {
+	modTime := time.Now().UTC()
+	if t, err := time.Parse(time.RFC3339Nano, entry.LastModified); err == nil {
+		modTime = t
+	}
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     entry.Key,
+		Size:     entry.Size,
+		Mode:     0o644,
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	}
+	if err := s.tw.WriteHeader(hdr); err != nil {
+		return 0, err
+	}
+	if _, err := io.Copy(s.tw, r); err != nil {
+		return 0, err
+	}
+	if err := s.tw.Flush(); err != nil {
+		return 0, err
+	}
+	return s.offset.n, nil
+}
+
+func (s *tarSink) Close() error {
+	if err := s.tw.Close(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+type dirSink struct {
+	root string
+}
+
+func openDirSink(root string, resume bool) (*dirSink, error) {
+	if !resume {
+		if entries, err := os.ReadDir(root); err == nil && len(entries) > 0 {
+			return nil, fmt.Errorf("ops: export dir %s is not empty (use resume)", root)
+		}
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &dirSink{root: root}, nil
+}
+
+func (s *dirSink) Write(entry ExportEntry, r io.Reader) (int64, error) {
+	path := filepath.Join(s.root, filepath.FromSlash(entry.Key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".export-*")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(tmp, r)
+	if err == nil && n != entry.Size {
+		err = fmt.Errorf("short read: got %d of %d bytes", n, entry.Size)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return 0, err
+	}
+	return 0, nil
+}
+
+func (s *dirSink) Close() error {
+	return nil
+}
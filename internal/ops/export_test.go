@@ -0,0 +1,144 @@
+package ops
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func newExportTestEngine(t *testing.T) (fs.Layout, string, *engine.Engine) {
+	t.Helper()
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "objects"))
+	metaPath := filepath.Join(dir, "meta.db")
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	return layout, metaPath, eng
+}
+
+func exportPut(t *testing.T, eng *engine.Engine, key, contentType, body string) {
+	t.Helper()
+	if _, _, err := eng.PutObject(context.Background(), "bucket", key, contentType, strings.NewReader(body)); err != nil {
+		t.Fatalf("PutObject %s: %v", key, err)
+	}
+}
+
+func readTarEntries(t *testing.T, path string) map[string]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	out := make(map[string]string)
+	tr := tar.NewReader(file)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("tar Next: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("tar read: %v", err)
+		}
+		out[hdr.Name] = string(data)
+	}
+}
+
+func TestExportTarWithPrefixAndResume(t *testing.T) {
+	layout, metaPath, eng := newExportTestEngine(t)
+	exportPut(t, eng, "a/one.txt", "text/plain", "one")
+	exportPut(t, eng, "a/two.json", "application/json", "{}")
+	exportPut(t, eng, "b/three.txt", "", "three")
+
+	out := filepath.Join(t.TempDir(), "bucket.tar")
+	report, err := Export(layout, metaPath, ExportOptions{Bucket: "bucket", Prefix: "a/", Out: out})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if report.Exported != 2 || report.ExportedBytes != 5 || report.Errors != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	entries := readTarEntries(t, out)
+	if len(entries) != 2 || entries["a/one.txt"] != "one" || entries["a/two.json"] != "{}" {
+		t.Fatalf("unexpected tar entries: %v", entries)
+	}
+
+	sidecar, err := os.Open(ExportManifestPath(out))
+	if err != nil {
+		t.Fatalf("open sidecar: %v", err)
+	}
+	var lines []ExportEntry
+	scanner := bufio.NewScanner(sidecar)
+	for scanner.Scan() {
+		var entry ExportEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("sidecar line: %v", err)
+		}
+		lines = append(lines, entry)
+	}
+	_ = sidecar.Close()
+	if len(lines) != 2 || lines[1].ContentType != "application/json" || lines[1].ETag == "" {
+		t.Fatalf("unexpected sidecar: %+v", lines)
+	}
+
+	if _, err := Export(layout, metaPath, ExportOptions{Bucket: "bucket", Prefix: "a/", Out: out}); err == nil {
+		t.Fatalf("expected error exporting over an existing sidecar without resume")
+	}
+
+	exportPut(t, eng, "a/zzz.txt", "", "late")
+	report, err = Export(layout, metaPath, ExportOptions{Bucket: "bucket", Prefix: "a/", Out: out, Resume: true})
+	if err != nil {
+		t.Fatalf("Export resume: %v", err)
+	}
+	if report.Exported != 1 || report.Skipped != 2 {
+		t.Fatalf("unexpected resume report: %+v", report)
+	}
+	entries = readTarEntries(t, out)
+	if len(entries) != 3 || entries["a/zzz.txt"] != "late" {
+		t.Fatalf("unexpected tar entries after resume: %v", entries)
+	}
+}
+
+func TestExportDirSkipsUnsafeKeys(t *testing.T) {
+	layout, metaPath, eng := newExportTestEngine(t)
+	exportPut(t, eng, "docs/readme.md", "text/markdown", "hello")
+	exportPut(t, eng, "../escape", "", "nope")
+	exportPut(t, eng, "folder/", "", "")
+
+	out := filepath.Join(t.TempDir(), "export")
+	report, err := Export(layout, metaPath, ExportOptions{Bucket: "bucket", Out: out, Format: ExportFormatDir})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if report.Exported != 1 || report.Warnings != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "docs", "readme.md"))
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("exported file: %q err=%v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(out), "escape")); !os.IsNotExist(err) {
+		t.Fatalf("expected unsafe key not written outside output, err=%v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package ops
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+	"github.com/kk-code-lab/seglake/internal/storage/segment"
+)
+
+// FsckSegmentsOptions configures a manifest vs. segment cross-check.
+type FsckSegmentsOptions struct {
+	// DryRun opens meta.db read-only and only reports.
+	DryRun bool
+	// Repair re-points bad chunks to a verified copy of the same chunk in
+	// another segment (dedup) before falling back to marking DAMAGED.
+	Repair bool
+}
+
+// segmentCheck is the cached verdict for one segment.
+type segmentCheck struct {
+	dataEnd int64
+	err     error
+}
+
+// FsckSegments cross-checks live manifests against the segments table: every
+// chunk must lie within its segment's recorded size, and sealed segments with
+// a recorded footer_checksum must end in a footer whose recomputed checksum
+// matches it. Versions with bad chunks are marked DAMAGED unless DryRun is set.
+func FsckSegments(layout fs.Layout, metaPath string, opts FsckSegmentsOptions) (*Report, error) {
+	if metaPath == "" {
+		return nil, fmt.Errorf("ops: fsck-segments requires meta path")
+	}
+	if opts.DryRun && opts.Repair {
+		return nil, fmt.Errorf("ops: fsck-segments repair cannot be combined with dry run")
+	}
+	var (
+		store *meta.Store
+		err   error
+	)
+	if opts.DryRun {
+		store, err = meta.OpenReadOnly(metaPath)
+	} else {
+		store, err = meta.Open(metaPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	report := newReport("fsck-segments")
+
+	livePaths, err := store.ListLiveManifestPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mpuPaths, err := store.ListMultipartPartManifestPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paths := mergeUniquePaths(livePaths, mpuPaths)
+	report.Manifests = len(paths)
+
+	checks := make(map[string]*segmentCheck)
+	checkSegment := func(id string) *segmentCheck {
+		if check, ok := checks[id]; ok {
+			return check
+		}
+		check := verifySegment(ctx, store, layout, id, report)
+		checks[id] = check
+		return check
+	}
+	chunkErr := func(ch manifest.ChunkRef) error {
+		check := checkSegment(ch.SegmentID)
+		if check.err != nil {
+			return check.err
+		}
+		if ch.Offset < segment.SegmentHeaderLen() || ch.Offset+int64(ch.Len) > check.dataEnd {
+			return fmt.Errorf("chunk out of bounds segment=%s offset=%d len=%d size=%d", ch.SegmentID, ch.Offset, ch.Len, check.dataEnd)
+		}
+		return nil
+	}
+
+	type damagedManifest struct {
+		path string
+		man  *manifest.Manifest
+		bad  []int
+	}
+	var damaged []damagedManifest
+	// replicas indexes chunks that passed the checks by hash, for repair.
+	replicas := make(map[[32]byte]manifest.ChunkRef)
+	for _, path := range paths {
+		man, err := readManifestFile(path)
+		if err != nil {
+			report.InvalidManifests++
+			report.addError(fmt.Errorf("manifest %s: %w", path, err))
+			continue
+		}
+		var bad []int
+		for idx, ch := range man.Chunks {
+			if ch.IsHole() {
+				continue
+			}
+			if err := chunkErr(ch); err != nil {
+				if checks[ch.SegmentID].err == nil {
+					report.OutOfBoundsChunks++
+				}
+				report.addError(fmt.Errorf("version %s: %w", man.VersionID, err))
+				bad = append(bad, idx)
+				continue
+			}
+			if _, ok := replicas[ch.Hash]; !ok {
+				replicas[ch.Hash] = ch
+			}
+		}
+		if len(bad) > 0 {
+			damaged = append(damaged, damagedManifest{path: path, man: man, bad: bad})
+		}
+	}
+	report.Segments = len(checks)
+
+	for _, dm := range damaged {
+		if opts.Repair && repointChunks(layout, dm.man, dm.bad, replicas) {
+			if err := writeManifestAtomic(dm.path, dm.man); err != nil {
+				report.addError(fmt.Errorf("repair %s: %w", dm.path, err))
+			} else {
+				report.RepointedChunks += len(dm.bad)
+				continue
+			}
+		}
+		report.DamagedVersions++
+		if opts.DryRun || dm.man.VersionID == "" {
+			continue
+		}
+		if err := store.MarkDamaged(ctx, dm.man.VersionID); err != nil {
+			report.addError(fmt.Errorf("mark damaged %s: %w", dm.man.VersionID, err))
+		}
+	}
+
+	report.FinishedAt = now().UTC()
+	if !opts.DryRun {
+		_ = store.RecordOpsRun(ctx, report.Mode, reportOpsFrom(report))
+	}
+	return report, nil
+}
+
+// verifySegment checks a segment's file against its meta row and returns the
+// end of its data area.
+func verifySegment(ctx context.Context, store *meta.Store, layout fs.Layout, id string, report *Report) *segmentCheck {
+	missing := func(err error) *segmentCheck {
+		report.MissingSegments++
+		if len(report.MissingSegmentIDs) < 100 {
+			report.MissingSegmentIDs = append(report.MissingSegmentIDs, id)
+		}
+		return &segmentCheck{err: err}
+	}
+	seg, err := store.GetSegment(ctx, id)
+	if err != nil {
+		return missing(fmt.Errorf("segment %s not recorded in meta: %w", id, err))
+	}
+	path, info, err := layout.LocateSegment(id)
+	if err != nil {
+		return missing(fmt.Errorf("missing segment %s", id))
+	}
+	// Open segments are still growing; their recorded size is stale.
+	if seg.State != string(segment.StateSealed) {
+		return &segmentCheck{dataEnd: info.Size()}
+	}
+	dataEnd := info.Size()
+	if seg.Size > 0 && seg.Size != info.Size() {
+		report.FooterMismatches++
+		return &segmentCheck{err: fmt.Errorf("segment %s size %d does not match recorded %d", id, info.Size(), seg.Size)}
+	}
+	// Segments filled by replication ranges carry no footer and are recorded
+	// without a checksum; only the bounds apply to them.
+	if len(seg.FooterChecksum) == 0 {
+		return &segmentCheck{dataEnd: dataEnd}
+	}
+	footer, err := readSegmentFooter(path)
+	if err != nil {
+		report.FooterMismatches++
+		return &segmentCheck{err: fmt.Errorf("segment %s footer unreadable: %w", id, err)}
+	}
+	sum := segment.FooterChecksum(footer)
+	if footer.ChecksumHash != sum || !bytes.Equal(seg.FooterChecksum, sum[:]) {
+		report.FooterMismatches++
+		return &segmentCheck{err: fmt.Errorf("segment %s footer checksum mismatch", id)}
+	}
+	if footer.BloomOffset > 0 && footer.BloomOffset < dataEnd {
+		dataEnd = footer.BloomOffset
+	} else if dataEnd > segment.FooterLen() {
+		dataEnd -= segment.FooterLen()
+	}
+	return &segmentCheck{dataEnd: dataEnd}
+}
+
+// readSegmentFooter decodes the trailing footer without validating it.
+func readSegmentFooter(path string) (segment.Footer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return segment.Footer{}, err
+	}
+	defer func() { _ = file.Close() }()
+	if _, err := file.Seek(-segment.FooterLen(), io.SeekEnd); err != nil {
+		return segment.Footer{}, err
+	}
+	return segment.DecodeFooter(file)
+}
+
+// repointChunks swaps every bad chunk for a replica whose data still hashes
+// to the chunk hash. It changes the manifest only when all bad chunks have one.
+func repointChunks(layout fs.Layout, man *manifest.Manifest, bad []int, replicas map[[32]byte]manifest.ChunkRef) bool {
+	repl := make(map[int]manifest.ChunkRef, len(bad))
+	for _, idx := range bad {
+		ch := man.Chunks[idx]
+		replica, ok := replicas[ch.Hash]
+		if !ok || replica.Len != ch.Len || !chunkHashMatches(layout, replica) {
+			return false
+		}
+		repl[idx] = replica
+	}
+	for idx, replica := range repl {
+		man.Chunks[idx].SegmentID = replica.SegmentID
+		man.Chunks[idx].Offset = replica.Offset
+	}
+	return true
+}
+
+func chunkHashMatches(layout fs.Layout, ch manifest.ChunkRef) bool {
+	file, err := layout.OpenSegment(ch.SegmentID)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = file.Close() }()
+	buf := make([]byte, ch.Len)
+	if _, err := file.ReadAt(buf, ch.Offset); err != nil {
+		return false
+	}
+	return segment.HashChunk(buf) == ch.Hash
+}
+
+func readManifestFile(path string) (*manifest.Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return (&manifest.BinaryCodec{}).Decode(file)
+}
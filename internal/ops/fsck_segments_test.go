@@ -0,0 +1,193 @@
+package ops
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+	"github.com/kk-code-lab/seglake/internal/storage/segment"
+)
+
+// setupFsckSegments stores k1 and k2 with identical content in two sealed
+// segments, then corrupts the footer of k1's segment.
+func setupFsckSegments(t *testing.T) (fs.Layout, string, *manifest.Manifest) {
+	t.Helper()
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "data"))
+	metaPath := filepath.Join(layout.Root, "meta.db")
+	if err := os.MkdirAll(layout.Root, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	eng, err := engine.New(engine.Options{
+		Layout:          layout,
+		MetaStore:       store,
+		SegmentMaxBytes: 64,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	data := bytes.Repeat([]byte("a"), 100)
+	var first *manifest.Manifest
+	for _, key := range []string{"k1", "k2", "k3"} {
+		if key == "k3" {
+			data = bytes.Repeat([]byte("b"), 100)
+		}
+		man, _, err := eng.PutObject(context.Background(), "bucket", key, "", bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("PutObject %s: %v", key, err)
+		}
+		if first == nil {
+			first = man
+		}
+	}
+	segPath := layout.SegmentPath(first.Chunks[0].SegmentID)
+	f, err := os.OpenFile(segPath, os.O_RDWR, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if _, err := f.WriteAt([]byte{0xFF, 0xFF}, info.Size()-segment.FooterLen()+8); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return layout, metaPath, first
+}
+
+func objectState(t *testing.T, metaPath, key string) string {
+	t.Helper()
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	obj, err := store.GetObjectMeta(context.Background(), "bucket", key)
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	return obj.State
+}
+
+func TestFsckSegmentsReportsAndMarksDamaged(t *testing.T) {
+	layout, metaPath, _ := setupFsckSegments(t)
+
+	report, err := FsckSegments(layout, metaPath, FsckSegmentsOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("FsckSegments dry run: %v", err)
+	}
+	if report.FooterMismatches != 1 || report.DamagedVersions != 1 {
+		t.Fatalf("expected 1 footer mismatch and 1 damaged version, got %d/%d", report.FooterMismatches, report.DamagedVersions)
+	}
+	if state := objectState(t, metaPath, "k1"); state != meta.VersionStateActive {
+		t.Fatalf("dry run changed state to %s", state)
+	}
+
+	if _, err := FsckSegments(layout, metaPath, FsckSegmentsOptions{}); err != nil {
+		t.Fatalf("FsckSegments: %v", err)
+	}
+	if state := objectState(t, metaPath, "k1"); state != meta.VersionStateDamaged {
+		t.Fatalf("expected k1 DAMAGED, got %s", state)
+	}
+	if state := objectState(t, metaPath, "k2"); state != meta.VersionStateActive {
+		t.Fatalf("expected k2 ACTIVE, got %s", state)
+	}
+}
+
+func TestFsckSegmentsRepairRepointsToDuplicateChunk(t *testing.T) {
+	layout, metaPath, first := setupFsckSegments(t)
+	badSegment := first.Chunks[0].SegmentID
+
+	if _, err := FsckSegments(layout, metaPath, FsckSegmentsOptions{DryRun: true, Repair: true}); err == nil {
+		t.Fatalf("expected error for repair with dry run")
+	}
+	report, err := FsckSegments(layout, metaPath, FsckSegmentsOptions{Repair: true})
+	if err != nil {
+		t.Fatalf("FsckSegments: %v", err)
+	}
+	if report.RepointedChunks != 1 || report.DamagedVersions != 0 {
+		t.Fatalf("expected 1 repointed chunk and no damage, got %d/%d", report.RepointedChunks, report.DamagedVersions)
+	}
+	if state := objectState(t, metaPath, "k1"); state != meta.VersionStateActive {
+		t.Fatalf("expected k1 ACTIVE, got %s", state)
+	}
+
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	paths, err := store.ListLiveManifestPaths(context.Background())
+	if err != nil {
+		t.Fatalf("ListLiveManifestPaths: %v", err)
+	}
+	for _, path := range paths {
+		man, err := readManifestFile(path)
+		if err != nil {
+			t.Fatalf("readManifestFile: %v", err)
+		}
+		for _, ch := range man.Chunks {
+			if ch.SegmentID == badSegment {
+				t.Fatalf("manifest %s still references %s", path, badSegment)
+			}
+		}
+	}
+}
+
+func TestFsckSegmentsFindsStagedSegments(t *testing.T) {
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "data"))
+	layout.StagingDir = filepath.Join(dir, "staging")
+	metaPath := filepath.Join(layout.Root, "meta.db")
+	if err := os.MkdirAll(layout.Root, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	eng, err := engine.New(engine.Options{
+		Layout:     layout,
+		MetaStore:  store,
+		StagingDir: layout.StagingDir,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	// The segment stays open, so it is only in the staging dir.
+	if _, _, err := eng.PutObject(context.Background(), "bucket", "k1", "", bytes.NewReader([]byte("staged"))); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	_ = eng.Close()
+	_ = store.Close()
+
+	report, err := FsckSegments(layout, metaPath, FsckSegmentsOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("FsckSegments: %v", err)
+	}
+	if report.Errors != 0 || report.MissingSegments != 0 || report.DamagedVersions != 0 {
+		t.Fatalf("expected clean report, got %+v", report)
+	}
+	// fsck reads footers, which open segments lack; it must still find them.
+	report, err = Fsck(layout, metaPath, true)
+	if err != nil {
+		t.Fatalf("Fsck: %v", err)
+	}
+	if report.MissingSegments != 0 {
+		t.Fatalf("fsck reported staged segment missing: %v", report.ErrorSample)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
@@ -38,22 +40,20 @@ func GCRewritePlanBuild(layout fs.Layout, metaPath string, minAge time.Duration,
 	if liveThreshold <= 0 || liveThreshold > 1 {
 		return nil, nil, errors.New("gc: live threshold must be (0,1]")
 	}
-	report := newReport("gc-rewrite-plan")
 	store, err := meta.Open(metaPath)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer func() { _ = store.Close() }()
+	return buildGCRewritePlan(context.Background(), store, minAge, liveThreshold, nil)
+}
 
-	livePaths, err := store.ListLiveManifestPaths(context.Background())
-	if err != nil {
-		return nil, nil, err
-	}
-	mpuPaths, err := store.ListMultipartPartManifestPaths(context.Background())
+func buildGCRewritePlan(ctx context.Context, store *meta.Store, minAge time.Duration, liveThreshold float64, skip func(segmentID string) bool) (*GCRewritePlan, *Report, error) {
+	report := newReport("gc-rewrite-plan")
+	livePaths, err := gcRewriteManifestPaths(ctx, store)
 	if err != nil {
 		return nil, nil, err
 	}
-	livePaths = mergeUniquePaths(livePaths, mpuPaths)
 	report.Manifests = len(livePaths)
 
 	liveBytes := make(map[string]int64)
@@ -68,11 +68,14 @@ func GCRewritePlanBuild(layout fs.Layout, metaPath string, minAge time.Duration,
 			continue
 		}
 		for _, ch := range man.Chunks {
+			if ch.IsHole() {
+				continue
+			}
 			liveBytes[ch.SegmentID] += int64(ch.Len)
 		}
 	}
 
-	segments, err := store.ListSegments(context.Background())
+	segments, err := store.ListSegments(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -94,6 +97,9 @@ func GCRewritePlanBuild(layout fs.Layout, metaPath string, minAge time.Duration,
 		if seg.Size <= 0 || live == 0 {
 			continue
 		}
+		if skip != nil && skip(seg.ID) {
+			continue
+		}
 		ratio := float64(live) / float64(seg.Size)
 		if ratio <= liveThreshold {
 			candidates = append(candidates, GCRewriteCandidate{
@@ -119,6 +125,18 @@ func GCRewritePlanBuild(layout fs.Layout, metaPath string, minAge time.Duration,
 	return plan, report, nil
 }
 
+func gcRewriteManifestPaths(ctx context.Context, store *meta.Store) ([]string, error) {
+	livePaths, err := store.ListLiveManifestPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mpuPaths, err := store.ListMultipartPartManifestPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeUniquePaths(livePaths, mpuPaths), nil
+}
+
 // WriteGCRewritePlan writes plan JSON to a file.
 func WriteGCRewritePlan(path string, plan *GCRewritePlan) error {
 	if plan == nil || path == "" {
@@ -148,16 +166,18 @@ func ReadGCRewritePlan(path string) (*GCRewritePlan, error) {
 }
 
 // GCRewrite compacts partially-dead segments by rewriting live chunks into new segments.
-func GCRewrite(layout fs.Layout, metaPath string, minAge time.Duration, liveThreshold float64, force bool, throttleBps int64, pauseFile string) (*Report, error) {
+// maxReads bounds concurrent chunk reads from source segments (<= 0 means 1);
+// throttleBps caps their combined rate.
+func GCRewrite(layout fs.Layout, metaPath string, minAge time.Duration, liveThreshold float64, force bool, throttleBps int64, maxReads int, pauseFile string) (*Report, error) {
 	plan, _, err := GCRewritePlanBuild(layout, metaPath, minAge, liveThreshold)
 	if err != nil {
 		return nil, err
 	}
-	return GCRewriteFromPlan(layout, metaPath, plan, force, throttleBps, pauseFile)
+	return GCRewriteFromPlan(layout, metaPath, plan, force, throttleBps, maxReads, pauseFile)
 }
 
 // GCRewriteFromPlan executes a rewrite using the provided plan.
-func GCRewriteFromPlan(layout fs.Layout, metaPath string, plan *GCRewritePlan, force bool, throttleBps int64, pauseFile string) (*Report, error) {
+func GCRewriteFromPlan(layout fs.Layout, metaPath string, plan *GCRewritePlan, force bool, throttleBps int64, maxReads int, pauseFile string) (*Report, error) {
 	if !force {
 		return nil, errors.New("gc: refuse to run without --force")
 	}
@@ -172,15 +192,62 @@ func GCRewriteFromPlan(layout fs.Layout, metaPath string, plan *GCRewritePlan, f
 	}
 	defer func() { _ = store.Close() }()
 
-	livePaths, err := store.ListLiveManifestPaths(context.Background())
+	if err := runGCRewrite(context.Background(), layout, store, plan, report, throttleBps, maxReads, pauseFile, nil); err != nil {
+		return report, err
+	}
+	_ = store.RecordOpsRun(context.Background(), report.Mode, reportOpsFrom(report))
+	return report, nil
+}
+
+// SegmentGuard protects segments referenced by in-flight reads, and blocks
+// new references to segments that are about to be removed.
+type SegmentGuard interface {
+	SegmentPinned(segmentID string) bool
+	RemoveSegmentIfUnpinned(segmentID string, remove func() error) (bool, error)
+	RetireSegments(segmentIDs []string)
+	ReleaseSegments(segmentIDs []string)
+}
+
+// GCRewriteOnline compacts partially-dead segments through an open store while
+// the server keeps serving; segments pinned by readers are left for a later pass.
+func GCRewriteOnline(ctx context.Context, layout fs.Layout, store *meta.Store, minAge time.Duration, liveThreshold float64, throttleBps int64, maxReads int, pauseFile string, guard SegmentGuard) (*Report, error) {
+	if liveThreshold <= 0 || liveThreshold > 1 {
+		return nil, errors.New("gc: live threshold must be (0,1]")
+	}
+	if store == nil || guard == nil {
+		return nil, errors.New("gc: store and segment guard required")
+	}
+	plan, _, err := buildGCRewritePlan(ctx, store, minAge, liveThreshold, guard.SegmentPinned)
 	if err != nil {
 		return nil, err
 	}
+	report := newReport("gc-rewrite-online")
+	report.Candidates = len(plan.Candidates)
+	if len(plan.Candidates) == 0 {
+		report.FinishedAt = now().UTC()
+		return report, nil
+	}
+	if err := runGCRewrite(ctx, layout, store, plan, report, throttleBps, maxReads, pauseFile, guard); err != nil {
+		report.Errors++
+		report.ErrorSample = append(report.ErrorSample, err.Error())
+		report.FinishedAt = now().UTC()
+		_ = store.RecordOpsRun(context.Background(), report.Mode, reportOpsFrom(report))
+		return report, err
+	}
+	_ = store.RecordOpsRun(context.Background(), report.Mode, reportOpsFrom(report))
+	return report, nil
+}
+
+func runGCRewrite(ctx context.Context, layout fs.Layout, store *meta.Store, plan *GCRewritePlan, report *Report, throttleBps int64, maxReads int, pauseFile string, guard SegmentGuard) error {
+	livePaths, err := gcRewriteManifestPaths(ctx, store)
+	if err != nil {
+		return err
+	}
 	report.Manifests = len(livePaths)
 
 	rewriteCandidates := make(map[string]meta.Segment)
 	for _, cand := range plan.Candidates {
-		seg, err := store.GetSegment(context.Background(), cand.ID)
+		seg, err := store.GetSegment(ctx, cand.ID)
 		if err != nil {
 			report.Errors++
 			continue
@@ -192,36 +259,113 @@ func GCRewriteFromPlan(layout fs.Layout, metaPath string, plan *GCRewritePlan, f
 		report.CandidateIDs = append(report.CandidateIDs, cand.ID)
 	}
 
-	if len(rewriteCandidates) > 0 {
-		if err := rewriteSegments(layout, store, livePaths, rewriteCandidates, report, throttleBps, pauseFile); err != nil {
-			return report, err
+	if len(rewriteCandidates) == 0 {
+		report.FinishedAt = now().UTC()
+		return nil
+	}
+	if err := rewriteSegments(ctx, layout, store, livePaths, rewriteCandidates, report, throttleBps, maxReads, pauseFile); err != nil {
+		return err
+	}
+
+	// Writes that committed after livePaths was listed (MPU complete, move,
+	// append, restore) may reference candidates. Block new references, then
+	// rescan and keep every candidate still referenced.
+	ids := make([]string, 0, len(rewriteCandidates))
+	for id := range rewriteCandidates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if guard != nil {
+		guard.RetireSegments(ids)
+	}
+	kept := make([]string, 0, len(ids))
+	defer func() {
+		if guard != nil {
+			guard.ReleaseSegments(kept)
 		}
+	}()
+	referenced, err := gcReferencedSegments(ctx, store, rewriteCandidates)
+	if err != nil {
+		kept = ids
+		return err
 	}
 
-	for _, seg := range rewriteCandidates {
-		if err := os.Remove(seg.Path); err != nil {
+	for _, id := range ids {
+		seg := rewriteCandidates[id]
+		if referenced[id] {
+			kept = append(kept, id)
+			report.addWarning(fmt.Sprintf("gc: segment %s referenced by a newer manifest; left for the next pass", id))
+			continue
+		}
+		remove := func() error {
+			if err := os.Remove(seg.Path); err != nil {
+				return err
+			}
+			_ = store.DeleteSegment(ctx, seg.ID)
+			return nil
+		}
+		removed := true
+		if guard != nil {
+			removed, err = guard.RemoveSegmentIfUnpinned(seg.ID, remove)
+		} else {
+			err = remove()
+		}
+		if err != nil {
+			kept = append(kept, id)
 			report.Errors++
 			continue
 		}
-		_ = store.DeleteSegment(context.Background(), seg.ID)
+		if !removed {
+			kept = append(kept, id)
+			report.addWarning(fmt.Sprintf("gc: segment %s in use; left for gc-run", seg.ID))
+			continue
+		}
 		report.Deleted++
 		report.Reclaimed += seg.Size
 	}
 
 	report.FinishedAt = now().UTC()
-	_ = store.RecordOpsRun(context.Background(), report.Mode, reportOpsFrom(report))
-	return report, nil
+	return nil
+}
+
+// gcReferencedSegments lists the manifests live now and returns the
+// candidates any of them still references. An unreadable manifest is an
+// error: it cannot prove that its segments are unreferenced.
+func gcReferencedSegments(ctx context.Context, store *meta.Store, candidates map[string]meta.Segment) (map[string]bool, error) {
+	paths, err := gcRewriteManifestPaths(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		man, err := (&manifest.BinaryCodec{}).Decode(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("gc: manifest %s: %w", path, err)
+		}
+		for _, ch := range man.Chunks {
+			if _, ok := candidates[ch.SegmentID]; ok {
+				referenced[ch.SegmentID] = true
+			}
+		}
+	}
+	return referenced, nil
 }
 
 type gcWriter struct {
-	layout    fs.Layout
-	writer    *segment.Writer
-	id        string
-	size      int64
-	closed    bool
-	throttle  *gcThrottle
-	pauseFile string
-	entries   []segment.IndexEntry
+	layout  fs.Layout
+	writer  *segment.Writer
+	id      string
+	size    int64
+	closed  bool
+	entries []segment.IndexEntry
 }
 
 func (w *gcWriter) ensure() error {
@@ -248,14 +392,6 @@ func (w *gcWriter) append(hash [32]byte, data []byte) (string, int64, error) {
 	if err := w.ensure(); err != nil {
 		return "", 0, err
 	}
-	if w.pauseFile != "" {
-		for {
-			if _, err := os.Stat(w.pauseFile); err != nil {
-				break
-			}
-			time.Sleep(200 * time.Millisecond)
-		}
-	}
 	if w.size+int64(len(data))+segment.RecordHeaderLen() > gcRewriteMaxSegmentBytes {
 		if err := w.seal(); err != nil {
 			return "", 0, err
@@ -270,9 +406,6 @@ func (w *gcWriter) append(hash [32]byte, data []byte) (string, int64, error) {
 	}
 	w.size += int64(len(data)) + segment.RecordHeaderLen()
 	w.entries = append(w.entries, segment.IndexEntry{Offset: offset, Hash: hash})
-	if w.throttle != nil {
-		w.throttle.wait(int64(len(data)))
-	}
 	return w.id, offset, nil
 }
 
@@ -296,17 +429,130 @@ func (w *gcWriter) seal() error {
 	return nil
 }
 
-func rewriteSegments(layout fs.Layout, store *meta.Store, livePaths []string, candidates map[string]meta.Segment, report *Report, throttleBps int64, pauseFile string) error {
+// waitGCPause blocks while pauseFile exists. It is checked before each batch
+// of reads, so pausing lets in-flight reads finish but starts no new ones.
+func waitGCPause(ctx context.Context, pauseFile string) error {
+	if pauseFile == "" {
+		return nil
+	}
+	for {
+		if _, err := os.Stat(pauseFile); err != nil {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// readGCChunks reads chunks concurrently, one goroutine per chunk; callers
+// bound len(chunks) by the read concurrency.
+func readGCChunks(sourceFiles map[string]*os.File, chunks []manifest.ChunkRef, throttle *gcThrottle) ([][]byte, error) {
+	out := make([][]byte, len(chunks))
+	read := func(i int) error {
+		ch := chunks[i]
+		data := make([]byte, ch.Len)
+		if _, err := sourceFiles[ch.SegmentID].ReadAt(data, ch.Offset); err != nil && err != io.EOF {
+			return err
+		}
+		throttle.wait(int64(len(data)))
+		out[i] = data
+		return nil
+	}
+	if len(chunks) == 1 {
+		return out, read(0)
+	}
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i := range chunks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = read(i)
+		}(i)
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
+}
+
+// gcRewriteJob is a live manifest with chunks in candidate segments; it is
+// written back once its last pending chunk has moved.
+type gcRewriteJob struct {
+	path    string
+	man     *manifest.Manifest
+	pending int
+}
+
+type gcPendingChunk struct {
+	job *gcRewriteJob
+	idx int
+}
+
+func rewriteSegments(ctx context.Context, layout fs.Layout, store *meta.Store, livePaths []string, candidates map[string]meta.Segment, report *Report, throttleBps int64, maxReads int, pauseFile string) error {
 	sourceFiles := make(map[string]*os.File)
 	defer func() {
 		for _, f := range sourceFiles {
 			_ = f.Close()
 		}
 	}()
-	writer := &gcWriter{layout: layout, throttle: newGCThrottle(throttleBps), pauseFile: pauseFile}
+	if maxReads <= 0 {
+		maxReads = 1
+	}
+	started := now()
+	defer func() {
+		if elapsed := now().Sub(started); elapsed > 0 {
+			report.RewriteBytesPerSec = int64(float64(report.RewrittenBytes) / elapsed.Seconds())
+		}
+	}()
+	throttle := newGCThrottle(throttleBps)
+	writer := &gcWriter{layout: layout}
 	newSegments := make(map[string]int64)
+	// Chunks are batched across manifests, so small manifests still get
+	// maxReads parallel reads.
+	var batch []gcPendingChunk
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		refs := make([]manifest.ChunkRef, len(batch))
+		for i, c := range batch {
+			refs[i] = c.job.man.Chunks[c.idx]
+		}
+		datas, err := readGCChunks(sourceFiles, refs, throttle)
+		if err != nil {
+			return err
+		}
+		// Append in batch order so the new segment layout stays sequential.
+		for i, c := range batch {
+			ch := &c.job.man.Chunks[c.idx]
+			newSegID, newOffset, err := writer.append(ch.Hash, datas[i])
+			if err != nil {
+				return err
+			}
+			ch.SegmentID = newSegID
+			ch.Offset = newOffset
+			newSegments[newSegID] = 0
+			report.RewrittenBytes += int64(len(datas[i]))
+			if c.job.pending--; c.job.pending == 0 {
+				if err := writeManifestAtomic(c.job.path, c.job.man); err != nil {
+					return err
+				}
+				report.RewrittenSegments++
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+	// On cancellation stop between batches, but still seal and record the
+	// segments already referenced by rewritten manifests.
+	var stopErr error
 
 	for _, path := range livePaths {
+		if err := ctx.Err(); err != nil {
+			stopErr = err
+			break
+		}
 		file, err := os.Open(path)
 		if err != nil {
 			continue
@@ -316,48 +562,51 @@ func rewriteSegments(layout fs.Layout, store *meta.Store, livePaths []string, ca
 		if err != nil {
 			continue
 		}
-		changed := false
+		var pending []int
 		for idx, ch := range man.Chunks {
 			if _, ok := candidates[ch.SegmentID]; !ok {
 				continue
 			}
-			sf := sourceFiles[ch.SegmentID]
-			if sf == nil {
-				segPath := layout.SegmentPath(ch.SegmentID)
-				f, err := os.Open(segPath)
+			if sourceFiles[ch.SegmentID] == nil {
+				f, err := layout.OpenSegment(ch.SegmentID)
 				if err != nil {
 					return err
 				}
 				sourceFiles[ch.SegmentID] = f
-				sf = f
 			}
-			data := make([]byte, ch.Len)
-			if _, err := sf.ReadAt(data, ch.Offset); err != nil && err != io.EOF {
-				return err
+			pending = append(pending, idx)
+		}
+		job := &gcRewriteJob{path: path, man: man, pending: len(pending)}
+		for _, idx := range pending {
+			batch = append(batch, gcPendingChunk{job: job, idx: idx})
+			if len(batch) < maxReads {
+				continue
 			}
-			newSegID, newOffset, err := writer.append(ch.Hash, data)
-			if err != nil {
+			if err := waitGCPause(ctx, pauseFile); err != nil {
+				stopErr = err
+				break
+			}
+			if err := flush(); err != nil {
 				return err
 			}
-			man.Chunks[idx].SegmentID = newSegID
-			man.Chunks[idx].Offset = newOffset
-			newSegments[newSegID] = 0
-			report.RewrittenBytes += int64(ch.Len)
-			changed = true
 		}
-		if !changed {
-			continue
+		if stopErr != nil {
+			break
 		}
-		if err := writeManifestAtomic(path, man); err != nil {
+	}
+	if stopErr == nil && len(batch) > 0 {
+		if err := waitGCPause(ctx, pauseFile); err != nil {
+			stopErr = err
+		} else if err := flush(); err != nil {
 			return err
 		}
-		report.RewrittenSegments++
 	}
 
 	if err := writer.seal(); err != nil {
 		return err
 	}
 
+	recordCtx := context.WithoutCancel(ctx)
 	for id := range newSegments {
 		path := layout.SegmentPath(id)
 		info, err := os.Stat(path)
@@ -373,12 +622,12 @@ func rewriteSegments(layout fs.Layout, store *meta.Store, livePaths []string, ca
 		if err != nil {
 			return err
 		}
-		if err := store.RecordSegment(context.Background(), id, path, string(segment.StateSealed), info.Size(), footer.ChecksumHash[:]); err != nil {
+		if err := store.RecordSegment(recordCtx, id, path, string(segment.StateSealed), info.Size(), footer.ChecksumHash[:]); err != nil {
 			return err
 		}
 		report.NewSegments++
 	}
-	return nil
+	return stopErr
 }
 
 func writeManifestAtomic(path string, man *manifest.Manifest) error {
@@ -413,36 +662,35 @@ func newID() (string, error) {
 	return hex.EncodeToString(buf[:]), nil
 }
 
+// gcThrottle is shared by concurrent readers. Each wait reserves its bytes
+// after the earlier reservations under mu and sleeps without it, so the cap
+// applies to the combined rate while readers still overlap.
 type gcThrottle struct {
+	mu          sync.Mutex
 	bytesPerSec int64
-	last        time.Time
-	accum       int64
+	next        time.Time
 }
 
 func newGCThrottle(bps int64) *gcThrottle {
 	if bps <= 0 {
 		return nil
 	}
-	return &gcThrottle{bytesPerSec: bps, last: now()}
+	return &gcThrottle{bytesPerSec: bps, next: now()}
 }
 
 func (t *gcThrottle) wait(n int64) {
 	if t == nil || n <= 0 {
 		return
 	}
-	t.accum += n
-	elapsed := time.Since(t.last)
-	if elapsed <= 0 {
-		return
-	}
-	allowed := int64(float64(t.bytesPerSec) * elapsed.Seconds())
-	if t.accum <= allowed {
-		return
+	t.mu.Lock()
+	current := now()
+	if t.next.Before(current) {
+		t.next = current
 	}
-	sleepFor := time.Duration(float64(t.accum-allowed)/float64(t.bytesPerSec)) * time.Second
+	t.next = t.next.Add(time.Duration(float64(n) / float64(t.bytesPerSec) * float64(time.Second)))
+	sleepFor := t.next.Sub(current)
+	t.mu.Unlock()
 	if sleepFor > 0 {
 		time.Sleep(sleepFor)
 	}
-	t.last = now()
-	t.accum = 0
 }
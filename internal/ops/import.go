@@ -0,0 +1,214 @@
+package ops
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	segfs "github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+const defaultImportWorkers = 4
+
+// ImportOptions configures a directory import.
+type ImportOptions struct {
+	Bucket string
+	// Prefix is prepended to the slash-separated path of each file.
+	Prefix string
+	Src    string
+	// Metadata is an optional JSON-lines sidecar (the export format); keys are
+	// paths relative to Src and content_type overrides the extension guess.
+	Metadata string
+	Workers  int
+	SiteID   string
+}
+
+type importJob struct {
+	path string
+	key  string
+	size int64
+}
+
+// Import walks a directory and stores each regular file as an object through
+// the engine. Every put is recorded in the oplog, so imported data replicates
+// like regular PUTs. Files whose current object already has the same size and
+// ETag are skipped.
+func Import(layout segfs.Layout, metaPath string, opts ImportOptions) (*Report, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("ops: import bucket required")
+	}
+	if opts.Src == "" {
+		return nil, errors.New("ops: import source required")
+	}
+	info, err := os.Stat(opts.Src)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("ops: import source %s is not a directory", opts.Src)
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultImportWorkers
+	}
+	contentTypes, err := loadImportMetadata(opts.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = store.Close() }()
+	store.SetSiteID(opts.SiteID)
+	ctx := context.Background()
+	exists, err := store.BucketExists(ctx, opts.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("ops: bucket %s not found", opts.Bucket)
+	}
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store})
+	if err != nil {
+		return nil, err
+	}
+
+	report := newReport("import")
+	var mu sync.Mutex
+	jobs := make(chan importJob)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				contentType := contentTypes[strings.TrimPrefix(job.key, opts.Prefix)]
+				if contentType == "" {
+					contentType = mime.TypeByExtension(filepath.Ext(job.path))
+				}
+				imported, err := importFile(ctx, eng, store, opts.Bucket, job, contentType)
+				mu.Lock()
+				switch {
+				case err != nil:
+					report.addError(fmt.Errorf("import: %s: %w", job.path, err))
+				case imported:
+					report.Imported++
+					report.ImportedBytes += job.size
+				default:
+					report.Skipped++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	walkErr := filepath.WalkDir(opts.Src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(opts.Src, path)
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			mu.Lock()
+			report.addWarning(fmt.Sprintf("import: skipped %s (not a regular file)", rel))
+			mu.Unlock()
+			return nil
+		}
+		fileInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		jobs <- importJob{path: path, key: opts.Prefix + filepath.ToSlash(rel), size: fileInfo.Size()}
+		return nil
+	})
+	close(jobs)
+	wg.Wait()
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	report.FinishedAt = now().UTC()
+	return report, nil
+}
+
+// importFile stores one file unless the current object already matches it.
+func importFile(ctx context.Context, eng *engine.Engine, store *meta.Store, bucket string, job importJob, contentType string) (bool, error) {
+	file, err := os.Open(job.path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = file.Close() }()
+	current, err := store.GetObjectMeta(ctx, bucket, job.key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if current != nil && current.State == meta.VersionStateActive && current.Size == job.size {
+		hasher := md5.New()
+		if _, err := io.Copy(hasher, file); err != nil {
+			return false, err
+		}
+		if hex.EncodeToString(hasher.Sum(nil)) == current.ETag {
+			return false, nil
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+	}
+	if _, _, err := eng.PutObject(ctx, bucket, job.key, contentType, file); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// loadImportMetadata reads content types from an export-style sidecar.
+func loadImportMetadata(path string) (map[string]string, error) {
+	out := make(map[string]string)
+	if path == "" {
+		return out, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry ExportEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("ops: import metadata %s:%d: %w", path, line, err)
+		}
+		if entry.Key != "" && entry.ContentType != "" {
+			out[entry.Key] = entry.ContentType
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,110 @@
+package ops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func TestImportDirectorySkipsUnchangedAndWritesOplog(t *testing.T) {
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "objects"))
+	metaPath := filepath.Join(dir, "meta.db")
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	if err := store.CreateBucket(context.Background(), "bucket"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	_ = store.Close()
+
+	src := filepath.Join(dir, "src")
+	files := map[string]string{
+		"index.html":      "<html></html>",
+		"data/blob.bin":   "binary",
+		"data/nested/a.x": "x",
+	}
+	for name, body := range files {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	sidecar := filepath.Join(dir, "meta.jsonl")
+	if err := os.WriteFile(sidecar, []byte(`{"key":"data/blob.bin","content_type":"application/x-custom"}`+"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile sidecar: %v", err)
+	}
+
+	opts := ImportOptions{Bucket: "bucket", Prefix: "in/", Src: src, Metadata: sidecar, Workers: 2, SiteID: "site-a"}
+	report, err := Import(layout, metaPath, opts)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Imported != 3 || report.Skipped != 0 || report.Errors != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	if err := os.WriteFile(filepath.Join(src, "index.html"), []byte("<html>v2</html>"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	report, err = Import(layout, metaPath, opts)
+	if err != nil {
+		t.Fatalf("Import rerun: %v", err)
+	}
+	if report.Imported != 1 || report.Skipped != 2 {
+		t.Fatalf("unexpected rerun report: %+v", report)
+	}
+
+	store, err = meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	blob, err := store.GetObjectMeta(ctx, "bucket", "in/data/blob.bin")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if blob.ContentType != "application/x-custom" {
+		t.Fatalf("sidecar content type: %q", blob.ContentType)
+	}
+	index, err := store.GetObjectMeta(ctx, "bucket", "in/index.html")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if index.Size != int64(len("<html>v2</html>")) || index.ContentType != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected index meta: %+v", index)
+	}
+	entries, err := store.ListOplog(ctx)
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	puts := 0
+	for _, entry := range entries {
+		if entry.OpType == "put" && entry.Bucket == "bucket" {
+			if entry.SiteID != "site-a" {
+				t.Fatalf("oplog site id: %q", entry.SiteID)
+			}
+			puts++
+		}
+	}
+	if puts != 4 {
+		t.Fatalf("expected 4 put oplog entries, got %d", puts)
+	}
+}
+
+func TestImportRequiresExistingBucket(t *testing.T) {
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "objects"))
+	if _, err := Import(layout, filepath.Join(dir, "meta.db"), ImportOptions{Bucket: "missing", Src: dir}); err == nil {
+		t.Fatalf("expected error for missing bucket")
+	}
+}
@@ -0,0 +1,332 @@
+package ops
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+const (
+	InventoryFormatCSV   = "csv"
+	InventoryFormatJSONL = "jsonl"
+
+	// InventoryManifestName is the file in the output directory listing the parts.
+	InventoryManifestName = "manifest.json"
+	// inventoryStorageClass is reported for objects without a recorded class.
+	inventoryStorageClass   = meta.StorageClassStandard
+	inventoryPageSize       = 1000
+	defaultInventoryPartMax = 100000
+)
+
+// inventoryCSVHeader is the first row of every CSV part.
+var inventoryCSVHeader = []string{"key", "size", "etag", "last_modified", "storage_class"}
+
+// InventoryOptions configures a bucket inventory.
+type InventoryOptions struct {
+	Bucket string
+	Prefix string
+	// Out is the output directory for the parts and manifest.json.
+	Out    string
+	Format string
+	// PartObjects caps the objects per part file (0 = 100000).
+	PartObjects int
+	// Resume continues an interrupted inventory after its last complete part.
+	Resume bool
+}
+
+// InventoryEntry is one JSON line of an inventory part.
+type InventoryEntry struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+	StorageClass string `json:"storage_class"`
+}
+
+// InventoryManifest lists the parts of an inventory (<out>/manifest.json).
+type InventoryManifest struct {
+	Bucket    string          `json:"bucket"`
+	Prefix    string          `json:"prefix,omitempty"`
+	Format    string          `json:"format"`
+	StartedAt time.Time       `json:"started_at"`
+	Completed bool            `json:"completed"`
+	Objects   int             `json:"objects"`
+	Parts     []InventoryPart `json:"parts"`
+	// BucketTags are the bucket's cost-allocation tags as of the last run.
+	BucketTags []meta.BucketTag `json:"bucket_tags,omitempty"`
+}
+
+// InventoryPart describes one finished part file.
+type InventoryPart struct {
+	File    string `json:"file"`
+	Objects int    `json:"objects"`
+	LastKey string `json:"last_key"`
+	MD5     string `json:"md5"`
+}
+
+// Inventory writes the current objects of a bucket (key, size, ETag,
+// last-modified, storage class) as CSV or JSON-lines part files plus a
+// manifest. Pages are streamed straight to the parts; the manifest is
+// rewritten after every finished part, so Resume restarts after the last one.
+// meta.db is opened read-only.
+func Inventory(metaPath string, opts InventoryOptions) (*Report, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("ops: inventory bucket required")
+	}
+	if opts.Out == "" {
+		return nil, errors.New("ops: inventory output required")
+	}
+	if opts.Format == "" {
+		opts.Format = InventoryFormatCSV
+	}
+	if opts.Format != InventoryFormatCSV && opts.Format != InventoryFormatJSONL {
+		return nil, fmt.Errorf("ops: invalid inventory format %q", opts.Format)
+	}
+	if opts.PartObjects <= 0 {
+		opts.PartObjects = defaultInventoryPartMax
+	}
+	store, err := meta.OpenReadOnly(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = store.Close() }()
+
+	report := newReport("inventory")
+	manifest, err := loadInventoryManifest(opts)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Completed {
+		report.addWarning("inventory: " + opts.Out + " is already complete")
+		report.InventoryObjects = manifest.Objects
+		report.InventoryParts = len(manifest.Parts)
+		report.FinishedAt = now().UTC()
+		return report, nil
+	}
+	if err := os.MkdirAll(opts.Out, 0o755); err != nil {
+		return nil, err
+	}
+	report.Skipped = manifest.Objects
+
+	ctx := context.Background()
+	if manifest.BucketTags, err = store.GetBucketTags(ctx, opts.Bucket); err != nil {
+		return nil, err
+	}
+	afterKey := ""
+	if n := len(manifest.Parts); n > 0 {
+		afterKey = manifest.Parts[n-1].LastKey
+	}
+	var part *inventoryPartWriter
+	for {
+		objs, err := store.ListObjects(ctx, opts.Bucket, opts.Prefix, afterKey, "", inventoryPageSize, now())
+		if err != nil {
+			if part != nil {
+				_ = part.abort()
+			}
+			return nil, err
+		}
+		for _, obj := range objs {
+			afterKey = obj.Key
+			// LIKE matches ASCII case-insensitively; drop keys outside the prefix.
+			if !strings.HasPrefix(obj.Key, opts.Prefix) {
+				continue
+			}
+			if part == nil {
+				name := fmt.Sprintf("part-%05d.%s", len(manifest.Parts), opts.Format)
+				if part, err = createInventoryPart(filepath.Join(opts.Out, name), opts.Format); err != nil {
+					return nil, err
+				}
+			}
+			if err := part.write(obj); err != nil {
+				_ = part.abort()
+				return nil, fmt.Errorf("ops: inventory %s: %w", obj.Key, err)
+			}
+			report.InventoryObjects++
+			if part.objects >= opts.PartObjects {
+				if err := finishInventoryPart(opts.Out, manifest, part); err != nil {
+					return nil, err
+				}
+				part = nil
+			}
+		}
+		if len(objs) < inventoryPageSize {
+			break
+		}
+	}
+	if part != nil {
+		if err := finishInventoryPart(opts.Out, manifest, part); err != nil {
+			return nil, err
+		}
+	}
+	manifest.Completed = true
+	if err := writeInventoryManifest(opts.Out, manifest); err != nil {
+		return nil, err
+	}
+	report.InventoryParts = len(manifest.Parts)
+	report.FinishedAt = now().UTC()
+	return report, nil
+}
+
+// loadInventoryManifest returns the manifest to continue from: a fresh one,
+// or with Resume the existing one, which must describe the same inventory.
+func loadInventoryManifest(opts InventoryOptions) (*InventoryManifest, error) {
+	fresh := &InventoryManifest{
+		Bucket:    opts.Bucket,
+		Prefix:    opts.Prefix,
+		Format:    opts.Format,
+		StartedAt: now().UTC(),
+		Parts:     []InventoryPart{},
+	}
+	path := filepath.Join(opts.Out, InventoryManifestName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fresh, nil
+		}
+		return nil, err
+	}
+	if !opts.Resume {
+		return nil, fmt.Errorf("ops: inventory manifest %s exists (use resume)", path)
+	}
+	var manifest InventoryManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("ops: inventory manifest %s: %w", path, err)
+	}
+	if manifest.Bucket != opts.Bucket || manifest.Prefix != opts.Prefix || manifest.Format != opts.Format {
+		return nil, fmt.Errorf("ops: inventory manifest %s is for bucket %q prefix %q format %s", path, manifest.Bucket, manifest.Prefix, manifest.Format)
+	}
+	return &manifest, nil
+}
+
+// writeInventoryManifest replaces manifest.json atomically.
+func writeInventoryManifest(dir string, manifest *InventoryManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".manifest-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, InventoryManifestName))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func finishInventoryPart(dir string, manifest *InventoryManifest, part *inventoryPartWriter) error {
+	if err := part.close(); err != nil {
+		return err
+	}
+	manifest.Parts = append(manifest.Parts, InventoryPart{
+		File:    filepath.Base(part.file.Name()),
+		Objects: part.objects,
+		LastKey: part.lastKey,
+		MD5:     hex.EncodeToString(part.sum.Sum(nil)),
+	})
+	manifest.Objects += part.objects
+	return writeInventoryManifest(dir, manifest)
+}
+
+// inventoryPartWriter streams rows into one part file. A part left behind by
+// an interrupted run is not in the manifest and is truncated on resume.
+type inventoryPartWriter struct {
+	file    *os.File
+	buf     *bufio.Writer
+	sum     hash.Hash
+	csv     *csv.Writer
+	json    *json.Encoder
+	objects int
+	lastKey string
+}
+
+func createInventoryPart(path, format string) (*inventoryPartWriter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	part := &inventoryPartWriter{file: file, sum: md5.New()}
+	part.buf = bufio.NewWriter(io.MultiWriter(file, part.sum))
+	if format == InventoryFormatJSONL {
+		part.json = json.NewEncoder(part.buf)
+		return part, nil
+	}
+	part.csv = csv.NewWriter(part.buf)
+	if err := part.csv.Write(inventoryCSVHeader); err != nil {
+		_ = part.abort()
+		return nil, err
+	}
+	return part, nil
+}
+
+func (p *inventoryPartWriter) write(obj meta.ObjectMeta) error {
+	storageClass := obj.StorageClass
+	if storageClass == "" {
+		storageClass = inventoryStorageClass
+	}
+	var err error
+	if p.json != nil {
+		err = p.json.Encode(InventoryEntry{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ETag:         obj.ETag,
+			LastModified: obj.LastModified,
+			StorageClass: storageClass,
+		})
+	} else {
+		err = p.csv.Write([]string{obj.Key, strconv.FormatInt(obj.Size, 10), obj.ETag, obj.LastModified, storageClass})
+	}
+	if err != nil {
+		return err
+	}
+	p.objects++
+	p.lastKey = obj.Key
+	return nil
+}
+
+func (p *inventoryPartWriter) close() error {
+	if p.csv != nil {
+		p.csv.Flush()
+		if err := p.csv.Error(); err != nil {
+			_ = p.file.Close()
+			return err
+		}
+	}
+	if err := p.buf.Flush(); err != nil {
+		_ = p.file.Close()
+		return err
+	}
+	if err := p.file.Sync(); err != nil {
+		_ = p.file.Close()
+		return err
+	}
+	return p.file.Close()
+}
+
+func (p *inventoryPartWriter) abort() error {
+	return p.file.Close()
+}
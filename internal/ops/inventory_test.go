@@ -0,0 +1,132 @@
+package ops
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+func readInventoryManifest(t *testing.T, out string) InventoryManifest {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(out, InventoryManifestName))
+	if err != nil {
+		t.Fatalf("ReadFile manifest: %v", err)
+	}
+	var manifest InventoryManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Unmarshal manifest: %v", err)
+	}
+	return manifest
+}
+
+func TestInventoryCSVPartsAndResume(t *testing.T) {
+	_, metaPath, eng := newExportTestEngine(t)
+	for _, key := range []string{"logs/a", "logs/b", "logs/c", "other/d"} {
+		exportPut(t, eng, key, "", "data-"+key)
+	}
+	out := filepath.Join(t.TempDir(), "inventory")
+
+	report, err := Inventory(metaPath, InventoryOptions{Bucket: "bucket", Prefix: "logs/", Out: out, PartObjects: 2})
+	if err != nil {
+		t.Fatalf("Inventory: %v", err)
+	}
+	if report.InventoryObjects != 3 || report.InventoryParts != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	manifest := readInventoryManifest(t, out)
+	if !manifest.Completed || manifest.Objects != 3 || len(manifest.Parts) != 2 || manifest.Parts[0].LastKey != "logs/b" {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	file, err := os.Open(filepath.Join(out, manifest.Parts[0].File))
+	if err != nil {
+		t.Fatalf("Open part: %v", err)
+	}
+	rows, err := csv.NewReader(file).ReadAll()
+	_ = file.Close()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != "key,size,etag,last_modified,storage_class" {
+		t.Fatalf("unexpected rows: %v", rows)
+	}
+	if rows[1][0] != "logs/a" || rows[1][1] != "11" || rows[1][2] == "" || rows[1][3] == "" || rows[1][4] != "STANDARD" {
+		t.Fatalf("unexpected row: %v", rows[1])
+	}
+
+	if _, err := Inventory(metaPath, InventoryOptions{Bucket: "bucket", Prefix: "logs/", Out: out}); err == nil {
+		t.Fatalf("expected error for existing manifest without resume")
+	}
+
+	// Simulate a run interrupted after the first part.
+	manifest.Completed = false
+	manifest.Objects = manifest.Parts[0].Objects
+	manifest.Parts = manifest.Parts[:1]
+	if err := writeInventoryManifest(out, &manifest); err != nil {
+		t.Fatalf("writeInventoryManifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(out, "part-00001.csv"), []byte("torn"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	report, err = Inventory(metaPath, InventoryOptions{Bucket: "bucket", Prefix: "logs/", Out: out, PartObjects: 2, Resume: true})
+	if err != nil {
+		t.Fatalf("Inventory resume: %v", err)
+	}
+	if report.Skipped != 2 || report.InventoryObjects != 1 || report.InventoryParts != 2 {
+		t.Fatalf("unexpected resume report: %+v", report)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "part-00001.csv"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "key,") || !strings.Contains(string(data), "logs/c,") {
+		t.Fatalf("unexpected resumed part: %q", data)
+	}
+}
+
+func TestInventoryJSONLines(t *testing.T) {
+	_, metaPath, eng := newExportTestEngine(t)
+	exportPut(t, eng, "k", "", "hello")
+	out := filepath.Join(t.TempDir(), "inventory")
+	if _, err := Inventory(metaPath, InventoryOptions{Bucket: "bucket", Out: out, Format: InventoryFormatJSONL}); err != nil {
+		t.Fatalf("Inventory: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "part-00000.jsonl"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var entry InventoryEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if entry.Key != "k" || entry.Size != 5 || entry.ETag != "5d41402abc4b2a76b9719d911017c592" || entry.StorageClass != "STANDARD" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+}
+
+func TestInventoryManifestBucketTags(t *testing.T) {
+	_, metaPath, eng := newExportTestEngine(t)
+	exportPut(t, eng, "k", "", "hello")
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	tags := []meta.BucketTag{{Key: "cost-center", Value: "42"}}
+	if err := store.SetBucketTags(context.Background(), "bucket", tags); err != nil {
+		t.Fatalf("SetBucketTags: %v", err)
+	}
+	out := filepath.Join(t.TempDir(), "inventory")
+	if _, err := Inventory(metaPath, InventoryOptions{Bucket: "bucket", Out: out}); err != nil {
+		t.Fatalf("Inventory: %v", err)
+	}
+	if manifest := readInventoryManifest(t, out); !reflect.DeepEqual(manifest.BucketTags, tags) {
+		t.Fatalf("manifest bucket tags=%+v want %+v", manifest.BucketTags, tags)
+	}
+}
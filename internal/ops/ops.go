@@ -35,13 +35,30 @@ type Report struct {
 	Reclaimed               int64           `json:"reclaimed_bytes,omitempty"`
 	RewrittenSegments       int             `json:"rewritten_segments,omitempty"`
 	RewrittenBytes          int64           `json:"rewritten_bytes,omitempty"`
+	RewriteBytesPerSec      int64           `json:"rewrite_bytes_per_sec,omitempty"`
 	NewSegments             int             `json:"new_segments,omitempty"`
 	CandidateIDs            []string        `json:"candidate_ids"`
 	MissingSegments         int             `json:"missing_segments,omitempty"`
 	InvalidManifests        int             `json:"invalid_manifests,omitempty"`
 	OutOfBoundsChunks       int             `json:"out_of_bounds_chunks,omitempty"`
+	FooterMismatches        int             `json:"footer_mismatches,omitempty"`
+	DamagedVersions         int             `json:"damaged_versions,omitempty"`
+	RepointedChunks         int             `json:"repointed_chunks,omitempty"`
 	RebuiltObjects          int             `json:"rebuilt_objects,omitempty"`
 	SkippedManifests        int             `json:"skipped_manifests,omitempty"`
+	Exported                int             `json:"exported,omitempty"`
+	ExportedBytes           int64           `json:"exported_bytes,omitempty"`
+	Imported                int             `json:"imported,omitempty"`
+	ImportedBytes           int64           `json:"imported_bytes,omitempty"`
+	Skipped                 int             `json:"skipped,omitempty"`
+	InventoryObjects        int             `json:"inventory_objects,omitempty"`
+	InventoryParts          int             `json:"inventory_parts,omitempty"`
+	ETagsChecked            int             `json:"etags_checked,omitempty"`
+	ETagsFixed              int             `json:"etags_fixed,omitempty"`
+	ScrubbedBytes           int64           `json:"scrubbed_bytes,omitempty"`
+	ScrubCursor             string          `json:"scrub_cursor,omitempty"`
+	CurrentRepointed        int             `json:"current_repointed,omitempty"`
+	CurrentRemoved          int             `json:"current_removed,omitempty"`
 	MissingSegmentIDs       []string        `json:"missing_segment_ids"`
 	Replication             []meta.ReplStat `json:"replication"`
 	CompareManifestsMissing int             `json:"compare_manifests_missing,omitempty"`
@@ -56,6 +73,11 @@ type Report struct {
 	CompareVersionsExtra    int             `json:"compare_versions_extra,omitempty"`
 	CompareVersionsLocal    int             `json:"compare_versions_local,omitempty"`
 	CompareVersionsRemote   int             `json:"compare_versions_remote,omitempty"`
+	CompareScanned          int             `json:"compare_scanned,omitempty"`
+	CompareSampled          int             `json:"compare_sampled,omitempty"`
+	CompareSampleMissing    int             `json:"compare_sample_missing,omitempty"`
+	CompareSampleMismatch   int             `json:"compare_sample_mismatch,omitempty"`
+	BundlePath              string          `json:"bundle_path,omitempty"`
 }
 
 const reportSchemaVersion = 1
@@ -97,6 +119,16 @@ func (r *Report) addWarning(msg string) {
 	}
 }
 
+func (r *Report) addError(err error) {
+	if r == nil {
+		return
+	}
+	r.Errors++
+	if len(r.ErrorSample) < 5 {
+		r.ErrorSample = append(r.ErrorSample, err.Error())
+	}
+}
+
 // DBIntegrityCheck runs PRAGMA integrity_check on meta.db.
 func DBIntegrityCheck(metaPath string) (*Report, error) {
 	report := newReport("db-integrity-check")
@@ -181,6 +213,7 @@ func Fsck(layout fs.Layout, metaPath string, liveOnly bool) (*Report, error) {
 	report.Manifests = len(manifests)
 
 	segmentInfo := make(map[string]os.FileInfo)
+	segmentPaths := make(map[string]string)
 	segmentSeen := make(map[string]struct{})
 	if store == nil && metaPath != "" {
 		store, _ = meta.Open(metaPath)
@@ -209,10 +242,13 @@ func Fsck(layout fs.Layout, metaPath string, liveOnly bool) (*Report, error) {
 			continue
 		}
 		for _, ch := range man.Chunks {
+			if ch.IsHole() {
+				continue
+			}
 			info, ok := segmentInfo[ch.SegmentID]
-			segPath := layout.SegmentPath(ch.SegmentID)
+			segPath := segmentPaths[ch.SegmentID]
 			if !ok {
-				info, err = os.Stat(segPath)
+				segPath, info, err = layout.LocateSegment(ch.SegmentID)
 				if err != nil {
 					report.MissingSegments++
 					if len(report.MissingSegmentIDs) < 100 {
@@ -231,6 +267,7 @@ func Fsck(layout fs.Layout, metaPath string, liveOnly bool) (*Report, error) {
 				}
 				_ = reader.Close()
 				segmentInfo[ch.SegmentID] = info
+				segmentPaths[ch.SegmentID] = segPath
 				report.Segments++
 			}
 			segmentSeen[ch.SegmentID] = struct{}{}
@@ -273,78 +310,6 @@ func Fsck(layout fs.Layout, metaPath string, liveOnly bool) (*Report, error) {
 	return report, nil
 }
 
-// Scrub verifies chunk hashes against stored data.
-func Scrub(layout fs.Layout, metaPath string, liveOnly bool) (*Report, error) {
-	report := newReport("scrub")
-	manifests, store, err := listManifestPaths(layout, metaPath, liveOnly, report)
-	if err != nil {
-		return nil, err
-	}
-	report.Manifests = len(manifests)
-
-	if store == nil && metaPath != "" {
-		store, err = meta.Open(metaPath)
-		if err != nil {
-			return nil, err
-		}
-	}
-	if store != nil {
-		defer func() { _ = store.Close() }()
-	}
-
-	addError := func(err error) {
-		report.Errors++
-		if len(report.ErrorSample) < 5 {
-			report.ErrorSample = append(report.ErrorSample, err.Error())
-		}
-	}
-
-	for _, path := range manifests {
-		file, err := os.Open(path)
-		if err != nil {
-			addError(err)
-			continue
-		}
-		man, err := (&manifest.BinaryCodec{}).Decode(file)
-		_ = file.Close()
-		if err != nil {
-			addError(err)
-			continue
-		}
-		for _, ch := range man.Chunks {
-			segPath := layout.SegmentPath(ch.SegmentID)
-			f, err := os.Open(segPath)
-			if err != nil {
-				addError(err)
-				continue
-			}
-			buf := make([]byte, ch.Len)
-			n, err := f.ReadAt(buf, ch.Offset)
-			_ = f.Close()
-			if err != nil && err != io.EOF {
-				addError(err)
-				continue
-			}
-			if n != int(ch.Len) {
-				addError(fmt.Errorf("short read segment=%s", ch.SegmentID))
-				continue
-			}
-			if segmentHash := segment.HashChunk(buf); segmentHash != ch.Hash {
-				addError(fmt.Errorf("hash mismatch segment=%s", ch.SegmentID))
-				if store != nil {
-					_ = store.MarkDamaged(context.Background(), man.VersionID)
-				}
-			}
-		}
-	}
-
-	report.FinishedAt = now().UTC()
-	if store != nil {
-		_ = store.RecordOpsRun(context.Background(), report.Mode, reportOpsFrom(report))
-	}
-	return report, nil
-}
-
 // Snapshot writes a minimal snapshot manifest and copies the SQLite files.
 func Snapshot(layout fs.Layout, metaPath string, outDir string) (*Report, error) {
 	if outDir == "" {
@@ -376,33 +341,6 @@ func Rebuild(layout fs.Layout, metaPath string) (*Report, error) {
 	return RebuildIndex(layout, metaPath)
 }
 
-// SupportBundle gathers snapshot and reports into a directory.
-func SupportBundle(layout fs.Layout, metaPath string, outDir string) (*Report, error) {
-	if outDir == "" {
-		return nil, errors.New("ops: support bundle output dir required")
-	}
-	report := newReport("support-bundle")
-	if err := os.MkdirAll(outDir, 0o755); err != nil {
-		return nil, err
-	}
-	snapDir := filepath.Join(outDir, "snapshot")
-	_, _ = Snapshot(layout, metaPath, snapDir)
-	fsck, _ := Fsck(layout, metaPath, true)
-	_ = writeJSON(filepath.Join(outDir, "fsck.json"), fsck)
-	scrub, _ := Scrub(layout, metaPath, true)
-	_ = writeJSON(filepath.Join(outDir, "scrub.json"), scrub)
-	if metaPath != "" {
-		if store, err := meta.Open(metaPath); err == nil {
-			if repl, err := store.GetReplStats(context.Background()); err == nil {
-				_ = writeJSON(filepath.Join(outDir, "repl.json"), repl)
-			}
-			_ = store.Close()
-		}
-	}
-	report.FinishedAt = now().UTC()
-	return report, nil
-}
-
 // GCPlan computes which sealed segments can be removed.
 func GCPlan(layout fs.Layout, metaPath string, minAge time.Duration, guardrails GCGuardrails) (*Report, []meta.Segment, error) {
 	report := newReport("gc-plan")
@@ -435,6 +373,9 @@ func GCPlan(layout fs.Layout, metaPath string, minAge time.Duration, guardrails
 			continue
 		}
 		for _, ch := range man.Chunks {
+			if ch.IsHole() {
+				continue
+			}
 			liveBytes[ch.SegmentID] += int64(ch.Len)
 		}
 	}
@@ -619,6 +560,91 @@ func MPUGCRun(metaPath string, ttl time.Duration, force bool, guardrails MPUGCGu
 	return report, nil
 }
 
+// MPULifecycleAbort deletes multipart uploads older than their bucket's
+// AbortIncompleteMultipartUpload rule (age from multipart_uploads.created_at).
+// Runs with candidates are recorded as mpu-gc-run.
+func MPULifecycleAbort(ctx context.Context, store *meta.Store) (*Report, error) {
+	if store == nil {
+		return nil, errors.New("mpu-gc: store required")
+	}
+	rules, err := store.ListBucketLifecycles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := newReport("mpu-gc-run")
+	for _, rule := range rules {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		cutoff := now().UTC().Add(-time.Duration(rule.AbortMPUDays) * 24 * time.Hour)
+		uploads, err := store.ListMultipartUploadsBefore(ctx, cutoff)
+		if err != nil {
+			return report, err
+		}
+		for _, up := range uploads {
+			if up.Bucket != rule.Bucket {
+				continue
+			}
+			report.Candidates++
+			report.CandidateIDs = append(report.CandidateIDs, up.UploadID)
+			_, bytes, err := store.DeleteMultipartUpload(ctx, up.UploadID)
+			if err != nil {
+				report.Errors++
+				continue
+			}
+			report.Deleted++
+			report.Reclaimed += bytes
+		}
+	}
+	report.FinishedAt = now().UTC()
+	if report.Candidates > 0 {
+		_ = store.RecordOpsRun(context.Background(), report.Mode, reportOpsFrom(report))
+	}
+	return report, nil
+}
+
+// ExpireVersions deletes versions whose x-seglake-ttl expiry is at or before
+// cutoff. Each delete is recorded in the oplog like a client DELETE of that
+// version.
+func ExpireVersions(ctx context.Context, store *meta.Store, cutoff time.Time) (*Report, error) {
+	if store == nil {
+		return nil, errors.New("ttl-expire: store required")
+	}
+	const batch = 1000
+	report := newReport("ttl-expire")
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		expired, err := store.ListExpiredVersions(ctx, cutoff, batch)
+		if err != nil {
+			return report, err
+		}
+		progress := false
+		for _, item := range expired {
+			report.Candidates++
+			deleted, err := store.DeleteObjectVersion(ctx, item.Bucket, item.Key, item.VersionID)
+			if err != nil {
+				report.Errors++
+				continue
+			}
+			if deleted {
+				report.Deleted++
+				progress = true
+			}
+		}
+		// A batch that deleted nothing would be listed again unchanged.
+		if len(expired) < batch || !progress {
+			break
+		}
+	}
+	report.FinishedAt = now().UTC()
+	if report.Candidates > 0 {
+		_ = store.RecordOpsRun(context.Background(), report.Mode, reportOpsFrom(report))
+	}
+	return report, nil
+}
+
 func mergeUniquePaths(a, b []string) []string {
 	if len(b) == 0 {
 		return a
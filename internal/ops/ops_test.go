@@ -2,12 +2,19 @@ package ops
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
 	"github.com/kk-code-lab/seglake/internal/storage/fs"
 	"github.com/kk-code-lab/seglake/internal/storage/manifest"
 	"github.com/kk-code-lab/seglake/internal/storage/segment"
@@ -301,6 +308,74 @@ func TestMPUGCPlanAndRun(t *testing.T) {
 	}
 }
 
+func TestMPULifecycleAbortUsesBucketRule(t *testing.T) {
+	dir := t.TempDir()
+	metaPath := filepath.Join(dir, "meta.db")
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	for _, bucket := range []string{"ruled", "plain"} {
+		if err := store.CreateBucket(ctx, bucket); err != nil {
+			t.Fatalf("CreateBucket: %v", err)
+		}
+	}
+	if err := store.SetBucketLifecycle(ctx, "ruled", 7); err != nil {
+		t.Fatalf("SetBucketLifecycle: %v", err)
+	}
+	uploads := []struct {
+		id     string
+		bucket string
+		age    time.Duration
+	}{
+		{id: "ruled-old", bucket: "ruled", age: 8 * 24 * time.Hour},
+		{id: "ruled-new", bucket: "ruled", age: time.Hour},
+		{id: "plain-old", bucket: "plain", age: 30 * 24 * time.Hour},
+	}
+	db, err := sql.Open("sqlite", metaPath)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	for _, up := range uploads {
+		if err := store.CreateMultipartUpload(ctx, up.bucket, "key", up.id, ""); err != nil {
+			t.Fatalf("CreateMultipartUpload: %v", err)
+		}
+		if err := store.PutMultipartPart(ctx, up.id, 1, "v-"+up.id, "etag", 100); err != nil {
+			t.Fatalf("PutMultipartPart: %v", err)
+		}
+		createdAt := time.Now().UTC().Add(-up.age).Format(time.RFC3339Nano)
+		if _, err := db.Exec("UPDATE multipart_uploads SET created_at=? WHERE upload_id=?", createdAt, up.id); err != nil {
+			t.Fatalf("backdate upload: %v", err)
+		}
+	}
+
+	report, err := MPULifecycleAbort(ctx, store)
+	if err != nil {
+		t.Fatalf("MPULifecycleAbort: %v", err)
+	}
+	if report.Deleted != 1 || report.Reclaimed != 100 || len(report.CandidateIDs) != 1 || report.CandidateIDs[0] != "ruled-old" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := store.GetMultipartUpload(ctx, "ruled-old"); err == nil {
+		t.Fatalf("expected ruled-old aborted")
+	}
+	for _, id := range []string{"ruled-new", "plain-old"} {
+		if _, err := store.GetMultipartUpload(ctx, id); err != nil {
+			t.Fatalf("expected %s kept: %v", id, err)
+		}
+	}
+	runs, err := store.ListOpsRuns(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListOpsRuns: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Mode != "mpu-gc-run" || runs[0].Deleted != 1 {
+		t.Fatalf("expected mpu-gc-run ops run, got %+v", runs)
+	}
+}
+
 func TestGCPlanIncludesMultipartParts(t *testing.T) {
 	dir := t.TempDir()
 	layout := fs.NewLayout(filepath.Join(dir, "data"))
@@ -380,6 +455,156 @@ func TestGCPlanIncludesMultipartParts(t *testing.T) {
 	}
 }
 
+func TestGCRewriteConcurrentReadsKeepOrder(t *testing.T) {
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "data"))
+	metaPath := filepath.Join(layout.Root, "meta.db")
+	if err := os.MkdirAll(layout.ManifestsDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll manifests: %v", err)
+	}
+	if err := os.MkdirAll(layout.SegmentsDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll segments: %v", err)
+	}
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	// Two mostly-dead segments whose live chunks interleave in one manifest.
+	man := &manifest.Manifest{Bucket: "b", Key: "k", VersionID: "v1"}
+	var want [][]byte
+	for s, segID := range []string{"seg-a", "seg-b"} {
+		segPath := layout.SegmentPath(segID)
+		writer, err := segment.NewWriter(segPath, 1)
+		if err != nil {
+			t.Fatalf("NewWriter: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			data := []byte{byte('a' + s), byte('0' + i), 'x', 'y'}
+			offset, err := writer.AppendRecord(segment.ChunkRecordHeader{Hash: [32]byte{byte(s), byte(i)}, Len: uint32(len(data))}, data)
+			if err != nil {
+				t.Fatalf("AppendRecord: %v", err)
+			}
+			man.Chunks = append(man.Chunks, manifest.ChunkRef{SegmentID: segID, Offset: offset, Len: uint32(len(data))})
+			want = append(want, data)
+			dead := make([]byte, 64)
+			if _, err := writer.AppendRecord(segment.ChunkRecordHeader{Hash: [32]byte{9, byte(s), byte(i)}, Len: uint32(len(dead))}, dead); err != nil {
+				t.Fatalf("AppendRecord: %v", err)
+			}
+		}
+		footer := segment.FinalizeFooter(segment.NewFooter(1))
+		if err := writer.Seal(footer); err != nil {
+			t.Fatalf("Seal: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		info, err := os.Stat(segPath)
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if err := store.RecordSegment(context.Background(), segID, segPath, "SEALED", info.Size(), footer.ChecksumHash[:]); err != nil {
+			t.Fatalf("RecordSegment: %v", err)
+		}
+	}
+	// Interleave: a0 b0 a1 b1 a2 b2.
+	chunks := man.Chunks
+	man.Chunks = []manifest.ChunkRef{chunks[0], chunks[3], chunks[1], chunks[4], chunks[2], chunks[5]}
+	want = [][]byte{want[0], want[3], want[1], want[4], want[2], want[5]}
+	for i := range man.Chunks {
+		man.Chunks[i].Index = i
+		man.Size += int64(man.Chunks[i].Len)
+	}
+	manPath := layout.ManifestPath(man.VersionID)
+	if err := writeManifest(manPath, man); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if err := store.RecordPut(context.Background(), "b", "k", man.VersionID, "", man.Size, manPath, ""); err != nil {
+		t.Fatalf("RecordPut: %v", err)
+	}
+	plan, _, err := GCRewritePlanBuild(layout, metaPath, 0, 0.5)
+	if err != nil {
+		t.Fatalf("GCRewritePlanBuild: %v", err)
+	}
+	if len(plan.Candidates) != 2 {
+		t.Fatalf("candidates=%d", len(plan.Candidates))
+	}
+
+	pauseFile := filepath.Join(dir, "pause")
+	if err := os.WriteFile(pauseFile, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		_ = os.Remove(pauseFile)
+	}()
+	started := time.Now()
+	report, err := GCRewriteFromPlan(layout, metaPath, plan, true, 0, 4, pauseFile)
+	if err != nil {
+		t.Fatalf("GCRewriteFromPlan: %v", err)
+	}
+	if time.Since(started) < 300*time.Millisecond {
+		t.Fatalf("rewrite did not wait for pause file")
+	}
+	if report.RewrittenBytes != man.Size || report.RewriteBytesPerSec <= 0 {
+		t.Fatalf("rewritten_bytes=%d rewrite_bps=%d", report.RewrittenBytes, report.RewriteBytesPerSec)
+	}
+
+	manFile, err := os.Open(manPath)
+	if err != nil {
+		t.Fatalf("Open manifest: %v", err)
+	}
+	updated, err := (&manifest.BinaryCodec{}).Decode(manFile)
+	_ = manFile.Close()
+	if err != nil {
+		t.Fatalf("Decode manifest: %v", err)
+	}
+	var lastOffset int64 = -1
+	for i, ch := range updated.Chunks {
+		if ch.SegmentID == "seg-a" || ch.SegmentID == "seg-b" {
+			t.Fatalf("chunk %d not rewritten", i)
+		}
+		if ch.Offset <= lastOffset {
+			t.Fatalf("chunk %d appended out of manifest order", i)
+		}
+		lastOffset = ch.Offset
+		f, err := os.Open(layout.SegmentPath(ch.SegmentID))
+		if err != nil {
+			t.Fatalf("Open segment: %v", err)
+		}
+		got := make([]byte, ch.Len)
+		_, err = f.ReadAt(got, ch.Offset)
+		_ = f.Close()
+		if err != nil {
+			t.Fatalf("ReadAt: %v", err)
+		}
+		if string(got) != string(want[i]) {
+			t.Fatalf("chunk %d=%q want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestGCThrottleSleepsWithoutLock(t *testing.T) {
+	throttle := newGCThrottle(1000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		throttle.wait(500)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if !throttle.mu.TryLock() {
+		t.Fatalf("throttle held its lock while sleeping")
+	}
+	// The next reader is queued behind the first reservation.
+	next := throttle.next
+	throttle.mu.Unlock()
+	if wait := time.Until(next); wait < 300*time.Millisecond {
+		t.Fatalf("reservation ends in %v, want about 400ms", wait)
+	}
+	<-done
+}
+
 func TestGCRewritePlanRun(t *testing.T) {
 	dir := t.TempDir()
 	layout := fs.NewLayout(filepath.Join(dir, "data"))
@@ -464,7 +689,7 @@ func TestGCRewritePlanRun(t *testing.T) {
 		t.Fatalf("ReadGCRewritePlan: %v", err)
 	}
 
-	gcReport, err := GCRewriteFromPlan(layout, metaPath, readPlan, true, 0, "")
+	gcReport, err := GCRewriteFromPlan(layout, metaPath, readPlan, true, 0, 0, "")
 	if err != nil {
 		t.Fatalf("GCRewriteFromPlan: %v", err)
 	}
@@ -489,6 +714,187 @@ func TestGCRewritePlanRun(t *testing.T) {
 	}
 }
 
+type fakeSegmentGuard struct {
+	pinned map[string]bool
+	busy   map[string]bool
+}
+
+func (g *fakeSegmentGuard) SegmentPinned(segmentID string) bool {
+	return g.pinned[segmentID]
+}
+
+func (g *fakeSegmentGuard) RemoveSegmentIfUnpinned(segmentID string, remove func() error) (bool, error) {
+	if g.pinned[segmentID] || g.busy[segmentID] {
+		return false, nil
+	}
+	return true, remove()
+}
+
+func (g *fakeSegmentGuard) RetireSegments([]string) {}
+
+func (g *fakeSegmentGuard) ReleaseSegments([]string) {}
+
+func TestGCRewriteOnlineHonorsPins(t *testing.T) {
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "data"))
+	if err := os.MkdirAll(layout.SegmentsDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll segments: %v", err)
+	}
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+
+	segIDs := []string{"seg-pinned", "seg-busy", "seg-free"}
+	for i, segID := range segIDs {
+		segPath, offset, size := createSegment(t, layout, segID)
+		if err := store.RecordSegment(ctx, segID, segPath, "SEALED", size, nil); err != nil {
+			t.Fatalf("RecordSegment: %v", err)
+		}
+		man := &manifest.Manifest{
+			Bucket:    "b",
+			Key:       segID,
+			VersionID: "v" + string(rune('1'+i)),
+			Size:      5,
+			Chunks: []manifest.ChunkRef{
+				{Index: 0, SegmentID: segID, Offset: offset, Len: 5},
+			},
+		}
+		manPath := layout.ManifestPath(man.VersionID)
+		if err := writeManifest(manPath, man); err != nil {
+			t.Fatalf("write manifest: %v", err)
+		}
+		if err := store.RecordPut(ctx, "b", man.Key, man.VersionID, "", man.Size, manPath, ""); err != nil {
+			t.Fatalf("RecordPut: %v", err)
+		}
+	}
+
+	guard := &fakeSegmentGuard{
+		pinned: map[string]bool{"seg-pinned": true},
+		busy:   map[string]bool{"seg-busy": true},
+	}
+	report, err := GCRewriteOnline(ctx, layout, store, 0, 1.0, 0, 0, "", guard)
+	if err != nil {
+		t.Fatalf("GCRewriteOnline: %v", err)
+	}
+	if report.Candidates != 2 {
+		t.Fatalf("expected 2 candidates, got %d", report.Candidates)
+	}
+	if report.Deleted != 1 || report.Warnings != 1 {
+		t.Fatalf("expected 1 deleted and 1 warning, got %d/%d", report.Deleted, report.Warnings)
+	}
+	if _, err := os.Stat(layout.SegmentPath("seg-pinned")); err != nil {
+		t.Fatalf("pinned segment removed: %v", err)
+	}
+	if _, err := os.Stat(layout.SegmentPath("seg-busy")); err != nil {
+		t.Fatalf("busy segment removed: %v", err)
+	}
+	if _, err := os.Stat(layout.SegmentPath("seg-free")); !os.IsNotExist(err) {
+		t.Fatalf("expected free segment removed, got %v", err)
+	}
+	trends, err := store.ListGCTrends(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListGCTrends: %v", err)
+	}
+	if len(trends) != 1 || trends[0].Mode != "gc-rewrite-online" {
+		t.Fatalf("expected gc-rewrite-online trend, got %+v", trends)
+	}
+}
+
+func TestGCRewriteOnlineKeepsSegmentsOfConcurrentCompletes(t *testing.T) {
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "data"))
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	// Tiny segments, so every part lands in its own sealed segment.
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store, SegmentMaxBytes: 64})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	ctx := context.Background()
+	const parts = 16
+	mans := make([]*manifest.Manifest, parts)
+	for i := range mans {
+		man, _, err := eng.PutObject(ctx, "b", fmt.Sprintf("part-%02d", i), "", strings.NewReader(fmt.Sprintf("part data %02d", i)))
+		if err != nil {
+			t.Fatalf("PutObject: %v", err)
+		}
+		mans[i] = man
+	}
+
+	// Like MPU complete, each writer commits a manifest reusing the part
+	// chunks it read before compaction rewrote the part manifests.
+	start := make(chan struct{})
+	committed := make([]bool, parts)
+	errs := make(chan error, parts+1)
+	var wg sync.WaitGroup
+	for i, man := range mans {
+		wg.Add(1)
+		go func(i int, man *manifest.Manifest) {
+			defer wg.Done()
+			<-start
+			_, _, err := eng.PutManifestWithCommit(ctx, "b", fmt.Sprintf("complete-%02d", i), "", man.Size, "etag", man.Chunks, nil)
+			switch {
+			case err == nil:
+				committed[i] = true
+			case !errors.Is(err, engine.ErrSegmentRetired):
+				errs <- err
+			}
+		}(i, man)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-start
+		if _, err := GCRewriteOnline(ctx, layout, store, 0, 1.0, 0, 1, "", eng); err != nil {
+			errs <- err
+		}
+	}()
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent complete/compaction: %v", err)
+	}
+
+	for i, ok := range committed {
+		if !ok {
+			continue
+		}
+		reader, _, err := eng.GetObject(ctx, "b", fmt.Sprintf("complete-%02d", i))
+		if err != nil {
+			t.Fatalf("GetObject complete-%02d: %v", i, err)
+		}
+		data, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if want := fmt.Sprintf("part data %02d", i); err != nil || string(data) != want {
+			t.Fatalf("complete-%02d: read %q (%v), want %q", i, data, err, want)
+		}
+	}
+
+	// Another pass drops the segments the completes kept alive; a complete
+	// still holding old chunk references is refused instead of committed.
+	if _, err := GCRewriteOnline(ctx, layout, store, 0, 1.0, 0, 1, "", eng); err != nil {
+		t.Fatalf("GCRewriteOnline: %v", err)
+	}
+	for _, man := range mans {
+		if _, err := layout.StatSegment(man.Chunks[0].SegmentID); err == nil {
+			continue
+		}
+		_, _, err := eng.PutManifestWithCommit(ctx, "b", "late-complete", "", man.Size, "etag", man.Chunks, nil)
+		if !errors.Is(err, engine.ErrSegmentRetired) {
+			t.Fatalf("complete referencing a removed segment: expected ErrSegmentRetired, got %v", err)
+		}
+		return
+	}
+	t.Fatalf("expected compaction to remove at least one part segment")
+}
+
 func createSegment(t *testing.T, layout fs.Layout, segID string) (string, int64, int64) {
 	t.Helper()
 	segPath := layout.SegmentPath(segID)
@@ -546,3 +952,71 @@ func TestGCPlanMinAgeRespected(t *testing.T) {
 		t.Fatalf("expected no candidates due to minAge")
 	}
 }
+
+func TestReclaimSegmentsAfterBucketPurge(t *testing.T) {
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "data"))
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store, SegmentMaxBytes: 4096})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	ctx := context.Background()
+	// gone/x1 fills a segment of its own; gone/x2 shares the next one with
+	// kept/k1, which kept/k2 seals.
+	for _, obj := range []struct {
+		bucket, key string
+		size        int
+	}{
+		{"gone", "x1", 5000},
+		{"gone", "x2", 100},
+		{"kept", "k1", 100},
+		{"kept", "k2", 5000},
+	} {
+		if err := store.CreateBucket(ctx, obj.bucket); err != nil {
+			t.Fatalf("CreateBucket: %v", err)
+		}
+		data := strings.Repeat(obj.key, obj.size/2)
+		if _, _, err := eng.PutObject(ctx, obj.bucket, obj.key, "", strings.NewReader(data)); err != nil {
+			t.Fatalf("PutObject: %v", err)
+		}
+	}
+
+	ids, err := BucketSegments(ctx, store, "gone")
+	if err != nil {
+		t.Fatalf("BucketSegments: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 segments, got %v", ids)
+	}
+	if _, err := store.PurgeBucketObjects(ctx, "gone"); err != nil {
+		t.Fatalf("PurgeBucketObjects: %v", err)
+	}
+	report, err := ReclaimSegments(ctx, layout, store, ids, eng)
+	if err != nil {
+		t.Fatalf("ReclaimSegments: %v", err)
+	}
+	if report.Deleted != 2 || report.Reclaimed == 0 {
+		t.Fatalf("expected 2 reclaimed segments, got %+v", report)
+	}
+	for _, id := range ids {
+		if _, err := layout.StatSegment(id); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("segment %s not removed: %v", id, err)
+		}
+	}
+	for _, key := range []string{"k1", "k2"} {
+		reader, _, err := eng.GetObject(ctx, "kept", key)
+		if err != nil {
+			t.Fatalf("GetObject %s: %v", key, err)
+		}
+		data, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil || !strings.HasPrefix(string(data), key+key) {
+			t.Fatalf("kept/%s unreadable after reclaim: %v", key, err)
+		}
+	}
+}
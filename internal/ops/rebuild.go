@@ -3,13 +3,13 @@ package ops
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/kk-code-lab/seglake/internal/clock"
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/storage/fs"
 	"github.com/kk-code-lab/seglake/internal/storage/manifest"
@@ -114,19 +114,21 @@ func RebuildIndex(layout fs.Layout, metaPath string) (*Report, error) {
 					lastPhysical = physical
 					logical = 0
 				}
-				hlcTS := fmt.Sprintf("%019d-%010d", physical, logical)
+				hlcTS := clock.FormatHLC(physical, logical)
 				lastModified := entry.modTime.Format(time.RFC3339Nano)
 				if err := store.RecordPutWithHLC(tx, hlcTS, "local", entry.bucket, entry.key, entry.versionID, "", entry.size, entry.path, "", lastModified, true); err != nil {
 					return err
 				}
 				report.RebuiltObjects++
 				for _, ch := range entry.chunks {
+					if ch.IsHole() {
+						continue
+					}
 					segmentRefs[ch.SegmentID] = struct{}{}
 				}
 			}
 			for segID := range segmentRefs {
-				segPath := layout.SegmentPath(segID)
-				info, err := os.Stat(segPath)
+				segPath, info, err := layout.LocateSegment(segID)
 				if err != nil {
 					report.MissingSegments++
 					continue
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/storage/fs"
@@ -109,6 +111,142 @@ func ReplValidate(layout fs.Layout, metaPath, compareDir string) (*Report, error
 	return report, nil
 }
 
+// RemoteObjectMeta is the remote view of a key used by sampled validation.
+type RemoteObjectMeta struct {
+	VersionID string
+	ETag      string
+	Size      int64
+	State     string
+}
+
+// ErrRemoteObjectMissing reports that the remote has no current version for a key.
+var ErrRemoteObjectMissing = errors.New("ops: remote object missing")
+
+// ReplSampleOptions configures sampled validation against a live remote.
+type ReplSampleOptions struct {
+	// Remote identifies the peer; the resume cursor is stored per remote.
+	Remote string
+	// SamplePercent is the share of scanned keys compared with the remote (0-100].
+	SamplePercent float64
+	// Limit caps keys scanned per run (0 = 10000).
+	Limit int
+	// RatePerSecond caps remote lookups per second (0 = unlimited).
+	RatePerSecond float64
+}
+
+const (
+	replSampleDefaultLimit = 10000
+	replSamplePageSize     = 500
+)
+
+// ReplValidateSample compares ETag/size/version of a random sample of current
+// keys with a remote site. Each run resumes after the last scanned key and
+// wraps around once the keyspace is exhausted.
+func ReplValidateSample(ctx context.Context, store *meta.Store, opts ReplSampleOptions, fetch func(ctx context.Context, bucket, key string) (*RemoteObjectMeta, error)) (*Report, error) {
+	if store == nil || fetch == nil {
+		return nil, errors.New("ops: repl-validate-sample requires store and fetcher")
+	}
+	if opts.Remote == "" {
+		return nil, errors.New("ops: repl-validate-sample requires remote")
+	}
+	if opts.SamplePercent <= 0 || opts.SamplePercent > 100 {
+		return nil, errors.New("ops: sample percent must be (0,100]")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = replSampleDefaultLimit
+	}
+	report := newReport("repl-validate-sample")
+	addError := func(msg string) {
+		report.Errors++
+		if len(report.ErrorSample) < 5 {
+			report.ErrorSample = append(report.ErrorSample, msg)
+		}
+	}
+	var interval time.Duration
+	if opts.RatePerSecond > 0 {
+		interval = time.Duration(float64(time.Second) / opts.RatePerSecond)
+	}
+	var lastFetch time.Time
+
+	startBucket, startKey, err := store.GetReplValidateCursor(ctx, opts.Remote)
+	if err != nil {
+		return nil, err
+	}
+	afterBucket, afterKey := startBucket, startKey
+	// After wrapping to the start of the keyspace, stop once the run reaches
+	// keys it already scanned.
+	wrapped := false
+	done := false
+	for !done && report.CompareScanned < limit {
+		pageSize := min(replSamplePageSize, limit-report.CompareScanned)
+		objects, err := store.ListCurrentObjectsAfter(ctx, afterBucket, afterKey, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			if wrapped && (obj.Bucket > startBucket || (obj.Bucket == startBucket && obj.Key > startKey)) {
+				done = true
+				break
+			}
+			report.CompareScanned++
+			afterBucket, afterKey = obj.Bucket, obj.Key
+			if opts.SamplePercent < 100 && rand.Float64()*100 >= opts.SamplePercent {
+				continue
+			}
+			if interval > 0 && !lastFetch.IsZero() {
+				if wait := interval - time.Since(lastFetch); wait > 0 {
+					if err := sleepContext(ctx, wait); err != nil {
+						return nil, err
+					}
+				}
+			}
+			lastFetch = now()
+			report.CompareSampled++
+			remote, err := fetch(ctx, obj.Bucket, obj.Key)
+			if err != nil {
+				if errors.Is(err, ErrRemoteObjectMissing) {
+					report.CompareSampleMissing++
+					addError(fmt.Sprintf("missing on remote: bucket=%s key=%s version=%s", obj.Bucket, obj.Key, obj.VersionID))
+					continue
+				}
+				_ = store.SetReplValidateCursor(ctx, opts.Remote, afterBucket, afterKey)
+				return nil, err
+			}
+			if remote.VersionID != obj.VersionID || remote.ETag != obj.ETag || remote.Size != obj.Size || remote.State != obj.State {
+				report.CompareSampleMismatch++
+				addError(fmt.Sprintf("diverged: bucket=%s key=%s version=%s remote_version=%s etag=%s remote_etag=%s size=%d remote_size=%d",
+					obj.Bucket, obj.Key, obj.VersionID, remote.VersionID, obj.ETag, remote.ETag, obj.Size, remote.Size))
+			}
+		}
+		if !done && len(objects) < pageSize {
+			// End of keyspace: the next scan starts from the beginning.
+			afterBucket, afterKey = "", ""
+			if wrapped || (startBucket == "" && startKey == "") {
+				done = true
+			}
+			wrapped = true
+		}
+		if err := store.SetReplValidateCursor(ctx, opts.Remote, afterBucket, afterKey); err != nil {
+			return nil, err
+		}
+	}
+	report.FinishedAt = now().UTC()
+	_ = store.RecordOpsRun(ctx, report.Mode, reportOpsFrom(report))
+	return report, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func normalizePaths(base string, paths []string) map[string]struct{} {
 	out := make(map[string]struct{}, len(paths))
 	for _, path := range paths {
@@ -4,8 +4,10 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/storage/engine"
@@ -63,7 +65,7 @@ func TestScrubMarksDamagedVersion(t *testing.T) {
 		t.Fatalf("Close: %v", err)
 	}
 
-	report, err := Scrub(layout, metaPath, true)
+	report, err := Scrub(layout, metaPath, ScrubOptions{})
 	if err != nil {
 		t.Fatalf("Scrub: %v", err)
 	}
@@ -133,7 +135,7 @@ func TestScrubReportsShortRead(t *testing.T) {
 		t.Fatalf("Close: %v", err)
 	}
 
-	report, err := Scrub(layout, metaPath, true)
+	report, err := Scrub(layout, metaPath, ScrubOptions{})
 	if err != nil {
 		t.Fatalf("Scrub: %v", err)
 	}
@@ -141,3 +143,146 @@ func TestScrubReportsShortRead(t *testing.T) {
 		t.Fatalf("expected scrub errors")
 	}
 }
+
+func TestScrubWindowContains(t *testing.T) {
+	at := func(hhmm string) time.Time {
+		parsed, err := time.Parse("15:04", hhmm)
+		if err != nil {
+			t.Fatalf("time.Parse: %v", err)
+		}
+		return time.Date(2026, 5, 6, parsed.Hour(), parsed.Minute(), 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		window string
+		inside []string
+		out    []string
+	}{
+		{"01:00-05:30", []string{"01:00", "05:29"}, []string{"00:59", "05:30", "23:00"}},
+		{"22:00-02:00", []string{"22:00", "23:59", "00:00", "01:59"}, []string{"02:00", "12:00", "21:59"}},
+		{"00:00-24:00", []string{"00:00", "23:59"}, nil},
+	} {
+		window, err := parseScrubWindow(tc.window)
+		if err != nil {
+			t.Fatalf("parseScrubWindow(%q): %v", tc.window, err)
+		}
+		for _, hhmm := range tc.inside {
+			if !window.contains(at(hhmm)) {
+				t.Fatalf("%s should contain %s", tc.window, hhmm)
+			}
+		}
+		for _, hhmm := range tc.out {
+			if window.contains(at(hhmm)) {
+				t.Fatalf("%s should not contain %s", tc.window, hhmm)
+			}
+		}
+	}
+	for _, bad := range []string{"1:00-2:00", "01:00", "01:00-01:00", "25:00-01:00", "01:60-02:00", "24:30-01:00"} {
+		if _, err := parseScrubWindow(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestScrubResumesFromCursor(t *testing.T) {
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "data"))
+	metaPath := filepath.Join(layout.Root, "meta.db")
+	if err := os.MkdirAll(layout.Root, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store})
+	if err != nil {
+		_ = store.Close()
+		t.Fatalf("engine.New: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if _, _, err := eng.PutObject(context.Background(), "bucket", key, "", strings.NewReader("data-"+key)); err != nil {
+			_ = store.Close()
+			t.Fatalf("PutObject: %v", err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// A window that closed an hour ago stops the run before any reads.
+	closed := time.Now().UTC().Add(-2*time.Hour).Format("15:04") + "-" + time.Now().UTC().Add(-time.Hour).Format("15:04")
+	report, err := Scrub(layout, metaPath, ScrubOptions{Window: closed, Resume: true})
+	if err != nil {
+		t.Fatalf("Scrub outside window: %v", err)
+	}
+	if report.ScrubbedBytes != 0 || report.Warnings != 1 {
+		t.Fatalf("expected no work outside window, got %+v", report)
+	}
+
+	report, err = Scrub(layout, metaPath, ScrubOptions{BytesPerSec: 1 << 20})
+	if err != nil {
+		t.Fatalf("Scrub: %v", err)
+	}
+	if report.Errors != 0 || report.ScrubbedBytes != int64(len("data-a")*3) || report.ScrubCursor != "" {
+		t.Fatalf("unexpected full pass report: %+v", report)
+	}
+	state := readScrubState(t, metaPath)
+	if state.CompletedAt == "" || state.Manifests != 3 || state.BytesScrubbed != report.ScrubbedBytes {
+		t.Fatalf("unexpected state after full pass: %+v", state)
+	}
+
+	// Rewind to an unfinished pass that stopped after the first manifest.
+	paths, err := listFiles(layout.ManifestsDir)
+	if err != nil || len(paths) != 3 {
+		t.Fatalf("listFiles: %v (%d)", err, len(paths))
+	}
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		name, _ := filepath.Rel(layout.ManifestsDir, path)
+		names = append(names, filepath.ToSlash(name))
+	}
+	sort.Strings(names)
+	if state.Cursor != names[2] {
+		t.Fatalf("expected cursor at last manifest %q, got %q", names[2], state.Cursor)
+	}
+	state.Cursor = names[0]
+	state.CompletedAt = ""
+	state.Manifests = 1
+	state.BytesScrubbed = 6
+	state.Errors = 2
+	store, err = meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	if err := store.SaveScrubState(context.Background(), state); err != nil {
+		_ = store.Close()
+		t.Fatalf("SaveScrubState: %v", err)
+	}
+	_ = store.Close()
+
+	report, err = Scrub(layout, metaPath, ScrubOptions{Resume: true})
+	if err != nil {
+		t.Fatalf("Scrub resume: %v", err)
+	}
+	if report.Skipped != 1 || report.ScrubbedBytes != 12 {
+		t.Fatalf("expected resume after cursor, got %+v", report)
+	}
+	state = readScrubState(t, metaPath)
+	if state.CompletedAt == "" || state.Manifests != 3 || state.BytesScrubbed != 18 || state.Errors != 2 {
+		t.Fatalf("unexpected state after resume: %+v", state)
+	}
+}
+
+func readScrubState(t *testing.T, metaPath string) meta.ScrubState {
+	t.Helper()
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	state, ok, err := store.GetScrubState(context.Background())
+	if err != nil || !ok {
+		t.Fatalf("GetScrubState: %v (found %v)", err, ok)
+	}
+	return state
+}
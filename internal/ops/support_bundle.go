@@ -0,0 +1,265 @@
+package ops
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+const supportBundleOpsRuns = 50
+
+// supportBundleManifest indexes the files packed into a support bundle.
+type supportBundleManifest struct {
+	CreatedAt     string              `json:"created_at"`
+	SchemaVersion int                 `json:"schema_version"`
+	Files         []supportBundleFile `json:"files"`
+	Errors        []string            `json:"errors,omitempty"`
+}
+
+type supportBundleFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// supportBundleAPIKey is the redacted view of an API key: no secret material.
+type supportBundleAPIKey struct {
+	AccessKey      string   `json:"access_key"`
+	Enabled        bool     `json:"enabled"`
+	CreatedAt      string   `json:"created_at,omitempty"`
+	Label          string   `json:"label,omitempty"`
+	LastUsedAt     string   `json:"last_used_at,omitempty"`
+	Policy         string   `json:"policy,omitempty"`
+	InflightLimit  int64    `json:"inflight_limit,omitempty"`
+	AllowedBuckets []string `json:"allowed_buckets,omitempty"`
+}
+
+// SupportBundle gathers diagnostics into a single tar.gz (outDir + ".tar.gz").
+// The bundle never contains meta.db or the oplog, so API key secrets and
+// other key material cannot leak through it.
+func SupportBundle(layout fs.Layout, metaPath string, outDir string) (*Report, error) {
+	if outDir == "" {
+		return nil, errors.New("ops: support bundle output dir required")
+	}
+	report := newReport("support-bundle")
+	bundlePath := outDir
+	if !strings.HasSuffix(bundlePath, ".tar.gz") {
+		bundlePath += ".tar.gz"
+	}
+	if err := os.MkdirAll(filepath.Dir(bundlePath), 0o755); err != nil {
+		return nil, err
+	}
+	stageDir, err := os.MkdirTemp(filepath.Dir(bundlePath), ".support-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(stageDir) }()
+
+	index := supportBundleManifest{Files: []supportBundleFile{}}
+	add := func(name string, v any) {
+		if err := writeJSON(filepath.Join(stageDir, name), v); err != nil {
+			index.Errors = append(index.Errors, name+": "+err.Error())
+		}
+	}
+	addErr := func(name string, err error) {
+		index.Errors = append(index.Errors, name+": "+err.Error())
+	}
+
+	snapshot := newReport("snapshot")
+	manifests, _ := listFiles(layout.ManifestsDir)
+	segments, _ := listFiles(layout.SegmentsDir)
+	snapshot.Manifests = len(manifests)
+	snapshot.Segments = len(segments)
+	snapshot.FinishedAt = now().UTC()
+	add("snapshot.json", snapshot)
+	fsck, _ := Fsck(layout, metaPath, true)
+	add("fsck.json", fsck)
+	scrub, _ := Scrub(layout, metaPath, ScrubOptions{})
+	add("scrub.json", scrub)
+	if metaPath != "" {
+		if store, err := meta.Open(metaPath); err == nil {
+			collectMetaDiagnostics(context.Background(), store, &index, add, addErr)
+			_ = store.Close()
+		} else {
+			addErr("meta", err)
+		}
+	}
+	index.CreatedAt = now().UTC().Format(time.RFC3339Nano)
+
+	entries, err := os.ReadDir(stageDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		file, err := describeBundleFile(filepath.Join(stageDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		index.Files = append(index.Files, file)
+	}
+	sort.Slice(index.Files, func(i, j int) bool { return index.Files[i].Name < index.Files[j].Name })
+	if err := writeJSON(filepath.Join(stageDir, "manifest.json"), index); err != nil {
+		return nil, err
+	}
+	if err := writeBundleTarGz(bundlePath, stageDir); err != nil {
+		return nil, err
+	}
+	report.BundlePath = bundlePath
+	for _, msg := range index.Errors {
+		report.addWarning(msg)
+	}
+	report.FinishedAt = now().UTC()
+	return report, nil
+}
+
+func collectMetaDiagnostics(ctx context.Context, store *meta.Store, index *supportBundleManifest, add func(string, any), addErr func(string, error)) {
+	if version, err := store.SchemaVersion(ctx); err == nil {
+		index.SchemaVersion = version
+		add("schema.json", map[string]int{"schema_version": version})
+	} else {
+		addErr("schema.json", err)
+	}
+	if pragmas, err := store.Pragmas(ctx); err == nil {
+		add("pragmas.json", pragmas)
+	} else {
+		addErr("pragmas.json", err)
+	}
+	if keys, err := redactedAPIKeys(ctx, store); err == nil {
+		add("api_keys.json", keys)
+	} else {
+		addErr("api_keys.json", err)
+	}
+	if runs, err := store.ListOpsRuns(ctx, supportBundleOpsRuns); err == nil {
+		if runs == nil {
+			runs = []meta.OpsRun{}
+		}
+		add("ops_runs.json", runs)
+	} else {
+		addErr("ops_runs.json", err)
+	}
+	if remotes, err := store.ListReplRemoteStates(ctx); err == nil {
+		if remotes == nil {
+			remotes = []meta.ReplRemoteState{}
+		}
+		add("repl_state_remote.json", remotes)
+	} else {
+		addErr("repl_state_remote.json", err)
+	}
+	if repl, err := store.GetReplStats(ctx); err == nil {
+		add("repl.json", repl)
+	} else {
+		addErr("repl.json", err)
+	}
+}
+
+func redactedAPIKeys(ctx context.Context, store *meta.Store) ([]supportBundleAPIKey, error) {
+	keys, err := store.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := store.ListAllKeyBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]supportBundleAPIKey, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, supportBundleAPIKey{
+			AccessKey:      key.AccessKey,
+			Enabled:        key.Enabled,
+			CreatedAt:      key.CreatedAt,
+			Label:          key.Label,
+			LastUsedAt:     key.LastUsedAt,
+			Policy:         key.Policy,
+			InflightLimit:  key.InflightLimit,
+			AllowedBuckets: allowed[key.AccessKey],
+		})
+	}
+	return out, nil
+}
+
+func describeBundleFile(path string) (supportBundleFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return supportBundleFile{}, err
+	}
+	defer func() { _ = file.Close() }()
+	hasher := sha256.New()
+	n, err := io.Copy(hasher, file)
+	if err != nil {
+		return supportBundleFile{}, err
+	}
+	return supportBundleFile{
+		Name:   filepath.Base(path),
+		Size:   n,
+		SHA256: hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+func writeBundleTarGz(dst, srcDir string) (err error) {
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(srcDir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = entry.Name()
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, in)
+		_ = in.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
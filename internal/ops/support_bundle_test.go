@@ -0,0 +1,94 @@
+package ops
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func TestSupportBundleRedactsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	layout := fs.NewLayout(filepath.Join(dir, "objects"))
+	metaPath := filepath.Join(dir, "meta.db")
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	ctx := context.Background()
+	const secret = "bundle-secret-value"
+	if err := store.UpsertAPIKey(ctx, "bundle-key", secret, "ro", true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	if err := store.AllowBucketForKey(ctx, "bundle-key", "bucket"); err != nil {
+		t.Fatalf("AllowBucketForKey: %v", err)
+	}
+	if err := store.SetReplRemotePullWatermark(ctx, "peer:9000", "0000000000000000001-0000000000"); err != nil {
+		t.Fatalf("SetReplRemotePullWatermark: %v", err)
+	}
+	_ = store.Close()
+
+	report, err := SupportBundle(layout, metaPath, filepath.Join(dir, "support", "bundle"))
+	if err != nil {
+		t.Fatalf("SupportBundle: %v", err)
+	}
+	if report.BundlePath != filepath.Join(dir, "support", "bundle.tar.gz") {
+		t.Fatalf("bundle path=%q", report.BundlePath)
+	}
+	file, err := os.Open(report.BundlePath)
+	if err != nil {
+		t.Fatalf("open bundle: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	contents := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("read %s: %v", hdr.Name, err)
+		}
+		contents[hdr.Name] = string(data)
+	}
+	for _, name := range []string{"manifest.json", "schema.json", "pragmas.json", "api_keys.json", "ops_runs.json", "repl_state_remote.json", "fsck.json", "scrub.json"} {
+		if _, ok := contents[name]; !ok {
+			t.Fatalf("missing %s in bundle (have %d files)", name, len(contents))
+		}
+	}
+	for name, data := range contents {
+		if strings.Contains(data, secret) || strings.Contains(data, "secret_key") {
+			t.Fatalf("%s leaks secret material", name)
+		}
+		if strings.HasPrefix(name, "meta.db") {
+			t.Fatalf("bundle contains %s", name)
+		}
+	}
+	var index supportBundleManifest
+	if err := json.Unmarshal([]byte(contents["manifest.json"]), &index); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if index.SchemaVersion == 0 || len(index.Files) != len(contents)-1 {
+		t.Fatalf("unexpected manifest: %+v", index)
+	}
+	if !strings.Contains(contents["api_keys.json"], `"bucket"`) || !strings.Contains(contents["repl_state_remote.json"], "peer:9000") {
+		t.Fatalf("unexpected bundle contents: keys=%s remotes=%s", contents["api_keys.json"], contents["repl_state_remote.json"])
+	}
+}
@@ -0,0 +1,357 @@
+package repl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/s3"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	storagefs "github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+const (
+	replBootstrapAttempts  = 8
+	replBootstrapPartFile  = ".repl-bootstrap.part"
+	replBootstrapStateFile = ".repl-bootstrap.json"
+)
+
+var errSnapshotExpired = errors.New("replication: snapshot expired on remote")
+
+// replBootstrapState records which remote snapshot the part file belongs to,
+// so an interrupted bootstrap can resume it with a Range request.
+type replBootstrapState struct {
+	Remote string `json:"remote"`
+	ID     string `json:"id"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// RunBootstrap downloads a metadata snapshot from remote, verifies it and
+// swaps it into dataDir, then pulls the oplog written since the snapshot.
+// Interrupted downloads resume from the partial file on the next attempt.
+func RunBootstrap(remote, accessKey, secretKey, region, dataDir, localSiteID string, force bool) error {
+	if remote == "" {
+		return fmt.Errorf("replication: -repl-remote required")
+	}
+	base, err := url.Parse(remote)
+	if err != nil {
+		return err
+	}
+	if base.Scheme == "" {
+		base.Scheme = "http"
+	}
+	if base.Host == "" && base.Path != "" && !strings.Contains(base.Path, "/") {
+		base.Host = base.Path
+		base.Path = ""
+	}
+	// No overall timeout: snapshots of large metadata can take a long time
+	// to stream, and stalls are handled by resuming.
+	client := &replClient{
+		base: base,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: 60 * time.Second,
+			},
+		},
+	}
+	if accessKey != "" && secretKey != "" {
+		if region == "" {
+			region = "us-east-1"
+		}
+		client.signer = &s3.AuthConfig{
+			AccessKey:            accessKey,
+			SecretKey:            secretKey,
+			Region:               region,
+			AllowUnsignedPayload: true,
+		}
+	}
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+	if localSiteID == "" {
+		localSiteID = "local"
+	}
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return err
+	}
+	metaPath := filepath.Join(dataDir, "meta.db")
+	if !force {
+		if _, err := os.Stat(metaPath); err == nil {
+			return fmt.Errorf("replication: meta.db exists (use -repl-bootstrap-force to overwrite)")
+		}
+	}
+	partPath, err := client.downloadSnapshot(dataDir, localSiteID)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "seglake-bootstrap-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	part, err := os.Open(partPath)
+	if err != nil {
+		return err
+	}
+	err = extractTarGz(part, tmpDir)
+	_ = part.Close()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "meta.db")); err != nil {
+		return fmt.Errorf("replication: snapshot missing meta.db: %w", err)
+	}
+	if err := replaceMetaFiles(tmpDir, dataDir, force); err != nil {
+		return err
+	}
+	clearBootstrapState(dataDir)
+
+	store, err := meta.Open(metaPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = store.Close() }()
+	store.SetSiteID(localSiteID)
+	layout := storagefs.NewLayout(filepath.Join(dataDir, "objects"))
+	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store})
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	since, err := store.MaxOplogHLC(ctx)
+	if err != nil {
+		return err
+	}
+	if since != "" {
+		if err := store.SetReplRemotePullWatermark(ctx, replRemoteKey(base), since); err != nil {
+			return err
+		}
+	}
+	if err := RunPull(remote, since, 1000, 0, 0, true, false, 0, 0, 5*time.Minute, accessKey, secretKey, region, store, eng); err != nil {
+		return err
+	}
+	return nil
+}
+
+// downloadSnapshot streams the remote snapshot into the part file in dataDir,
+// resuming with Range requests after connection failures, and returns the
+// path once the checksum advertised by the remote matches.
+func (c *replClient) downloadSnapshot(dataDir, localSiteID string) (string, error) {
+	partPath := filepath.Join(dataDir, replBootstrapPartFile)
+	statePath := filepath.Join(dataDir, replBootstrapStateFile)
+	remoteKey := replRemoteKey(c.base)
+	state := loadBootstrapState(statePath)
+	if state.Remote != remoteKey {
+		state = replBootstrapState{Remote: remoteKey}
+	}
+	backoff := 500 * time.Millisecond
+	var lastErr error
+	for attempt := 1; attempt <= replBootstrapAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		done, err := c.fetchSnapshotRange(partPath, statePath, &state, localSiteID)
+		if err == nil && done {
+			if err := verifySnapshotChecksum(partPath, state.SHA256); err != nil {
+				clearBootstrapState(dataDir)
+				return "", err
+			}
+			return partPath, nil
+		}
+		if errors.Is(err, errSnapshotExpired) {
+			state = replBootstrapState{Remote: remoteKey}
+		} else if err != nil && !isRetryableBootstrapError(err) {
+			return "", err
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+	if lastErr == nil {
+		lastErr = errors.New("snapshot incomplete")
+	}
+	return "", fmt.Errorf("replication: snapshot download failed after %d attempts: %w", replBootstrapAttempts, lastErr)
+}
+
+// fetchSnapshotRange issues one snapshot request, appending to the part file
+// when the remote honours the Range header. It reports whether the full
+// snapshot is on disk.
+func (c *replClient) fetchSnapshotRange(partPath, statePath string, state *replBootstrapState, localSiteID string) (bool, error) {
+	var offset int64
+	if state.ID != "" {
+		if info, err := os.Stat(partPath); err == nil {
+			offset = info.Size()
+		}
+		if state.Size > 0 && offset == state.Size {
+			return true, nil
+		}
+	}
+	query := url.Values{}
+	header := http.Header{}
+	if state.ID != "" {
+		query.Set("id", state.ID)
+		if offset > 0 {
+			header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+	}
+	resp, err := c.doWithHeader(http.MethodGet, "/v1/replication/snapshot", query, header, nil)
+	if err != nil {
+		return false, retryableBootstrapError{err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if remoteSite := resp.Header.Get("X-Seglake-Site-Id"); remoteSite != "" && remoteSite == localSiteID {
+		return false, fmt.Errorf("replication: remote site_id %q matches local site_id; use a distinct -site-id", remoteSite)
+	}
+	var flags int
+	switch resp.StatusCode {
+	case http.StatusOK:
+		size, _ := strconv.ParseInt(resp.Header.Get("X-Seglake-Snapshot-Size"), 10, 64)
+		*state = replBootstrapState{
+			Remote: state.Remote,
+			ID:     resp.Header.Get("X-Seglake-Snapshot-Id"),
+			SHA256: resp.Header.Get("X-Seglake-Snapshot-Sha256"),
+			Size:   size,
+		}
+		if state.ID == "" || state.SHA256 == "" {
+			return false, errors.New("replication: remote does not support resumable snapshots")
+		}
+		if err := saveBootstrapState(statePath, *state); err != nil {
+			return false, err
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			return false, errSnapshotExpired
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+	case http.StatusNotFound, http.StatusRequestedRangeNotSatisfiable:
+		if state.ID != "" {
+			return false, errSnapshotExpired
+		}
+		fallthrough
+	default:
+		payload, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("snapshot fetch failed: status=%d body=%s", resp.StatusCode, string(payload))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return false, retryableBootstrapError{err}
+		}
+		return false, err
+	}
+	// The snapshot is a copy of the remote meta.db, API secrets included.
+	file, err := os.OpenFile(partPath, flags, 0o600)
+	if err != nil {
+		return false, err
+	}
+	// A partial download left by an older release may be world-readable.
+	if err := file.Chmod(0o600); err != nil {
+		_ = file.Close()
+		return false, err
+	}
+	_, copyErr := io.Copy(file, resp.Body)
+	if err := file.Sync(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if err := file.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return false, retryableBootstrapError{copyErr}
+	}
+	info, err := os.Stat(partPath)
+	if err != nil {
+		return false, err
+	}
+	if state.Size > 0 && info.Size() < state.Size {
+		return false, retryableBootstrapError{io.ErrUnexpectedEOF}
+	}
+	return true, nil
+}
+
+type retryableBootstrapError struct {
+	err error
+}
+
+func (e retryableBootstrapError) Error() string { return e.err.Error() }
+
+func (e retryableBootstrapError) Unwrap() error { return e.err }
+
+func isRetryableBootstrapError(err error) bool {
+	var retryable retryableBootstrapError
+	return errors.As(err, &retryable)
+}
+
+func verifySnapshotChecksum(path, want string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(got, want) {
+		return fmt.Errorf("replication: snapshot checksum mismatch (got %s want %s)", got, want)
+	}
+	return nil
+}
+
+func contentRangeStart(value string) (int64, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, false
+	}
+	rangePart, _, ok := strings.Cut(strings.TrimPrefix(value, "bytes "), "/")
+	if !ok {
+		return 0, false
+	}
+	startStr, _, ok := strings.Cut(rangePart, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
+func loadBootstrapState(path string) replBootstrapState {
+	var state replBootstrapState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return replBootstrapState{}
+	}
+	return state
+}
+
+func saveBootstrapState(path string, state replBootstrapState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func clearBootstrapState(dataDir string) {
+	_ = os.Remove(filepath.Join(dataDir, replBootstrapPartFile))
+	_ = os.Remove(filepath.Join(dataDir, replBootstrapStateFile))
+}
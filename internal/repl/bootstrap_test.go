@@ -0,0 +1,126 @@
+package repl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/s3"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+// abortingWriter drops the connection after limit bytes to simulate a flaky link.
+type abortingWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (w *abortingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n, _ := w.ResponseWriter.Write(p[:w.limit])
+		w.limit -= n
+		if f, ok := w.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+		panic(http.ErrAbortHandler)
+	}
+	w.limit -= len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func TestRunBootstrapResumesInterruptedSnapshot(t *testing.T) {
+	t.Parallel()
+	remoteDir := t.TempDir()
+	remote, err := meta.Open(filepath.Join(remoteDir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open remote: %v", err)
+	}
+	t.Cleanup(func() { _ = remote.Close() })
+	remote.SetSiteID("site-a")
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(remoteDir, "objects")),
+		MetaStore: remote,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	if _, _, err := eng.PutObject(context.Background(), "bucket", "key", "", strings.NewReader("hello")); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	handler := &s3.Handler{Engine: eng, Meta: remote}
+	var ranged, aborted atomic.Int32
+	var localDir string
+	leaked := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/replication/snapshot" {
+			if r.Header.Get("Range") != "" {
+				ranged.Add(1)
+				// The interrupted download stays on disk until the resume.
+				for _, name := range []string{replBootstrapPartFile, replBootstrapStateFile} {
+					if info, err := os.Stat(filepath.Join(localDir, name)); err == nil && info.Mode().Perm() != 0o600 {
+						leaked <- name + " mode " + info.Mode().Perm().String()
+					}
+				}
+			} else if aborted.Add(1) == 1 {
+				handler.ServeHTTP(&abortingWriter{ResponseWriter: w, limit: 64}, r)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	sameSiteDir := t.TempDir()
+	if err := RunBootstrap(server.URL, "", "", "", sameSiteDir, "site-a", false); err == nil || !strings.Contains(err.Error(), "site_id") {
+		t.Fatalf("expected site_id refusal, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(sameSiteDir, "meta.db")); err == nil {
+		t.Fatalf("meta.db written despite site_id refusal")
+	}
+	aborted.Store(0)
+
+	localDir = t.TempDir()
+	if err := RunBootstrap(server.URL, "", "", "", localDir, "site-b", false); err != nil {
+		t.Fatalf("RunBootstrap: %v", err)
+	}
+	if ranged.Load() == 0 {
+		t.Fatalf("expected a ranged resume request")
+	}
+	select {
+	case msg := <-leaked:
+		t.Fatalf("partial snapshot not private: %s", msg)
+	default:
+	}
+	for _, name := range []string{replBootstrapPartFile, replBootstrapStateFile} {
+		if _, err := os.Stat(filepath.Join(localDir, name)); err == nil {
+			t.Fatalf("%s not cleaned up", name)
+		}
+	}
+	local, err := meta.Open(filepath.Join(localDir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open local: %v", err)
+	}
+	defer func() { _ = local.Close() }()
+	if _, err := local.GetObjectMeta(context.Background(), "bucket", "key"); err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	want, err := local.MaxOplogHLC(context.Background())
+	if err != nil {
+		t.Fatalf("MaxOplogHLC: %v", err)
+	}
+	got, err := local.GetReplRemotePullWatermark(context.Background(), replRemoteKey(mustParseURL(t, server.URL)))
+	if err != nil {
+		t.Fatalf("GetReplRemotePullWatermark: %v", err)
+	}
+	if want == "" || got != want {
+		t.Fatalf("watermark=%q want %q", got, want)
+	}
+}
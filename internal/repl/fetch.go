@@ -0,0 +1,212 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+)
+
+const (
+	defaultFetchConcurrency = 4
+	fetchInitialBackoff     = 200 * time.Millisecond
+)
+
+// replFetchOptions bounds the data fetch stage of a pull cycle.
+type replFetchOptions struct {
+	// concurrency is the number of parallel downloads (<= 0 = 4).
+	concurrency int
+	// backoffMax caps the delay between retries of one item (<= 0 = no cap).
+	backoffMax time.Duration
+	// deadline stops retrying failed items; each item is tried at least once.
+	deadline time.Time
+}
+
+// replFetchStats counts the data fetched during a pull cycle.
+type replFetchStats struct {
+	manifests int
+	chunks    int
+	bytes     int64
+}
+
+func (s *replFetchStats) add(other replFetchStats) {
+	s.manifests += other.manifests
+	s.chunks += other.chunks
+	s.bytes += other.bytes
+}
+
+// replFetcher downloads manifests and chunks with a bounded pool of workers.
+// Downloads run in parallel; writes into the same segment are serialized so
+// the segment size recorded in meta never goes backwards.
+type replFetcher struct {
+	client *replClient
+	eng    *engine.Engine
+	opts   replFetchOptions
+
+	mu       sync.Mutex
+	segments map[string]*sync.Mutex
+}
+
+func newReplFetcher(client *replClient, eng *engine.Engine, opts replFetchOptions) *replFetcher {
+	if opts.concurrency <= 0 {
+		opts.concurrency = defaultFetchConcurrency
+	}
+	return &replFetcher{client: client, eng: eng, opts: opts, segments: make(map[string]*sync.Mutex)}
+}
+
+// fetchManifests stores the given manifests and returns the chunks they
+// reference that are not present locally.
+func (f *replFetcher) fetchManifests(ctx context.Context, versionIDs []string) ([]replMissingChunk, replFetchStats, error) {
+	var (
+		mu      sync.Mutex
+		missing []replMissingChunk
+		stats   replFetchStats
+	)
+	err := f.run(ctx, len(versionIDs), func(ctx context.Context, i int) error {
+		var man *manifest.Manifest
+		var size int
+		err := f.retry(ctx, func() error {
+			data, err := f.client.getManifest(versionIDs[i])
+			if err != nil {
+				return err
+			}
+			size = len(data)
+			man, err = f.eng.StoreManifestBytes(ctx, data)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("repl: manifest %s: %w", versionIDs[i], err)
+		}
+		chunks, err := f.eng.MissingChunks(man)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, ch := range chunks {
+			missing = append(missing, replMissingChunk{SegmentID: ch.SegmentID, Offset: ch.Offset, Length: ch.Length})
+		}
+		stats.manifests++
+		stats.bytes += int64(size)
+		return nil
+	})
+	return missing, stats, err
+}
+
+// fetchChunks writes the given chunk ranges into local segments and returns
+// the keys of the chunks that were stored, also when another one failed.
+func (f *replFetcher) fetchChunks(ctx context.Context, chunks []replMissingChunk) ([]string, replFetchStats, error) {
+	var (
+		mu      sync.Mutex
+		fetched []string
+		stats   replFetchStats
+	)
+	err := f.run(ctx, len(chunks), func(ctx context.Context, i int) error {
+		ch := chunks[i]
+		err := f.retry(ctx, func() error {
+			data, err := f.client.getChunk(ch.SegmentID, ch.Offset, ch.Length)
+			if err != nil {
+				return err
+			}
+			return f.writeSegmentRange(ctx, ch, data)
+		})
+		if err != nil {
+			return fmt.Errorf("repl: chunk %s: %w", chunkKey(ch), err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fetched = append(fetched, chunkKey(ch))
+		stats.chunks++
+		stats.bytes += ch.Length
+		return nil
+	})
+	return fetched, stats, err
+}
+
+func (f *replFetcher) writeSegmentRange(ctx context.Context, ch replMissingChunk, data []byte) error {
+	f.mu.Lock()
+	lock, ok := f.segments[ch.SegmentID]
+	if !ok {
+		lock = &sync.Mutex{}
+		f.segments[ch.SegmentID] = lock
+	}
+	f.mu.Unlock()
+	lock.Lock()
+	defer lock.Unlock()
+	return f.eng.WriteSegmentRange(ctx, ch.SegmentID, ch.Offset, data)
+}
+
+// run calls fn for items 0..n-1 on up to concurrency workers. After the first
+// error no new items are started; the first error is returned once all
+// running items have finished.
+func (f *replFetcher) run(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	if n == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	items := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	workers := min(f.opts.concurrency, n)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range items {
+				if err := fn(ctx, i); err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+send:
+	for i := 0; i < n; i++ {
+		select {
+		case items <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(items)
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// retry calls fn until it succeeds, backing off from 200ms up to backoffMax.
+// Once the deadline has passed the last error is returned.
+func (f *replFetcher) retry(ctx context.Context, fn func() error) error {
+	backoff := fetchInitialBackoff
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if !f.opts.deadline.IsZero() && !now().Add(backoff).Before(f.opts.deadline) {
+			return fmt.Errorf("retry deadline exceeded: %w", err)
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		if f.opts.backoffMax > 0 && backoff > f.opts.backoffMax {
+			backoff = f.opts.backoffMax
+		}
+	}
+}
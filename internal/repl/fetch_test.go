@@ -0,0 +1,167 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+	"github.com/kk-code-lab/seglake/internal/storage/segment"
+)
+
+// newFetchTestServer serves one put entry whose manifest has four 4-byte
+// chunks spread over two segments. failChunk names a chunk (segment:offset)
+// that always fails.
+func newFetchTestServer(t *testing.T, failChunk string, inflight, maxInflight *int32) (*httptest.Server, [][]byte) {
+	t.Helper()
+	data := [][]byte{[]byte("aaaa"), []byte("bbbb"), []byte("cccc"), []byte("dddd")}
+	man := &manifest.Manifest{Bucket: "bucket", Key: "key", VersionID: "v1", Size: 16}
+	for i, chunk := range data {
+		man.Chunks = append(man.Chunks, manifest.ChunkRef{
+			Index:     i,
+			Hash:      segment.HashChunk(chunk),
+			SegmentID: fmt.Sprintf("seg-%d", i%2),
+			Offset:    int64(i/2) * 4,
+			Len:       4,
+		})
+	}
+	buf := &bytes.Buffer{}
+	if err := (&manifest.BinaryCodec{}).Encode(buf, man); err != nil {
+		t.Fatalf("encode manifest: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/replication/oplog":
+			_ = json.NewEncoder(w).Encode(replOplogResponse{
+				Entries: []meta.OplogEntry{{
+					SiteID:    "site-a",
+					HLCTS:     "0000000000000000002-0000000001",
+					OpType:    "put",
+					Bucket:    "bucket",
+					Key:       "key",
+					VersionID: "v1",
+				}},
+				LastHLC: "0000000000000000002-0000000001",
+			})
+		case "/v1/replication/manifest":
+			_, _ = w.Write(buf.Bytes())
+		case "/v1/replication/chunk":
+			n := atomic.AddInt32(inflight, 1)
+			defer atomic.AddInt32(inflight, -1)
+			for {
+				old := atomic.LoadInt32(maxInflight)
+				if n <= old || atomic.CompareAndSwapInt32(maxInflight, old, n) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			segmentID := r.URL.Query().Get("segmentId")
+			offset := r.URL.Query().Get("offset")
+			if segmentID+":"+offset == failChunk {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			for _, ref := range man.Chunks {
+				if ref.SegmentID == segmentID && fmt.Sprint(ref.Offset) == offset {
+					_, _ = w.Write(data[ref.Index])
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, data
+}
+
+func newFetchTestEngine(t *testing.T) (*meta.Store, *engine.Engine) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	return store, eng
+}
+
+func TestReplPullFetchesChunksConcurrently(t *testing.T) {
+	t.Parallel()
+	store, eng := newFetchTestEngine(t)
+	var inflight, maxInflight int32
+	server, data := newFetchTestServer(t, "", &inflight, &maxInflight)
+	client := &replClient{base: mustParseURL(t, server.URL), client: server.Client()}
+
+	var watermarks []string
+	advance := func(hlc string) { watermarks = append(watermarks, hlc) }
+	fetch := replFetchOptions{concurrency: 4, deadline: time.Now().Add(time.Minute)}
+	if _, _, err := runReplPullOnce(context.Background(), client, "", 100, 0, true, store, eng, newReplMissingCache(), fetch, advance); err != nil {
+		t.Fatalf("runReplPullOnce: %v", err)
+	}
+	if got := atomic.LoadInt32(&maxInflight); got < 2 {
+		t.Fatalf("expected parallel chunk fetches, max inflight=%d", got)
+	}
+	if len(watermarks) != 1 {
+		t.Fatalf("watermarks=%v", watermarks)
+	}
+	for i, want := range data {
+		got, err := eng.ReadSegmentRange(fmt.Sprintf("seg-%d", i%2), int64(i/2)*4, 4)
+		if err != nil {
+			t.Fatalf("ReadSegmentRange: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("chunk %d: got %q, want %q", i, got, want)
+		}
+	}
+	segments, err := store.ListSegments(context.Background())
+	if err != nil {
+		t.Fatalf("ListSegments: %v", err)
+	}
+	for _, seg := range segments {
+		if seg.Size != 8 {
+			t.Fatalf("segment %s size=%d, want 8", seg.ID, seg.Size)
+		}
+	}
+}
+
+func TestReplPullFetchFailureKeepsWatermark(t *testing.T) {
+	t.Parallel()
+	store, eng := newFetchTestEngine(t)
+	var inflight, maxInflight int32
+	server, _ := newFetchTestServer(t, "seg-1:4", &inflight, &maxInflight)
+	client := &replClient{base: mustParseURL(t, server.URL), client: server.Client()}
+
+	var watermarks []string
+	advance := func(hlc string) { watermarks = append(watermarks, hlc) }
+	cache := newReplMissingCache()
+	fetch := replFetchOptions{concurrency: 4, backoffMax: 100 * time.Millisecond, deadline: time.Now().Add(500 * time.Millisecond)}
+	if _, _, err := runReplPullOnce(context.Background(), client, "", 100, 0, true, store, eng, cache, fetch, advance); err == nil {
+		t.Fatalf("expected fetch error")
+	}
+	if len(watermarks) != 0 {
+		t.Fatalf("watermark advanced past unfetched data: %v", watermarks)
+	}
+	missing := cache.snapshot()
+	if _, ok := missing[chunkKey(replMissingChunk{SegmentID: "seg-1", Offset: 4, Length: 4})]; !ok || len(missing) != 1 {
+		t.Fatalf("expected only the failed chunk to stay missing, got %v", missing)
+	}
+}
@@ -21,7 +21,6 @@ import (
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/s3"
 	"github.com/kk-code-lab/seglake/internal/storage/engine"
-	storagefs "github.com/kk-code-lab/seglake/internal/storage/fs"
 )
 
 type replClient struct {
@@ -49,93 +48,37 @@ type replOplogApplyResponse struct {
 	Applied          int                `json:"applied"`
 	MissingManifests []string           `json:"missing_manifests,omitempty"`
 	MissingChunks    []replMissingChunk `json:"missing_chunks,omitempty"`
+	NextBatchSize    int                `json:"next_batch_size,omitempty"`
 }
 
-func RunBootstrap(remote, accessKey, secretKey, region, dataDir string, force bool) error {
-	if remote == "" {
-		return fmt.Errorf("replication: -repl-remote required")
-	}
-	base, err := url.Parse(remote)
-	if err != nil {
-		return err
-	}
-	if base.Scheme == "" {
-		base.Scheme = "http"
-	}
-	if base.Host == "" && base.Path != "" && !strings.Contains(base.Path, "/") {
-		base.Host = base.Path
-		base.Path = ""
-	}
-	client := &replClient{
-		base: base,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-	}
-	if accessKey != "" && secretKey != "" {
-		if region == "" {
-			region = "us-east-1"
-		}
-		client.signer = &s3.AuthConfig{
-			AccessKey:            accessKey,
-			SecretKey:            secretKey,
-			Region:               region,
-			AllowUnsignedPayload: true,
-		}
-	}
-	if dataDir == "" {
-		dataDir = "./data"
-	}
-	if err := os.MkdirAll(dataDir, 0o755); err != nil {
-		return err
-	}
-	metaPath := filepath.Join(dataDir, "meta.db")
-	if !force {
-		if _, err := os.Stat(metaPath); err == nil {
-			return fmt.Errorf("replication: meta.db exists (use -repl-bootstrap-force to overwrite)")
-		}
-	}
-	tmpDir, err := os.MkdirTemp("", "seglake-bootstrap-")
-	if err != nil {
-		return err
-	}
-	defer func() { _ = os.RemoveAll(tmpDir) }()
+// pushBatch is repl-push's adaptive batch size: it follows the peer's
+// next_batch_size (which halves when an apply is slow and grows by a fixed
+// step otherwise) and halves itself when a push fails, always staying
+// within [min, max].
+type pushBatch struct {
+	size, min, max int
+}
 
-	resp, err := client.getSnapshot()
-	if err != nil {
-		return err
-	}
-	if err := extractTarGz(resp, tmpDir); err != nil {
-		return err
-	}
-	if err := resp.Close(); err != nil {
-		return err
-	}
-	if _, err := os.Stat(filepath.Join(tmpDir, "meta.db")); err != nil {
-		return fmt.Errorf("replication: snapshot missing meta.db: %w", err)
-	}
-	if err := replaceMetaFiles(tmpDir, dataDir, force); err != nil {
-		return err
+func newPushBatch(minSize, maxSize int) *pushBatch {
+	if maxSize <= 0 {
+		maxSize = 1000
 	}
+	minSize = min(max(minSize, 1), maxSize)
+	return &pushBatch{size: maxSize, min: minSize, max: maxSize}
+}
 
-	store, err := meta.Open(metaPath)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = store.Close() }()
-	layout := storagefs.NewLayout(filepath.Join(dataDir, "objects"))
-	eng, err := engine.New(engine.Options{Layout: layout, MetaStore: store})
-	if err != nil {
-		return err
+// follow adopts the peer's suggestion; 0 (a peer without adaptive apply)
+// keeps the current size.
+func (b *pushBatch) follow(suggested int) {
+	if suggested > 0 {
+		b.size = min(max(suggested, b.min), b.max)
 	}
-	since, err := store.MaxOplogHLC(context.Background())
-	if err != nil {
-		return err
-	}
-	if err := RunPull(remote, since, 1000, true, false, 0, 0, 5*time.Minute, accessKey, secretKey, region, store, eng); err != nil {
-		return err
-	}
-	return nil
+}
+
+// shrink halves the batch after a failed push, e.g. a timeout on a peer that
+// cannot keep up.
+func (b *pushBatch) shrink() {
+	b.size = max(b.size/2, b.min)
 }
 
 type replMissingCache struct {
@@ -176,6 +119,15 @@ func (c *replMissingCache) snapshot() map[string]replMissingChunk {
 	return out
 }
 
+func (c *replMissingCache) remove(keys []string) {
+	if c == nil {
+		return
+	}
+	for _, key := range keys {
+		delete(c.chunks, key)
+	}
+}
+
 func (c *replMissingCache) clear() {
 	if c == nil {
 		return
@@ -183,7 +135,10 @@ func (c *replMissingCache) clear() {
 	c.chunks = make(map[string]replMissingChunk)
 }
 
-func RunPush(remote, since string, limit int, watch bool, interval, backoffMax time.Duration, accessKey, secretKey, region string, store *meta.Store) error {
+// RunPush pushes the local oplog to remote in batches of at most limit
+// entries; the batch adapts to the peer's apply latency but never drops
+// below minBatch (<= 0 = 1).
+func RunPush(remote, since string, limit, minBatch int, watch bool, interval, backoffMax time.Duration, accessKey, secretKey, region string, store *meta.Store) error {
 	if store == nil {
 		return fmt.Errorf("replication: store required")
 	}
@@ -219,9 +174,7 @@ func RunPush(remote, since string, limit int, watch bool, interval, backoffMax t
 			AllowUnsignedPayload: true,
 		}
 	}
-	if limit <= 0 {
-		limit = 1000
-	}
+	batch := newPushBatch(minBatch, limit)
 	ctx := context.Background()
 	if since == "" {
 		if hlc, err := store.GetReplRemotePushWatermark(ctx, remoteKey); err == nil && hlc != "" {
@@ -236,12 +189,12 @@ func RunPush(remote, since string, limit int, watch bool, interval, backoffMax t
 	}
 	backoff := interval
 	for {
-		lastHLC, pushed, applied, err := runReplPushOnce(ctx, client, store, since, limit)
+		lastHLC, pushed, applied, err := runReplPushOnce(ctx, client, store, since, batch)
 		if err != nil {
 			if !watch {
 				return err
 			}
-			fmt.Printf("repl: error=%v backoff=%s\n", err, backoff)
+			fmt.Printf("repl: error=%v backoff=%s batch=%d\n", err, backoff, batch.size)
 			time.Sleep(backoff)
 			backoff *= 2
 			if backoff > backoffMax {
@@ -262,7 +215,11 @@ func RunPush(remote, since string, limit int, watch bool, interval, backoffMax t
 	}
 }
 
-func RunPull(remote, since string, limit int, fetchData bool, watch bool, interval, backoffMax, retryTimeout time.Duration, accessKey, secretKey, region string, store *meta.Store, eng *engine.Engine) error {
+// RunPull pulls and applies the remote oplog; applyChunk bounds how many
+// entries are applied per transaction (<= 0 = the whole fetched batch) and
+// fetchConcurrency how many manifests/chunks are downloaded in parallel
+// (<= 0 = 4).
+func RunPull(remote, since string, limit, applyChunk, fetchConcurrency int, fetchData bool, watch bool, interval, backoffMax, retryTimeout time.Duration, accessKey, secretKey, region string, store *meta.Store, eng *engine.Engine) error {
 	if eng == nil {
 		return fmt.Errorf("replication: engine required")
 	}
@@ -319,8 +276,20 @@ func RunPull(remote, since string, limit int, fetchData bool, watch bool, interv
 		retryTimeout = 5 * time.Minute
 	}
 	retryDeadline := now().Add(retryTimeout)
+	// advance persists progress after each applied chunk, so a failed batch
+	// is retried from its first unapplied chunk. A failed save only costs a
+	// re-pull after restart (applying is idempotent), so it is logged.
+	advance := func(hlc string) {
+		since = hlc
+		if store != nil {
+			if err := store.SetReplRemotePullWatermark(ctx, remoteKey, hlc); err != nil {
+				fmt.Printf("repl: watermark error=%v hlc=%s\n", err, hlc)
+			}
+		}
+	}
 	for {
-		lastHLC, applied, err := runReplPullOnce(ctx, client, since, limit, fetchData, store, eng, missingCache, retryDeadline)
+		fetch := replFetchOptions{concurrency: fetchConcurrency, backoffMax: backoffMax, deadline: retryDeadline}
+		lastHLC, applied, err := runReplPullOnce(ctx, client, since, limit, applyChunk, fetchData, store, eng, missingCache, fetch, advance)
 		if err != nil {
 			if !watch {
 				return err
@@ -335,10 +304,7 @@ func RunPull(remote, since string, limit int, fetchData bool, watch bool, interv
 		}
 		backoff = interval
 		if lastHLC != "" {
-			since = lastHLC
-			if store != nil {
-				_ = store.SetReplRemotePullWatermark(ctx, remoteKey, lastHLC)
-			}
+			advance(lastHLC)
 		}
 		if !watch {
 			return nil
@@ -356,31 +322,13 @@ func chunkKey(ch replMissingChunk) string {
 	return fmt.Sprintf("%s:%d:%d", ch.SegmentID, ch.Offset, ch.Length)
 }
 
-func fetchChunkWithRetry(ctx context.Context, client *replClient, eng *engine.Engine, ch replMissingChunk, attempts int, deadline time.Time) error {
-	if attempts <= 0 {
-		attempts = 1
-	}
-	backoff := 200 * time.Millisecond
-	var lastErr error
-	for i := 0; i < attempts; i++ {
-		if !deadline.IsZero() && now().After(deadline) {
-			return fmt.Errorf("repl: retry deadline exceeded")
-		}
-		data, err := client.getChunk(ch.SegmentID, ch.Offset, ch.Length)
-		if err == nil {
-			if err := eng.WriteSegmentRange(ctx, ch.SegmentID, ch.Offset, data); err != nil {
-				return err
-			}
-			return nil
-		}
-		lastErr = err
-		time.Sleep(backoff)
-		backoff *= 2
-	}
-	return lastErr
-}
-
-func runReplPullOnce(ctx context.Context, client *replClient, since string, limit int, fetchData bool, store *meta.Store, eng *engine.Engine, cache *replMissingCache, retryDeadline time.Time) (string, int, error) {
+// runReplPullOnce fetches one oplog batch and applies it in chunks of up to
+// applyChunk entries (<= 0 = the whole batch), each in its own transaction.
+// Missing data for a chunk is fetched before advance is called with the
+// chunk's last HLC, so a crash or error mid-batch resumes after the last
+// fully applied and fetched chunk; re-applied entries are deduplicated by the
+// oplog. The data fetched in the cycle is reported once at the end.
+func runReplPullOnce(ctx context.Context, client *replClient, since string, limit, applyChunk int, fetchData bool, store *meta.Store, eng *engine.Engine, cache *replMissingCache, fetch replFetchOptions, advance func(hlc string)) (string, int, error) {
 	oplogResp, err := client.getOplog(since, limit)
 	if err != nil {
 		return "", 0, err
@@ -392,19 +340,61 @@ func runReplPullOnce(ctx context.Context, client *replClient, since string, limi
 	if store == nil {
 		return "", 0, errors.New("repl: store required")
 	}
-	applied, err := store.ApplyOplogEntries(ctx, oplogResp.Entries)
-	if err != nil {
-		return "", 0, err
+	applied := 0
+	var fetched replFetchStats
+	defer func() {
+		if fetched.manifests > 0 || fetched.chunks > 0 {
+			fmt.Printf("repl: fetched manifests=%d chunks=%d bytes=%d\n", fetched.manifests, fetched.chunks, fetched.bytes)
+		}
+	}()
+	for _, chunk := range oplogApplyChunks(oplogResp.Entries, applyChunk) {
+		n, err := store.ApplyOplogEntries(ctx, chunk)
+		if err != nil {
+			return "", applied, err
+		}
+		applied += n
+		if fetchData {
+			stats, err := fetchReplData(ctx, client, chunk, store, eng, cache, fetch)
+			fetched.add(stats)
+			if err != nil {
+				return "", applied, err
+			}
+		}
+		if advance != nil {
+			advance(chunk[len(chunk)-1].HLCTS)
+		}
 	}
 	fmt.Printf("repl: applied=%d remote_last_hlc=%s\n", applied, oplogResp.LastHLC)
+	return oplogResp.LastHLC, applied, nil
+}
 
-	if !fetchData {
-		return oplogResp.LastHLC, applied, nil
+// oplogApplyChunks splits entries into chunks of about size entries. A chunk
+// never ends between entries with the same HLC, because the watermark resumes
+// strictly after the chunk's last HLC.
+func oplogApplyChunks(entries []meta.OplogEntry, size int) [][]meta.OplogEntry {
+	if size <= 0 || size >= len(entries) {
+		return [][]meta.OplogEntry{entries}
+	}
+	var out [][]meta.OplogEntry
+	for start := 0; start < len(entries); {
+		end := min(start+size, len(entries))
+		for end < len(entries) && entries[end].HLCTS == entries[end-1].HLCTS {
+			end++
+		}
+		out = append(out, entries[start:end])
+		start = end
 	}
+	return out
+}
+
+// fetchReplData fetches manifests and chunks missing for applied put entries,
+// fetch.concurrency at a time. Data fetched before an error is kept and
+// counted; the chunks still missing stay in cache for the next cycle.
+func fetchReplData(ctx context.Context, client *replClient, entries []meta.OplogEntry, store *meta.Store, eng *engine.Engine, cache *replMissingCache, fetch replFetchOptions) (replFetchStats, error) {
 	missingManifests := make(map[string]struct{})
 	missingChunks := make(map[string]replMissingChunk)
 	if eng != nil {
-		for _, entry := range oplogResp.Entries {
+		for _, entry := range entries {
 			if entry.OpType != "put" || entry.VersionID == "" {
 				continue
 			}
@@ -414,11 +404,11 @@ func runReplPullOnce(ctx context.Context, client *replClient, since string, limi
 					missingManifests[entry.VersionID] = struct{}{}
 					continue
 				}
-				return "", applied, err
+				return replFetchStats{}, err
 			}
 			chunks, err := eng.MissingChunks(man)
 			if err != nil {
-				return "", applied, err
+				return replFetchStats{}, err
 			}
 			for _, ch := range chunks {
 				key := chunkKey(replMissingChunk{
@@ -442,59 +432,39 @@ func runReplPullOnce(ctx context.Context, client *replClient, since string, limi
 			}
 		}
 	}
-	fetchedManifests := 0
-	var fetchedBytes int64
+	fetcher := newReplFetcher(client, eng, fetch)
+	manifestIDs := make([]string, 0, len(missingManifests))
 	for versionID := range missingManifests {
-		manifestBytes, err := client.getManifest(versionID)
-		if err != nil {
-			return "", applied, err
-		}
-		fetchedBytes += int64(len(manifestBytes))
-		man, err := eng.StoreManifestBytes(ctx, manifestBytes)
-		if err != nil {
-			return "", applied, err
-		}
-		chunks, err := eng.MissingChunks(man)
-		if err != nil {
-			return "", applied, err
-		}
-		for _, ch := range chunks {
-			key := fmt.Sprintf("%s:%d:%d", ch.SegmentID, ch.Offset, ch.Length)
-			if _, ok := missingChunks[key]; !ok {
-				missingChunks[key] = replMissingChunk{
-					SegmentID: ch.SegmentID,
-					Offset:    ch.Offset,
-					Length:    ch.Length,
-				}
-			}
-		}
-		fetchedManifests++
+		manifestIDs = append(manifestIDs, versionID)
 	}
-	fetched := 0
-	if len(missingChunks) > 0 {
-		for _, ch := range missingChunks {
-			if now().After(retryDeadline) {
-				return "", applied, errors.New("repl: retry deadline exceeded")
-			}
-			if err := fetchChunkWithRetry(ctx, client, eng, ch, 3, retryDeadline); err != nil {
-				return "", applied, err
+	var stats replFetchStats
+	chunks, manifestStats, err := fetcher.fetchManifests(ctx, manifestIDs)
+	stats.add(manifestStats)
+	cache.addChunks(chunks)
+	for _, ch := range chunks {
+		if _, ok := missingChunks[chunkKey(ch)]; !ok {
+			missingChunks[chunkKey(ch)] = ch
+		}
+	}
+	if err == nil {
+		var fetched []string
+		var chunkStats replFetchStats
+		fetched, chunkStats, err = fetcher.fetchChunks(ctx, mapToChunks(missingChunks))
+		stats.add(chunkStats)
+		if cache != nil {
+			if len(fetched) == len(missingChunks) {
+				cache.clear()
+			} else {
+				cache.remove(fetched)
 			}
-			fetchedBytes += ch.Length
-			fetched++
 		}
 	}
-	if cache != nil && fetched == len(missingChunks) {
-		cache.clear()
-	}
-	if fetchedManifests > 0 || fetched > 0 {
-		fmt.Printf("repl: fetched manifests=%d chunks=%d\n", fetchedManifests, fetched)
-	}
-	if store != nil && fetchedBytes > 0 {
-		if err := store.RecordReplBytes(ctx, fetchedBytes); err != nil {
-			return "", applied, err
+	if store != nil && stats.bytes > 0 {
+		if recordErr := store.RecordReplBytes(ctx, stats.bytes); recordErr != nil && err == nil {
+			err = recordErr
 		}
 	}
-	return oplogResp.LastHLC, applied, nil
+	return stats, err
 }
 
 func mapToChunks(items map[string]replMissingChunk) []replMissingChunk {
@@ -508,8 +478,10 @@ func mapToChunks(items map[string]replMissingChunk) []replMissingChunk {
 	return out
 }
 
-func runReplPushOnce(ctx context.Context, client *replClient, store *meta.Store, since string, limit int) (string, int, int, error) {
-	entries, err := store.ListOplogSince(ctx, since, limit)
+// runReplPushOnce pushes one batch of batch.size entries and adapts
+// batch for the next one.
+func runReplPushOnce(ctx context.Context, client *replClient, store *meta.Store, since string, batch *pushBatch) (string, int, int, error) {
+	entries, err := store.ListOplogSince(ctx, since, batch.size)
 	if err != nil {
 		return "", 0, 0, err
 	}
@@ -519,11 +491,15 @@ func runReplPushOnce(ctx context.Context, client *replClient, store *meta.Store,
 	}
 	resp, err := client.applyOplog(entries)
 	if err != nil {
+		batch.shrink()
 		return "", 0, 0, err
 	}
+	batch.follow(resp.NextBatchSize)
 	lastHLC := entries[len(entries)-1].HLCTS
-	_ = store.SetReplRemotePushWatermark(ctx, replRemoteKey(client.base), lastHLC)
-	fmt.Printf("repl: pushed=%d applied=%d last_hlc=%s\n", len(entries), resp.Applied, lastHLC)
+	if err := store.SetReplRemotePushWatermark(ctx, replRemoteKey(client.base), lastHLC); err != nil {
+		fmt.Printf("repl: watermark error=%v hlc=%s\n", err, lastHLC)
+	}
+	fmt.Printf("repl: pushed=%d applied=%d batch=%d last_hlc=%s\n", len(entries), resp.Applied, batch.size, lastHLC)
 	return lastHLC, len(entries), resp.Applied, nil
 }
 
@@ -569,19 +545,6 @@ func (c *replClient) getOplog(since string, limit int) (*replOplogResponse, erro
 	return &out, nil
 }
 
-func (c *replClient) getSnapshot() (io.ReadCloser, error) {
-	resp, err := c.do(http.MethodGet, "/v1/replication/snapshot", nil, nil)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != http.StatusOK {
-		payload, _ := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		return nil, fmt.Errorf("snapshot fetch failed: status=%d body=%s", resp.StatusCode, string(payload))
-	}
-	return resp.Body, nil
-}
-
 func (c *replClient) applyOplog(entries []meta.OplogEntry) (*replOplogApplyResponse, error) {
 	body, err := json.Marshal(replOplogApplyRequest{Entries: entries})
 	if err != nil {
@@ -636,6 +599,10 @@ func (c *replClient) getChunk(segmentID string, offset, length int64) ([]byte, e
 }
 
 func (c *replClient) do(method, route string, query url.Values, body io.Reader) (*http.Response, error) {
+	return c.doWithHeader(method, route, query, nil, body)
+}
+
+func (c *replClient) doWithHeader(method, route string, query url.Values, header http.Header, body io.Reader) (*http.Response, error) {
 	if c == nil || c.base == nil {
 		return nil, errors.New("replication: client not configured")
 	}
@@ -646,7 +613,7 @@ func (c *replClient) do(method, route string, query url.Values, body io.Reader)
 	target := c.base.ResolveReference(rel)
 	urlStr := target.String()
 	if c.signer != nil {
-		presigned, err := c.signer.Presign(method, urlStr, 5*time.Minute)
+		presigned, err := c.signer.Presign(method, urlStr, "", 5*time.Minute)
 		if err != nil {
 			return nil, err
 		}
@@ -656,6 +623,11 @@ func (c *replClient) do(method, route string, query url.Values, body io.Reader)
 	if err != nil {
 		return nil, err
 	}
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
 	if method == http.MethodPost {
 		req.Header.Set("Content-Type", "application/json")
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/s3"
 	"github.com/kk-code-lab/seglake/internal/storage/engine"
 	"github.com/kk-code-lab/seglake/internal/storage/fs"
 	"github.com/kk-code-lab/seglake/internal/storage/manifest"
@@ -69,7 +71,7 @@ func TestReplPullRetriesChunk(t *testing.T) {
 
 	client := &replClient{base: mustParseURL(t, server.URL), client: server.Client()}
 	cache := newReplMissingCache()
-	if _, _, err := runReplPullOnce(context.Background(), client, "", 100, true, store, eng, cache, time.Now().Add(time.Minute)); err != nil {
+	if _, _, err := runReplPullOnce(context.Background(), client, "", 100, 0, true, store, eng, cache, replFetchOptions{deadline: time.Now().Add(time.Minute)}, nil); err != nil {
 		t.Fatalf("runReplPullOnce: %v", err)
 	}
 	data, err := eng.ReadSegmentRange("seg-test", 0, 4)
@@ -97,6 +99,93 @@ func TestReplMissingCache(t *testing.T) {
 	}
 }
 
+func TestOplogApplyChunksKeepsEqualHLCTogether(t *testing.T) {
+	t.Parallel()
+	entries := []meta.OplogEntry{{HLCTS: "1"}, {HLCTS: "2"}, {HLCTS: "2"}, {HLCTS: "2"}, {HLCTS: "3"}, {HLCTS: "4"}}
+	chunks := oplogApplyChunks(entries, 2)
+	var sizes []int
+	for _, chunk := range chunks {
+		sizes = append(sizes, len(chunk))
+	}
+	// 1,2,2,2 stay together; 3,4 form the second chunk.
+	if len(sizes) != 2 || sizes[0] != 4 || sizes[1] != 2 {
+		t.Fatalf("chunk sizes=%v", sizes)
+	}
+	if whole := oplogApplyChunks(entries, 0); len(whole) != 1 || len(whole[0]) != len(entries) {
+		t.Fatalf("expected a single chunk without a size")
+	}
+}
+
+func TestReplPullChunkedApplyAdvancesWatermark(t *testing.T) {
+	t.Parallel()
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	var broken atomic.Bool
+	broken.Store(true)
+	entries := func() []meta.OplogEntry {
+		out := make([]meta.OplogEntry, 0, 5)
+		for i := 1; i <= 5; i++ {
+			payload := `{"etag":"e","size":1,"last_modified_utc":"2025-12-22T12:00:00Z"}`
+			if i == 4 && broken.Load() {
+				payload = "{bad"
+			}
+			out = append(out, meta.OplogEntry{
+				SiteID:    "site-a",
+				HLCTS:     fmt.Sprintf("000000000000000000%d-0000000001", i),
+				OpType:    "put",
+				Bucket:    "bucket",
+				Key:       fmt.Sprintf("key%d", i),
+				VersionID: fmt.Sprintf("v%d", i),
+				Payload:   payload,
+			})
+		}
+		return out
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list := entries()
+		_ = json.NewEncoder(w).Encode(replOplogResponse{Entries: list, LastHLC: list[len(list)-1].HLCTS})
+	}))
+	t.Cleanup(server.Close)
+	client := &replClient{base: mustParseURL(t, server.URL), client: server.Client()}
+
+	var watermarks []string
+	advance := func(hlc string) { watermarks = append(watermarks, hlc) }
+	_, applied, err := runReplPullOnce(context.Background(), client, "", 100, 2, false, store, nil, nil, replFetchOptions{deadline: time.Now().Add(time.Minute)}, advance)
+	if err == nil {
+		t.Fatalf("expected apply error from the second chunk")
+	}
+	if applied != 2 || len(watermarks) != 1 || watermarks[0] != entries()[1].HLCTS {
+		t.Fatalf("applied=%d watermarks=%v", applied, watermarks)
+	}
+	if _, err := store.GetObjectMeta(context.Background(), "bucket", "key2"); err != nil {
+		t.Fatalf("first chunk not committed: %v", err)
+	}
+	if _, err := store.GetObjectMeta(context.Background(), "bucket", "key3"); err == nil {
+		t.Fatalf("failed chunk must roll back entirely")
+	}
+
+	// Replaying the whole batch re-applies the first chunk without duplicates.
+	broken.Store(false)
+	watermarks = nil
+	if _, _, err := runReplPullOnce(context.Background(), client, "", 100, 2, false, store, nil, nil, replFetchOptions{deadline: time.Now().Add(time.Minute)}, advance); err != nil {
+		t.Fatalf("runReplPullOnce: %v", err)
+	}
+	if len(watermarks) != 3 || watermarks[2] != entries()[4].HLCTS {
+		t.Fatalf("watermarks=%v", watermarks)
+	}
+	oplog, err := store.ListOplog(context.Background())
+	if err != nil {
+		t.Fatalf("ListOplog: %v", err)
+	}
+	if len(oplog) != 5 {
+		t.Fatalf("expected 5 oplog entries, got %d", len(oplog))
+	}
+}
+
 func TestReplPullRetryDeadline(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -142,7 +231,7 @@ func TestReplPullRetryDeadline(t *testing.T) {
 
 	client := &replClient{base: mustParseURL(t, server.URL), client: server.Client()}
 	cache := newReplMissingCache()
-	_, _, err = runReplPullOnce(context.Background(), client, "", 100, true, store, eng, cache, time.Now())
+	_, _, err = runReplPullOnce(context.Background(), client, "", 100, 0, true, store, eng, cache, replFetchOptions{deadline: time.Now()}, nil)
 	if err == nil {
 		t.Fatalf("expected deadline error")
 	}
@@ -179,3 +268,97 @@ func mustManifestBytes(t *testing.T, bucket, key, versionID, segmentID string, d
 	}
 	return buf.Bytes()
 }
+
+func TestReplPushAdaptsBatchToApplyLatency(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	local, err := meta.Open(filepath.Join(dir, "local.db"))
+	if err != nil {
+		t.Fatalf("meta.Open local: %v", err)
+	}
+	t.Cleanup(func() { _ = local.Close() })
+	remote, err := meta.Open(filepath.Join(dir, "remote.db"))
+	if err != nil {
+		t.Fatalf("meta.Open remote: %v", err)
+	}
+	t.Cleanup(func() { _ = remote.Close() })
+	for i := range 60 {
+		key := fmt.Sprintf("k%02d", i)
+		if err := local.RecordPut(ctx, "bucket", key, "v-"+key, "etag", 1, "", ""); err != nil {
+			t.Fatalf("RecordPut: %v", err)
+		}
+	}
+
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: remote,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+
+	// Every apply takes longer than a 1ns target, so the peer keeps halving.
+	slow := &s3.Handler{Engine: eng, Meta: remote, ReplApplyTarget: time.Nanosecond}
+	server := httptest.NewServer(slow)
+	t.Cleanup(server.Close)
+	client := &replClient{base: mustParseURL(t, server.URL), client: server.Client()}
+
+	batch := newPushBatch(4, 32)
+	var sizes []int
+	since := ""
+	for {
+		lastHLC, pushed, _, err := runReplPushOnce(ctx, client, local, since, batch)
+		if err != nil {
+			t.Fatalf("runReplPushOnce: %v", err)
+		}
+		if pushed == 0 {
+			break
+		}
+		sizes = append(sizes, pushed)
+		since = lastHLC
+	}
+	want := []int{32, 16, 8, 4}
+	if len(sizes) < len(want) {
+		t.Fatalf("batch sizes=%v, want prefix %v", sizes, want)
+	}
+	for i, n := range want {
+		if sizes[i] != n {
+			t.Fatalf("batch sizes=%v, want prefix %v", sizes, want)
+		}
+	}
+	if batch.size != 4 {
+		t.Fatalf("expected batch to settle at min 4, got %d", batch.size)
+	}
+
+	// A fast peer lets the batch grow back up to max.
+	slow.ReplApplyTarget = time.Hour
+	if err := local.RecordPut(ctx, "bucket", "late", "v-late", "etag", 1, "", ""); err != nil {
+		t.Fatalf("RecordPut: %v", err)
+	}
+	if _, _, _, err := runReplPushOnce(ctx, client, local, since, batch); err != nil {
+		t.Fatalf("runReplPushOnce: %v", err)
+	}
+	if batch.size != 32 {
+		t.Fatalf("expected batch to grow to max 32, got %d", batch.size)
+	}
+}
+
+func TestPushBatchShrinksOnError(t *testing.T) {
+	t.Parallel()
+	batch := newPushBatch(10, 100)
+	batch.shrink()
+	batch.shrink()
+	if batch.size != 25 {
+		t.Fatalf("expected 25 after two failures, got %d", batch.size)
+	}
+	batch.shrink()
+	batch.shrink()
+	if batch.size != 10 {
+		t.Fatalf("expected shrink to stop at min 10, got %d", batch.size)
+	}
+	batch.follow(0)
+	if batch.size != 10 {
+		t.Fatalf("a zero suggestion should keep the size, got %d", batch.size)
+	}
+}
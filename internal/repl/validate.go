@@ -0,0 +1,132 @@
+package repl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/ops"
+	"github.com/kk-code-lab/seglake/internal/s3"
+)
+
+const replObjectMetaAttempts = 5
+
+type replObjectMetaResponse struct {
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	VersionID string `json:"version_id"`
+	ETag      string `json:"etag"`
+	Size      int64  `json:"size"`
+	State     string `json:"state"`
+}
+
+// RunValidateSample compares a random sample of local keys with the remote's
+// current versions, resuming from the cursor stored by the previous run.
+func RunValidateSample(remote, accessKey, secretKey, region string, samplePercent float64, limit int, ratePerSecond float64, store *meta.Store) (*ops.Report, error) {
+	if store == nil {
+		return nil, fmt.Errorf("replication: meta store required")
+	}
+	if remote == "" {
+		return nil, fmt.Errorf("replication: -repl-remote required")
+	}
+	base, err := url.Parse(remote)
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme == "" {
+		base.Scheme = "http"
+	}
+	if base.Host == "" && base.Path != "" && !strings.Contains(base.Path, "/") {
+		base.Host = base.Path
+		base.Path = ""
+	}
+	client := &replClient{
+		base: base,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+	if accessKey != "" && secretKey != "" {
+		if region == "" {
+			region = "us-east-1"
+		}
+		client.signer = &s3.AuthConfig{
+			AccessKey:            accessKey,
+			SecretKey:            secretKey,
+			Region:               region,
+			AllowUnsignedPayload: true,
+		}
+	}
+	opts := ops.ReplSampleOptions{
+		Remote:        replRemoteKey(base),
+		SamplePercent: samplePercent,
+		Limit:         limit,
+		RatePerSecond: ratePerSecond,
+	}
+	return ops.ReplValidateSample(context.Background(), store, opts, client.getObjectMeta)
+}
+
+// getObjectMeta fetches the remote's current version of a key, backing off
+// when the remote asks the caller to slow down.
+func (c *replClient) getObjectMeta(ctx context.Context, bucket, key string) (*ops.RemoteObjectMeta, error) {
+	query := url.Values{}
+	query.Set("bucket", bucket)
+	query.Set("key", key)
+	backoff := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		resp, err := c.do(http.MethodGet, "/v1/replication/object-meta", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		switch resp.StatusCode {
+		case http.StatusOK:
+			var out replObjectMetaResponse
+			err := json.NewDecoder(resp.Body).Decode(&out)
+			_ = resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			return &ops.RemoteObjectMeta{
+				VersionID: out.VersionID,
+				ETag:      out.ETag,
+				Size:      out.Size,
+				State:     out.State,
+			}, nil
+		case http.StatusNotFound:
+			_ = resp.Body.Close()
+			return nil, ops.ErrRemoteObjectMissing
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			wait := retryAfter(resp.Header.Get("Retry-After"), backoff)
+			_ = resp.Body.Close()
+			if attempt >= replObjectMetaAttempts {
+				return nil, fmt.Errorf("object meta fetch throttled: status=%d", resp.StatusCode)
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+			backoff *= 2
+		default:
+			payload, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("object meta fetch failed: status=%d body=%s", resp.StatusCode, string(payload))
+		}
+	}
+}
+
+func retryAfter(value string, fallback time.Duration) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return fallback
+}
@@ -0,0 +1,84 @@
+package repl
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/s3"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func TestRunValidateSampleDetectsDivergence(t *testing.T) {
+	t.Parallel()
+	localDir := t.TempDir()
+	local, err := meta.Open(filepath.Join(localDir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open local: %v", err)
+	}
+	t.Cleanup(func() { _ = local.Close() })
+	remoteDir := t.TempDir()
+	remote, err := meta.Open(filepath.Join(remoteDir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open remote: %v", err)
+	}
+	t.Cleanup(func() { _ = remote.Close() })
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(remoteDir, "objects")),
+		MetaStore: remote,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, obj := range []struct {
+		key     string
+		version string
+		etag    string
+	}{
+		{key: "a", version: "v-a", etag: "etag-a"},
+		{key: "b", version: "v-b", etag: "etag-b"},
+		{key: "c", version: "v-c", etag: "etag-c"},
+	} {
+		if err := local.RecordPut(ctx, "bucket", obj.key, obj.version, obj.etag, 1, "", ""); err != nil {
+			t.Fatalf("RecordPut local: %v", err)
+		}
+	}
+	if err := remote.RecordPut(ctx, "bucket", "a", "v-a", "etag-a", 1, "", ""); err != nil {
+		t.Fatalf("RecordPut remote: %v", err)
+	}
+	if err := remote.RecordPut(ctx, "bucket", "b", "v-b", "etag-other", 1, "", ""); err != nil {
+		t.Fatalf("RecordPut remote: %v", err)
+	}
+
+	server := httptest.NewServer(&s3.Handler{Engine: eng, Meta: remote})
+	t.Cleanup(server.Close)
+
+	report, err := RunValidateSample(server.URL, "", "", "", 100, 2, 0, local)
+	if err != nil {
+		t.Fatalf("RunValidateSample: %v", err)
+	}
+	if report.CompareScanned != 2 || report.CompareSampled != 2 || report.CompareSampleMismatch != 1 || report.CompareSampleMissing != 0 {
+		t.Fatalf("unexpected first report: %+v", report)
+	}
+	if len(report.ErrorSample) == 0 || !strings.Contains(report.ErrorSample[0], "key=b") {
+		t.Fatalf("expected divergence for key b, got %v", report.ErrorSample)
+	}
+
+	report, err = RunValidateSample(server.URL, "", "", "", 100, 2, 0, local)
+	if err != nil {
+		t.Fatalf("RunValidateSample resume: %v", err)
+	}
+	// Resumes after b: scans c, then wraps to the start of the keyspace.
+	if report.CompareScanned != 2 || report.CompareSampleMissing != 1 || report.CompareSampleMismatch != 0 {
+		t.Fatalf("expected resume to scan c then wrap to a, got %+v", report)
+	}
+	if len(report.ErrorSample) == 0 || !strings.Contains(report.ErrorSample[0], "missing on remote: bucket=bucket key=c version=v-c") {
+		t.Fatalf("expected missing key c, got %v", report.ErrorSample)
+	}
+}
@@ -0,0 +1,134 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+// ACLs are not stored: every bucket and object reports the private canned
+// ACL (owner FULL_CONTROL), and PUT ?acl only accepts canned ACLs that keep it.
+var acceptedCannedACLs = map[string]struct{}{
+	"private":                   {},
+	"bucket-owner-full-control": {},
+}
+
+type accessControlPolicy struct {
+	XMLName           xml.Name          `xml:"AccessControlPolicy"`
+	Xmlns             string            `xml:"xmlns,attr,omitempty"`
+	Owner             owner             `xml:"Owner"`
+	AccessControlList accessControlList `xml:"AccessControlList"`
+}
+
+type accessControlList struct {
+	Grant []aclGrant `xml:"Grant"`
+}
+
+type aclGrant struct {
+	Grantee    aclGrantee `xml:"Grantee"`
+	Permission string     `xml:"Permission"`
+}
+
+type aclGrantee struct {
+	XMLNSXsi    string `xml:"xmlns:xsi,attr"`
+	Type        string `xml:"xsi:type,attr"`
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+// handleACL serves GET and PUT ?acl on a bucket (key == "") or an object.
+func (h *Handler) handleACL(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, requestID string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		writeErrorWithResource(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "", requestID, r.URL.Path)
+		return
+	}
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	bucketOwner, err := h.Meta.GetBucketOwner(ctx, bucket)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if key != "" {
+		objMeta, err := h.aclObject(ctx, r, bucket, key)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+		if err != nil || strings.EqualFold(objMeta.State, meta.VersionStateDeleteMarker) || objMeta.Expired(h.now()) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "key not found", requestID, r.URL.Path)
+			return
+		}
+	}
+	if r.Method == http.MethodPut {
+		h.handlePutACL(w, r, requestID)
+		return
+	}
+	aclOwner := h.defaultOwner()
+	if bucketOwner != "" {
+		aclOwner = owner{ID: bucketOwner, DisplayName: bucketOwner}
+	}
+	resp := accessControlPolicy{
+		Xmlns: versioningXMLNamespace,
+		Owner: aclOwner,
+		AccessControlList: accessControlList{Grant: []aclGrant{{
+			Grantee: aclGrantee{
+				XMLNSXsi:    "http://www.w3.org/2001/XMLSchema-instance",
+				Type:        "CanonicalUser",
+				ID:          aclOwner.ID,
+				DisplayName: aclOwner.DisplayName,
+			},
+			Permission: "FULL_CONTROL",
+		}}},
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(resp)
+}
+
+// handlePutACL acknowledges a canned ACL that matches the reported private
+// ACL; explicit grants and other canned ACLs are not implemented.
+func (h *Handler) handlePutACL(w http.ResponseWriter, r *http.Request, requestID string) {
+	for name := range r.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-amz-grant-") {
+			writeErrorWithResource(w, http.StatusNotImplemented, "NotImplemented", "acl grants are not implemented", requestID, r.URL.Path)
+			return
+		}
+	}
+	canned := strings.ToLower(strings.TrimSpace(r.Header.Get("x-amz-acl")))
+	if canned == "" {
+		if r.ContentLength != 0 {
+			writeErrorWithResource(w, http.StatusNotImplemented, "NotImplemented", "acl grants are not implemented", requestID, r.URL.Path)
+			return
+		}
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "x-amz-acl required", requestID, r.URL.Path)
+		return
+	}
+	if _, ok := acceptedCannedACLs[canned]; !ok {
+		writeErrorWithResource(w, http.StatusNotImplemented, "NotImplemented", "canned acl "+canned+" is not implemented", requestID, r.URL.Path)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) aclObject(ctx context.Context, r *http.Request, bucket, key string) (*meta.ObjectMeta, error) {
+	versionID := r.URL.Query().Get("versionId")
+	if versionID == "" {
+		return h.Meta.GetObjectMeta(ctx, bucket, key)
+	}
+	if versionID == "null" {
+		return h.Meta.GetNullObjectVersion(ctx, bucket, key)
+	}
+	return h.Meta.GetObjectVersion(ctx, bucket, key, versionID)
+}
@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestACLReportsPrivateAndAcceptsPrivateCanned(t *testing.T) {
+	handler := newTestHandler(t)
+	putObject(t, handler, "demo", "key", "data")
+
+	for _, target := range []string{"/demo?acl", "/demo/key?acl"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", target, rec.Code, rec.Body.String())
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, "<AccessControlPolicy") || !strings.Contains(body, "<Permission>FULL_CONTROL</Permission>") ||
+			!strings.Contains(body, `xsi:type="CanonicalUser"`) || strings.Count(body, "<Grant>") != 1 {
+			t.Fatalf("GET %s: unexpected body %s", target, body)
+		}
+	}
+
+	cases := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		body    string
+		want    int
+	}{
+		{name: "private", target: "/demo/key?acl", headers: map[string]string{"x-amz-acl": "private"}, want: http.StatusOK},
+		{name: "owner-full-control", target: "/demo/key?acl", headers: map[string]string{"x-amz-acl": "bucket-owner-full-control"}, want: http.StatusOK},
+		{name: "bucket-private", target: "/demo?acl", headers: map[string]string{"x-amz-acl": "private"}, want: http.StatusOK},
+		{name: "public-read", target: "/demo/key?acl", headers: map[string]string{"x-amz-acl": "public-read"}, want: http.StatusNotImplemented},
+		{name: "grant", target: "/demo/key?acl", headers: map[string]string{"x-amz-grant-read": "uri=http://acs.amazonaws.com/groups/global/AllUsers"}, want: http.StatusNotImplemented},
+		{name: "grant-body", target: "/demo/key?acl", body: "<AccessControlPolicy/>", want: http.StatusNotImplemented},
+		{name: "missing-key", target: "/demo/missing?acl", headers: map[string]string{"x-amz-acl": "private"}, want: http.StatusNotFound},
+		{name: "missing-bucket", target: "/nope?acl", headers: map[string]string{"x-amz-acl": "private"}, want: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPut, tc.target, strings.NewReader(tc.body))
+		for name, value := range tc.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+
+	// The ACL requests must not have touched the object or the bucket.
+	req := httptest.NewRequest(http.MethodGet, "/demo/key", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "data" {
+		t.Fatalf("object changed: %d %q", rec.Code, rec.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodDelete, "/demo?acl", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("DELETE ?acl: status %d", rec.Code)
+	}
+}
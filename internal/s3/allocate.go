@@ -0,0 +1,143 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+)
+
+// allocateHeader turns a PUT without a body into a sparse object of the
+// given size that reads as zeros and uses no segment space.
+const allocateHeader = "X-Seglake-Allocate"
+
+func isAllocateRequest(r *http.Request) bool {
+	return r.Header.Get(allocateHeader) != ""
+}
+
+// handleAllocateObject stores a zero-filled object of the requested size as a
+// new version of bucket/key. Appends to it extend the object after the holes;
+// overwrites (writeOffsetHeader) fill them.
+func (h *Handler) handleAllocateObject(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, requestID string) {
+	defer func() { _ = r.Body.Close() }()
+	if isAppendRequest(r) || isOverwriteRequest(r) || r.Header.Get("X-Amz-Copy-Source") != "" || r.Header.Get(copySourceURLHeader) != "" || r.Header.Get(moveSourceHeader) != "" {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "allocate cannot be combined with an append, overwrite, copy or move", requestID, r.URL.Path)
+		return
+	}
+	if r.ContentLength != 0 {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "allocate requires an empty body", requestID, r.URL.Path)
+		return
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(r.Header.Get(allocateHeader)), 10, 64)
+	if err != nil || size < 0 {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid "+strings.ToLower(allocateHeader), requestID, r.URL.Path)
+		return
+	}
+	if size > engine.MaxAllocateSize || (h.MaxObjectSize > 0 && size > h.MaxObjectSize) {
+		writeErrorWithResource(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "entity too large", requestID, r.URL.Path)
+		return
+	}
+	// Only the create-if-absent form of If-None-Match is meaningful here.
+	ifNoneMatch := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if ifNoneMatch != "" && ifNoneMatch != "*" {
+		writeErrorWithResource(w, http.StatusNotImplemented, "NotImplemented", "allocate supports only If-None-Match: *", requestID, r.URL.Path)
+		return
+	}
+	ttl, err := parseObjectTTL(r.Header.Get(ttlHeader))
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", err.Error(), requestID, r.URL.Path)
+		return
+	}
+	idemKey := r.Header.Get(idempotencyKeyHeader)
+	if h.Meta == nil {
+		idemKey = ""
+	}
+	if len(idemKey) > maxIdempotencyKeyLen {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "idempotency key too long", requestID, r.URL.Path)
+		return
+	}
+	if idemKey != "" && h.replayIdempotentPut(ctx, w, r, bucket, key, idemKey, requestID) {
+		return
+	}
+	if !h.enforceIfMatch(ctx, w, r, bucket, key, requestID) {
+		return
+	}
+	versioningState, err := h.bucketVersioningState(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	var extraCommit func(tx *sql.Tx, result *engine.PutResult, manifestPath string) error
+	if ttl > 0 || idemKey != "" {
+		expiresAt := h.now().Add(ttl)
+		extraCommit = func(tx *sql.Tx, result *engine.PutResult, _ string) error {
+			if ttl > 0 {
+				if err := h.Meta.SetVersionExpiryTx(tx, result.VersionID, expiresAt); err != nil {
+					return err
+				}
+			}
+			if idemKey == "" {
+				return nil
+			}
+			return h.Meta.RecordIdempotencyKeyTx(ctx, tx, bucket, key, idemKey, result.VersionID, result.ETag)
+		}
+	}
+	var absentCheck func(tx *sql.Tx) error
+	if ifNoneMatch == "*" {
+		absentCheck = h.ifNoneMatchAnyCheck(ctx, bucket, key)
+	}
+	check := combineChecks(h.idempotencyCheck(ctx, bucket, key, idemKey), h.ifMatchCheck(ctx, r, bucket, key), absentCheck)
+	contentType := strings.TrimSpace(r.Header.Get("Content-Type"))
+	_, result, err := h.Engine.AllocateObject(ctx, bucket, key, contentType, size, check, extraCommit)
+	if err != nil {
+		switch {
+		case errors.Is(err, errIdempotentReplay):
+			if h.replayIdempotentPut(ctx, w, r, bucket, key, idemKey, requestID) {
+				return
+			}
+		case errors.Is(err, engine.ErrPreconditionFailed):
+			writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "precondition failed", requestID, r.URL.Path)
+			return
+		case errors.Is(err, engine.ErrInsufficientStorage):
+			writeErrorWithResource(w, http.StatusInsufficientStorage, "InsufficientStorage", "insufficient storage", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.Header().Set("ETag", `"`+result.ETag+`"`)
+	if versionID, ok := versionIDHeaderForPut(versioningState, result.VersionID); ok {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	w.Header().Set(objectSizeHeader, strconv.FormatInt(result.Size, 10))
+	w.Header().Set("Last-Modified", formatHTTPTime(result.CommittedAt))
+	h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectCreatedPut, putResultObject(result))
+	w.WriteHeader(http.StatusOK)
+}
+
+// ifNoneMatchAnyCheck rejects the write in the commit transaction when
+// bucket/key has a current version (If-None-Match: *). Delete markers and
+// expired versions count as absent.
+func (h *Handler) ifNoneMatchAnyCheck(ctx context.Context, bucket, key string) func(tx *sql.Tx) error {
+	if h == nil || h.Meta == nil {
+		return nil
+	}
+	return func(tx *sql.Tx) error {
+		metaObj, err := h.Meta.GetObjectMetaTx(ctx, tx, bucket, key)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		if strings.EqualFold(metaObj.State, meta.VersionStateDeleteMarker) || metaObj.Expired(h.now()) {
+			return nil
+		}
+		return engine.ErrPreconditionFailed
+	}
+}
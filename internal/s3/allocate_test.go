@@ -0,0 +1,197 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/clock"
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+)
+
+func TestAllocateObjectCreatesSparseObject(t *testing.T) {
+	h := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPut, "/bucket/sparse", nil)
+	req.Header.Set(allocateHeader, "1024")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("allocate status=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(objectSizeHeader); got != "1024" {
+		t.Fatalf("size=%q", got)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"e4384ce6618d6cff0050709838612d31-1"` {
+		t.Fatalf("unexpected etag %s", etag)
+	}
+	segments, err := h.Meta.ListSegments(context.Background())
+	if err != nil {
+		t.Fatalf("ListSegments: %v", err)
+	}
+	if len(segments) != 0 {
+		t.Fatalf("allocation wrote segments: %+v", segments)
+	}
+
+	if w := appendRequest(t, h, "/bucket/sparse", "1024", "tail"); w.Code != http.StatusOK {
+		t.Fatalf("append status=%d body=%s", w.Code, w.Body.String())
+	}
+	get := httptest.NewRecorder()
+	h.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/bucket/sparse", nil))
+	body, _ := io.ReadAll(get.Body)
+	if want := append(make([]byte, 1024), "tail"...); get.Code != http.StatusOK || !bytes.Equal(body, want) {
+		t.Fatalf("GET status=%d len=%d", get.Code, len(body))
+	}
+
+	for _, tc := range []struct {
+		name, size, body string
+	}{
+		{name: "body", size: "4", body: "data"},
+		{name: "negative", size: "-1"},
+		{name: "not a number", size: "big"},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/bucket/bad", strings.NewReader(tc.body))
+		req.Header.Set(allocateHeader, tc.size)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status=%d", tc.name, w.Code)
+		}
+	}
+	huge := httptest.NewRequest(http.MethodPut, "/bucket/huge", nil)
+	huge.Header.Set(allocateHeader, strconv.FormatInt(engine.MaxAllocateSize+1, 10))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, huge)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversize allocate status=%d", w.Code)
+	}
+}
+
+func TestAllocateObjectHonorsConditionsTTLAndIdempotency(t *testing.T) {
+	h := newTestHandler(t)
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	h.Clock = clock.FixedClock{T: start}
+	allocate := func(key string, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, "/bucket/"+key, nil)
+		req.Header.Set(allocateHeader, "16")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	putObject(t, h, "bucket", "existing", "data")
+
+	cases := []struct {
+		name   string
+		key    string
+		header map[string]string
+		want   int
+	}{
+		{"if-match mismatch", "existing", map[string]string{"If-Match": `"nope"`}, http.StatusPreconditionFailed},
+		{"if-match missing key", "absent", map[string]string{"If-Match": `"nope"`}, http.StatusPreconditionFailed},
+		{"if-none-match existing", "existing", map[string]string{"If-None-Match": "*"}, http.StatusPreconditionFailed},
+		{"if-none-match etag", "absent", map[string]string{"If-None-Match": `"abc"`}, http.StatusNotImplemented},
+		{"invalid ttl", "absent", map[string]string{ttlHeader: "soon"}, http.StatusBadRequest},
+		{"if-none-match absent", "fresh", map[string]string{"If-None-Match": "*"}, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if w := allocate(tc.key, tc.header); w.Code != tc.want {
+			t.Fatalf("%s: status=%d want %d body=%s", tc.name, w.Code, tc.want, w.Body.String())
+		}
+	}
+	get := httptest.NewRecorder()
+	h.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/bucket/existing", nil))
+	if get.Body.String() != "data" {
+		t.Fatalf("rejected allocate overwrote existing: %q", get.Body.String())
+	}
+
+	if w := allocate("temp", map[string]string{ttlHeader: "60"}); w.Code != http.StatusOK {
+		t.Fatalf("ttl allocate status=%d body=%s", w.Code, w.Body.String())
+	}
+	tempMeta, err := h.Meta.GetObjectMeta(context.Background(), "bucket", "temp")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if want := start.Add(time.Minute).Format(meta.ExpiryTimeFormat); tempMeta.ExpiresAt != want {
+		t.Fatalf("expires_at=%q want %q", tempMeta.ExpiresAt, want)
+	}
+
+	first := allocate("idem", map[string]string{idempotencyKeyHeader: "retry-1"})
+	if first.Code != http.StatusOK {
+		t.Fatalf("idempotent allocate status=%d body=%s", first.Code, first.Body.String())
+	}
+	idemMeta, err := h.Meta.GetObjectMeta(context.Background(), "bucket", "idem")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	retry := allocate("idem", map[string]string{idempotencyKeyHeader: "retry-1"})
+	if retry.Code != http.StatusOK || retry.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Fatalf("retry status=%d etag=%s", retry.Code, retry.Header().Get("ETag"))
+	}
+	after, err := h.Meta.GetObjectMeta(context.Background(), "bucket", "idem")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if after.VersionID != idemMeta.VersionID {
+		t.Fatalf("retry created version %s, want %s", after.VersionID, idemMeta.VersionID)
+	}
+}
+
+func overwriteRequest(t *testing.T, h *Handler, path, offset, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	req.Header.Set(writeOffsetHeader, offset)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestOverwriteFillsAllocatedObject(t *testing.T) {
+	h := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPut, "/bucket/sparse", nil)
+	req.Header.Set(allocateHeader, "1024")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("allocate status=%d body=%s", w.Code, w.Body.String())
+	}
+	w = overwriteRequest(t, h, "/bucket/sparse", "10", "hello")
+	if w.Code != http.StatusOK {
+		t.Fatalf("overwrite status=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(objectSizeHeader); got != "1024" {
+		t.Fatalf("size=%q", got)
+	}
+	if w := overwriteRequest(t, h, "/bucket/sparse", "12", "LL"); w.Code != http.StatusOK {
+		t.Fatalf("second overwrite status=%d body=%s", w.Code, w.Body.String())
+	}
+	get := httptest.NewRecorder()
+	h.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/bucket/sparse", nil))
+	body, _ := io.ReadAll(get.Body)
+	want := make([]byte, 1024)
+	copy(want[10:], "heLLo")
+	if get.Code != http.StatusOK || !bytes.Equal(body, want) {
+		t.Fatalf("GET status=%d len=%d", get.Code, len(body))
+	}
+
+	if w := overwriteRequest(t, h, "/bucket/sparse", "1020", "12345"); w.Code != http.StatusConflict || w.Header().Get(objectSizeHeader) != "1024" {
+		t.Fatalf("past end status=%d size=%q", w.Code, w.Header().Get(objectSizeHeader))
+	}
+	if w := overwriteRequest(t, h, "/bucket/missing", "0", "x"); w.Code != http.StatusNotFound {
+		t.Fatalf("missing key status=%d", w.Code)
+	}
+	if w := overwriteRequest(t, h, "/bucket/sparse", "-1", "x"); w.Code != http.StatusBadRequest {
+		t.Fatalf("negative offset status=%d", w.Code)
+	}
+}
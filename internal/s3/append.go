@@ -0,0 +1,215 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+)
+
+const (
+	// appendHeader turns a PUT into an append to the current version.
+	appendHeader = "X-Seglake-Append"
+	// appendPositionHeader must equal the current object size, so concurrent
+	// appenders cannot interleave.
+	appendPositionHeader = "X-Seglake-Append-Position"
+	// objectSizeHeader reports the object size after the append, i.e. the
+	// position of the next append.
+	objectSizeHeader = "X-Seglake-Object-Size"
+)
+
+var errAppendPositionChanged = fmt.Errorf("%w: append position changed", engine.ErrPreconditionFailed)
+
+func isAppendRequest(r *http.Request) bool {
+	return strings.EqualFold(strings.TrimSpace(r.Header.Get(appendHeader)), "true")
+}
+
+// handleAppendObject writes the request body after the current version of
+// bucket/key as a new version. The new manifest references the existing
+// chunks, so only the appended bytes are written.
+func (h *Handler) handleAppendObject(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, requestID string) {
+	defer func() { _ = r.Body.Close() }()
+	if r.Header.Get("X-Amz-Copy-Source") != "" || r.Header.Get(copySourceURLHeader) != "" || r.Header.Get(moveSourceHeader) != "" {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "append cannot be combined with a copy or move source", requestID, r.URL.Path)
+		return
+	}
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	position, err := strconv.ParseInt(strings.TrimSpace(r.Header.Get(appendPositionHeader)), 10, 64)
+	if err != nil || position < 0 {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid "+strings.ToLower(appendPositionHeader), requestID, r.URL.Path)
+		return
+	}
+	var base *meta.ObjectMeta
+	current, err := h.Meta.GetObjectMeta(ctx, bucket, key)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	case strings.EqualFold(current.State, meta.VersionStateDeleteMarker) || current.Expired(h.now()):
+	case strings.EqualFold(current.State, meta.VersionStateDamaged):
+		w.Header().Set("X-Error", "DamagedObject")
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "object damaged", requestID, r.URL.Path)
+		return
+	default:
+		base = current
+	}
+	baseSize := int64(0)
+	if base != nil {
+		baseSize = base.Size
+	}
+	if position != baseSize {
+		w.Header().Set(objectSizeHeader, strconv.FormatInt(baseSize, 10))
+		writeErrorWithResource(w, http.StatusConflict, "InvalidWriteOffset", "append position does not match object size", requestID, r.URL.Path)
+		return
+	}
+	contentLength, hasLength, err := contentLengthFromRequest(r)
+	if err != nil {
+		switch err {
+		case errMissingContentLength:
+			writeErrorWithResource(w, http.StatusLengthRequired, "MissingContentLength", "missing content length", requestID, r.URL.Path)
+		default:
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid content length", requestID, r.URL.Path)
+		}
+		return
+	}
+	reader := io.Reader(r.Body)
+	reader, streamingMode, decodedLen, hasDecoded, reqErr := setupStreamingReader(r, reader, h.DisableTrailerChecksums)
+	if reqErr != nil {
+		writeErrorWithResource(w, reqErr.status, reqErr.code, reqErr.message, requestID, r.URL.Path)
+		return
+	}
+	if h.MaxObjectSize > 0 {
+		remaining := h.MaxObjectSize - baseSize
+		switch {
+		case streamingMode != streamingNone && hasDecoded && decodedLen > remaining:
+			writeErrorWithResource(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "entity too large", requestID, r.URL.Path)
+			return
+		case hasLength && contentLength > remaining:
+			writeErrorWithResource(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "entity too large", requestID, r.URL.Path)
+			return
+		}
+		if !hasLength {
+			reader = newSizeLimitReader(reader, remaining)
+		}
+	}
+	expectedMD5, err := parseContentMD5(r.Header.Get("Content-MD5"))
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidDigest", "invalid content-md5", requestID, r.URL.Path)
+		return
+	}
+	if h.RequireContentMD5 && len(expectedMD5) == 0 {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidDigest", "content-md5 required", requestID, r.URL.Path)
+		return
+	}
+	payloadHash := ""
+	verifyPayload := false
+	if streamingMode == streamingNone {
+		if hashHeader := r.Header.Get("X-Amz-Content-Sha256"); hashHeader != "" {
+			expected, verify, err := parsePayloadHash(hashHeader)
+			if err != nil {
+				writeErrorWithResource(w, http.StatusBadRequest, "InvalidDigest", "invalid payload hash", requestID, r.URL.Path)
+				return
+			}
+			payloadHash = expected
+			verifyPayload = verify
+		}
+	}
+	if verifyPayload || len(expectedMD5) > 0 {
+		reader = newValidatingReader(reader, payloadHash, verifyPayload, expectedMD5)
+	}
+	versioningState, err := h.bucketVersioningState(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	contentType := strings.TrimSpace(r.Header.Get("Content-Type"))
+	var result *engine.PutResult
+	if base == nil {
+		_, result, err = h.Engine.PutObjectWithCheck(ctx, bucket, key, contentType, reader, h.appendBaseCheck(ctx, bucket, key, ""), nil)
+	} else {
+		var man *manifest.Manifest
+		man, err = h.Engine.GetManifest(ctx, base.VersionID)
+		if err != nil {
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+		if contentType == "" {
+			contentType = base.ContentType
+		}
+		_, result, err = h.Engine.AppendObject(ctx, bucket, key, contentType, man, base.ETag, reader, h.appendBaseCheck(ctx, bucket, key, base.VersionID), nil)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errAppendPositionChanged):
+			writeErrorWithResource(w, http.StatusConflict, "InvalidWriteOffset", "object changed during append", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errPayloadHashMismatch):
+			writeErrorWithResource(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "payload hash mismatch", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errInvalidDigest):
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidDigest", "invalid payload hash", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errBadDigest):
+			writeErrorWithResource(w, http.StatusBadRequest, "BadDigest", "content-md5 mismatch", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errInvalidContentLength):
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid content length", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errEntityTooLarge):
+			writeErrorWithResource(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "entity too large", requestID, r.URL.Path)
+			return
+		case errors.Is(err, engine.ErrInsufficientStorage):
+			writeErrorWithResource(w, http.StatusInsufficientStorage, "InsufficientStorage", "insufficient storage", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.Header().Set("ETag", `"`+result.ETag+`"`)
+	if versionID, ok := versionIDHeaderForPut(versioningState, result.VersionID); ok {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	w.Header().Set(objectSizeHeader, strconv.FormatInt(result.Size, 10))
+	w.Header().Set("Last-Modified", formatHTTPTime(result.CommittedAt))
+	h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectCreatedPut, putResultObject(result))
+	w.WriteHeader(http.StatusOK)
+}
+
+// appendBaseCheck rejects the append in the commit transaction when the
+// current version is no longer versionID ("" = no live object).
+func (h *Handler) appendBaseCheck(ctx context.Context, bucket, key, versionID string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		metaObj, err := h.Meta.GetObjectMetaTx(ctx, tx, bucket, key)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) && versionID == "" {
+				return nil
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return errAppendPositionChanged
+			}
+			return err
+		}
+		if versionID == "" {
+			if strings.EqualFold(metaObj.State, meta.VersionStateDeleteMarker) || metaObj.Expired(h.now()) {
+				return nil
+			}
+			return errAppendPositionChanged
+		}
+		if metaObj.VersionID != versionID {
+			return errAppendPositionChanged
+		}
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func appendRequest(t *testing.T, h *Handler, path, position, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	req.Header.Set(appendHeader, "true")
+	req.Header.Set(appendPositionHeader, position)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAppendObjectChecksPosition(t *testing.T) {
+	h := newTestHandler(t)
+
+	w := appendRequest(t, h, "/bucket/log", "0", "first\n")
+	if w.Code != http.StatusOK {
+		t.Fatalf("create status=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(objectSizeHeader); got != "6" {
+		t.Fatalf("size after create=%q", got)
+	}
+	firstVersion := w.Header().Get("x-amz-version-id")
+
+	w = appendRequest(t, h, "/bucket/log", "6", "second\n")
+	if w.Code != http.StatusOK {
+		t.Fatalf("append status=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(objectSizeHeader); got != "13" {
+		t.Fatalf("size after append=%q", got)
+	}
+	if got := w.Header().Get("x-amz-version-id"); got == "" || got == firstVersion {
+		t.Fatalf("expected a new version, got %q", got)
+	}
+	if etag := w.Header().Get("ETag"); !strings.HasSuffix(etag, `-2"`) {
+		t.Fatalf("unexpected etag %s", etag)
+	}
+
+	w = appendRequest(t, h, "/bucket/log", "6", "stale\n")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "InvalidWriteOffset") {
+		t.Fatalf("stale position: status=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(objectSizeHeader); got != "13" {
+		t.Fatalf("size on conflict=%q", got)
+	}
+	if w := appendRequest(t, h, "/bucket/log", "", "x"); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing position: status=%d", w.Code)
+	}
+
+	get := httptest.NewRecorder()
+	h.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/bucket/log", nil))
+	body, _ := io.ReadAll(get.Body)
+	if get.Code != http.StatusOK || string(body) != "first\nsecond\n" {
+		t.Fatalf("GET status=%d body=%q", get.Code, body)
+	}
+}
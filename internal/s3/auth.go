@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"sort"
 	"strings"
@@ -25,7 +26,26 @@ type AuthConfig struct {
 	MaxSkew              time.Duration
 	AllowUnsignedPayload bool
 	SecretLookup         func(ctx context.Context, accessKey string) (string, bool, error)
-	Clock                clock.Clock
+	// SecretsLookup replaces SecretLookup when set: besides the current
+	// secret and enabled state it returns the previous secret while a
+	// rotation overlap is open ("" = none), in one lookup.
+	SecretsLookup func(ctx context.Context, accessKey string) (string, string, bool, error)
+	Clock         clock.Clock
+	// AllowedRegions are credential-scope regions accepted besides Region
+	// ("*" accepts any). Region stays the one reported in responses.
+	AllowedRegions []string
+	// MaxPresignExpiry caps X-Amz-Expires of presigned URLs (0 = 7 days, the S3 limit).
+	MaxPresignExpiry time.Duration
+}
+
+// maxPresignExpiry is the S3 limit on presigned URL lifetimes.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+func (c *AuthConfig) presignExpiryLimit() time.Duration {
+	if c == nil || c.MaxPresignExpiry <= 0 || c.MaxPresignExpiry > maxPresignExpiry {
+		return maxPresignExpiry
+	}
+	return c.MaxPresignExpiry
 }
 
 func (c *AuthConfig) now() time.Time {
@@ -37,7 +57,7 @@ func (c *AuthConfig) now() time.Time {
 
 // VerifyRequest validates AWS SigV4 Authorization headers.
 func (c *AuthConfig) VerifyRequest(r *http.Request) error {
-	if c == nil || (c.AccessKey == "" && c.SecretKey == "" && c.SecretLookup == nil) {
+	if c == nil || (c.AccessKey == "" && c.SecretKey == "" && c.SecretLookup == nil && c.SecretsLookup == nil) {
 		return nil
 	}
 	if r.URL.Query().Get("X-Amz-Algorithm") != "" {
@@ -78,10 +98,10 @@ func (c *AuthConfig) VerifyRequest(r *http.Request) error {
 	if term != "aws4_request" || service != "s3" {
 		return errSignatureMismatch
 	}
-	if c.Region != "" && region != normalizeRegion(c.Region) {
+	if !c.regionAllowed(region) {
 		return errSignatureMismatch
 	}
-	secretKey, err := c.secretFor(r.Context(), accessKey)
+	secrets, err := c.secretsFor(r.Context(), accessKey)
 	if err != nil {
 		return err
 	}
@@ -145,9 +165,8 @@ func (c *AuthConfig) VerifyRequest(r *http.Request) error {
 		hex.EncodeToString(hashed[:]),
 	}, "\n")
 
-	signingKey := deriveSigningKey(secretKey, dateScope, regionRaw, "s3")
-	expected := hmacSHA256Hex(signingKey, stringToSign)
-	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(strings.ToLower(expected))) {
+	signingKey, ok := matchSigningKey(secrets, dateScope, regionRaw, stringToSign, signature)
+	if !ok {
 		return errSignatureMismatch
 	}
 	sigCtx := &sigv4Context{
@@ -159,7 +178,9 @@ func (c *AuthConfig) VerifyRequest(r *http.Request) error {
 		amzDate:       amzDate,
 		scope:         scope,
 	}
-	*r = *r.WithContext(context.WithValue(r.Context(), sigv4ContextKey{}, sigCtx))
+	ctx := context.WithValue(r.Context(), sigv4ContextKey{}, sigCtx)
+	ctx = context.WithValue(ctx, signedHeadersKey{}, &signedHeaderSet{headers: signedHeadersLower})
+	*r = *r.WithContext(ctx)
 	return nil
 }
 
@@ -191,10 +212,10 @@ func (c *AuthConfig) verifyPresigned(r *http.Request) error {
 	if term != "aws4_request" || service != "s3" {
 		return errSignatureMismatch
 	}
-	if c.Region != "" && region != normalizeRegion(c.Region) {
+	if !c.regionAllowed(region) {
 		return errSignatureMismatch
 	}
-	secretKey, err := c.secretFor(r.Context(), accessKey)
+	secrets, err := c.secretsFor(r.Context(), accessKey)
 	if err != nil {
 		return err
 	}
@@ -208,9 +229,13 @@ func (c *AuthConfig) verifyPresigned(r *http.Request) error {
 		maxSkew = 5 * time.Minute
 	}
 	expSeconds, err := parseInt(expires)
-	if err != nil || expSeconds < 1 || expSeconds > 604800 {
+	if err != nil {
 		return errAuthMalformed
 	}
+	// Compare in seconds: a huge X-Amz-Expires would overflow a Duration.
+	if expSeconds < 1 || expSeconds > int64(c.presignExpiryLimit()/time.Second) {
+		return errPresignExpiry
+	}
 	delta := time.Since(reqTime)
 	if delta > time.Duration(expSeconds)*time.Second {
 		return errSignatureMismatch
@@ -249,41 +274,68 @@ func (c *AuthConfig) verifyPresigned(r *http.Request) error {
 		hex.EncodeToString(hashed[:]),
 	}, "\n")
 
-	signingKey := deriveSigningKey(secretKey, dateScope, regionRaw, "s3")
-	expected := hmacSHA256Hex(signingKey, stringToSign)
-	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(strings.ToLower(expected))) {
+	if _, ok := matchSigningKey(secrets, dateScope, regionRaw, stringToSign, signature); !ok {
 		return errSignatureMismatch
 	}
+	*r = *r.WithContext(context.WithValue(r.Context(), signedHeadersKey{}, &signedHeaderSet{presigned: true, headers: signedHeadersLower}))
 	return nil
 }
 
-func (c *AuthConfig) secretFor(ctx context.Context, accessKey string) (string, error) {
+// secretsFor returns the secrets accepted for accessKey: the current one and,
+// during a rotation overlap, the previous one.
+func (c *AuthConfig) secretsFor(ctx context.Context, accessKey string) ([]string, error) {
 	if accessKey == "" {
-		return "", errAccessDenied
+		return nil, errAccessDenied
 	}
 	if c.AccessKey != "" && accessKey == c.AccessKey {
 		if c.SecretKey == "" {
-			return "", errAccessDenied
+			return nil, errAccessDenied
 		}
-		return c.SecretKey, nil
+		return []string{c.SecretKey}, nil
 	}
 	if c.OpsAccessKey != "" && accessKey == c.OpsAccessKey {
 		if c.OpsSecretKey == "" {
-			return "", errAccessDenied
+			return nil, errAccessDenied
 		}
-		return c.OpsSecretKey, nil
-	}
-	if c.SecretLookup == nil {
-		return "", errAccessDenied
+		return []string{c.OpsSecretKey}, nil
+	}
+	var (
+		secret, previous string
+		enabled          bool
+		err              error
+	)
+	switch {
+	case c.SecretsLookup != nil:
+		secret, previous, enabled, err = c.SecretsLookup(ctx, accessKey)
+	case c.SecretLookup != nil:
+		secret, enabled, err = c.SecretLookup(ctx, accessKey)
+	default:
+		return nil, errAccessDenied
 	}
-	secret, enabled, err := c.SecretLookup(ctx, accessKey)
 	if err != nil {
-		return "", errAccessDenied
+		return nil, errAccessDenied
 	}
 	if !enabled || secret == "" {
-		return "", errAccessDenied
+		return nil, errAccessDenied
+	}
+	// The previous secret is only tried while its overlap window is open.
+	if previous != "" && previous != secret {
+		return []string{secret, previous}, nil
+	}
+	return []string{secret}, nil
+}
+
+// matchSigningKey returns the signing key of the first secret whose
+// signature over stringToSign equals signature.
+func matchSigningKey(secrets []string, dateScope, regionRaw, stringToSign, signature string) ([]byte, bool) {
+	for _, secret := range secrets {
+		signingKey := deriveSigningKey(secret, dateScope, regionRaw, "s3")
+		expected := hmacSHA256Hex(signingKey, stringToSign)
+		if hmac.Equal([]byte(strings.ToLower(signature)), []byte(strings.ToLower(expected))) {
+			return signingKey, true
+		}
 	}
-	return secret, nil
+	return nil, false
 }
 
 var (
@@ -291,6 +343,7 @@ var (
 	errAccessDenied         = errors.New("access denied")
 	errTimeSkew             = errors.New("request time too skewed")
 	errAuthMalformed        = errors.New("authorization header malformed")
+	errPresignExpiry        = errors.New("presigned url expiry out of range")
 	errMissingContentSHA256 = errors.New("missing x-amz-content-sha256")
 )
 
@@ -353,34 +406,7 @@ func canonicalQuery(r *http.Request) string {
 }
 
 func canonicalQueryPresigned(r *http.Request) string {
-	values := r.URL.Query()
-	values.Del("X-Amz-Signature")
-	type pair struct {
-		k string
-		v string
-	}
-	var pairs []pair
-	for k, vs := range values {
-		for _, v := range vs {
-			pairs = append(pairs, pair{encodeRfc3986(k), encodeRfc3986(v)})
-		}
-	}
-	sort.Slice(pairs, func(i, j int) bool {
-		if pairs[i].k == pairs[j].k {
-			return pairs[i].v < pairs[j].v
-		}
-		return pairs[i].k < pairs[j].k
-	})
-	var b strings.Builder
-	for i, p := range pairs {
-		if i > 0 {
-			b.WriteByte('&')
-		}
-		b.WriteString(p.k)
-		b.WriteByte('=')
-		b.WriteString(p.v)
-	}
-	return b.String()
+	return canonicalQueryPresignedFromValues(r.URL.Query())
 }
 
 func buildCanonicalHeaders(r *http.Request, signedHeaders string) (string, []string, error) {
@@ -422,11 +448,94 @@ func hasSignedHeader(headers []string, name string) bool {
 	return false
 }
 
+type signedHeadersKey struct{}
+
+// signedHeaderSet records which headers a verified signature covered.
+type signedHeaderSet struct {
+	presigned bool
+	headers   []string
+}
+
+func signedHeadersFromRequest(r *http.Request) (*signedHeaderSet, bool) {
+	if r == nil {
+		return nil, false
+	}
+	set, ok := r.Context().Value(signedHeadersKey{}).(*signedHeaderSet)
+	if !ok || set == nil {
+		return nil, false
+	}
+	return set, true
+}
+
+// unsignedTransportHeaders are x-amz-* and x-seglake-* headers that do not
+// change what gets stored, so presigned requests may carry them unsigned.
+// The trace headers are commonly added by proxies and SDKs after signing.
+var unsignedTransportHeaders = map[string]struct{}{
+	"x-amz-content-sha256": {},
+	"x-amz-date":           {},
+	"x-amz-request-id":     {},
+	"x-amz-security-token": {},
+	"x-amz-user-agent":     {},
+	traceIDHeader:          {},
+}
+
+// unsignedHeaderError rejects a presigned request carrying a sensitive
+// header its signature did not cover.
+type unsignedHeaderError struct {
+	name string
+}
+
+func (e unsignedHeaderError) Error() string {
+	return "header " + e.name + " must be signed"
+}
+
+// unsignedSensitiveHeader returns the first header of a presigned request
+// that affects what the request does (Content-MD5, x-amz-* including the copy
+// source, x-seglake-*) but was not signed, or "".
+func unsignedSensitiveHeader(r *http.Request) string {
+	set, ok := signedHeadersFromRequest(r)
+	if !ok || !set.presigned {
+		return ""
+	}
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name != "content-md5" && !strings.HasPrefix(name, "x-amz-") && !strings.HasPrefix(name, "x-seglake-") {
+			continue
+		}
+		if _, ok := unsignedTransportHeaders[name]; ok {
+			continue
+		}
+		if !hasSignedHeader(set.headers, name) {
+			return name
+		}
+	}
+	return ""
+}
+
 func normalizeSpaces(s string) string {
 	fields := strings.Fields(s)
 	return strings.Join(fields, " ")
 }
 
+// regionAllowed reports whether a normalized credential-scope region may sign
+// requests. An empty Region accepts any region.
+func (c *AuthConfig) regionAllowed(region string) bool {
+	if c.Region == "" || region == normalizeRegion(c.Region) {
+		return true
+	}
+	for _, allowed := range c.AllowedRegions {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || region == normalizeRegion(allowed) {
+			return true
+		}
+	}
+	return false
+}
+
 func normalizeRegion(r string) string {
 	r = strings.ToLower(r)
 	if r == "us" {
@@ -454,6 +563,9 @@ func parseInt(s string) (int64, error) {
 		if s[i] < '0' || s[i] > '9' {
 			return 0, errors.New("invalid integer")
 		}
+		if v > (math.MaxInt64-int64(s[i]-'0'))/10 {
+			return 0, errors.New("integer out of range")
+		}
 		v = v*10 + int64(s[i]-'0')
 	}
 	return v, nil
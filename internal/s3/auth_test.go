@@ -1,14 +1,19 @@
 package s3
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
 )
 
 func TestSigV4RejectsWrongRegion(t *testing.T) {
@@ -23,6 +28,53 @@ func TestSigV4RejectsWrongRegion(t *testing.T) {
 	}
 }
 
+func TestSigV4AcceptsAllowedRegions(t *testing.T) {
+	auth := &AuthConfig{AccessKey: "test", SecretKey: "testsecret", Region: "eu-west-1", AllowedRegions: []string{"us-east-1", " ap-south-1 "}, AllowUnsignedPayload: true}
+	for _, tc := range []struct {
+		region string
+		ok     bool
+	}{
+		{"eu-west-1", true},
+		{"us-east-1", true},
+		{"us", true},
+		{"ap-south-1", true},
+		{"us-west-2", false},
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/bucket/key", nil)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		signRequestTest(req, "test", "testsecret", tc.region)
+		if err := auth.VerifyRequest(req); (err == nil) != tc.ok {
+			t.Fatalf("header region %s: err=%v want ok=%v", tc.region, err, tc.ok)
+		}
+		presigned, err := auth.Presign(http.MethodGet, "http://example.com/bucket/key", tc.region, time.Minute)
+		if err != nil {
+			t.Fatalf("Presign: %v", err)
+		}
+		if !strings.Contains(presigned, "%2F"+tc.region+"%2Fs3%2F") {
+			t.Fatalf("presigned url not scoped to %s: %s", tc.region, presigned)
+		}
+		req, err = http.NewRequest(http.MethodGet, presigned, nil)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		if err := auth.VerifyRequest(req); (err == nil) != tc.ok {
+			t.Fatalf("presigned region %s: err=%v want ok=%v", tc.region, err, tc.ok)
+		}
+	}
+
+	wildcard := &AuthConfig{AccessKey: "test", SecretKey: "testsecret", Region: "eu-west-1", AllowedRegions: []string{"*"}, AllowUnsignedPayload: true}
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/bucket/key", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	signRequestTest(req, "test", "testsecret", "sa-east-1")
+	if err := wildcard.VerifyRequest(req); err != nil {
+		t.Fatalf("wildcard region: %v", err)
+	}
+}
+
 func TestSigV4RejectsWrongService(t *testing.T) {
 	req, err := http.NewRequest(http.MethodGet, "http://example.com/bucket/key", nil)
 	if err != nil {
@@ -249,3 +301,106 @@ func sha256SumHex(input string) string {
 	sum := sha256.Sum256([]byte(input))
 	return hex.EncodeToString(sum[:])
 }
+
+func TestPresignRejectsExpiryAboveConfiguredMax(t *testing.T) {
+	signer := &AuthConfig{AccessKey: "test", SecretKey: "testsecret", Region: "us-east-1"}
+	h := newTestHandler(t)
+	h.Auth = &AuthConfig{AccessKey: "test", SecretKey: "testsecret", Region: "us-east-1", MaxPresignExpiry: time.Hour}
+	if _, err := h.Auth.Presign(http.MethodGet, "http://example.com/bucket/key", "", 2*time.Hour); err == nil {
+		t.Fatalf("expected presign beyond the max to fail")
+	}
+
+	for _, tc := range []struct {
+		expires time.Duration
+		status  int
+	}{
+		{expires: time.Hour, status: http.StatusNotFound},
+		{expires: 2 * time.Hour, status: http.StatusBadRequest},
+	} {
+		signed, err := signer.Presign(http.MethodGet, "http://example.com/bucket/key", "", tc.expires)
+		if err != nil {
+			t.Fatalf("Presign: %v", err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, signed, nil))
+		if w.Code != tc.status {
+			t.Fatalf("expires=%s: status %d, want %d: %s", tc.expires, w.Code, tc.status, w.Body.String())
+		}
+		if tc.status == http.StatusBadRequest && !strings.Contains(w.Body.String(), "AuthorizationQueryParametersError") {
+			t.Fatalf("unexpected body: %s", w.Body.String())
+		}
+	}
+
+	// Values whose Duration overflows, or that overflow int64 (malformed), are
+	// rejected too.
+	signed, err := signer.Presign(http.MethodGet, "http://example.com/bucket/key", "", time.Hour)
+	if err != nil {
+		t.Fatalf("Presign: %v", err)
+	}
+	for _, expires := range []string{"9223372036854776", "99999999999999999999"} {
+		target := strings.Replace(signed, "X-Amz-Expires=3600", "X-Amz-Expires="+expires, 1)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expires=%s: status %d: %s", expires, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestSigV4AcceptsPreviousSecretDuringRotation(t *testing.T) {
+	ctx := context.Background()
+	store, err := meta.Open(filepath.Join(t.TempDir(), "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	if err := store.UpsertAPIKey(ctx, "k1", "old", "rw", true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	auth := &AuthConfig{
+		Region:               "us-east-1",
+		AllowUnsignedPayload: true,
+		SecretsLookup:        store.LookupAPISecrets,
+	}
+	verify := func(secret string) (header, presigned error) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/bucket/key", nil)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		signRequestTest(req, "k1", secret, "us-east-1")
+		header = auth.VerifyRequest(req)
+		signer := &AuthConfig{AccessKey: "k1", SecretKey: secret, Region: "us-east-1"}
+		signed, err := signer.Presign(http.MethodGet, "http://example.com/bucket/key", "", time.Minute)
+		if err != nil {
+			t.Fatalf("Presign: %v", err)
+		}
+		if req, err = http.NewRequest(http.MethodGet, signed, nil); err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		return header, auth.VerifyRequest(req)
+	}
+
+	if err := store.RotateAPIKeySecret(ctx, "k1", "new", time.Hour); err != nil {
+		t.Fatalf("RotateAPIKeySecret: %v", err)
+	}
+	for _, secret := range []string{"old", "new"} {
+		if header, presigned := verify(secret); header != nil || presigned != nil {
+			t.Fatalf("%s secret during overlap: header=%v presigned=%v", secret, header, presigned)
+		}
+	}
+	if err := store.RotateAPIKeySecret(ctx, "k1", "newer", 0); err != nil {
+		t.Fatalf("RotateAPIKeySecret without overlap: %v", err)
+	}
+	for _, secret := range []string{"old", "new"} {
+		if header, presigned := verify(secret); header != errSignatureMismatch || presigned != errSignatureMismatch {
+			t.Fatalf("%s secret after revoke: header=%v presigned=%v", secret, header, presigned)
+		}
+	}
+	if err := store.SetAPIKeyEnabled(ctx, "k1", false); err != nil {
+		t.Fatalf("SetAPIKeyEnabled: %v", err)
+	}
+	if header, _ := verify("newer"); header != errAccessDenied {
+		t.Fatalf("disabled key: expected access denied, got %v", header)
+	}
+}
@@ -3,12 +3,15 @@ package s3
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/xml"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/storage/engine"
@@ -238,3 +241,252 @@ func TestPublicBucketUnsignedAccess(t *testing.T) {
 		t.Fatalf("PUT status: %d", putResp.StatusCode)
 	}
 }
+
+func TestBucketPolicySecureTransportCondition(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	ctx := context.Background()
+	if _, _, err := eng.PutObject(ctx, "public", "hello.txt", "", bytes.NewReader([]byte("hello"))); err != nil {
+		t.Fatalf("PutObject seed: %v", err)
+	}
+	policy := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::public/*"},
+    {"Effect": "Deny", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::public/*",
+     "Condition": {"Bool": {"aws:SecureTransport": "false"}}}
+  ]
+}`
+	if err := store.SetBucketPolicy(ctx, "public", policy); err != nil {
+		t.Fatalf("SetBucketPolicy: %v", err)
+	}
+	h := &Handler{
+		Engine: eng,
+		Meta:   store,
+		Auth: &AuthConfig{
+			Region:    "us-east-1",
+			AccessKey: "root",
+			SecretKey: "rootsecret",
+		},
+		PublicBuckets:  map[string]struct{}{"public": {}},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	}
+
+	cases := []struct {
+		name   string
+		remote string
+		tls    bool
+		proto  string
+		want   int
+	}{
+		{"plain http", "192.0.2.1:1234", false, "", http.StatusForbidden},
+		{"tls", "192.0.2.1:1234", true, "", http.StatusOK},
+		{"trusted proxy https", "10.0.0.5:1234", false, "https", http.StatusOK},
+		{"trusted proxy http over tls", "10.0.0.5:1234", true, "http", http.StatusForbidden},
+		{"trusted proxy chain", "10.0.0.5:1234", false, "http, https", http.StatusOK},
+		{"trusted proxy chain spoofed https", "10.0.0.5:1234", false, "https, http", http.StatusForbidden},
+		{"untrusted proxy https", "192.0.2.1:1234", false, "https", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/public/hello.txt", nil)
+		req.RemoteAddr = tc.remote
+		if tc.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		if tc.proto != "" {
+			req.Header.Set("X-Forwarded-Proto", tc.proto)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: status=%d want %d", tc.name, w.Code, tc.want)
+		}
+	}
+}
+
+func TestAuthzKeyPrefixScope(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	if err := store.UpsertAPIKey(ctx, "ak", "sk", "rw", true, 4); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	if err := store.AllowBucketForKey(ctx, "ak", "shared/tenant-a/*"); err != nil {
+		t.Fatalf("AllowBucketForKey: %v", err)
+	}
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	for _, key := range []string{"tenant-a/x", "tenant-b/y"} {
+		if _, _, err := eng.PutObject(ctx, "shared", key, "", bytes.NewReader([]byte("ok"))); err != nil {
+			t.Fatalf("PutObject seed: %v", err)
+		}
+	}
+	handler := &Handler{
+		Engine: eng,
+		Meta:   store,
+		Auth: &AuthConfig{
+			Region:               "us-east-1",
+			SecretLookup:         store.LookupAPISecret,
+			AllowUnsignedPayload: true,
+		},
+		InflightLimiter: NewInflightLimiter(4),
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "/shared/tenant-a/x", "", http.StatusOK},
+		{http.MethodGet, "/shared/tenant-b/y", "", http.StatusForbidden},
+		{http.MethodPut, "/shared/tenant-a/new", "data", http.StatusOK},
+		{http.MethodPut, "/shared/tenant-b/new", "data", http.StatusForbidden},
+		{http.MethodDelete, "/shared/tenant-b/y", "", http.StatusForbidden},
+		{http.MethodDelete, "/shared/tenant-a/new", "", http.StatusNoContent},
+		{http.MethodGet, "/shared?list-type=2&prefix=tenant-a/", "", http.StatusOK},
+		{http.MethodGet, "/shared?list-type=2", "", http.StatusForbidden},
+		{http.MethodGet, "/shared?list-type=2&prefix=tenant-b/", "", http.StatusForbidden},
+		{http.MethodGet, "/other/tenant-a/x", "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req, err := http.NewRequest(tc.method, server.URL+tc.path, bytes.NewReader([]byte(tc.body)))
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		signRequestTest(req, "ak", "sk", "us-east-1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tc.method, tc.path, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Fatalf("%s %s: status %d, want %d", tc.method, tc.path, resp.StatusCode, tc.want)
+		}
+	}
+}
+
+func TestAuthzCopySourceRequiresPolicyGet(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	ctx := context.Background()
+	policy := `{"version":"v1","statements":[{"effect":"allow","actions":["*"],"resources":[{"bucket":"shared","prefix":"tenant-a/"}]}]}`
+	if err := store.UpsertAPIKey(ctx, "ak", "sk", policy, true, 4); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	for _, key := range []string{"tenant-a/src", "tenant-b/secret"} {
+		if _, _, err := eng.PutObject(ctx, "shared", key, "", bytes.NewReader([]byte("SECRET"))); err != nil {
+			t.Fatalf("PutObject seed: %v", err)
+		}
+	}
+	handler := &Handler{
+		Engine: eng,
+		Meta:   store,
+		Auth: &AuthConfig{
+			Region:               "us-east-1",
+			SecretLookup:         store.LookupAPISecret,
+			AllowUnsignedPayload: true,
+		},
+		InflightLimiter: NewInflightLimiter(4),
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	cases := []struct {
+		src  string
+		dst  string
+		want int
+	}{
+		{"/shared/tenant-b/secret", "/shared/tenant-a/x", http.StatusForbidden},
+		{"/shared/tenant-a/src", "/shared/tenant-a/y", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req, err := http.NewRequest(http.MethodPut, server.URL+tc.dst, nil)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		req.Header.Set("X-Amz-Copy-Source", tc.src)
+		signRequestTest(req, "ak", "sk", "us-east-1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("copy %s: %v", tc.src, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Fatalf("copy %s -> %s: status %d, want %d", tc.src, tc.dst, resp.StatusCode, tc.want)
+		}
+	}
+	if _, err := store.GetObjectMeta(ctx, "shared", "tenant-a/x"); err == nil {
+		t.Fatalf("denied copy created the destination")
+	}
+}
+
+func TestPresignedBehindTrustedProxyUsesForwardedHost(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "demo", "key", "hello")
+	h.Auth = &AuthConfig{AccessKey: "test", SecretKey: "testsecret", Region: "us-east-1", AllowUnsignedPayload: true}
+	presigned, err := h.Auth.Presign(http.MethodGet, "https://s3.example.com/demo/key", "", time.Minute)
+	if err != nil {
+		t.Fatalf("Presign: %v", err)
+	}
+	u, err := url.Parse(presigned)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		trusted []string
+		want    int
+	}{
+		{"untrusted", nil, http.StatusForbidden},
+		{"trusted", []string{"10.0.0.0/8"}, http.StatusOK},
+	} {
+		h.TrustedProxies = tc.trusted
+		req := httptest.NewRequest(http.MethodGet, u.RequestURI(), nil)
+		req.Host = "10.0.0.9:9000"
+		req.RemoteAddr = "10.0.0.5:1234"
+		req.Header.Set("X-Forwarded-Host", "s3.example.com")
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: status=%d want %d: %s", tc.name, w.Code, tc.want, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// defaultContentType is stored for a PUT without Content-Type when the
+// bucket has no default and does not sniff.
+const defaultContentType = "application/octet-stream"
+
+type bucketContentTypeResponse struct {
+	Bucket      string `json:"bucket"`
+	ContentType string `json:"content_type"`
+}
+
+// handleBucketContentType shows (GET), sets (PUT with ?content_type=) or
+// removes (DELETE) the default Content-Type of ?bucket=.
+func (h *Handler) handleBucketContentType(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	query := r.URL.Query()
+	bucket := strings.TrimSpace(query.Get("bucket"))
+	if bucket == "" {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "bucket required", requestID, r.URL.Path)
+		return
+	}
+	if r.Method != http.MethodGet {
+		contentType := ""
+		if r.Method == http.MethodPut {
+			contentType = strings.TrimSpace(query.Get("content_type"))
+			if _, _, err := mime.ParseMediaType(contentType); err != nil {
+				writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid content_type", requestID, r.URL.Path)
+				return
+			}
+		}
+		if err := h.Meta.SetBucketDefaultContentType(ctx, bucket, contentType); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+				return
+			}
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+	}
+	contentType, err := h.Meta.GetBucketDefaultContentType(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(bucketContentTypeResponse{Bucket: bucket, ContentType: contentType})
+}
+
+// putContentType picks the Content-Type stored for a PUT without one: the
+// bucket default, else the sniffed type when the bucket sniffs, else
+// defaultContentType. It may wrap reader to replay sniffed bytes.
+func (h *Handler) putContentType(ctx context.Context, bucket string, reader io.Reader) (string, io.Reader, error) {
+	if h.Meta != nil {
+		contentType, err := h.Meta.GetBucketDefaultContentType(ctx, bucket)
+		if err != nil || contentType != "" {
+			return contentType, reader, err
+		}
+	}
+	if h.sniffsContentType(bucket) {
+		var contentType string
+		if contentType, reader = sniffContentType(reader); contentType != "" {
+			return contentType, reader, nil
+		}
+	}
+	return defaultContentType, reader, nil
+}
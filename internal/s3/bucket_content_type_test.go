@@ -0,0 +1,62 @@
+package s3
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBucketDefaultContentType(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "media", "seed", "x")
+
+	serve := func(method, target, body, contentType string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	setDefault := "/v1/ops/default-content-type?bucket=media&content_type=" + url.QueryEscape("image/webp")
+
+	if rec := serve(http.MethodPut, "/v1/ops/default-content-type?bucket=missing&content_type=image/webp", "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing bucket, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/v1/ops/default-content-type?bucket=media&content_type=not+a+type", "", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid content_type, got %d", rec.Code)
+	}
+	rec := serve(http.MethodPut, setDefault, "", "")
+	var resp bucketContentTypeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK || resp.ContentType != "image/webp" {
+		t.Fatalf("set default: status=%d resp=%+v (%v)", rec.Code, resp, err)
+	}
+
+	if rec := serve(http.MethodPut, "/media/implicit", "RIFF....WEBP", ""); rec.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d", rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/media/explicit", "{}", "application/json"); rec.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d", rec.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		for key, want := range map[string]string{"implicit": "image/webp", "explicit": "application/json"} {
+			rec := serve(method, "/media/"+key, "", "")
+			if got := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || got != want {
+				t.Fatalf("%s %s: status=%d content-type=%q want %q", method, key, rec.Code, got, want)
+			}
+		}
+	}
+
+	if rec := serve(http.MethodDelete, "/v1/ops/default-content-type?bucket=media", "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("clear default: %d", rec.Code)
+	}
+	serve(http.MethodPut, "/media/after-clear", "RIFF....WEBP", "")
+	if got := serve(http.MethodHead, "/media/after-clear", "", "").Header().Get("Content-Type"); got != defaultContentType {
+		t.Fatalf("content-type after clear=%q want %q", got, defaultContentType)
+	}
+}
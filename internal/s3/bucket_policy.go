@@ -20,7 +20,7 @@ func (h *Handler) handleGetBucketPolicy(ctx context.Context, w http.ResponseWrit
 	}
 	exists, err := h.Meta.BucketExists(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if !exists {
@@ -33,7 +33,7 @@ func (h *Handler) handleGetBucketPolicy(ctx context.Context, w http.ResponseWrit
 			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucketPolicy", "bucket policy not found", requestID, r.URL.Path)
 			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if policy == "" {
@@ -56,15 +56,19 @@ func (h *Handler) handlePutBucketPolicy(ctx context.Context, w http.ResponseWrit
 	}
 	exists, err := h.Meta.BucketExists(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if !exists {
 		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
 		return
 	}
-	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	h.limitControlBody(w, r)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
+		if h.writeBodyTooLarge(w, err, requestID, r.URL.Path) {
+			return
+		}
 		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid policy body", requestID, r.URL.Path)
 		return
 	}
@@ -78,7 +82,7 @@ func (h *Handler) handlePutBucketPolicy(ctx context.Context, w http.ResponseWrit
 		return
 	}
 	if err := h.Meta.SetBucketPolicy(ctx, bucket, policy); err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -95,7 +99,7 @@ func (h *Handler) handleDeleteBucketPolicy(ctx context.Context, w http.ResponseW
 	}
 	exists, err := h.Meta.BucketExists(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if !exists {
@@ -103,7 +107,7 @@ func (h *Handler) handleDeleteBucketPolicy(ctx context.Context, w http.ResponseW
 		return
 	}
 	if err := h.Meta.DeleteBucketPolicy(ctx, bucket); err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -30,7 +30,7 @@ func (h *Handler) handleGetBucketVersioning(ctx context.Context, w http.Response
 			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
 			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	resp := bucketVersioningConfiguration{
@@ -53,7 +53,11 @@ func (h *Handler) handlePutBucketVersioning(ctx context.Context, w http.Response
 		return
 	}
 	var req bucketVersioningConfiguration
+	h.limitControlBody(w, r)
 	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		if h.writeBodyTooLarge(w, err, requestID, r.URL.Path) {
+			return
+		}
 		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid xml", requestID, r.URL.Path)
 		return
 	}
@@ -72,7 +76,7 @@ func (h *Handler) handlePutBucketVersioning(ctx context.Context, w http.Response
 			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
 			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/ops"
+)
+
+const defaultCompactLiveThreshold = 0.5
+
+// compactor runs online segment rewrites from the maintenance loop.
+type compactor struct {
+	h       *Handler
+	lastRun time.Time
+	running int32
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func newCompactor(h *Handler) *compactor {
+	return &compactor{h: h}
+}
+
+// maybeStart launches a compaction pass when enabled, idle and due.
+func (c *compactor) maybeStart(ctx context.Context) {
+	h := c.h
+	if h.CompactInterval <= 0 || h.Engine == nil || h.Meta == nil {
+		return
+	}
+	if atomic.LoadInt32(&c.running) != 0 {
+		return
+	}
+	now := h.now()
+	if !c.lastRun.IsZero() && now.Sub(c.lastRun) < h.CompactInterval {
+		return
+	}
+	if atomic.LoadInt64(&h.writeInflight) != 0 {
+		return
+	}
+	if h.CompactPauseFile != "" {
+		if _, err := os.Stat(h.CompactPauseFile); err == nil {
+			return
+		}
+	}
+	c.lastRun = now
+	threshold := h.CompactLiveThreshold
+	if threshold <= 0 {
+		threshold = defaultCompactLiveThreshold
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	atomic.StoreInt32(&c.running, 1)
+	// Compaction writes segments, so it counts as an inflight write and
+	// delays maintenance quiesce until it has stopped.
+	atomic.AddInt64(&h.writeInflight, 1)
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer atomic.StoreInt32(&c.running, 0)
+		defer atomic.AddInt64(&h.writeInflight, -1)
+		defer cancel()
+		report, err := ops.GCRewriteOnline(runCtx, h.Engine.Layout(), h.Meta, h.CompactMinAge, threshold, h.CompactBps, h.CompactReads, h.CompactPauseFile, h.Engine)
+		if err != nil {
+			log.Printf("compaction_failed err=%v", err)
+			return
+		}
+		if report != nil && report.Candidates > 0 {
+			log.Printf("compaction_done candidates=%d deleted=%d reclaimed_bytes=%d rewritten_bytes=%d rewrite_bps=%d warnings=%d", report.Candidates, report.Deleted, report.Reclaimed, report.RewrittenBytes, report.RewriteBytesPerSec, report.Warnings)
+		}
+	}()
+}
+
+// stop cancels a running pass (e.g. when maintenance mode is entered).
+func (c *compactor) stop() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+func (c *compactor) wait() {
+	c.stop()
+	c.wg.Wait()
+}
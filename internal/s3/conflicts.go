@@ -45,7 +45,7 @@ func (h *Handler) handleConflicts(ctx context.Context, w http.ResponseWriter, r
 	}
 	items, err := h.Meta.ListConflicts(ctx, bucket, prefix, afterBucket, afterKey, afterVersion, limit)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	resp := conflictsResponse{
@@ -95,3 +95,63 @@ func TestMetaConflictsEndpoint(t *testing.T) {
 		t.Fatalf("unexpected item: %+v", resp.Items[0])
 	}
 }
+
+func TestOpsDamagedEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+	var versions []string
+	for _, key := range []string{"a", "b"} {
+		_, result, err := eng.PutObject(ctx, "bucket", key, "text/plain", bytes.NewReader([]byte("data")))
+		if err != nil {
+			t.Fatalf("PutObject: %v", err)
+		}
+		if err := store.MarkDamaged(ctx, result.VersionID); err != nil {
+			t.Fatalf("MarkDamaged: %v", err)
+		}
+		versions = append(versions, result.VersionID)
+	}
+
+	handler := &Handler{Engine: eng, Meta: store}
+	req := httptest.NewRequest(http.MethodGet, "/v1/ops/damaged?limit=1", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("damaged status: %d", rec.Code)
+	}
+	var resp damagedResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].Key != "a" || resp.Items[0].VersionID != versions[0] {
+		t.Fatalf("unexpected items: %+v", resp.Items)
+	}
+	if resp.Items[0].ManifestPath == "" {
+		t.Fatalf("expected manifest path")
+	}
+	if resp.NextBucket != "bucket" || resp.NextKey != "a" || resp.NextVersion != versions[0] {
+		t.Fatalf("unexpected cursor: %+v", resp)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/ops/damaged?limit=1&after_bucket=bucket&after_key=a&after_version="+versions[0], nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	resp = damagedResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].Key != "b" {
+		t.Fatalf("unexpected second page: %+v", resp.Items)
+	}
+}
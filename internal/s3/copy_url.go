@@ -0,0 +1,294 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+)
+
+const (
+	copySourceURLHeader         = "X-Seglake-Copy-Source-Url"
+	defaultCopySourceURLTimeout = 5 * time.Minute
+	maxCopySourceURLRedirects   = 5
+)
+
+var (
+	errCopySourceHostNotAllowed = errors.New("copy source host not allowed")
+	errCopySourceAddrBlocked    = errors.New("copy source address blocked")
+)
+
+// Cloud metadata endpoints outside the link-local ranges.
+var copySourceMetadataIPs = []net.IP{
+	net.ParseIP("fd00:ec2::254"),
+	net.ParseIP("100.100.100.200"),
+}
+
+// blockedCopySourceIP rejects addresses a server-side fetch must never reach:
+// loopback, link-local (incl. 169.254.169.254), multicast, unspecified and
+// known metadata services.
+func blockedCopySourceIP(ip net.IP) bool {
+	if ip == nil {
+		return true
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, blocked := range copySourceMetadataIPs {
+		if blocked.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// copySourceHostAllowed matches host against exact names or "*.suffix" patterns.
+func copySourceHostAllowed(host string, patterns []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) checkCopySourceURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported copy source scheme %q", u.Scheme)
+	}
+	if u.User != nil {
+		return errors.New("copy source url must not contain credentials")
+	}
+	if !copySourceHostAllowed(u.Hostname(), h.CopySourceURLHosts) {
+		return errCopySourceHostNotAllowed
+	}
+	return nil
+}
+
+// guardedDialer refuses to connect to any address blocked reports, returning
+// errBlocked. The check runs on the resolved address of every dial.
+func guardedDialer(blocked func(net.IP) bool, errBlocked error) *net.Dialer {
+	return &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if blocked(net.ParseIP(host)) {
+				return errBlocked
+			}
+			return nil
+		},
+	}
+}
+
+// copySourceClient checks every dialed address (after DNS resolution, so
+// rebinding cannot bypass it) and re-applies the host allowlist on redirects.
+func (h *Handler) copySourceClient(timeout time.Duration) *http.Client {
+	blocked := h.copySourceIPBlocked
+	if blocked == nil {
+		blocked = blockedCopySourceIP
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No environment proxy: the dial check must see the real target.
+			Proxy:                 nil,
+			DialContext:           guardedDialer(blocked, errCopySourceAddrBlocked).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxCopySourceURLRedirects {
+				return errors.New("too many redirects")
+			}
+			return h.checkCopySourceURL(req.URL)
+		},
+	}
+}
+
+// handlePutFromURL fetches x-seglake-copy-source-url server-side and stores it
+// as the object body.
+func (h *Handler) handlePutFromURL(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, requestID string) {
+	defer func() { _ = r.Body.Close() }()
+	if len(h.CopySourceURLHosts) == 0 {
+		writeErrorWithResource(w, http.StatusForbidden, "AccessDenied", "copy from url disabled", requestID, r.URL.Path)
+		return
+	}
+	source, err := url.Parse(strings.TrimSpace(r.Header.Get(copySourceURLHeader)))
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source url", requestID, r.URL.Path)
+		return
+	}
+	if err := h.checkCopySourceURL(source); err != nil {
+		if errors.Is(err, errCopySourceHostNotAllowed) {
+			writeErrorWithResource(w, http.StatusForbidden, "AccessDenied", err.Error(), requestID, r.URL.Path)
+			return
+		}
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", err.Error(), requestID, r.URL.Path)
+		return
+	}
+	ttl, err := parseObjectTTL(r.Header.Get(ttlHeader))
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", err.Error(), requestID, r.URL.Path)
+		return
+	}
+	storageClass, ok := parseStorageClass(r)
+	if !ok {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidStorageClass", "invalid storage class", requestID, r.URL.Path)
+		return
+	}
+	userMetadata, err := parseUserMetadata(r)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "MetadataTooLarge", "user metadata exceeds 2 KB", requestID, r.URL.Path)
+		return
+	}
+	tags, err := parseTaggingHeader(r)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidTag", err.Error(), requestID, r.URL.Path)
+		return
+	}
+	idemKey := r.Header.Get(idempotencyKeyHeader)
+	if h.Meta == nil {
+		idemKey = ""
+	}
+	if len(idemKey) > maxIdempotencyKeyLen {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "idempotency key too long", requestID, r.URL.Path)
+		return
+	}
+	// A retry is answered before fetching the source again.
+	if idemKey != "" && h.replayIdempotentPut(ctx, w, r, bucket, key, idemKey, requestID) {
+		return
+	}
+	if !h.enforceIfMatch(ctx, w, r, bucket, key, requestID) {
+		return
+	}
+	timeout := h.CopySourceURLTimeout
+	if timeout <= 0 {
+		timeout = defaultCopySourceURLTimeout
+	}
+	fetchReq, err := http.NewRequestWithContext(ctx, http.MethodGet, source.String(), nil)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid copy source url", requestID, r.URL.Path)
+		return
+	}
+	resp, err := h.copySourceClient(timeout).Do(fetchReq)
+	if err != nil {
+		if errors.Is(err, errCopySourceAddrBlocked) || errors.Is(err, errCopySourceHostNotAllowed) {
+			writeErrorWithResource(w, http.StatusForbidden, "AccessDenied", "copy source address not allowed", requestID, r.URL.Path)
+			return
+		}
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "fetch copy source: "+err.Error(), requestID, r.URL.Path)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("copy source returned status %d", resp.StatusCode), requestID, r.URL.Path)
+		return
+	}
+	if h.MaxObjectSize > 0 && resp.ContentLength > h.MaxObjectSize {
+		writeErrorWithResource(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "entity too large", requestID, r.URL.Path)
+		return
+	}
+	reader := io.Reader(resp.Body)
+	if h.MaxObjectSize > 0 {
+		reader = newSizeLimitReader(reader, h.MaxObjectSize)
+	}
+	contentType := strings.TrimSpace(r.Header.Get("Content-Type"))
+	if contentType == "" {
+		contentType = strings.TrimSpace(resp.Header.Get("Content-Type"))
+	}
+	versioningState, err := h.bucketVersioningState(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if contentType == "" {
+		contentType, reader, err = h.putContentType(ctx, bucket, reader)
+		if err != nil {
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+	}
+	var extraCommit func(tx *sql.Tx, result *engine.PutResult, manifestPath string) error
+	if ttl > 0 || idemKey != "" || storageClass != meta.StorageClassStandard || len(userMetadata) > 0 || len(tags) > 0 {
+		expiresAt := h.now().Add(ttl)
+		extraCommit = func(tx *sql.Tx, result *engine.PutResult, _ string) error {
+			if ttl > 0 {
+				if err := h.Meta.SetVersionExpiryTx(tx, result.VersionID, expiresAt); err != nil {
+					return err
+				}
+			}
+			if storageClass != meta.StorageClassStandard {
+				if err := h.Meta.SetVersionStorageClassTx(tx, result.VersionID, storageClass); err != nil {
+					return err
+				}
+			}
+			if len(userMetadata) > 0 || len(tags) > 0 {
+				if err := h.Meta.SetVersionMetadataTx(tx, result.VersionID, userMetadata, tags); err != nil {
+					return err
+				}
+			}
+			if idemKey == "" {
+				return nil
+			}
+			return h.Meta.RecordIdempotencyKeyTx(ctx, tx, bucket, key, idemKey, result.VersionID, result.ETag)
+		}
+	}
+	check := combineChecks(h.idempotencyCheck(ctx, bucket, key, idemKey), h.ifMatchCheck(ctx, r, bucket, key))
+	_, result, err := h.Engine.PutObjectWithCheck(ctx, bucket, key, contentType, reader, check, extraCommit)
+	if err != nil {
+		switch {
+		case errors.Is(err, errIdempotentReplay):
+			if h.replayIdempotentPut(ctx, w, r, bucket, key, idemKey, requestID) {
+				return
+			}
+		case errors.Is(err, engine.ErrPreconditionFailed):
+			writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "if-match failed", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errEntityTooLarge):
+			writeErrorWithResource(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "entity too large", requestID, r.URL.Path)
+			return
+		case errors.Is(err, engine.ErrInsufficientStorage):
+			writeErrorWithResource(w, http.StatusInsufficientStorage, "InsufficientStorage", "insufficient storage", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if result.ETag != "" {
+		w.Header().Set("ETag", `"`+result.ETag+`"`)
+	}
+	if versionID, ok := versionIDHeaderForPut(versioningState, result.VersionID); ok {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	w.Header().Set("Last-Modified", formatHTTPTime(result.CommittedAt))
+	h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectCreatedPut, putResultObject(result))
+	w.WriteHeader(http.StatusOK)
+}
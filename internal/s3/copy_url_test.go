@@ -0,0 +1,255 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/clock"
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+func TestPutFromURLFetchesAllowedSource(t *testing.T) {
+	body := "remote-object-body"
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/obj", http.StatusFound)
+		case "/evil-redirect":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(body))
+		}
+	}))
+	defer source.Close()
+
+	h := newTestHandler(t)
+	h.CopySourceURLHosts = []string{"127.0.0.1"}
+	put := func(key, src string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/bucket/"+key, nil)
+		req.Header.Set(copySourceURLHeader, src)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	// Loopback is blocked by default even when the host is allowlisted.
+	if w := put("blocked", source.URL+"/obj"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected loopback blocked, got %d %s", w.Code, w.Body.String())
+	}
+	h.copySourceIPBlocked = func(ip net.IP) bool { return !ip.IsLoopback() && blockedCopySourceIP(ip) }
+
+	cases := []struct {
+		name string
+		src  string
+		code int
+	}{
+		{name: "host not allowlisted", src: "http://example.com/obj", code: http.StatusForbidden},
+		{name: "bad scheme", src: "file:///etc/passwd", code: http.StatusBadRequest},
+		{name: "redirect to metadata", src: source.URL + "/evil-redirect", code: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		if w := put("k-"+strings.ReplaceAll(tc.name, " ", "-"), tc.src); w.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.code, w.Code, w.Body.String())
+		}
+	}
+
+	w := put("copied", source.URL+"/redirect")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	sum := md5.Sum([]byte(body))
+	if got, want := w.Header().Get("ETag"), `"`+hex.EncodeToString(sum[:])+`"`; got != want {
+		t.Fatalf("etag=%s want %s", got, want)
+	}
+	getReq := httptest.NewRequest(http.MethodGet, "/bucket/copied", nil)
+	getW := httptest.NewRecorder()
+	h.ServeHTTP(getW, getReq)
+	if getW.Code != http.StatusOK || getW.Body.String() != body {
+		t.Fatalf("GET copied: %d %q", getW.Code, getW.Body.String())
+	}
+
+	h.MaxObjectSize = 4
+	if w := put("too-big", source.URL+"/obj"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+// newPutFromURLHandler returns a handler allowed to fetch from the loopback
+// test source.
+func newPutFromURLHandler(t *testing.T) *Handler {
+	t.Helper()
+	h := newTestHandler(t)
+	h.CopySourceURLHosts = []string{"127.0.0.1"}
+	h.copySourceIPBlocked = func(ip net.IP) bool { return !ip.IsLoopback() && blockedCopySourceIP(ip) }
+	return h
+}
+
+func putFromURL(h *Handler, key, src string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/bucket/"+key, nil)
+	req.Header.Set(copySourceURLHeader, src)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestPutFromURLHonorsIdempotencyKeyAndIfMatch(t *testing.T) {
+	var fetches atomic.Int32
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write([]byte("remote"))
+	}))
+	defer source.Close()
+	h := newPutFromURLHandler(t)
+
+	first := putFromURL(h, "key", source.URL, map[string]string{idempotencyKeyHeader: "retry-1"})
+	if first.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d %s", first.Code, first.Body.String())
+	}
+	versionID := first.Header().Get("x-amz-version-id")
+	retry := putFromURL(h, "key", source.URL, map[string]string{idempotencyKeyHeader: "retry-1"})
+	if retry.Code != http.StatusOK || retry.Header().Get("x-amz-version-id") != versionID {
+		t.Fatalf("retry: %d version %q, want %q", retry.Code, retry.Header().Get("x-amz-version-id"), versionID)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("retry fetched the source again: %d fetches", got)
+	}
+	versions, err := h.Meta.ListObjectVersions(context.Background(), "bucket", "key", "", "", 10, time.Time{})
+	if err != nil {
+		t.Fatalf("ListObjectVersions: %v", err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("expected 1 version, got %d", len(versions))
+	}
+
+	if w := putFromURL(h, "key", source.URL, map[string]string{"If-Match": `"deadbeef"`}); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d %s", w.Code, w.Body.String())
+	}
+	if w := putFromURL(h, "key", source.URL, map[string]string{"If-Match": first.Header().Get("ETag")}); w.Code != http.StatusOK {
+		t.Fatalf("matching If-Match: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPutFromURLSetsTTL(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("remote"))
+	}))
+	defer source.Close()
+	h := newPutFromURLHandler(t)
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	h.Clock = clock.FixedClock{T: start}
+
+	if w := putFromURL(h, "bad", source.URL, map[string]string{ttlHeader: "soon"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid ttl: expected 400, got %d %s", w.Code, w.Body.String())
+	}
+	if w := putFromURL(h, "temp", source.URL, map[string]string{ttlHeader: "60"}); w.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d %s", w.Code, w.Body.String())
+	}
+	objMeta, err := h.Meta.GetObjectMeta(context.Background(), "bucket", "temp")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if want := start.Add(time.Minute).Format(meta.ExpiryTimeFormat); objMeta.ExpiresAt != want {
+		t.Fatalf("expires_at=%q want %q", objMeta.ExpiresAt, want)
+	}
+}
+
+func TestPutFromURLStoresMetadataTagsAndStorageClass(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("remote"))
+	}))
+	defer source.Close()
+	h := newPutFromURLHandler(t)
+
+	if w := putFromURL(h, "bad", source.URL, map[string]string{"x-amz-storage-class": "FAST"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid storage class: expected 400, got %d %s", w.Code, w.Body.String())
+	}
+	w := putFromURL(h, "key", source.URL, map[string]string{
+		"x-amz-meta-owner":    "ops",
+		"x-amz-tagging":       "env=prod",
+		"x-amz-storage-class": "ONEZONE_IA",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d %s", w.Code, w.Body.String())
+	}
+	getW := httptest.NewRecorder()
+	h.ServeHTTP(getW, httptest.NewRequest(http.MethodGet, "/bucket/key", nil))
+	if getW.Code != http.StatusOK {
+		t.Fatalf("GET status: %d %s", getW.Code, getW.Body.String())
+	}
+	if got := getW.Header().Get("x-amz-meta-owner"); got != "ops" {
+		t.Fatalf("x-amz-meta-owner=%q", got)
+	}
+	if got := getW.Header().Get("x-amz-tagging-count"); got != "1" {
+		t.Fatalf("x-amz-tagging-count=%q", got)
+	}
+	if got := getW.Header().Get("x-amz-storage-class"); got != "ONEZONE_IA" {
+		t.Fatalf("x-amz-storage-class=%q", got)
+	}
+}
+
+func TestPutFromURLFallsBackToBucketDefaultContentType(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/typed" {
+			w.Header().Set("Content-Type", "text/plain")
+		} else {
+			// Keep net/http from sniffing a Content-Type for the response.
+			w.Header()["Content-Type"] = nil
+		}
+		_, _ = w.Write([]byte("RIFF....WEBP"))
+	}))
+	defer source.Close()
+	h := newPutFromURLHandler(t)
+	putObject(t, h, "bucket", "seed", "x")
+	if err := h.Meta.SetBucketDefaultContentType(context.Background(), "bucket", "image/webp"); err != nil {
+		t.Fatalf("SetBucketDefaultContentType: %v", err)
+	}
+
+	cases := []struct {
+		key     string
+		src     string
+		headers map[string]string
+		want    string
+	}{
+		{key: "untyped", src: source.URL + "/untyped", want: "image/webp"},
+		{key: "source-typed", src: source.URL + "/typed", want: "text/plain"},
+		{key: "request-typed", src: source.URL + "/untyped", headers: map[string]string{"Content-Type": "application/json"}, want: "application/json"},
+	}
+	for _, tc := range cases {
+		if w := putFromURL(h, tc.key, tc.src, tc.headers); w.Code != http.StatusOK {
+			t.Fatalf("%s: PUT status: %d %s", tc.key, w.Code, w.Body.String())
+		}
+		headW := httptest.NewRecorder()
+		h.ServeHTTP(headW, httptest.NewRequest(http.MethodHead, "/bucket/"+tc.key, nil))
+		if got := headW.Header().Get("Content-Type"); headW.Code != http.StatusOK || got != tc.want {
+			t.Fatalf("%s: status=%d content-type=%q want %q", tc.key, headW.Code, got, tc.want)
+		}
+	}
+}
+
+func TestCopySourceHostAllowed(t *testing.T) {
+	patterns := []string{"*.s3.amazonaws.com", "minio.internal"}
+	cases := map[string]bool{
+		"bucket.s3.amazonaws.com": true,
+		"MINIO.internal":          true,
+		"s3.amazonaws.com.evil":   false,
+		"evil-minio.internal":     false,
+		"":                        false,
+	}
+	for host, want := range cases {
+		if got := copySourceHostAllowed(host, patterns); got != want {
+			t.Fatalf("host %q: got %v want %v", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type damagedItem struct {
+	Bucket       string `json:"bucket"`
+	Key          string `json:"key"`
+	VersionID    string `json:"version_id"`
+	LastModified string `json:"last_modified_utc"`
+	ManifestPath string `json:"manifest_path,omitempty"`
+}
+
+type damagedResponse struct {
+	Items       []damagedItem `json:"items"`
+	NextBucket  string        `json:"next_bucket,omitempty"`
+	NextKey     string        `json:"next_key,omitempty"`
+	NextVersion string        `json:"next_version,omitempty"`
+}
+
+func (h *Handler) handleDamaged(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	query := r.URL.Query()
+	afterBucket := strings.TrimSpace(query.Get("after_bucket"))
+	afterKey := strings.TrimSpace(query.Get("after_key"))
+	afterVersion := strings.TrimSpace(query.Get("after_version"))
+	limit := 1000
+	if rawLimit := strings.TrimSpace(query.Get("limit")); rawLimit != "" {
+		v, err := parseInt(rawLimit)
+		if err != nil || v <= 0 || v > 10000 {
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid limit", requestID, r.URL.Path)
+			return
+		}
+		limit = int(v)
+	}
+	items, err := h.Meta.ListDamagedVersions(ctx, afterBucket, afterKey, afterVersion, limit)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	resp := damagedResponse{
+		Items: make([]damagedItem, 0, len(items)),
+	}
+	for _, item := range items {
+		resp.Items = append(resp.Items, damagedItem{
+			Bucket:       item.Bucket,
+			Key:          item.Key,
+			VersionID:    item.VersionID,
+			LastModified: item.LastModified,
+			ManifestPath: item.ManifestPath,
+		})
+	}
+	if len(items) == limit {
+		last := items[len(items)-1]
+		resp.NextBucket = last.Bucket
+		resp.NextKey = last.Key
+		resp.NextVersion = last.VersionID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requestTimeoutHeader lets a client bound a read request, either as a Go
+// duration ("1.5s", "250ms") or as whole seconds.
+const requestTimeoutHeader = "x-seglake-timeout"
+
+// requestTimeout returns the deadline for a read request: x-seglake-timeout
+// when set, capped by RequestTimeout, else RequestTimeout (0 = none).
+func (h *Handler) requestTimeout(r *http.Request) (time.Duration, error) {
+	value := strings.TrimSpace(r.Header.Get(requestTimeoutHeader))
+	if value == "" {
+		return h.RequestTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.ParseInt(value, 10, 64)
+		if convErr != nil {
+			return 0, errors.New("invalid " + requestTimeoutHeader)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout <= 0 {
+		return 0, errors.New("invalid " + requestTimeoutHeader)
+	}
+	if h.RequestTimeout > 0 && timeout > h.RequestTimeout {
+		timeout = h.RequestTimeout
+	}
+	return timeout, nil
+}
+
+// deadlineWriter turns the InternalError a handler writes after its context
+// deadline fired into 503 ServiceUnavailable. Responses whose headers were
+// already sent are cut short by the aborted read instead.
+type deadlineWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	requestID   string
+	resource    string
+	wroteHeader bool
+	discard     bool
+}
+
+func (w *deadlineWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.discard = true
+		writeErrorWithResource(w.ResponseWriter, http.StatusServiceUnavailable, "ServiceUnavailable", "request deadline exceeded", w.requestID, w.resource)
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
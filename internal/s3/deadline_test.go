@@ -0,0 +1,103 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeoutHeader(t *testing.T) {
+	h := &Handler{RequestTimeout: 10 * time.Second}
+	cases := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: 10 * time.Second},
+		{value: "250ms", want: 250 * time.Millisecond},
+		{value: "3", want: 3 * time.Second},
+		{value: "1h", want: 10 * time.Second},
+		{value: "0", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/demo/key", nil)
+		if tc.value != "" {
+			req.Header.Set(requestTimeoutHeader, tc.value)
+		}
+		got, err := h.requestTimeout(req)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%q: err=%v", tc.value, err)
+		}
+		if !tc.wantErr && got != tc.want {
+			t.Fatalf("%q: got %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestRequestDeadlineReturnsServiceUnavailable(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "demo", "key", "hello")
+
+	req := httptest.NewRequest(http.MethodGet, "/demo/key", nil)
+	req.Header.Set(requestTimeoutHeader, "1ns")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "request deadline exceeded") || strings.Contains(w.Body.String(), "InternalError") {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/demo/key", nil)
+	req.Header.Set(requestTimeoutHeader, "5s")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Fatalf("expected 200 hello, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/demo/key", nil)
+	req.Header.Set(requestTimeoutHeader, "later")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+
+	// Writes are not bounded by the header.
+	req = httptest.NewRequest(http.MethodPut, "/demo/other", strings.NewReader("x"))
+	req.Header.Set(requestTimeoutHeader, "1ns")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected PUT 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRequestDeadlineSkipsReplicationStreamsAndOps(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "demo", "key", "hello")
+	h.RequestTimeout = time.Nanosecond
+
+	// Building and streaming the snapshot outlasts the deadline.
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/replication/snapshot", nil))
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Fatalf("snapshot: expected 200 with a body, got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/ops/metrics/reset", nil))
+	if w.Code == http.StatusServiceUnavailable {
+		t.Fatalf("metrics reset hit the read deadline: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/demo/key", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("GET: expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+func TestDeleteProtectionGatesPermanentDeletes(t *testing.T) {
+	h := newTestHandler(t)
+	h.DeleteConfirmSecret = "s3cret"
+	putObject(t, h, "vault", "seed", "x")
+	if err := h.Meta.SetBucketVersioningState(t.Context(), "vault", meta.BucketVersioningDisabled); err != nil {
+		t.Fatalf("SetBucketVersioningState: %v", err)
+	}
+
+	serve := func(method, target, confirm string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		if confirm != "" {
+			req.Header.Set(deleteConfirmHeader, confirm)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := serve(http.MethodPut, "/v1/ops/delete-protection?bucket=missing", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing bucket, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPut, "/v1/ops/delete-protection?bucket=vault", ""); rec.Code != http.StatusOK {
+		t.Fatalf("enable: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Unversioned bucket: every delete is permanent.
+	if rec := serve(http.MethodDelete, "/vault/seed", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for unconfirmed delete, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/vault/seed", "wrong"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a wrong token, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/vault/seed", "s3cret"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected confirmed delete to succeed, got %d", rec.Code)
+	}
+
+	// Suspended bucket: a plain delete replaces the null version.
+	if err := h.Meta.SetBucketVersioningState(t.Context(), "vault", meta.BucketVersioningSuspended); err != nil {
+		t.Fatalf("SetBucketVersioningState: %v", err)
+	}
+	putObject(t, h, "vault", "null", "v0")
+	if rec := serve(http.MethodDelete, "/vault/null", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for unconfirmed delete in a suspended bucket, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "/vault/null?versionId=null", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the null version to survive, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/vault/null", "s3cret"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected confirmed delete to succeed, got %d", rec.Code)
+	}
+
+	// Versioned bucket: delete markers pass, version deletes need the token.
+	if err := h.Meta.SetBucketVersioningState(t.Context(), "vault", meta.BucketVersioningEnabled); err != nil {
+		t.Fatalf("SetBucketVersioning: %v", err)
+	}
+	putObject(t, h, "vault", "doc", "v1")
+	versionID := serve(http.MethodHead, "/vault/doc", "").Header().Get("x-amz-version-id")
+	if versionID == "" {
+		t.Fatalf("missing version id")
+	}
+	if rec := serve(http.MethodDelete, "/vault/doc", ""); rec.Code != http.StatusNoContent || rec.Header().Get("x-amz-delete-marker") != "true" {
+		t.Fatalf("expected delete marker, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/vault/doc?versionId="+versionID, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for unconfirmed version delete, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/vault/doc?versionId="+versionID, "s3cret"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected confirmed version delete to succeed, got %d", rec.Code)
+	}
+
+	if rec := serve(http.MethodDelete, "/v1/ops/delete-protection?bucket=vault", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 when disabling without the token, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodDelete, "/v1/ops/delete-protection?bucket=vault", "s3cret"); rec.Code != http.StatusOK {
+		t.Fatalf("disable: %d", rec.Code)
+	}
+	putObject(t, h, "vault", "doc", "v2")
+	versionID = serve(http.MethodHead, "/vault/doc", "").Header().Get("x-amz-version-id")
+	if rec := serve(http.MethodDelete, "/vault/doc?versionId="+versionID, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected version delete without protection to succeed, got %d", rec.Code)
+	}
+}
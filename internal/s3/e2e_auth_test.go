@@ -304,7 +304,7 @@ func TestS3E2EPresignedGet(t *testing.T) {
 		t.Fatalf("PUT status: %d", putResp.StatusCode)
 	}
 
-	presigned, err := handler.Auth.Presign(http.MethodGet, putURL, 5*time.Minute)
+	presigned, err := handler.Auth.Presign(http.MethodGet, putURL, "", 5*time.Minute)
 	if err != nil {
 		t.Fatalf("Presign: %v", err)
 	}
@@ -357,7 +357,7 @@ func TestS3E2EPresignedPut(t *testing.T) {
 	defer server.Close()
 
 	putURL := server.URL + "/bucket/presigned-put"
-	presigned, err := handler.Auth.Presign(http.MethodPut, putURL, 5*time.Minute)
+	presigned, err := handler.Auth.Presign(http.MethodPut, putURL, "", 5*time.Minute)
 	if err != nil {
 		t.Fatalf("Presign: %v", err)
 	}
@@ -644,11 +644,11 @@ func TestS3E2EPresignedExpiry(t *testing.T) {
 	server := httptest.NewServer(handler)
 	defer server.Close()
 
-	putURL, err := auth.Presign(http.MethodPut, server.URL+"/bucket/presign-expire", 1*time.Second)
+	putURL, err := auth.Presign(http.MethodPut, server.URL+"/bucket/presign-expire", "", 1*time.Second)
 	if err != nil {
 		t.Fatalf("presign put: %v", err)
 	}
-	getURL, err := auth.Presign(http.MethodGet, server.URL+"/bucket/presign-expire", 1*time.Second)
+	getURL, err := auth.Presign(http.MethodGet, server.URL+"/bucket/presign-expire", "", 1*time.Second)
 	if err != nil {
 		t.Fatalf("presign get: %v", err)
 	}
@@ -726,7 +726,7 @@ func TestS3E2EPresignedRange(t *testing.T) {
 		t.Fatalf("PUT status: %d", putResp.StatusCode)
 	}
 
-	getURL, err := handler.Auth.Presign(http.MethodGet, server.URL+"/bucket/presigned-range", 5*time.Minute)
+	getURL, err := handler.Auth.Presign(http.MethodGet, server.URL+"/bucket/presigned-range", "", 5*time.Minute)
 	if err != nil {
 		t.Fatalf("Presign: %v", err)
 	}
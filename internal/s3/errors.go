@@ -44,59 +44,91 @@ func writeErrorWithResource(w http.ResponseWriter, status int, code, message, re
 }
 
 var statusByCode = map[string]int{
-	"AccessDenied":                 http.StatusForbidden,
-	"AuthorizationHeaderMalformed": http.StatusBadRequest,
-	"BadDigest":                    http.StatusBadRequest,
-	"BucketNotEmpty":               http.StatusConflict,
-	"EntityTooLarge":               http.StatusRequestEntityTooLarge,
-	"InternalError":                http.StatusInternalServerError,
-	"InvalidArgument":              http.StatusBadRequest,
-	"InvalidBucketName":            http.StatusBadRequest,
-	"InvalidDigest":                http.StatusBadRequest,
-	"InvalidPart":                  http.StatusBadRequest,
-	"InvalidRange":                 http.StatusRequestedRangeNotSatisfiable,
-	"InvalidRequest":               http.StatusBadRequest,
-	"InvalidURI":                   http.StatusBadRequest,
-	"MissingContentLength":         http.StatusLengthRequired,
-	"MethodNotAllowed":             http.StatusMethodNotAllowed,
-	"NoSuchBucket":                 http.StatusNotFound,
-	"NoSuchBucketPolicy":           http.StatusNotFound,
-	"NoSuchKey":                    http.StatusNotFound,
-	"NoSuchUpload":                 http.StatusNotFound,
-	"NoSuchVersion":                http.StatusNotFound,
-	"PreconditionFailed":           http.StatusPreconditionFailed,
-	"RequestTimeTooSkewed":         http.StatusForbidden,
-	"ServiceUnavailable":           http.StatusServiceUnavailable,
-	"SignatureDoesNotMatch":        http.StatusForbidden,
-	"SlowDown":                     http.StatusServiceUnavailable,
-	"XAmzContentSHA256Mismatch":    http.StatusBadRequest,
+	"AccessDenied":                       http.StatusForbidden,
+	"AuthorizationHeaderMalformed":       http.StatusBadRequest,
+	"AuthorizationQueryParametersError":  http.StatusBadRequest,
+	"BadDigest":                          http.StatusBadRequest,
+	"BucketAlreadyExists":                http.StatusConflict,
+	"BucketAlreadyOwnedByYou":            http.StatusConflict,
+	"BucketNotEmpty":                     http.StatusConflict,
+	"EntityTooLarge":                     http.StatusRequestEntityTooLarge,
+	"EntityTooSmall":                     http.StatusBadRequest,
+	"InternalError":                      http.StatusInternalServerError,
+	"InvalidArgument":                    http.StatusBadRequest,
+	"InvalidBucketName":                  http.StatusBadRequest,
+	"InvalidDigest":                      http.StatusBadRequest,
+	"InvalidPart":                        http.StatusBadRequest,
+	"InvalidPartOrder":                   http.StatusBadRequest,
+	"InvalidRange":                       http.StatusRequestedRangeNotSatisfiable,
+	"InvalidRequest":                     http.StatusBadRequest,
+	"InvalidStorageClass":                http.StatusBadRequest,
+	"InvalidTag":                         http.StatusBadRequest,
+	"IllegalLocationConstraintException": http.StatusConflict,
+	"InsufficientStorage":                http.StatusInsufficientStorage,
+	"InvalidURI":                         http.StatusBadRequest,
+	"KeyTooLongError":                    http.StatusBadRequest,
+	"MissingContentLength":               http.StatusLengthRequired,
+	"MalformedXML":                       http.StatusBadRequest,
+	"MethodNotAllowed":                   http.StatusMethodNotAllowed,
+	"NoSuchBucket":                       http.StatusNotFound,
+	"NoSuchBucketPolicy":                 http.StatusNotFound,
+	"NoSuchKey":                          http.StatusNotFound,
+	"NoSuchLifecycleConfiguration":       http.StatusNotFound,
+	"NoSuchTagSet":                       http.StatusNotFound,
+	"NoSuchUpload":                       http.StatusNotFound,
+	"NoSuchVersion":                      http.StatusNotFound,
+	"NoSuchWebsiteConfiguration":         http.StatusNotFound,
+	"NotImplemented":                     http.StatusNotImplemented,
+	"OperationAborted":                   http.StatusConflict,
+	"PreconditionFailed":                 http.StatusPreconditionFailed,
+	"RequestTimeTooSkewed":               http.StatusForbidden,
+	"ServiceUnavailable":                 http.StatusServiceUnavailable,
+	"SignatureDoesNotMatch":              http.StatusForbidden,
+	"SlowDown":                           http.StatusServiceUnavailable,
+	"XAmzContentSHA256Mismatch":          http.StatusBadRequest,
 }
 
 var defaultMessageByCode = map[string]string{
-	"AccessDenied":                 "access denied",
-	"AuthorizationHeaderMalformed": "authorization header malformed",
-	"BadDigest":                    "bad digest",
-	"BucketNotEmpty":               "bucket not empty",
-	"EntityTooLarge":               "entity too large",
-	"InternalError":                "internal error",
-	"InvalidArgument":              "invalid argument",
-	"InvalidBucketName":            "invalid bucket name",
-	"InvalidDigest":                "invalid digest",
-	"InvalidPart":                  "invalid part",
-	"InvalidRange":                 "invalid range",
-	"InvalidRequest":               "invalid request",
-	"InvalidURI":                   "invalid uri",
-	"MissingContentLength":         "missing content length",
-	"MethodNotAllowed":             "the specified method is not allowed against this resource",
-	"NoSuchBucket":                 "bucket not found",
-	"NoSuchBucketPolicy":           "bucket policy not found",
-	"NoSuchKey":                    "key not found",
-	"NoSuchUpload":                 "upload not found",
-	"NoSuchVersion":                "version not found",
-	"PreconditionFailed":           "precondition failed",
-	"RequestTimeTooSkewed":         "request time too skewed",
-	"ServiceUnavailable":           "service unavailable",
-	"SignatureDoesNotMatch":        "signature mismatch",
-	"SlowDown":                     "slow down",
-	"XAmzContentSHA256Mismatch":    "payload hash mismatch",
+	"AccessDenied":                       "access denied",
+	"AuthorizationHeaderMalformed":       "authorization header malformed",
+	"AuthorizationQueryParametersError":  "invalid presigned url query parameters",
+	"BadDigest":                          "bad digest",
+	"BucketAlreadyExists":                "bucket already exists",
+	"BucketAlreadyOwnedByYou":            "bucket already owned by you",
+	"BucketNotEmpty":                     "bucket not empty",
+	"EntityTooLarge":                     "entity too large",
+	"EntityTooSmall":                     "entity too small",
+	"InternalError":                      "internal error",
+	"InvalidArgument":                    "invalid argument",
+	"InvalidBucketName":                  "invalid bucket name",
+	"InvalidDigest":                      "invalid digest",
+	"InvalidPart":                        "invalid part",
+	"InvalidPartOrder":                   "invalid part order",
+	"InvalidRange":                       "invalid range",
+	"InvalidRequest":                     "invalid request",
+	"InvalidStorageClass":                "the storage class you specified is not valid",
+	"InvalidTag":                         "the tag provided was not a valid tag",
+	"IllegalLocationConstraintException": "illegal location constraint",
+	"InsufficientStorage":                "insufficient storage",
+	"InvalidURI":                         "invalid uri",
+	"KeyTooLongError":                    "your key is too long",
+	"MissingContentLength":               "missing content length",
+	"MalformedXML":                       "malformed xml",
+	"MethodNotAllowed":                   "the specified method is not allowed against this resource",
+	"NoSuchBucket":                       "bucket not found",
+	"NoSuchBucketPolicy":                 "bucket policy not found",
+	"NoSuchKey":                          "key not found",
+	"NoSuchLifecycleConfiguration":       "the lifecycle configuration does not exist",
+	"NoSuchTagSet":                       "the tag set does not exist",
+	"NoSuchUpload":                       "upload not found",
+	"NoSuchVersion":                      "version not found",
+	"NoSuchWebsiteConfiguration":         "the specified bucket does not have a website configuration",
+	"NotImplemented":                     "a header or query you provided implies functionality that is not implemented",
+	"OperationAborted":                   "a conflicting operation is in progress",
+	"PreconditionFailed":                 "precondition failed",
+	"RequestTimeTooSkewed":               "request time too skewed",
+	"ServiceUnavailable":                 "service unavailable",
+	"SignatureDoesNotMatch":              "signature mismatch",
+	"SlowDown":                           "slow down",
+	"XAmzContentSHA256Mismatch":          "payload hash mismatch",
 }
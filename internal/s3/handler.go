@@ -1,6 +1,7 @@
 package s3
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/xml"
@@ -42,6 +43,14 @@ type Handler struct {
 	TrustedProxies []string
 	// MaxObjectSize enforces an optional max object size (0 = unlimited).
 	MaxObjectSize int64
+	// MaxBodyBytes caps control-plane request bodies such as policies, XML
+	// configurations and CompleteMultipartUpload (0 = 1 MiB).
+	MaxBodyBytes int64
+	// MaxListKeys caps max-keys on object and version listings (0 = 1000).
+	MaxListKeys int
+	// DisableListPrefixIndex keeps "/" delimiter listings on the key scan even
+	// when the meta prefix index is available.
+	DisableListPrefixIndex bool
 	// MaxURLLength enforces an optional max request URI length in bytes (0 = unlimited).
 	MaxURLLength int
 	// DataDir is the base data directory for ops endpoints.
@@ -54,8 +63,26 @@ type Handler struct {
 	CORSAllowHeaders []string
 	// CORSMaxAge is the Access-Control-Max-Age value in seconds (0 = default).
 	CORSMaxAge int
+	// CORSExposeHeaders lists response headers exposed to browsers (empty = ETag, x-amz-version-id).
+	CORSExposeHeaders []string
+	// CORSAllowCredentials sets Access-Control-Allow-Credentials; the request origin is echoed instead of "*".
+	CORSAllowCredentials bool
+	// SlowRequestThreshold logs requests at least this slow with a slow_request marker (0 disables).
+	SlowRequestThreshold time.Duration
+	// RequestLogSampleRate is the fraction (0..1) of other requests logged as request_sample (0 disables).
+	RequestLogSampleRate float64
+	// RejectAmbiguousKeys refuses PUT/POST to keys with empty, "." or ".."
+	// path segments, which path-normalizing clients and proxies cannot
+	// address. Keys are otherwise taken literally from the request path.
+	RejectAmbiguousKeys bool
+	// RejectUnknownSubresources refuses bucket and object requests carrying
+	// a value-less query key that is neither a known sub-resource nor a
+	// known parameter (e.g. a typo'd ?acll), instead of ignoring it.
+	RejectUnknownSubresources bool
 	// RequireContentMD5 enforces Content-MD5 on PUT and UploadPart.
 	RequireContentMD5 bool
+	// DisableTrailerChecksums accepts x-amz-checksum-* trailers on aws-chunked uploads without validating or storing them.
+	DisableTrailerChecksums bool
 	// ReplayCacheTTL enables replay protection within the TTL window (0 disables).
 	ReplayCacheTTL time.Duration
 	// ReplayCacheMaxEntries caps replay cache size (0 = default).
@@ -64,12 +91,81 @@ type Handler struct {
 	ReplayBlock bool
 	// RequireIfMatchBuckets enforces If-Match on overwrites for selected buckets.
 	RequireIfMatchBuckets map[string]struct{}
+	// SniffContentTypes infers Content-Type from the first 512 bytes of
+	// PUTs that omit it, for selected buckets ("*" for all).
+	SniffContentTypes map[string]struct{}
 	// APIKeyUseMinInterval throttles last_used_at updates per access key (0 = default).
 	APIKeyUseMinInterval time.Duration
-	apiKeyUseMu          sync.Mutex
-	apiKeyUseLast        map[string]time.Time
-	replayCache          *replayCache
-	writeInflight        int64
+	// MPUAllowPartGaps accepts non-contiguous part numbers on CompleteMultipartUpload.
+	MPUAllowPartGaps bool
+	// MPUMaxBufferedParts caps part rows held in memory per CompleteMultipartUpload batch (0 = 1000).
+	MPUMaxBufferedParts int
+	// MPUMaxParts caps the parts stored per multipart upload, checked on
+	// UploadPart (0 = 10000).
+	MPUMaxParts int
+	// MPUMinPartSize is the smallest size of a non-final part accepted by
+	// CompleteMultipartUpload (0 = 5 MiB).
+	MPUMinPartSize int64
+	// MPUMaxUploads caps the ACTIVE multipart uploads per bucket, checked on
+	// CreateMultipartUpload (0 = no cap).
+	MPUMaxUploads int
+	// MPUMaxUploadsBuckets overrides MPUMaxUploads for individual buckets
+	// (0 = no cap for that bucket).
+	MPUMaxUploadsBuckets map[string]int
+	// DeleteConfirmSecret is the x-seglake-confirm value required for
+	// permanent deletes in buckets with delete protection (empty = such
+	// deletes are always refused).
+	DeleteConfirmSecret string
+	// DefaultOwner is reported as bucket owner for requests without an access key (empty = "seglake").
+	DefaultOwner string
+	// CompactInterval enables background segment compaction at this interval (0 disables).
+	CompactInterval time.Duration
+	// CompactMinAge is the minimum sealed age before a segment is compacted.
+	CompactMinAge time.Duration
+	// CompactLiveThreshold rewrites segments with live-bytes ratio <= value (0 = 0.5).
+	CompactLiveThreshold float64
+	// CompactBps caps compaction rewrite throughput in bytes per second (0 = unlimited).
+	CompactBps int64
+	// CompactReads bounds concurrent segment reads during compaction (0 = 1).
+	CompactReads int
+	// CompactPauseFile pauses compaction while the file exists.
+	CompactPauseFile string
+	// CopySourceURLHosts allowlists hosts for PUT with x-seglake-copy-source-url ("*.suffix" wildcards; empty disables).
+	CopySourceURLHosts []string
+	// CopySourceURLTimeout bounds a server-side fetch from a copy source URL (0 = 5m).
+	CopySourceURLTimeout time.Duration
+	// LifecycleInterval is how often bucket lifecycle rules are applied (0 = 1h).
+	LifecycleInterval time.Duration
+	// IdempotencyTTL is how long x-seglake-idempotency-key results are
+	// replayed to retried PUTs (0 = 1h).
+	IdempotencyTTL time.Duration
+	// ReadOnly serves GET and HEAD only (e.g. a frozen snapshot on a read-only
+	// mount): other methods are rejected and API key use is not recorded.
+	ReadOnly bool
+	// RequestTimeout bounds read requests (GET/HEAD, listings); clients may
+	// ask for less with x-seglake-timeout (0 = no default deadline).
+	RequestTimeout time.Duration
+	// RequireOpsClientCert rejects /v1/ops/* and /v1/replication/* requests
+	// without a verified TLS client certificate, in addition to SigV4.
+	RequireOpsClientCert bool
+	// Notifier delivers bucket notification events to webhooks (nil disables).
+	Notifier *Notifier
+	// ReplLagThreshold marks a remote unhealthy in replication status when lag exceeds it (0 = 1m).
+	ReplLagThreshold time.Duration
+	// ReplApplyTarget is the oplog apply latency that repl-push is steered
+	// toward via next_batch_size (0 = 1s).
+	ReplApplyTarget     time.Duration
+	apiKeyUseMu         sync.Mutex
+	apiKeyUseLast       map[string]time.Time
+	replayCache         *replayCache
+	writeInflight       int64
+	replSnapshotMu      sync.Mutex
+	replSnapshotBuildMu sync.Mutex
+	replSnapshots       map[string]*replSnapshot
+	requestLogBuckets   requestLogBuckets
+	mpuCompletingMu     sync.Mutex
+	mpuCompleting       map[string]struct{}
+	copySourceIPBlocked func(net.IP) bool
 }
 
 func (h *Handler) now() time.Time {
@@ -95,6 +191,7 @@ func isUnsignedRequest(r *http.Request) bool {
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = h.withForwardedHost(r)
 	op := h.opForRequest(r)
 	bytesIn := int64(0)
 	if r.Body != nil && r.Body != http.NoBody {
@@ -112,6 +209,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	mw := &metricsWriter{ResponseWriter: w, status: http.StatusOK}
 	h.applyCORSHeaders(mw, r)
+	traceID := requestTraceID(r)
+	if traceID != "" {
+		mw.Header().Set(traceIDHeader, traceID)
+	}
 	start := h.now()
 	accessKey := extractAccessKey(r)
 	if r.Method == http.MethodOptions {
@@ -119,14 +220,22 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.handleOptions(mw, r, requestID)
 		return
 	}
+	if isReadyzRequest(r) {
+		h.handleReadyz(mw, r)
+		return
+	}
 	requestID, ok := h.prepareRequest(mw, r)
 	if !ok {
 		return
 	}
+	if h.ReadOnly && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeErrorWithResource(mw, http.StatusMethodNotAllowed, "MethodNotAllowed", "server is read-only", requestID, r.URL.Path)
+		return
+	}
 	if h.Meta != nil {
 		state, err := h.Meta.MaintenanceState(r.Context())
 		if err != nil {
-			writeErrorWithResource(mw, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+			h.writeInternalError(mw, err, requestID, r.URL.Path)
 			return
 		}
 		r = r.WithContext(context.WithValue(r.Context(), maintenanceStateKey{}, state.State))
@@ -143,7 +252,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		if !h.InflightLimiter.AcquireWithLimit(accessKey, limit) {
-			writeErrorWithResource(mw, http.StatusServiceUnavailable, "SlowDown", "too many inflight requests", requestID, r.URL.Path)
+			h.writeSlowDown(mw, op, "too many inflight requests", requestID, r.URL.Path)
 			return
 		}
 		defer h.InflightLimiter.Release(accessKey)
@@ -157,46 +266,89 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		defer atomic.AddInt64(&h.writeInflight, -1)
 	}
 	defer func() {
+		elapsed := time.Since(start)
 		if h.Metrics != nil {
 			h.Metrics.AddBytesIn(bytesIn)
 			h.Metrics.AddBytesOut(mw.bytes)
-			h.Metrics.Record(op, mw.status, time.Since(start), bucketName, keyName)
+			h.Metrics.Record(op, mw.status, elapsed, bucketName, keyName)
+			h.Metrics.RecordAccessKey(accessKey, mw.status)
 		}
+		h.logRequestTiming(op, bucketName, keyName, requestID, traceID, mw.status, elapsed, bytesIn, mw.bytes)
 	}()
-	if h.handleMetaAndReplication(r.Context(), mw, r, requestID) {
+	var rw http.ResponseWriter = mw
+	if isReadOp(op) {
+		timeout, err := h.requestTimeout(r)
+		if err != nil {
+			writeErrorWithResource(mw, http.StatusBadRequest, "InvalidArgument", err.Error(), requestID, r.URL.Path)
+			return
+		}
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+			rw = &deadlineWriter{ResponseWriter: mw, ctx: ctx, requestID: requestID, resource: r.URL.Path}
+		}
+	}
+	if h.handleMetaAndReplication(r.Context(), rw, r, requestID) {
 		return
 	}
+	if name := notImplementedSubresource(r.URL.Query()); name != "" {
+		writeErrorWithResource(rw, http.StatusNotImplemented, "NotImplemented", "?"+name+" is not implemented", requestID, r.URL.Path)
+		return
+	}
+	if h.RejectUnknownSubresources {
+		if name := unknownSubresource(r.URL.Query()); name != "" {
+			writeErrorWithResource(rw, http.StatusBadRequest, "InvalidArgument", "unknown sub-resource ?"+name, requestID, r.URL.Path)
+			return
+		}
+	}
 	hostBucket := h.hostBucket(r)
-	if h.handleBucketLevelRequests(r.Context(), mw, r, requestID, bucketOnly, hasBucketOnly, hostBucket) {
+	if r.URL.Query().Has("acl") {
+		switch {
+		case hasBucketKey:
+			h.handleACL(r.Context(), rw, r, bucket, key, requestID)
+		case hasBucketOnly:
+			h.handleACL(r.Context(), rw, r, bucketOnly, "", requestID)
+		default:
+			writeErrorWithResource(rw, http.StatusBadRequest, "InvalidURI", "", requestID, r.URL.Path)
+		}
+		return
+	}
+	if r.URL.Query().Has("tagging") && hasBucketKey {
+		// Only bucket tags are supported; object tagging is not.
+		writeErrorWithResource(rw, http.StatusNotImplemented, "NotImplemented", "?tagging is not implemented for objects", requestID, r.URL.Path)
+		return
+	}
+	if h.handleBucketLevelRequests(r.Context(), rw, r, requestID, bucketOnly, hasBucketOnly, hostBucket) {
 		return
 	}
 	if !hasBucketKey {
 		if r.Method == http.MethodPut {
 			if hasBucketOnly {
-				h.handleCreateBucket(r.Context(), mw, r, bucketOnly, requestID, r.URL.Path)
+				h.handleCreateBucket(r.Context(), rw, r, bucketOnly, requestID, r.URL.Path)
 				return
 			}
 		}
 		if r.Method == http.MethodGet {
 			if isListV1Request(r, hasBucketOnly) {
-				h.handleListV1(r.Context(), mw, r, bucketOnly, requestID)
+				h.handleListV1(r.Context(), rw, r, bucketOnly, requestID)
 				return
 			}
 		}
 		if r.Method == http.MethodDelete {
 			if hasBucketOnly {
-				h.handleDeleteBucket(r.Context(), mw, bucketOnly, requestID, r.URL.Path)
+				h.handleDeleteBucket(r.Context(), rw, bucketOnly, requestID, r.URL.Path)
 				return
 			}
 		}
 		if hasBucketOnly {
-			writeErrorWithResource(mw, http.StatusMethodNotAllowed, "MethodNotAllowed", "", requestID, r.URL.Path)
+			writeErrorWithResource(rw, http.StatusMethodNotAllowed, "MethodNotAllowed", "", requestID, r.URL.Path)
 			return
 		}
-		writeErrorWithResource(mw, http.StatusBadRequest, "InvalidURI", "", requestID, r.URL.Path)
+		writeErrorWithResource(rw, http.StatusBadRequest, "InvalidURI", "", requestID, r.URL.Path)
 		return
 	}
-	h.handleObjectRequests(r.Context(), mw, r, requestID, bucket, key)
+	h.handleObjectRequests(r.Context(), rw, r, requestID, bucket, key)
 }
 
 func (h *Handler) handleMetaAndReplication(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) bool {
@@ -213,6 +365,13 @@ func (h *Handler) handleMetaAndReplication(ctx context.Context, w http.ResponseW
 				h.handleStats(ctx, w, requestID, r.URL.Path)
 			},
 		},
+		{
+			method: http.MethodGet,
+			prefix: "/v1/meta/usage",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+				h.handleUsage(ctx, w, requestID, r.URL.Path)
+			},
+		},
 		{
 			method: http.MethodGet,
 			prefix: "/v1/meta/conflicts",
@@ -220,6 +379,77 @@ func (h *Handler) handleMetaAndReplication(ctx context.Context, w http.ResponseW
 				h.handleConflicts(ctx, w, r, requestID)
 			},
 		},
+		{
+			method: http.MethodGet,
+			prefix: "/v1/ops/damaged",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+				h.handleDamaged(ctx, w, r, requestID)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			prefix:  "/v1/ops/key-versions",
+			handler: h.handleKeyVersions,
+		},
+		{
+			method: http.MethodGet,
+			prefix: "/v1/ops/metrics",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+				h.handleOpsMetrics(ctx, w, r, requestID, false)
+			},
+		},
+		{
+			method: http.MethodPost,
+			prefix: "/v1/ops/metrics/reset",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+				h.handleOpsMetrics(ctx, w, r, requestID, true)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			prefix:  "/v1/ops/default-content-type",
+			handler: h.handleBucketContentType,
+		},
+		{
+			method:  http.MethodPut,
+			prefix:  "/v1/ops/default-content-type",
+			handler: h.handleBucketContentType,
+		},
+		{
+			method:  http.MethodDelete,
+			prefix:  "/v1/ops/default-content-type",
+			handler: h.handleBucketContentType,
+		},
+		{
+			method:  http.MethodGet,
+			prefix:  "/v1/ops/delete-protection",
+			handler: h.handleDeleteProtection,
+		},
+		{
+			method:  http.MethodPut,
+			prefix:  "/v1/ops/delete-protection",
+			handler: h.handleDeleteProtection,
+		},
+		{
+			method:  http.MethodDelete,
+			prefix:  "/v1/ops/delete-protection",
+			handler: h.handleDeleteProtection,
+		},
+		{
+			method:  http.MethodGet,
+			prefix:  "/v1/ops/request-logging",
+			handler: h.handleRequestLogging,
+		},
+		{
+			method:  http.MethodPut,
+			prefix:  "/v1/ops/request-logging",
+			handler: h.handleRequestLogging,
+		},
+		{
+			method:  http.MethodDelete,
+			prefix:  "/v1/ops/request-logging",
+			handler: h.handleRequestLogging,
+		},
 		{
 			method: http.MethodGet,
 			prefix: "/v1/replication/oplog",
@@ -227,6 +457,13 @@ func (h *Handler) handleMetaAndReplication(ctx context.Context, w http.ResponseW
 				h.handleOplog(ctx, w, r, requestID)
 			},
 		},
+		{
+			method: http.MethodGet,
+			prefix: "/v1/replication/status",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+				h.handleReplicationStatus(ctx, w, requestID, r.URL.Path)
+			},
+		},
 		{
 			method: http.MethodGet,
 			prefix: "/v1/replication/snapshot",
@@ -248,6 +485,13 @@ func (h *Handler) handleMetaAndReplication(ctx context.Context, w http.ResponseW
 				h.handleReplicationChunk(ctx, w, r, requestID)
 			},
 		},
+		{
+			method: http.MethodGet,
+			prefix: "/v1/replication/object-meta",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+				h.handleReplicationObjectMeta(ctx, w, r, requestID)
+			},
+		},
 		{
 			method: http.MethodPost,
 			prefix: "/v1/replication/oplog",
@@ -261,6 +505,10 @@ func (h *Handler) handleMetaAndReplication(ctx context.Context, w http.ResponseW
 			continue
 		}
 		if strings.HasPrefix(r.URL.Path, route.prefix) {
+			if h.RequireOpsClientCert && requiresClientCert(r.URL.Path) && !hasVerifiedClientCert(r) {
+				writeErrorWithResource(w, http.StatusForbidden, "AccessDenied", "client certificate required", requestID, r.URL.Path)
+				return true
+			}
 			route.handler(ctx, w, r, requestID)
 			return true
 		}
@@ -268,6 +516,24 @@ func (h *Handler) handleMetaAndReplication(ctx context.Context, w http.ResponseW
 	return false
 }
 
+// clientCertPrefixes are the route prefixes gated by RequireOpsClientCert.
+var clientCertPrefixes = []string{"/v1/ops/", "/v1/replication/"}
+
+func requiresClientCert(path string) bool {
+	for _, prefix := range clientCertPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasVerifiedClientCert reports whether the TLS handshake presented a client
+// certificate that chained to the configured client CA.
+func hasVerifiedClientCert(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
+
 type bucketListKind int
 
 const (
@@ -282,6 +548,18 @@ const (
 	bucketDeletePolicy
 	bucketGetVersioning
 	bucketPutVersioning
+	bucketGetLifecycle
+	bucketPutLifecycle
+	bucketDeleteLifecycle
+	bucketGetWebsite
+	bucketPutWebsite
+	bucketDeleteWebsite
+	bucketGetTagging
+	bucketPutTagging
+	bucketDeleteTagging
+	bucketGetNotification
+	bucketPutNotification
+	bucketDeleteNotification
 	bucketHead
 )
 
@@ -320,6 +598,30 @@ func bucketListKindForRequest(r *http.Request, hostBucket string, hasBucketOnly
 			}
 			return bucketGetVersioning
 		}
+		if r.URL.Query().Has("lifecycle") {
+			if !hasBucketOnly && hostBucket == "" {
+				return bucketListNone
+			}
+			return bucketGetLifecycle
+		}
+		if r.URL.Query().Has("website") {
+			if !hasBucketOnly && hostBucket == "" {
+				return bucketListNone
+			}
+			return bucketGetWebsite
+		}
+		if r.URL.Query().Has("tagging") {
+			if !hasBucketOnly && hostBucket == "" {
+				return bucketListNone
+			}
+			return bucketGetTagging
+		}
+		if r.URL.Query().Has("notification") {
+			if !hasBucketOnly && hostBucket == "" {
+				return bucketListNone
+			}
+			return bucketGetNotification
+		}
 		if r.URL.Query().Has("policy") {
 			if !hasBucketOnly && hostBucket == "" {
 				return bucketListNone
@@ -350,6 +652,50 @@ func bucketListKindForRequest(r *http.Request, hostBucket string, hasBucketOnly
 			return bucketPutVersioning
 		}
 	}
+	if r.URL.Query().Has("lifecycle") {
+		if !hasBucketOnly && hostBucket == "" {
+			return bucketListNone
+		}
+		switch r.Method {
+		case http.MethodPut:
+			return bucketPutLifecycle
+		case http.MethodDelete:
+			return bucketDeleteLifecycle
+		}
+	}
+	if r.URL.Query().Has("website") {
+		if !hasBucketOnly && hostBucket == "" {
+			return bucketListNone
+		}
+		switch r.Method {
+		case http.MethodPut:
+			return bucketPutWebsite
+		case http.MethodDelete:
+			return bucketDeleteWebsite
+		}
+	}
+	if r.URL.Query().Has("tagging") {
+		if !hasBucketOnly && hostBucket == "" {
+			return bucketListNone
+		}
+		switch r.Method {
+		case http.MethodPut:
+			return bucketPutTagging
+		case http.MethodDelete:
+			return bucketDeleteTagging
+		}
+	}
+	if r.URL.Query().Has("notification") {
+		if !hasBucketOnly && hostBucket == "" {
+			return bucketListNone
+		}
+		switch r.Method {
+		case http.MethodPut:
+			return bucketPutNotification
+		case http.MethodDelete:
+			return bucketDeleteNotification
+		}
+	}
 	return bucketListNone
 }
 
@@ -387,7 +733,7 @@ func (h *Handler) handleBucketLevelRequests(ctx context.Context, w http.Response
 		}
 		exists, err := h.Meta.BucketExists(ctx, bucket)
 		if err != nil {
-			writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+			h.writeInternalError(w, err, requestID, r.URL.Path)
 			return true
 		}
 		if !exists {
@@ -441,6 +787,90 @@ func (h *Handler) handleBucketLevelRequests(ctx context.Context, w http.Response
 		}
 		h.handlePutBucketVersioning(ctx, w, r, bucket, requestID)
 		return true
+	case bucketGetLifecycle:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handleGetBucketLifecycle(ctx, w, r, bucket, requestID)
+		return true
+	case bucketPutLifecycle:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handlePutBucketLifecycle(ctx, w, r, bucket, requestID)
+		return true
+	case bucketDeleteLifecycle:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handleDeleteBucketLifecycle(ctx, w, r, bucket, requestID)
+		return true
+	case bucketGetWebsite:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handleGetBucketWebsite(ctx, w, r, bucket, requestID)
+		return true
+	case bucketPutWebsite:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handlePutBucketWebsite(ctx, w, r, bucket, requestID)
+		return true
+	case bucketDeleteWebsite:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handleDeleteBucketWebsite(ctx, w, r, bucket, requestID)
+		return true
+	case bucketGetTagging:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handleGetBucketTagging(ctx, w, r, bucket, requestID)
+		return true
+	case bucketPutTagging:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handlePutBucketTagging(ctx, w, r, bucket, requestID)
+		return true
+	case bucketDeleteTagging:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handleDeleteBucketTagging(ctx, w, r, bucket, requestID)
+		return true
+	case bucketGetNotification:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handleGetBucketNotification(ctx, w, r, bucket, requestID)
+		return true
+	case bucketPutNotification:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handlePutBucketNotification(ctx, w, r, bucket, requestID)
+		return true
+	case bucketDeleteNotification:
+		bucket := bucketOnly
+		if bucket == "" {
+			bucket = hostBucket
+		}
+		h.handleDeleteBucketNotification(ctx, w, r, bucket, requestID)
+		return true
 	case bucketHead:
 		bucket := bucketOnly
 		if bucket == "" {
@@ -453,12 +883,61 @@ func (h *Handler) handleBucketLevelRequests(ctx context.Context, w http.Response
 }
 
 func (h *Handler) handleObjectRequests(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID, bucket, key string) {
+	// Validate for every method so a key that can be written can also be read and deleted.
+	if err := ValidateObjectKey(key); err != nil {
+		code := objectKeyErrorCode(err)
+		writeErrorWithResource(w, http.StatusBadRequest, code, err.Error(), requestID, r.URL.Path)
+		return
+	}
+	// Only writes are refused so keys created earlier stay readable and deletable.
+	if h.RejectAmbiguousKeys && (r.Method == http.MethodPut || r.Method == http.MethodPost) {
+		if err := validateKeySegments(key); err != nil {
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", err.Error(), requestID, r.URL.Path)
+			return
+		}
+	}
 	type objectRoute struct {
 		method  string
 		match   func(*http.Request) bool
 		handler func()
 	}
 	routes := []objectRoute{
+		{
+			method: http.MethodPut,
+			match: func(r *http.Request) bool {
+				return isAllocateRequest(r) && r.URL.Query().Get("uploadId") == ""
+			},
+			handler: func() {
+				h.handleAllocateObject(ctx, w, r, bucket, key, requestID)
+			},
+		},
+		{
+			method: http.MethodPut,
+			match: func(r *http.Request) bool {
+				return isOverwriteRequest(r) && r.URL.Query().Get("uploadId") == ""
+			},
+			handler: func() {
+				h.handleOverwriteObject(ctx, w, r, bucket, key, requestID)
+			},
+		},
+		{
+			method: http.MethodPut,
+			match: func(r *http.Request) bool {
+				return isAppendRequest(r) && r.URL.Query().Get("uploadId") == ""
+			},
+			handler: func() {
+				h.handleAppendObject(ctx, w, r, bucket, key, requestID)
+			},
+		},
+		{
+			method: http.MethodPut,
+			match: func(r *http.Request) bool {
+				return r.Header.Get(moveSourceHeader) != ""
+			},
+			handler: func() {
+				h.handleMoveObject(ctx, w, r, bucket, key, r.Header.Get(moveSourceHeader), requestID)
+			},
+		},
 		{
 			method: http.MethodPut,
 			match: func(r *http.Request) bool {
@@ -477,6 +956,15 @@ func (h *Handler) handleObjectRequests(ctx context.Context, w http.ResponseWrite
 				h.handleUploadPart(ctx, w, r, bucket, key, r.URL.Query().Get("uploadId"), requestID)
 			},
 		},
+		{
+			method: http.MethodPut,
+			match: func(r *http.Request) bool {
+				return r.Header.Get(copySourceURLHeader) != ""
+			},
+			handler: func() {
+				h.handlePutFromURL(ctx, w, r, bucket, key, requestID)
+			},
+		},
 		{
 			method: http.MethodPut,
 			match:  func(*http.Request) bool { return true },
@@ -497,6 +985,9 @@ func (h *Handler) handleObjectRequests(ctx context.Context, w http.ResponseWrite
 			method: http.MethodGet,
 			match:  func(*http.Request) bool { return true },
 			handler: func() {
+				if h.websiteRedirect(ctx, w, r, bucket, key, requestID) {
+					return
+				}
 				h.handleGet(ctx, w, r, bucket, key, requestID, false)
 			},
 		},
@@ -621,6 +1112,9 @@ func (h *Handler) prepareRequest(w http.ResponseWriter, r *http.Request) (string
 				writeErrorWithResource(w, http.StatusForbidden, "RequestTimeTooSkewed", "request time too skewed", requestID, r.URL.Path)
 			case errAuthMalformed:
 				writeErrorWithResource(w, http.StatusBadRequest, "AuthorizationHeaderMalformed", "authorization header malformed", requestID, r.URL.Path)
+			case errPresignExpiry:
+				msg := fmt.Sprintf("X-Amz-Expires must be between 1 and %d seconds", int64(h.Auth.presignExpiryLimit()/time.Second))
+				writeErrorWithResource(w, http.StatusBadRequest, "AuthorizationQueryParametersError", msg, requestID, r.URL.Path)
 			case errMissingContentSHA256:
 				writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "missing required header for this request: x-amz-content-sha256", requestID, r.URL.Path)
 			default:
@@ -646,14 +1140,27 @@ func (h *Handler) prepareRequest(w http.ResponseWriter, r *http.Request) (string
 		}
 	}
 	if err := h.authorizeRequest(r.Context(), r); err != nil {
-		writeErrorWithResource(w, http.StatusForbidden, "AccessDenied", "access denied", requestID, r.URL.Path)
+		msg := "access denied"
+		var unsigned unsignedHeaderError
+		if errors.As(err, &unsigned) {
+			msg = unsigned.Error()
+		}
+		writeErrorWithResource(w, http.StatusForbidden, "AccessDenied", msg, requestID, r.URL.Path)
 		return requestID, false
 	}
 	return requestID, true
 }
 
 func (h *Handler) authorizeRequest(ctx context.Context, r *http.Request) error {
-	if h == nil || h.Meta == nil || r == nil {
+	if h == nil || r == nil {
+		return nil
+	}
+	// Checked here rather than per handler so no presigned operation (copy,
+	// upload-part-copy, copy from URL, ...) can carry unsigned headers.
+	if name := unsignedSensitiveHeader(r); name != "" {
+		return unsignedHeaderError{name: name}
+	}
+	if h.Meta == nil {
 		return nil
 	}
 	accessKey := extractAccessKey(r)
@@ -672,7 +1179,8 @@ func (h *Handler) authorizeRequest(ctx context.Context, r *http.Request) error {
 		}
 		return h.authorizeUnsignedRequest(ctx, r)
 	}
-	action := policyActionForRequest(h.opForRequest(r))
+	op := h.opForRequest(r)
+	action := policyActionForRequest(op)
 	if action == policyActionOps && h.Auth != nil && h.Auth.OpsAccessKey != "" && h.Auth.OpsSecretKey != "" && accessKey == h.Auth.OpsAccessKey {
 		return nil
 	}
@@ -702,8 +1210,13 @@ func (h *Handler) authorizeRequest(ctx context.Context, r *http.Request) error {
 	if _, keyParsed, ok := h.parseBucketKey(r); ok {
 		keyName = keyParsed
 	}
+	// Listings are scoped by their prefix parameter, so a prefix-restricted
+	// key can only enumerate within its prefix.
+	if isListOp(op) {
+		keyName = r.URL.Query().Get("prefix")
+	}
 	if bucket != "" {
-		allowed, err := h.Meta.IsBucketAllowed(ctx, accessKey, bucket)
+		allowed, err := h.allowlistPermits(ctx, accessKey, op, bucket, keyName)
 		if err != nil {
 			return err
 		}
@@ -711,6 +1224,37 @@ func (h *Handler) authorizeRequest(ctx context.Context, r *http.Request) error {
 			return errAccessDenied
 		}
 	}
+	// A copy reads its source, so the key also needs get on the source.
+	copyBucket, copyKey, isCopy := "", "", false
+	if op == "copy" {
+		copyBucket, copyKey, isCopy = parseCopySource(r.Header.Get("X-Amz-Copy-Source"))
+		if isCopy {
+			allowed, err := h.allowlistPermits(ctx, accessKey, "get", copyBucket, copyKey)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return errAccessDenied
+			}
+		}
+	}
+	// A move reads and deletes its source, so the key also needs get and
+	// delete on the source.
+	moveBucket, moveKey, isMove := "", "", false
+	if op == "move" {
+		moveBucket, moveKey, isMove = parseCopySource(r.Header.Get(moveSourceHeader))
+		if isMove {
+			for _, srcOp := range []string{"get", "delete"} {
+				allowed, err := h.allowlistPermits(ctx, accessKey, srcOp, moveBucket, moveKey)
+				if err != nil {
+					return err
+				}
+				if !allowed {
+					return errAccessDenied
+				}
+			}
+		}
+	}
 	if action != "" {
 		pol, err := ParsePolicy(policy)
 		if err != nil {
@@ -720,28 +1264,19 @@ func (h *Handler) authorizeRequest(ctx context.Context, r *http.Request) error {
 		if targetBucket == "" {
 			targetBucket = "*"
 		}
-		reqCtx := h.policyContextFromRequest(r)
-		identityAllowed, identityDenied := pol.DecisionWithContext(action, targetBucket, keyName, reqCtx)
-		if identityDenied {
+		if !h.policyAllows(ctx, pol, action, targetBucket, keyName, h.policyContextFromRequest(r)) {
 			return errAccessDenied
 		}
-		bucketAllowed := false
-		bucketDenied := false
-		if bucket != "" {
-			if bucketPolicy, err := h.Meta.GetBucketPolicy(ctx, bucket); err == nil && bucketPolicy != "" {
-				if bpol, err := ParsePolicy(bucketPolicy); err == nil {
-					bucketAllowed, bucketDenied = bpol.DecisionWithContext(action, bucket, keyName, reqCtx)
-				} else {
+		if isCopy && !h.policyAllows(ctx, pol, policyActionGetObject, copyBucket, copyKey, h.policyContextFromRequest(r)) {
+			return errAccessDenied
+		}
+		if isMove {
+			for _, srcAction := range []string{policyActionGetObject, policyActionDeleteObject} {
+				if !h.policyAllows(ctx, pol, srcAction, moveBucket, moveKey, h.policyContextFromRequest(r)) {
 					return errAccessDenied
 				}
 			}
 		}
-		if bucketDenied {
-			return errAccessDenied
-		}
-		if !identityAllowed && !bucketAllowed {
-			return errAccessDenied
-		}
 	}
 	if action == policyActionOps && strings.EqualFold(strings.TrimSpace(key.Policy), "rw") {
 		return errAccessDenied
@@ -754,6 +1289,63 @@ func (h *Handler) authorizeRequest(ctx context.Context, r *http.Request) error {
 	return nil
 }
 
+// allowlistPermits checks the key's bucket allowlist. Entries are bucket
+// names or "bucket/prefix/*" patterns; a prefix entry covers keys under the
+// prefix, listings whose prefix starts with it, and HeadBucket. An empty
+// allowlist permits every bucket.
+func (h *Handler) allowlistPermits(ctx context.Context, accessKey, op, bucket, keyName string) (bool, error) {
+	entries, err := h.Meta.ListAllowedBuckets(ctx, accessKey)
+	if err != nil {
+		return false, err
+	}
+	if len(entries) == 0 {
+		return true, nil
+	}
+	for _, entry := range entries {
+		res, err := parseResourcePattern(entry)
+		if err != nil || res.Bucket != bucket {
+			continue
+		}
+		if res.Prefix == "" || op == "head_bucket" || strings.HasPrefix(keyName, res.Prefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isListOp(op string) bool {
+	switch op {
+	case "list_v1", "list_v2", "list_versions", "mpu_list_uploads":
+		return true
+	default:
+		return false
+	}
+}
+
+// policyAllows combines the identity policy with the bucket policy; an
+// explicit deny in either wins and an unparsable bucket policy denies.
+func (h *Handler) policyAllows(ctx context.Context, pol *Policy, action, bucket, keyName string, reqCtx *PolicyContext) bool {
+	identityAllowed, identityDenied := pol.DecisionWithContext(action, bucket, keyName, reqCtx)
+	if identityDenied {
+		return false
+	}
+	bucketAllowed := false
+	if bucket != "" && bucket != "*" {
+		if bucketPolicy, err := h.Meta.GetBucketPolicy(ctx, bucket); err == nil && bucketPolicy != "" {
+			bpol, err := ParsePolicy(bucketPolicy)
+			if err != nil {
+				return false
+			}
+			var bucketDenied bool
+			bucketAllowed, bucketDenied = bpol.DecisionWithContext(action, bucket, keyName, reqCtx)
+			if bucketDenied {
+				return false
+			}
+		}
+	}
+	return identityAllowed || bucketAllowed
+}
+
 func maintenanceStateFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return "off"
@@ -774,8 +1366,10 @@ func (h *Handler) authorizeUnsignedRequest(ctx context.Context, r *http.Request)
 	if !ok || !h.isPublicBucket(bucket) {
 		return errAccessDenied
 	}
-	action := policyActionForRequest(h.opForRequest(r))
-	if action == "" {
+	op := h.opForRequest(r)
+	action := policyActionForRequest(op)
+	// Anonymous moves are refused: the source may be outside the public bucket.
+	if action == "" || op == "move" {
 		return errAccessDenied
 	}
 	bucketPolicy, err := h.Meta.GetBucketPolicy(ctx, bucket)
@@ -824,7 +1418,7 @@ func (h *Handler) isPublicBucket(bucket string) bool {
 }
 
 func (h *Handler) recordAPIKeyUse(accessKey string) {
-	if h == nil || h.Meta == nil || accessKey == "" {
+	if h == nil || h.Meta == nil || h.ReadOnly || accessKey == "" {
 		return
 	}
 	interval := h.APIKeyUseMinInterval
@@ -860,16 +1454,10 @@ func (h *Handler) policyContextFromRequest(r *http.Request) *PolicyContext {
 		}
 		headers[strings.ToLower(k)] = values[0]
 	}
-	ip := clientIP(r.RemoteAddr)
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" && h.isTrustedProxy(r.RemoteAddr) {
-		parts := strings.Split(forwarded, ",")
-		if len(parts) > 0 && strings.TrimSpace(parts[0]) != "" {
-			ip = strings.TrimSpace(parts[0])
-		}
-	}
+	ip := h.sourceIP(r)
 	secure := r.TLS != nil
-	if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded != "" && h.isTrustedProxy(r.RemoteAddr) {
-		secure = strings.EqualFold(strings.TrimSpace(forwarded), "https")
+	if proto := h.forwardedHeader(r, "X-Forwarded-Proto"); proto != "" {
+		secure = strings.EqualFold(proto, "https")
 	}
 	prefix := ""
 	delimiter := ""
@@ -888,6 +1476,47 @@ func (h *Handler) policyContextFromRequest(r *http.Request) *PolicyContext {
 	}
 }
 
+// sourceIP returns the client address of r: the first X-Forwarded-For entry
+// when r comes from a trusted proxy, otherwise the peer address.
+func (h *Handler) sourceIP(r *http.Request) string {
+	ip := clientIP(r.RemoteAddr)
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" && h.isTrustedProxy(r.RemoteAddr) {
+		parts := strings.Split(forwarded, ",")
+		if len(parts) > 0 && strings.TrimSpace(parts[0]) != "" {
+			ip = strings.TrimSpace(parts[0])
+		}
+	}
+	return ip
+}
+
+// forwardedHeader returns the value of an X-Forwarded-* header set by the
+// trusted proxy in front of us, or "" unless the request comes from one. Of a
+// comma-separated list only the last entry counts: that is the one our proxy
+// appended, while earlier ones come from the client and could claim https or
+// any host.
+func (h *Handler) forwardedHeader(r *http.Request, name string) string {
+	values := r.Header.Values(name)
+	if len(values) == 0 || !h.isTrustedProxy(r.RemoteAddr) {
+		return ""
+	}
+	value := values[len(values)-1]
+	if i := strings.LastIndex(value, ","); i >= 0 {
+		value = value[i+1:]
+	}
+	return strings.TrimSpace(value)
+}
+
+// withForwardedHost returns r addressed to the X-Forwarded-Host of a trusted
+// proxy: behind a TLS-terminating proxy the client signed (and addressed a
+// virtual-hosted bucket by) the public host, not the upstream one.
+func (h *Handler) withForwardedHost(r *http.Request) *http.Request {
+	if host := h.forwardedHeader(r, "X-Forwarded-Host"); host != "" && host != r.Host {
+		r = r.WithContext(r.Context())
+		r.Host = host
+	}
+	return r
+}
+
 func (h *Handler) isTrustedProxy(remoteAddr string) bool {
 	if h == nil {
 		return false
@@ -913,6 +1542,19 @@ func (h *Handler) isTrustedProxy(remoteAddr string) bool {
 
 func (h *Handler) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, requestID string) {
 	defer func() { _ = r.Body.Close() }()
+	idemKey := r.Header.Get(idempotencyKeyHeader)
+	if h.Meta == nil {
+		idemKey = ""
+	}
+	if len(idemKey) > maxIdempotencyKeyLen {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "idempotency key too long", requestID, r.URL.Path)
+		return
+	}
+	// A retry of a PUT that already committed is answered before If-Match,
+	// which the first attempt changed.
+	if idemKey != "" && h.replayIdempotentPut(ctx, w, r, bucket, key, idemKey, requestID) {
+		return
+	}
 	if !h.enforceIfMatch(ctx, w, r, bucket, key, requestID) {
 		return
 	}
@@ -927,11 +1569,12 @@ func (h *Handler) handlePut(ctx context.Context, w http.ResponseWriter, r *http.
 		return
 	}
 	reader := io.Reader(r.Body)
-	reader, streamingMode, decodedLen, hasDecoded, reqErr := setupStreamingReader(r, reader)
+	reader, streamingMode, decodedLen, hasDecoded, reqErr := setupStreamingReader(r, reader, h.DisableTrailerChecksums)
 	if reqErr != nil {
 		writeErrorWithResource(w, reqErr.status, reqErr.code, reqErr.message, requestID, r.URL.Path)
 		return
 	}
+	chunked, _ := reader.(*awsChunkedReader)
 	if h.MaxObjectSize > 0 {
 		switch {
 		case streamingMode != streamingNone && hasDecoded && decodedLen > h.MaxObjectSize:
@@ -954,6 +1597,26 @@ func (h *Handler) handlePut(ctx context.Context, w http.ResponseWriter, r *http.
 		writeErrorWithResource(w, http.StatusBadRequest, "InvalidDigest", "content-md5 required", requestID, r.URL.Path)
 		return
 	}
+	ttl, err := parseObjectTTL(r.Header.Get(ttlHeader))
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", err.Error(), requestID, r.URL.Path)
+		return
+	}
+	storageClass, ok := parseStorageClass(r)
+	if !ok {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidStorageClass", "invalid storage class", requestID, r.URL.Path)
+		return
+	}
+	userMetadata, err := parseUserMetadata(r)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "MetadataTooLarge", "user metadata exceeds 2 KB", requestID, r.URL.Path)
+		return
+	}
+	tags, err := parseTaggingHeader(r)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidTag", err.Error(), requestID, r.URL.Path)
+		return
+	}
 	payloadHash := ""
 	verifyPayload := false
 	if streamingMode == streamingNone {
@@ -970,15 +1633,65 @@ func (h *Handler) handlePut(ctx context.Context, w http.ResponseWriter, r *http.
 	if verifyPayload || len(expectedMD5) > 0 {
 		reader = newValidatingReader(reader, payloadHash, verifyPayload, expectedMD5)
 	}
-	contentType := strings.TrimSpace(r.Header.Get("Content-Type"))
 	versioningState, err := h.bucketVersioningState(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
-	_, result, err := h.Engine.PutObject(ctx, bucket, key, contentType, reader)
+	// Everything above rejects without reading the body, so a client that
+	// sent Expect: 100-continue gets the error instead of a 100 Continue and
+	// never uploads. The first body read below sends the 100 Continue.
+	contentType := strings.TrimSpace(r.Header.Get("Content-Type"))
+	if contentType == "" {
+		contentType, reader, err = h.putContentType(ctx, bucket, reader)
+		if err != nil {
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+	}
+	var extraCommit func(tx *sql.Tx, result *engine.PutResult, manifestPath string) error
+	if chunked != nil || idemKey != "" || ttl > 0 || storageClass != meta.StorageClassStandard || len(userMetadata) > 0 || len(tags) > 0 {
+		expiresAt := h.now().Add(ttl)
+		extraCommit = func(tx *sql.Tx, result *engine.PutResult, _ string) error {
+			// The body has been read to the end when the barrier commits, so
+			// the trailer has been verified by then.
+			if algorithm, value := chunked.Checksum(); algorithm != "" {
+				if err := h.Meta.SetVersionChecksumTx(tx, result.VersionID, algorithm, value); err != nil {
+					return err
+				}
+			}
+			if ttl > 0 {
+				if err := h.Meta.SetVersionExpiryTx(tx, result.VersionID, expiresAt); err != nil {
+					return err
+				}
+			}
+			if storageClass != meta.StorageClassStandard {
+				if err := h.Meta.SetVersionStorageClassTx(tx, result.VersionID, storageClass); err != nil {
+					return err
+				}
+			}
+			if len(userMetadata) > 0 || len(tags) > 0 {
+				if err := h.Meta.SetVersionMetadataTx(tx, result.VersionID, userMetadata, tags); err != nil {
+					return err
+				}
+			}
+			if idemKey == "" {
+				return nil
+			}
+			return h.Meta.RecordIdempotencyKeyTx(ctx, tx, bucket, key, idemKey, result.VersionID, result.ETag)
+		}
+	}
+	check := combineChecks(h.idempotencyCheck(ctx, bucket, key, idemKey), h.ifMatchCheck(ctx, r, bucket, key))
+	_, result, err := h.Engine.PutObjectWithCheck(ctx, bucket, key, contentType, reader, check, extraCommit)
 	if err != nil {
 		switch {
+		case errors.Is(err, errIdempotentReplay):
+			if h.replayIdempotentPut(ctx, w, r, bucket, key, idemKey, requestID) {
+				return
+			}
+		case errors.Is(err, engine.ErrPreconditionFailed):
+			writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "if-match failed", requestID, r.URL.Path)
+			return
 		case errors.Is(err, errPayloadHashMismatch):
 			writeErrorWithResource(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "payload hash mismatch", requestID, r.URL.Path)
 			return
@@ -994,8 +1707,11 @@ func (h *Handler) handlePut(ctx context.Context, w http.ResponseWriter, r *http.
 		case errors.Is(err, errEntityTooLarge):
 			writeErrorWithResource(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "entity too large", requestID, r.URL.Path)
 			return
+		case errors.Is(err, engine.ErrInsufficientStorage):
+			writeErrorWithResource(w, http.StatusInsufficientStorage, "InsufficientStorage", "insufficient storage", requestID, r.URL.Path)
+			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if result.ETag != "" {
@@ -1004,7 +1720,11 @@ func (h *Handler) handlePut(ctx context.Context, w http.ResponseWriter, r *http.
 	if versionID, ok := versionIDHeaderForPut(versioningState, result.VersionID); ok {
 		w.Header().Set("x-amz-version-id", versionID)
 	}
+	if algorithm, value := chunked.Checksum(); algorithm != "" {
+		w.Header().Set("x-amz-checksum-"+algorithm, value)
+	}
 	w.Header().Set("Last-Modified", formatHTTPTime(result.CommittedAt))
+	h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectCreatedPut, putResultObject(result))
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -1033,7 +1753,13 @@ func (h *Handler) handleGet(ctx context.Context, w http.ResponseWriter, r *http.
 			writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "key not found", requestID, r.URL.Path)
 			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	// An expired version stays readable in meta until the lifecycle pass
+	// deletes it, but is already gone for clients.
+	if objMeta.Expired(h.now()) {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "key not found", requestID, r.URL.Path)
 		return
 	}
 	if strings.EqualFold(objMeta.State, meta.VersionStateDeleteMarker) {
@@ -1061,15 +1787,38 @@ func (h *Handler) handleGet(ctx context.Context, w http.ResponseWriter, r *http.
 	if objMeta.ContentType != "" {
 		w.Header().Set("Content-Type", objMeta.ContentType)
 	}
+	// S3 omits the header for STANDARD objects.
+	if objMeta.StorageClass != "" && objMeta.StorageClass != meta.StorageClassStandard {
+		w.Header().Set("x-amz-storage-class", objMeta.StorageClass)
+	}
 	if objMeta.LastModified != "" {
 		if t, err := time.Parse(time.RFC3339Nano, objMeta.LastModified); err == nil {
 			w.Header().Set("Last-Modified", formatHTTPTime(t))
 		}
 	}
+	userMetadata, tags, err := h.Meta.VersionMetadata(ctx, objMeta.VersionID)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	setUserMetadataHeaders(w, userMetadata, tags)
+	if strings.EqualFold(r.Header.Get("x-amz-checksum-mode"), "ENABLED") {
+		algorithm, value, err := h.Meta.VersionChecksum(ctx, objMeta.VersionID)
+		if err != nil {
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+		if algorithm != "" {
+			w.Header().Set("x-amz-checksum-"+algorithm, value)
+		}
+	}
 	if h.checkPreconditions(w, r, objMeta, requestID, r.URL.Path) {
 		return
 	}
 	rangeHeader := r.Header.Get("Range")
+	if rangeHeader != "" && !ifRangeMatches(r.Header.Get("If-Range"), objMeta) {
+		rangeHeader = ""
+	}
 	if rangeHeader != "" {
 		ranges, ok := parseRanges(rangeHeader, objMeta.Size)
 		if !ok || len(ranges) == 0 {
@@ -1085,35 +1834,46 @@ func (h *Handler) handleGet(ctx context.Context, w http.ResponseWriter, r *http.
 				w.WriteHeader(http.StatusPartialContent)
 				return
 			}
-			reader, _, err := h.Engine.GetRange(ctx, objMeta.VersionID, start, length)
+			reader, man, start, length, err := h.Engine.GetRange(ctx, objMeta.VersionID, start, length)
 			if err != nil {
-				writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+				h.writeInternalError(w, err, requestID, r.URL.Path)
 				return
 			}
 			defer func() { _ = reader.Close() }()
 			w.Header().Set("Content-Length", intToString(length))
-			w.Header().Set("Content-Range", formatContentRange(start, length, objMeta.Size))
+			w.Header().Set("Content-Range", formatContentRange(start, length, man.Size))
 			w.WriteHeader(http.StatusPartialContent)
 			_, _ = ioCopy(w, reader)
 			return
 		}
 		boundary := "seglake-" + requestID
-		w.Header().Set("Content-Type", "multipart/byteranges; boundary="+boundary)
-		w.WriteHeader(http.StatusPartialContent)
 		if headOnly {
+			w.Header().Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+			w.WriteHeader(http.StatusPartialContent)
+			return
+		}
+		// Resolve the manifest and open segments once for all ranges.
+		set, err := h.Engine.OpenRanges(ctx, objMeta.VersionID)
+		if err != nil {
+			h.writeInternalError(w, err, requestID, r.URL.Path)
 			return
 		}
+		defer func() { _ = set.Close() }()
+		w.Header().Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+		w.WriteHeader(http.StatusPartialContent)
 		for _, br := range ranges {
-			start, length := br.start, br.length
+			reader, start, length, err := set.Range(br.start, br.length)
+			if err != nil {
+				return
+			}
 			_, _ = io.WriteString(w, "--"+boundary+"\r\n")
 			_, _ = io.WriteString(w, "Content-Type: application/octet-stream\r\n")
-			_, _ = io.WriteString(w, "Content-Range: "+formatContentRange(start, length, objMeta.Size)+"\r\n\r\n")
-			reader, _, err := h.Engine.GetRange(ctx, objMeta.VersionID, start, length)
-			if err != nil {
+			_, _ = io.WriteString(w, "Content-Range: "+formatContentRange(start, length, set.Manifest().Size)+"\r\n\r\n")
+			if _, err := ioCopy(w, reader); err != nil {
+				// Leave the body without its closing boundary so the
+				// client sees a truncated response.
 				return
 			}
-			_, _ = ioCopy(w, reader)
-			_ = reader.Close()
 			_, _ = io.WriteString(w, "\r\n")
 		}
 		_, _ = io.WriteString(w, "--"+boundary+"--\r\n")
@@ -1128,7 +1888,7 @@ func (h *Handler) handleGet(ctx context.Context, w http.ResponseWriter, r *http.
 	}
 	reader, _, err := h.Engine.Get(ctx, objMeta.VersionID)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	defer func() { _ = reader.Close() }()
@@ -1154,13 +1914,20 @@ func (h *Handler) handleCopyObject(ctx context.Context, w http.ResponseWriter, r
 		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "invalid copy source", requestID, r.URL.Path)
 		return
 	}
+	// Like S3, the copy takes the class from the request (STANDARD when
+	// absent), not from the source.
+	storageClass, ok := parseStorageClass(r)
+	if !ok {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidStorageClass", "invalid storage class", requestID, r.URL.Path)
+		return
+	}
 	if h.Meta == nil {
 		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
 		return
 	}
 	exists, err := h.Meta.BucketExists(ctx, srcBucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if !exists {
@@ -1173,7 +1940,7 @@ func (h *Handler) handleCopyObject(ctx context.Context, w http.ResponseWriter, r
 			writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "key not found", requestID, r.URL.Path)
 			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if strings.EqualFold(srcMeta.State, meta.VersionStateDeleteMarker) {
@@ -1185,9 +1952,14 @@ func (h *Handler) handleCopyObject(ctx context.Context, w http.ResponseWriter, r
 		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "object damaged", requestID, r.URL.Path)
 		return
 	}
+	// Copy-source conditions never yield 304; any failed check is a 412.
+	if evaluatePreconditions(srcMeta, copySourcePreconditions(r)) != preconditionPass {
+		writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "copy source precondition failed", requestID, r.URL.Path)
+		return
+	}
 	reader, _, err := h.Engine.Get(ctx, srcMeta.VersionID)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	defer func() { _ = reader.Close() }()
@@ -1195,12 +1967,22 @@ func (h *Handler) handleCopyObject(ctx context.Context, w http.ResponseWriter, r
 	contentType := srcMeta.ContentType
 	versioningState, err := h.bucketVersioningState(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
-	_, result, err := h.Engine.PutObject(ctx, bucket, key, contentType, reader)
+	var extraCommit func(tx *sql.Tx, result *engine.PutResult, manifestPath string) error
+	if storageClass != meta.StorageClassStandard {
+		extraCommit = func(tx *sql.Tx, result *engine.PutResult, _ string) error {
+			return h.Meta.SetVersionStorageClassTx(tx, result.VersionID, storageClass)
+		}
+	}
+	_, result, err := h.Engine.PutObjectWithCommit(ctx, bucket, key, contentType, reader, extraCommit)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		if errors.Is(err, engine.ErrInsufficientStorage) {
+			writeErrorWithResource(w, http.StatusInsufficientStorage, "InsufficientStorage", "insufficient storage", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if result == nil {
@@ -1215,6 +1997,7 @@ func (h *Handler) handleCopyObject(ctx context.Context, w http.ResponseWriter, r
 			w.Header().Set("x-amz-version-id", versionID)
 		}
 	}
+	h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectCreatedCopy, putResultObject(result))
 	resp := copyObjectResult{
 		ETag:         `"` + result.ETag + `"`,
 		LastModified: result.CommittedAt.UTC().Format(time.RFC3339),
@@ -1242,7 +2025,7 @@ func (h *Handler) enforceIfMatch(ctx context.Context, w http.ResponseWriter, r *
 			}
 			return true
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return false
 	}
 	if strings.EqualFold(metaObj.State, meta.VersionStateDeleteMarker) {
@@ -1266,6 +2049,40 @@ func (h *Handler) enforceIfMatch(ctx context.Context, w http.ResponseWriter, r *
 	return true
 }
 
+// ifMatchCheck re-evaluates If-Match inside the commit transaction, so a
+// version applied from replication after enforceIfMatch ran still fails the
+// write instead of being silently overwritten.
+func (h *Handler) ifMatchCheck(ctx context.Context, r *http.Request, bucket, key string) func(tx *sql.Tx) error {
+	ifMatch := r.Header.Get("If-Match")
+	if h == nil || h.Meta == nil || ifMatch == "" {
+		return nil
+	}
+	return func(tx *sql.Tx) error {
+		metaObj, err := h.Meta.GetObjectMetaTx(ctx, tx, bucket, key)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return engine.ErrPreconditionFailed
+			}
+			return err
+		}
+		if strings.EqualFold(metaObj.State, meta.VersionStateDeleteMarker) || !etagMatch(ifMatch, metaObj.ETag) {
+			return engine.ErrPreconditionFailed
+		}
+		return nil
+	}
+}
+
+func (h *Handler) sniffsContentType(bucket string) bool {
+	if h == nil || bucket == "" || len(h.SniffContentTypes) == 0 {
+		return false
+	}
+	if _, ok := h.SniffContentTypes["*"]; ok {
+		return true
+	}
+	_, ok := h.SniffContentTypes[bucket]
+	return ok
+}
+
 func (h *Handler) requiresIfMatch(bucket string) bool {
 	if h == nil || bucket == "" || len(h.RequireIfMatchBuckets) == 0 {
 		return false
@@ -1284,7 +2101,7 @@ func (h *Handler) handleDeleteObject(ctx context.Context, w http.ResponseWriter,
 	}
 	exists, err := h.Meta.BucketExists(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	if !exists {
@@ -1297,6 +2114,14 @@ func (h *Handler) handleDeleteObject(ctx context.Context, w http.ResponseWriter,
 		return
 	}
 	versionID := r.URL.Query().Get("versionId")
+	// Deleting a version, or any delete in an unversioned or suspended
+	// bucket (which replaces the null version), is permanent; delete markers
+	// in a versioned bucket stay allowed.
+	if versionID != "" || isNullVersioningState(versioningState) {
+		if !h.checkDeleteProtection(ctx, w, r, bucket, requestID, resource) {
+			return
+		}
+	}
 	if versionID != "" {
 		requestedNull := versionID == "null" && isNullVersioningState(versioningState)
 		if versionID == "null" && !requestedNull {
@@ -1314,12 +2139,17 @@ func (h *Handler) handleDeleteObject(ctx context.Context, w http.ResponseWriter,
 				writeErrorWithResource(w, http.StatusNotFound, "NoSuchVersion", "version not found", requestID, resource)
 				return
 			}
-			writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+			h.writeInternalError(w, err, requestID, resource)
 			return
 		}
 		if requestedNull {
 			versionID = metaVersion.VersionID
 		}
+		// A delete marker has no ETag, so If-Match never matches it.
+		if evaluatePreconditions(metaVersion, preconditionHeaders{IfMatch: r.Header.Get("If-Match")}) == preconditionFailed {
+			writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "if-match failed", requestID, resource)
+			return
+		}
 		var deleted bool
 		if requestedNull && versioningState == meta.BucketVersioningDisabled {
 			var deletedVersion string
@@ -1329,7 +2159,7 @@ func (h *Handler) handleDeleteObject(ctx context.Context, w http.ResponseWriter,
 				return derr
 			})
 			if err != nil {
-				writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+				h.writeInternalError(w, err, requestID, resource)
 				return
 			}
 			deleted = deletedVersion != ""
@@ -1340,7 +2170,7 @@ func (h *Handler) handleDeleteObject(ctx context.Context, w http.ResponseWriter,
 				return derr
 			})
 			if err != nil {
-				writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+				h.writeInternalError(w, err, requestID, resource)
 				return
 			}
 		}
@@ -1356,30 +2186,49 @@ func (h *Handler) handleDeleteObject(ctx context.Context, w http.ResponseWriter,
 		} else {
 			w.Header().Set("x-amz-version-id", versionID)
 		}
+		h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectRemovedDelete, notifyObject{VersionID: versionID})
 	} else {
+		// If-Match is checked against the current version in the commit
+		// transaction. -require-if-match-buckets only guards overwrites, so
+		// deletes without the header are still allowed there.
+		check := h.ifMatchCheck(ctx, r, bucket, key)
 		if versioningState == meta.BucketVersioningDisabled {
-			err := h.Engine.CommitMeta(ctx, func(tx *sql.Tx) error {
-				_, derr := h.Meta.DeleteObjectUnversionedTx(ctx, tx, bucket, key)
+			var deletedVersion string
+			err := h.Engine.CommitMetaWithCheck(ctx, check, func(tx *sql.Tx) error {
+				var derr error
+				deletedVersion, derr = h.Meta.DeleteObjectUnversionedTx(ctx, tx, bucket, key)
 				return derr
 			})
 			if err != nil {
-				writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+				if errors.Is(err, engine.ErrPreconditionFailed) {
+					writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "if-match failed", requestID, resource)
+					return
+				}
+				h.writeInternalError(w, err, requestID, resource)
 				return
 			}
+			if deletedVersion != "" {
+				h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectRemovedDelete, notifyObject{})
+			}
 		} else {
 			var markerVersion string
-			err := h.Engine.CommitMeta(ctx, func(tx *sql.Tx) error {
+			err := h.Engine.CommitMetaWithCheck(ctx, check, func(tx *sql.Tx) error {
 				var derr error
-				markerVersion, derr = h.Meta.DeleteObjectTx(ctx, tx, bucket, key)
+				markerVersion, derr = h.Meta.CreateDeleteMarkerTx(ctx, tx, bucket, key)
 				return derr
 			})
 			if err != nil {
-				writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+				if errors.Is(err, engine.ErrPreconditionFailed) {
+					writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "if-match failed", requestID, resource)
+					return
+				}
+				h.writeInternalError(w, err, requestID, resource)
 				return
 			}
 			if markerVersion != "" {
 				w.Header().Set("x-amz-delete-marker", "true")
 				w.Header().Set("x-amz-version-id", markerVersion)
+				h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectRemovedMarker, notifyObject{VersionID: markerVersion})
 			}
 		}
 	}
@@ -1393,7 +2242,7 @@ func (h *Handler) handleDeleteBucket(ctx context.Context, w http.ResponseWriter,
 	}
 	exists, err := h.Meta.BucketExists(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	if !exists {
@@ -1402,7 +2251,7 @@ func (h *Handler) handleDeleteBucket(ctx context.Context, w http.ResponseWriter,
 	}
 	hasObjects, err := h.Meta.BucketHasLiveObjects(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	if hasObjects {
@@ -1415,7 +2264,7 @@ func (h *Handler) handleDeleteBucket(ctx context.Context, w http.ResponseWriter,
 		}
 		return h.Meta.DeleteBucketTx(ctx, tx, bucket)
 	}); err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -1433,6 +2282,8 @@ func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request, requestI
 }
 
 func (h *Handler) applyCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	// The CORS headers depend on the Origin, so caches must key on it.
+	w.Header().Add("Vary", "Origin")
 	origin := r.Header.Get("Origin")
 	if origin == "" {
 		return
@@ -1442,6 +2293,10 @@ func (h *Handler) applyCORSHeaders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+	w.Header().Set("Access-Control-Expose-Headers", h.corsExposeHeaders())
+	if h.CORSAllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
 }
 
 func (h *Handler) applyCORSPreflightHeaders(w http.ResponseWriter, r *http.Request) {
@@ -1450,6 +2305,9 @@ func (h *Handler) applyCORSPreflightHeaders(w http.ResponseWriter, r *http.Reque
 		allowOrigin := h.corsAllowOrigin(origin)
 		if allowOrigin != "" {
 			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+			if h.CORSAllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
 		}
 	}
 	w.Header().Set("Access-Control-Allow-Methods", h.corsAllowMethods())
@@ -1457,14 +2315,21 @@ func (h *Handler) applyCORSPreflightHeaders(w http.ResponseWriter, r *http.Reque
 	w.Header().Set("Access-Control-Max-Age", intToString(int64(h.corsMaxAge())))
 }
 
+// corsAllowOrigin returns the Access-Control-Allow-Origin value for origin, or
+// "" when it is not allowed. With credentials allowed, browsers reject "*", so
+// the specific origin is echoed.
 func (h *Handler) corsAllowOrigin(origin string) string {
+	wildcard := "*"
+	if h.CORSAllowCredentials {
+		wildcard = origin
+	}
 	origins := h.CORSAllowOrigins
 	if len(origins) == 0 {
-		return "*"
+		return wildcard
 	}
 	for _, allowed := range origins {
 		if allowed == "*" {
-			return "*"
+			return wildcard
 		}
 		if strings.EqualFold(allowed, origin) {
 			return origin
@@ -1487,6 +2352,13 @@ func (h *Handler) corsAllowHeaders() string {
 	return "authorization, content-md5, content-type, x-amz-date, x-amz-content-sha256"
 }
 
+func (h *Handler) corsExposeHeaders() string {
+	if len(h.CORSExposeHeaders) > 0 {
+		return strings.Join(h.CORSExposeHeaders, ", ")
+	}
+	return "ETag, x-amz-version-id"
+}
+
 func (h *Handler) corsMaxAge() int {
 	if h.CORSMaxAge > 0 {
 		return h.CORSMaxAge
@@ -1494,6 +2366,45 @@ func (h *Handler) corsMaxAge() int {
 	return 86400
 }
 
+type createBucketConfiguration struct {
+	XMLName            xml.Name `xml:"CreateBucketConfiguration"`
+	LocationConstraint string   `xml:"LocationConstraint"`
+}
+
+// createBucketLocation returns the requested LocationConstraint; an empty body
+// or constraint means us-east-1.
+func createBucketLocation(r *http.Request) (string, error) {
+	if r == nil || r.Body == nil {
+		return "us-east-1", nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return "us-east-1", nil
+	}
+	var cfg createBucketConfiguration
+	if err := xml.Unmarshal(body, &cfg); err != nil {
+		return "", err
+	}
+	location := strings.TrimSpace(cfg.LocationConstraint)
+	switch location {
+	case "":
+		return "us-east-1", nil
+	case "EU":
+		return "eu-west-1", nil
+	}
+	return location, nil
+}
+
+func (h *Handler) region() string {
+	if h.Auth != nil && h.Auth.Region != "" {
+		return h.Auth.Region
+	}
+	return "us-east-1"
+}
+
 func (h *Handler) handleCreateBucket(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID, resource string) {
 	if h.Meta == nil {
 		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, resource)
@@ -1503,37 +2414,68 @@ func (h *Handler) handleCreateBucket(ctx context.Context, w http.ResponseWriter,
 		writeErrorWithResource(w, http.StatusBadRequest, "InvalidBucketName", "invalid bucket name", requestID, resource)
 		return
 	}
+	h.limitControlBody(w, r)
+	location, err := createBucketLocation(r)
+	if err != nil {
+		if h.writeBodyTooLarge(w, err, requestID, resource) {
+			return
+		}
+		writeErrorWithResource(w, http.StatusBadRequest, "MalformedXML", "invalid CreateBucketConfiguration", requestID, resource)
+		return
+	}
+	if location != h.region() {
+		writeErrorWithResource(w, http.StatusConflict, "IllegalLocationConstraintException", "location constraint does not match server region "+h.region(), requestID, resource)
+		return
+	}
+	state := ""
 	versioningHeader := ""
 	if r != nil {
 		versioningHeader = r.Header.Get("x-seglake-versioning")
 	}
 	if strings.TrimSpace(versioningHeader) != "" {
-		state, ok := parseCreateBucketVersioningHeader(versioningHeader)
+		var ok bool
+		state, ok = parseCreateBucketVersioningHeader(versioningHeader)
 		if !ok {
 			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid x-seglake-versioning header", requestID, resource)
 			return
 		}
-		if err := h.Engine.CommitMeta(ctx, func(tx *sql.Tx) error {
-			if h.Meta == nil {
-				return fmt.Errorf("meta store not configured")
-			}
-			return h.Meta.CreateBucketWithVersioningTx(ctx, tx, bucket, state)
-		}); err != nil {
-			writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-		return
 	}
+	caller := ""
+	if r != nil {
+		caller = extractAccessKey(r)
+	}
+	created := false
 	if err := h.Engine.CommitMeta(ctx, func(tx *sql.Tx) error {
 		if h.Meta == nil {
 			return fmt.Errorf("meta store not configured")
 		}
-		return h.Meta.CreateBucketTx(ctx, tx, bucket)
+		var err error
+		created, err = h.Meta.CreateBucketOwnedTx(ctx, tx, bucket, state, caller)
+		return err
 	}); err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
+	if !created {
+		owner, err := h.Meta.GetBucketOwner(ctx, bucket)
+		if err != nil {
+			h.writeInternalError(w, err, requestID, resource)
+			return
+		}
+		// Buckets without a recorded owner predate ownership tracking and are
+		// treated as owned by any caller allowed to reach them.
+		if owner != "" && owner != caller {
+			writeErrorWithResource(w, http.StatusConflict, "BucketAlreadyExists", "bucket name is already taken", requestID, resource)
+			return
+		}
+		// us-east-1 lets the owner re-create a bucket as a no-op; every other
+		// region reports the conflict.
+		if h.region() != "us-east-1" {
+			writeErrorWithResource(w, http.StatusConflict, "BucketAlreadyOwnedByYou", "bucket already exists and is owned by you", requestID, resource)
+			return
+		}
+	}
+	w.Header().Set("Location", "/"+bucket)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -1574,6 +2516,10 @@ func (h *Handler) parseBucketKey(r *http.Request) (bucket string, key string, ok
 	hostBucket := h.hostBucket(r)
 	if strings.Contains(path, "/") {
 		parts := strings.SplitN(path, "/", 2)
+		if hostBucket != "" && parts[0] == "" {
+			// "//key" addresses the key "/key" in the host's bucket.
+			return hostBucket, path, true
+		}
 		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
 			return "", "", false
 		}
@@ -1623,12 +2569,54 @@ func (h *Handler) opForRequest(r *http.Request) string {
 	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/meta/stats") {
 		return "meta_stats"
 	}
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/meta/usage") {
+		return "meta_usage"
+	}
 	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/meta/conflicts") {
 		return "meta_conflicts"
 	}
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/ops/damaged") {
+		return "ops_damaged"
+	}
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/ops/key-versions") {
+		return "ops_key_versions"
+	}
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/ops/metrics") {
+		return "ops_metrics"
+	}
+	if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v1/ops/metrics/reset") {
+		return "ops_metrics_reset"
+	}
+	if strings.HasPrefix(r.URL.Path, "/v1/ops/default-content-type") {
+		switch r.Method {
+		case http.MethodGet:
+			return "ops_default_content_type"
+		case http.MethodPut, http.MethodDelete:
+			return "ops_default_content_type_set"
+		}
+	}
+	if strings.HasPrefix(r.URL.Path, "/v1/ops/delete-protection") {
+		switch r.Method {
+		case http.MethodGet:
+			return "ops_delete_protection"
+		case http.MethodPut, http.MethodDelete:
+			return "ops_delete_protection_set"
+		}
+	}
+	if strings.HasPrefix(r.URL.Path, "/v1/ops/request-logging") {
+		switch r.Method {
+		case http.MethodGet:
+			return "ops_request_logging"
+		case http.MethodPut, http.MethodDelete:
+			return "ops_request_logging_set"
+		}
+	}
 	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/replication/oplog") {
 		return "repl_oplog"
 	}
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/replication/status") {
+		return "repl_status"
+	}
 	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/replication/snapshot") {
 		return "repl_snapshot"
 	}
@@ -1638,9 +2626,33 @@ func (h *Handler) opForRequest(r *http.Request) string {
 	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/replication/chunk") {
 		return "repl_chunk"
 	}
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/replication/object-meta") {
+		return "repl_object_meta"
+	}
 	if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v1/replication/oplog") {
 		return "repl_oplog_apply"
 	}
+	if notImplementedSubresource(r.URL.Query()) != "" {
+		return "not_implemented"
+	}
+	if r.URL.Query().Has("tagging") {
+		if _, _, objectLevel := h.parseBucketKey(r); objectLevel {
+			return "not_implemented"
+		}
+	}
+	if r.URL.Query().Has("acl") && (r.Method == http.MethodGet || r.Method == http.MethodPut) {
+		_, _, objectLevel := h.parseBucketKey(r)
+		switch {
+		case objectLevel && r.Method == http.MethodGet:
+			return "get_object_acl"
+		case objectLevel:
+			return "put_object_acl"
+		case r.Method == http.MethodGet:
+			return "get_bucket_acl"
+		default:
+			return "put_bucket_acl"
+		}
+	}
 	if r.Method == http.MethodGet && r.URL.Path == "/" && h.hostBucket(r) == "" && r.URL.Query().Get("list-type") == "" {
 		return "list_buckets"
 	}
@@ -1686,6 +2698,58 @@ func (h *Handler) opForRequest(r *http.Request) string {
 			}
 		}
 	}
+	if r.URL.Query().Has("lifecycle") {
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if (path != "" && !strings.Contains(path, "/")) || (path == "" && h.hostBucket(r) != "") {
+			switch r.Method {
+			case http.MethodGet:
+				return "get_bucket_lifecycle"
+			case http.MethodPut:
+				return "put_bucket_lifecycle"
+			case http.MethodDelete:
+				return "delete_bucket_lifecycle"
+			}
+		}
+	}
+	if r.URL.Query().Has("website") {
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if (path != "" && !strings.Contains(path, "/")) || (path == "" && h.hostBucket(r) != "") {
+			switch r.Method {
+			case http.MethodGet:
+				return "get_bucket_website"
+			case http.MethodPut:
+				return "put_bucket_website"
+			case http.MethodDelete:
+				return "delete_bucket_website"
+			}
+		}
+	}
+	if r.URL.Query().Has("tagging") {
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if (path != "" && !strings.Contains(path, "/")) || (path == "" && h.hostBucket(r) != "") {
+			switch r.Method {
+			case http.MethodGet:
+				return "get_bucket_tagging"
+			case http.MethodPut:
+				return "put_bucket_tagging"
+			case http.MethodDelete:
+				return "delete_bucket_tagging"
+			}
+		}
+	}
+	if r.URL.Query().Has("notification") {
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if (path != "" && !strings.Contains(path, "/")) || (path == "" && h.hostBucket(r) != "") {
+			switch r.Method {
+			case http.MethodGet:
+				return "get_bucket_notification"
+			case http.MethodPut:
+				return "put_bucket_notification"
+			case http.MethodDelete:
+				return "delete_bucket_notification"
+			}
+		}
+	}
 	if r.Method == http.MethodGet && r.URL.Query().Has("versions") {
 		path := strings.TrimPrefix(r.URL.Path, "/")
 		if path != "" && !strings.Contains(path, "/") {
@@ -1716,6 +2780,9 @@ func (h *Handler) opForRequest(r *http.Request) string {
 	if r.Method == http.MethodDelete && r.URL.Query().Get("uploadId") != "" {
 		return "mpu_abort"
 	}
+	if r.Method == http.MethodPut && r.Header.Get(moveSourceHeader) != "" {
+		return "move"
+	}
 	if r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "" {
 		return "copy"
 	}
@@ -1748,8 +2815,13 @@ func (h *Handler) opForRequest(r *http.Request) string {
 
 func isWriteOp(op string) bool {
 	switch op {
-	case "put", "delete", "delete_bucket", "copy",
+	case "put", "delete", "delete_bucket", "copy", "move",
 		"put_bucket_policy", "delete_bucket_policy", "put_bucket_versioning",
+		"put_bucket_lifecycle", "delete_bucket_lifecycle",
+		"put_bucket_website", "delete_bucket_website",
+		"put_bucket_tagging", "delete_bucket_tagging",
+		"put_bucket_notification", "delete_bucket_notification",
+		"ops_request_logging_set", "ops_default_content_type_set", "ops_delete_protection_set",
 		"mpu_initiate", "mpu_upload_part", "mpu_complete", "mpu_abort",
 		"repl_oplog_apply":
 		return true
@@ -1758,7 +2830,29 @@ func isWriteOp(op string) bool {
 	}
 }
 
-// RunMaintenanceLoop transitions maintenance states based on inflight writes.
+// isReadOp reports whether op is a read bounded by RequestTimeout. The list
+// is explicit so ops actions and "other" requests stay unbounded, and the
+// replication snapshot and chunk downloads are left out: they stream large
+// bodies that legitimately outlast a read deadline.
+func isReadOp(op string) bool {
+	switch op {
+	case "get", "head", "head_bucket", "get_object_acl",
+		"list_buckets", "list_v1", "list_v2", "list_versions", "mpu_list_uploads", "mpu_list_parts",
+		"get_bucket_acl", "get_bucket_policy", "get_bucket_versioning", "get_bucket_lifecycle",
+		"get_bucket_website", "get_bucket_tagging", "get_bucket_notification",
+		"meta_stats", "meta_usage", "meta_conflicts",
+		"ops_damaged", "ops_key_versions", "ops_metrics", "ops_request_logging",
+		"ops_default_content_type", "ops_delete_protection",
+		"repl_oplog", "repl_manifest", "repl_object_meta", "repl_status":
+		return true
+	default:
+		return false
+	}
+}
+
+// RunMaintenanceLoop transitions maintenance states based on inflight writes,
+// runs background compaction when enabled, applies bucket lifecycle rules and
+// expires idempotency keys.
 func (h *Handler) RunMaintenanceLoop(ctx context.Context, interval time.Duration) {
 	if h == nil || h.Meta == nil {
 		return
@@ -1768,6 +2862,11 @@ func (h *Handler) RunMaintenanceLoop(ctx context.Context, interval time.Duration
 	}
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	compactor := newCompactor(h)
+	defer compactor.wait()
+	lifecycle := newLifecycleRunner(h)
+	defer lifecycle.wait()
+	var lastIdempotencyExpiry time.Time
 	for {
 		select {
 		case <-ctx.Done():
@@ -1777,6 +2876,14 @@ func (h *Handler) RunMaintenanceLoop(ctx context.Context, interval time.Duration
 			if err != nil {
 				continue
 			}
+			if state.State == "off" {
+				compactor.maybeStart(ctx)
+				lifecycle.maybeStart(ctx)
+				h.expireIdempotencyKeys(ctx, &lastIdempotencyExpiry)
+			} else {
+				compactor.stop()
+				lifecycle.stop()
+			}
 			if state.State == "entering" {
 				if atomic.LoadInt64(&h.writeInflight) == 0 {
 					if next, err := h.Meta.SetMaintenanceState(ctx, "quiesced"); err == nil {
@@ -1859,53 +2966,121 @@ func parseCopySource(raw string) (bucket, key string, ok bool) {
 	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
 		return "", "", false
 	}
+	if ValidateObjectKey(parts[1]) != nil {
+		return "", "", false
+	}
 	return parts[0], parts[1], true
 }
 
-func (h *Handler) checkPreconditions(w http.ResponseWriter, r *http.Request, meta *meta.ObjectMeta, requestID, resource string) bool {
-	if meta == nil {
-		return false
+type preconditionHeaders struct {
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   string
+	IfUnmodifiedSince string
+}
+
+type preconditionResult int
+
+const (
+	preconditionPass preconditionResult = iota
+	preconditionFailed
+	preconditionNotModified
+)
+
+func requestPreconditions(r *http.Request) preconditionHeaders {
+	return preconditionHeaders{
+		IfMatch:           r.Header.Get("If-Match"),
+		IfNoneMatch:       r.Header.Get("If-None-Match"),
+		IfModifiedSince:   r.Header.Get("If-Modified-Since"),
+		IfUnmodifiedSince: r.Header.Get("If-Unmodified-Since"),
+	}
+}
+
+func copySourcePreconditions(r *http.Request) preconditionHeaders {
+	return preconditionHeaders{
+		IfMatch:           r.Header.Get("x-amz-copy-source-if-match"),
+		IfNoneMatch:       r.Header.Get("x-amz-copy-source-if-none-match"),
+		IfModifiedSince:   r.Header.Get("x-amz-copy-source-if-modified-since"),
+		IfUnmodifiedSince: r.Header.Get("x-amz-copy-source-if-unmodified-since"),
+	}
+}
+
+// evaluatePreconditions checks conditional headers against an arbitrary object.
+// A matching If-Match skips If-Unmodified-Since, and If-None-Match skips
+// If-Modified-Since (RFC 7232 / S3 semantics).
+func evaluatePreconditions(objMeta *meta.ObjectMeta, cond preconditionHeaders) preconditionResult {
+	if objMeta == nil {
+		return preconditionPass
 	}
 	var lastModified time.Time
-	if meta.LastModified != "" {
-		if t, err := time.Parse(time.RFC3339Nano, meta.LastModified); err == nil {
+	if objMeta.LastModified != "" {
+		if t, err := time.Parse(time.RFC3339Nano, objMeta.LastModified); err == nil {
 			lastModified = t
 		}
 	}
-	ifMatch := r.Header.Get("If-Match")
-	if ifMatch != "" {
-		if !etagMatch(ifMatch, meta.ETag) {
-			writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "etag mismatch", requestID, resource)
-			return true
+	if cond.IfMatch != "" {
+		if !etagMatch(cond.IfMatch, objMeta.ETag) {
+			return preconditionFailed
 		}
-	}
-	ifUnmodified := r.Header.Get("If-Unmodified-Since")
-	if ifUnmodified != "" && !lastModified.IsZero() {
-		if since, err := parseHTTPTime(ifUnmodified); err == nil {
+	} else if cond.IfUnmodifiedSince != "" && !lastModified.IsZero() {
+		if since, err := parseHTTPTime(cond.IfUnmodifiedSince); err == nil {
 			if lastModified.After(since) {
-				writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "precondition failed", requestID, resource)
-				return true
+				return preconditionFailed
 			}
 		}
 	}
-	ifNone := r.Header.Get("If-None-Match")
-	if ifNone != "" {
-		if etagMatch(ifNone, meta.ETag) {
-			w.WriteHeader(http.StatusNotModified)
-			return true
+	if cond.IfNoneMatch != "" {
+		if etagMatch(cond.IfNoneMatch, objMeta.ETag) {
+			return preconditionNotModified
 		}
-		return false
+		return preconditionPass
 	}
-	ifModified := r.Header.Get("If-Modified-Since")
-	if ifModified != "" && !lastModified.IsZero() {
-		if since, err := parseHTTPTime(ifModified); err == nil {
+	if cond.IfModifiedSince != "" && !lastModified.IsZero() {
+		if since, err := parseHTTPTime(cond.IfModifiedSince); err == nil {
 			if !lastModified.After(since) {
-				w.WriteHeader(http.StatusNotModified)
-				return true
+				return preconditionNotModified
 			}
 		}
 	}
-	return false
+	return preconditionPass
+}
+
+func (h *Handler) checkPreconditions(w http.ResponseWriter, r *http.Request, objMeta *meta.ObjectMeta, requestID, resource string) bool {
+	switch evaluatePreconditions(objMeta, requestPreconditions(r)) {
+	case preconditionFailed:
+		writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "precondition failed", requestID, resource)
+		return true
+	case preconditionNotModified:
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	default:
+		return false
+	}
+}
+
+// ifRangeMatches reports whether a Range should be honored given If-Range.
+// An empty header always matches; an entity tag needs a strong match and a
+// date must equal Last-Modified exactly, otherwise the full object is served.
+func ifRangeMatches(header string, objMeta *meta.ObjectMeta) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return true
+	}
+	if strings.HasPrefix(header, "W/") {
+		return false
+	}
+	if strings.HasPrefix(header, "\"") {
+		return objMeta.ETag != "" && strings.EqualFold(strings.Trim(header, "\""), strings.Trim(objMeta.ETag, "\""))
+	}
+	since, err := parseHTTPTime(header)
+	if err != nil || objMeta.LastModified == "" {
+		return false
+	}
+	lastModified, err := time.Parse(time.RFC3339Nano, objMeta.LastModified)
+	if err != nil {
+		return false
+	}
+	return lastModified.Truncate(time.Second).Equal(since)
 }
 
 func etagMatch(header, etag string) bool {
@@ -46,7 +46,7 @@ func newReplayTestHandler(t *testing.T, replayTTL time.Duration, replayBlock boo
 func TestReplaySoftDoesNotBlock(t *testing.T) {
 	handler := newReplayTestHandler(t, time.Minute, false)
 	rawURL := "http://localhost:9000/bucket/key"
-	signed, err := handler.Auth.Presign(http.MethodGet, rawURL, time.Minute)
+	signed, err := handler.Auth.Presign(http.MethodGet, rawURL, "", time.Minute)
 	if err != nil {
 		t.Fatalf("Presign: %v", err)
 	}
@@ -73,7 +73,7 @@ func TestReplaySoftDoesNotBlock(t *testing.T) {
 func TestReplayHardBlocks(t *testing.T) {
 	handler := newReplayTestHandler(t, time.Minute, true)
 	rawURL := "http://localhost:9000/bucket/key"
-	signed, err := handler.Auth.Presign(http.MethodGet, rawURL, time.Minute)
+	signed, err := handler.Auth.Presign(http.MethodGet, rawURL, "", time.Minute)
 	if err != nil {
 		t.Fatalf("Presign: %v", err)
 	}
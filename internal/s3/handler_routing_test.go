@@ -1,8 +1,11 @@
 package s3
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -129,3 +132,127 @@ func TestIsListV1Request(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireOpsClientCert(t *testing.T) {
+	handler := newTestHandler(t)
+	handler.RequireOpsClientCert = true
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	cases := []struct {
+		name   string
+		target string
+		tls    *tls.ConnectionState
+		want   int
+	}{
+		{name: "replication-no-cert", target: "/v1/replication/status", want: http.StatusForbidden},
+		{name: "ops-unverified-cert", target: "/v1/ops/damaged", tls: &tls.ConnectionState{}, want: http.StatusForbidden},
+		{name: "replication-verified", target: "/v1/replication/status", tls: verified, want: http.StatusOK},
+		{name: "ops-verified", target: "/v1/ops/damaged", tls: verified, want: http.StatusOK},
+		{name: "meta-no-cert", target: "/v1/meta/stats", want: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			req.TLS = tc.tls
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNotImplementedSubresources(t *testing.T) {
+	handler := newTestHandler(t)
+	putObject(t, handler, "demo", "key", "data")
+	cases := []struct {
+		method string
+		target string
+		want   int
+	}{
+		{method: http.MethodGet, target: "/demo?encryption", want: http.StatusNotImplemented},
+		{method: http.MethodGet, target: "/demo?cors", want: http.StatusNotImplemented},
+		{method: http.MethodPut, target: "/demo/key?tagging", want: http.StatusNotImplemented},
+		{method: http.MethodDelete, target: "/demo/key?tagging", want: http.StatusNotImplemented},
+		{method: http.MethodGet, target: "/demo?location", want: http.StatusOK},
+		{method: http.MethodGet, target: "/demo/key", want: http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.target, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s %s: status %d, want %d: %s", tc.method, tc.target, rec.Code, tc.want, rec.Body.String())
+		}
+		if tc.want == http.StatusNotImplemented && !strings.Contains(rec.Body.String(), "<Code>NotImplemented</Code>") {
+			t.Fatalf("%s %s: unexpected body %s", tc.method, tc.target, rec.Body.String())
+		}
+	}
+}
+
+func TestRejectUnknownSubresources(t *testing.T) {
+	handler := newTestHandler(t)
+	putObject(t, handler, "demo", "key", "data")
+	cases := []struct {
+		method string
+		target string
+		strict int
+		lax    int
+	}{
+		{method: http.MethodGet, target: "/demo/key?acll", strict: http.StatusBadRequest, lax: http.StatusOK},
+		{method: http.MethodGet, target: "/demo?versionz=", strict: http.StatusBadRequest, lax: http.StatusOK},
+		{method: http.MethodPut, target: "/demo/other?taggin", strict: http.StatusBadRequest, lax: http.StatusOK},
+		{method: http.MethodGet, target: "/demo?list-type=2&prefix=&delimiter=/", strict: http.StatusOK, lax: http.StatusOK},
+		{method: http.MethodGet, target: "/demo/key?response-content-type=text/plain", strict: http.StatusOK, lax: http.StatusOK},
+		{method: http.MethodGet, target: "/demo?acl", strict: http.StatusOK, lax: http.StatusOK},
+		{method: http.MethodGet, target: "/demo?cors", strict: http.StatusNotImplemented, lax: http.StatusNotImplemented},
+	}
+	for _, strict := range []bool{false, true} {
+		handler.RejectUnknownSubresources = strict
+		for _, tc := range cases {
+			want := tc.lax
+			if strict {
+				want = tc.strict
+			}
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader("x"))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != want {
+				t.Fatalf("strict=%v %s %s: status %d, want %d: %s", strict, tc.method, tc.target, rec.Code, want, rec.Body.String())
+			}
+			if want == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "<Code>InvalidArgument</Code>") {
+				t.Fatalf("%s %s: unexpected body %s", tc.method, tc.target, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestReadOnlyRejectsMutations(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "demo", "key", "hello")
+	h.ReadOnly = true
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{method: http.MethodGet, path: "/demo/key", want: http.StatusOK},
+		{method: http.MethodHead, path: "/demo/key", want: http.StatusOK},
+		{method: http.MethodGet, path: "/demo?list-type=2", want: http.StatusOK},
+		{method: http.MethodGet, path: readyzPath, want: http.StatusOK},
+		{method: http.MethodPut, path: "/demo/other", want: http.StatusMethodNotAllowed},
+		{method: http.MethodDelete, path: "/demo/key", want: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, path: "/demo/key?uploads", want: http.StatusMethodNotAllowed},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader("x"))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s %s: expected %d, got %d: %s", tc.method, tc.path, tc.want, w.Code, w.Body.String())
+		}
+		if tc.want == http.StatusMethodNotAllowed && !strings.Contains(w.Body.String(), "server is read-only") {
+			t.Fatalf("%s %s: unexpected body %s", tc.method, tc.path, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+)
+
+const (
+	idempotencyKeyHeader   = "x-seglake-idempotency-key"
+	maxIdempotencyKeyLen   = 256
+	defaultIdempotencyTTL  = time.Hour
+	idempotencyExpiryEvery = time.Minute
+)
+
+// errIdempotentReplay rejects a PUT whose idempotency key was recorded by a
+// concurrent retry between the lookup and the commit.
+var errIdempotentReplay = fmt.Errorf("%w: idempotency key already used", engine.ErrPreconditionFailed)
+
+func (h *Handler) idempotencyTTL() time.Duration {
+	if h == nil || h.IdempotencyTTL <= 0 {
+		return defaultIdempotencyTTL
+	}
+	return h.IdempotencyTTL
+}
+
+// replayIdempotentPut answers a retried PUT with the result recorded for its
+// idempotency key. It returns false when there is nothing to replay.
+func (h *Handler) replayIdempotentPut(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, idemKey, requestID string) bool {
+	rec, err := h.Meta.LookupIdempotencyKey(ctx, bucket, key, idemKey, h.idempotencyTTL())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return true
+	}
+	versioningState, err := h.bucketVersioningState(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return true
+	}
+	if rec.ETag != "" {
+		w.Header().Set("ETag", `"`+rec.ETag+`"`)
+	}
+	if versionID, ok := versionIDHeaderForPut(versioningState, rec.VersionID); ok {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	w.Header().Set("Last-Modified", formatHTTPTime(rec.CreatedAt))
+	w.WriteHeader(http.StatusOK)
+	return true
+}
+
+// idempotencyCheck rejects the write in the commit transaction when a
+// concurrent retry already recorded the key.
+func (h *Handler) idempotencyCheck(ctx context.Context, bucket, key, idemKey string) func(tx *sql.Tx) error {
+	if idemKey == "" {
+		return nil
+	}
+	ttl := h.idempotencyTTL()
+	return func(tx *sql.Tx) error {
+		_, err := h.Meta.LookupIdempotencyKeyTx(ctx, tx, bucket, key, idemKey, ttl)
+		if err == nil {
+			return errIdempotentReplay
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+}
+
+// combineChecks runs commit checks in order and stops at the first error.
+func combineChecks(checks ...func(tx *sql.Tx) error) func(tx *sql.Tx) error {
+	var active []func(tx *sql.Tx) error
+	for _, check := range checks {
+		if check != nil {
+			active = append(active, check)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	return func(tx *sql.Tx) error {
+		for _, check := range active {
+			if err := check(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// expireIdempotencyKeys drops mappings older than the TTL from the
+// maintenance loop, at most once per idempotencyExpiryEvery.
+func (h *Handler) expireIdempotencyKeys(ctx context.Context, lastRun *time.Time) {
+	now := h.now()
+	if !lastRun.IsZero() && now.Sub(*lastRun) < idempotencyExpiryEvery {
+		return
+	}
+	*lastRun = now
+	removed, err := h.Meta.ExpireIdempotencyKeys(ctx, now.Add(-h.idempotencyTTL()))
+	if err != nil {
+		log.Printf("idempotency_expire_failed err=%v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("idempotency_expire removed=%d", removed)
+	}
+}
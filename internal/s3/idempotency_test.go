@@ -0,0 +1,83 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func idempotentPut(t *testing.T, h *Handler, path, body, idemKey string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	req.Header.Set(idempotencyKeyHeader, idemKey)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT %s status: %d %s", path, w.Code, w.Body.String())
+	}
+	return w
+}
+
+func TestPutIdempotencyKeyReplaysOriginalResult(t *testing.T) {
+	h := newTestHandler(t)
+	first := idempotentPut(t, h, "/bucket/key", "first", "retry-1")
+	etag, versionID := first.Header().Get("ETag"), first.Header().Get("x-amz-version-id")
+	if etag == "" || versionID == "" {
+		t.Fatalf("expected ETag and version id, got %q %q", etag, versionID)
+	}
+
+	retry := idempotentPut(t, h, "/bucket/key", "first", "retry-1")
+	if retry.Header().Get("ETag") != etag || retry.Header().Get("x-amz-version-id") != versionID {
+		t.Fatalf("retry returned %q %q, want %q %q", retry.Header().Get("ETag"), retry.Header().Get("x-amz-version-id"), etag, versionID)
+	}
+	current, err := h.Meta.GetObjectMeta(context.Background(), "bucket", "key")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if current.VersionID != versionID {
+		t.Fatalf("retry wrote a new version %s", current.VersionID)
+	}
+
+	other := idempotentPut(t, h, "/bucket/key", "second", "retry-2")
+	if other.Header().Get("x-amz-version-id") == versionID {
+		t.Fatalf("new idempotency key must write a new version")
+	}
+	otherKey := idempotentPut(t, h, "/bucket/other", "first", "retry-1")
+	if otherKey.Header().Get("x-amz-version-id") == versionID {
+		t.Fatalf("idempotency keys must be scoped to bucket/key")
+	}
+}
+
+func TestPutIdempotencyKeyConcurrentRetry(t *testing.T) {
+	h := newTestHandler(t)
+	var inner *httptest.ResponseRecorder
+	// The retry commits while the first attempt is still streaming its body;
+	// the first attempt then finds the key in its commit transaction.
+	body := &eofHookReader{r: strings.NewReader("payload"), hook: func() {
+		inner = idempotentPut(t, h, "/bucket/key", "payload", "retry-1")
+	}}
+	req := httptest.NewRequest(http.MethodPut, "/bucket/key", body)
+	req.ContentLength = int64(len("payload"))
+	req.Header.Set(idempotencyKeyHeader, "retry-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d %s", w.Code, w.Body.String())
+	}
+	if inner == nil {
+		t.Fatalf("retry did not run")
+	}
+	if got, want := w.Header().Get("x-amz-version-id"), inner.Header().Get("x-amz-version-id"); got != want {
+		t.Fatalf("version id %q, want replayed %q", got, want)
+	}
+	versions, err := h.Meta.ListObjectVersions(context.Background(), "bucket", "key", "", "", 10, time.Time{})
+	if err != nil {
+		t.Fatalf("ListObjectVersions: %v", err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("expected one version, got %d", len(versions))
+	}
+}
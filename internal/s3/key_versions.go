@@ -0,0 +1,81 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type keyVersionsItem struct {
+	Key           string `json:"key"`
+	VersionCount  int64  `json:"version_count"`
+	TotalBytes    int64  `json:"total_bytes"`
+	LatestVersion string `json:"latest_version,omitempty"`
+}
+
+type keyVersionsResponse struct {
+	Bucket  string            `json:"bucket"`
+	Prefix  string            `json:"prefix,omitempty"`
+	Items   []keyVersionsItem `json:"items"`
+	NextKey string            `json:"next_key,omitempty"`
+}
+
+// handleKeyVersions reports version count, stored bytes and current version
+// per key of ?bucket= (optionally under ?prefix=), one page of keys at a time.
+func (h *Handler) handleKeyVersions(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	query := r.URL.Query()
+	bucket := strings.TrimSpace(query.Get("bucket"))
+	if bucket == "" {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "bucket required", requestID, r.URL.Path)
+		return
+	}
+	prefix := query.Get("prefix")
+	afterKey := query.Get("after_key")
+	limit := 1000
+	if rawLimit := strings.TrimSpace(query.Get("limit")); rawLimit != "" {
+		v, err := parseInt(rawLimit)
+		if err != nil || v <= 0 || v > 10000 {
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid limit", requestID, r.URL.Path)
+			return
+		}
+		limit = int(v)
+	}
+	exists, err := h.Meta.BucketExists(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if !exists {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+		return
+	}
+	stats, err := h.Meta.ListKeyVersionStats(ctx, bucket, prefix, afterKey, limit)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	resp := keyVersionsResponse{
+		Bucket: bucket,
+		Prefix: prefix,
+		Items:  make([]keyVersionsItem, 0, len(stats)),
+	}
+	for _, item := range stats {
+		resp.Items = append(resp.Items, keyVersionsItem{
+			Key:           item.Key,
+			VersionCount:  item.VersionCount,
+			TotalBytes:    item.TotalBytes,
+			LatestVersion: item.LatestVersion,
+		})
+	}
+	if len(stats) == limit {
+		resp.NextKey = stats[len(stats)-1].Key
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,62 @@
+package s3
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKeyVersionsEndpoint(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "bucket", "seed", "x")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/bucket?versioning", strings.NewReader("<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("versioning status: %d %s", w.Code, w.Body.String())
+	}
+	putObject(t, h, "bucket", "hot", "1")
+	putObject(t, h, "bucket", "hot", "22")
+	putObject(t, h, "bucket", "hot", "333")
+	putObject(t, h, "bucket", "cold", "4444")
+
+	get := func(target string) (int, keyVersionsResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp keyVersionsResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	code, resp := get("/v1/ops/key-versions?bucket=bucket&limit=2")
+	if code != http.StatusOK || len(resp.Items) != 2 || resp.NextKey != "hot" {
+		t.Fatalf("page 1: %d %+v", code, resp)
+	}
+	if cold := resp.Items[0]; cold.Key != "cold" || cold.VersionCount != 1 || cold.TotalBytes != 4 || cold.LatestVersion == "" {
+		t.Fatalf("unexpected cold stats %+v", cold)
+	}
+	if hot := resp.Items[1]; hot.Key != "hot" || hot.VersionCount != 3 || hot.TotalBytes != 6 {
+		t.Fatalf("unexpected hot stats %+v", hot)
+	}
+	if code, resp = get("/v1/ops/key-versions?bucket=bucket&limit=2&after_key=hot"); code != http.StatusOK || len(resp.Items) != 1 || resp.Items[0].Key != "seed" || resp.NextKey != "" {
+		t.Fatalf("page 2: %d %+v", code, resp)
+	}
+	if code, resp = get("/v1/ops/key-versions?bucket=bucket&prefix=h"); code != http.StatusOK || len(resp.Items) != 1 || resp.Items[0].Key != "hot" {
+		t.Fatalf("prefix: %d %+v", code, resp)
+	}
+	for target, want := range map[string]int{
+		"/v1/ops/key-versions":                       http.StatusBadRequest,
+		"/v1/ops/key-versions?bucket=bucket&limit=0": http.StatusBadRequest,
+		"/v1/ops/key-versions?bucket=missing":        http.StatusNotFound,
+	} {
+		if code, _ := get(target); code != want {
+			t.Fatalf("%s: expected %d, got %d", target, want, code)
+		}
+	}
+}
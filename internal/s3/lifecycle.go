@@ -0,0 +1,253 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/ops"
+)
+
+const (
+	defaultLifecycleInterval = time.Hour
+	lifecycleAbortRuleID     = "abort-incomplete-multipart-upload"
+)
+
+type lifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Xmlns   string          `xml:"xmlns,attr,omitempty"`
+	Rules   []lifecycleRule `xml:"Rule"`
+}
+
+type lifecycleRule struct {
+	ID                             string                   `xml:"ID,omitempty"`
+	Status                         string                   `xml:"Status"`
+	Prefix                         *string                  `xml:"Prefix"`
+	Filter                         *lifecycleFilter         `xml:"Filter"`
+	AbortIncompleteMultipartUpload *lifecycleAbortMultipart `xml:"AbortIncompleteMultipartUpload"`
+	Expiration                     *struct{}                `xml:"Expiration"`
+	Transition                     *struct{}                `xml:"Transition"`
+	NoncurrentVersionExpiration    *struct{}                `xml:"NoncurrentVersionExpiration"`
+	NoncurrentVersionTransition    *struct{}                `xml:"NoncurrentVersionTransition"`
+}
+
+type lifecycleFilter struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type lifecycleAbortMultipart struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+// parseLifecycleConfiguration returns the abort-incomplete-MPU age in days
+// from enabled rules (the smallest wins; 0 = no enabled rule).
+func parseLifecycleConfiguration(cfg lifecycleConfiguration) (int, error) {
+	if len(cfg.Rules) == 0 {
+		return 0, errors.New("at least one rule required")
+	}
+	days := 0
+	for _, rule := range cfg.Rules {
+		if rule.Expiration != nil || rule.Transition != nil || rule.NoncurrentVersionExpiration != nil || rule.NoncurrentVersionTransition != nil {
+			return 0, errors.New("only AbortIncompleteMultipartUpload is supported")
+		}
+		if rule.AbortIncompleteMultipartUpload == nil {
+			return 0, errors.New("rule requires AbortIncompleteMultipartUpload")
+		}
+		if (rule.Prefix != nil && *rule.Prefix != "") || (rule.Filter != nil && rule.Filter.Prefix != "") {
+			return 0, errors.New("prefix filters are not supported")
+		}
+		ruleDays := rule.AbortIncompleteMultipartUpload.DaysAfterInitiation
+		if ruleDays <= 0 {
+			return 0, errors.New("DaysAfterInitiation must be > 0")
+		}
+		switch strings.TrimSpace(rule.Status) {
+		case "Enabled":
+			if days == 0 || ruleDays < days {
+				days = ruleDays
+			}
+		case "Disabled":
+		default:
+			return 0, errors.New("invalid rule status")
+		}
+	}
+	return days, nil
+}
+
+func (h *Handler) handleGetBucketLifecycle(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	exists, err := h.Meta.BucketExists(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if !exists {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+		return
+	}
+	rule, err := h.Meta.GetBucketLifecycle(ctx, bucket)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchLifecycleConfiguration", "", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	resp := lifecycleConfiguration{
+		Xmlns: versioningXMLNamespace,
+		Rules: []lifecycleRule{{
+			ID:                             lifecycleAbortRuleID,
+			Status:                         "Enabled",
+			Filter:                         &lifecycleFilter{},
+			AbortIncompleteMultipartUpload: &lifecycleAbortMultipart{DaysAfterInitiation: rule.AbortMPUDays},
+		}},
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) handlePutBucketLifecycle(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	var req lifecycleConfiguration
+	h.limitControlBody(w, r)
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		if h.writeBodyTooLarge(w, err, requestID, r.URL.Path) {
+			return
+		}
+		writeErrorWithResource(w, http.StatusBadRequest, "MalformedXML", "invalid xml", requestID, r.URL.Path)
+		return
+	}
+	days, err := parseLifecycleConfiguration(req)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", err.Error(), requestID, r.URL.Path)
+		return
+	}
+	if days == 0 {
+		exists, err := h.Meta.BucketExists(ctx, bucket)
+		if err != nil {
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+		if !exists {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+			return
+		}
+		err = h.Meta.DeleteBucketLifecycle(ctx, bucket)
+	} else {
+		err = h.Meta.SetBucketLifecycle(ctx, bucket, days)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) handleDeleteBucketLifecycle(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	exists, err := h.Meta.BucketExists(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if !exists {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+		return
+	}
+	if err := h.Meta.DeleteBucketLifecycle(ctx, bucket); err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lifecycleRunner applies bucket lifecycle rules and x-seglake-ttl expiry
+// from the maintenance loop.
+type lifecycleRunner struct {
+	h       *Handler
+	lastRun time.Time
+	running int32
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func newLifecycleRunner(h *Handler) *lifecycleRunner {
+	return &lifecycleRunner{h: h}
+}
+
+// maybeStart launches a lifecycle pass when one is due.
+func (l *lifecycleRunner) maybeStart(ctx context.Context) {
+	h := l.h
+	if h.Meta == nil || atomic.LoadInt32(&l.running) != 0 {
+		return
+	}
+	interval := h.LifecycleInterval
+	if interval <= 0 {
+		interval = defaultLifecycleInterval
+	}
+	now := h.now()
+	if !l.lastRun.IsZero() && now.Sub(l.lastRun) < interval {
+		return
+	}
+	l.lastRun = now
+	runCtx, cancel := context.WithCancel(ctx)
+	l.cancel = cancel
+	atomic.StoreInt32(&l.running, 1)
+	// Aborting uploads writes metadata, so it delays maintenance quiesce.
+	atomic.AddInt64(&h.writeInflight, 1)
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		defer atomic.StoreInt32(&l.running, 0)
+		defer atomic.AddInt64(&h.writeInflight, -1)
+		defer cancel()
+		report, err := ops.MPULifecycleAbort(runCtx, h.Meta)
+		if err != nil {
+			log.Printf("lifecycle_failed err=%v", err)
+			return
+		}
+		if report != nil && report.Candidates > 0 {
+			log.Printf("lifecycle_mpu_abort candidates=%d deleted=%d reclaimed_bytes=%d errors=%d", report.Candidates, report.Deleted, report.Reclaimed, report.Errors)
+		}
+		report, err = ops.ExpireVersions(runCtx, h.Meta, h.now())
+		if err != nil {
+			log.Printf("lifecycle_ttl_failed err=%v", err)
+			return
+		}
+		if report != nil && report.Candidates > 0 {
+			log.Printf("lifecycle_ttl_expire candidates=%d deleted=%d errors=%d", report.Candidates, report.Deleted, report.Errors)
+		}
+	}()
+}
+
+func (l *lifecycleRunner) stop() {
+	if l.cancel != nil {
+		l.cancel()
+	}
+}
+
+func (l *lifecycleRunner) wait() {
+	l.stop()
+	l.wg.Wait()
+}
@@ -0,0 +1,154 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/clock"
+	"github.com/kk-code-lab/seglake/internal/ops"
+)
+
+func TestBucketLifecycleAbortIncompleteMultipartUpload(t *testing.T) {
+	h := newTestHandler(t)
+	if err := h.Meta.CreateBucket(context.Background(), "bucket"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/bucket?lifecycle", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, ""); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "NoSuchLifecycleConfiguration") {
+		t.Fatalf("expected NoSuchLifecycleConfiguration, got %d %s", w.Code, w.Body.String())
+	}
+
+	cases := []struct {
+		name string
+		body string
+		code int
+	}{
+		{
+			name: "expiration unsupported",
+			body: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "prefix filter unsupported",
+			body: `<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter><Prefix>logs/</Prefix></Filter><AbortIncompleteMultipartUpload><DaysAfterInitiation>3</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "zero days",
+			body: `<LifecycleConfiguration><Rule><Status>Enabled</Status><AbortIncompleteMultipartUpload><DaysAfterInitiation>0</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "abort rule",
+			body: `<LifecycleConfiguration><Rule><ID>mpu</ID><Status>Enabled</Status><Filter><Prefix></Prefix></Filter><AbortIncompleteMultipartUpload><DaysAfterInitiation>7</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`,
+			code: http.StatusOK,
+		},
+	}
+	for _, tc := range cases {
+		if w := do(http.MethodPut, tc.body); w.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.code, w.Code, w.Body.String())
+		}
+	}
+
+	w := do(http.MethodGet, "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<DaysAfterInitiation>7</DaysAfterInitiation>") {
+		t.Fatalf("unexpected lifecycle: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodDelete, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete lifecycle: %d", w.Code)
+	}
+	if w := do(http.MethodGet, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected lifecycle removed, got %d", w.Code)
+	}
+}
+
+func TestObjectTTLExpiresVersion(t *testing.T) {
+	h := newTestHandler(t)
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	h.Clock = clock.FixedClock{T: start}
+	putKey := func(key, ttl string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/bucket/"+key, strings.NewReader("share"))
+		req.Header.Set(ttlHeader, ttl)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	put := func(ttl string) *httptest.ResponseRecorder {
+		return putKey("temp", ttl)
+	}
+	get := func() int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bucket/temp", nil))
+		return w.Code
+	}
+	for _, ttl := range []string{"0", "-5", "soon"} {
+		if w := put(ttl); w.Code != http.StatusBadRequest {
+			t.Fatalf("ttl %q: expected 400, got %d", ttl, w.Code)
+		}
+	}
+	if w := put("60"); w.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", w.Code, w.Body.String())
+	}
+	if w := putKey("dir/temp", "60"); w.Code != http.StatusOK {
+		t.Fatalf("PUT dir/temp: %d %s", w.Code, w.Body.String())
+	}
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("GET before expiry: %d", code)
+	}
+
+	list := func(target string) string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d", target, w.Code)
+		}
+		return w.Body.String()
+	}
+	for _, target := range []string{"/bucket?list-type=2", "/bucket?versions"} {
+		if !strings.Contains(list(target), "<Key>temp</Key>") {
+			t.Fatalf("%s: expected temp before expiry", target)
+		}
+	}
+	if body := list("/bucket?list-type=2&delimiter=/"); !strings.Contains(body, "<Prefix>dir/</Prefix>") {
+		t.Fatalf("expected dir/ prefix before expiry: %s", body)
+	}
+
+	h.Clock = clock.FixedClock{T: start.Add(time.Minute)}
+	if code := get(); code != http.StatusNotFound {
+		t.Fatalf("GET after expiry: %d", code)
+	}
+	// Listings hide the version before the expiry pass deletes it.
+	for _, target := range []string{"/bucket?list-type=2", "/bucket?versions", "/bucket?list-type=2&delimiter=/"} {
+		if body := list(target); strings.Contains(body, "<Key>temp</Key>") {
+			t.Fatalf("%s: expired version listed: %s", target, body)
+		}
+	}
+	// A prefix whose keys have all expired is not a common prefix either.
+	if body := list("/bucket?list-type=2&delimiter=/"); strings.Contains(body, "<Prefix>dir/</Prefix>") {
+		t.Fatalf("expired prefix listed: %s", body)
+	}
+	objMeta, err := h.Meta.GetObjectMeta(context.Background(), "bucket", "temp")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	report, err := ops.ExpireVersions(context.Background(), h.Meta, h.now())
+	if err != nil {
+		t.Fatalf("ExpireVersions: %v", err)
+	}
+	if report.Deleted != 2 {
+		t.Fatalf("expected 2 expired versions, got %+v", report)
+	}
+	if _, err := h.Meta.GetObjectMeta(context.Background(), "bucket", "temp"); err == nil {
+		t.Fatalf("expected %s to be deleted", objMeta.VersionID)
+	}
+}
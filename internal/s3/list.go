@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
 )
 
 type listBucketResult struct {
@@ -43,6 +45,7 @@ type listContents struct {
 	Size         int64  `xml:"Size"`
 	LastModified string `xml:"LastModified"`
 	StorageClass string `xml:"StorageClass"`
+	Owner        *owner `xml:"Owner,omitempty"`
 }
 
 type commonPrefix struct {
@@ -58,7 +61,7 @@ type locationResult struct {
 func (h *Handler) handleListV2(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
 	exists, err := h.Meta.BucketExists(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if !exists {
@@ -68,15 +71,15 @@ func (h *Handler) handleListV2(ctx context.Context, w http.ResponseWriter, r *ht
 	q := r.URL.Query()
 	prefix := q.Get("prefix")
 	delimiter := q.Get("delimiter")
-	maxKeys := parseMaxKeys(q.Get("max-keys"))
-	afterKey, afterVersion := decodeContinuation(q.Get("continuation-token"))
+	maxKeys := h.parseMaxKeys(q.Get("max-keys"))
+	afterKey, afterVersion, afterPrefix := decodeContinuation(q.Get("continuation-token"))
 	if afterKey == "" {
 		afterKey = q.Get("start-after")
 	}
 
-	contents, common, count, truncated, lastKey, lastVersion, err := h.listObjects(ctx, bucket, prefix, delimiter, afterKey, afterVersion, maxKeys)
+	contents, common, count, truncated, lastKey, lastVersion, lastPrefix, err := h.listObjects(ctx, bucket, prefix, delimiter, afterKey, afterVersion, afterPrefix, maxKeys)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 
@@ -92,7 +95,14 @@ func (h *Handler) handleListV2(ctx context.Context, w http.ResponseWriter, r *ht
 		CommonPrefixes: common,
 	}
 	if truncated && lastKey != "" {
-		resp.NextContinuationToken = encodeContinuation(lastKey, lastVersion)
+		resp.NextContinuationToken = encodeContinuation(lastKey, lastVersion, lastPrefix)
+	}
+	// Owner is opt-in for V2 listings, which keeps the common response small.
+	if fetchOwner, _ := strconv.ParseBool(q.Get("fetch-owner")); fetchOwner {
+		listOwner := h.requestOwner(ctx, r)
+		for i := range resp.Contents {
+			resp.Contents[i].Owner = &listOwner
+		}
 	}
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(http.StatusOK)
@@ -102,7 +112,7 @@ func (h *Handler) handleListV2(ctx context.Context, w http.ResponseWriter, r *ht
 func (h *Handler) handleListV1(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
 	exists, err := h.Meta.BucketExists(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if !exists {
@@ -112,12 +122,18 @@ func (h *Handler) handleListV1(ctx context.Context, w http.ResponseWriter, r *ht
 	q := r.URL.Query()
 	prefix := q.Get("prefix")
 	delimiter := q.Get("delimiter")
-	maxKeys := parseMaxKeys(q.Get("max-keys"))
+	maxKeys := h.parseMaxKeys(q.Get("max-keys"))
 	marker := q.Get("marker")
+	// A marker that is a common prefix (as returned in NextMarker) resumes
+	// after every key grouped under it.
+	markerPrefix := ""
+	if delimiter != "" && strings.HasPrefix(marker, prefix) && strings.HasSuffix(marker, delimiter) {
+		markerPrefix = marker
+	}
 
-	contents, common, _, truncated, lastKey, _, err := h.listObjects(ctx, bucket, prefix, delimiter, marker, "", maxKeys)
+	contents, common, _, truncated, lastKey, _, lastPrefix, err := h.listObjects(ctx, bucket, prefix, delimiter, marker, "", markerPrefix, maxKeys)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 
@@ -133,6 +149,9 @@ func (h *Handler) handleListV1(ctx context.Context, w http.ResponseWriter, r *ht
 	}
 	if truncated && lastKey != "" {
 		resp.NextMarker = lastKey
+		if lastPrefix != "" {
+			resp.NextMarker = lastPrefix
+		}
 	}
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(http.StatusOK)
@@ -164,7 +183,7 @@ func (h *Handler) handleHeadBucket(ctx context.Context, w http.ResponseWriter, r
 	}
 	exists, err := h.Meta.BucketExists(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if !exists {
@@ -174,59 +193,79 @@ func (h *Handler) handleHeadBucket(ctx context.Context, w http.ResponseWriter, r
 	w.WriteHeader(http.StatusOK)
 }
 
-func (h *Handler) listObjects(ctx context.Context, bucket, prefix, delimiter, afterKey, afterVersion string, maxKeys int) ([]listContents, []commonPrefix, int, bool, string, string, error) {
-	pageLimit := maxKeys
-	if pageLimit <= 0 {
-		pageLimit = 1000
+// listObjects returns one page of a listing. With a delimiter, keys are
+// grouped into common prefixes across DB pages; afterPrefix is the common
+// prefix the previous page ended in, so its remaining keys are skipped rather
+// than emitting the prefix again. The returned prefix is the common prefix
+// holding lastKey, if any, and belongs in the continuation token.
+func (h *Handler) listObjects(ctx context.Context, bucket, prefix, delimiter, afterKey, afterVersion, afterPrefix string, maxKeys int) ([]listContents, []commonPrefix, int, bool, string, string, string, error) {
+	if maxKeys <= 0 {
+		maxKeys = defaultMaxListKeys
 	}
+	if from, ok := h.prefixIndexCursor(prefix, delimiter, afterKey, afterPrefix); ok {
+		return h.listObjectsIndexed(ctx, bucket, prefix, from, maxKeys)
+	}
+	// Fetch one row past maxKeys so a full page is only reported as
+	// truncated when more entries actually follow.
+	pageLimit := maxKeys + 1
 
 	contents := make([]listContents, 0)
 	common := make([]commonPrefix, 0)
 	commonSet := make(map[string]struct{})
+	if delimiter != "" && afterPrefix != "" {
+		commonSet[afterPrefix] = struct{}{}
+	}
 	count := 0
 	truncated := false
 	var lastKey string
 	var lastVersion string
+	lastPrefix := afterPrefix
 
 	for {
-		objs, err := h.Meta.ListObjects(ctx, bucket, prefix, afterKey, afterVersion, pageLimit)
+		objs, err := h.Meta.ListObjects(ctx, bucket, prefix, afterKey, afterVersion, pageLimit, h.now())
 		if err != nil {
-			return nil, nil, 0, false, "", "", err
+			return nil, nil, 0, false, "", "", "", err
 		}
 		if len(objs) == 0 {
 			break
 		}
 		for _, obj := range objs {
-			lastKey = obj.Key
-			lastVersion = obj.VersionID
-			if delimiter != "" && strings.HasPrefix(obj.Key, prefix) {
-				rest := strings.TrimPrefix(obj.Key, prefix)
-				if idx := strings.Index(rest, delimiter); idx >= 0 {
-					cp := prefix + rest[:idx+len(delimiter)]
-					if _, ok := commonSet[cp]; !ok {
-						commonSet[cp] = struct{}{}
-						common = append(common, commonPrefix{Prefix: cp})
-						count++
-					}
+			// LIKE matches ASCII case-insensitively; drop keys outside the prefix.
+			if !strings.HasPrefix(obj.Key, prefix) {
+				lastKey = obj.Key
+				lastVersion = obj.VersionID
+				continue
+			}
+			if cp := groupPrefix(obj.Key, prefix, delimiter); cp != "" {
+				if _, ok := commonSet[cp]; !ok {
 					if count >= maxKeys {
 						truncated = true
 						break
 					}
-					continue
+					commonSet[cp] = struct{}{}
+					common = append(common, commonPrefix{Prefix: cp})
+					count++
 				}
+				lastKey = obj.Key
+				lastVersion = obj.VersionID
+				lastPrefix = cp
+				continue
+			}
+			if count >= maxKeys {
+				truncated = true
+				break
 			}
 			contents = append(contents, listContents{
 				Key:          obj.Key,
 				ETag:         `"` + obj.ETag + `"`,
 				Size:         obj.Size,
 				LastModified: formatLastModified(obj.LastModified),
-				StorageClass: "STANDARD",
+				StorageClass: meta.StorageClassOrStandard(obj.StorageClass),
 			})
 			count++
-			if count >= maxKeys {
-				truncated = true
-				break
-			}
+			lastKey = obj.Key
+			lastVersion = obj.VersionID
+			lastPrefix = ""
 		}
 		if truncated {
 			break
@@ -237,44 +276,201 @@ func (h *Handler) listObjects(ctx context.Context, bucket, prefix, delimiter, af
 		afterKey = lastKey
 		afterVersion = lastVersion
 	}
-	return contents, common, count, truncated, lastKey, lastVersion, nil
+	return contents, common, count, truncated, lastKey, lastVersion, lastPrefix, nil
+}
+
+// prefixIndexCursor reports whether a listing can be served from the meta
+// prefix index and returns the inclusive key it resumes from. The index only
+// covers first-level "/" prefixes, so the request prefix must not contain
+// the delimiter and a start-after key inside a prefix falls back to the scan.
+func (h *Handler) prefixIndexCursor(prefix, delimiter, afterKey, afterPrefix string) (string, bool) {
+	if h.DisableListPrefixIndex || delimiter != meta.PrefixIndexDelimiter || !h.Meta.HasPrefixIndex() {
+		return "", false
+	}
+	if strings.Contains(prefix, delimiter) {
+		return "", false
+	}
+	from := prefix
+	switch {
+	case afterPrefix != "":
+		if !strings.HasPrefix(afterPrefix, prefix) || groupPrefix(afterPrefix, prefix, delimiter) != afterPrefix {
+			return "", false
+		}
+		from = max(from, keyRangeEnd(afterPrefix))
+	case afterKey != "":
+		if strings.Contains(afterKey, delimiter) {
+			return "", false
+		}
+		// afterKey + NUL is the smallest key sorting after afterKey.
+		from = max(from, afterKey+"\x00")
+	}
+	return from, true
+}
+
+// listObjectsIndexed is listObjects for first-level "/" listings on a meta
+// DB with the prefix index. Common prefixes come from the index and only the
+// top-level keys between them are read, so a prefix costs one index probe
+// however many keys sit under it.
+func (h *Handler) listObjectsIndexed(ctx context.Context, bucket, prefix, from string, maxKeys int) ([]listContents, []commonPrefix, int, bool, string, string, string, error) {
+	contents := make([]listContents, 0)
+	common := make([]commonPrefix, 0)
+	count := 0
+	var lastKey, lastVersion, lastPrefix string
+	end := keyRangeEnd(prefix)
+	now := h.now()
+
+	// addKeys lists the keys in [from, before) and reports whether the page
+	// filled up before they ran out.
+	addKeys := func(from, before string) (bool, error) {
+		for {
+			limit := maxKeys - count + 1
+			objs, err := h.Meta.ListObjectsInRange(ctx, bucket, from, before, limit, now)
+			if err != nil {
+				return false, err
+			}
+			for _, obj := range objs {
+				// A key under a prefix the index did not return was written
+				// after the prefix lookup; treat it as not yet visible.
+				if groupPrefix(obj.Key, prefix, meta.PrefixIndexDelimiter) != "" {
+					continue
+				}
+				if count >= maxKeys {
+					return true, nil
+				}
+				contents = append(contents, listContents{
+					Key:          obj.Key,
+					ETag:         `"` + obj.ETag + `"`,
+					Size:         obj.Size,
+					LastModified: formatLastModified(obj.LastModified),
+					StorageClass: meta.StorageClassOrStandard(obj.StorageClass),
+				})
+				count++
+				lastKey = obj.Key
+				lastVersion = obj.VersionID
+				lastPrefix = ""
+			}
+			if len(objs) < limit {
+				return false, nil
+			}
+			from = objs[len(objs)-1].Key + "\x00"
+		}
+	}
+
+	for {
+		limit := maxKeys - count + 1
+		prefixes, err := h.Meta.ListFirstLevelPrefixes(ctx, bucket, from, end, limit, now)
+		if err != nil {
+			return nil, nil, 0, false, "", "", "", err
+		}
+		for _, cp := range prefixes {
+			full, err := addKeys(from, cp)
+			if err != nil {
+				return nil, nil, 0, false, "", "", "", err
+			}
+			if full || count >= maxKeys {
+				return contents, common, count, true, lastKey, lastVersion, lastPrefix, nil
+			}
+			common = append(common, commonPrefix{Prefix: cp})
+			count++
+			lastKey = cp
+			lastVersion = ""
+			lastPrefix = cp
+			from = keyRangeEnd(cp)
+		}
+		if len(prefixes) == limit {
+			// More prefixes may follow; the keys after the last one are
+			// read on the next round.
+			continue
+		}
+		full, err := addKeys(from, end)
+		if err != nil {
+			return nil, nil, 0, false, "", "", "", err
+		}
+		return contents, common, count, full, lastKey, lastVersion, lastPrefix, nil
+	}
 }
 
-func parseMaxKeys(raw string) int {
+// keyRangeEnd returns the smallest key sorting after every key that starts
+// with prefix, or "" (unbounded) for an empty prefix.
+func keyRangeEnd(prefix string) string {
+	b := []byte(prefix)
+	for len(b) > 0 {
+		if last := b[len(b)-1]; last < 0xff {
+			b[len(b)-1] = last + 1
+			return string(b)
+		}
+		b = b[:len(b)-1]
+	}
+	return ""
+}
+
+// groupPrefix returns the common prefix a key rolls up into for a delimiter
+// listing, or "" when the key is listed on its own.
+func groupPrefix(key, prefix, delimiter string) string {
+	if delimiter == "" {
+		return ""
+	}
+	rest := key[len(prefix):]
+	idx := strings.Index(rest, delimiter)
+	if idx < 0 {
+		return ""
+	}
+	return prefix + rest[:idx+len(delimiter)]
+}
+
+// defaultMaxListKeys matches the S3 page size ceiling.
+const defaultMaxListKeys = 1000
+
+// parseMaxKeys returns the requested page size, silently clamped to the
+// handler's MaxListKeys ceiling.
+func (h *Handler) parseMaxKeys(raw string) int {
+	limit := h.MaxListKeys
+	if limit <= 0 {
+		limit = defaultMaxListKeys
+	}
 	if raw == "" {
-		return 1000
+		return limit
 	}
 	v, err := strconv.Atoi(raw)
 	if err != nil || v <= 0 {
-		return 1000
+		return limit
 	}
-	if v > 1000 {
-		return 1000
+	if v > limit {
+		return limit
 	}
 	return v
 }
 
-func encodeContinuation(key, versionID string) string {
-	if versionID == "" {
-		return base64.RawURLEncoding.EncodeToString([]byte(key))
+// encodeContinuation packs the resume position of a ListV2 page: the last
+// key and version, plus the common prefix the page ended in (if any).
+func encodeContinuation(key, versionID, commonPrefix string) string {
+	raw := key
+	if versionID != "" || commonPrefix != "" {
+		raw += "\n" + versionID
+	}
+	if commonPrefix != "" {
+		raw += "\n" + commonPrefix
 	}
-	return base64.RawURLEncoding.EncodeToString([]byte(key + "\n" + versionID))
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
 }
 
-func decodeContinuation(raw string) (string, string) {
+func decodeContinuation(raw string) (string, string, string) {
 	if raw == "" {
-		return "", ""
+		return "", "", ""
 	}
 	data, err := base64.RawURLEncoding.DecodeString(raw)
 	if err != nil {
 		// Fallback: treat token as raw key for compatibility.
-		return raw, ""
+		return raw, "", ""
 	}
-	parts := strings.SplitN(string(data), "\n", 2)
-	if len(parts) == 1 {
-		return parts[0], ""
+	parts := strings.SplitN(string(data), "\n", 3)
+	switch len(parts) {
+	case 1:
+		return parts[0], "", ""
+	case 2:
+		return parts[0], parts[1], ""
 	}
-	return parts[0], parts[1]
+	return parts[0], parts[1], parts[2]
 }
 
 func formatLastModified(raw string) string {
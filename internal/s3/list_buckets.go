@@ -2,9 +2,13 @@ package s3
 
 import (
 	"context"
+	"database/sql"
 	"encoding/xml"
+	"errors"
 	"net/http"
-	"time"
+	"strings"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
 )
 
 type listBucketsResult struct {
@@ -32,46 +36,99 @@ func (h *Handler) handleListBuckets(ctx context.Context, w http.ResponseWriter,
 		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, "/")
 		return
 	}
-	names, err := h.Meta.ListBuckets(ctx)
+	infos, err := h.Meta.ListBucketInfos(ctx)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, "/")
+		h.writeInternalError(w, err, requestID, "/")
 		return
 	}
-	accessKey := extractAccessKey(r)
-	if accessKey != "" {
-		allowed, err := h.Meta.ListAllowedBuckets(ctx, accessKey)
-		if err != nil {
-			writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, "/")
-			return
-		}
-		if len(allowed) > 0 {
-			allowedSet := make(map[string]struct{}, len(allowed))
-			for _, name := range allowed {
-				allowedSet[name] = struct{}{}
-			}
-			filtered := make([]string, 0, len(names))
-			for _, name := range names {
-				if _, ok := allowedSet[name]; ok {
-					filtered = append(filtered, name)
-				}
-			}
-			names = filtered
-		}
-	}
 	out := listBucketsResult{
-		Owner: owner{ID: "seglake", DisplayName: "seglake"},
+		Owner: h.requestOwner(ctx, r),
 		Buckets: buckets{
-			Bucket: make([]bucket, 0, len(names)),
+			Bucket: make([]bucket, 0, len(infos)),
 		},
 	}
-	now := h.now().UTC().Format(time.RFC3339)
-	for _, name := range names {
+	if accessKey := extractAccessKey(r); accessKey != "" {
+		infos, err = h.visibleBuckets(ctx, r, accessKey, infos)
+		if err != nil {
+			h.writeInternalError(w, err, requestID, "/")
+			return
+		}
+	}
+	for _, info := range infos {
 		out.Buckets.Bucket = append(out.Buckets.Bucket, bucket{
-			Name:         name,
-			CreationDate: now,
+			Name:         info.Name,
+			CreationDate: formatLastModified(info.CreatedAt),
 		})
 	}
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(http.StatusOK)
 	_ = xml.NewEncoder(w).Encode(out)
 }
+
+// requestOwner is the owner reported to the caller: its access key (with the
+// key label as display name) or the default owner for unsigned requests.
+func (h *Handler) requestOwner(ctx context.Context, r *http.Request) owner {
+	accessKey := extractAccessKey(r)
+	if accessKey == "" {
+		return h.defaultOwner()
+	}
+	out := owner{ID: accessKey, DisplayName: accessKey}
+	if h.Meta != nil {
+		if key, err := h.Meta.GetAPIKey(ctx, accessKey); err == nil && key.Label != "" {
+			out.DisplayName = key.Label
+		}
+	}
+	return out
+}
+
+func (h *Handler) defaultOwner() owner {
+	name := strings.TrimSpace(h.DefaultOwner)
+	if name == "" {
+		name = "seglake"
+	}
+	return owner{ID: name, DisplayName: name}
+}
+
+// visibleBuckets filters buckets to those the access key may list: the key's
+// bucket allowlist applies first, then identity and bucket policies must allow
+// ListBucket on the bucket. Keys not in the API key table see every bucket.
+func (h *Handler) visibleBuckets(ctx context.Context, r *http.Request, accessKey string, infos []meta.BucketInfo) ([]meta.BucketInfo, error) {
+	key, err := h.Meta.GetAPIKey(ctx, accessKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return infos, nil
+		}
+		return nil, err
+	}
+	allowed, err := h.Meta.ListAllowedBuckets(ctx, accessKey)
+	if err != nil {
+		return nil, err
+	}
+	var allowedSet map[string]struct{}
+	if len(allowed) > 0 {
+		allowedSet = make(map[string]struct{}, len(allowed))
+		for _, entry := range allowed {
+			if res, err := parseResourcePattern(entry); err == nil {
+				allowedSet[res.Bucket] = struct{}{}
+			}
+		}
+	}
+	pol, err := ParsePolicy(key.Policy)
+	if err != nil {
+		return nil, nil
+	}
+	reqCtx := h.policyContextFromRequest(r)
+	out := make([]meta.BucketInfo, 0, len(infos))
+	for _, info := range infos {
+		if allowedSet != nil {
+			if _, ok := allowedSet[info.Name]; !ok {
+				continue
+			}
+		}
+		if !h.policyAllows(ctx, pol, policyActionListBucket, info.Name, "", reqCtx) {
+			continue
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
@@ -0,0 +1,98 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func TestListBucketsFiltersAndReportsOwner(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		if err := store.CreateBucket(ctx, name); err != nil {
+			t.Fatalf("CreateBucket: %v", err)
+		}
+	}
+	if err := store.UpsertAPIKey(ctx, "allow-key", "allow-secret", "rw", true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	if err := store.AllowBucketForKey(ctx, "allow-key", "beta"); err != nil {
+		t.Fatalf("AllowBucketForKey: %v", err)
+	}
+	gammaOnly := `{"version":"v1","statements":[{"effect":"allow","actions":["listbuckets"],"resources":[{"bucket":"*"}]},{"effect":"allow","actions":["listbucket"],"resources":[{"bucket":"gamma"}]}]}`
+	if err := store.UpsertAPIKey(ctx, "policy-key", "policy-secret", gammaOnly, true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	if err := store.UpsertAPIKey(ctx, "all-key", "all-secret", "rw", true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+
+	handler := &Handler{
+		Engine: eng,
+		Meta:   store,
+		Auth: &AuthConfig{
+			Region:               "us-east-1",
+			SecretLookup:         store.LookupAPISecret,
+			AllowUnsignedPayload: true,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		accessKey string
+		secretKey string
+		want      []string
+	}{
+		{name: "allowlist", accessKey: "allow-key", secretKey: "allow-secret", want: []string{"beta"}},
+		{name: "policy", accessKey: "policy-key", secretKey: "policy-secret", want: []string{"gamma"}},
+		{name: "all", accessKey: "all-key", secretKey: "all-secret", want: []string{"alpha", "beta", "gamma"}},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		signRequestTest(req, tc.accessKey, tc.secretKey, "us-east-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status=%d body=%s", tc.name, rec.Code, rec.Body.String())
+		}
+		var out listBucketsResult
+		if err := xml.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("%s: xml: %v", tc.name, err)
+		}
+		if out.Owner.ID != tc.accessKey {
+			t.Fatalf("%s: owner=%q", tc.name, out.Owner.ID)
+		}
+		if len(out.Buckets.Bucket) != len(tc.want) {
+			t.Fatalf("%s: buckets=%+v want %v", tc.name, out.Buckets.Bucket, tc.want)
+		}
+		for i, b := range out.Buckets.Bucket {
+			if b.Name != tc.want[i] {
+				t.Fatalf("%s: bucket[%d]=%q want %q", tc.name, i, b.Name, tc.want[i])
+			}
+			if b.CreationDate == "" {
+				t.Fatalf("%s: missing creation date for %s", tc.name, b.Name)
+			}
+		}
+	}
+}
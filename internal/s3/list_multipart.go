@@ -42,9 +42,16 @@ func (h *Handler) handleListMultipartUploads(ctx context.Context, w http.Respons
 		return
 	}
 
-	out, common, truncated, nextKey, nextUpload, err := h.listMultipartUploads(ctx, bucket, prefix, delimiter, keyMarker, uploadIDMarker, maxUploads)
+	// A key marker that is a common prefix (as returned in NextKeyMarker)
+	// resumes after every upload grouped under it.
+	markerPrefix := ""
+	if delimiter != "" && uploadIDMarker == "" && strings.HasPrefix(keyMarker, prefix) && strings.HasSuffix(keyMarker, delimiter) {
+		markerPrefix = keyMarker
+	}
+
+	out, common, truncated, nextKey, nextUpload, nextPrefix, err := h.listMultipartUploads(ctx, bucket, prefix, delimiter, keyMarker, uploadIDMarker, markerPrefix, maxUploads)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	resp := listMultipartResult{
@@ -58,57 +65,78 @@ func (h *Handler) handleListMultipartUploads(ctx context.Context, w http.Respons
 		Uploads:        out,
 		CommonPrefixes: common,
 	}
-	if resp.IsTruncated && nextKey != "" && nextUpload != "" {
-		resp.NextKeyMarker = nextKey
-		resp.NextUploadIDMarker = nextUpload
+	if truncated {
+		if nextPrefix != "" {
+			resp.NextKeyMarker = nextPrefix
+		} else if nextKey != "" && nextUpload != "" {
+			resp.NextKeyMarker = nextKey
+			resp.NextUploadIDMarker = nextUpload
+		}
 	}
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(http.StatusOK)
 	_ = xml.NewEncoder(w).Encode(resp)
 }
 
-func (h *Handler) listMultipartUploads(ctx context.Context, bucket, prefix, delimiter, keyMarker, uploadIDMarker string, maxUploads int) ([]multipartUploadOut, []commonPrefix, bool, string, string, error) {
-	pageLimit := maxUploads
-	if pageLimit <= 0 {
-		pageLimit = 1000
+// listMultipartUploads returns one page of in-progress uploads, grouping keys
+// into common prefixes like listObjects. afterPrefix is the common prefix the
+// previous page ended in; the returned prefix is set when this page ends in
+// one and replaces the key/upload-id pair as the continuation marker.
+func (h *Handler) listMultipartUploads(ctx context.Context, bucket, prefix, delimiter, keyMarker, uploadIDMarker, afterPrefix string, maxUploads int) ([]multipartUploadOut, []commonPrefix, bool, string, string, string, error) {
+	if maxUploads <= 0 {
+		maxUploads = 1000
 	}
+	// Fetch one row past maxUploads so a full page is only reported as
+	// truncated when more uploads actually follow.
+	pageLimit := maxUploads + 1
 
 	uploadsOut := make([]multipartUploadOut, 0)
 	common := make([]commonPrefix, 0)
 	commonSet := make(map[string]struct{})
+	if afterPrefix != "" {
+		commonSet[afterPrefix] = struct{}{}
+	}
 	count := 0
 	truncated := false
 	afterKey := keyMarker
 	afterUpload := uploadIDMarker
 	var lastKey string
 	var lastUpload string
+	lastPrefix := afterPrefix
 
 	for {
 		uploads, err := h.Meta.ListMultipartUploads(ctx, bucket, prefix, afterKey, afterUpload, pageLimit)
 		if err != nil {
-			return nil, nil, false, "", "", err
+			return nil, nil, false, "", "", "", err
 		}
 		if len(uploads) == 0 {
 			break
 		}
 		for _, up := range uploads {
-			lastKey = up.Key
-			lastUpload = up.UploadID
-			if delimiter != "" && strings.HasPrefix(up.Key, prefix) {
-				rest := strings.TrimPrefix(up.Key, prefix)
-				if idx := strings.Index(rest, delimiter); idx >= 0 {
-					cp := prefix + rest[:idx+len(delimiter)]
-					if _, ok := commonSet[cp]; !ok {
-						commonSet[cp] = struct{}{}
-						common = append(common, commonPrefix{Prefix: cp})
-						count++
-					}
+			// LIKE matches ASCII case-insensitively; drop keys outside the prefix.
+			if !strings.HasPrefix(up.Key, prefix) {
+				lastKey = up.Key
+				lastUpload = up.UploadID
+				continue
+			}
+			if cp := groupPrefix(up.Key, prefix, delimiter); cp != "" {
+				if _, ok := commonSet[cp]; !ok {
 					if count >= maxUploads {
 						truncated = true
 						break
 					}
-					continue
+					commonSet[cp] = struct{}{}
+					common = append(common, commonPrefix{Prefix: cp})
+					count++
 				}
+				lastKey = up.Key
+				lastUpload = up.UploadID
+				lastPrefix = cp
+				continue
+			}
+			if count >= maxUploads {
+				truncated = true
+				break
 			}
 			uploadsOut = append(uploadsOut, multipartUploadOut{
 				Key:       up.Key,
@@ -116,10 +144,9 @@ func (h *Handler) listMultipartUploads(ctx context.Context, bucket, prefix, deli
 				Initiated: formatLastModified(up.CreatedAt),
 			})
 			count++
-			if count >= maxUploads {
-				truncated = true
-				break
-			}
+			lastKey = up.Key
+			lastUpload = up.UploadID
+			lastPrefix = ""
 		}
 		if truncated {
 			break
@@ -131,7 +158,7 @@ func (h *Handler) listMultipartUploads(ctx context.Context, bucket, prefix, deli
 		afterUpload = lastUpload
 	}
 
-	return uploadsOut, common, truncated, lastKey, lastUpload, nil
+	return uploadsOut, common, truncated, lastKey, lastUpload, lastPrefix, nil
 }
 
 func parseMaxUploads(raw string) int {
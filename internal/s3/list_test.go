@@ -1,7 +1,12 @@
 package s3
 
 import (
+	"context"
+	"database/sql"
+	"encoding/xml"
 	"net/http/httptest"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -94,6 +99,60 @@ func TestListV2DelimiterMaxKeysTruncates(t *testing.T) {
 	}
 }
 
+func TestListV2FetchOwner(t *testing.T) {
+	handler := newListTestHandler(t)
+	handler.DefaultOwner = "lake-admin"
+	listPutObject(t, handler, "a.txt")
+
+	body := listAndReadBody(t, handler, "/bucket?list-type=2", "LIST")
+	if strings.Contains(body, "<Owner>") {
+		t.Fatalf("unexpected owner without fetch-owner: %s", body)
+	}
+	body = listAndReadBody(t, handler, "/bucket?list-type=2&fetch-owner=false", "LIST")
+	if strings.Contains(body, "<Owner>") {
+		t.Fatalf("unexpected owner with fetch-owner=false: %s", body)
+	}
+	body = listAndReadBody(t, handler, "/bucket?list-type=2&fetch-owner=true", "LIST")
+	if !strings.Contains(body, "<Owner><ID>lake-admin</ID><DisplayName>lake-admin</DisplayName></Owner>") {
+		t.Fatalf("expected owner with fetch-owner=true: %s", body)
+	}
+}
+
+func TestListMaxKeysClampedToCeiling(t *testing.T) {
+	handler := newListTestHandler(t)
+	handler.MaxListKeys = 2
+
+	listPutObject(t, handler, "a.txt")
+	listPutObject(t, handler, "b.txt")
+	listPutObject(t, handler, "c.txt")
+
+	for _, path := range []string{
+		"/bucket?list-type=2&max-keys=1000000",
+		"/bucket?max-keys=1000000",
+		"/bucket?versions&max-keys=1000000",
+	} {
+		body := listAndReadBody(t, handler, path, "LIST")
+		if !strings.Contains(body, "<MaxKeys>2</MaxKeys>") {
+			t.Fatalf("%s: expected clamped MaxKeys, got %s", path, body)
+		}
+		if !strings.Contains(body, "<IsTruncated>true</IsTruncated>") {
+			t.Fatalf("%s: expected truncated response", path)
+		}
+		if strings.Contains(body, "<Key>c.txt</Key>") {
+			t.Fatalf("%s: expected c.txt beyond the ceiling", path)
+		}
+	}
+
+	exact := newListTestHandler(t)
+	exact.MaxListKeys = 2
+	listPutObject(t, exact, "a.txt")
+	listPutObject(t, exact, "b.txt")
+	body := listAndReadBody(t, exact, "/bucket?list-type=2&max-keys=1000000", "LIST")
+	if !strings.Contains(body, "<IsTruncated>false</IsTruncated>") {
+		t.Fatalf("expected exact page not truncated, got %s", body)
+	}
+}
+
 func TestListV2AcceptsTrailingSlashBucketPath(t *testing.T) {
 	handler := newListTestHandler(t)
 
@@ -206,6 +265,72 @@ func TestCreateBucketWithVersioningHeader(t *testing.T) {
 	}
 }
 
+func TestCreateBucketValidation(t *testing.T) {
+	handler := newListTestHandler(t)
+	handler.Auth = nil
+
+	tests := []struct {
+		name   string
+		bucket string
+		body   string
+		owner  string
+		status int
+		code   string
+	}{
+		{name: "create", bucket: "demo", owner: "alice", status: 200},
+		{name: "owned by you in us-east-1", bucket: "demo", owner: "alice", status: 200},
+		{name: "already exists", bucket: "demo", owner: "bob", status: 409, code: "BucketAlreadyExists"},
+		{name: "bad name", bucket: "Bad..Name", status: 400, code: "InvalidBucketName"},
+		{name: "ip name", bucket: "192.168.1.1", status: 400, code: "InvalidBucketName"},
+		{name: "matching location", bucket: "east", body: `<CreateBucketConfiguration><LocationConstraint>us-east-1</LocationConstraint></CreateBucketConfiguration>`, status: 200},
+		{name: "wrong location", bucket: "west", body: `<CreateBucketConfiguration><LocationConstraint>eu-west-1</LocationConstraint></CreateBucketConfiguration>`, status: 409, code: "IllegalLocationConstraintException"},
+		{name: "malformed body", bucket: "broken", body: `<CreateBucketConfiguration>`, status: 400, code: "MalformedXML"},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest("PUT", "/"+tc.bucket, strings.NewReader(tc.body))
+		if tc.owner != "" {
+			req.Header.Set("Authorization", "AWS "+tc.owner+":sig")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("%s: status=%d body=%s", tc.name, w.Code, w.Body.String())
+		}
+		if tc.code != "" && !strings.Contains(w.Body.String(), "<Code>"+tc.code+"</Code>") {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.code, w.Body.String())
+		}
+	}
+}
+
+func TestCreateBucketOwnedByYouOutsideUSEast1(t *testing.T) {
+	handler := newListTestHandler(t)
+	handler.Auth = &AuthConfig{Region: "eu-west-1"}
+	body := `<CreateBucketConfiguration><LocationConstraint>eu-west-1</LocationConstraint></CreateBucketConfiguration>`
+
+	tests := []struct {
+		name   string
+		owner  string
+		status int
+		code   string
+	}{
+		{name: "create", owner: "alice", status: 200},
+		{name: "owned by you", owner: "alice", status: 409, code: "BucketAlreadyOwnedByYou"},
+		{name: "already exists", owner: "bob", status: 409, code: "BucketAlreadyExists"},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest("PUT", "/demo", strings.NewReader(body))
+		req.Header.Set("Authorization", "AWS "+tc.owner+":sig")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("%s: status=%d body=%s", tc.name, w.Code, w.Body.String())
+		}
+		if tc.code != "" && !strings.Contains(w.Body.String(), "<Code>"+tc.code+"</Code>") {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.code, w.Body.String())
+		}
+	}
+}
+
 func TestPutGetBucketVersioning(t *testing.T) {
 	handler := newListTestHandler(t)
 
@@ -431,3 +556,281 @@ func newListTestHandler(t *testing.T) *Handler {
 		Meta:   store,
 	}
 }
+
+func TestListV2DelimiterPaginatesNestedPrefixes(t *testing.T) {
+	handler := newListTestHandler(t)
+	for _, key := range []string{"a-b-c", "a-b-d", "a-c-x", "a-c-y", "a-d", "b-e-f", "b-g", "c"} {
+		listPutObject(t, handler, key)
+	}
+
+	cases := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"P:a-", "P:b-", "K:c"}},
+		{prefix: "a-", want: []string{"P:a-b-", "P:a-c-", "K:a-d"}},
+	}
+	for _, tc := range cases {
+		var got []string
+		token := ""
+		for page := 0; page < 10; page++ {
+			path := "/bucket?list-type=2&delimiter=-&max-keys=1&prefix=" + tc.prefix
+			if token != "" {
+				path += "&continuation-token=" + token
+			}
+			var resp listBucketResult
+			body := listAndReadBody(t, handler, path, "LIST")
+			if err := xml.Unmarshal([]byte(body), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			for _, cp := range resp.CommonPrefixes {
+				got = append(got, "P:"+cp.Prefix)
+			}
+			for _, obj := range resp.Contents {
+				got = append(got, "K:"+obj.Key)
+			}
+			if !resp.IsTruncated {
+				break
+			}
+			token = resp.NextContinuationToken
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("prefix %q: got %v, want %v", tc.prefix, got, tc.want)
+		}
+	}
+}
+
+func TestListV1DelimiterNextMarkerIsCommonPrefix(t *testing.T) {
+	handler := newListTestHandler(t)
+	for _, key := range []string{"a-b-c", "a-b-d", "a-c", "b"} {
+		listPutObject(t, handler, key)
+	}
+
+	var got []string
+	marker := ""
+	for page := 0; page < 10; page++ {
+		var resp listBucketResultV1
+		body := listAndReadBody(t, handler, "/bucket?delimiter=-&max-keys=1&prefix=a-&marker="+marker, "LIST")
+		if err := xml.Unmarshal([]byte(body), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		for _, cp := range resp.CommonPrefixes {
+			got = append(got, "P:"+cp.Prefix)
+		}
+		for _, obj := range resp.Contents {
+			got = append(got, "K:"+obj.Key)
+		}
+		if !resp.IsTruncated {
+			break
+		}
+		if page == 0 && resp.NextMarker != "a-b-" {
+			t.Fatalf("expected NextMarker a-b-, got %q", resp.NextMarker)
+		}
+		marker = resp.NextMarker
+	}
+	if want := "P:a-b-,K:a-c"; strings.Join(got, ",") != want {
+		t.Fatalf("got %v, want %s", got, want)
+	}
+}
+
+func TestContinuationTokenRoundTrip(t *testing.T) {
+	key, version, prefix := decodeContinuation(encodeContinuation("a-b-d", "v1", "a-b-"))
+	if key != "a-b-d" || version != "v1" || prefix != "a-b-" {
+		t.Fatalf("unexpected decode: %q %q %q", key, version, prefix)
+	}
+	key, version, prefix = decodeContinuation(encodeContinuation("k", "", ""))
+	if key != "k" || version != "" || prefix != "" {
+		t.Fatalf("unexpected decode: %q %q %q", key, version, prefix)
+	}
+}
+
+func TestListMultipartUploadsPagesThroughAll(t *testing.T) {
+	handler := newListTestHandler(t)
+	keys := []string{"a-b-c", "a-b-d", "a-c", "b", "b", "c", "d-e"}
+	for _, key := range keys {
+		req := httptest.NewRequest("POST", "/bucket/"+key+"?uploads", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("initiate %s status: %d", key, w.Code)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"flat", "", []string{"K:a-b-c", "K:a-b-d", "K:a-c", "K:b", "K:b", "K:c", "K:d-e"}},
+		{"delimiter", "&delimiter=-", []string{"P:a-", "K:b", "K:b", "K:c", "P:d-"}},
+		{"prefix and delimiter", "&prefix=a-&delimiter=-", []string{"P:a-b-", "K:a-c"}},
+	}
+	for _, tc := range cases {
+		var got []string
+		uploadIDs := make(map[string]struct{})
+		keyMarker, uploadMarker := "", ""
+		pages := 0
+		for ; pages < 20; pages++ {
+			path := "/bucket?uploads&max-uploads=2" + tc.query
+			if keyMarker != "" {
+				path += "&key-marker=" + keyMarker
+			}
+			if uploadMarker != "" {
+				path += "&upload-id-marker=" + uploadMarker
+			}
+			var resp listMultipartResult
+			body := listAndReadBody(t, handler, path, "LIST UPLOADS")
+			if err := xml.Unmarshal([]byte(body), &resp); err != nil {
+				t.Fatalf("%s: decode: %v", tc.name, err)
+			}
+			if len(resp.Uploads)+len(resp.CommonPrefixes) > 2 {
+				t.Fatalf("%s: page over max-uploads: %s", tc.name, body)
+			}
+			for _, cp := range resp.CommonPrefixes {
+				got = append(got, "P:"+cp.Prefix)
+			}
+			for _, up := range resp.Uploads {
+				got = append(got, "K:"+up.Key)
+				if _, dup := uploadIDs[up.UploadID]; dup {
+					t.Fatalf("%s: upload %s listed twice", tc.name, up.UploadID)
+				}
+				uploadIDs[up.UploadID] = struct{}{}
+			}
+			if !resp.IsTruncated {
+				break
+			}
+			if resp.NextKeyMarker == "" {
+				t.Fatalf("%s: truncated page without NextKeyMarker", tc.name)
+			}
+			keyMarker, uploadMarker = resp.NextKeyMarker, resp.NextUploadIDMarker
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+		// A full last page is not reported as truncated.
+		if wantPages := (len(tc.want) + 1) / 2; pages+1 != wantPages {
+			t.Fatalf("%s: pages=%d want %d", tc.name, pages+1, wantPages)
+		}
+	}
+}
+
+func TestListPrefixIndexMatchesScan(t *testing.T) {
+	handler := newListTestHandler(t)
+	for _, key := range []string{"a", "a/1", "a/2", "a0", "b/1", "b0", "c/x", "d", "e/f/g", "ph/1", "photo", "photos/1", "Ph/2", "z/"} {
+		listPutObject(t, handler, key)
+	}
+	// c/ only holds a delete marker afterwards and must not be listed.
+	req := httptest.NewRequest("DELETE", "/bucket/c/x", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != 204 {
+		t.Fatalf("DELETE status: %d", w.Code)
+	}
+	scan := &Handler{Engine: handler.Engine, Meta: handler.Meta, DisableListPrefixIndex: true}
+
+	collect := func(h *Handler, query string, maxKeys int) []string {
+		var got []string
+		token := ""
+		for page := 0; page < 50; page++ {
+			path := "/bucket?list-type=2&delimiter=/&max-keys=" + strconv.Itoa(maxKeys) + query
+			if token != "" {
+				path += "&continuation-token=" + token
+			}
+			var resp listBucketResult
+			body := listAndReadBody(t, h, path, "LIST")
+			if err := xml.Unmarshal([]byte(body), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.KeyCount > maxKeys {
+				t.Fatalf("page over max-keys: %s", body)
+			}
+			page := make([]string, 0, resp.KeyCount)
+			for _, cp := range resp.CommonPrefixes {
+				page = append(page, "P:"+cp.Prefix)
+			}
+			for _, obj := range resp.Contents {
+				page = append(page, "K:"+obj.Key)
+			}
+			sort.Slice(page, func(i, j int) bool { return page[i][2:] < page[j][2:] })
+			got = append(got, page...)
+			got = append(got, "|")
+			if !resp.IsTruncated {
+				break
+			}
+			token = resp.NextContinuationToken
+		}
+		return got
+	}
+
+	for _, query := range []string{"", "&prefix=p", "&prefix=ph", "&prefix=b", "&start-after=a", "&start-after=b0", "&start-after=a/1"} {
+		for _, maxKeys := range []int{1, 2, 3, 1000} {
+			want := collect(scan, query, maxKeys)
+			got := collect(handler, query, maxKeys)
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Fatalf("query %q max-keys=%d: index %v, scan %v", query, maxKeys, got, want)
+			}
+		}
+	}
+	if got := strings.Join(collect(handler, "", 1000), ","); got != "P:Ph/,K:a,P:a/,K:a0,P:b/,K:b0,K:d,P:e/,P:ph/,K:photo,P:photos/,P:z/,|" {
+		t.Fatalf("unexpected listing: %s", got)
+	}
+}
+
+// BenchmarkListDelimiter reads the first page of a "/" delimiter listing on a
+// bucket of 1M keys under 1000 prefixes, from the prefix index and from the
+// key scan. The scan walks every key, so run it with -benchtime 1x -timeout 0.
+func BenchmarkListDelimiter(b *testing.B) {
+	const prefixes, keysPerPrefix = 1000, 1000
+	path := b.TempDir() + "/meta.db"
+	store, err := meta.Open(path)
+	if err != nil {
+		b.Fatalf("meta.Open: %v", err)
+	}
+	b.Cleanup(func() { _ = store.Close() })
+	ctx := context.Background()
+	if err := store.CreateBucket(ctx, "bucket"); err != nil {
+		b.Fatalf("CreateBucket: %v", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		b.Fatalf("sql.Open: %v", err)
+	}
+	for _, stmt := range []string{
+		`WITH RECURSIVE n(i) AS (SELECT 0 UNION ALL SELECT i+1 FROM n WHERE i+1 < ?)
+INSERT INTO versions(version_id, bucket, key, etag, size, content_type, last_modified_utc, hlc_ts, site_id, state)
+SELECT printf('v%07d', i), 'bucket', printf('dir%04d/obj%04d', i / ?, i % ?), 'etag', 1, '', '2024-01-01T00:00:00Z', printf('%020d', i), 'local', 'ACTIVE'
+FROM n`,
+		`INSERT INTO objects_current(bucket, key, version_id) SELECT bucket, key, version_id FROM versions WHERE bucket='bucket'`,
+	} {
+		args := []any{}
+		if strings.Contains(stmt, "RECURSIVE") {
+			args = append(args, prefixes*keysPerPrefix, keysPerPrefix, keysPerPrefix)
+		}
+		if _, err := db.ExecContext(ctx, stmt, args...); err != nil {
+			_ = db.Close()
+			b.Fatalf("seed: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	for _, tc := range []struct {
+		name    string
+		disable bool
+	}{
+		{name: "index"},
+		{name: "scan", disable: true},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			h := &Handler{Meta: store, DisableListPrefixIndex: tc.disable}
+			for i := 0; i < b.N; i++ {
+				_, common, _, _, _, _, _, err := h.listObjects(ctx, "bucket", "", "/", "", "", "", 1000)
+				if err != nil {
+					b.Fatalf("listObjects: %v", err)
+				}
+				if len(common) != prefixes {
+					b.Fatalf("common prefixes=%d want %d", len(common), prefixes)
+				}
+			}
+		})
+	}
+}
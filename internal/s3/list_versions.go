@@ -47,7 +47,7 @@ type listDeleteMarker struct {
 func (h *Handler) handleListVersions(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
 	exists, err := h.Meta.BucketExists(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if !exists {
@@ -64,11 +64,11 @@ func (h *Handler) handleListVersions(ctx context.Context, w http.ResponseWriter,
 		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid encoding-type", requestID, r.URL.Path)
 		return
 	}
-	maxKeys := parseMaxKeys(q.Get("max-keys"))
+	maxKeys := h.parseMaxKeys(q.Get("max-keys"))
 
 	versioningState, err := h.bucketVersioningState(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	if versioningState == meta.BucketVersioningDisabled {
@@ -95,7 +95,7 @@ func (h *Handler) handleListVersions(ctx context.Context, w http.ResponseWriter,
 
 	versions, deletes, common, _, truncated, lastKey, lastVersion, lastIsNull, err := h.listObjectVersions(ctx, bucket, prefix, delimiter, keyMarker, markerVersionID, maxKeys)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 
@@ -153,10 +153,12 @@ func (h *Handler) handleListVersions(ctx context.Context, w http.ResponseWriter,
 }
 
 func (h *Handler) listObjectVersions(ctx context.Context, bucket, prefix, delimiter, afterKey, afterVersion string, maxKeys int) ([]listObjectVersion, []listDeleteMarker, []commonPrefix, int, bool, string, string, bool, error) {
-	pageLimit := maxKeys
-	if pageLimit <= 0 {
-		pageLimit = 1000
+	if maxKeys <= 0 {
+		maxKeys = defaultMaxListKeys
 	}
+	// Fetch one row past maxKeys so a full page is only reported as
+	// truncated when more versions actually follow.
+	pageLimit := maxKeys + 1
 
 	versions := make([]listObjectVersion, 0)
 	deletes := make([]listDeleteMarker, 0)
@@ -170,7 +172,7 @@ func (h *Handler) listObjectVersions(ctx context.Context, bucket, prefix, delimi
 	var lastListedKey string
 
 	for {
-		objs, err := h.Meta.ListObjectVersions(ctx, bucket, prefix, afterKey, afterVersion, pageLimit)
+		objs, err := h.Meta.ListObjectVersions(ctx, bucket, prefix, afterKey, afterVersion, pageLimit, h.now())
 		if err != nil {
 			return nil, nil, nil, 0, false, "", "", false, err
 		}
@@ -178,26 +180,30 @@ func (h *Handler) listObjectVersions(ctx context.Context, bucket, prefix, delimi
 			break
 		}
 		for _, obj := range objs {
-			lastKey = obj.Key
-			lastVersion = obj.VersionID
-			lastIsNull = obj.IsNull
 			if delimiter != "" && strings.HasPrefix(obj.Key, prefix) {
 				rest := strings.TrimPrefix(obj.Key, prefix)
 				if idx := strings.Index(rest, delimiter); idx >= 0 {
 					cp := prefix + rest[:idx+len(delimiter)]
 					if _, ok := commonSet[cp]; !ok {
+						if count >= maxKeys {
+							truncated = true
+							break
+						}
 						commonSet[cp] = struct{}{}
 						common = append(common, commonPrefix{Prefix: cp})
 						count++
 					}
-					if count >= maxKeys {
-						truncated = true
-						break
-					}
+					lastKey = obj.Key
+					lastVersion = obj.VersionID
+					lastIsNull = obj.IsNull
 					continue
 				}
 			}
 
+			if count >= maxKeys {
+				truncated = true
+				break
+			}
 			isLatest := false
 			if obj.Key != lastListedKey {
 				lastListedKey = obj.Key
@@ -222,14 +228,13 @@ func (h *Handler) listObjectVersions(ctx context.Context, bucket, prefix, delimi
 					LastModified: formatLastModified(obj.LastModified),
 					ETag:         `"` + obj.ETag + `"`,
 					Size:         obj.Size,
-					StorageClass: "STANDARD",
+					StorageClass: meta.StorageClassOrStandard(obj.StorageClass),
 				})
 			}
 			count++
-			if count >= maxKeys {
-				truncated = true
-				break
-			}
+			lastKey = obj.Key
+			lastVersion = obj.VersionID
+			lastIsNull = obj.IsNull
 		}
 		if truncated {
 			break
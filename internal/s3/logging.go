@@ -1,31 +1,165 @@
 package s3
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/clock"
 )
 
+// Access log formats for LoggingOptions.Format.
+const (
+	// LogFormatText logs key=value lines through the standard logger (default).
+	LogFormatText = "text"
+	// LogFormatJSON writes one JSON object per request and line (accessLogEntry)
+	// to the standard logger's output, without the logger's prefix.
+	LogFormatJSON = "json"
+)
+
+// ParseLogFormat validates an access log format; "" means LogFormatText.
+func ParseLogFormat(raw string) (string, error) {
+	switch raw {
+	case "":
+		return LogFormatText, nil
+	case LogFormatText, LogFormatJSON:
+		return raw, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q (want %s or %s)", raw, LogFormatText, LogFormatJSON)
+	}
+}
+
+// RequestInfo is what JSON access log lines report about a request beyond
+// the HTTP exchange itself.
+type RequestInfo struct {
+	Op       string
+	Bucket   string
+	Key      string
+	SourceIP string
+}
+
+// accessLogEntry is a JSON access log line. Credentials never appear: the
+// Authorization header is not logged and presigned query signatures are
+// redacted from path.
+type accessLogEntry struct {
+	TS         string `json:"ts"`
+	RequestID  string `json:"request_id"`
+	TraceID    string `json:"trace_id,omitempty"`
+	AccessKey  string `json:"access_key,omitempty"`
+	Op         string `json:"op,omitempty"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Bucket     string `json:"bucket,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Status     int    `json:"status"`
+	BytesIn    int64  `json:"bytes_in"`
+	BytesOut   int64  `json:"bytes_out"`
+	DurationMs int64  `json:"duration_ms"`
+	SourceIP   string `json:"source_ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+}
+
+// LoggingOptions selects the requests LoggingMiddleware logs.
+type LoggingOptions struct {
+	// Bucket, when set, returns the bucket of requests that are logged in
+	// detail (bucket, key, access key, client address, user agent and bytes
+	// sent), typically Handler.RequestLogBucket.
+	Bucket func(*http.Request) (string, bool)
+	// BucketsOnly logs only the requests matched by Bucket.
+	BucketsOnly bool
+	// Format is LogFormatText ("" too) or LogFormatJSON.
+	Format string
+	// Describe, when set, fills op, bucket, key and client address of JSON
+	// lines, typically Handler.DescribeRequest.
+	Describe func(*http.Request) RequestInfo
+}
+
 // LoggingMiddleware logs request method/path/status/latency with request-id.
-func LoggingMiddleware(next http.Handler, clk clock.Clock) http.Handler {
+func LoggingMiddleware(next http.Handler, clk clock.Clock, opts LoggingOptions) http.Handler {
 	if clk == nil {
 		clk = clock.RealClock{}
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucket, detail := "", false
+		if opts.Bucket != nil {
+			bucket, detail = opts.Bucket(r)
+		}
+		if !detail && opts.BucketsOnly {
+			next.ServeHTTP(w, r)
+			return
+		}
 		start := clk.Now()
+		var bytesIn int64
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &countingReadCloser{reader: r.Body, counter: &bytesIn}
+		}
+		var info RequestInfo
+		if opts.Format == LogFormatJSON && opts.Describe != nil {
+			info = opts.Describe(r)
+		}
 		lw := &loggingWriter{ResponseWriter: w, status: http.StatusOK}
 		next.ServeHTTP(lw, r)
 		end := clk.Now()
 		reqID := w.Header().Get("x-amz-request-id")
-		log.Printf("method=%s path=%s status=%d dur_ms=%d req_id=%s", r.Method, redactURL(r.URL), lw.status, end.Sub(start).Milliseconds(), reqID)
+		traceID := w.Header().Get(traceIDHeader)
+		if opts.Format == LogFormatJSON {
+			if info.Bucket == "" {
+				info.Bucket = bucket
+			}
+			if info.SourceIP == "" {
+				info.SourceIP = clientIP(r.RemoteAddr)
+			}
+			writeAccessLogJSON(accessLogEntry{
+				TS:         start.UTC().Format(time.RFC3339Nano),
+				RequestID:  reqID,
+				TraceID:    traceID,
+				AccessKey:  extractAccessKey(r),
+				Op:         info.Op,
+				Method:     r.Method,
+				Path:       redactURL(r.URL),
+				Bucket:     info.Bucket,
+				Key:        info.Key,
+				Status:     lw.status,
+				BytesIn:    bytesIn,
+				BytesOut:   lw.bytes,
+				DurationMs: end.Sub(start).Milliseconds(),
+				SourceIP:   info.SourceIP,
+				UserAgent:  r.UserAgent(),
+			})
+			return
+		}
+		trace := ""
+		if traceID != "" {
+			trace = " trace_id=" + traceID
+		}
+		if !detail {
+			log.Printf("method=%s path=%s status=%d dur_ms=%d req_id=%s%s", r.Method, redactURL(r.URL), lw.status, end.Sub(start).Milliseconds(), reqID, trace)
+			return
+		}
+		log.Printf("method=%s path=%s status=%d dur_ms=%d req_id=%s%s bucket=%s access_key=%s remote=%s user_agent=%q bytes_in=%d bytes_out=%d",
+			r.Method, redactURL(r.URL), lw.status, end.Sub(start).Milliseconds(), reqID, trace,
+			bucket, extractAccessKey(r), clientIP(r.RemoteAddr), r.UserAgent(), bytesIn, lw.bytes)
 	})
 }
 
+// writeAccessLogJSON writes entry as a single line, so concurrent requests
+// never interleave within a line.
+func writeAccessLogJSON(entry accessLogEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("level=warn access log: %v", err)
+		return
+	}
+	_, _ = log.Writer().Write(append(data, '\n'))
+}
+
 type loggingWriter struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
 }
 
 func (w *loggingWriter) WriteHeader(code int) {
@@ -33,6 +167,12 @@ func (w *loggingWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+func (w *loggingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
 func redactURL(u *url.URL) string {
 	if u == nil {
 		return ""
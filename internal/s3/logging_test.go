@@ -1,6 +1,10 @@
 package s3
 
 import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
@@ -28,3 +32,93 @@ func containsAll(s string, parts []string) bool {
 	}
 	return true
 }
+
+func TestTraceIDEchoedAndLogged(t *testing.T) {
+	buf := captureLog(t)
+	h := newTestHandler(t)
+	handler := LoggingMiddleware(h, nil, LoggingOptions{})
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		value  string
+		want   string
+	}{
+		{name: "trace header", header: traceIDHeader, value: "trace-123", want: "trace-123"},
+		{name: "client request id", header: "x-amz-request-id", value: "client-req-1", want: "client-req-1"},
+		{name: "invalid", header: traceIDHeader, value: "bad value\nforged=1", want: ""},
+	} {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(tc.header, tc.value)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if got := w.Header().Get(traceIDHeader); got != tc.want {
+			t.Fatalf("%s: echoed trace id %q, want %q", tc.name, got, tc.want)
+		}
+		reqID := w.Header().Get("x-amz-request-id")
+		if reqID == "" || reqID == tc.value {
+			t.Fatalf("%s: request id %q must be server generated", tc.name, reqID)
+		}
+		line := buf.String()
+		if !strings.Contains(line, "req_id="+reqID) {
+			t.Fatalf("%s: log %q missing request id", tc.name, line)
+		}
+		if tc.want != "" && !strings.Contains(line, "trace_id="+tc.want) {
+			t.Fatalf("%s: log %q missing trace id", tc.name, line)
+		}
+		if tc.want == "" && strings.Contains(line, "trace_id=") {
+			t.Fatalf("%s: log %q has unexpected trace id", tc.name, line)
+		}
+	}
+}
+
+func TestLoggingMiddlewareJSON(t *testing.T) {
+	buf := captureLog(t)
+	log.SetFlags(log.LstdFlags)
+	h := newTestHandler(t)
+	putObject(t, h, "bucket", "dir/key", "hello")
+	handler := LoggingMiddleware(h, nil, LoggingOptions{Format: LogFormatJSON, Describe: h.DescribeRequest})
+
+	buf.Reset()
+	req := httptest.NewRequest(http.MethodPut, "/bucket/dir/other?X-Amz-Signature=secret", strings.NewReader("payload"))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260101/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=deadbeef")
+	req.RemoteAddr = "192.0.2.7:5555"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	buf.Reset()
+	get := httptest.NewRequest(http.MethodGet, "/bucket/dir/key", nil)
+	get.RemoteAddr = "192.0.2.8:5555"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, get)
+
+	line := buf.String()
+	if strings.Count(line, "\n") != 1 || !strings.HasPrefix(line, "{") {
+		t.Fatalf("expected one JSON line without log prefix, got %q", line)
+	}
+	var entry accessLogEntry
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if entry.Op != "get" || entry.Bucket != "bucket" || entry.Key != "dir/key" || entry.Status != http.StatusOK ||
+		entry.BytesOut != 5 || entry.SourceIP != "192.0.2.8" || entry.RequestID != w.Header().Get("x-amz-request-id") || entry.TS == "" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	buf.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), req.Clone(req.Context()))
+	line = buf.String()
+	if strings.Contains(line, "deadbeef") || strings.Contains(line, "secret") || !strings.Contains(line, "X-Amz-Signature=REDACTED") || !strings.Contains(line, `"access_key":"AKIDEXAMPLE"`) {
+		t.Fatalf("credentials not redacted: %q", line)
+	}
+}
+
+func TestParseLogFormat(t *testing.T) {
+	for raw, want := range map[string]string{"": LogFormatText, "text": LogFormatText, "json": LogFormatJSON} {
+		if got, err := ParseLogFormat(raw); err != nil || got != want {
+			t.Fatalf("ParseLogFormat(%q) = %q, %v", raw, got, err)
+		}
+	}
+	if _, err := ParseLogFormat("combined"); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
@@ -1,6 +1,7 @@
 package s3
 
 import (
+	"math"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -24,12 +25,50 @@ type Metrics struct {
 	keyRequests map[string]map[string]int64
 	keyLatency  map[string]*latencyWindow
 
+	accessKeyMu       sync.Mutex
+	accessKeyRequests map[string]map[string]int64
+
 	bytesIn        atomic.Int64
 	bytesOut       atomic.Int64
 	replayDetected atomic.Int64
+	metaBusyRetry  atomic.Int64
 
 	maintMu          sync.Mutex
 	maintTransitions map[string]int64
+
+	slowDownMu sync.Mutex
+	slowDown   map[string]*slowDownState
+
+	notifyMu      sync.Mutex
+	notifications map[string]int64
+
+	// since is when counting started: process start or the last reset
+	// (guarded by requestsMu).
+	since time.Time
+}
+
+const (
+	// slowDownHalfLife is how fast an op's SlowDown score decays.
+	slowDownHalfLife = 2 * time.Second
+	// slowDownResetAfter clears the score once an op has gone this long
+	// without a rejection.
+	slowDownResetAfter = 10 * time.Second
+	// maxRetryAfter bounds the Retry-After hint sent with SlowDown.
+	maxRetryAfter = 16 * time.Second
+)
+
+// slowDownState is a decaying count of recent SlowDown rejections for an op.
+type slowDownState struct {
+	score float64
+	last  time.Time
+	total int64
+}
+
+// SlowDownStats reports limiter rejections for one op and the Retry-After
+// hint the next rejection would get.
+type SlowDownStats struct {
+	Total             int64 `json:"total"`
+	RetryAfterSeconds int64 `json:"retry_after_seconds"`
 }
 
 type latencyWindow struct {
@@ -50,14 +89,18 @@ type LatencyStats struct {
 // NewMetrics creates a Metrics instance.
 func NewMetrics() *Metrics {
 	return &Metrics{
-		requests:         make(map[string]map[string]int64),
-		inflight:         make(map[string]int64),
-		latency:          make(map[string]*latencyWindow),
-		bucketRequests:   make(map[string]map[string]int64),
-		bucketLatency:    make(map[string]*latencyWindow),
-		keyRequests:      make(map[string]map[string]int64),
-		keyLatency:       make(map[string]*latencyWindow),
-		maintTransitions: make(map[string]int64),
+		requests:          make(map[string]map[string]int64),
+		inflight:          make(map[string]int64),
+		latency:           make(map[string]*latencyWindow),
+		bucketRequests:    make(map[string]map[string]int64),
+		bucketLatency:     make(map[string]*latencyWindow),
+		keyRequests:       make(map[string]map[string]int64),
+		keyLatency:        make(map[string]*latencyWindow),
+		accessKeyRequests: make(map[string]map[string]int64),
+		maintTransitions:  make(map[string]int64),
+		slowDown:          make(map[string]*slowDownState),
+		notifications:     make(map[string]int64),
+		since:             time.Now().UTC(),
 	}
 }
 
@@ -102,6 +145,22 @@ func (m *Metrics) IncReplayDetected() {
 	m.replayDetected.Add(1)
 }
 
+// IncMetaBusyRetry counts a meta.db transaction retried after SQLite BUSY.
+func (m *Metrics) IncMetaBusyRetry() {
+	if m == nil {
+		return
+	}
+	m.metaBusyRetry.Add(1)
+}
+
+// MetaBusyRetries returns the number of meta.db busy retries so far.
+func (m *Metrics) MetaBusyRetries() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.metaBusyRetry.Load()
+}
+
 func (m *Metrics) IncMaintenanceTransition(state string) {
 	if m == nil || state == "" {
 		return
@@ -111,6 +170,97 @@ func (m *Metrics) IncMaintenanceTransition(state string) {
 	m.maintMu.Unlock()
 }
 
+// IncNotification counts a bucket notification delivery outcome
+// (delivered, failed or dropped).
+func (m *Metrics) IncNotification(result string) {
+	if m == nil || result == "" {
+		return
+	}
+	m.notifyMu.Lock()
+	m.notifications[result]++
+	m.notifyMu.Unlock()
+}
+
+// Notifications returns bucket notification delivery counts by outcome.
+func (m *Metrics) Notifications() map[string]int64 {
+	if m == nil {
+		return nil
+	}
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+	if len(m.notifications) == 0 {
+		return nil
+	}
+	out := make(map[string]int64, len(m.notifications))
+	for result, v := range m.notifications {
+		out[result] = v
+	}
+	return out
+}
+
+// ObserveSlowDown records a SlowDown rejection for op and returns the
+// Retry-After hint: 1s, doubling as the op's recent rejection rate grows, up
+// to maxRetryAfter, and back to 1s once rejections stop.
+func (m *Metrics) ObserveSlowDown(op string, now time.Time) time.Duration {
+	if m == nil {
+		return time.Second
+	}
+	m.slowDownMu.Lock()
+	defer m.slowDownMu.Unlock()
+	st := m.slowDown[op]
+	if st == nil {
+		st = &slowDownState{}
+		m.slowDown[op] = st
+	}
+	st.score = st.decayed(now) + 1
+	st.last = now
+	st.total++
+	return retryAfterForScore(st.score)
+}
+
+// SlowDowns returns per-op SlowDown totals and current Retry-After hints.
+func (m *Metrics) SlowDowns(now time.Time) map[string]SlowDownStats {
+	if m == nil {
+		return nil
+	}
+	m.slowDownMu.Lock()
+	defer m.slowDownMu.Unlock()
+	if len(m.slowDown) == 0 {
+		return nil
+	}
+	out := make(map[string]SlowDownStats, len(m.slowDown))
+	for op, st := range m.slowDown {
+		out[op] = SlowDownStats{
+			Total:             st.total,
+			RetryAfterSeconds: int64(retryAfterForScore(st.decayed(now)+1) / time.Second),
+		}
+	}
+	return out
+}
+
+func (st *slowDownState) decayed(now time.Time) float64 {
+	if st.last.IsZero() {
+		return 0
+	}
+	elapsed := now.Sub(st.last)
+	if elapsed >= slowDownResetAfter {
+		return 0
+	}
+	if elapsed <= 0 {
+		return st.score
+	}
+	return st.score * math.Exp2(-elapsed.Seconds()/slowDownHalfLife.Seconds())
+}
+
+// retryAfterForScore doubles a 1s hint for every doubling of the score.
+func retryAfterForScore(score float64) time.Duration {
+	retry := time.Second
+	for ; score >= 2 && retry < maxRetryAfter; score /= 2 {
+		retry *= 2
+	}
+	return min(retry, maxRetryAfter)
+}
+
 func (m *Metrics) Record(op string, status int, dur time.Duration, bucketName, key string) {
 	if m == nil {
 		return
@@ -118,10 +268,7 @@ func (m *Metrics) Record(op string, status int, dur time.Duration, bucketName, k
 	if op == "" {
 		op = "unknown"
 	}
-	class := "0xx"
-	if status > 0 {
-		class = string([]byte{byte('0' + status/100), 'x', 'x'})
-	}
+	class := statusClass(status)
 	m.requestsMu.Lock()
 	byClass := m.requests[op]
 	if byClass == nil {
@@ -161,6 +308,34 @@ func (m *Metrics) Record(op string, status int, dur time.Duration, bucketName, k
 	}
 }
 
+// RecordAccessKey counts an authenticated request against its access key.
+func (m *Metrics) RecordAccessKey(accessKey string, status int) {
+	if m == nil || accessKey == "" {
+		return
+	}
+	m.accessKeyMu.Lock()
+	updateClassMap(m.accessKeyRequests, accessKey, statusClass(status), 1000)
+	m.accessKeyMu.Unlock()
+}
+
+// AccessKeyRequests returns request counts by status class per access key.
+func (m *Metrics) AccessKeyRequests() map[string]map[string]int64 {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]map[string]int64)
+	m.accessKeyMu.Lock()
+	for accessKey, byClass := range m.accessKeyRequests {
+		copyClass := make(map[string]int64, len(byClass))
+		for class, v := range byClass {
+			copyClass[class] = v
+		}
+		out[accessKey] = copyClass
+	}
+	m.accessKeyMu.Unlock()
+	return out
+}
+
 func (m *Metrics) Snapshot() (requests map[string]map[string]int64, inflight map[string]int64, bytesIn, bytesOut int64, replayDetected int64, latency map[string]LatencyStats, bucketReqs map[string]map[string]int64, bucketLatency map[string]LatencyStats, keyReqs map[string]map[string]int64, keyLatency map[string]LatencyStats, maintTransitions map[string]int64) {
 	if m == nil {
 		return nil, nil, 0, 0, 0, nil, nil, nil, nil, nil, nil
@@ -234,6 +409,137 @@ func (m *Metrics) Snapshot() (requests map[string]map[string]int64, inflight map
 	return requests, inflight, bytesIn, bytesOut, replayDetected, latency, bucketReqs, bucketLatency, keyReqs, keyLatency, maintTransitions
 }
 
+// MetricsSnapshot is a point-in-time copy of all counters, served by
+// /v1/ops/metrics. Counters cover Since..TakenAt.
+type MetricsSnapshot struct {
+	Since                  time.Time                   `json:"since"`
+	TakenAt                time.Time                   `json:"taken_at"`
+	Reset                  bool                        `json:"reset"`
+	RequestsTotal          map[string]map[string]int64 `json:"requests_total"`
+	Inflight               map[string]int64            `json:"inflight"`
+	BytesInTotal           int64                       `json:"bytes_in_total"`
+	BytesOutTotal          int64                       `json:"bytes_out_total"`
+	ReplayDetected         int64                       `json:"replay_detected"`
+	MetaBusyRetries        int64                       `json:"meta_busy_retries"`
+	LatencyMs              map[string]LatencyStats     `json:"latency_ms"`
+	RequestsByBucket       map[string]map[string]int64 `json:"requests_total_by_bucket"`
+	LatencyByBucketMs      map[string]LatencyStats     `json:"latency_ms_by_bucket"`
+	RequestsByKey          map[string]map[string]int64 `json:"requests_total_by_key"`
+	LatencyByKeyMs         map[string]LatencyStats     `json:"latency_ms_by_key"`
+	RequestsByAccessKey    map[string]map[string]int64 `json:"requests_total_by_access_key"`
+	MaintenanceTransitions map[string]int64            `json:"maintenance_transitions"`
+	SlowDownTotal          map[string]int64            `json:"slow_down_total"`
+	Notifications          map[string]int64            `json:"notifications"`
+}
+
+// TakeSnapshot copies every counter while holding all metric locks, so the
+// values are consistent with each other. With reset the cumulative counters
+// are zeroed in the same critical section and Since moves to now; gauges
+// (inflight), latency windows and SlowDown backoff scores are kept.
+func (m *Metrics) TakeSnapshot(now time.Time, reset bool) MetricsSnapshot {
+	if m == nil {
+		return MetricsSnapshot{TakenAt: now, Reset: reset}
+	}
+	m.requestsMu.Lock()
+	defer m.requestsMu.Unlock()
+	m.inflightMu.Lock()
+	defer m.inflightMu.Unlock()
+	m.latencyMu.Lock()
+	defer m.latencyMu.Unlock()
+	m.bucketMu.Lock()
+	defer m.bucketMu.Unlock()
+	m.keyMu.Lock()
+	defer m.keyMu.Unlock()
+	m.accessKeyMu.Lock()
+	defer m.accessKeyMu.Unlock()
+	m.maintMu.Lock()
+	defer m.maintMu.Unlock()
+	m.slowDownMu.Lock()
+	defer m.slowDownMu.Unlock()
+	m.notifyMu.Lock()
+	defer m.notifyMu.Unlock()
+
+	snap := MetricsSnapshot{
+		Since:                  m.since,
+		TakenAt:                now,
+		Reset:                  reset,
+		RequestsTotal:          copyClassMaps(m.requests),
+		Inflight:               make(map[string]int64, len(m.inflight)),
+		LatencyMs:              snapshotLatencyWindows(m.latency),
+		RequestsByBucket:       copyClassMaps(m.bucketRequests),
+		LatencyByBucketMs:      snapshotLatencyWindows(m.bucketLatency),
+		RequestsByKey:          copyClassMaps(m.keyRequests),
+		LatencyByKeyMs:         snapshotLatencyWindows(m.keyLatency),
+		RequestsByAccessKey:    copyClassMaps(m.accessKeyRequests),
+		MaintenanceTransitions: make(map[string]int64, len(m.maintTransitions)),
+		SlowDownTotal:          make(map[string]int64, len(m.slowDown)),
+		Notifications:          make(map[string]int64, len(m.notifications)),
+	}
+	for op, v := range m.inflight {
+		snap.Inflight[op] = v
+	}
+	for state, v := range m.maintTransitions {
+		snap.MaintenanceTransitions[state] = v
+	}
+	for op, st := range m.slowDown {
+		snap.SlowDownTotal[op] = st.total
+	}
+	for result, v := range m.notifications {
+		snap.Notifications[result] = v
+	}
+	if !reset {
+		snap.BytesInTotal = m.bytesIn.Load()
+		snap.BytesOutTotal = m.bytesOut.Load()
+		snap.ReplayDetected = m.replayDetected.Load()
+		snap.MetaBusyRetries = m.metaBusyRetry.Load()
+		return snap
+	}
+	// The byte counters are updated without a lock; Swap keeps every
+	// increment in exactly one interval.
+	snap.BytesInTotal = m.bytesIn.Swap(0)
+	snap.BytesOutTotal = m.bytesOut.Swap(0)
+	snap.ReplayDetected = m.replayDetected.Swap(0)
+	snap.MetaBusyRetries = m.metaBusyRetry.Swap(0)
+	m.requests = make(map[string]map[string]int64)
+	m.bucketRequests = make(map[string]map[string]int64)
+	m.keyRequests = make(map[string]map[string]int64)
+	m.accessKeyRequests = make(map[string]map[string]int64)
+	m.maintTransitions = make(map[string]int64)
+	m.notifications = make(map[string]int64)
+	for _, st := range m.slowDown {
+		st.total = 0
+	}
+	m.since = now
+	return snap
+}
+
+func copyClassMaps(src map[string]map[string]int64) map[string]map[string]int64 {
+	out := make(map[string]map[string]int64, len(src))
+	for name, byClass := range src {
+		copyClass := make(map[string]int64, len(byClass))
+		for class, v := range byClass {
+			copyClass[class] = v
+		}
+		out[name] = copyClass
+	}
+	return out
+}
+
+func snapshotLatencyWindows(src map[string]*latencyWindow) map[string]LatencyStats {
+	out := make(map[string]LatencyStats, len(src))
+	for name, window := range src {
+		out[name] = window.snapshot()
+	}
+	return out
+}
+
+func statusClass(status int) string {
+	if status <= 0 {
+		return "0xx"
+	}
+	return string([]byte{byte('0' + status/100), 'x', 'x'})
+}
+
 func updateClassMap(target map[string]map[string]int64, key, class string, limit int) {
 	if key == "" {
 		return
@@ -1,6 +1,9 @@
 package s3
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"testing"
 	"time"
@@ -57,3 +60,99 @@ func TestMetricsBucketKeyLimits(t *testing.T) {
 		t.Fatalf("expected key-0 to be updated")
 	}
 }
+
+func TestObserveSlowDownBackoffIsBoundedAndResets(t *testing.T) {
+	m := NewMetrics()
+	now := time.Unix(1700000000, 0)
+	want := []time.Duration{time.Second, 2 * time.Second, 2 * time.Second, 4 * time.Second}
+	for i, w := range want {
+		if got := m.ObserveSlowDown("put", now); got != w {
+			t.Fatalf("rejection %d: retry-after=%s want %s", i+1, got, w)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		m.ObserveSlowDown("put", now)
+	}
+	if got := m.ObserveSlowDown("put", now); got != maxRetryAfter {
+		t.Fatalf("retry-after=%s want cap %s", got, maxRetryAfter)
+	}
+	// Other ops keep their own score.
+	if got := m.ObserveSlowDown("get", now); got != time.Second {
+		t.Fatalf("get retry-after=%s want 1s", got)
+	}
+	// The score decays while rejections slow down...
+	if got := m.ObserveSlowDown("put", now.Add(4*slowDownHalfLife)); got >= maxRetryAfter {
+		t.Fatalf("retry-after=%s did not decay", got)
+	}
+	// ...and resets once contention has cleared.
+	later := now.Add(4*slowDownHalfLife + slowDownResetAfter)
+	stats := m.SlowDowns(later)
+	if stats["put"].Total != 106 || stats["put"].RetryAfterSeconds != 1 {
+		t.Fatalf("put stats=%+v", stats["put"])
+	}
+	if got := m.ObserveSlowDown("put", later); got != time.Second {
+		t.Fatalf("retry-after after quiet period=%s want 1s", got)
+	}
+}
+
+func TestMetricsTakeSnapshotResetKeepsGauges(t *testing.T) {
+	m := NewMetrics()
+	m.Record("get", 200, time.Millisecond, "bucket", "key")
+	m.RecordAccessKey("AKIA", 200)
+	m.AddBytesOut(42)
+	m.InflightInc("put")
+	m.ObserveSlowDown("put", time.Unix(1700000000, 0))
+
+	now := time.Unix(1700000100, 0).UTC()
+	snap := m.TakeSnapshot(now, true)
+	if !snap.Reset || snap.RequestsTotal["get"]["2xx"] != 1 || snap.BytesOutTotal != 42 || snap.Inflight["put"] != 1 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+	if snap.RequestsByAccessKey["AKIA"]["2xx"] != 1 || snap.SlowDownTotal["put"] != 1 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	after := m.TakeSnapshot(now.Add(time.Minute), false)
+	if !after.Since.Equal(now) {
+		t.Fatalf("since=%s want %s", after.Since, now)
+	}
+	if len(after.RequestsTotal) != 0 || len(after.RequestsByBucket) != 0 || len(after.RequestsByAccessKey) != 0 || after.BytesOutTotal != 0 || after.SlowDownTotal["put"] != 0 {
+		t.Fatalf("counters not reset: %+v", after)
+	}
+	if after.Inflight["put"] != 1 || after.LatencyMs["get"].N != 1 {
+		t.Fatalf("gauges or latency lost on reset: %+v", after)
+	}
+}
+
+func TestOpsMetricsEndpoint(t *testing.T) {
+	h := newTestHandler(t)
+	h.Metrics = NewMetrics()
+	h.Metrics.Record("get", 200, time.Millisecond, "bucket", "key")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/ops/metrics/reset", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reset status: %d", rec.Code)
+	}
+	var snap MetricsSnapshot
+	if err := json.NewDecoder(rec.Body).Decode(&snap); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !snap.Reset || snap.RequestsTotal["get"]["2xx"] != 1 {
+		t.Fatalf("unexpected reset snapshot: %+v", snap)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/ops/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("snapshot status: %d", rec.Code)
+	}
+	snap = MetricsSnapshot{}
+	if err := json.NewDecoder(rec.Body).Decode(&snap); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// Only the reset request itself has been counted since.
+	if snap.Reset || snap.RequestsTotal["get"] != nil || snap.RequestsTotal["ops_metrics_reset"]["2xx"] != 1 {
+		t.Fatalf("unexpected snapshot after reset: %+v", snap.RequestsTotal)
+	}
+}
@@ -0,0 +1,175 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+)
+
+// moveSourceHeader turns a PUT into a server-side move of "bucket/key", in
+// the same format as X-Amz-Copy-Source.
+const moveSourceHeader = "X-Seglake-Move-Source"
+
+var errMoveSourceChanged = fmt.Errorf("%w: move source changed", engine.ErrPreconditionFailed)
+
+// handleMoveObject repoints the source object's manifest to bucket/key and
+// retires the source in the same commit transaction. No chunk data is
+// rewritten; the destination gets a new version that shares the source chunks.
+func (h *Handler) handleMoveObject(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, moveSource, requestID string) {
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "move source and copy source are exclusive", requestID, r.URL.Path)
+		return
+	}
+	srcBucket, srcKey, ok := parseCopySource(moveSource)
+	if !ok {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "invalid move source", requestID, r.URL.Path)
+		return
+	}
+	if srcBucket == bucket && srcKey == key {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "move source and destination are the same object", requestID, r.URL.Path)
+		return
+	}
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	if !h.enforceIfMatch(ctx, w, r, bucket, key, requestID) {
+		return
+	}
+	exists, err := h.Meta.BucketExists(ctx, srcBucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if !exists {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+		return
+	}
+	srcMeta, err := h.Meta.GetObjectMeta(ctx, srcBucket, srcKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "key not found", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if strings.EqualFold(srcMeta.State, meta.VersionStateDeleteMarker) || srcMeta.Expired(h.now()) {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "key not found", requestID, r.URL.Path)
+		return
+	}
+	if strings.EqualFold(srcMeta.State, meta.VersionStateDamaged) {
+		w.Header().Set("X-Error", "DamagedObject")
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "object damaged", requestID, r.URL.Path)
+		return
+	}
+	man, err := h.Engine.GetManifest(ctx, srcMeta.VersionID)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	srcState, err := h.bucketVersioningState(ctx, srcBucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	versioningState, err := h.bucketVersioningState(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	// The destination keeps the source's TTL rather than becoming permanent.
+	var expiresAt time.Time
+	if srcMeta.ExpiresAt != "" {
+		expiresAt, err = time.Parse(meta.ExpiryTimeFormat, srcMeta.ExpiresAt)
+		if err != nil {
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+	}
+	check := combineChecks(h.ifMatchCheck(ctx, r, bucket, key), h.moveSourceCheck(ctx, srcBucket, srcKey, srcMeta.VersionID))
+	var markerVersion string
+	_, result, err := h.Engine.PutManifestWithCheck(ctx, bucket, key, srcMeta.ContentType, man.Size, srcMeta.ETag, man.Chunks, check, func(tx *sql.Tx, result *engine.PutResult, _ string) error {
+		if class := meta.StorageClassOrStandard(srcMeta.StorageClass); class != meta.StorageClassStandard {
+			if err := h.Meta.SetVersionStorageClassTx(tx, result.VersionID, class); err != nil {
+				return err
+			}
+		}
+		if !expiresAt.IsZero() {
+			if err := h.Meta.SetVersionExpiryTx(tx, result.VersionID, expiresAt); err != nil {
+				return err
+			}
+		}
+		userMetadata, tags, err := h.Meta.VersionMetadataTx(ctx, tx, srcMeta.VersionID)
+		if err != nil {
+			return err
+		}
+		if len(userMetadata) > 0 || len(tags) > 0 {
+			if err := h.Meta.SetVersionMetadataTx(tx, result.VersionID, userMetadata, tags); err != nil {
+				return err
+			}
+		}
+		var derr error
+		markerVersion, derr = h.Meta.MoveObjectTx(ctx, tx, srcBucket, srcKey, srcState != meta.BucketVersioningDisabled)
+		return derr
+	})
+	if err != nil {
+		if errors.Is(err, errMoveSourceChanged) {
+			writeErrorWithResource(w, http.StatusConflict, "OperationAborted", "move source changed", requestID, r.URL.Path)
+			return
+		}
+		if errors.Is(err, engine.ErrPreconditionFailed) {
+			writeErrorWithResource(w, http.StatusPreconditionFailed, "PreconditionFailed", "if-match failed", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if result == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "move result missing", requestID, r.URL.Path)
+		return
+	}
+	w.Header().Set("ETag", `"`+result.ETag+`"`)
+	if versionID, ok := versionIDHeaderForPut(versioningState, result.VersionID); ok {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectCreatedCopy, putResultObject(result))
+	if markerVersion != "" {
+		h.notifyEvent(ctx, r, srcBucket, srcKey, requestID, eventObjectRemovedMarker, notifyObject{VersionID: markerVersion, At: result.CommittedAt})
+	} else {
+		h.notifyEvent(ctx, r, srcBucket, srcKey, requestID, eventObjectRemovedDelete, notifyObject{VersionID: srcMeta.VersionID, At: result.CommittedAt})
+	}
+	resp := copyObjectResult{
+		ETag:         `"` + result.ETag + `"`,
+		LastModified: result.CommittedAt.UTC().Format(time.RFC3339),
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(resp)
+}
+
+// moveSourceCheck rejects the move in the commit transaction when the source
+// is no longer the version whose manifest is being moved.
+func (h *Handler) moveSourceCheck(ctx context.Context, bucket, key, versionID string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		metaObj, err := h.Meta.GetObjectMetaTx(ctx, tx, bucket, key)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errMoveSourceChanged
+			}
+			return err
+		}
+		if metaObj.VersionID != versionID || strings.EqualFold(metaObj.State, meta.VersionStateDeleteMarker) {
+			return errMoveSourceChanged
+		}
+		return nil
+	}
+}
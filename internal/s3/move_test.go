@@ -0,0 +1,218 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/clock"
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+func moveRequest(t *testing.T, h *Handler, dst, src string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPut, dst, nil)
+	req.Header.Set(moveSourceHeader, src)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestMoveObjectReusesManifestAndReplicates(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	putReq := httptest.NewRequest(http.MethodPut, "/bucket/src", strings.NewReader("hello move"))
+	putReq.Header.Set("x-amz-meta-owner", "ops")
+	putReq.Header.Set("x-amz-tagging", "team=storage")
+	putW := httptest.NewRecorder()
+	h.ServeHTTP(putW, putReq)
+	if putW.Code != http.StatusOK {
+		t.Fatalf("PUT src: %d %s", putW.Code, putW.Body.String())
+	}
+	if err := h.Meta.SetBucketVersioningState(ctx, "bucket", meta.BucketVersioningDisabled); err != nil {
+		t.Fatalf("SetBucketVersioningState: %v", err)
+	}
+	srcMeta, err := h.Meta.GetObjectMeta(ctx, "bucket", "src")
+	if err != nil {
+		t.Fatalf("GetObjectMeta src: %v", err)
+	}
+	srcMan, err := h.Engine.GetManifest(ctx, srcMeta.VersionID)
+	if err != nil {
+		t.Fatalf("GetManifest src: %v", err)
+	}
+	before, err := h.Meta.ListOplogSince(ctx, "", 100)
+	if err != nil {
+		t.Fatalf("ListOplogSince: %v", err)
+	}
+
+	w := moveRequest(t, h, "/bucket/dst", "/bucket/src", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("move status=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("ETag"); got != `"`+srcMeta.ETag+`"` {
+		t.Fatalf("etag=%s want %s", got, srcMeta.ETag)
+	}
+
+	getReq := httptest.NewRequest(http.MethodGet, "/bucket/dst", nil)
+	getW := httptest.NewRecorder()
+	h.ServeHTTP(getW, getReq)
+	if getW.Code != http.StatusOK || getW.Body.String() != "hello move" {
+		t.Fatalf("GET dst status=%d body=%q", getW.Code, getW.Body.String())
+	}
+	if getW.Header().Get("x-amz-meta-owner") != "ops" || getW.Header().Get("x-amz-tagging-count") != "1" {
+		t.Fatalf("GET dst lost metadata or tags: %v", getW.Header())
+	}
+	srcReq := httptest.NewRequest(http.MethodGet, "/bucket/src", nil)
+	srcW := httptest.NewRecorder()
+	h.ServeHTTP(srcW, srcReq)
+	if srcW.Code != http.StatusNotFound {
+		t.Fatalf("GET src status=%d", srcW.Code)
+	}
+
+	dstMeta, err := h.Meta.GetObjectMeta(ctx, "bucket", "dst")
+	if err != nil {
+		t.Fatalf("GetObjectMeta dst: %v", err)
+	}
+	dstMan, err := h.Engine.GetManifest(ctx, dstMeta.VersionID)
+	if err != nil {
+		t.Fatalf("GetManifest dst: %v", err)
+	}
+	if len(dstMan.Chunks) != len(srcMan.Chunks) {
+		t.Fatalf("chunks=%d want %d", len(dstMan.Chunks), len(srcMan.Chunks))
+	}
+	for i := range srcMan.Chunks {
+		if dstMan.Chunks[i] != srcMan.Chunks[i] {
+			t.Fatalf("chunk %d rewritten: %+v vs %+v", i, dstMan.Chunks[i], srcMan.Chunks[i])
+		}
+	}
+
+	after, err := h.Meta.ListOplogSince(ctx, before[len(before)-1].HLCTS, 100)
+	if err != nil {
+		t.Fatalf("ListOplogSince: %v", err)
+	}
+	if len(after) != 2 {
+		t.Fatalf("oplog entries=%d want 2", len(after))
+	}
+	if after[0].OpType != "put" || after[0].Key != "dst" || after[1].OpType != "delete" || after[1].Key != "src" {
+		t.Fatalf("oplog=%+v", after)
+	}
+
+	replica, err := meta.Open(filepath.Join(t.TempDir(), "replica.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = replica.Close() })
+	if _, err := replica.ApplyOplogEntries(ctx, append(before, after...)); err != nil {
+		t.Fatalf("ApplyOplogEntries: %v", err)
+	}
+	replDst, err := replica.GetObjectMeta(ctx, "bucket", "dst")
+	if err != nil {
+		t.Fatalf("replica dst: %v", err)
+	}
+	if replDst.VersionID != dstMeta.VersionID {
+		t.Fatalf("replica dst version=%s want %s", replDst.VersionID, dstMeta.VersionID)
+	}
+	replMetadata, replTags, err := replica.VersionMetadata(ctx, replDst.VersionID)
+	if err != nil {
+		t.Fatalf("replica VersionMetadata: %v", err)
+	}
+	if replMetadata["owner"] != "ops" || replTags["team"] != "storage" {
+		t.Fatalf("replica dst metadata=%v tags=%v", replMetadata, replTags)
+	}
+	if replSrc, err := replica.GetObjectMeta(ctx, "bucket", "src"); err == nil && replSrc.State != meta.VersionStateDeleteMarker {
+		t.Fatalf("replica src still live: %+v", replSrc)
+	}
+}
+
+func TestMoveObjectVersionedSourceLeavesDeleteMarker(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	putObject(t, h, "bucket", "src", "versioned")
+	if err := h.Meta.SetBucketVersioningState(ctx, "bucket", meta.BucketVersioningEnabled); err != nil {
+		t.Fatalf("SetBucketVersioningState: %v", err)
+	}
+	srcMeta, err := h.Meta.GetObjectMeta(ctx, "bucket", "src")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	w := moveRequest(t, h, "/bucket/dst", "/bucket/src", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("move status=%d body=%s", w.Code, w.Body.String())
+	}
+	current, err := h.Meta.GetObjectMeta(ctx, "bucket", "src")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if current.State != meta.VersionStateDeleteMarker {
+		t.Fatalf("src state=%s want delete marker", current.State)
+	}
+	if _, err := h.Meta.GetObjectVersion(ctx, "bucket", "src", srcMeta.VersionID); err != nil {
+		t.Fatalf("source version lost: %v", err)
+	}
+}
+
+func TestMoveObjectKeepsTTL(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	h.Clock = clock.FixedClock{T: start}
+	req := httptest.NewRequest(http.MethodPut, "/bucket/src", strings.NewReader("temp"))
+	req.Header.Set(ttlHeader, "60")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", w.Code, w.Body.String())
+	}
+	if w := moveRequest(t, h, "/bucket/dst", "/bucket/src", nil); w.Code != http.StatusOK {
+		t.Fatalf("move status=%d body=%s", w.Code, w.Body.String())
+	}
+	dstMeta, err := h.Meta.GetObjectMeta(ctx, "bucket", "dst")
+	if err != nil {
+		t.Fatalf("GetObjectMeta dst: %v", err)
+	}
+	if want := start.Add(time.Minute).Format(meta.ExpiryTimeFormat); dstMeta.ExpiresAt != want {
+		t.Fatalf("dst expires_at=%q want %q", dstMeta.ExpiresAt, want)
+	}
+
+	h.Clock = clock.FixedClock{T: start.Add(time.Minute)}
+	if w := moveRequest(t, h, "/bucket/again", "/bucket/dst", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("move of expired source: status=%d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestMoveObjectRejections(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "bucket", "src", "data")
+	putObject(t, h, "bucket", "existing", "old")
+
+	cases := []struct {
+		name   string
+		dst    string
+		src    string
+		header map[string]string
+		want   int
+	}{
+		{"same object", "/bucket/src", "/bucket/src", nil, http.StatusBadRequest},
+		{"missing source", "/bucket/dst", "/bucket/nope", nil, http.StatusNotFound},
+		{"missing source bucket", "/bucket/dst", "/other/src", nil, http.StatusNotFound},
+		{"with copy source", "/bucket/dst", "/bucket/src", map[string]string{"X-Amz-Copy-Source": "/bucket/src"}, http.StatusBadRequest},
+		{"destination if-match mismatch", "/bucket/existing", "/bucket/src", map[string]string{"If-Match": `"nope"`}, http.StatusPreconditionFailed},
+	}
+	for _, tc := range cases {
+		w := moveRequest(t, h, tc.dst, tc.src, tc.header)
+		if w.Code != tc.want {
+			t.Fatalf("%s: status=%d want %d body=%s", tc.name, w.Code, tc.want, w.Body.String())
+		}
+	}
+	// Nothing above may have retired the source.
+	if _, err := h.Meta.GetObjectMeta(context.Background(), "bucket", "src"); err != nil {
+		t.Fatalf("source gone after rejected moves: %v", err)
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"sort"
 	"strconv"
 	"strings"
 
@@ -44,11 +43,18 @@ type completePartItem struct {
 }
 
 type listPartsResult struct {
-	XMLName  xml.Name          `xml:"ListPartsResult"`
-	Bucket   string            `xml:"Bucket"`
-	Key      string            `xml:"Key"`
-	UploadID string            `xml:"UploadId"`
-	Parts    []listPartContent `xml:"Part"`
+	XMLName              xml.Name          `xml:"ListPartsResult"`
+	Bucket               string            `xml:"Bucket"`
+	Key                  string            `xml:"Key"`
+	UploadID             string            `xml:"UploadId"`
+	Initiator            owner             `xml:"Initiator"`
+	Owner                owner             `xml:"Owner"`
+	StorageClass         string            `xml:"StorageClass"`
+	PartNumberMarker     int               `xml:"PartNumberMarker"`
+	NextPartNumberMarker int               `xml:"NextPartNumberMarker,omitempty"`
+	MaxParts             int               `xml:"MaxParts"`
+	IsTruncated          bool              `xml:"IsTruncated"`
+	Parts                []listPartContent `xml:"Part"`
 }
 
 type listPartContent struct {
@@ -64,16 +70,69 @@ const (
 	maxPartNumber       = 10000
 )
 
+var (
+	errTooManyParts   = fmt.Errorf("%w: too many parts", engine.ErrPreconditionFailed)
+	errTooManyUploads = fmt.Errorf("%w: too many active multipart uploads", engine.ErrPreconditionFailed)
+)
+
+// mpuMaxUploads returns the cap on ACTIVE multipart uploads in bucket: its
+// MPUMaxUploadsBuckets entry if any, else MPUMaxUploads (0 = no cap).
+func (h *Handler) mpuMaxUploads(bucket string) int {
+	if limit, ok := h.MPUMaxUploadsBuckets[bucket]; ok {
+		return limit
+	}
+	return h.MPUMaxUploads
+}
+
+// mpuMaxParts returns the configured per-upload part cap, never above
+// maxPartNumber.
+func (h *Handler) mpuMaxParts() int {
+	if h.MPUMaxParts <= 0 || h.MPUMaxParts > maxPartNumber {
+		return maxPartNumber
+	}
+	return h.MPUMaxParts
+}
+
+func (h *Handler) mpuMinPartSize() int64 {
+	if h.MPUMinPartSize <= 0 {
+		return minPartSize
+	}
+	return h.MPUMinPartSize
+}
+
 func (h *Handler) handleInitiateMultipart(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, requestID, resource string) {
+	storageClass, ok := parseStorageClass(r)
+	if !ok {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidStorageClass", "invalid storage class", requestID, resource)
+		return
+	}
 	uploadID := newRequestID() + newRequestID()
 	contentType := strings.TrimSpace(r.Header.Get("Content-Type"))
-	if err := h.Engine.CommitMeta(ctx, func(tx *sql.Tx) error {
+	maxUploads := h.mpuMaxUploads(bucket)
+	// The cap is a check, so a rejected upload does not fail the barrier batch.
+	if err := h.Engine.CommitMetaWithCheck(ctx, func(tx *sql.Tx) error {
+		if maxUploads <= 0 || h.Meta == nil {
+			return nil
+		}
+		active, err := h.Meta.CountActiveMultipartUploadsTx(ctx, tx, bucket)
+		if err != nil {
+			return err
+		}
+		if active >= maxUploads {
+			return errTooManyUploads
+		}
+		return nil
+	}, func(tx *sql.Tx) error {
 		if h.Meta == nil {
 			return fmt.Errorf("meta store not configured")
 		}
-		return h.Meta.CreateMultipartUploadTx(ctx, tx, bucket, key, uploadID, contentType)
+		return h.Meta.CreateMultipartUploadTx(ctx, tx, bucket, key, uploadID, contentType, storageClass)
 	}); err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		if errors.Is(err, errTooManyUploads) {
+			h.writeSlowDown(w, "mpu_uploads", "too many active multipart uploads in bucket", requestID, resource)
+			return
+		}
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	resp := initiateMultipartResult{
@@ -97,7 +156,19 @@ func (h *Handler) handleUploadPart(ctx context.Context, w http.ResponseWriter, r
 			writeErrorWithResource(w, http.StatusNotFound, "NoSuchUpload", "upload not found", requestID, r.URL.Path)
 			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	// Checked before the body is read and again in the commit transaction,
+	// where concurrent parts of the same upload are serialized.
+	maxParts := h.mpuMaxParts()
+	stored, err := h.Meta.CountMultipartParts(ctx, uploadID, partNumber)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if stored >= maxParts {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "too many parts", requestID, r.URL.Path)
 		return
 	}
 	contentLength, hasLength, err := contentLengthFromRequest(r)
@@ -111,7 +182,7 @@ func (h *Handler) handleUploadPart(ctx context.Context, w http.ResponseWriter, r
 		return
 	}
 	reader := io.Reader(r.Body)
-	reader, streamingMode, decodedLen, hasDecoded, reqErr := setupStreamingReader(r, reader)
+	reader, streamingMode, decodedLen, hasDecoded, reqErr := setupStreamingReader(r, reader, h.DisableTrailerChecksums)
 	if reqErr != nil {
 		writeErrorWithResource(w, reqErr.status, reqErr.code, reqErr.message, requestID, r.URL.Path)
 		return
@@ -166,7 +237,20 @@ func (h *Handler) handleUploadPart(ctx context.Context, w http.ResponseWriter, r
 	if verifyPayload || len(expectedMD5) > 0 {
 		reader = newValidatingReader(reader, payloadHash, verifyPayload, expectedMD5)
 	}
-	_, result, err := h.Engine.PutObjectWithCommit(ctx, "", "", "", reader, func(tx *sql.Tx, result *engine.PutResult, manifestPath string) error {
+	// The cap is a check, so a rejected part does not fail the barrier batch.
+	_, result, err := h.Engine.PutObjectWithCheck(ctx, "", "", "", reader, func(tx *sql.Tx) error {
+		if h.Meta == nil {
+			return nil
+		}
+		stored, err := h.Meta.CountMultipartPartsTx(ctx, tx, uploadID, partNumber)
+		if err != nil {
+			return err
+		}
+		if stored >= maxParts {
+			return errTooManyParts
+		}
+		return nil
+	}, func(tx *sql.Tx, result *engine.PutResult, manifestPath string) error {
 		if h.Meta == nil {
 			return fmt.Errorf("meta store not configured")
 		}
@@ -174,6 +258,9 @@ func (h *Handler) handleUploadPart(ctx context.Context, w http.ResponseWriter, r
 	})
 	if err != nil {
 		switch {
+		case errors.Is(err, errTooManyParts):
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "too many parts", requestID, r.URL.Path)
+			return
 		case errors.Is(err, errPayloadHashMismatch):
 			writeErrorWithResource(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "payload hash mismatch", requestID, r.URL.Path)
 			return
@@ -189,8 +276,11 @@ func (h *Handler) handleUploadPart(ctx context.Context, w http.ResponseWriter, r
 		case errors.Is(err, errEntityTooLarge):
 			writeErrorWithResource(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", "entity too large", requestID, r.URL.Path)
 			return
+		case errors.Is(err, engine.ErrInsufficientStorage):
+			writeErrorWithResource(w, http.StatusInsufficientStorage, "InsufficientStorage", "insufficient storage", requestID, r.URL.Path)
+			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	w.Header().Set("ETag", `"`+result.ETag+`"`)
@@ -204,14 +294,34 @@ func (h *Handler) handleListParts(ctx context.Context, w http.ResponseWriter, r
 			writeErrorWithResource(w, http.StatusNotFound, "NoSuchUpload", "upload not found", requestID, r.URL.Path)
 			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
-	parts, err := h.Meta.ListMultipartParts(ctx, uploadID)
+	q := r.URL.Query()
+	marker := 0
+	if raw := q.Get("part-number-marker"); raw != "" {
+		marker, err = strconv.Atoi(raw)
+		if err != nil || marker < 0 {
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid part-number-marker", requestID, r.URL.Path)
+			return
+		}
+	}
+	maxParts := parseMaxUploads(q.Get("max-parts"))
+	// Fetch one part past maxParts so a full last page is not reported as truncated.
+	parts, err := h.Meta.ListMultipartPartsAfter(ctx, uploadID, marker, maxParts+1)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
+	truncated := len(parts) > maxParts
+	if truncated {
+		parts = parts[:maxParts]
+	}
+	// Uploads do not record their initiator; report the bucket owner like ?acl.
+	partsOwner := h.defaultOwner()
+	if bucketOwner, err := h.Meta.GetBucketOwner(ctx, upload.Bucket); err == nil && bucketOwner != "" {
+		partsOwner = owner{ID: bucketOwner, DisplayName: bucketOwner}
+	}
 	out := make([]listPartContent, 0, len(parts))
 	for _, part := range parts {
 		out = append(out, listPartContent{
@@ -222,10 +332,19 @@ func (h *Handler) handleListParts(ctx context.Context, w http.ResponseWriter, r
 		})
 	}
 	resp := listPartsResult{
-		Bucket:   upload.Bucket,
-		Key:      upload.Key,
-		UploadID: uploadID,
-		Parts:    out,
+		Bucket:           upload.Bucket,
+		Key:              upload.Key,
+		UploadID:         uploadID,
+		Initiator:        partsOwner,
+		Owner:            partsOwner,
+		StorageClass:     meta.StorageClassOrStandard(upload.StorageClass),
+		PartNumberMarker: marker,
+		MaxParts:         maxParts,
+		IsTruncated:      truncated,
+		Parts:            out,
+	}
+	if len(parts) > 0 {
+		resp.NextPartNumberMarker = parts[len(parts)-1].PartNumber
 	}
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(http.StatusOK)
@@ -235,23 +354,42 @@ func (h *Handler) handleListParts(ctx context.Context, w http.ResponseWriter, r
 func (h *Handler) handleCompleteMultipart(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, uploadID string, requestID string) {
 	if h.MPUCompleteLimiter != nil {
 		if !h.MPUCompleteLimiter.Acquire() {
-			writeErrorWithResource(w, http.StatusServiceUnavailable, "SlowDown", "too many inflight multipart completes", requestID, r.URL.Path)
+			h.writeSlowDown(w, "mpu_complete", "too many inflight multipart completes", requestID, r.URL.Path)
 			return
 		}
 		defer h.MPUCompleteLimiter.Release()
 	}
+	// Claim the upload before reading it, so a retry that overlaps the
+	// original complete gets 409 and one that follows it sees COMPLETED.
+	if !h.beginMPUComplete(uploadID) {
+		writeErrorWithResource(w, http.StatusConflict, "OperationAborted", "multipart upload completion already in progress", requestID, r.URL.Path)
+		return
+	}
+	defer h.endMPUComplete(uploadID)
 	upload, err := h.Meta.GetMultipartUpload(ctx, uploadID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeErrorWithResource(w, http.StatusNotFound, "NoSuchUpload", "upload not found", requestID, r.URL.Path)
 			return
 		}
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if upload.Bucket != bucket || upload.Key != key {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchUpload", "upload not found", requestID, r.URL.Path)
+		return
+	}
+	if upload.State == "COMPLETED" {
+		h.writeCompletedMultipart(ctx, w, r, upload, requestID)
 		return
 	}
 
 	var req completeMultipartRequest
+	h.limitControlBody(w, r)
 	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		if h.writeBodyTooLarge(w, err, requestID, r.URL.Path) {
+			return
+		}
 		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid xml", requestID, r.URL.Path)
 		return
 	}
@@ -259,80 +397,54 @@ func (h *Handler) handleCompleteMultipart(ctx context.Context, w http.ResponseWr
 		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "no parts", requestID, r.URL.Path)
 		return
 	}
-	sort.Slice(req.Parts, func(i, j int) bool {
-		return req.Parts[i].PartNumber < req.Parts[j].PartNumber
-	})
-
-	parts, err := h.Meta.ListMultipartParts(ctx, uploadID)
-	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+	if code, msg := validateCompletePartOrder(req.Parts, h.MPUAllowPartGaps); code != "" {
+		writeErrorWithResource(w, http.StatusBadRequest, code, msg, requestID, r.URL.Path)
 		return
 	}
-	partMap := make(map[int]meta.MultipartPart, len(parts))
-	for _, p := range parts {
-		partMap[p.PartNumber] = p
-	}
-
-	ordered := make([]meta.MultipartPart, 0, len(req.Parts))
-	for _, p := range req.Parts {
-		part, ok := partMap[p.PartNumber]
-		if !ok {
-			writeErrorWithResource(w, http.StatusBadRequest, "InvalidPart", "missing part", requestID, r.URL.Path)
-			return
-		}
-		if normalizeETag(p.ETag) != part.ETag {
-			writeErrorWithResource(w, http.StatusBadRequest, "InvalidPart", "etag mismatch", requestID, r.URL.Path)
-			return
-		}
-		ordered = append(ordered, part)
-	}
-	if err := validatePartSizes(ordered); err != nil {
-		writeErrorWithResource(w, http.StatusBadRequest, "InvalidPart", err.Error(), requestID, r.URL.Path)
+	if len(req.Parts) > h.mpuMaxParts() {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "too many parts", requestID, r.URL.Path)
 		return
 	}
 
-	chunks := make([]manifest.ChunkRef, 0, len(ordered))
-	totalSize := int64(0)
-	chunkIndex := 0
-	for _, part := range ordered {
-		partManifest, err := h.Engine.GetManifest(ctx, part.VersionID)
-		if err != nil {
-			writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
-			return
-		}
-		if partManifest.Size != 0 && part.Size != 0 && partManifest.Size != part.Size {
-			writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "part size mismatch", requestID, r.URL.Path)
+	assembly, err := assembleMultipart(ctx, req.Parts, h.MPUMaxBufferedParts, h.mpuMinPartSize(),
+		func(ctx context.Context, afterPart, limit int) ([]meta.MultipartPart, error) {
+			return h.Meta.ListMultipartPartsAfter(ctx, uploadID, afterPart, limit)
+		}, h.Engine.GetManifest)
+	if err != nil {
+		var partErr *completePartError
+		if errors.As(err, &partErr) {
+			writeErrorWithResource(w, partErr.status, partErr.code, partErr.msg, requestID, r.URL.Path)
 			return
 		}
-		for _, ch := range partManifest.Chunks {
-			ch.Index = chunkIndex
-			chunkIndex++
-			chunks = append(chunks, ch)
-		}
-		if partManifest.Size > 0 {
-			totalSize += partManifest.Size
-		} else {
-			totalSize += part.Size
-		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
 	}
-
-	multiETag := multipartETag(ordered)
+	totalSize, chunks, multiETag := assembly.size, assembly.chunks, assembly.etag
 	versioningState, err := h.bucketVersioningState(ctx, bucket)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	_, result, err := h.Engine.PutManifestWithCommit(ctx, upload.Bucket, upload.Key, upload.ContentType, totalSize, multiETag, chunks, func(tx *sql.Tx, result *engine.PutResult, manifestPath string) error {
 		if h.Meta == nil {
 			return fmt.Errorf("meta store not configured")
 		}
-		if err := h.Meta.CompleteMultipartUploadTx(ctx, tx, uploadID); err != nil {
+		if err := h.Meta.CompleteMultipartUploadTx(ctx, tx, uploadID, result.VersionID, multiETag); err != nil {
 			return err
 		}
+		if class := meta.StorageClassOrStandard(upload.StorageClass); class != meta.StorageClassStandard {
+			if err := h.Meta.SetVersionStorageClassTx(tx, result.VersionID, class); err != nil {
+				return err
+			}
+		}
 		return h.Meta.RecordMPUCompleteTx(ctx, tx, upload.Bucket, upload.Key, result.VersionID, multiETag, result.Size)
 	})
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, r.URL.Path)
+		if errors.Is(err, engine.ErrInsufficientStorage) {
+			writeErrorWithResource(w, http.StatusInsufficientStorage, "InsufficientStorage", "insufficient storage", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
 		return
 	}
 	resp := completeMultipartResult{
@@ -348,12 +460,60 @@ func (h *Handler) handleCompleteMultipart(ctx context.Context, w http.ResponseWr
 		if versionID, ok := versionIDHeaderForPut(versioningState, result.VersionID); ok {
 			w.Header().Set("x-amz-version-id", versionID)
 		}
+		h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectCreatedComplete, putResultObject(result))
 	}
 	w.Header().Set("Content-Type", "application/xml")
 	w.WriteHeader(http.StatusOK)
 	_ = xml.NewEncoder(w).Encode(resp)
 }
 
+// writeCompletedMultipart answers a retried CompleteMultipartUpload with the
+// result recorded by the call that completed the upload. The part list is not
+// checked again: the parts are gone once the upload completes.
+func (h *Handler) writeCompletedMultipart(ctx context.Context, w http.ResponseWriter, r *http.Request, upload *meta.MultipartUpload, requestID string) {
+	if upload.VersionID == "" {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchUpload", "upload already completed", requestID, r.URL.Path)
+		return
+	}
+	versioningState, err := h.bucketVersioningState(ctx, upload.Bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.Header().Set("ETag", `"`+upload.ETag+`"`)
+	if versionID, ok := versionIDHeaderForPut(versioningState, upload.VersionID); ok {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(completeMultipartResult{
+		Bucket: upload.Bucket,
+		Key:    upload.Key,
+		ETag:   `"` + upload.ETag + `"`,
+	})
+}
+
+// beginMPUComplete claims uploadID for one CompleteMultipartUpload; it
+// returns false while another complete of the same upload is running.
+func (h *Handler) beginMPUComplete(uploadID string) bool {
+	h.mpuCompletingMu.Lock()
+	defer h.mpuCompletingMu.Unlock()
+	if _, busy := h.mpuCompleting[uploadID]; busy {
+		return false
+	}
+	if h.mpuCompleting == nil {
+		h.mpuCompleting = make(map[string]struct{})
+	}
+	h.mpuCompleting[uploadID] = struct{}{}
+	return true
+}
+
+func (h *Handler) endMPUComplete(uploadID string) {
+	h.mpuCompletingMu.Lock()
+	delete(h.mpuCompleting, uploadID)
+	h.mpuCompletingMu.Unlock()
+}
+
 func (h *Handler) handleAbortMultipart(ctx context.Context, w http.ResponseWriter, uploadID string, requestID, resource string) {
 	if err := h.Engine.CommitMeta(ctx, func(tx *sql.Tx) error {
 		if h.Meta == nil {
@@ -361,7 +521,7 @@ func (h *Handler) handleAbortMultipart(ctx context.Context, w http.ResponseWrite
 		}
 		return h.Meta.AbortMultipartUploadTx(ctx, tx, uploadID)
 	}); err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -382,31 +542,112 @@ func normalizeETag(etag string) string {
 	return etag
 }
 
-func validatePartSizes(parts []meta.MultipartPart) error {
-	if len(parts) == 0 {
-		return fmt.Errorf("no parts")
-	}
-	for i := 0; i < len(parts)-1; i++ {
-		if parts[i].Size < minPartSize {
-			return fmt.Errorf("part too small")
+// validateCompletePartOrder checks the CompleteMultipartUpload part list:
+// at most maxPartNumber parts in strictly ascending order and, unless gaps are
+// allowed, numbered contiguously from 1. It returns an S3 error code and message.
+func validateCompletePartOrder(parts []completePartItem, allowGaps bool) (string, string) {
+	if len(parts) > maxPartNumber {
+		return "InvalidArgument", "too many parts"
+	}
+	for i, p := range parts {
+		if p.PartNumber < 1 || p.PartNumber > maxPartNumber {
+			return "InvalidArgument", "invalid part number"
 		}
-	}
-	for i := 0; i < len(parts); i++ {
-		if parts[i].Size > maxPartSize {
-			return fmt.Errorf("part too large")
+		if i > 0 && p.PartNumber <= parts[i-1].PartNumber {
+			return "InvalidPartOrder", "parts must be in ascending order"
+		}
+		if allowGaps {
+			continue
+		}
+		if (i == 0 && p.PartNumber != 1) || (i > 0 && p.PartNumber != parts[i-1].PartNumber+1) {
+			return "InvalidPartOrder", "part numbers must be contiguous"
 		}
 	}
-	return nil
+	return "", ""
 }
 
-func multipartETag(parts []meta.MultipartPart) string {
-	h := md5.New()
-	for _, part := range parts {
-		sum, err := hex.DecodeString(part.ETag)
+// defaultMPUCompletePartBatch is the number of part rows read per batch while
+// completing a multipart upload.
+const defaultMPUCompletePartBatch = 1000
+
+// completePartError is a client-facing CompleteMultipartUpload failure.
+type completePartError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *completePartError) Error() string { return e.msg }
+
+// mpuAssembly is the content of a completed upload's manifest.
+type mpuAssembly struct {
+	chunks []manifest.ChunkRef
+	size   int64
+	etag   string
+}
+
+// assembleMultipart walks the requested parts in order and concatenates the
+// chunk references of each part manifest; part payloads are never read. Part
+// rows are fetched at most batch at a time, so besides the growing chunk list
+// only one batch of rows and one part manifest are held in memory. Every part
+// but the last must hold at least minSize bytes.
+func assembleMultipart(ctx context.Context, items []completePartItem, batch int, minSize int64, listParts func(ctx context.Context, afterPart, limit int) ([]meta.MultipartPart, error), partManifest func(ctx context.Context, versionID string) (*manifest.Manifest, error)) (*mpuAssembly, error) {
+	if batch <= 0 {
+		batch = defaultMPUCompletePartBatch
+	}
+	out := &mpuAssembly{}
+	etag := md5.New()
+	var page []meta.MultipartPart
+	pos := 0
+	exhausted := false
+	prevSize := int64(-1)
+	for _, item := range items {
+		for pos < len(page) && page[pos].PartNumber < item.PartNumber {
+			pos++
+		}
+		if pos == len(page) && !exhausted {
+			var err error
+			page, err = listParts(ctx, item.PartNumber-1, batch)
+			if err != nil {
+				return nil, err
+			}
+			pos = 0
+			exhausted = len(page) < batch
+		}
+		if pos == len(page) || page[pos].PartNumber != item.PartNumber {
+			return nil, &completePartError{status: http.StatusBadRequest, code: "InvalidPart", msg: "missing part"}
+		}
+		part := page[pos]
+		if normalizeETag(item.ETag) != part.ETag {
+			return nil, &completePartError{status: http.StatusBadRequest, code: "InvalidPart", msg: "etag mismatch"}
+		}
+		if prevSize >= 0 && prevSize < minSize {
+			return nil, &completePartError{status: http.StatusBadRequest, code: "EntityTooSmall", msg: "part too small"}
+		}
+		if part.Size > maxPartSize {
+			return nil, &completePartError{status: http.StatusBadRequest, code: "InvalidPart", msg: "part too large"}
+		}
+		man, err := partManifest(ctx, part.VersionID)
 		if err != nil {
-			continue
+			return nil, err
+		}
+		if man.Size != 0 && part.Size != 0 && man.Size != part.Size {
+			return nil, errors.New("part size mismatch")
+		}
+		for _, ch := range man.Chunks {
+			ch.Index = len(out.chunks)
+			out.chunks = append(out.chunks, ch)
+		}
+		if man.Size > 0 {
+			out.size += man.Size
+		} else {
+			out.size += part.Size
+		}
+		if sum, err := hex.DecodeString(part.ETag); err == nil {
+			etag.Write(sum)
 		}
-		h.Write(sum)
+		prevSize = part.Size
 	}
-	return hex.EncodeToString(h.Sum(nil)) + "-" + strconv.Itoa(len(parts))
+	out.etag = hex.EncodeToString(etag.Sum(nil)) + "-" + strconv.Itoa(len(items))
+	return out, nil
 }
@@ -2,15 +2,22 @@ package s3
 
 import (
 	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/xml"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/storage/engine"
 	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
 )
 
 func TestMultipartFlowUnit(t *testing.T) {
@@ -126,6 +133,237 @@ func TestMultipartRejectsOversizedPart(t *testing.T) {
 	}
 }
 
+func TestValidateCompletePartOrder(t *testing.T) {
+	parts := func(numbers ...int) []completePartItem {
+		out := make([]completePartItem, 0, len(numbers))
+		for _, n := range numbers {
+			out = append(out, completePartItem{PartNumber: n})
+		}
+		return out
+	}
+	tooMany := make([]int, maxPartNumber+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+	tests := []struct {
+		name      string
+		parts     []completePartItem
+		allowGaps bool
+		code      string
+	}{
+		{name: "contiguous", parts: parts(1, 2, 3)},
+		{name: "descending", parts: parts(2, 1), code: "InvalidPartOrder"},
+		{name: "duplicate", parts: parts(1, 1), code: "InvalidPartOrder"},
+		{name: "gap rejected", parts: parts(1, 3), code: "InvalidPartOrder"},
+		{name: "not starting at one", parts: parts(2, 3), code: "InvalidPartOrder"},
+		{name: "gap allowed", parts: parts(1, 3, 7), allowGaps: true},
+		{name: "descending with gaps allowed", parts: parts(3, 1), allowGaps: true, code: "InvalidPartOrder"},
+		{name: "out of range", parts: parts(0), code: "InvalidArgument"},
+		{name: "too many", parts: parts(tooMany...), code: "InvalidArgument"},
+	}
+	for _, tc := range tests {
+		code, _ := validateCompletePartOrder(tc.parts, tc.allowGaps)
+		if code != tc.code {
+			t.Fatalf("%s: code=%q want %q", tc.name, code, tc.code)
+		}
+	}
+}
+
+func TestMultipartCompleteRejectsSmallPart(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(dir + "/meta.db")
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(dir + "/objects"),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	handler := &Handler{Engine: eng, Meta: store}
+
+	initW := httptest.NewRecorder()
+	handler.ServeHTTP(initW, httptest.NewRequest("POST", "/bucket/key?uploads", nil))
+	if initW.Code != http.StatusOK {
+		t.Fatalf("init status: %d", initW.Code)
+	}
+	var initResp initiateMultipartResult
+	if err := xml.NewDecoder(strings.NewReader(initW.Body.String())).Decode(&initResp); err != nil {
+		t.Fatalf("init decode: %v", err)
+	}
+	var completeBody strings.Builder
+	completeBody.WriteString("<CompleteMultipartUpload>")
+	for _, n := range []string{"1", "2"} {
+		partW := httptest.NewRecorder()
+		handler.ServeHTTP(partW, httptest.NewRequest("PUT", "/bucket/key?partNumber="+n+"&uploadId="+initResp.UploadID, strings.NewReader("small")))
+		if partW.Code != http.StatusOK {
+			t.Fatalf("part %s status: %d", n, partW.Code)
+		}
+		completeBody.WriteString("<Part><PartNumber>" + n + "</PartNumber><ETag>" + partW.Result().Header.Get("ETag") + "</ETag></Part>")
+	}
+	completeBody.WriteString("</CompleteMultipartUpload>")
+
+	completeW := httptest.NewRecorder()
+	handler.ServeHTTP(completeW, httptest.NewRequest("POST", "/bucket/key?uploadId="+initResp.UploadID, strings.NewReader(completeBody.String())))
+	if completeW.Code != http.StatusBadRequest {
+		t.Fatalf("complete status: %d", completeW.Code)
+	}
+	if !strings.Contains(completeW.Body.String(), "<Code>EntityTooSmall</Code>") {
+		t.Fatalf("expected EntityTooSmall, got %s", completeW.Body.String())
+	}
+}
+
+func TestMultipartConfiguredPartLimits(t *testing.T) {
+	h := newTestHandler(t)
+	h.MPUMaxParts = 2
+	h.MPUMinPartSize = 4
+	initW := httptest.NewRecorder()
+	h.ServeHTTP(initW, httptest.NewRequest("POST", "/bucket/key?uploads", nil))
+	if initW.Code != http.StatusOK {
+		t.Fatalf("init status: %d", initW.Code)
+	}
+	var initResp initiateMultipartResult
+	if err := xml.NewDecoder(strings.NewReader(initW.Body.String())).Decode(&initResp); err != nil {
+		t.Fatalf("init decode: %v", err)
+	}
+	uploadPart := func(n, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("PUT", "/bucket/key?partNumber="+n+"&uploadId="+initResp.UploadID, strings.NewReader(body)))
+		return w
+	}
+	etags := map[string]string{}
+	for _, n := range []string{"1", "2"} {
+		w := uploadPart(n, "part")
+		if w.Code != http.StatusOK {
+			t.Fatalf("part %s status: %d", n, w.Code)
+		}
+		etags[n] = w.Header().Get("ETag")
+	}
+	if w := uploadPart("3", "part"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too many parts") {
+		t.Fatalf("expected too many parts, got %d %s", w.Code, w.Body.String())
+	}
+	// Replacing a stored part does not count against the cap.
+	w := uploadPart("2", "tail")
+	if w.Code != http.StatusOK {
+		t.Fatalf("replace part status: %d", w.Code)
+	}
+	etags["2"] = w.Header().Get("ETag")
+
+	body := "<CompleteMultipartUpload>" +
+		"<Part><PartNumber>1</PartNumber><ETag>" + etags["1"] + "</ETag></Part>" +
+		"<Part><PartNumber>2</PartNumber><ETag>" + etags["2"] + "</ETag></Part>" +
+		"</CompleteMultipartUpload>"
+	completeW := httptest.NewRecorder()
+	h.ServeHTTP(completeW, httptest.NewRequest("POST", "/bucket/key?uploadId="+initResp.UploadID, strings.NewReader(body)))
+	if completeW.Code != http.StatusOK {
+		t.Fatalf("complete with 4-byte parts: %d %s", completeW.Code, completeW.Body.String())
+	}
+}
+
+func TestMultipartMaxPartsKeepsConcurrentPuts(t *testing.T) {
+	h := newTestHandler(t)
+	h.MPUMaxParts = 1
+	initW := httptest.NewRecorder()
+	h.ServeHTTP(initW, httptest.NewRequest("POST", "/bucket/key?uploads", nil))
+	var initResp initiateMultipartResult
+	if err := xml.NewDecoder(initW.Body).Decode(&initResp); err != nil {
+		t.Fatalf("init decode: %v", err)
+	}
+
+	// All parts pass the early count; the cap check in the barrier
+	// transaction rejects all but one while PUTs share their batches.
+	const n = 8
+	puts := make([]*httptest.ResponseRecorder, n)
+	parts := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			puts[i] = httptest.NewRecorder()
+			h.ServeHTTP(puts[i], httptest.NewRequest("PUT", "/bucket/obj-"+strconv.Itoa(i), strings.NewReader("payload")))
+		}()
+		go func() {
+			defer wg.Done()
+			parts[i] = httptest.NewRecorder()
+			h.ServeHTTP(parts[i], httptest.NewRequest("PUT", "/bucket/key?partNumber="+strconv.Itoa(i+1)+"&uploadId="+initResp.UploadID, strings.NewReader("part")))
+		}()
+	}
+	wg.Wait()
+	stored := 0
+	for i := range n {
+		if puts[i].Code != http.StatusOK {
+			t.Fatalf("PUT %d status: %d %s", i, puts[i].Code, puts[i].Body.String())
+		}
+		switch parts[i].Code {
+		case http.StatusOK:
+			stored++
+		case http.StatusBadRequest:
+		default:
+			t.Fatalf("part %d status: %d %s", i+1, parts[i].Code, parts[i].Body.String())
+		}
+	}
+	if stored != 1 {
+		t.Fatalf("expected 1 stored part, got %d", stored)
+	}
+}
+
+func TestMultipartETagMatchesS3(t *testing.T) {
+	h := newTestHandler(t)
+	initW := httptest.NewRecorder()
+	h.ServeHTTP(initW, httptest.NewRequest("POST", "/bucket/key?uploads", nil))
+	if initW.Code != http.StatusOK {
+		t.Fatalf("init status: %d", initW.Code)
+	}
+	var initResp initiateMultipartResult
+	if err := xml.NewDecoder(strings.NewReader(initW.Body.String())).Decode(&initResp); err != nil {
+		t.Fatalf("init decode: %v", err)
+	}
+	parts := [][]byte{bytes.Repeat([]byte("a"), int(minPartSize)), []byte("tail")}
+	composite := md5.New()
+	var completeBody strings.Builder
+	completeBody.WriteString("<CompleteMultipartUpload>")
+	for i, data := range parts {
+		n := strconv.Itoa(i + 1)
+		partW := httptest.NewRecorder()
+		h.ServeHTTP(partW, httptest.NewRequest("PUT", "/bucket/key?partNumber="+n+"&uploadId="+initResp.UploadID, bytes.NewReader(data)))
+		if partW.Code != http.StatusOK {
+			t.Fatalf("part %s status: %d", n, partW.Code)
+		}
+		sum := md5.Sum(data)
+		if got := partW.Header().Get("ETag"); got != `"`+hex.EncodeToString(sum[:])+`"` {
+			t.Fatalf("part %s etag=%s", n, got)
+		}
+		composite.Write(sum[:])
+		completeBody.WriteString("<Part><PartNumber>" + n + "</PartNumber><ETag>" + partW.Header().Get("ETag") + "</ETag></Part>")
+	}
+	completeBody.WriteString("</CompleteMultipartUpload>")
+	want := `"` + hex.EncodeToString(composite.Sum(nil)) + `-2"`
+
+	completeW := httptest.NewRecorder()
+	h.ServeHTTP(completeW, httptest.NewRequest("POST", "/bucket/key?uploadId="+initResp.UploadID, strings.NewReader(completeBody.String())))
+	if completeW.Code != http.StatusOK {
+		t.Fatalf("complete status: %d %s", completeW.Code, completeW.Body.String())
+	}
+	var result completeMultipartResult
+	if err := xml.NewDecoder(completeW.Body).Decode(&result); err != nil {
+		t.Fatalf("complete decode: %v", err)
+	}
+	if result.ETag != want || completeW.Header().Get("ETag") != want {
+		t.Fatalf("complete etag=%s header=%s want %s", result.ETag, completeW.Header().Get("ETag"), want)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/bucket/key", nil))
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != want {
+			t.Fatalf("%s: status=%d etag=%s want %s", method, w.Code, w.Header().Get("ETag"), want)
+		}
+	}
+}
+
 func TestParsePartNumberLimit(t *testing.T) {
 	if _, ok := parsePartNumber("10001"); ok {
 		t.Fatalf("expected part number to be rejected")
@@ -193,3 +431,317 @@ func TestListMultipartUploadsDelimiterEmptyPrefix(t *testing.T) {
 		t.Fatalf("expected keys grouped by delimiter")
 	}
 }
+
+func TestAssembleMultipartBoundsBufferedParts(t *testing.T) {
+	const total, batch = 10000, 250
+	items := make([]completePartItem, total)
+	for i := range items {
+		items[i] = completePartItem{PartNumber: i + 1, ETag: `"` + strings.Repeat("0", 32) + `"`}
+	}
+	calls, maxRows := 0, 0
+	listParts := func(_ context.Context, afterPart, limit int) ([]meta.MultipartPart, error) {
+		calls++
+		var out []meta.MultipartPart
+		for n := afterPart + 1; n <= total && len(out) < limit; n++ {
+			out = append(out, meta.MultipartPart{PartNumber: n, VersionID: strconv.Itoa(n), ETag: strings.Repeat("0", 32), Size: minPartSize})
+		}
+		if len(out) > maxRows {
+			maxRows = len(out)
+		}
+		return out, nil
+	}
+	partManifest := func(_ context.Context, versionID string) (*manifest.Manifest, error) {
+		return &manifest.Manifest{Size: minPartSize, Chunks: []manifest.ChunkRef{{SegmentID: "seg-" + versionID, Len: uint32(minPartSize)}}}, nil
+	}
+	assembly, err := assembleMultipart(context.Background(), items, batch, minPartSize, listParts, partManifest)
+	if err != nil {
+		t.Fatalf("assembleMultipart: %v", err)
+	}
+	if maxRows > batch || calls != total/batch {
+		t.Fatalf("expected %d batches of at most %d rows, got %d calls max %d", total/batch, batch, calls, maxRows)
+	}
+	if len(assembly.chunks) != total || assembly.size != total*minPartSize {
+		t.Fatalf("chunks=%d size=%d", len(assembly.chunks), assembly.size)
+	}
+	for i, ch := range assembly.chunks {
+		if ch.Index != i || ch.SegmentID != "seg-"+strconv.Itoa(i+1) {
+			t.Fatalf("chunk %d: index=%d segment=%s", i, ch.Index, ch.SegmentID)
+		}
+	}
+	if !strings.HasSuffix(assembly.etag, "-10000") {
+		t.Fatalf("etag=%s", assembly.etag)
+	}
+
+	items[5000].PartNumber = total + 1
+	if _, err := assembleMultipart(context.Background(), items[:5001], batch, minPartSize, listParts, partManifest); err == nil {
+		t.Fatalf("expected missing part error")
+	}
+}
+
+func TestMultipartCompleteRangeSpansParts(t *testing.T) {
+	h := newTestHandler(t)
+	h.MPUMaxBufferedParts = 1
+	initW := httptest.NewRecorder()
+	h.ServeHTTP(initW, httptest.NewRequest("POST", "/bucket/key?uploads", nil))
+	if initW.Code != http.StatusOK {
+		t.Fatalf("init status: %d", initW.Code)
+	}
+	var initResp initiateMultipartResult
+	if err := xml.NewDecoder(strings.NewReader(initW.Body.String())).Decode(&initResp); err != nil {
+		t.Fatalf("init decode: %v", err)
+	}
+	parts := [][]byte{bytes.Repeat([]byte("a"), int(minPartSize)), bytes.Repeat([]byte("b"), int(minPartSize)), []byte("tail")}
+	var completeBody strings.Builder
+	completeBody.WriteString("<CompleteMultipartUpload>")
+	for i, data := range parts {
+		n := strconv.Itoa(i + 1)
+		partW := httptest.NewRecorder()
+		h.ServeHTTP(partW, httptest.NewRequest("PUT", "/bucket/key?partNumber="+n+"&uploadId="+initResp.UploadID, bytes.NewReader(data)))
+		if partW.Code != http.StatusOK {
+			t.Fatalf("part %s status: %d", n, partW.Code)
+		}
+		completeBody.WriteString("<Part><PartNumber>" + n + "</PartNumber><ETag>" + partW.Header().Get("ETag") + "</ETag></Part>")
+	}
+	completeBody.WriteString("</CompleteMultipartUpload>")
+	completeW := httptest.NewRecorder()
+	h.ServeHTTP(completeW, httptest.NewRequest("POST", "/bucket/key?uploadId="+initResp.UploadID, strings.NewReader(completeBody.String())))
+	if completeW.Code != http.StatusOK {
+		t.Fatalf("complete status: %d %s", completeW.Code, completeW.Body.String())
+	}
+
+	whole := bytes.Join(parts, nil)
+	start, end := minPartSize-2, 2*minPartSize+1
+	req := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(end, 10))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("range status: %d", w.Code)
+	}
+	if !bytes.Equal(w.Body.Bytes(), whole[start:end+1]) {
+		t.Fatalf("range body mismatch: len=%d", w.Body.Len())
+	}
+}
+
+func TestListPartsPaginates(t *testing.T) {
+	handler := newTestHandler(t)
+	initReq := httptest.NewRequest("POST", "/bucket/key?uploads", nil)
+	initW := httptest.NewRecorder()
+	handler.ServeHTTP(initW, initReq)
+	if initW.Code != http.StatusOK {
+		t.Fatalf("init status: %d", initW.Code)
+	}
+	var initResp initiateMultipartResult
+	if err := xml.NewDecoder(initW.Body).Decode(&initResp); err != nil {
+		t.Fatalf("init decode: %v", err)
+	}
+	for i := 1; i <= 250; i++ {
+		req := httptest.NewRequest("PUT", "/bucket/key?partNumber="+strconv.Itoa(i)+"&uploadId="+initResp.UploadID, strings.NewReader("part"+strconv.Itoa(i)))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("part %d status: %d", i, w.Code)
+		}
+	}
+
+	var seen []int
+	marker := 0
+	for page := 0; ; page++ {
+		if page > 3 {
+			t.Fatalf("too many pages")
+		}
+		req := httptest.NewRequest("GET", "/bucket/key?uploadId="+initResp.UploadID+"&max-parts=100&part-number-marker="+strconv.Itoa(marker), nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list parts status: %d", w.Code)
+		}
+		var resp listPartsResult
+		if err := xml.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("list parts decode: %v", err)
+		}
+		if resp.MaxParts != 100 || resp.PartNumberMarker != marker || resp.StorageClass != "STANDARD" || resp.Owner.ID == "" || resp.Initiator.ID == "" {
+			t.Fatalf("unexpected page header: %+v", resp)
+		}
+		for _, part := range resp.Parts {
+			seen = append(seen, part.PartNumber)
+		}
+		if !resp.IsTruncated {
+			if len(resp.Parts) != 50 {
+				t.Fatalf("last page parts=%d", len(resp.Parts))
+			}
+			break
+		}
+		if len(resp.Parts) != 100 || resp.NextPartNumberMarker != resp.Parts[99].PartNumber {
+			t.Fatalf("page parts=%d next=%d", len(resp.Parts), resp.NextPartNumberMarker)
+		}
+		marker = resp.NextPartNumberMarker
+	}
+	if len(seen) != 250 {
+		t.Fatalf("expected 250 parts, got %d", len(seen))
+	}
+	for i, n := range seen {
+		if n != i+1 {
+			t.Fatalf("part %d out of order: %d", i, n)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/bucket/key?uploadId="+initResp.UploadID+"&part-number-marker=x", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid marker, got %d", w.Code)
+	}
+}
+
+func TestMultipartCompleteRetryReturnsOriginalResult(t *testing.T) {
+	h := newTestHandler(t)
+	initW := httptest.NewRecorder()
+	h.ServeHTTP(initW, httptest.NewRequest("POST", "/bucket/key?uploads", nil))
+	if initW.Code != http.StatusOK {
+		t.Fatalf("init status: %d", initW.Code)
+	}
+	var initResp initiateMultipartResult
+	if err := xml.NewDecoder(strings.NewReader(initW.Body.String())).Decode(&initResp); err != nil {
+		t.Fatalf("init decode: %v", err)
+	}
+	partW := httptest.NewRecorder()
+	h.ServeHTTP(partW, httptest.NewRequest("PUT", "/bucket/key?partNumber=1&uploadId="+initResp.UploadID, strings.NewReader("part1")))
+	if partW.Code != http.StatusOK {
+		t.Fatalf("part status: %d", partW.Code)
+	}
+	completeBody := `<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>` + partW.Header().Get("ETag") + `</ETag></Part></CompleteMultipartUpload>`
+	complete := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", target, strings.NewReader(completeBody)))
+		return w
+	}
+
+	// Another complete of the same upload is still running.
+	if !h.beginMPUComplete(initResp.UploadID) {
+		t.Fatalf("beginMPUComplete failed on idle upload")
+	}
+	busy := complete("/bucket/key?uploadId=" + initResp.UploadID)
+	h.endMPUComplete(initResp.UploadID)
+	if busy.Code != http.StatusConflict || !strings.Contains(busy.Body.String(), "<Code>OperationAborted</Code>") {
+		t.Fatalf("expected 409 OperationAborted while in progress, got %d %s", busy.Code, busy.Body.String())
+	}
+
+	first := complete("/bucket/key?uploadId=" + initResp.UploadID)
+	if first.Code != http.StatusOK {
+		t.Fatalf("complete status: %d %s", first.Code, first.Body.String())
+	}
+	retry := complete("/bucket/key?uploadId=" + initResp.UploadID)
+	if retry.Code != http.StatusOK {
+		t.Fatalf("retry status: %d %s", retry.Code, retry.Body.String())
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Fatalf("retry result differs:\n%s\n%s", first.Body.String(), retry.Body.String())
+	}
+	if versionID := first.Header().Get("x-amz-version-id"); versionID == "" || retry.Header().Get("x-amz-version-id") != versionID {
+		t.Fatalf("version id mismatch: %q vs %q", versionID, retry.Header().Get("x-amz-version-id"))
+	}
+	versions, err := h.Meta.ListObjectVersions(context.Background(), "bucket", "key", "", "", 10, time.Time{})
+	if err != nil {
+		t.Fatalf("ListObjectVersions: %v", err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("retry must not create a version, got %d", len(versions))
+	}
+
+	if w := complete("/bucket/other?uploadId=" + initResp.UploadID); w.Code != http.StatusNotFound {
+		t.Fatalf("expected NoSuchUpload for another key, got %d", w.Code)
+	}
+	unknown := complete("/bucket/key?uploadId=does-not-exist")
+	if unknown.Code != http.StatusNotFound || !strings.Contains(unknown.Body.String(), "<Code>NoSuchUpload</Code>") {
+		t.Fatalf("expected 404 NoSuchUpload, got %d %s", unknown.Code, unknown.Body.String())
+	}
+}
+
+func TestMultipartMaxActiveUploadsPerBucket(t *testing.T) {
+	h := newTestHandler(t)
+	h.MPUMaxUploads = 2
+	h.MPUMaxUploadsBuckets = map[string]int{"big": 3}
+	initiate := func(bucket string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/"+bucket+"/key?uploads", nil))
+		return rec
+	}
+
+	var first initiateMultipartResult
+	for i := range 2 {
+		rec := initiate("bucket")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("initiate %d status: %d", i+1, rec.Code)
+		}
+		if i == 0 {
+			if err := xml.NewDecoder(rec.Body).Decode(&first); err != nil {
+				t.Fatalf("init decode: %v", err)
+			}
+		}
+	}
+	rec := initiate("bucket")
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "<Code>SlowDown</Code>") {
+		t.Fatalf("expected 503 SlowDown for the 3rd upload, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected Retry-After on SlowDown")
+	}
+
+	abort := httptest.NewRecorder()
+	h.ServeHTTP(abort, httptest.NewRequest("DELETE", "/bucket/key?uploadId="+first.UploadID, nil))
+	if abort.Code != http.StatusNoContent {
+		t.Fatalf("abort status: %d", abort.Code)
+	}
+	if rec := initiate("bucket"); rec.Code != http.StatusOK {
+		t.Fatalf("expected initiate after abort to succeed, got %d", rec.Code)
+	}
+
+	for i := range 3 {
+		if rec := initiate("big"); rec.Code != http.StatusOK {
+			t.Fatalf("override bucket initiate %d status: %d", i+1, rec.Code)
+		}
+	}
+	if rec := initiate("big"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for the 4th upload in the override bucket, got %d", rec.Code)
+	}
+}
+
+func TestMultipartMaxActiveUploadsKeepsConcurrentPuts(t *testing.T) {
+	h := newTestHandler(t)
+	h.MPUMaxUploads = 1
+	init := httptest.NewRecorder()
+	h.ServeHTTP(init, httptest.NewRequest("POST", "/bucket/key?uploads", nil))
+	if init.Code != http.StatusOK {
+		t.Fatalf("initiate status: %d", init.Code)
+	}
+
+	// Rejected initiates share barrier batches with the PUTs.
+	const n = 8
+	puts := make([]*httptest.ResponseRecorder, n)
+	inits := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			puts[i] = httptest.NewRecorder()
+			h.ServeHTTP(puts[i], httptest.NewRequest("PUT", "/bucket/obj-"+strconv.Itoa(i), strings.NewReader("payload")))
+		}()
+		go func() {
+			defer wg.Done()
+			inits[i] = httptest.NewRecorder()
+			h.ServeHTTP(inits[i], httptest.NewRequest("POST", "/bucket/key?uploads", nil))
+		}()
+	}
+	wg.Wait()
+	for i := range n {
+		if puts[i].Code != http.StatusOK {
+			t.Fatalf("PUT %d status: %d %s", i, puts[i].Code, puts[i].Body.String())
+		}
+		if inits[i].Code != http.StatusServiceUnavailable {
+			t.Fatalf("initiate %d: expected 503, got %d", i, inits[i].Code)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+)
+
+// Event names delivered in bucket notifications (without the "s3:" prefix
+// used in configurations).
+const (
+	eventObjectCreatedPut      = "ObjectCreated:Put"
+	eventObjectCreatedCopy     = "ObjectCreated:Copy"
+	eventObjectCreatedComplete = "ObjectCreated:CompleteMultipartUpload"
+	eventObjectRemovedDelete   = "ObjectRemoved:Delete"
+	eventObjectRemovedMarker   = "ObjectRemoved:DeleteMarkerCreated"
+)
+
+// notificationEventTypes lists the event types a configuration may name.
+var notificationEventTypes = map[string]struct{}{
+	"s3:ObjectCreated:*":               {},
+	"s3:" + eventObjectCreatedPut:      {},
+	"s3:" + eventObjectCreatedCopy:     {},
+	"s3:" + eventObjectCreatedComplete: {},
+	"s3:ObjectRemoved:*":               {},
+	"s3:" + eventObjectRemovedDelete:   {},
+	"s3:" + eventObjectRemovedMarker:   {},
+}
+
+// notificationConfiguration is the ?notification body. Only seglake's
+// WebhookConfiguration is supported; the AWS queue, topic and function
+// targets are rejected.
+type notificationConfiguration struct {
+	XMLName  xml.Name               `xml:"NotificationConfiguration"`
+	Xmlns    string                 `xml:"xmlns,attr,omitempty"`
+	Webhooks []webhookConfiguration `xml:"WebhookConfiguration"`
+	Queues   []struct{}             `xml:"QueueConfiguration"`
+	Topics   []struct{}             `xml:"TopicConfiguration"`
+	Lambdas  []struct{}             `xml:"CloudFunctionConfiguration"`
+	Bridge   *struct{}              `xml:"EventBridgeConfiguration"`
+}
+
+type webhookConfiguration struct {
+	ID     string              `xml:"Id,omitempty"`
+	URL    string              `xml:"Url"`
+	Events []string            `xml:"Event"`
+	Filter *notificationFilter `xml:"Filter,omitempty"`
+}
+
+type notificationFilter struct {
+	Rules []notificationFilterRule `xml:"S3Key>FilterRule"`
+}
+
+type notificationFilterRule struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+// notificationRule is one webhook target as stored in meta (JSON).
+type notificationRule struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Prefix string   `json:"prefix,omitempty"`
+	Suffix string   `json:"suffix,omitempty"`
+}
+
+type notificationRules struct {
+	Webhooks []notificationRule `json:"webhooks"`
+}
+
+// parseNotificationConfiguration validates cfg against the webhook host
+// allowlist. An empty configuration (no webhooks) disables notifications.
+func parseNotificationConfiguration(cfg notificationConfiguration, hosts []string) (notificationRules, error) {
+	var out notificationRules
+	if len(cfg.Queues) > 0 || len(cfg.Topics) > 0 || len(cfg.Lambdas) > 0 || cfg.Bridge != nil {
+		return out, errors.New("only WebhookConfiguration is supported")
+	}
+	ids := make(map[string]struct{}, len(cfg.Webhooks))
+	for i, hook := range cfg.Webhooks {
+		rule := notificationRule{ID: strings.TrimSpace(hook.ID), URL: strings.TrimSpace(hook.URL)}
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("webhook-%d", i+1)
+		}
+		if _, dup := ids[rule.ID]; dup {
+			return out, fmt.Errorf("duplicate Id %q", rule.ID)
+		}
+		ids[rule.ID] = struct{}{}
+		target, err := url.Parse(rule.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" || target.User != nil {
+			return out, fmt.Errorf("invalid Url %q", rule.URL)
+		}
+		if !copySourceHostAllowed(target.Hostname(), hosts) {
+			return out, fmt.Errorf("webhook host %q is not allowed", target.Hostname())
+		}
+		if len(hook.Events) == 0 {
+			return out, errors.New("at least one Event required")
+		}
+		for _, event := range hook.Events {
+			event = strings.TrimSpace(event)
+			if _, ok := notificationEventTypes[event]; !ok {
+				return out, fmt.Errorf("unsupported Event %q", event)
+			}
+			rule.Events = append(rule.Events, event)
+		}
+		if hook.Filter != nil {
+			for _, fr := range hook.Filter.Rules {
+				switch strings.ToLower(strings.TrimSpace(fr.Name)) {
+				case "prefix":
+					rule.Prefix = fr.Value
+				case "suffix":
+					rule.Suffix = fr.Value
+				default:
+					return out, fmt.Errorf("invalid FilterRule Name %q", fr.Name)
+				}
+			}
+		}
+		out.Webhooks = append(out.Webhooks, rule)
+	}
+	return out, nil
+}
+
+// notificationConfigurationFromRules is the inverse of parseNotificationConfiguration.
+func notificationConfigurationFromRules(rules notificationRules) notificationConfiguration {
+	cfg := notificationConfiguration{Xmlns: versioningXMLNamespace}
+	for _, rule := range rules.Webhooks {
+		hook := webhookConfiguration{ID: rule.ID, URL: rule.URL, Events: rule.Events}
+		if rule.Prefix != "" || rule.Suffix != "" {
+			hook.Filter = &notificationFilter{}
+			if rule.Prefix != "" {
+				hook.Filter.Rules = append(hook.Filter.Rules, notificationFilterRule{Name: "prefix", Value: rule.Prefix})
+			}
+			if rule.Suffix != "" {
+				hook.Filter.Rules = append(hook.Filter.Rules, notificationFilterRule{Name: "suffix", Value: rule.Suffix})
+			}
+		}
+		cfg.Webhooks = append(cfg.Webhooks, hook)
+	}
+	return cfg
+}
+
+// matches reports whether the rule wants eventName (e.g. ObjectCreated:Put) for key.
+func (rule notificationRule) matches(eventName, key string) bool {
+	if !strings.HasPrefix(key, rule.Prefix) || !strings.HasSuffix(key, rule.Suffix) {
+		return false
+	}
+	group, _, _ := strings.Cut(eventName, ":")
+	for _, event := range rule.Events {
+		if event == "s3:"+eventName || event == "s3:"+group+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) handleGetBucketNotification(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	exists, err := h.Meta.BucketExists(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if !exists {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+		return
+	}
+	// Like AWS, a bucket without notifications returns an empty configuration.
+	var rules notificationRules
+	raw, err := h.Meta.GetBucketNotification(ctx, bucket)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(notificationConfigurationFromRules(rules))
+}
+
+func (h *Handler) handlePutBucketNotification(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	var hosts []string
+	if h.Notifier != nil {
+		hosts = h.Notifier.Hosts
+	}
+	if len(hosts) == 0 {
+		writeErrorWithResource(w, http.StatusForbidden, "AccessDenied", "bucket notifications disabled", requestID, r.URL.Path)
+		return
+	}
+	var req notificationConfiguration
+	h.limitControlBody(w, r)
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		if h.writeBodyTooLarge(w, err, requestID, r.URL.Path) {
+			return
+		}
+		writeErrorWithResource(w, http.StatusBadRequest, "MalformedXML", "invalid xml", requestID, r.URL.Path)
+		return
+	}
+	rules, err := parseNotificationConfiguration(req, hosts)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", err.Error(), requestID, r.URL.Path)
+		return
+	}
+	if len(rules.Webhooks) == 0 {
+		h.handleDeleteBucketNotification(ctx, w, r, bucket, requestID)
+		return
+	}
+	raw, err := json.Marshal(rules)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if err := h.Meta.SetBucketNotification(ctx, bucket, string(raw)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) handleDeleteBucketNotification(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	exists, err := h.Meta.BucketExists(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if !exists {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+		return
+	}
+	if err := h.Meta.DeleteBucketNotification(ctx, bucket); err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// notifyObject describes the object version an event refers to.
+type notifyObject struct {
+	Size      int64
+	ETag      string
+	VersionID string
+	At        time.Time
+}
+
+func putResultObject(result *engine.PutResult) notifyObject {
+	return notifyObject{Size: result.Size, ETag: result.ETag, VersionID: result.VersionID, At: result.CommittedAt}
+}
+
+// notifyEvent queues one event per matching webhook of the bucket after a
+// mutation has committed. Lookup failures are logged and never fail the
+// request.
+func (h *Handler) notifyEvent(ctx context.Context, r *http.Request, bucket, key, requestID, eventName string, obj notifyObject) {
+	if h.Notifier == nil || len(h.Notifier.Hosts) == 0 || h.Meta == nil {
+		return
+	}
+	raw, err := h.Meta.GetBucketNotification(ctx, bucket)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("notification: bucket=%s: %v", bucket, err)
+		}
+		return
+	}
+	var rules notificationRules
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		log.Printf("notification: bucket=%s: %v", bucket, err)
+		return
+	}
+	if obj.At.IsZero() {
+		obj.At = h.now()
+	}
+	region := "us-east-1"
+	if h.Auth != nil && h.Auth.Region != "" {
+		region = h.Auth.Region
+	}
+	for _, rule := range rules.Webhooks {
+		if !rule.matches(eventName, key) {
+			continue
+		}
+		if !h.Notifier.allows(rule.URL) {
+			log.Printf("notification: bucket=%s id=%s: webhook host not allowed", bucket, rule.ID)
+			continue
+		}
+		record := s3EventRecord{
+			EventVersion: "2.1",
+			EventSource:  "seglake:s3",
+			AWSRegion:    region,
+			EventTime:    obj.At.UTC().Format("2006-01-02T15:04:05.000Z"),
+			EventName:    eventName,
+			UserIdentity: s3EventIdentity{PrincipalID: extractAccessKey(r)},
+			ResponseElements: map[string]string{
+				"x-amz-request-id": requestID,
+			},
+			S3: s3EventEntity{
+				SchemaVersion:   "1.0",
+				ConfigurationID: rule.ID,
+				Bucket:          s3EventBucket{Name: bucket, ARN: "arn:aws:s3:::" + bucket},
+				Object: s3EventObject{
+					Key:       url.QueryEscape(key),
+					Size:      obj.Size,
+					ETag:      obj.ETag,
+					VersionID: obj.VersionID,
+					Sequencer: fmt.Sprintf("%016X", obj.At.UnixNano()),
+				},
+			},
+		}
+		h.Notifier.enqueue(rule.URL, s3EventMessage{Records: []s3EventRecord{record}})
+	}
+}
+
+// s3EventMessage is the webhook body, shaped like an AWS S3 event.
+type s3EventMessage struct {
+	Records []s3EventRecord `json:"Records"`
+}
+
+type s3EventRecord struct {
+	EventVersion     string            `json:"eventVersion"`
+	EventSource      string            `json:"eventSource"`
+	AWSRegion        string            `json:"awsRegion"`
+	EventTime        string            `json:"eventTime"`
+	EventName        string            `json:"eventName"`
+	UserIdentity     s3EventIdentity   `json:"userIdentity"`
+	ResponseElements map[string]string `json:"responseElements"`
+	S3               s3EventEntity     `json:"s3"`
+}
+
+type s3EventIdentity struct {
+	PrincipalID string `json:"principalId"`
+}
+
+type s3EventEntity struct {
+	SchemaVersion   string        `json:"s3SchemaVersion"`
+	ConfigurationID string        `json:"configurationId"`
+	Bucket          s3EventBucket `json:"bucket"`
+	Object          s3EventObject `json:"object"`
+}
+
+type s3EventBucket struct {
+	Name string `json:"name"`
+	ARN  string `json:"arn"`
+}
+
+type s3EventObject struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size,omitempty"`
+	ETag      string `json:"eTag,omitempty"`
+	VersionID string `json:"versionId,omitempty"`
+	Sequencer string `json:"sequencer"`
+}
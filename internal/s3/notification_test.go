@@ -0,0 +1,227 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type webhookRecorder struct {
+	mu       sync.Mutex
+	messages []s3EventMessage
+	fail     int
+	got      chan struct{}
+}
+
+func newWebhookRecorder(t *testing.T, fail int) (*webhookRecorder, *httptest.Server) {
+	t.Helper()
+	rec := &webhookRecorder{fail: fail, got: make(chan struct{}, 16)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		if rec.fail > 0 {
+			rec.fail--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var msg s3EventMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		rec.messages = append(rec.messages, msg)
+		rec.got <- struct{}{}
+	}))
+	t.Cleanup(server.Close)
+	return rec, server
+}
+
+func (rec *webhookRecorder) wait(t *testing.T, n int) []s3EventMessage {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-rec.got:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %d", i+1)
+		}
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]s3EventMessage(nil), rec.messages...)
+}
+
+func newNotifyTestHandler(t *testing.T, server *httptest.Server) *Handler {
+	t.Helper()
+	h := newTestHandler(t)
+	h.Metrics = NewMetrics()
+	h.Notifier = NewNotifier(16)
+	h.Notifier.Hosts = []string{"127.0.0.1"}
+	h.Notifier.Client = server.Client()
+	h.Notifier.Metrics = h.Metrics
+	h.Notifier.backoff = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go h.Notifier.Run(ctx, 1)
+	return h
+}
+
+func TestBucketNotificationWebhook(t *testing.T) {
+	rec, server := newWebhookRecorder(t, 1)
+	h := newNotifyTestHandler(t, server)
+	putObject(t, h, "bucket", "logs/seed.gz", "seed")
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/bucket?notification", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, ""); w.Code != http.StatusOK || strings.Contains(w.Body.String(), "WebhookConfiguration") {
+		t.Fatalf("expected empty configuration, got %d %s", w.Code, w.Body.String())
+	}
+	cases := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"queue unsupported", `<NotificationConfiguration><QueueConfiguration><Queue>arn:aws:sqs:::q</Queue><Event>s3:ObjectCreated:*</Event></QueueConfiguration></NotificationConfiguration>`, http.StatusBadRequest},
+		{"host not allowed", `<NotificationConfiguration><WebhookConfiguration><Url>https://example.com/hook</Url><Event>s3:ObjectCreated:*</Event></WebhookConfiguration></NotificationConfiguration>`, http.StatusBadRequest},
+		{"bad event", `<NotificationConfiguration><WebhookConfiguration><Url>` + server.URL + `</Url><Event>s3:ObjectRestore:*</Event></WebhookConfiguration></NotificationConfiguration>`, http.StatusBadRequest},
+		{"webhook", `<NotificationConfiguration><WebhookConfiguration><Id>gz</Id><Url>` + server.URL + `/hook</Url><Event>s3:ObjectCreated:*</Event><Event>s3:ObjectRemoved:*</Event>` +
+			`<Filter><S3Key><FilterRule><Name>prefix</Name><Value>logs/</Value></FilterRule><FilterRule><Name>suffix</Name><Value>.gz</Value></FilterRule></S3Key></Filter></WebhookConfiguration></NotificationConfiguration>`, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if w := do(http.MethodPut, tc.body); w.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.code, w.Code, w.Body.String())
+		}
+	}
+	if w := do(http.MethodGet, ""); !strings.Contains(w.Body.String(), "<Id>gz</Id>") || !strings.Contains(w.Body.String(), "<Value>.gz</Value>") {
+		t.Fatalf("unexpected configuration: %s", w.Body.String())
+	}
+
+	putObject(t, h, "bucket", "other/a.gz", "skip")
+	putObject(t, h, "bucket", "logs/a.txt", "skip")
+	putObject(t, h, "bucket", "logs/a+b.gz", "hello")
+	req := httptest.NewRequest(http.MethodDelete, "/bucket/logs/a+b.gz", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status: %d", w.Code)
+	}
+
+	// The first POST fails with 503 and is retried.
+	messages := rec.wait(t, 2)
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 events, got %+v", messages)
+	}
+	put := messages[0].Records[0]
+	if put.EventName != eventObjectCreatedPut || put.S3.ConfigurationID != "gz" || put.S3.Bucket.Name != "bucket" ||
+		put.S3.Object.Key != "logs%2Fa%2Bb.gz" || put.S3.Object.Size != 5 || put.S3.Object.ETag != "5d41402abc4b2a76b9719d911017c592" {
+		t.Fatalf("unexpected put event: %+v", put)
+	}
+	if del := messages[1].Records[0]; del.EventName != eventObjectRemovedMarker || del.S3.Object.VersionID == "" || del.S3.Object.Key != "logs%2Fa%2Bb.gz" {
+		t.Fatalf("unexpected delete event: %+v", del)
+	}
+	// The receiver records an event before the notifier counts it.
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Metrics.Notifications()["delivered"] < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := h.Metrics.Notifications(); got["delivered"] != 2 || got["failed"] != 0 {
+		t.Fatalf("unexpected notification counts: %v", got)
+	}
+
+	if w := do(http.MethodDelete, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete notification: %d", w.Code)
+	}
+	if w := do(http.MethodGet, ""); strings.Contains(w.Body.String(), "WebhookConfiguration") {
+		t.Fatalf("expected configuration removed: %s", w.Body.String())
+	}
+}
+
+func TestBucketNotificationDisabledWithoutHosts(t *testing.T) {
+	h := newTestHandler(t)
+	if err := h.Meta.CreateBucket(context.Background(), "bucket"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	body := `<NotificationConfiguration><WebhookConfiguration><Url>https://hooks.example.com/</Url><Event>s3:ObjectCreated:*</Event></WebhookConfiguration></NotificationConfiguration>`
+	req := httptest.NewRequest(http.MethodPut, "/bucket?notification", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestNotifierDropsWhenQueueFull(t *testing.T) {
+	n := NewNotifier(1)
+	n.Metrics = NewMetrics()
+	n.enqueue("http://127.0.0.1/hook", s3EventMessage{})
+	n.enqueue("http://127.0.0.1/hook", s3EventMessage{})
+	if got := n.Metrics.Notifications(); got["dropped"] != 1 {
+		t.Fatalf("expected one dropped event, got %v", got)
+	}
+}
+
+func TestNotificationRechecksReplicatedHosts(t *testing.T) {
+	rec, server := newWebhookRecorder(t, 0)
+	h := newTestHandler(t)
+	h.Notifier = NewNotifier(16)
+	h.Notifier.Hosts = []string{"127.0.0.1"}
+	h.Notifier.Metrics = NewMetrics()
+	putObject(t, h, "bucket", "seed", "seed")
+	// A replicated config never passed this node's PUT-time host check.
+	target := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/hook"
+	raw, err := json.Marshal(notificationRules{Webhooks: []notificationRule{{ID: "r", URL: target, Events: []string{"s3:ObjectCreated:*"}}}})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if err := h.Meta.SetBucketNotification(context.Background(), "bucket", string(raw)); err != nil {
+		t.Fatalf("SetBucketNotification: %v", err)
+	}
+	putObject(t, h, "bucket", "key", "data")
+	if n := len(h.Notifier.queue); n != 0 {
+		t.Fatalf("expected no queued events, got %d", n)
+	}
+
+	// Deliveries already queued are checked against the current list too.
+	h.Notifier.deliver(context.Background(), server.Client(), notifyDelivery{url: target, body: []byte("{}")})
+	if got := h.Notifier.Metrics.Notifications(); got["failed"] != 1 {
+		t.Fatalf("expected a failed delivery, got %v", got)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.messages) != 0 {
+		t.Fatalf("unexpected POST: %+v", rec.messages)
+	}
+}
+
+func TestNotifierRefusesBlockedAddresses(t *testing.T) {
+	rec, server := newWebhookRecorder(t, 0)
+	n := NewNotifier(1)
+	n.Hosts = []string{"localhost"}
+	n.Metrics = NewMetrics()
+	n.backoff = 10 * time.Millisecond
+	// An allowlisted name that resolves to loopback must not be dialed.
+	target := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/hook"
+	n.deliver(context.Background(), n.httpClient(), notifyDelivery{url: target, body: []byte("{}")})
+	if got := n.Metrics.Notifications(); got["failed"] != 1 {
+		t.Fatalf("expected a failed delivery, got %v", got)
+	}
+	rec.mu.Lock()
+	if len(rec.messages) != 0 {
+		t.Fatalf("unexpected POST: %+v", rec.messages)
+	}
+	rec.mu.Unlock()
+
+	n.ipBlocked = func(ip net.IP) bool { return !ip.IsLoopback() && blockedCopySourceIP(ip) }
+	n.deliver(context.Background(), n.httpClient(), notifyDelivery{url: target, body: []byte("{}")})
+	if got := n.Metrics.Notifications(); got["delivered"] != 1 {
+		t.Fatalf("expected a delivery once loopback is allowed, got %v", got)
+	}
+}
@@ -0,0 +1,195 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNotifyQueueSize = 1000
+	defaultNotifyWorkers   = 4
+	defaultNotifyRetries   = 3
+	defaultNotifyTimeout   = 5 * time.Second
+	notifyInitialBackoff   = time.Second
+	notifyMaxBackoff       = 30 * time.Second
+)
+
+var errWebhookAddrBlocked = errors.New("webhook address blocked")
+
+// Notifier delivers bucket notification events to webhooks in the
+// background. Requests only enqueue; a full queue drops the event (counted as
+// "dropped" in Metrics) so request latency never depends on the receiver.
+// Delivery is best-effort: events still queued at shutdown are lost.
+type Notifier struct {
+	// Hosts allowlists webhook hosts (exact or "*.suffix"); empty disables
+	// bucket notifications.
+	Hosts []string
+	// Retries is the number of redeliveries after a failed POST (NewNotifier sets 3).
+	Retries int
+	// Timeout bounds one POST (0 = 5s).
+	Timeout time.Duration
+	// Metrics counts delivered, failed and dropped events.
+	Metrics *Metrics
+	// Client overrides the HTTP client (tests).
+	Client *http.Client
+
+	queue     chan notifyDelivery
+	backoff   time.Duration
+	ipBlocked func(net.IP) bool
+}
+
+// allows reports whether target's host is still in Hosts. Configs can arrive
+// through replication, which never ran the PUT-time check against this
+// node's allowlist.
+func (n *Notifier) allows(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return false
+	}
+	return copySourceHostAllowed(u.Hostname(), n.Hosts)
+}
+
+type notifyDelivery struct {
+	url  string
+	body []byte
+}
+
+// NewNotifier creates a Notifier with a queue of queueSize events (0 = 1000).
+func NewNotifier(queueSize int) *Notifier {
+	if queueSize <= 0 {
+		queueSize = defaultNotifyQueueSize
+	}
+	return &Notifier{
+		Retries: defaultNotifyRetries,
+		queue:   make(chan notifyDelivery, queueSize),
+		backoff: notifyInitialBackoff,
+	}
+}
+
+func (n *Notifier) enqueue(target string, msg s3EventMessage) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("notification: encode: %v", err)
+		return
+	}
+	select {
+	case n.queue <- notifyDelivery{url: target, body: body}:
+	default:
+		n.Metrics.IncNotification("dropped")
+	}
+}
+
+// Run delivers queued events on workers goroutines (0 = 4) until ctx is done.
+func (n *Notifier) Run(ctx context.Context, workers int) {
+	if workers <= 0 {
+		workers = defaultNotifyWorkers
+	}
+	client := n.httpClient()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d := <-n.queue:
+					n.deliver(ctx, client, d)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// httpClient returns Client or a client that, like copy from URL, never
+// dials loopback, link-local or metadata addresses. The check runs on the
+// resolved address of every dial, so a name that rebinds to an internal
+// address between retries is still refused.
+func (n *Notifier) httpClient() *http.Client {
+	if n.Client != nil {
+		return n.Client
+	}
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = defaultNotifyTimeout
+	}
+	blocked := n.ipBlocked
+	if blocked == nil {
+		blocked = blockedCopySourceIP
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No environment proxy: the dial check must see the real target.
+			Proxy:                 nil,
+			DialContext:           guardedDialer(blocked, errWebhookAddrBlocked).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+		},
+		// A redirect could point the POST at a host outside the allowlist.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// deliver POSTs one event, retrying network errors, 429 and 5xx with
+// exponential backoff.
+func (n *Notifier) deliver(ctx context.Context, client *http.Client, d notifyDelivery) {
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, client, d)
+		if err == nil {
+			n.Metrics.IncNotification("delivered")
+			return
+		}
+		if !retry || attempt >= n.Retries {
+			n.Metrics.IncNotification("failed")
+			log.Printf("notification: url=%s attempts=%d: %v", d.url, attempt+1, err)
+			return
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			n.Metrics.IncNotification("failed")
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, notifyMaxBackoff)
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, client *http.Client, d notifyDelivery) (bool, error) {
+	if !n.allows(d.url) {
+		return false, errors.New("webhook host not allowed")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return !errors.Is(err, errWebhookAddrBlocked), err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("status %d", resp.StatusCode)
+}
@@ -0,0 +1,22 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// handleOpsMetrics returns a JSON snapshot of the in-memory request metrics.
+// POST /v1/ops/metrics/reset returns the snapshot and zeroes the cumulative
+// counters in one step, so periodic pollers can compute per-interval rates
+// without losing increments between the read and the reset.
+func (h *Handler) handleOpsMetrics(_ context.Context, w http.ResponseWriter, r *http.Request, requestID string, reset bool) {
+	if h.Metrics == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "metrics not initialized", requestID, r.URL.Path)
+		return
+	}
+	snap := h.Metrics.TakeSnapshot(h.now().UTC(), reset)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(snap)
+}
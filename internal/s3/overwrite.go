@@ -0,0 +1,165 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+)
+
+// writeOffsetHeader turns a PUT into a ranged overwrite of the current
+// version: the body replaces the bytes starting at the given offset.
+const writeOffsetHeader = "X-Seglake-Write-Offset"
+
+func isOverwriteRequest(r *http.Request) bool {
+	return r.Header.Get(writeOffsetHeader) != ""
+}
+
+// handleOverwriteObject writes the request body over the current version of
+// bucket/key at the requested offset as a new version, e.g. to fill an
+// allocated object. Chunks outside the range are reused; the range must lie
+// within the object (appends extend it).
+func (h *Handler) handleOverwriteObject(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, requestID string) {
+	defer func() { _ = r.Body.Close() }()
+	if isAppendRequest(r) || r.Header.Get("X-Amz-Copy-Source") != "" || r.Header.Get(copySourceURLHeader) != "" || r.Header.Get(moveSourceHeader) != "" {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "write offset cannot be combined with an append, copy or move", requestID, r.URL.Path)
+		return
+	}
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(r.Header.Get(writeOffsetHeader)), 10, 64)
+	if err != nil || offset < 0 {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid "+strings.ToLower(writeOffsetHeader), requestID, r.URL.Path)
+		return
+	}
+	base, err := h.Meta.GetObjectMeta(ctx, bucket, key)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "key not found", requestID, r.URL.Path)
+		return
+	case err != nil:
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	case strings.EqualFold(base.State, meta.VersionStateDeleteMarker) || base.Expired(h.now()):
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "key not found", requestID, r.URL.Path)
+		return
+	case strings.EqualFold(base.State, meta.VersionStateDamaged):
+		w.Header().Set("X-Error", "DamagedObject")
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "object damaged", requestID, r.URL.Path)
+		return
+	}
+	contentLength, _, err := contentLengthFromRequest(r)
+	if err != nil {
+		switch err {
+		case errMissingContentLength:
+			writeErrorWithResource(w, http.StatusLengthRequired, "MissingContentLength", "missing content length", requestID, r.URL.Path)
+		default:
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid content length", requestID, r.URL.Path)
+		}
+		return
+	}
+	reader := io.Reader(r.Body)
+	reader, streamingMode, decodedLen, hasDecoded, reqErr := setupStreamingReader(r, reader, h.DisableTrailerChecksums)
+	if reqErr != nil {
+		writeErrorWithResource(w, reqErr.status, reqErr.code, reqErr.message, requestID, r.URL.Path)
+		return
+	}
+	// The range end must be known before the body is read.
+	length := contentLength
+	if streamingMode != streamingNone {
+		if !hasDecoded {
+			writeErrorWithResource(w, http.StatusLengthRequired, "MissingContentLength", "missing decoded content length", requestID, r.URL.Path)
+			return
+		}
+		length = decodedLen
+	}
+	if length <= 0 {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "write offset requires a body", requestID, r.URL.Path)
+		return
+	}
+	if offset > base.Size-length {
+		w.Header().Set(objectSizeHeader, strconv.FormatInt(base.Size, 10))
+		writeErrorWithResource(w, http.StatusConflict, "InvalidWriteOffset", "write range exceeds object size", requestID, r.URL.Path)
+		return
+	}
+	expectedMD5, err := parseContentMD5(r.Header.Get("Content-MD5"))
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidDigest", "invalid content-md5", requestID, r.URL.Path)
+		return
+	}
+	if h.RequireContentMD5 && len(expectedMD5) == 0 {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidDigest", "content-md5 required", requestID, r.URL.Path)
+		return
+	}
+	payloadHash := ""
+	verifyPayload := false
+	if streamingMode == streamingNone {
+		if hashHeader := r.Header.Get("X-Amz-Content-Sha256"); hashHeader != "" {
+			expected, verify, err := parsePayloadHash(hashHeader)
+			if err != nil {
+				writeErrorWithResource(w, http.StatusBadRequest, "InvalidDigest", "invalid payload hash", requestID, r.URL.Path)
+				return
+			}
+			payloadHash = expected
+			verifyPayload = verify
+		}
+	}
+	if verifyPayload || len(expectedMD5) > 0 {
+		reader = newValidatingReader(reader, payloadHash, verifyPayload, expectedMD5)
+	}
+	versioningState, err := h.bucketVersioningState(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	man, err := h.Engine.GetManifest(ctx, base.VersionID)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	contentType := strings.TrimSpace(r.Header.Get("Content-Type"))
+	if contentType == "" {
+		contentType = base.ContentType
+	}
+	_, result, err := h.Engine.OverwriteObject(ctx, bucket, key, contentType, man, base.ETag, offset, length, reader, h.appendBaseCheck(ctx, bucket, key, base.VersionID), nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, errAppendPositionChanged):
+			writeErrorWithResource(w, http.StatusConflict, "InvalidWriteOffset", "object changed during write", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errPayloadHashMismatch):
+			writeErrorWithResource(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "payload hash mismatch", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errInvalidDigest):
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidDigest", "invalid payload hash", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errBadDigest):
+			writeErrorWithResource(w, http.StatusBadRequest, "BadDigest", "content-md5 mismatch", requestID, r.URL.Path)
+			return
+		case errors.Is(err, errInvalidContentLength):
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "invalid content length", requestID, r.URL.Path)
+			return
+		case errors.Is(err, engine.ErrInsufficientStorage):
+			writeErrorWithResource(w, http.StatusInsufficientStorage, "InsufficientStorage", "insufficient storage", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.Header().Set("ETag", `"`+result.ETag+`"`)
+	if versionID, ok := versionIDHeaderForPut(versioningState, result.VersionID); ok {
+		w.Header().Set("x-amz-version-id", versionID)
+	}
+	w.Header().Set(objectSizeHeader, strconv.FormatInt(result.Size, 10))
+	w.Header().Set("Last-Modified", formatHTTPTime(result.CommittedAt))
+	h.notifyEvent(ctx, r, bucket, key, requestID, eventObjectCreatedPut, putResultObject(result))
+	w.WriteHeader(http.StatusOK)
+}
@@ -26,6 +26,44 @@ type Resource struct {
 	Prefix string `json:"prefix,omitempty"`
 }
 
+// UnmarshalJSON accepts either {"bucket":..,"prefix":..} or a pattern string
+// such as "bucket", "bucket/*" or "bucket/tenant-a/*".
+func (r *Resource) UnmarshalJSON(data []byte) error {
+	var pattern string
+	if err := json.Unmarshal(data, &pattern); err == nil {
+		res, err := parseResourcePattern(pattern)
+		if err != nil {
+			return err
+		}
+		*r = res
+		return nil
+	}
+	type plain Resource
+	var res plain
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+	*r = Resource(res)
+	return nil
+}
+
+// parseResourcePattern parses "bucket[/prefix[*]]"; only a trailing "*" is
+// allowed and "bucket/*" covers the whole bucket.
+func parseResourcePattern(pattern string) (Resource, error) {
+	pattern = strings.TrimSpace(pattern)
+	bucket, prefix, _ := strings.Cut(pattern, "/")
+	if bucket == "" {
+		return Resource{}, fmt.Errorf("policy resource bucket required")
+	}
+	if strings.Contains(bucket, "*") && bucket != "*" {
+		return Resource{}, fmt.Errorf("policy resource wildcard not supported: %q", pattern)
+	}
+	if strings.Contains(strings.TrimSuffix(prefix, "*"), "*") {
+		return Resource{}, fmt.Errorf("policy resource wildcard not supported: %q", pattern)
+	}
+	return Resource{Bucket: bucket, Prefix: strings.TrimSuffix(prefix, "*")}, nil
+}
+
 type Conditions struct {
 	SourceIP        []string          `json:"source_ip,omitempty"`
 	Before          string            `json:"before,omitempty"`
@@ -33,6 +71,7 @@ type Conditions struct {
 	Headers         map[string]string `json:"headers,omitempty"`
 	Prefix          string            `json:"prefix,omitempty"`
 	PrefixLike      bool              `json:"prefix_like,omitempty"`
+	PrefixPatterns  []string          `json:"prefix_patterns,omitempty"`
 	Delimiter       string            `json:"delimiter,omitempty"`
 	SecureTransport *bool             `json:"secure_transport,omitempty"`
 }
@@ -63,6 +102,15 @@ const (
 	policyActionDeleteBucketPolicy    = "deletebucketpolicy"
 	policyActionGetBucketVersioning   = "getbucketversioning"
 	policyActionPutBucketVersioning   = "putbucketversioning"
+	policyActionGetLifecycle          = "getlifecycleconfiguration"
+	policyActionPutLifecycle          = "putlifecycleconfiguration"
+	policyActionGetBucketWebsite      = "getbucketwebsite"
+	policyActionPutBucketWebsite      = "putbucketwebsite"
+	policyActionDeleteBucketWebsite   = "deletebucketwebsite"
+	policyActionGetBucketTagging      = "getbuckettagging"
+	policyActionPutBucketTagging      = "putbuckettagging"
+	policyActionGetBucketNotification = "getbucketnotification"
+	policyActionPutBucketNotification = "putbucketnotification"
 	policyActionGetObject             = "getobject"
 	policyActionHeadObject            = "headobject"
 	policyActionPutObject             = "putobject"
@@ -93,6 +141,15 @@ var validPolicyActions = map[string]struct{}{
 	policyActionDeleteBucketPolicy:    {},
 	policyActionGetBucketVersioning:   {},
 	policyActionPutBucketVersioning:   {},
+	policyActionGetLifecycle:          {},
+	policyActionPutLifecycle:          {},
+	policyActionGetBucketWebsite:      {},
+	policyActionPutBucketWebsite:      {},
+	policyActionDeleteBucketWebsite:   {},
+	policyActionGetBucketTagging:      {},
+	policyActionPutBucketTagging:      {},
+	policyActionGetBucketNotification: {},
+	policyActionPutBucketNotification: {},
 	policyActionGetObject:             {},
 	policyActionHeadObject:            {},
 	policyActionPutObject:             {},
@@ -344,6 +401,11 @@ func (c *Conditions) validate() error {
 	if c.PrefixLike && c.Prefix == "" {
 		return fmt.Errorf("policy condition prefix_like requires prefix")
 	}
+	for _, pattern := range c.PrefixPatterns {
+		if pattern == "" {
+			return fmt.Errorf("policy condition prefix_patterns require non-empty patterns")
+		}
+	}
 	return nil
 }
 
@@ -352,7 +414,7 @@ func (c *Conditions) match(ctx *PolicyContext) bool {
 		return true
 	}
 	if ctx == nil {
-		return len(c.SourceIP) == 0 && c.Before == "" && c.After == "" && len(c.Headers) == 0 && c.Prefix == "" && c.Delimiter == "" && c.SecureTransport == nil
+		return len(c.SourceIP) == 0 && c.Before == "" && c.After == "" && len(c.Headers) == 0 && c.Prefix == "" && len(c.PrefixPatterns) == 0 && c.Delimiter == "" && c.SecureTransport == nil
 	}
 	if len(c.SourceIP) > 0 {
 		ip := net.ParseIP(ctx.SourceIP)
@@ -406,6 +468,18 @@ func (c *Conditions) match(ctx *PolicyContext) bool {
 			return false
 		}
 	}
+	if len(c.PrefixPatterns) > 0 {
+		ok := false
+		for _, pattern := range c.PrefixPatterns {
+			if likeMatch(pattern, ctx.Prefix) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
 	if c.Delimiter != "" && ctx.Delimiter != c.Delimiter {
 		return false
 	}
@@ -415,10 +489,39 @@ func (c *Conditions) match(ctx *PolicyContext) bool {
 	return true
 }
 
+// likeMatch reports whether s matches an AWS StringLike pattern, where *
+// matches any run of characters (including /) and ? matches one character.
+func likeMatch(pattern, s string) bool {
+	star, mark := -1, 0
+	p, i := 0, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, i
+			p++
+		case star >= 0:
+			p = star + 1
+			mark++
+			i = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
 func policyActionForRequest(op string) string {
 	switch op {
 	case "meta_stats":
 		return policyActionGetMetaStats
+	case "meta_usage", "repl_status":
+		return policyActionOps
 	case "meta_conflicts":
 		return policyActionGetMetaConflicts
 	case "repl_oplog":
@@ -429,9 +532,12 @@ func policyActionForRequest(op string) string {
 		return policyActionReplicationRead
 	case "repl_chunk":
 		return policyActionReplicationRead
+	case "repl_object_meta":
+		return policyActionReplicationRead
 	case "repl_oplog_apply":
 		return policyActionReplicationWrite
-	case "ops_run":
+	case "ops_run", "ops_damaged", "ops_key_versions", "ops_metrics", "ops_metrics_reset", "ops_request_logging", "ops_request_logging_set",
+		"ops_default_content_type", "ops_default_content_type_set", "ops_delete_protection", "ops_delete_protection_set":
 		return policyActionOps
 	case "list_buckets":
 		return policyActionListBuckets
@@ -439,7 +545,7 @@ func policyActionForRequest(op string) string {
 		return policyActionListBucket
 	case "get_bucket_policy":
 		return policyActionGetBucketPolicy
-	case "put_bucket_policy":
+	case "put_bucket_policy", "put_bucket_acl":
 		return policyActionPutBucketPolicy
 	case "delete_bucket_policy":
 		return policyActionDeleteBucketPolicy
@@ -447,15 +553,33 @@ func policyActionForRequest(op string) string {
 		return policyActionGetBucketVersioning
 	case "put_bucket_versioning":
 		return policyActionPutBucketVersioning
-	case "list_v1", "list_v2":
+	case "get_bucket_lifecycle":
+		return policyActionGetLifecycle
+	case "put_bucket_lifecycle", "delete_bucket_lifecycle":
+		return policyActionPutLifecycle
+	case "get_bucket_website":
+		return policyActionGetBucketWebsite
+	case "put_bucket_website":
+		return policyActionPutBucketWebsite
+	case "delete_bucket_website":
+		return policyActionDeleteBucketWebsite
+	case "get_bucket_tagging":
+		return policyActionGetBucketTagging
+	case "put_bucket_tagging", "delete_bucket_tagging":
+		return policyActionPutBucketTagging
+	case "get_bucket_notification":
+		return policyActionGetBucketNotification
+	case "put_bucket_notification", "delete_bucket_notification":
+		return policyActionPutBucketNotification
+	case "list_v1", "list_v2", "get_bucket_acl":
 		return policyActionListBucket
 	case "list_versions":
 		return policyActionListBucketVersions
-	case "get":
+	case "get", "get_object_acl":
 		return policyActionGetObject
 	case "head":
 		return policyActionHeadObject
-	case "put":
+	case "put", "move", "put_object_acl":
 		return policyActionPutObject
 	case "delete":
 		return policyActionDeleteObject
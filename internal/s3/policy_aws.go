@@ -69,30 +69,39 @@ func (l *awsStringList) UnmarshalJSON(data []byte) error {
 }
 
 var awsActionToPolicy = map[string]string{
-	"*":                       policyActionAll,
-	"listallmybuckets":        policyActionListBuckets,
-	"listbuckets":             policyActionListBuckets,
-	"listbucket":              policyActionListBucket,
-	"listbucketversions":      policyActionListBucketVersions,
-	"listobjectversions":      policyActionListBucketVersions,
-	"getbucketlocation":       policyActionGetBucketLocation,
-	"getbucketpolicy":         policyActionGetBucketPolicy,
-	"putbucketpolicy":         policyActionPutBucketPolicy,
-	"deletebucketpolicy":      policyActionDeleteBucketPolicy,
-	"getbucketversioning":     policyActionGetBucketVersioning,
-	"putbucketversioning":     policyActionPutBucketVersioning,
-	"getobject":               policyActionGetObject,
-	"headobject":              policyActionHeadObject,
-	"putobject":               policyActionPutObject,
-	"deleteobject":            policyActionDeleteObject,
-	"deletebucket":            policyActionDeleteBucket,
-	"copyobject":              policyActionCopyObject,
-	"createmultipartupload":   policyActionCreateMultipartUpload,
-	"uploadpart":              policyActionUploadPart,
-	"completemultipartupload": policyActionCompleteMultipart,
-	"abortmultipartupload":    policyActionAbortMultipart,
-	"listmultipartuploads":    policyActionListMultipartUploads,
-	"listmultipartparts":      policyActionListMultipartParts,
+	"*":                         policyActionAll,
+	"listallmybuckets":          policyActionListBuckets,
+	"listbuckets":               policyActionListBuckets,
+	"listbucket":                policyActionListBucket,
+	"listbucketversions":        policyActionListBucketVersions,
+	"listobjectversions":        policyActionListBucketVersions,
+	"getbucketlocation":         policyActionGetBucketLocation,
+	"getbucketpolicy":           policyActionGetBucketPolicy,
+	"putbucketpolicy":           policyActionPutBucketPolicy,
+	"deletebucketpolicy":        policyActionDeleteBucketPolicy,
+	"getbucketversioning":       policyActionGetBucketVersioning,
+	"putbucketversioning":       policyActionPutBucketVersioning,
+	"getlifecycleconfiguration": policyActionGetLifecycle,
+	"putlifecycleconfiguration": policyActionPutLifecycle,
+	"getbucketwebsite":          policyActionGetBucketWebsite,
+	"putbucketwebsite":          policyActionPutBucketWebsite,
+	"deletebucketwebsite":       policyActionDeleteBucketWebsite,
+	"getbuckettagging":          policyActionGetBucketTagging,
+	"putbuckettagging":          policyActionPutBucketTagging,
+	"getbucketnotification":     policyActionGetBucketNotification,
+	"putbucketnotification":     policyActionPutBucketNotification,
+	"getobject":                 policyActionGetObject,
+	"headobject":                policyActionHeadObject,
+	"putobject":                 policyActionPutObject,
+	"deleteobject":              policyActionDeleteObject,
+	"deletebucket":              policyActionDeleteBucket,
+	"copyobject":                policyActionCopyObject,
+	"createmultipartupload":     policyActionCreateMultipartUpload,
+	"uploadpart":                policyActionUploadPart,
+	"completemultipartupload":   policyActionCompleteMultipart,
+	"abortmultipartupload":      policyActionAbortMultipart,
+	"listmultipartuploads":      policyActionListMultipartUploads,
+	"listmultipartparts":        policyActionListMultipartParts,
 }
 
 func isAWSPolicyJSON(raw string) bool {
@@ -241,10 +250,14 @@ func mapAWSConditions(conds map[string]map[string]any) (Conditions, error) {
 			for key, value := range entries {
 				switch strings.ToLower(key) {
 				case "s3:prefix":
-					prefix, like, err := awsPrefixCondition(value, strings.EqualFold(op, "stringlike"))
+					prefix, like, patterns, err := awsPrefixCondition(value, strings.EqualFold(op, "stringlike"))
 					if err != nil {
 						return Conditions{}, err
 					}
+					if len(patterns) > 0 {
+						out.PrefixPatterns = append(out.PrefixPatterns, patterns...)
+						continue
+					}
 					if out.Prefix != "" && out.Prefix != prefix {
 						return Conditions{}, errors.New("aws policy condition prefix specified multiple times")
 					}
@@ -383,21 +396,32 @@ func awsBoolValue(value any) (bool, error) {
 	}
 }
 
-func awsPrefixCondition(value any, allowLike bool) (string, bool, error) {
-	prefix, err := awsSingleString(value)
-	if err != nil {
-		return "", false, err
-	}
-	if strings.Contains(prefix, "*") {
-		if !allowLike {
-			return "", false, errors.New("aws policy prefix wildcard not supported")
+// awsPrefixCondition maps an s3:prefix value to a plain or trailing-* prefix.
+// StringLike values with other wildcards or several values come back as
+// patterns for Conditions.PrefixPatterns instead.
+func awsPrefixCondition(value any, allowLike bool) (string, bool, []string, error) {
+	if !allowLike {
+		prefix, err := awsSingleString(value)
+		if err != nil {
+			return "", false, nil, err
 		}
-		if !strings.HasSuffix(prefix, "*") || strings.Contains(prefix[:len(prefix)-1], "*") {
-			return "", false, errors.New("aws policy prefix wildcard not supported")
+		if strings.Contains(prefix, "*") {
+			return "", false, nil, errors.New("aws policy prefix wildcard not supported")
 		}
-		return strings.TrimSuffix(prefix, "*"), true, nil
+		return prefix, false, nil, nil
+	}
+	values, err := awsStringValue(value)
+	if err != nil {
+		return "", false, nil, err
+	}
+	if len(values) == 0 {
+		return "", false, nil, errors.New("aws policy condition requires a value")
+	}
+	if len(values) == 1 && !strings.ContainsAny(strings.TrimSuffix(values[0], "*"), "*?") {
+		prefix := values[0]
+		return strings.TrimSuffix(prefix, "*"), strings.HasSuffix(prefix, "*"), nil, nil
 	}
-	return prefix, false, nil
+	return "", false, values, nil
 }
 
 func validateAWSPrincipal(principal any) error {
@@ -460,3 +460,47 @@ func TestPolicyConditionsSourceIPMultipleCIDR(t *testing.T) {
 		t.Fatalf("list objects status: %d", resp2.StatusCode)
 	}
 }
+
+func TestPolicyMoveRequiresSourceGetAndDelete(t *testing.T) {
+	policy := `{"version":"v1","statements":[{"effect":"allow","actions":["PutObject"],"resources":[{"bucket":"demo"}]}]}`
+	handler := newPolicyHandler(t, policy)
+
+	if _, _, err := handler.Engine.PutObject(context.Background(), "demo", "src", "", bytes.NewReader([]byte("x"))); err != nil {
+		t.Fatalf("PutObject seed: %v", err)
+	}
+
+	req := newTestRequest(http.MethodPut, "/demo/dst", nil)
+	req.Header.Set(moveSourceHeader, "/demo/src")
+	signRequestTest(req, "ak", "sk", "us-east-1")
+	resp := doRequest(t, handler, req)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("MOVE without delete status: %d", resp.StatusCode)
+	}
+
+	policy = `{"version":"v1","statements":[{"effect":"allow","actions":["PutObject","DeleteObject"],"resources":[{"bucket":"demo"}]}]}`
+	if err := handler.Meta.UpsertAPIKey(context.Background(), "ak", "sk", policy, true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	req = newTestRequest(http.MethodPut, "/demo/dst", nil)
+	req.Header.Set(moveSourceHeader, "/demo/src")
+	signRequestTest(req, "ak", "sk", "us-east-1")
+	resp = doRequest(t, handler, req)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("MOVE without get status: %d", resp.StatusCode)
+	}
+
+	policy = `{"version":"v1","statements":[{"effect":"allow","actions":["PutObject","GetObject","DeleteObject"],"resources":[{"bucket":"demo"}]}]}`
+	if err := handler.Meta.UpsertAPIKey(context.Background(), "ak", "sk", policy, true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	req = newTestRequest(http.MethodPut, "/demo/dst", nil)
+	req.Header.Set(moveSourceHeader, "/demo/src")
+	signRequestTest(req, "ak", "sk", "us-east-1")
+	resp = doRequest(t, handler, req)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("MOVE status: %d", resp.StatusCode)
+	}
+}
@@ -36,6 +36,23 @@ func TestParsePolicyJSON(t *testing.T) {
 	}
 }
 
+func TestParsePolicyResourcePattern(t *testing.T) {
+	raw := `{"version":"v1","statements":[{"effect":"allow","actions":["GetObject","ListBucket"],"resources":["demo/tenant-a/*"]}]}`
+	pol, err := ParsePolicy(raw)
+	if err != nil {
+		t.Fatalf("ParsePolicy pattern: %v", err)
+	}
+	if !pol.Allows("GetObject", "demo", "tenant-a/x") || !pol.Allows("ListBucket", "demo", "tenant-a/") {
+		t.Fatalf("expected allow within prefix")
+	}
+	if pol.Allows("GetObject", "demo", "tenant-b/x") || pol.Allows("ListBucket", "demo", "") {
+		t.Fatalf("expected deny outside prefix")
+	}
+	if _, err := ParsePolicy(`{"version":"v1","statements":[{"effect":"allow","actions":["GetObject"],"resources":["demo/*/x"]}]}`); err == nil {
+		t.Fatalf("expected error for inner wildcard")
+	}
+}
+
 func TestPolicyDenyOverrides(t *testing.T) {
 	raw := `{"version":"v1","statements":[{"effect":"allow","actions":["GetObject"],"resources":[{"bucket":"*"}]},{"effect":"deny","actions":["GetObject"],"resources":[{"bucket":"demo","prefix":"secret/"}]}]}`
 	pol, err := ParsePolicy(raw)
@@ -287,6 +304,61 @@ func TestParsePolicyAWSConditionsPrefixLike(t *testing.T) {
 	}
 }
 
+func TestParsePolicyAWSConditionsPrefixPatterns(t *testing.T) {
+	raw := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": "s3:ListBucket",
+      "Resource": "arn:aws:s3:::demo",
+      "Condition": {
+        "StringLike": { "s3:prefix": ["home/*/docs/*", "shared/v?/"] }
+      }
+    }
+  ]
+}`
+	pol, err := ParsePolicy(raw)
+	if err != nil {
+		t.Fatalf("ParsePolicy aws: %v", err)
+	}
+	cases := map[string]bool{
+		"home/alice/docs/":        true,
+		"home/alice/docs/2025/q1": true,
+		"home/alice/photos/":      false,
+		"shared/v2/":              true,
+		"shared/v10/":             false,
+		"":                        false,
+	}
+	for prefix, want := range cases {
+		ctx := &PolicyContext{Now: time.Now().UTC(), Prefix: prefix}
+		if allowed, _ := pol.DecisionWithContext("ListBucket", "demo", "", ctx); allowed != want {
+			t.Fatalf("prefix %q: allowed=%v want %v", prefix, allowed, want)
+		}
+	}
+}
+
+func TestLikeMatch(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"a*c", "abbbc", true},
+		{"a*c", "abbbd", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"*/logs/*", "x/y/logs/z", true},
+		{"a**b", "ab", true},
+		{"abc", "abcd", false},
+	}
+	for _, tc := range cases {
+		if got := likeMatch(tc.pattern, tc.s); got != tc.want {
+			t.Fatalf("likeMatch(%q, %q)=%v want %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
+
 func FuzzParsePolicyAWS(f *testing.F) {
 	valid := `{
   "Version": "2012-10-17",
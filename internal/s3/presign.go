@@ -10,12 +10,18 @@ import (
 	"time"
 )
 
-// Presign builds a presigned URL for a request.
-func (c *AuthConfig) Presign(method, rawURL string, expires time.Duration) (string, error) {
+// Presign builds a presigned URL for a request, scoped to region (empty =
+// the configured Region).
+// Query parameters already on rawURL (e.g. uploads, uploadId, partNumber for
+// multipart) are covered by the signature.
+func (c *AuthConfig) Presign(method, rawURL, region string, expires time.Duration) (string, error) {
 	if c == nil || c.AccessKey == "" || c.SecretKey == "" {
 		return "", errAccessDenied
 	}
-	if expires <= 0 || expires > 7*24*time.Hour {
+	if region == "" {
+		region = c.Region
+	}
+	if expires <= 0 || expires > c.presignExpiryLimit() {
 		return "", errSignatureMismatch
 	}
 	u, err := url.Parse(rawURL)
@@ -24,7 +30,7 @@ func (c *AuthConfig) Presign(method, rawURL string, expires time.Duration) (stri
 	}
 	amzDate := c.now().UTC().Format("20060102T150405Z")
 	dateScope := amzDate[:8]
-	scope := dateScope + "/" + c.Region + "/s3/aws4_request"
+	scope := dateScope + "/" + region + "/s3/aws4_request"
 
 	query := u.Query()
 	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
@@ -49,7 +55,7 @@ func (c *AuthConfig) Presign(method, rawURL string, expires time.Duration) (stri
 		scope,
 		hex.EncodeToString(hash[:]),
 	}, "\n")
-	signingKey := deriveSigningKey(c.SecretKey, dateScope, c.Region, "s3")
+	signingKey := deriveSigningKey(c.SecretKey, dateScope, region, "s3")
 	signature := hmacSHA256Hex(signingKey, stringToSign)
 	query = u.Query()
 	query.Set("X-Amz-Signature", signature)
@@ -57,6 +63,9 @@ func (c *AuthConfig) Presign(method, rawURL string, expires time.Duration) (stri
 	return u.String(), nil
 }
 
+// canonicalQueryPresignedFromValues is the canonical query string of a
+// presigned request: every parameter except X-Amz-Signature, sorted and
+// RFC 3986 encoded. Presign and verifyPresigned both use it.
 func canonicalQueryPresignedFromValues(values url.Values) string {
 	copied := make(url.Values, len(values))
 	for k, vs := range values {
@@ -0,0 +1,82 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func TestPresignedMultipartUpload(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := engine.New(engine.Options{Layout: fs.NewLayout(filepath.Join(dir, "objects")), MetaStore: store})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	handler := &Handler{
+		Engine: eng,
+		Meta:   store,
+		Auth: &AuthConfig{
+			AccessKey:            "test",
+			SecretKey:            "testsecret",
+			Region:               "us-east-1",
+			AllowUnsignedPayload: true,
+			MaxSkew:              5 * time.Minute,
+		},
+	}
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		presigned, err := handler.Auth.Presign(method, "http://example.com"+target, "", 5*time.Minute)
+		if err != nil {
+			t.Fatalf("Presign %s %s: %v", method, target, err)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, presigned, strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: status %d: %s", method, target, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	var initResult initiateMultipartResult
+	if err := xml.Unmarshal(serve(http.MethodPost, "/bucket/key?uploads", "").Body.Bytes(), &initResult); err != nil || initResult.UploadID == "" {
+		t.Fatalf("init decode: %+v (%v)", initResult, err)
+	}
+	uploadID := url.QueryEscape(initResult.UploadID)
+	etag1 := serve(http.MethodPut, "/bucket/key?partNumber=1&uploadId="+uploadID, strings.Repeat("a", 5<<20)).Header().Get("ETag")
+
+	tampered, err := handler.Auth.Presign(http.MethodPut, "http://example.com/bucket/key?partNumber=3&uploadId="+uploadID, "", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Presign: %v", err)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, strings.Replace(tampered, "partNumber=3", "partNumber=2", 1), strings.NewReader("tail")))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for altered partNumber, got %d", w.Code)
+	}
+	etag2 := serve(http.MethodPut, "/bucket/key?uploadId="+uploadID+"&partNumber=2", "tail").Header().Get("ETag")
+	completeBody := `<CompleteMultipartUpload>` +
+		`<Part><PartNumber>1</PartNumber><ETag>` + etag1 + `</ETag></Part>` +
+		`<Part><PartNumber>2</PartNumber><ETag>` + etag2 + `</ETag></Part>` +
+		`</CompleteMultipartUpload>`
+	serve(http.MethodPost, "/bucket/key?uploadId="+uploadID, completeBody)
+
+	body := serve(http.MethodGet, "/bucket/key", "").Body.String()
+	if len(body) != 5<<20+len("tail") || !strings.HasSuffix(body, "tail") {
+		t.Fatalf("unexpected object size %d", len(body))
+	}
+}
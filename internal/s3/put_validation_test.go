@@ -1,14 +1,21 @@
 package s3
 
 import (
+	"bufio"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
+	"encoding/xml"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
 )
 
 func TestPutValidatesContentMD5(t *testing.T) {
@@ -119,6 +126,63 @@ func TestPutRequiresIfMatchOnOverwrite(t *testing.T) {
 	}
 }
 
+// eofHookReader runs hook once when the wrapped reader reaches EOF.
+type eofHookReader struct {
+	r    io.Reader
+	hook func()
+}
+
+func (e *eofHookReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err == io.EOF && e.hook != nil {
+		e.hook()
+		e.hook = nil
+	}
+	return n, err
+}
+
+func TestPutIfMatchRecheckedAgainstInterleavedApply(t *testing.T) {
+	h := newTestHandler(t)
+	first := httptest.NewRecorder()
+	h.ServeHTTP(first, httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader("first")))
+	if first.Code != http.StatusOK {
+		t.Fatalf("initial PUT status: %d", first.Code)
+	}
+	etag := first.Header().Get("ETag")
+
+	// The replicated put lands after enforceIfMatch passed but before the
+	// barrier commits the conditional write.
+	body := &eofHookReader{r: strings.NewReader("second"), hook: func() {
+		entry := meta.OplogEntry{
+			SiteID:    "site-remote",
+			HLCTS:     "9000000000000000000-0000000001",
+			OpType:    "put",
+			Bucket:    "bucket",
+			Key:       "key",
+			VersionID: "remote-v1",
+			Payload:   `{"etag":"remoteetag","size":6,"last_modified_utc":"2025-12-22T12:00:00Z"}`,
+		}
+		if _, err := h.Meta.ApplyOplogEntries(context.Background(), []meta.OplogEntry{entry}); err != nil {
+			t.Errorf("ApplyOplogEntries: %v", err)
+		}
+	}}
+	req := httptest.NewRequest(http.MethodPut, "/bucket/key", body)
+	req.ContentLength = int64(len("second"))
+	req.Header.Set("If-Match", etag)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d: %s", w.Code, w.Body.String())
+	}
+	current, err := h.Meta.GetObjectMeta(context.Background(), "bucket", "key")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if current.VersionID != "remote-v1" {
+		t.Fatalf("expected remote version to stay current, got %s", current.VersionID)
+	}
+}
+
 func TestPutRequiresIfMatchSkipsDeleteMarker(t *testing.T) {
 	h := newTestHandler(t)
 	h.RequireIfMatchBuckets = map[string]struct{}{"bucket": {}}
@@ -145,6 +209,63 @@ func TestPutRequiresIfMatchSkipsDeleteMarker(t *testing.T) {
 	}
 }
 
+func TestDeleteHonorsIfMatch(t *testing.T) {
+	h := newTestHandler(t)
+	h.RequireIfMatchBuckets = map[string]struct{}{"bucket": {}}
+	if err := h.Meta.CreateBucketWithVersioning(context.Background(), "plain", meta.BucketVersioningDisabled); err != nil {
+		t.Fatalf("CreateBucketWithVersioning: %v", err)
+	}
+	do := func(method, target, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, bucket := range []string{"bucket", "plain"} {
+		put := do(http.MethodPut, "/"+bucket+"/key", "", "data")
+		if put.Code != http.StatusOK {
+			t.Fatalf("%s: PUT status: %d", bucket, put.Code)
+		}
+		if w := do(http.MethodDelete, "/"+bucket+"/key", "\"deadbeef\"", ""); w.Code != http.StatusPreconditionFailed {
+			t.Fatalf("%s: expected 412 on mismatch, got %d", bucket, w.Code)
+		}
+		if w := do(http.MethodGet, "/"+bucket+"/key", "", ""); w.Code != http.StatusOK {
+			t.Fatalf("%s: object must survive a failed delete, got %d", bucket, w.Code)
+		}
+		if w := do(http.MethodDelete, "/"+bucket+"/key", put.Header().Get("ETag"), ""); w.Code != http.StatusNoContent {
+			t.Fatalf("%s: expected 204 on match, got %d", bucket, w.Code)
+		}
+		// Missing keys and delete markers have no ETag to match.
+		if w := do(http.MethodDelete, "/"+bucket+"/key", "*", ""); w.Code != http.StatusPreconditionFailed {
+			t.Fatalf("%s: expected 412 without a current version, got %d", bucket, w.Code)
+		}
+	}
+
+	// -require-if-match-buckets guards overwrites only.
+	if w := do(http.MethodPut, "/bucket/other", "", "data"); w.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/bucket/other", "", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected unconditional delete to pass, got %d", w.Code)
+	}
+
+	put := do(http.MethodPut, "/bucket/versioned", "", "v1")
+	versionID := put.Header().Get("x-amz-version-id")
+	if do(http.MethodPut, "/bucket/versioned", put.Header().Get("ETag"), "v2").Code != http.StatusOK {
+		t.Fatalf("overwrite failed")
+	}
+	if w := do(http.MethodDelete, "/bucket/versioned?versionId="+versionID, "\"deadbeef\"", ""); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for version delete mismatch, got %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/bucket/versioned?versionId="+versionID, put.Header().Get("ETag"), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for version delete match, got %d %s", w.Code, w.Body.String())
+	}
+}
+
 func TestCopyRequiresIfMatchOnOverwrite(t *testing.T) {
 	h := newTestHandler(t)
 	h.RequireIfMatchBuckets = map[string]struct{}{"bucket": {}}
@@ -185,6 +306,64 @@ func TestCopyRequiresIfMatchOnOverwrite(t *testing.T) {
 	}
 }
 
+func TestCopySourceConditionals(t *testing.T) {
+	h := newTestHandler(t)
+	putReq := httptest.NewRequest(http.MethodPut, "/bucket/src", strings.NewReader("data"))
+	putW := httptest.NewRecorder()
+	h.ServeHTTP(putW, putReq)
+	if putW.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d", putW.Code)
+	}
+	srcETag := putW.Header().Get("ETag")
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC1123)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC1123)
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{name: "if-match ok", headers: map[string]string{"x-amz-copy-source-if-match": srcETag}, want: http.StatusOK},
+		{name: "if-match mismatch", headers: map[string]string{"x-amz-copy-source-if-match": `"deadbeef"`}, want: http.StatusPreconditionFailed},
+		{name: "if-none-match hit", headers: map[string]string{"x-amz-copy-source-if-none-match": srcETag}, want: http.StatusPreconditionFailed},
+		{name: "if-none-match miss", headers: map[string]string{"x-amz-copy-source-if-none-match": `"deadbeef"`}, want: http.StatusOK},
+		{name: "if-modified-since future", headers: map[string]string{"x-amz-copy-source-if-modified-since": future}, want: http.StatusPreconditionFailed},
+		{name: "if-modified-since past", headers: map[string]string{"x-amz-copy-source-if-modified-since": past}, want: http.StatusOK},
+		{name: "if-unmodified-since past", headers: map[string]string{"x-amz-copy-source-if-unmodified-since": past}, want: http.StatusPreconditionFailed},
+		{name: "if-unmodified-since future", headers: map[string]string{"x-amz-copy-source-if-unmodified-since": future}, want: http.StatusOK},
+		{
+			name: "if-match wins over if-unmodified-since",
+			headers: map[string]string{
+				"x-amz-copy-source-if-match":            srcETag,
+				"x-amz-copy-source-if-unmodified-since": past,
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "if-none-match wins over if-modified-since",
+			headers: map[string]string{
+				"x-amz-copy-source-if-none-match":     `"deadbeef"`,
+				"x-amz-copy-source-if-modified-since": future,
+			},
+			want: http.StatusOK,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/bucket/dst", nil)
+			req.Header.Set("X-Amz-Copy-Source", "/bucket/src")
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
+
 func TestGetSetsContentTypeAndConditionals(t *testing.T) {
 	h := newTestHandler(t)
 	req := httptest.NewRequest(http.MethodPut, "/bucket/key", strings.NewReader("data"))
@@ -219,6 +398,52 @@ func TestGetSetsContentTypeAndConditionals(t *testing.T) {
 	}
 }
 
+func TestPutSniffsContentType(t *testing.T) {
+	h := newTestHandler(t)
+	h.SniffContentTypes = map[string]struct{}{"sniffed": {}}
+	png := append([]byte("\x89PNG\r\n\x1a\n"), []byte(strings.Repeat("p", 2000))...)
+	cases := []struct {
+		name        string
+		bucket      string
+		body        string
+		contentType string
+		want        string
+	}{
+		{"png past the peek", "sniffed", string(png), "", "image/png"},
+		{"short html", "sniffed", "<html><body>hi</body></html>", "", "text/html; charset=utf-8"},
+		{"explicit type kept", "sniffed", string(png), "application/x-custom", "application/x-custom"},
+		{"empty body", "sniffed", "", "", "application/octet-stream"},
+		{"bucket not opted in", "plain", string(png), "", "application/octet-stream"},
+	}
+	for i, tc := range cases {
+		key := fmt.Sprintf("key-%d", i)
+		sum := md5.Sum([]byte(tc.body))
+		req := httptest.NewRequest(http.MethodPut, "/"+tc.bucket+"/"+key, strings.NewReader(tc.body))
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: PUT status=%d body=%s", tc.name, w.Code, w.Body.String())
+		}
+		metaObj, err := h.Meta.GetObjectMeta(context.Background(), tc.bucket, key)
+		if err != nil {
+			t.Fatalf("%s: GetObjectMeta: %v", tc.name, err)
+		}
+		if metaObj.ContentType != tc.want {
+			t.Fatalf("%s: content-type=%q want %q", tc.name, metaObj.ContentType, tc.want)
+		}
+		getReq := httptest.NewRequest(http.MethodGet, "/"+tc.bucket+"/"+key, nil)
+		getW := httptest.NewRecorder()
+		h.ServeHTTP(getW, getReq)
+		if getW.Body.String() != tc.body {
+			t.Fatalf("%s: body changed by sniffing (%d bytes, want %d)", tc.name, getW.Body.Len(), len(tc.body))
+		}
+	}
+}
+
 func TestOptionsCORS(t *testing.T) {
 	h := newTestHandler(t)
 	req := httptest.NewRequest(http.MethodOptions, "/bucket/key", nil)
@@ -236,3 +461,237 @@ func TestOptionsCORS(t *testing.T) {
 		t.Fatalf("expected Access-Control-Allow-Methods to be set")
 	}
 }
+
+func TestCORSActualResponseHeaders(t *testing.T) {
+	h := newTestHandler(t)
+	origin := "https://app.example.com"
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/bucket/missing", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get()
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("expected *, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "ETag, x-amz-version-id" {
+		t.Fatalf("unexpected expose headers %q", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Fatalf("expected Vary: Origin, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Fatalf("unexpected credentials header %q", got)
+	}
+
+	h.CORSAllowCredentials = true
+	h.CORSExposeHeaders = []string{"ETag"}
+	w = get()
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != origin {
+		t.Fatalf("expected echoed origin with credentials, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Fatalf("expected credentials header, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "ETag" {
+		t.Fatalf("unexpected expose headers %q", got)
+	}
+}
+
+func TestObjectKeyValidationConsistentAcrossMethods(t *testing.T) {
+	h := newTestHandler(t)
+	cases := []struct {
+		name string
+		path string
+		code string
+	}{
+		{name: "nul", path: "/bucket/bad%00key", code: "InvalidArgument"},
+		{name: "control", path: "/bucket/bad%01key", code: "InvalidArgument"},
+		{name: "invalid utf8", path: "/bucket/bad%ffkey", code: "InvalidArgument"},
+		{name: "too long", path: "/bucket/" + strings.Repeat("k", 1025), code: "KeyTooLongError"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, method := range []string{http.MethodPut, http.MethodGet, http.MethodDelete} {
+				req := httptest.NewRequest(method, tc.path, strings.NewReader("x"))
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+				if w.Code != http.StatusBadRequest {
+					t.Fatalf("%s: expected 400, got %d", method, w.Code)
+				}
+				if !strings.Contains(w.Body.String(), tc.code) {
+					t.Fatalf("%s: expected %s, got %s", method, tc.code, w.Body.String())
+				}
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/bucket/"+strings.Repeat("k", 1024), strings.NewReader("x"))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 1024-byte key to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDotSegmentKeysRoundTrip(t *testing.T) {
+	h := newTestHandler(t)
+	cases := []struct{ path, key string }{
+		{path: "/bucket//key", key: "/key"},
+		{path: "/bucket/a//b", key: "a//b"},
+		{path: "/bucket/./key", key: "./key"},
+		{path: "/bucket/a/../key", key: "a/../key"},
+		{path: "/bucket/dir/", key: "dir/"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, tc.path, strings.NewReader(tc.key)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("PUT %s: status %d: %s", tc.path, w.Code, w.Body.String())
+		}
+		if _, err := h.Meta.CurrentVersion(t.Context(), "bucket", tc.key); err != nil {
+			t.Fatalf("PUT %s: expected stored key %q: %v", tc.path, tc.key, err)
+		}
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != tc.key {
+			t.Fatalf("GET %s: status %d body %q", tc.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRejectAmbiguousKeys(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "bucket", "a/../key", "legacy")
+	h.RejectAmbiguousKeys = true
+	for _, path := range []string{"/bucket//key", "/bucket/a//b", "/bucket/./key", "/bucket/a/..", "/bucket/a/../key"} {
+		for _, req := range []*http.Request{
+			httptest.NewRequest(http.MethodPut, path, strings.NewReader("x")),
+			httptest.NewRequest(http.MethodPost, path+"?uploads", nil),
+		} {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "InvalidArgument") {
+				t.Fatalf("%s %s: expected 400 InvalidArgument, got %d %s", req.Method, path, w.Code, w.Body.String())
+			}
+		}
+	}
+	for _, key := range []string{"dir/", "a.b/..c", "key"} {
+		putObject(t, h, "bucket", key, "ok")
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/bucket/a/../key", nil))
+		if w.Code != http.StatusOK && w.Code != http.StatusNoContent {
+			t.Fatalf("%s existing key: status %d", method, w.Code)
+		}
+	}
+}
+
+func TestControlBodyLimit(t *testing.T) {
+	h := newTestHandler(t)
+	h.MaxBodyBytes = 64
+	putObject(t, h, "bucket", "seed", "x")
+
+	big := "<VersioningConfiguration><Status>Enabled</Status>" + strings.Repeat(" ", 64) + "</VersioningConfiguration>"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/bucket?versioning", strings.NewReader(big)))
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "EntityTooLarge") {
+		t.Fatalf("versioning: expected 413 EntityTooLarge, got %d %s", w.Code, w.Body.String())
+	}
+
+	initW := httptest.NewRecorder()
+	h.ServeHTTP(initW, httptest.NewRequest(http.MethodPost, "/bucket/mpu?uploads", nil))
+	if initW.Code != http.StatusOK {
+		t.Fatalf("init status: %d", initW.Code)
+	}
+	var initResp initiateMultipartResult
+	if err := xml.NewDecoder(initW.Body).Decode(&initResp); err != nil {
+		t.Fatalf("init decode: %v", err)
+	}
+	complete := "<CompleteMultipartUpload>" + strings.Repeat("<Part><PartNumber>1</PartNumber><ETag>\"x\"</ETag></Part>", 4) + "</CompleteMultipartUpload>"
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bucket/mpu?uploadId="+initResp.UploadID, strings.NewReader(complete)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("complete: expected 413, got %d %s", w.Code, w.Body.String())
+	}
+
+	// Object bodies are governed by MaxObjectSize, not MaxBodyBytes.
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/bucket/large", strings.NewReader(strings.Repeat("a", 1024)))
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("object PUT: expected 200, got %d", w.Code)
+	}
+}
+
+func TestPutExpectContinueRejectsBeforeBody(t *testing.T) {
+	h := newTestHandler(t)
+	h.MaxObjectSize = 1 << 20
+	putObject(t, h, "bucket", "existing", "data")
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	send := func(t *testing.T, path string, length int, header string) (*bufio.Reader, net.Conn) {
+		t.Helper()
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial: %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		fmt.Fprintf(conn, "PUT %s HTTP/1.1\r\nHost: example\r\nContent-Length: %d\r\nExpect: 100-continue\r\n%s\r\n", path, length, header)
+		return bufio.NewReader(conn), conn
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		length int
+		header string
+		want   int
+	}{
+		{"too large", "/bucket/big", 2 << 20, "", http.StatusRequestEntityTooLarge},
+		{"if-match", "/bucket/existing", 1024, "If-Match: \"nope\"\r\n", http.StatusPreconditionFailed},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The body is never sent: the rejection must arrive without a
+			// 100 Continue and without the server waiting for body bytes.
+			br, _ := send(t, tc.path, tc.length, tc.header)
+			resp, err := http.ReadResponse(br, nil)
+			if err != nil {
+				t.Fatalf("ReadResponse: %v", err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tc.want {
+				t.Fatalf("status=%d want %d", resp.StatusCode, tc.want)
+			}
+		})
+	}
+
+	t.Run("accepted", func(t *testing.T) {
+		br, conn := send(t, "/bucket/small", 5, "")
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("ReadResponse: %v", err)
+		}
+		if resp.StatusCode != http.StatusContinue {
+			t.Fatalf("status=%d want 100", resp.StatusCode)
+		}
+		if _, err := io.WriteString(conn, "hello"); err != nil {
+			t.Fatalf("write body: %v", err)
+		}
+		resp, err = http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("ReadResponse: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status=%d want 200", resp.StatusCode)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -65,6 +66,66 @@ func TestRangeHeadReturnsPartialContent(t *testing.T) {
 	}
 }
 
+func TestRangeGetPastEOFHeadersMatchBody(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "bucket", "key", "abcdefghij")
+
+	req := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+	req.Header.Set("Range", "bytes=7-100")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("status: %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 7-9/10" {
+		t.Fatalf("content-range: %q", got)
+	}
+	if got := w.Header().Get("Content-Length"); got != strconv.Itoa(w.Body.Len()) || w.Body.String() != "hij" {
+		t.Fatalf("content-length %q for body %q", got, w.Body.String())
+	}
+}
+
+func TestGetIfRange(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "bucket", "key", "abcdefghij")
+
+	head := httptest.NewRecorder()
+	h.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/bucket/key", nil))
+	etag := head.Header().Get("ETag")
+	lastModified := head.Header().Get("Last-Modified")
+
+	tests := []struct {
+		name    string
+		ifRange string
+		status  int
+		body    string
+	}{
+		{name: "etag match", ifRange: etag, status: http.StatusPartialContent, body: "bcd"},
+		{name: "date match", ifRange: lastModified, status: http.StatusPartialContent, body: "bcd"},
+		{name: "stale etag", ifRange: `"0123456789abcdef"`, status: http.StatusOK, body: "abcdefghij"},
+		{name: "weak etag", ifRange: "W/" + etag, status: http.StatusOK, body: "abcdefghij"},
+		{name: "stale date", ifRange: "Mon, 02 Jan 2006 15:04:05 GMT", status: http.StatusOK, body: "abcdefghij"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/bucket/key", nil)
+			req.Header.Set("Range", "bytes=1-3")
+			req.Header.Set("If-Range", tt.ifRange)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status: %d", w.Code)
+			}
+			if got := w.Body.String(); got != tt.body {
+				t.Fatalf("body: %q", got)
+			}
+			if tt.status == http.StatusOK && w.Header().Get("Content-Range") != "" {
+				t.Fatalf("unexpected content-range: %q", w.Header().Get("Content-Range"))
+			}
+		})
+	}
+}
+
 func newTestHandler(t *testing.T) *Handler {
 	t.Helper()
 	dir := t.TempDir()
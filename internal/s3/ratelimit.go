@@ -1,13 +1,17 @@
 package s3
 
 import (
+	"errors"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/kk-code-lab/seglake/internal/clock"
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
 )
 
 // AuthLimiter rate-limits failed auth attempts per IP and per access key.
@@ -71,11 +75,16 @@ func (l *AuthLimiter) Cleanup() {
 	l.perKey.cleanup(cutoff)
 }
 
-// InflightLimiter tracks concurrent requests per access key.
+// InflightLimiter tracks concurrent requests per access key. With a global
+// budget and a fair share, a key may use the whole budget while no other key
+// has requests in flight, but only its share once others are waiting.
 type InflightLimiter struct {
 	mu     sync.Mutex
 	limit  int64
 	counts map[string]int64
+	global int64
+	share  float64
+	total  int64
 }
 
 // NewInflightLimiter creates a limiter with a fixed per-key limit.
@@ -89,6 +98,19 @@ func NewInflightLimiter(limit int64) *InflightLimiter {
 	}
 }
 
+// SetFairShare caps all keys together at global inflight requests (<=0
+// disables) and, while more than one key is active, each key at share
+// (0..1) of that budget. The per-key limit still applies on top.
+func (l *InflightLimiter) SetFairShare(global int64, share float64) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.global = global
+	l.share = share
+}
+
 func (l *InflightLimiter) Acquire(key string) bool {
 	return l.AcquireWithLimit(key, 0)
 }
@@ -103,13 +125,32 @@ func (l *InflightLimiter) AcquireWithLimit(key string, limit int64) bool {
 	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.counts[key] >= limit {
+	current := l.counts[key]
+	if current >= limit {
 		return false
 	}
+	if l.global > 0 {
+		if l.total >= l.global {
+			return false
+		}
+		if l.share > 0 && l.share < 1 && l.total > current && current >= l.fairShareLocked() {
+			return false
+		}
+	}
 	l.counts[key]++
+	l.total++
 	return true
 }
 
+// fairShareLocked is the per-key cap while the budget is contended.
+func (l *InflightLimiter) fairShareLocked() int64 {
+	share := int64(float64(l.global) * l.share)
+	if share < 1 {
+		share = 1
+	}
+	return share
+}
+
 func (l *InflightLimiter) Release(key string) {
 	if l == nil || key == "" {
 		return
@@ -118,7 +159,49 @@ func (l *InflightLimiter) Release(key string) {
 	defer l.mu.Unlock()
 	if l.counts[key] > 0 {
 		l.counts[key]--
+		l.total--
+	}
+	if l.counts[key] == 0 {
+		delete(l.counts, key)
+	}
+}
+
+// Usage returns the current inflight requests per access key.
+func (l *InflightLimiter) Usage() map[string]int64 {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]int64, len(l.counts))
+	for key, n := range l.counts {
+		out[key] = n
+	}
+	return out
+}
+
+// writeSlowDown answers a limiter rejection with 503 SlowDown and a
+// Retry-After hint that grows with the op's recent rejection rate, so SDK
+// retries back off instead of hammering the limiter.
+func (h *Handler) writeSlowDown(w http.ResponseWriter, op, message, requestID, resource string) {
+	retryAfter := h.Metrics.ObserveSlowDown(op, h.now())
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(retryAfter/time.Second), 10))
+	writeErrorWithResource(w, http.StatusServiceUnavailable, "SlowDown", message, requestID, resource)
+}
+
+// writeInternalError answers an unexpected failure with 500 InternalError,
+// except that meta.db lock contention which outlasted the store's retries is
+// reported as 503 SlowDown so clients retry later.
+func (h *Handler) writeInternalError(w http.ResponseWriter, err error, requestID, resource string) {
+	if errors.Is(err, meta.ErrBusy) {
+		h.writeSlowDown(w, "meta_busy", "metadata database is busy", requestID, resource)
+		return
+	}
+	if errors.Is(err, engine.ErrSegmentRetired) {
+		h.writeSlowDown(w, "segment_retired", "referenced data is being compacted", requestID, resource)
+		return
 	}
+	writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
 }
 
 // Semaphore limits total concurrent operations.
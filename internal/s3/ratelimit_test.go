@@ -1,9 +1,16 @@
 package s3
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/kk-code-lab/seglake/internal/clock"
+	"github.com/kk-code-lab/seglake/internal/meta"
 )
 
 func TestAuthLimiterBlocksAfterBurst(t *testing.T) {
@@ -67,3 +74,105 @@ func TestInflightLimiterWithOverride(t *testing.T) {
 		t.Fatalf("expected acquire after release with override")
 	}
 }
+
+func TestInflightLimiterFairShare(t *testing.T) {
+	limiter := NewInflightLimiter(100)
+	limiter.SetFairShare(4, 0.5)
+
+	// Idle budget: one key may burst to the full global budget.
+	for i := 0; i < 4; i++ {
+		if !limiter.Acquire("hog") {
+			t.Fatalf("expected burst acquire %d", i)
+		}
+	}
+	if limiter.Acquire("hog") || limiter.Acquire("other") {
+		t.Fatalf("expected global budget exhausted")
+	}
+
+	// Once another key is active, the hog is held to its share (2 of 4).
+	limiter.Release("hog")
+	if !limiter.Acquire("other") {
+		t.Fatalf("expected other key to acquire freed slot")
+	}
+	limiter.Release("hog")
+	if limiter.Acquire("hog") {
+		t.Fatalf("expected hog capped at fair share while contended")
+	}
+	if !limiter.Acquire("other") {
+		t.Fatalf("expected other key to acquire within share")
+	}
+	usage := limiter.Usage()
+	if usage["hog"] != 2 || usage["other"] != 2 {
+		t.Fatalf("unexpected usage: %v", usage)
+	}
+
+	limiter.Release("other")
+	limiter.Release("other")
+	if _, ok := limiter.Usage()["other"]; ok {
+		t.Fatalf("expected idle key dropped from usage")
+	}
+	if !limiter.Acquire("hog") {
+		t.Fatalf("expected hog to burst again once idle")
+	}
+}
+
+func TestInflightLimiterFairShareKeepsPerKeyLimit(t *testing.T) {
+	limiter := NewInflightLimiter(2)
+	limiter.SetFairShare(10, 0.5)
+	if !limiter.Acquire("a") || !limiter.Acquire("a") {
+		t.Fatalf("expected acquires within per-key limit")
+	}
+	if limiter.Acquire("a") {
+		t.Fatalf("expected per-key limit to cap bursting")
+	}
+}
+
+func TestSlowDownSetsGrowingRetryAfter(t *testing.T) {
+	h := newTestHandler(t)
+	// A fixed clock keeps the rejections from decaying between requests.
+	h.Clock = clock.FixedClock{T: time.Now()}
+	h.Metrics = NewMetrics()
+	h.MPUCompleteLimiter = NewSemaphore(1)
+	if !h.MPUCompleteLimiter.Acquire() {
+		t.Fatalf("Acquire failed")
+	}
+	defer h.MPUCompleteLimiter.Release()
+
+	for _, want := range []string{"1", "2", "2", "4"} {
+		req := httptest.NewRequest(http.MethodPost, "/bucket/key?uploadId=u1", strings.NewReader("<CompleteMultipartUpload/>"))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "<Code>SlowDown</Code>") {
+			t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Retry-After"); got != want {
+			t.Fatalf("Retry-After=%q want %q", got, want)
+		}
+	}
+	if got := h.Metrics.SlowDowns(h.now())["mpu_complete"].Total; got != 4 {
+		t.Fatalf("mpu_complete slow downs=%d want 4", got)
+	}
+}
+
+func TestInternalErrorMapsMetaBusyToSlowDown(t *testing.T) {
+	h := newTestHandler(t)
+	h.Metrics = NewMetrics()
+
+	w := httptest.NewRecorder()
+	h.writeInternalError(w, fmt.Errorf("put: %w", fmt.Errorf("%w: database is locked", meta.ErrBusy)), "req", "/bucket/key")
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "<Code>SlowDown</Code>") {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected Retry-After on busy SlowDown")
+	}
+	if got := h.Metrics.SlowDowns(h.now())["meta_busy"].Total; got != 1 {
+		t.Fatalf("meta_busy slow downs=%d want 1", got)
+	}
+
+	w = httptest.NewRecorder()
+	h.writeInternalError(w, errors.New("disk on fire"), "req", "/bucket/key")
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "<Code>InternalError</Code>") {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+}
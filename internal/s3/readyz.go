@@ -0,0 +1,37 @@
+package s3
+
+import "net/http"
+
+// readyzPath lives under /v1 like the other non-S3 endpoints, so it does not
+// shadow a bucket.
+const readyzPath = "/v1/readyz"
+
+// isReadyzRequest matches the unauthenticated readiness probe.
+func isReadyzRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	return r.URL.Path == readyzPath
+}
+
+// handleReadyz reports 503 while storage is unavailable, e.g. after the last
+// write failed because the objects volume is full. While that lasts each
+// check probes the volume, since an unready node gets no writes that would
+// clear the state.
+func (h *Handler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	body := "ok\n"
+	switch {
+	case h.Engine == nil || h.Meta == nil:
+		status = http.StatusServiceUnavailable
+		body = "storage not initialized\n"
+	case h.Engine.ProbeDiskSpace():
+		status = http.StatusServiceUnavailable
+		body = "insufficient storage\n"
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write([]byte(body))
+	}
+}
@@ -0,0 +1,34 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyzIsUnauthenticated(t *testing.T) {
+	h := newTestHandler(t)
+	h.Auth = &AuthConfig{AccessKey: "test", SecretKey: "testsecret", Region: "us-east-1"}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, readyzPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("readyz status: %d body=%s", w.Code, w.Body.String())
+	}
+
+	// A bucket named readyz is an ordinary bucket.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected bucket listing to require auth, got %d", w.Code)
+	}
+}
+
+func TestReadyzRequiresStorage(t *testing.T) {
+	h := &Handler{}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, readyzPath, nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz status: %d", w.Code)
+	}
+}
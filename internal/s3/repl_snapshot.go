@@ -0,0 +1,220 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/ops"
+)
+
+// Snapshots are cached so a bootstrap can resume an interrupted download by
+// id with Range requests; entries expire after replSnapshotTTL.
+const replSnapshotTTL = time.Hour
+
+// A request without an id reuses the newest snapshot younger than
+// replSnapshotReuse, so it keeps at least half its TTL for resumes. At most
+// replSnapshotMaxKept snapshots stay on disk; the oldest are dropped first.
+const (
+	replSnapshotReuse   = replSnapshotTTL / 2
+	replSnapshotMaxKept = 2
+)
+
+const (
+	replSnapshotIDHeader     = "X-Seglake-Snapshot-Id"
+	replSnapshotSHA256Header = "X-Seglake-Snapshot-Sha256"
+	replSnapshotSizeHeader   = "X-Seglake-Snapshot-Size"
+	replSiteIDHeader         = "X-Seglake-Site-Id"
+)
+
+type replSnapshot struct {
+	id      string
+	path    string
+	sha256  string
+	size    int64
+	created time.Time
+}
+
+func (h *Handler) handleReplicationSnapshot(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+	if h == nil || h.Engine == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "storage not initialized", requestID, r.URL.Path)
+		return
+	}
+	var (
+		snap *replSnapshot
+		err  error
+	)
+	if id := strings.TrimSpace(r.URL.Query().Get("id")); id != "" {
+		snap = h.cachedReplSnapshot(id)
+		if snap == nil {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "snapshot expired", requestID, r.URL.Path)
+			return
+		}
+	} else {
+		snap, err = h.currentReplSnapshot(ctx)
+		if err != nil {
+			writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "snapshot failed", requestID, r.URL.Path)
+			return
+		}
+	}
+	file, err := os.Open(snap.path)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "snapshot expired", requestID, r.URL.Path)
+		return
+	}
+	defer func() { _ = file.Close() }()
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"snapshot.tar.gz\"")
+	w.Header().Set("ETag", `"`+snap.id+`"`)
+	w.Header().Set(replSnapshotIDHeader, snap.id)
+	w.Header().Set(replSnapshotSHA256Header, snap.sha256)
+	w.Header().Set(replSnapshotSizeHeader, strconv.FormatInt(snap.size, 10))
+	if h.Meta != nil {
+		w.Header().Set(replSiteIDHeader, h.Meta.SiteID())
+	}
+	http.ServeContent(w, r, "snapshot.tar.gz", snap.created, file)
+}
+
+func (h *Handler) replSnapshotDir() string {
+	return filepath.Join(filepath.Dir(h.Engine.Layout().Root), ".repl-snapshots")
+}
+
+func (h *Handler) cachedReplSnapshot(id string) *replSnapshot {
+	h.replSnapshotMu.Lock()
+	defer h.replSnapshotMu.Unlock()
+	snap := h.replSnapshots[id]
+	if snap == nil || h.now().Sub(snap.created) > replSnapshotTTL {
+		return nil
+	}
+	return snap
+}
+
+// currentReplSnapshot returns the newest reusable snapshot, building one when
+// there is none. Builds are serialized, so concurrent bootstraps share one.
+func (h *Handler) currentReplSnapshot(ctx context.Context) (*replSnapshot, error) {
+	h.replSnapshotBuildMu.Lock()
+	defer h.replSnapshotBuildMu.Unlock()
+	h.replSnapshotMu.Lock()
+	var newest *replSnapshot
+	for _, snap := range h.replSnapshots {
+		if h.now().Sub(snap.created) <= replSnapshotReuse && (newest == nil || snap.created.After(newest.created)) {
+			newest = snap
+		}
+	}
+	h.replSnapshotMu.Unlock()
+	if newest != nil {
+		return newest, nil
+	}
+	return h.buildReplSnapshot(ctx)
+}
+
+// buildReplSnapshot writes a fresh snapshot tarball to disk, records its
+// checksum and drops expired snapshots and those over replSnapshotMaxKept.
+// The tarball holds meta.db with API secrets, so only the owner may read it.
+func (h *Handler) buildReplSnapshot(ctx context.Context) (*replSnapshot, error) {
+	layout := h.Engine.Layout()
+	metaPath := filepath.Join(filepath.Dir(layout.Root), "meta.db")
+	dir := h.replSnapshotDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	// Tighten a directory created by an older version.
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp("", "seglake-snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	if _, err := ops.Snapshot(layout, metaPath, tmpDir); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	id := newRequestID()
+	path := filepath.Join(dir, id+".tar.gz")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	hasher := sha256.New()
+	counter := &countingWriter{}
+	if err := writeTarGz(io.MultiWriter(file, hasher, counter), tmpDir); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	snap := &replSnapshot{
+		id:      id,
+		path:    path,
+		sha256:  hex.EncodeToString(hasher.Sum(nil)),
+		size:    counter.n,
+		created: h.now().UTC(),
+	}
+	h.replSnapshotMu.Lock()
+	defer h.replSnapshotMu.Unlock()
+	if h.replSnapshots == nil {
+		h.replSnapshots = make(map[string]*replSnapshot)
+	}
+	for oldID, old := range h.replSnapshots {
+		if snap.created.Sub(old.created) > replSnapshotTTL {
+			_ = os.Remove(old.path)
+			delete(h.replSnapshots, oldID)
+		}
+	}
+	h.replSnapshots[id] = snap
+	for len(h.replSnapshots) > replSnapshotMaxKept {
+		var oldest *replSnapshot
+		for _, old := range h.replSnapshots {
+			if oldest == nil || old.created.Before(oldest.created) {
+				oldest = old
+			}
+		}
+		_ = os.Remove(oldest.path)
+		delete(h.replSnapshots, oldest.id)
+	}
+	pruneStaleReplSnapshots(dir, h.replSnapshots)
+	return snap, nil
+}
+
+// pruneStaleReplSnapshots removes snapshot files no cached entry refers to,
+// such as those left by earlier runs. Callers hold replSnapshotBuildMu, so no
+// other snapshot is being written.
+func pruneStaleReplSnapshots(dir string, live map[string]*replSnapshot) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), ".tar.gz")
+		if _, ok := live[id]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+	}
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,75 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+const defaultReplLagThreshold = time.Minute
+
+type replStatusResponse struct {
+	SiteID              string             `json:"site_id"`
+	MaxOplogHLC         string             `json:"max_oplog_hlc"`
+	LagThresholdSeconds float64            `json:"lag_threshold_seconds"`
+	Remotes             []replRemoteStatus `json:"remotes"`
+}
+
+type replRemoteStatus struct {
+	meta.ReplStat
+	Healthy bool `json:"healthy"`
+}
+
+// handleReplicationStatus reports per-remote pull/push lag and backlog with a
+// derived health flag for monitoring.
+func (h *Handler) handleReplicationStatus(ctx context.Context, w http.ResponseWriter, requestID string, resource string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, resource)
+		return
+	}
+	stats, err := h.Meta.GetReplStats(ctx)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, resource)
+		return
+	}
+	maxHLC, err := h.Meta.MaxOplogHLC(ctx)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, resource)
+		return
+	}
+	threshold := h.ReplLagThreshold
+	if threshold <= 0 {
+		threshold = defaultReplLagThreshold
+	}
+	resp := replStatusResponse{
+		SiteID:              h.Meta.SiteID(),
+		MaxOplogHLC:         maxHLC,
+		LagThresholdSeconds: threshold.Seconds(),
+		Remotes:             make([]replRemoteStatus, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		resp.Remotes = append(resp.Remotes, replRemoteStatus{
+			ReplStat: stat,
+			Healthy:  replStatHealthy(stat, threshold),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// replStatHealthy reports whether pull and push watermarks are within the lag
+// threshold. Push lag is ignored when nothing is waiting to be pushed.
+func replStatHealthy(stat meta.ReplStat, threshold time.Duration) bool {
+	limit := threshold.Seconds()
+	if stat.LastPullHLC != "" && stat.PullLagSeconds > limit {
+		return false
+	}
+	if stat.PushBacklog > 0 && stat.PushLagSeconds > limit {
+		return false
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"compress/gzip"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"io"
@@ -12,9 +13,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
-	"github.com/kk-code-lab/seglake/internal/ops"
 )
 
 type oplogResponse struct {
@@ -30,6 +31,19 @@ type oplogApplyResponse struct {
 	Applied          int            `json:"applied"`
 	MissingManifests []string       `json:"missing_manifests,omitempty"`
 	MissingChunks    []missingChunk `json:"missing_chunks,omitempty"`
+	// NextBatchSize is the batch size the pusher should send next, derived
+	// from how long this batch took to apply (see nextPushBatch).
+	NextBatchSize int `json:"next_batch_size"`
+}
+
+type replObjectMetaResponse struct {
+	Bucket       string `json:"bucket"`
+	Key          string `json:"key"`
+	VersionID    string `json:"version_id"`
+	ETag         string `json:"etag"`
+	Size         int64  `json:"size"`
+	State        string `json:"state"`
+	LastModified string `json:"last_modified"`
 }
 
 type missingChunk struct {
@@ -43,8 +57,24 @@ const (
 	replMaxLimit        = 10000
 	replMaxManifestSize = 4 << 20
 	replMaxChunkSize    = 8 << 20
+	// replBatchStep is the additive increase of the suggested push batch.
+	replBatchStep          = 100
+	replDefaultApplyTarget = time.Second
 )
 
+// nextPushBatch suggests the size of a pusher's next oplog batch (AIMD):
+// half of this one when applying it took longer than target, otherwise this
+// one plus replBatchStep, within [1, replMaxLimit].
+func nextPushBatch(n int, elapsed, target time.Duration) int {
+	if n <= 0 {
+		return replDefaultLimit
+	}
+	if elapsed > target {
+		return max(n/2, 1)
+	}
+	return min(n+replBatchStep, replMaxLimit)
+}
+
 func (h *Handler) handleOplog(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
 	if h == nil || h.Meta == nil {
 		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
@@ -87,12 +117,20 @@ func (h *Handler) handleOplogApply(ctx context.Context, w http.ResponseWriter, r
 		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "too many oplog entries", requestID, r.URL.Path)
 		return
 	}
+	start := h.now()
 	applied, err := h.Meta.ApplyOplogEntries(ctx, req.Entries)
 	if err != nil {
 		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "oplog apply failed", requestID, r.URL.Path)
 		return
 	}
-	resp := oplogApplyResponse{Applied: applied}
+	target := h.ReplApplyTarget
+	if target <= 0 {
+		target = replDefaultApplyTarget
+	}
+	resp := oplogApplyResponse{
+		Applied:       applied,
+		NextBatchSize: nextPushBatch(len(req.Entries), h.now().Sub(start), target),
+	}
 	if h.Engine != nil {
 		missingManifests := make(map[string]struct{})
 		missingChunks := make(map[string]missingChunk)
@@ -199,32 +237,49 @@ func (h *Handler) handleReplicationChunk(ctx context.Context, w http.ResponseWri
 	_, _ = w.Write(data)
 }
 
-func (h *Handler) handleReplicationSnapshot(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
-	if h == nil || h.Engine == nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "storage not initialized", requestID, r.URL.Path)
+func (h *Handler) handleReplicationObjectMeta(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+	if h == nil || h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
 		return
 	}
-	layout := h.Engine.Layout()
-	metaPath := filepath.Join(filepath.Dir(layout.Root), "meta.db")
-	tmpDir, err := os.MkdirTemp("", "seglake-snapshot-")
-	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "snapshot temp failed", requestID, r.URL.Path)
+	bucket := strings.TrimSpace(r.URL.Query().Get("bucket"))
+	key := r.URL.Query().Get("key")
+	versionID := strings.TrimSpace(r.URL.Query().Get("versionId"))
+	if bucket == "" || key == "" {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidRequest", "bucket/key required", requestID, r.URL.Path)
 		return
 	}
-	defer func() { _ = os.RemoveAll(tmpDir) }()
-	if _, err := ops.Snapshot(layout, metaPath, tmpDir); err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "snapshot failed", requestID, r.URL.Path)
-		return
+	var (
+		objMeta *meta.ObjectMeta
+		err     error
+	)
+	if versionID != "" {
+		objMeta, err = h.Meta.GetObjectVersion(ctx, bucket, key, versionID)
+	} else {
+		objMeta, err = h.Meta.GetObjectMeta(ctx, bucket, key)
 	}
-	w.Header().Set("Content-Type", "application/gzip")
-	w.Header().Set("Content-Disposition", "attachment; filename=\"snapshot.tar.gz\"")
-	if err := writeTarGz(w, tmpDir); err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "snapshot stream failed", requestID, r.URL.Path)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchKey", "key not found", requestID, r.URL.Path)
+			return
+		}
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "object meta read failed", requestID, r.URL.Path)
 		return
 	}
+	resp := replObjectMetaResponse{
+		Bucket:       bucket,
+		Key:          key,
+		VersionID:    objMeta.VersionID,
+		ETag:         objMeta.ETag,
+		Size:         objMeta.Size,
+		State:        objMeta.State,
+		LastModified: objMeta.LastModified,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
-func writeTarGz(w http.ResponseWriter, root string) error {
+func writeTarGz(w io.Writer, root string) error {
 	gz := gzip.NewWriter(w)
 	defer func() { _ = gz.Close() }()
 	tw := tar.NewWriter(gz)
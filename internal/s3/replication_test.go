@@ -5,15 +5,22 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/kk-code-lab/seglake/internal/clock"
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/storage/engine"
 	"github.com/kk-code-lab/seglake/internal/storage/fs"
@@ -127,6 +134,74 @@ func TestReplicationOplogApplyEndpoint(t *testing.T) {
 	}
 }
 
+// stepClock advances by step on every reading.
+type stepClock struct {
+	mu   sync.Mutex
+	t    time.Time
+	step time.Duration
+}
+
+func (c *stepClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(c.step)
+	return c.t
+}
+
+func TestReplicationOplogApplyBatchSizeUsesHandlerClock(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open meta: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+
+	reqBody, err := json.Marshal(oplogApplyRequest{
+		Entries: []meta.OplogEntry{{
+			SiteID:    "site-a",
+			HLCTS:     "0000000000000000001-0000000001",
+			OpType:    "put",
+			Bucket:    "bucket",
+			Key:       "key",
+			VersionID: "v1",
+			Payload:   `{"etag":"etag","size":10,"last_modified_utc":"2025-12-22T12:00:00Z"}`,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("body: %v", err)
+	}
+
+	// Every clock reading is a second apart, so the apply looks slower than
+	// the target no matter how fast it really is.
+	handler := &Handler{
+		Engine:          eng,
+		Meta:            store,
+		Clock:           &stepClock{t: time.Date(2025, 12, 22, 12, 0, 0, 0, time.UTC), step: time.Second},
+		ReplApplyTarget: 500 * time.Millisecond,
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/replication/oplog", bytes.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", rec.Code, rec.Body.String())
+	}
+	var resp oplogApplyResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.NextBatchSize != 1 {
+		t.Fatalf("expected shrunk batch size 1, got %d", resp.NextBatchSize)
+	}
+}
+
 func TestReplicationManifestEndpoint(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -272,6 +347,130 @@ func TestReplicationSnapshotEndpoint(t *testing.T) {
 	}
 }
 
+func TestReplicationSnapshotResumesByID(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open meta: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	store.SetSiteID("site-a")
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	handler := &Handler{Engine: eng, Meta: store}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/replication/snapshot", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", rec.Code, rec.Body.String())
+	}
+	full := rec.Body.Bytes()
+	id := rec.Header().Get("X-Seglake-Snapshot-Id")
+	sum := sha256.Sum256(full)
+	if id == "" || rec.Header().Get("X-Seglake-Snapshot-Sha256") != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected snapshot headers: %v", rec.Header())
+	}
+	if rec.Header().Get("X-Seglake-Snapshot-Size") != strconv.Itoa(len(full)) {
+		t.Fatalf("size header=%q want %d", rec.Header().Get("X-Seglake-Snapshot-Size"), len(full))
+	}
+	if rec.Header().Get("X-Seglake-Site-Id") != "site-a" {
+		t.Fatalf("site header=%q", rec.Header().Get("X-Seglake-Site-Id"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/replication/snapshot?id="+id, nil)
+	req.Header.Set("Range", "bytes=10-")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("range status=%d", rec.Code)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), full[10:]) {
+		t.Fatalf("range body mismatch")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/replication/snapshot?id=unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown id status=%d", rec.Code)
+	}
+}
+
+func TestReplicationSnapshotReusedCappedAndPrivate(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open meta: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	handler := &Handler{Engine: eng, Meta: store, Clock: clock.FixedClock{T: start}}
+	snapshot := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/replication/snapshot", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", rec.Code, rec.Body.String())
+		}
+		return rec.Header().Get("X-Seglake-Snapshot-Id")
+	}
+
+	first := snapshot()
+	if again := snapshot(); again != first {
+		t.Fatalf("expected reuse of %s, got %s", first, again)
+	}
+	snapDir := handler.replSnapshotDir()
+	info, err := os.Stat(snapDir)
+	if err != nil {
+		t.Fatalf("Stat dir: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Fatalf("snapshot dir perm=%o want 700", perm)
+	}
+	info, err = os.Stat(filepath.Join(snapDir, first+".tar.gz"))
+	if err != nil {
+		t.Fatalf("Stat snapshot: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("snapshot perm=%o want 600", perm)
+	}
+
+	ids := []string{first}
+	for i := 1; i <= replSnapshotMaxKept; i++ {
+		handler.Clock = clock.FixedClock{T: start.Add(time.Duration(i) * (replSnapshotReuse + time.Second))}
+		id := snapshot()
+		if id == ids[len(ids)-1] {
+			t.Fatalf("expected a new snapshot after the reuse window")
+		}
+		ids = append(ids, id)
+	}
+	entries, err := os.ReadDir(snapDir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != replSnapshotMaxKept {
+		t.Fatalf("snapshots on disk=%d want %d", len(entries), replSnapshotMaxKept)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/replication/snapshot?id="+first, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("evicted snapshot status=%d", rec.Code)
+	}
+}
+
 func listTarGz(data []byte) ([]string, error) {
 	gz, err := gzip.NewReader(bytes.NewReader(data))
 	if err != nil {
@@ -354,3 +553,129 @@ func TestReplicationChunkLimit(t *testing.T) {
 		t.Fatalf("expected 400, got %d", rec.Code)
 	}
 }
+
+func TestReplicationObjectMetaEndpoint(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open meta: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	if err := store.RecordPut(context.Background(), "bucket", "key", "v1", "etag-1", 7, "", ""); err != nil {
+		t.Fatalf("RecordPut: %v", err)
+	}
+	handler := &Handler{Engine: eng, Meta: store}
+
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		version string
+	}{
+		{name: "current", query: "bucket=bucket&key=key", status: http.StatusOK, version: "v1"},
+		{name: "version", query: "bucket=bucket&key=key&versionId=v1", status: http.StatusOK, version: "v1"},
+		{name: "missing", query: "bucket=bucket&key=nope", status: http.StatusNotFound},
+		{name: "no key", query: "bucket=bucket", status: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/v1/replication/object-meta?"+tc.query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: status=%d body=%s", tc.name, rec.Code, rec.Body.String())
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var resp replObjectMetaResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: json decode: %v", tc.name, err)
+		}
+		if resp.VersionID != tc.version || resp.ETag != "etag-1" || resp.Size != 7 {
+			t.Fatalf("%s: unexpected response %+v", tc.name, resp)
+		}
+	}
+}
+
+func TestReplicationStatusReportsHealth(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open meta: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	store.SetSiteID("site-a")
+	ctx := context.Background()
+	if err := store.RecordPut(ctx, "bucket", "key", "v1", "etag", 1, "", ""); err != nil {
+		t.Fatalf("RecordPut: %v", err)
+	}
+	if err := store.UpsertAPIKey(ctx, "ops-key", "ops-secret", "ops", true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	if err := store.UpsertAPIKey(ctx, "ro-key", "ro-secret", "ro", true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	fresh := fmt.Sprintf("%019d-%010d", time.Now().UnixNano(), 0)
+	stale := fmt.Sprintf("%019d-%010d", time.Now().Add(-time.Hour).UnixNano(), 0)
+	if err := store.SetReplRemotePullWatermark(ctx, "http://fresh", fresh); err != nil {
+		t.Fatalf("SetReplRemotePullWatermark: %v", err)
+	}
+	if err := store.SetReplRemotePullWatermark(ctx, "http://stale", stale); err != nil {
+		t.Fatalf("SetReplRemotePullWatermark: %v", err)
+	}
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	handler := &Handler{
+		Engine:           eng,
+		Meta:             store,
+		ReplLagThreshold: time.Minute,
+		Auth: &AuthConfig{
+			Region:               "us-east-1",
+			SecretLookup:         store.LookupAPISecret,
+			AllowUnsignedPayload: true,
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/v1/replication/status", nil)
+	signRequestTest(req, "ro-key", "ro-secret", "us-east-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("ro key: status=%d body=%s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/v1/replication/status", nil)
+	signRequestTest(req, "ops-key", "ops-secret", "us-east-1")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ops key: status=%d body=%s", rec.Code, rec.Body.String())
+	}
+	var resp replStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if resp.SiteID != "site-a" || resp.MaxOplogHLC == "" || resp.LagThresholdSeconds != 60 {
+		t.Fatalf("unexpected status header: %+v", resp)
+	}
+	healthy := map[string]bool{}
+	for _, remote := range resp.Remotes {
+		healthy[remote.Remote] = remote.Healthy
+	}
+	if len(healthy) != 2 || !healthy["http://fresh"] || healthy["http://stale"] {
+		t.Fatalf("unexpected remote health: %+v", resp.Remotes)
+	}
+}
@@ -0,0 +1,147 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// requestLogRefresh is how long the cached set of request-logged buckets is
+// used before it is reloaded from meta, so changes made by another process
+// apply without a restart.
+const requestLogRefresh = 5 * time.Second
+
+// requestLogBuckets caches the buckets with detailed request logging enabled.
+// Lookups are an atomic load and a map read; a stale set is reloaded by one
+// caller while the others keep using it.
+type requestLogBuckets struct {
+	set      atomic.Pointer[map[string]struct{}]
+	loadedAt atomic.Int64
+	loading  atomic.Bool
+}
+
+func (c *requestLogBuckets) get(h *Handler) map[string]struct{} {
+	set := c.set.Load()
+	now := h.now()
+	if set != nil && now.Sub(time.Unix(0, c.loadedAt.Load())) < requestLogRefresh {
+		return *set
+	}
+	if !c.loading.CompareAndSwap(false, true) {
+		if set == nil {
+			return nil
+		}
+		return *set
+	}
+	defer c.loading.Store(false)
+	fresh, err := c.load(h)
+	if err != nil {
+		log.Printf("level=warn request logging: load buckets: %v", err)
+		// Keep the stale set and retry after the next refresh interval.
+		c.loadedAt.Store(now.UnixNano())
+		if set == nil {
+			return nil
+		}
+		return *set
+	}
+	return fresh
+}
+
+func (c *requestLogBuckets) load(h *Handler) (map[string]struct{}, error) {
+	buckets, err := h.Meta.ListRequestLoggingBuckets(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]struct{}, len(buckets))
+	for _, bucket := range buckets {
+		set[bucket] = struct{}{}
+	}
+	c.set.Store(&set)
+	c.loadedAt.Store(h.now().UnixNano())
+	return set, nil
+}
+
+// RequestLogBucket returns the bucket addressed by r when detailed request
+// logging is enabled for it (LoggingOptions.Bucket).
+func (h *Handler) RequestLogBucket(r *http.Request) (string, bool) {
+	if h == nil || h.Meta == nil || r == nil {
+		return "", false
+	}
+	set := h.requestLogBuckets.get(h)
+	if len(set) == 0 {
+		return "", false
+	}
+	bucket, ok := h.bucketFromRequest(h.withForwardedHost(r))
+	if !ok {
+		return "", false
+	}
+	if _, ok := set[bucket]; !ok {
+		return "", false
+	}
+	return bucket, true
+}
+
+// DescribeRequest reports the op, bucket, key and client address of r for
+// JSON access log lines (LoggingOptions.Describe). The client address is the
+// first X-Forwarded-For entry when r comes from a trusted proxy.
+func (h *Handler) DescribeRequest(r *http.Request) RequestInfo {
+	if h == nil || r == nil {
+		return RequestInfo{}
+	}
+	r = h.withForwardedHost(r)
+	info := RequestInfo{Op: h.opForRequest(r), SourceIP: h.sourceIP(r)}
+	if bucket, key, ok := h.parseBucketKey(r); ok {
+		info.Bucket, info.Key = bucket, key
+	} else if bucket, ok := h.parseBucketOnly(r); ok {
+		info.Bucket = bucket
+	}
+	return info
+}
+
+type requestLoggingResponse struct {
+	Buckets []string `json:"buckets"`
+}
+
+// handleRequestLogging lists (GET), enables (PUT) or disables (DELETE)
+// detailed request logging for ?bucket=. Changes take effect immediately on
+// this server and within requestLogRefresh on others sharing meta.db.
+func (h *Handler) handleRequestLogging(ctx context.Context, w http.ResponseWriter, r *http.Request, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	if r.Method != http.MethodGet {
+		bucket := strings.TrimSpace(r.URL.Query().Get("bucket"))
+		if bucket == "" {
+			writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", "bucket required", requestID, r.URL.Path)
+			return
+		}
+		if err := h.Meta.SetBucketRequestLogging(ctx, bucket, r.Method == http.MethodPut); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+				return
+			}
+			h.writeInternalError(w, err, requestID, r.URL.Path)
+			return
+		}
+	}
+	set, err := h.requestLogBuckets.load(h)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	resp := requestLoggingResponse{Buckets: slices.Sorted(maps.Keys(set))}
+	if resp.Buckets == nil {
+		resp.Buckets = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
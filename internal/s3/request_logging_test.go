@@ -0,0 +1,77 @@
+package s3
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBucketRequestLogging(t *testing.T) {
+	buf := captureLog(t)
+	h := newTestHandler(t)
+	putObject(t, h, "loud", "key", "hello")
+	putObject(t, h, "quiet", "key", "hello")
+	handler := LoggingMiddleware(h, nil, LoggingOptions{Bucket: h.RequestLogBucket, BucketsOnly: true})
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		buf.Reset()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	if serve(http.MethodGet, "/loud/key"); buf.Len() != 0 {
+		t.Fatalf("expected no log before enabling, got %q", buf.String())
+	}
+	if rec := serve(http.MethodPut, "/v1/ops/request-logging?bucket=missing"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing bucket, got %d", rec.Code)
+	}
+	rec := serve(http.MethodPut, "/v1/ops/request-logging?bucket=loud")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("enable status: %d %s", rec.Code, rec.Body.String())
+	}
+	var resp requestLoggingResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Buckets) != 1 || resp.Buckets[0] != "loud" {
+		t.Fatalf("unexpected response %+v (%v)", resp, err)
+	}
+
+	serve(http.MethodGet, "/loud/key")
+	line := buf.String()
+	if !containsAll(line, []string{"path=/loud/key", "status=200", "bucket=loud", "bytes_out=5"}) {
+		t.Fatalf("missing detail in %q", line)
+	}
+	if serve(http.MethodGet, "/quiet/key"); buf.Len() != 0 {
+		t.Fatalf("expected quiet bucket unlogged, got %q", buf.String())
+	}
+
+	if rec := serve(http.MethodDelete, "/v1/ops/request-logging?bucket=loud"); rec.Code != http.StatusOK {
+		t.Fatalf("disable status: %d", rec.Code)
+	}
+	if serve(http.MethodGet, "/loud/key"); buf.Len() != 0 {
+		t.Fatalf("expected no log after disabling, got %q", buf.String())
+	}
+}
+
+func TestLoggingMiddlewareLogsAllWithoutBucketsOnly(t *testing.T) {
+	buf := captureLog(t)
+	h := newTestHandler(t)
+	putObject(t, h, "bucket", "key", "hello")
+	if err := h.Meta.SetBucketRequestLogging(t.Context(), "bucket", true); err != nil {
+		t.Fatalf("SetBucketRequestLogging: %v", err)
+	}
+	handler := LoggingMiddleware(h, nil, LoggingOptions{Bucket: h.RequestLogBucket})
+	for _, target := range []string{"/bucket/key", "/other/key"} {
+		buf.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		line := buf.String()
+		if !strings.Contains(line, "path="+target) {
+			t.Fatalf("expected %s logged, got %q", target, line)
+		}
+		if detailed := strings.Contains(line, "bucket="); detailed != (target == "/bucket/key") {
+			t.Fatalf("unexpected detail for %s: %q", target, line)
+		}
+	}
+}
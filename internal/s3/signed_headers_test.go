@@ -0,0 +1,158 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func TestPresignedPutRejectsUnsignedSensitiveHeaders(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := engine.New(engine.Options{Layout: fs.NewLayout(filepath.Join(dir, "objects")), MetaStore: store})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	handler := &Handler{
+		Engine: eng,
+		Meta:   store,
+		Auth: &AuthConfig{
+			AccessKey:            "test",
+			SecretKey:            "testsecret",
+			Region:               "us-east-1",
+			AllowUnsignedPayload: true,
+			MaxSkew:              5 * time.Minute,
+		},
+	}
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{name: "plain", status: http.StatusOK},
+		{name: "meta", header: "X-Amz-Meta-Owner", value: "mallory", status: http.StatusForbidden},
+		{name: "content-md5", header: "Content-MD5", value: "1B2M2Y8AsgTpgAmY7PhCfg==", status: http.StatusForbidden},
+		{name: "payload hash", header: "X-Amz-Content-Sha256", value: "UNSIGNED-PAYLOAD", status: http.StatusOK},
+		{name: "trace id", header: "X-Seglake-Trace-Id", value: "trace-123", status: http.StatusOK},
+		{name: "request id", header: "X-Amz-Request-Id", value: "req-123", status: http.StatusOK},
+		{name: "ttl", header: "X-Seglake-Ttl", value: "60", status: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		presigned, err := handler.Auth.Presign(http.MethodPut, "http://example.com/bucket/"+tc.name, "", 5*time.Minute)
+		if err != nil {
+			t.Fatalf("Presign: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPut, presigned, strings.NewReader(""))
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.status, w.Code, w.Body.String())
+		}
+		if tc.status == http.StatusForbidden && !strings.Contains(w.Body.String(), "<Code>AccessDenied</Code>") {
+			t.Fatalf("%s: expected AccessDenied, got %s", tc.name, w.Body.String())
+		}
+	}
+}
+
+func TestPresignedCopyRejectsUnsignedCopySource(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := engine.New(engine.Options{Layout: fs.NewLayout(filepath.Join(dir, "objects")), MetaStore: store})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	handler := &Handler{
+		Engine: eng,
+		Meta:   store,
+		Auth: &AuthConfig{
+			AccessKey:            "test",
+			SecretKey:            "testsecret",
+			Region:               "us-east-1",
+			AllowUnsignedPayload: true,
+			MaxSkew:              5 * time.Minute,
+		},
+	}
+	serve := func(method, target, body string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		presigned, err := handler.Auth.Presign(method, "http://example.com"+target, "", 5*time.Minute)
+		if err != nil {
+			t.Fatalf("Presign: %v", err)
+		}
+		req := httptest.NewRequest(method, presigned, strings.NewReader(body))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	if w := serve(http.MethodPut, "/bucket/secret", "secret", nil); w.Code != http.StatusOK {
+		t.Fatalf("PUT source: %d %s", w.Code, w.Body.String())
+	}
+	w := serve(http.MethodPost, "/bucket/dst?uploads", "", nil)
+	var initResult initiateMultipartResult
+	if err := xml.Unmarshal(w.Body.Bytes(), &initResult); err != nil || initResult.UploadID == "" {
+		t.Fatalf("initiate: %d %s (%v)", w.Code, w.Body.String(), err)
+	}
+
+	copySource := http.Header{"X-Amz-Copy-Source": {"/bucket/secret"}}
+	cases := []struct {
+		name   string
+		target string
+		header http.Header
+	}{
+		{name: "copy", target: "/bucket/dst", header: copySource},
+		{name: "upload part copy", target: "/bucket/dst?partNumber=1&uploadId=" + url.QueryEscape(initResult.UploadID), header: copySource},
+		{name: "copy from url", target: "/bucket/dst", header: http.Header{"X-Seglake-Copy-Source-Url": {"http://example.com/bucket/secret"}}},
+	}
+	for _, tc := range cases {
+		w := serve(http.MethodPut, tc.target, "", tc.header)
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "must be signed") {
+			t.Fatalf("%s: expected 403 must be signed, got %d: %s", tc.name, w.Code, w.Body.String())
+		}
+	}
+	if w := serve(http.MethodGet, "/bucket/dst", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("expected no copied object, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUnsignedSensitiveHeaderHonorsSignedSet(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/bucket/key", nil)
+	req.Header.Set("X-Amz-Meta-Owner", "alice")
+	req.Header.Set("X-Amz-Tagging", "a=b")
+	if name := unsignedSensitiveHeader(req); name != "" {
+		t.Fatalf("header-signed requests are not checked, got %q", name)
+	}
+	set := &signedHeaderSet{presigned: true, headers: []string{"host", "x-amz-meta-owner"}}
+	req = req.WithContext(context.WithValue(req.Context(), signedHeadersKey{}, set))
+	if name := unsignedSensitiveHeader(req); name != "x-amz-tagging" {
+		t.Fatalf("expected x-amz-tagging, got %q", name)
+	}
+	set.headers = append(set.headers, "x-amz-tagging")
+	if name := unsignedSensitiveHeader(req); name != "" {
+		t.Fatalf("expected no unsigned header, got %q", name)
+	}
+}
@@ -0,0 +1,27 @@
+package s3
+
+import (
+	"log"
+	"math/rand/v2"
+	"time"
+)
+
+// logRequestTiming writes a warn line for requests slower than
+// SlowRequestThreshold and a debug line for a RequestLogSampleRate fraction of
+// the others.
+func (h *Handler) logRequestTiming(op, bucket, key, requestID, traceID string, status int, dur time.Duration, bytesIn, bytesOut int64) {
+	trace := ""
+	if traceID != "" {
+		trace = " trace_id=" + traceID
+	}
+	if h.SlowRequestThreshold > 0 && dur >= h.SlowRequestThreshold {
+		log.Printf("level=warn slow_request op=%s bucket=%s key=%q status=%d dur_ms=%d bytes_in=%d bytes_out=%d req_id=%s%s",
+			op, bucket, key, status, dur.Milliseconds(), bytesIn, bytesOut, requestID, trace)
+		return
+	}
+	if h.RequestLogSampleRate <= 0 || rand.Float64() >= h.RequestLogSampleRate {
+		return
+	}
+	log.Printf("level=debug request_sample op=%s bucket=%s key=%q status=%d dur_ms=%d bytes_in=%d bytes_out=%d req_id=%s%s",
+		op, bucket, key, status, dur.Milliseconds(), bytesIn, bytesOut, requestID, trace)
+}
@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prevOut, prevFlags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(prevOut)
+		log.SetFlags(prevFlags)
+	})
+	return &buf
+}
+
+func TestLogRequestTiming(t *testing.T) {
+	buf := captureLog(t)
+	h := &Handler{SlowRequestThreshold: 100 * time.Millisecond}
+
+	h.logRequestTiming("get", "bucket", "key", "req-1", "", 200, 50*time.Millisecond, 0, 10)
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected log for fast request: %q", buf.String())
+	}
+	h.logRequestTiming("put", "bucket", "slow key", "req-2", "trace-2", 200, 250*time.Millisecond, 42, 0)
+	line := buf.String()
+	for _, want := range []string{"level=warn slow_request", "op=put", "bucket=bucket", `key="slow key"`, "status=200", "dur_ms=250", "bytes_in=42", "req_id=req-2", "trace_id=trace-2"} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("slow log %q missing %q", line, want)
+		}
+	}
+
+	buf.Reset()
+	h.RequestLogSampleRate = 1
+	h.logRequestTiming("get", "bucket", "key", "req-3", "", 200, time.Millisecond, 0, 10)
+	if !strings.Contains(buf.String(), "level=debug request_sample op=get") {
+		t.Fatalf("expected sampled log, got %q", buf.String())
+	}
+	buf.Reset()
+	h.logRequestTiming("get", "bucket", "key", "req-4", "", 200, time.Second, 0, 10)
+	if strings.Count(buf.String(), "\n") != 1 || !strings.Contains(buf.String(), "slow_request") {
+		t.Fatalf("expected only the slow line, got %q", buf.String())
+	}
+}
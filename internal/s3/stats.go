@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
 )
 
 type statsResponse struct {
@@ -20,6 +22,7 @@ type statsResponse struct {
 	LastFsckErrors          int                         `json:"last_fsck_errors,omitempty"`
 	LastScrubAt             string                      `json:"last_scrub_at,omitempty"`
 	LastScrubErrors         int                         `json:"last_scrub_errors,omitempty"`
+	Scrub                   *meta.ScrubState            `json:"scrub,omitempty"`
 	LastGCAt                string                      `json:"last_gc_at,omitempty"`
 	LastGCErrors            int                         `json:"last_gc_errors,omitempty"`
 	LastGCReclaimed         int64                       `json:"last_gc_reclaimed_bytes,omitempty"`
@@ -36,17 +39,79 @@ type statsResponse struct {
 	WriteInflight           int64                       `json:"write_inflight,omitempty"`
 	RequestsTotal           map[string]map[string]int64 `json:"requests_total,omitempty"`
 	Inflight                map[string]int64            `json:"inflight,omitempty"`
+	InflightByKey           map[string]int64            `json:"inflight_by_key,omitempty"`
 	BytesInTotal            int64                       `json:"bytes_in_total,omitempty"`
 	BytesOutTotal           int64                       `json:"bytes_out_total,omitempty"`
 	ReplayDetected          int64                       `json:"replay_detected,omitempty"`
+	MetaBusyRetries         int64                       `json:"meta_busy_retries,omitempty"`
 	LatencyMs               map[string]LatencyStats     `json:"latency_ms,omitempty"`
 	RequestsByBucket        map[string]map[string]int64 `json:"requests_total_by_bucket,omitempty"`
 	LatencyByBucketMs       map[string]LatencyStats     `json:"latency_ms_by_bucket,omitempty"`
 	RequestsByKey           map[string]map[string]int64 `json:"requests_total_by_key,omitempty"`
 	LatencyByKeyMs          map[string]LatencyStats     `json:"latency_ms_by_key,omitempty"`
 	MaintenanceTransitions  map[string]int64            `json:"maintenance_transitions,omitempty"`
+	SlowDown                map[string]SlowDownStats    `json:"slow_down,omitempty"`
+	Notifications           map[string]int64            `json:"notifications,omitempty"`
 	GCTrends                []meta.GCTrend              `json:"gc_trends,omitempty"`
+	SizeHistogram           []meta.SizeHistogramBucket  `json:"size_histogram,omitempty"`
 	Replication             []meta.ReplStat             `json:"replication,omitempty"`
+	Barrier                 *barrierStats               `json:"barrier,omitempty"`
+	Writes                  *writeStats                 `json:"writes,omitempty"`
+}
+
+type barrierStats struct {
+	IntervalMs              int64   `json:"interval_ms"`
+	MaxBytes                int64   `json:"max_bytes"`
+	BarriersTotal           int64   `json:"barriers_total"`
+	OpsTotal                int64   `json:"ops_total"`
+	BytesTotal              int64   `json:"bytes_total"`
+	AvgBytesPerBarrier      int64   `json:"avg_bytes_per_barrier"`
+	LastBarrierBytes        int64   `json:"last_barrier_bytes"`
+	LastBarrierOps          int64   `json:"last_barrier_ops"`
+	LastBarrierAt           string  `json:"last_barrier_at,omitempty"`
+	SecondsSinceLastBarrier float64 `json:"seconds_since_last_barrier,omitempty"`
+}
+
+func newBarrierStats(stats engine.BarrierStats, now time.Time) *barrierStats {
+	out := &barrierStats{
+		IntervalMs:       stats.Interval.Milliseconds(),
+		MaxBytes:         stats.MaxBytes,
+		BarriersTotal:    stats.Barriers,
+		OpsTotal:         stats.Ops,
+		BytesTotal:       stats.Bytes,
+		LastBarrierBytes: stats.LastBytes,
+		LastBarrierOps:   stats.LastOps,
+	}
+	if stats.Barriers > 0 {
+		out.AvgBytesPerBarrier = stats.Bytes / stats.Barriers
+	}
+	if !stats.LastBarrierAt.IsZero() {
+		out.LastBarrierAt = stats.LastBarrierAt.Format(time.RFC3339Nano)
+		out.SecondsSinceLastBarrier = max(now.Sub(stats.LastBarrierAt).Seconds(), 0)
+	}
+	return out
+}
+
+type writeStats struct {
+	SyncMode           string  `json:"sync_mode"`
+	LogicalBytes       int64   `json:"logical_bytes"`
+	SegmentBytes       int64   `json:"segment_bytes"`
+	ManifestBytes      int64   `json:"manifest_bytes"`
+	SegmentFsyncs      int64   `json:"segment_fsyncs"`
+	ManifestFsyncs     int64   `json:"manifest_fsyncs"`
+	WriteAmplification float64 `json:"write_amplification"`
+}
+
+func newWriteStats(stats engine.WriteStats) *writeStats {
+	return &writeStats{
+		SyncMode:           stats.SyncMode,
+		LogicalBytes:       stats.LogicalBytes,
+		SegmentBytes:       stats.SegmentBytes,
+		ManifestBytes:      stats.ManifestBytes,
+		SegmentFsyncs:      stats.SegmentSyncs,
+		ManifestFsyncs:     stats.ManifestSyncs,
+		WriteAmplification: stats.Amplification(),
+	}
 }
 
 func (h *Handler) handleStats(ctx context.Context, w http.ResponseWriter, requestID string, resource string) {
@@ -56,22 +121,32 @@ func (h *Handler) handleStats(ctx context.Context, w http.ResponseWriter, reques
 	}
 	stats, err := h.Meta.GetStats(ctx)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	gcTrends, err := h.Meta.ListGCTrends(ctx, 30)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
+		return
+	}
+	sizeHistogram, err := h.Meta.SizeHistogram(ctx)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	replStats, err := h.Meta.GetReplStats(ctx)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	maintenanceState, err := h.Meta.MaintenanceState(ctx)
 	if err != nil {
-		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", err.Error(), requestID, resource)
+		h.writeInternalError(w, err, requestID, resource)
+		return
+	}
+	scrubState, scrubStarted, err := h.Meta.GetScrubState(ctx)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, resource)
 		return
 	}
 	liveManifests := int64(0)
@@ -124,8 +199,12 @@ func (h *Handler) handleStats(ctx context.Context, w http.ResponseWriter, reques
 		MaintenanceUpdatedAt:    maintenanceState.UpdatedAt,
 		WriteInflight:           h.WriteInflight(),
 		GCTrends:                gcTrends,
+		SizeHistogram:           sizeHistogram,
 		Replication:             replStats,
 	}
+	if scrubStarted {
+		resp.Scrub = &scrubState
+	}
 	if h.Metrics != nil {
 		reqs, inflight, bytesIn, bytesOut, replayDetected, latency, bucketReqs, bucketLatency, keyReqs, keyLatency, maintTransitions := h.Metrics.Snapshot()
 		resp.RequestsTotal = reqs
@@ -133,12 +212,22 @@ func (h *Handler) handleStats(ctx context.Context, w http.ResponseWriter, reques
 		resp.BytesInTotal = bytesIn
 		resp.BytesOutTotal = bytesOut
 		resp.ReplayDetected = replayDetected
+		resp.MetaBusyRetries = h.Metrics.MetaBusyRetries()
 		resp.LatencyMs = latency
 		resp.RequestsByBucket = bucketReqs
 		resp.LatencyByBucketMs = bucketLatency
 		resp.RequestsByKey = keyReqs
 		resp.LatencyByKeyMs = keyLatency
 		resp.MaintenanceTransitions = maintTransitions
+		resp.SlowDown = h.Metrics.SlowDowns(h.now())
+		resp.Notifications = h.Metrics.Notifications()
+	}
+	if h.Engine != nil {
+		resp.Barrier = newBarrierStats(h.Engine.BarrierStats(), h.now())
+		resp.Writes = newWriteStats(h.Engine.WriteStats())
+	}
+	if h.InflightLimiter != nil {
+		resp.InflightByKey = h.InflightLimiter.Usage()
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -3,11 +3,14 @@ package s3
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"path/filepath"
 	"testing"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/engine"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
 )
 
 func TestStatsIncludesReplayDetected(t *testing.T) {
@@ -37,3 +40,69 @@ func TestStatsIncludesReplayDetected(t *testing.T) {
 		t.Fatalf("expected replay_detected=1, got %d", resp.ReplayDetected)
 	}
 }
+
+func TestUsageEndpointRequiresOpsAndReportsCounters(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open meta: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	ctx := context.Background()
+	if err := store.RecordPut(ctx, "bucket", "key", "v1", "etag", 42, "", ""); err != nil {
+		t.Fatalf("RecordPut: %v", err)
+	}
+	if err := store.UpsertAPIKey(ctx, "ops-key", "ops-secret", "ops", true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	if err := store.UpsertAPIKey(ctx, "ro-key", "ro-secret", "ro", true, 0); err != nil {
+		t.Fatalf("UpsertAPIKey: %v", err)
+	}
+	eng, err := engine.New(engine.Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "objects")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("engine.New: %v", err)
+	}
+	handler := &Handler{
+		Engine:  eng,
+		Meta:    store,
+		Metrics: NewMetrics(),
+		Auth: &AuthConfig{
+			Region:               "us-east-1",
+			SecretLookup:         store.LookupAPISecret,
+			AllowUnsignedPayload: true,
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/v1/meta/usage", nil)
+	signRequestTest(req, "ro-key", "ro-secret", "us-east-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("ro key: status=%d body=%s", rec.Code, rec.Body.String())
+	}
+
+	var resp usageResponse
+	for i := 0; i < 2; i++ {
+		req = httptest.NewRequest(http.MethodGet, "http://example.com/v1/meta/usage", nil)
+		signRequestTest(req, "ops-key", "ops-secret", "us-east-1")
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("ops key: status=%d body=%s", rec.Code, rec.Body.String())
+		}
+		resp = usageResponse{}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode usage: %v", err)
+		}
+	}
+	if len(resp.Buckets) != 1 || resp.Buckets[0].Objects != 1 || resp.Buckets[0].Bytes != 42 {
+		t.Fatalf("unexpected buckets: %+v", resp.Buckets)
+	}
+	// Denied requests never pass authentication and are not counted.
+	if len(resp.AccessKeys) != 1 || resp.AccessKeys[0].AccessKey != "ops-key" || resp.AccessKeys[0].Requests["2xx"] != 1 {
+		t.Fatalf("unexpected access keys: %+v", resp.AccessKeys)
+	}
+}
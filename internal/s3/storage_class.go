@@ -0,0 +1,41 @@
+package s3
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+// storageClassHeader selects the storage class on PUT, CopyObject and
+// CreateMultipartUpload. Seglake keeps a single tier: the class is validated,
+// recorded per version and reported back, but does not change placement.
+const storageClassHeader = "X-Amz-Storage-Class"
+
+// knownStorageClasses are the classes S3 accepts.
+var knownStorageClasses = map[string]struct{}{
+	meta.StorageClassStandard: {},
+	"REDUCED_REDUNDANCY":      {},
+	"STANDARD_IA":             {},
+	"ONEZONE_IA":              {},
+	"INTELLIGENT_TIERING":     {},
+	"GLACIER":                 {},
+	"DEEP_ARCHIVE":            {},
+	"GLACIER_IR":              {},
+	"OUTPOSTS":                {},
+	"SNOW":                    {},
+	"EXPRESS_ONEZONE":         {},
+}
+
+// parseStorageClass returns the storage class requested by r, STANDARD when
+// the header is absent, or false for an unknown class.
+func parseStorageClass(r *http.Request) (string, bool) {
+	raw := strings.TrimSpace(r.Header.Get(storageClassHeader))
+	if raw == "" {
+		return meta.StorageClassStandard, true
+	}
+	if _, ok := knownStorageClasses[raw]; !ok {
+		return "", false
+	}
+	return raw, true
+}
@@ -0,0 +1,118 @@
+package s3
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStorageClassRoundTrip(t *testing.T) {
+	h := newTestHandler(t)
+	serve := func(method, target, class, copySource string) *httptest.ResponseRecorder {
+		t.Helper()
+		var body *strings.Reader
+		if method == http.MethodPut && copySource == "" {
+			body = strings.NewReader("data")
+		} else {
+			body = strings.NewReader("")
+		}
+		req := httptest.NewRequest(method, target, body)
+		if class != "" {
+			req.Header.Set("x-amz-storage-class", class)
+		}
+		if copySource != "" {
+			req.Header.Set("X-Amz-Copy-Source", copySource)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(http.MethodPut, "/bucket/cold", "STANDARD_IA", ""); w.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d %s", w.Code, w.Body.String())
+	}
+	putObject(t, h, "bucket", "plain", "data")
+	if w := serve(http.MethodPut, "/bucket/bad", "FAST", ""); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "InvalidStorageClass") {
+		t.Fatalf("expected InvalidStorageClass, got %d %s", w.Code, w.Body.String())
+	}
+
+	for key, want := range map[string]string{"cold": "STANDARD_IA", "plain": ""} {
+		for _, method := range []string{http.MethodHead, http.MethodGet} {
+			w := serve(method, "/bucket/"+key, "", "")
+			if w.Code != http.StatusOK || w.Header().Get("x-amz-storage-class") != want {
+				t.Fatalf("%s %s: status %d class %q, want %q", method, key, w.Code, w.Header().Get("x-amz-storage-class"), want)
+			}
+		}
+	}
+
+	// The copy takes the requested class, not the source's.
+	if w := serve(http.MethodPut, "/bucket/copy", "", "/bucket/cold"); w.Code != http.StatusOK {
+		t.Fatalf("copy status: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(http.MethodPut, "/bucket/glacier", "GLACIER", "/bucket/plain"); w.Code != http.StatusOK {
+		t.Fatalf("copy status: %d %s", w.Code, w.Body.String())
+	}
+	if w := serve(http.MethodPut, "/bucket/copy-bad", "FAST", "/bucket/plain"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for copy with unknown class, got %d", w.Code)
+	}
+
+	list := serve(http.MethodGet, "/bucket?list-type=2", "", "").Body.String()
+	versions := serve(http.MethodGet, "/bucket?versions", "", "").Body.String()
+	for _, body := range []string{list, versions} {
+		for _, want := range []string{
+			"<Key>cold</Key>", "<StorageClass>STANDARD_IA</StorageClass>",
+			"<Key>glacier</Key>", "<StorageClass>GLACIER</StorageClass>",
+			"<Key>copy</Key>", "<StorageClass>STANDARD</StorageClass>",
+		} {
+			if !strings.Contains(body, want) {
+				t.Fatalf("missing %s in %s", want, body)
+			}
+		}
+	}
+}
+
+func TestMultipartStorageClass(t *testing.T) {
+	h := newTestHandler(t)
+	bad := httptest.NewRequest(http.MethodPost, "/bucket/key?uploads", nil)
+	bad.Header.Set("x-amz-storage-class", "FAST")
+	badW := httptest.NewRecorder()
+	h.ServeHTTP(badW, bad)
+	if badW.Code != http.StatusBadRequest || !strings.Contains(badW.Body.String(), "InvalidStorageClass") {
+		t.Fatalf("expected InvalidStorageClass, got %d %s", badW.Code, badW.Body.String())
+	}
+
+	initReq := httptest.NewRequest(http.MethodPost, "/bucket/key?uploads", nil)
+	initReq.Header.Set("x-amz-storage-class", "ONEZONE_IA")
+	initW := httptest.NewRecorder()
+	h.ServeHTTP(initW, initReq)
+	if initW.Code != http.StatusOK {
+		t.Fatalf("init status: %d", initW.Code)
+	}
+	var initResp initiateMultipartResult
+	if err := xml.NewDecoder(strings.NewReader(initW.Body.String())).Decode(&initResp); err != nil {
+		t.Fatalf("init decode: %v", err)
+	}
+	partW := httptest.NewRecorder()
+	h.ServeHTTP(partW, httptest.NewRequest(http.MethodPut, "/bucket/key?partNumber=1&uploadId="+initResp.UploadID, strings.NewReader("part1")))
+	if partW.Code != http.StatusOK {
+		t.Fatalf("part status: %d", partW.Code)
+	}
+	listW := httptest.NewRecorder()
+	h.ServeHTTP(listW, httptest.NewRequest(http.MethodGet, "/bucket/key?uploadId="+initResp.UploadID, nil))
+	if !strings.Contains(listW.Body.String(), "<StorageClass>ONEZONE_IA</StorageClass>") {
+		t.Fatalf("ListParts missing storage class: %s", listW.Body.String())
+	}
+	completeBody := `<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>` + partW.Header().Get("ETag") + `</ETag></Part></CompleteMultipartUpload>`
+	completeW := httptest.NewRecorder()
+	h.ServeHTTP(completeW, httptest.NewRequest(http.MethodPost, "/bucket/key?uploadId="+initResp.UploadID, strings.NewReader(completeBody)))
+	if completeW.Code != http.StatusOK {
+		t.Fatalf("complete status: %d %s", completeW.Code, completeW.Body.String())
+	}
+	headW := httptest.NewRecorder()
+	h.ServeHTTP(headW, httptest.NewRequest(http.MethodHead, "/bucket/key", nil))
+	if got := headW.Header().Get("x-amz-storage-class"); got != "ONEZONE_IA" {
+		t.Fatalf("expected ONEZONE_IA, got %q", got)
+	}
+}
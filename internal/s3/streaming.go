@@ -135,7 +135,9 @@ func sigv4ContextFromRequest(r *http.Request) (*sigv4Context, bool) {
 	return ctx, true
 }
 
-func setupStreamingReader(r *http.Request, reader io.Reader) (io.Reader, streamingMode, int64, bool, *requestError) {
+// setupStreamingReader wraps aws-chunked bodies in a decoding reader. With
+// skipChecksum, checksum trailers are parsed but neither validated nor kept.
+func setupStreamingReader(r *http.Request, reader io.Reader, skipChecksum bool) (io.Reader, streamingMode, int64, bool, *requestError) {
 	streamingMode, err := parseStreamingMode(r.Header.Get("X-Amz-Content-Sha256"))
 	if err != nil {
 		return reader, streamingNone, 0, false, &requestError{
@@ -194,19 +196,21 @@ func setupStreamingReader(r *http.Request, reader io.Reader) (io.Reader, streami
 		expectedLen = decodedLen
 	}
 	reader = newAWSChunkedReader(reader, awsChunkedConfig{
-		mode:        streamingMode,
-		sigv4:       sigCtx,
-		trailerKeys: trailerKeys,
-		expectedLen: expectedLen,
+		mode:         streamingMode,
+		sigv4:        sigCtx,
+		trailerKeys:  trailerKeys,
+		expectedLen:  expectedLen,
+		skipChecksum: skipChecksum,
 	})
 	return reader, streamingMode, decodedLen, hasDecoded, nil
 }
 
 type awsChunkedConfig struct {
-	mode        streamingMode
-	sigv4       *sigv4Context
-	trailerKeys []string
-	expectedLen int64
+	mode         streamingMode
+	sigv4        *sigv4Context
+	trailerKeys  []string
+	expectedLen  int64
+	skipChecksum bool
 }
 
 type awsChunkedReader struct {
@@ -223,6 +227,8 @@ type awsChunkedReader struct {
 	totalRead   int64
 	expectedLen int64
 	trailerKeys []string
+	// checksumValue is the trailer value once checksum has been verified.
+	checksumValue string
 }
 
 func newAWSChunkedReader(reader io.Reader, cfg awsChunkedConfig) io.Reader {
@@ -239,7 +245,7 @@ func newAWSChunkedReader(reader io.Reader, cfg awsChunkedConfig) io.Reader {
 	if cfg.mode == streamingSigned || cfg.mode == streamingSignedTrailer {
 		r.chunkHasher = sha256.New()
 	}
-	if len(cfg.trailerKeys) > 0 {
+	if len(cfg.trailerKeys) > 0 && !cfg.skipChecksum {
 		if v, ok := newChecksumValidator(cfg.trailerKeys); ok {
 			r.checksum = v
 		}
@@ -399,6 +405,7 @@ func (r *awsChunkedReader) verifyTrailers(trailers map[string]string) error {
 		if err := r.checksum.Verify(trailers); err != nil {
 			return err
 		}
+		r.checksumValue = trailers[r.checksum.name]
 	}
 	if r.mode == streamingSignedTrailer {
 		sig, ok := trailers["x-amz-trailer-signature"]
@@ -413,6 +420,16 @@ func (r *awsChunkedReader) verifyTrailers(trailers map[string]string) error {
 	return nil
 }
 
+// Checksum returns the algorithm (e.g. "crc32c") and base64 value of the
+// trailing checksum verified on this body; both are empty until the body has
+// been read to the end or when no checksum trailer was sent.
+func (r *awsChunkedReader) Checksum() (string, string) {
+	if r == nil || r.checksum == nil || r.checksumValue == "" {
+		return "", ""
+	}
+	return strings.TrimPrefix(r.checksum.name, "x-amz-checksum-"), r.checksumValue
+}
+
 func (r *awsChunkedReader) readLine() (string, error) {
 	line, err := r.r.ReadSlice('\n')
 	if err != nil {
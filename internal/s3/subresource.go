@@ -0,0 +1,109 @@
+package s3
+
+import (
+	"net/url"
+	"sort"
+)
+
+// subresources lists the S3 sub-resource query keys seglake recognizes and
+// whether it serves them. Requests naming an unsupported one get 501
+// NotImplemented instead of falling through to a listing or an object
+// read/write that ignores the sub-resource.
+var subresources = map[string]bool{
+	"location":     true,
+	"policy":       true,
+	"uploadId":     true,
+	"uploads":      true,
+	"versionId":    true,
+	"versioning":   true,
+	"versions":     true,
+	"lifecycle":    true,
+	"website":      true,
+	"tagging":      true,
+	"partNumber":   true,
+	"acl":          true,
+	"notification": true,
+
+	"accelerate":          false,
+	"analytics":           false,
+	"attributes":          false,
+	"cors":                false,
+	"delete":              false,
+	"encryption":          false,
+	"intelligent-tiering": false,
+	"inventory":           false,
+	"legal-hold":          false,
+	"logging":             false,
+	"metrics":             false,
+	"object-lock":         false,
+	"ownershipControls":   false,
+	"policyStatus":        false,
+	"publicAccessBlock":   false,
+	"replication":         false,
+	"requestPayment":      false,
+	"restore":             false,
+	"retention":           false,
+	"select":              false,
+	"torrent":             false,
+}
+
+// notImplementedSubresource returns the first (by name) recognized but
+// unsupported sub-resource in query, or "".
+func notImplementedSubresource(query url.Values) string {
+	var names []string
+	for name := range query {
+		if supported, known := subresources[name]; known && !supported {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// queryParams are the bucket and object query parameters that are not
+// sub-resources. Any of them may be sent empty (e.g. ?prefix=).
+var queryParams = map[string]struct{}{
+	"continuation-token": {},
+	"delimiter":          {},
+	"encoding-type":      {},
+	"fetch-owner":        {},
+	"key-marker":         {},
+	"list-type":          {},
+	"marker":             {},
+	"max-keys":           {},
+	"max-parts":          {},
+	"max-uploads":        {},
+	"part-number-marker": {},
+	"prefix":             {},
+	"start-after":        {},
+	"upload-id-marker":   {},
+	"version-id-marker":  {},
+	"x-id":               {},
+}
+
+// unknownSubresource returns the first (by name) query key that looks like a
+// sub-resource, i.e. has no value (?acll or ?acll=), but is neither a
+// recognized sub-resource nor a known parameter, or "". Keys with a value are
+// never reported, so presign and response-* overrides pass.
+func unknownSubresource(query url.Values) string {
+	var names []string
+	for name, values := range query {
+		if _, known := subresources[name]; known {
+			continue
+		}
+		if _, known := queryParams[name]; known {
+			continue
+		}
+		if len(values) == 1 && values[0] == "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
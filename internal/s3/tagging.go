@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+// Bucket tag limits, as in AWS.
+const (
+	maxBucketTags     = 50
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+type tagging struct {
+	XMLName xml.Name     `xml:"Tagging"`
+	Xmlns   string       `xml:"xmlns,attr,omitempty"`
+	TagSet  []taggingTag `xml:"TagSet>Tag"`
+}
+
+type taggingTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// parseTagging validates a tag set against the tag limits.
+func parseTagging(req tagging) ([]meta.BucketTag, error) {
+	if len(req.TagSet) > maxBucketTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxBucketTags)
+	}
+	seen := make(map[string]struct{}, len(req.TagSet))
+	tags := make([]meta.BucketTag, 0, len(req.TagSet))
+	for _, t := range req.TagSet {
+		if t.Key == "" || utf8.RuneCountInString(t.Key) > maxTagKeyLength {
+			return nil, fmt.Errorf("tag key must be 1-%d characters", maxTagKeyLength)
+		}
+		if utf8.RuneCountInString(t.Value) > maxTagValueLength {
+			return nil, fmt.Errorf("tag value must be at most %d characters", maxTagValueLength)
+		}
+		if !utf8.ValidString(t.Key) || !utf8.ValidString(t.Value) {
+			return nil, errors.New("tags must be valid UTF-8")
+		}
+		if strings.HasPrefix(strings.ToLower(t.Key), "aws:") {
+			return nil, errors.New("tag keys starting with aws: are reserved")
+		}
+		if _, dup := seen[t.Key]; dup {
+			return nil, errors.New("cannot provide multiple tags with the same key")
+		}
+		seen[t.Key] = struct{}{}
+		tags = append(tags, meta.BucketTag{Key: t.Key, Value: t.Value})
+	}
+	return tags, nil
+}
+
+func (h *Handler) handleGetBucketTagging(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	exists, err := h.Meta.BucketExists(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if !exists {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+		return
+	}
+	tags, err := h.Meta.GetBucketTags(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if len(tags) == 0 {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchTagSet", "", requestID, r.URL.Path)
+		return
+	}
+	resp := tagging{Xmlns: versioningXMLNamespace}
+	for _, t := range tags {
+		resp.TagSet = append(resp.TagSet, taggingTag{Key: t.Key, Value: t.Value})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) handlePutBucketTagging(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	var req tagging
+	h.limitControlBody(w, r)
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		if h.writeBodyTooLarge(w, err, requestID, r.URL.Path) {
+			return
+		}
+		writeErrorWithResource(w, http.StatusBadRequest, "MalformedXML", "invalid xml", requestID, r.URL.Path)
+		return
+	}
+	tags, err := parseTagging(req)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidTag", err.Error(), requestID, r.URL.Path)
+		return
+	}
+	if err := h.Meta.SetBucketTags(ctx, bucket, tags); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleDeleteBucketTagging(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	if err := h.Meta.DeleteBucketTags(ctx, bucket); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
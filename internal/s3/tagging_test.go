@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+func TestBucketTagging(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "bucket", "key", "data")
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	tagSet := func(pairs ...string) string {
+		var b strings.Builder
+		b.WriteString("<Tagging><TagSet>")
+		for i := 0; i+1 < len(pairs); i += 2 {
+			fmt.Fprintf(&b, "<Tag><Key>%s</Key><Value>%s</Value></Tag>", pairs[i], pairs[i+1])
+		}
+		b.WriteString("</TagSet></Tagging>")
+		return b.String()
+	}
+
+	if w := do(http.MethodGet, "/bucket?tagging", ""); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "NoSuchTagSet") {
+		t.Fatalf("expected NoSuchTagSet, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/missing?tagging", tagSet("team", "core")); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing bucket, got %d", w.Code)
+	}
+	tooMany := make([]string, 0, 2*(maxBucketTags+1))
+	for i := 0; i <= maxBucketTags; i++ {
+		tooMany = append(tooMany, fmt.Sprintf("k%d", i), "v")
+	}
+	cases := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"malformed", "<Tagging>", http.StatusBadRequest},
+		{"empty key", tagSet("", "v"), http.StatusBadRequest},
+		{"long key", tagSet(strings.Repeat("k", maxTagKeyLength+1), "v"), http.StatusBadRequest},
+		{"long value", tagSet("k", strings.Repeat("v", maxTagValueLength+1)), http.StatusBadRequest},
+		{"duplicate key", tagSet("k", "a", "k", "b"), http.StatusBadRequest},
+		{"reserved prefix", tagSet("aws:createdBy", "me"), http.StatusBadRequest},
+		{"too many", tagSet(tooMany...), http.StatusBadRequest},
+		{"valid", tagSet("team", "core", "cost-center", "42"), http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		if w := do(http.MethodPut, "/bucket?tagging", tc.body); w.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.code, w.Code, w.Body.String())
+		}
+	}
+	w := do(http.MethodGet, "/bucket?tagging", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<Tag><Key>cost-center</Key><Value>42</Value></Tag><Tag><Key>team</Key><Value>core</Value></Tag>") {
+		t.Fatalf("GET tagging: %d %s", w.Code, w.Body.String())
+	}
+
+	// ?tagging with a key addresses object tags, which stay unsupported.
+	if w := do(http.MethodGet, "/bucket/key?tagging", ""); w.Code != http.StatusNotImplemented {
+		t.Fatalf("object tagging: expected 501, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = do(http.MethodGet, "/v1/meta/usage", "")
+	var usage usageResponse
+	if err := json.NewDecoder(w.Body).Decode(&usage); err != nil || len(usage.Buckets) != 1 {
+		t.Fatalf("usage: %+v (%v)", usage, err)
+	}
+	if tags := usage.Buckets[0].Tags; len(tags) != 2 || tags[0] != (meta.BucketTag{Key: "cost-center", Value: "42"}) {
+		t.Fatalf("usage tags: %+v", tags)
+	}
+
+	if w := do(http.MethodDelete, "/bucket?tagging", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE tagging: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/bucket?tagging", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
@@ -0,0 +1,38 @@
+package s3
+
+import "net/http"
+
+// traceIDHeader carries a client correlation id. It is echoed back and
+// logged next to the request id; x-amz-request-id itself is always generated
+// by the server.
+const traceIDHeader = "x-seglake-trace-id"
+
+const maxTraceIDLen = 128
+
+// requestTraceID returns the client's x-seglake-trace-id, else the
+// x-amz-request-id it sent, or "" when neither is a usable token.
+func requestTraceID(r *http.Request) string {
+	for _, name := range []string{traceIDHeader, "x-amz-request-id"} {
+		if value := r.Header.Get(name); validTraceID(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+// validTraceID keeps trace ids to short tokens that cannot break log lines.
+func validTraceID(value string) bool {
+	if value == "" || len(value) > maxTraceIDLen {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '/', c == '+', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
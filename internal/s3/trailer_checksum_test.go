@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"encoding/base64"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func trailerChecksumRequest(path, payload, checksum string) *http.Request {
+	body := strings.Join([]string{
+		strconv.FormatInt(int64(len(payload)), 16),
+		payload,
+		"0",
+		"x-amz-checksum-crc32c: " + checksum,
+		"",
+		"",
+	}, "\r\n")
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	req.Header.Set("X-Amz-Content-Sha256", "STREAMING-UNSIGNED-PAYLOAD-TRAILER")
+	req.Header.Set("Content-Encoding", "aws-chunked")
+	req.Header.Set("X-Amz-Trailer", "x-amz-checksum-crc32c")
+	req.Header.Set("X-Amz-Decoded-Content-Length", strconv.Itoa(len(payload)))
+	return req
+}
+
+func crc32cBase64(payload string) string {
+	sum := crc32.Checksum([]byte(payload), crc32.MakeTable(crc32.Castagnoli))
+	return base64.StdEncoding.EncodeToString([]byte{byte(sum >> 24), byte(sum >> 16), byte(sum >> 8), byte(sum)})
+}
+
+func TestPutTrailerChecksumStored(t *testing.T) {
+	h := newTestHandler(t)
+	checksum := crc32cBase64("hello world")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, trailerChecksumRequest("/bucket/key", "hello world", checksum))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("x-amz-checksum-crc32c"); got != checksum {
+		t.Fatalf("PUT checksum header: %q", got)
+	}
+
+	head := httptest.NewRequest(http.MethodHead, "/bucket/key", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, head)
+	if got := w.Header().Get("x-amz-checksum-crc32c"); got != "" {
+		t.Fatalf("checksum returned without checksum mode: %q", got)
+	}
+	head.Header.Set("x-amz-checksum-mode", "ENABLED")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, head)
+	if got := w.Header().Get("x-amz-checksum-crc32c"); got != checksum {
+		t.Fatalf("HEAD checksum header: %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, trailerChecksumRequest("/bucket/bad", "hello world", crc32cBase64("other")))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "BadDigest") {
+		t.Fatalf("expected BadDigest, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestPutTrailerChecksumDisabled(t *testing.T) {
+	h := newTestHandler(t)
+	h.DisableTrailerChecksums = true
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, trailerChecksumRequest("/bucket/key", "hello world", crc32cBase64("other")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status: %d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("x-amz-checksum-crc32c"); got != "" {
+		t.Fatalf("unexpected checksum header: %q", got)
+	}
+	head := httptest.NewRequest(http.MethodHead, "/bucket/key", nil)
+	head.Header.Set("x-amz-checksum-mode", "ENABLED")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, head)
+	if got := w.Header().Get("x-amz-checksum-crc32c"); got != "" {
+		t.Fatalf("unexpected stored checksum: %q", got)
+	}
+}
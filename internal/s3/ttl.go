@@ -0,0 +1,27 @@
+package s3
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ttlHeader sets a per-version expiry on PUT, in seconds from the request.
+// Expired versions answer 404 and are deleted by the lifecycle pass.
+const ttlHeader = "X-Seglake-Ttl"
+
+// maxObjectTTL keeps the expiry well inside the range of time.Time.
+const maxObjectTTL = 100 * 365 * 24 * time.Hour
+
+// parseObjectTTL parses the ttlHeader value; an empty value means no expiry.
+func parseObjectTTL(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	seconds, err := parseInt(raw)
+	if err != nil || seconds <= 0 || seconds > int64(maxObjectTTL/time.Second) {
+		return 0, errors.New("invalid " + strings.ToLower(ttlHeader))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
@@ -0,0 +1,55 @@
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+type usageResponse struct {
+	Buckets    []meta.BucketUsage `json:"buckets"`
+	AccessKeys []accessKeyUsage   `json:"access_keys"`
+}
+
+type accessKeyUsage struct {
+	AccessKey     string           `json:"access_key"`
+	RequestsTotal int64            `json:"requests_total"`
+	Requests      map[string]int64 `json:"requests"`
+}
+
+// handleUsage reports per-bucket storage (ACTIVE versions, all versions
+// counted) and per-access-key request counters since process start.
+func (h *Handler) handleUsage(ctx context.Context, w http.ResponseWriter, requestID string, resource string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, resource)
+		return
+	}
+	buckets, err := h.Meta.ListBucketUsage(ctx)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, resource)
+		return
+	}
+	resp := usageResponse{
+		Buckets:    buckets,
+		AccessKeys: []accessKeyUsage{},
+	}
+	if resp.Buckets == nil {
+		resp.Buckets = []meta.BucketUsage{}
+	}
+	for accessKey, byClass := range h.Metrics.AccessKeyRequests() {
+		entry := accessKeyUsage{AccessKey: accessKey, Requests: byClass}
+		for _, v := range byClass {
+			entry.RequestsTotal += v
+		}
+		resp.AccessKeys = append(resp.AccessKeys, entry)
+	}
+	sort.Slice(resp.AccessKeys, func(i, j int) bool {
+		return resp.AccessKeys[i].AccessKey < resp.AccessKeys[j].AccessKey
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	userMetadataPrefix = "X-Amz-Meta-"
+	taggingHeader      = "X-Amz-Tagging"
+	// maxUserMetadataSize bounds the x-amz-meta-* names and values, as in AWS.
+	maxUserMetadataSize = 2 << 10
+	// maxObjectTags is the AWS limit for the tag set of one object.
+	maxObjectTags = 10
+)
+
+var errUserMetadataTooLarge = errors.New("user metadata too large")
+
+// parseUserMetadata returns the x-amz-meta-* headers of r keyed by the
+// lower-cased name without the prefix; repeated headers are joined with ",".
+func parseUserMetadata(r *http.Request) (map[string]string, error) {
+	var metadata map[string]string
+	size := 0
+	for name, values := range r.Header {
+		if len(name) <= len(userMetadataPrefix) || !strings.EqualFold(name[:len(userMetadataPrefix)], userMetadataPrefix) {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		key := strings.ToLower(name[len(userMetadataPrefix):])
+		value := strings.Join(values, ",")
+		size += len(key) + len(value)
+		metadata[key] = value
+	}
+	if size > maxUserMetadataSize {
+		return nil, errUserMetadataTooLarge
+	}
+	return metadata, nil
+}
+
+// parseTaggingHeader parses the URL-encoded tag set of x-amz-tagging
+// ("k1=v1&k2=v2") against the tag limits.
+func parseTaggingHeader(r *http.Request) (map[string]string, error) {
+	raw := r.Header.Get(taggingHeader)
+	if raw == "" {
+		return nil, nil
+	}
+	var req tagging
+	for _, pair := range strings.Split(raw, "&") {
+		if pair == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag key %q", k)
+		}
+		value, err := url.QueryUnescape(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag value %q", v)
+		}
+		req.TagSet = append(req.TagSet, taggingTag{Key: key, Value: value})
+	}
+	if len(req.TagSet) > maxObjectTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxObjectTags)
+	}
+	parsed, err := parseTagging(req)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(parsed))
+	for _, t := range parsed {
+		tags[t.Key] = t.Value
+	}
+	return tags, nil
+}
+
+// setUserMetadataHeaders returns the stored metadata as x-amz-meta-* headers
+// and the tag count as x-amz-tagging-count, as S3 does on GET/HEAD.
+func setUserMetadataHeaders(w http.ResponseWriter, metadata, tags map[string]string) {
+	for key, value := range metadata {
+		w.Header().Set(userMetadataPrefix+key, value)
+	}
+	if len(tags) > 0 {
+		w.Header().Set("x-amz-tagging-count", strconv.Itoa(len(tags)))
+	}
+}
@@ -0,0 +1,53 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPutStoresUserMetadataAndTags(t *testing.T) {
+	h := newTestHandler(t)
+	put := func(key string, headers map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, "/bucket/"+key, strings.NewReader("data"))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	if w := put("doc", map[string]string{"x-amz-meta-Owner": "ops", "x-amz-tagging": "team=storage&env=prod%20eu"}); w.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", w.Code, w.Body.String())
+	}
+	req := httptest.NewRequest(http.MethodHead, "/bucket/doc", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("x-amz-meta-owner") != "ops" || w.Header().Get("x-amz-tagging-count") != "2" {
+		t.Fatalf("unexpected HEAD: %d %v", w.Code, w.Header())
+	}
+	metadata, tags, err := h.Meta.VersionMetadata(t.Context(), w.Header().Get("x-amz-version-id"))
+	if err != nil {
+		t.Fatalf("VersionMetadata: %v", err)
+	}
+	if metadata["owner"] != "ops" || tags["team"] != "storage" || tags["env"] != "prod eu" {
+		t.Fatalf("unexpected stored metadata %v tags %v", metadata, tags)
+	}
+
+	// An overwrite without headers carries none.
+	putObject(t, h, "bucket", "doc", "v2")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bucket/doc", nil))
+	if w.Header().Get("x-amz-meta-owner") != "" || w.Header().Get("x-amz-tagging-count") != "" {
+		t.Fatalf("unexpected metadata on overwrite: %v", w.Header())
+	}
+
+	if w := put("big", map[string]string{"x-amz-meta-blob": strings.Repeat("x", maxUserMetadataSize)}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "MetadataTooLarge") {
+		t.Fatalf("expected MetadataTooLarge, got %d %s", w.Code, w.Body.String())
+	}
+	if w := put("tags", map[string]string{"x-amz-tagging": "aws:owner=x"}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "InvalidTag") {
+		t.Fatalf("expected InvalidTag, got %d %s", w.Code, w.Body.String())
+	}
+}
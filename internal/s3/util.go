@@ -1,12 +1,14 @@
 package s3
 
 import (
+	"bufio"
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"hash"
 	"io"
 	"net/http"
@@ -134,6 +136,36 @@ func (r *validatingReader) Read(p []byte) (int, error) {
 	return n, err
 }
 
+// defaultMaxBodyBytes caps control-plane request bodies when MaxBodyBytes is unset.
+const defaultMaxBodyBytes = 1 << 20
+
+func (h *Handler) maxBodyBytes() int64 {
+	if h == nil || h.MaxBodyBytes <= 0 {
+		return defaultMaxBodyBytes
+	}
+	return h.MaxBodyBytes
+}
+
+// limitControlBody caps a control-plane request body at maxBodyBytes; object
+// data stays governed by MaxObjectSize.
+func (h *Handler) limitControlBody(w http.ResponseWriter, r *http.Request) {
+	if r == nil || r.Body == nil {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBodyBytes())
+}
+
+// writeBodyTooLarge writes 413 EntityTooLarge when err came from a body capped
+// by limitControlBody.
+func (h *Handler) writeBodyTooLarge(w http.ResponseWriter, err error, requestID, resource string) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	writeErrorWithResource(w, http.StatusRequestEntityTooLarge, "EntityTooLarge", fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), requestID, resource)
+	return true
+}
+
 type sizeLimitReader struct {
 	reader    io.Reader
 	remaining int64
@@ -166,6 +198,18 @@ func (r *sizeLimitReader) Read(p []byte) (int, error) {
 	return n, err
 }
 
+// sniffContentType infers a content type from the first 512 bytes of reader
+// with http.DetectContentType. The returned reader replays the peeked bytes,
+// and any read error, before the rest of the body. An empty body yields "".
+func sniffContentType(reader io.Reader) (string, io.Reader) {
+	br := bufio.NewReaderSize(reader, 512)
+	head, _ := br.Peek(512)
+	if len(head) == 0 {
+		return "", br
+	}
+	return http.DetectContentType(head), br
+}
+
 func parseContentMD5(header string) ([]byte, error) {
 	header = strings.TrimSpace(header)
 	if header == "" {
@@ -4,9 +4,18 @@ import (
 	"errors"
 	"net"
 	"strings"
+	"unicode/utf8"
 )
 
-var errInvalidBucketName = errors.New("invalid bucket name")
+// maxObjectKeyBytes is the S3 limit on UTF-8 encoded object key length.
+const maxObjectKeyBytes = 1024
+
+var (
+	errInvalidBucketName = errors.New("invalid bucket name")
+	errKeyTooLong        = errors.New("object key exceeds 1024 bytes")
+	errInvalidObjectKey  = errors.New("object key must be valid UTF-8 without control characters")
+	errAmbiguousKey      = errors.New("object key has empty, \".\" or \"..\" path segments that clients and proxies may normalize away")
+)
 
 // ValidateBucketName enforces common S3 bucket naming rules.
 func ValidateBucketName(bucket string) error {
@@ -58,3 +67,52 @@ func ValidateBucketName(bucket string) error {
 func isLowerAlphaNum(b byte) bool {
 	return (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9')
 }
+
+// ValidateObjectKey rejects keys that S3 clients cannot round-trip: keys
+// longer than 1024 bytes, invalid UTF-8, and characters that XML 1.0 cannot
+// carry (NUL and other C0 controls except tab, LF and CR).
+func ValidateObjectKey(key string) error {
+	if len(key) > maxObjectKeyBytes {
+		return errKeyTooLong
+	}
+	if !utf8.ValidString(key) {
+		return errInvalidObjectKey
+	}
+	for _, r := range key {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return errInvalidObjectKey
+		}
+		if r == 0xFFFE || r == 0xFFFF {
+			return errInvalidObjectKey
+		}
+	}
+	return nil
+}
+
+// validateKeySegments rejects keys that a path-normalizing client or proxy
+// would rewrite before they reach seglake: a "." or ".." segment, or an empty
+// segment ("//", or a leading "/"). The key is stored literally either way, so
+// a write would create a key that those clients cannot address again. A
+// trailing "/" (folder marker) is allowed.
+func validateKeySegments(key string) error {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		switch segment {
+		case ".", "..":
+			return errAmbiguousKey
+		case "":
+			if i < len(segments)-1 {
+				return errAmbiguousKey
+			}
+		}
+	}
+	return nil
+}
+
+// objectKeyErrorCode maps a ValidateObjectKey error to its S3 error code.
+func objectKeyErrorCode(err error) string {
+	if errors.Is(err, errKeyTooLong) {
+		return "KeyTooLongError"
+	}
+	return "InvalidArgument"
+}
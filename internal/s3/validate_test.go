@@ -49,3 +49,31 @@ func TestValidateBucketName(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateObjectKey(t *testing.T) {
+	valid := []string{
+		"a",
+		"dir/file.txt",
+		"tab\tkey",
+		"zażółć/日本",
+		strings.Repeat("k", 1024),
+	}
+	for _, key := range valid {
+		if err := ValidateObjectKey(key); err != nil {
+			t.Fatalf("expected valid key %q: %v", key, err)
+		}
+	}
+
+	invalid := []string{
+		strings.Repeat("k", 1025),
+		"nul\x00key",
+		"bell\x07key",
+		"bad\xffutf8",
+		"nonchar￿",
+	}
+	for _, key := range invalid {
+		if err := ValidateObjectKey(key); err == nil {
+			t.Fatalf("expected invalid key %q", key)
+		}
+	}
+}
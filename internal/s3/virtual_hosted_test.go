@@ -22,3 +22,13 @@ func TestHostBucketParsesSubdomain(t *testing.T) {
 		t.Fatalf("expected bucket, got %q", got)
 	}
 }
+
+func TestParseBucketKeyVirtualHostedLeadingSlash(t *testing.T) {
+	h := &Handler{VirtualHosted: true}
+	req := httptest.NewRequest("GET", "http://bucket.localhost//key", nil)
+	req.Host = "bucket.localhost"
+	bucket, key, ok := h.parseBucketKey(req)
+	if !ok || bucket != "bucket" || key != "/key" {
+		t.Fatalf("expected bucket /key, got %q %q %v", bucket, key, ok)
+	}
+}
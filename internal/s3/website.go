@@ -0,0 +1,174 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// websiteRedirectCacheControl lets the CDN and clients cache the redirect
+// briefly; the object itself is cached under the CDN's own rules.
+const websiteRedirectCacheControl = "public, max-age=300"
+
+type websiteConfiguration struct {
+	XMLName               xml.Name              `xml:"WebsiteConfiguration"`
+	Xmlns                 string                `xml:"xmlns,attr,omitempty"`
+	RedirectAllRequestsTo *websiteRedirectAllTo `xml:"RedirectAllRequestsTo"`
+	IndexDocument         *struct{}             `xml:"IndexDocument"`
+	ErrorDocument         *struct{}             `xml:"ErrorDocument"`
+	RoutingRules          *struct{}             `xml:"RoutingRules"`
+}
+
+type websiteRedirectAllTo struct {
+	HostName string `xml:"HostName"`
+	Protocol string `xml:"Protocol,omitempty"`
+}
+
+// parseWebsiteConfiguration returns the redirect base URL. HostName may carry
+// a path prefix (cdn.example.com/assets), which AWS does not allow.
+func parseWebsiteConfiguration(cfg websiteConfiguration) (string, error) {
+	if cfg.IndexDocument != nil || cfg.ErrorDocument != nil || cfg.RoutingRules != nil {
+		return "", errors.New("only RedirectAllRequestsTo is supported")
+	}
+	if cfg.RedirectAllRequestsTo == nil {
+		return "", errors.New("RedirectAllRequestsTo required")
+	}
+	protocol := strings.ToLower(strings.TrimSpace(cfg.RedirectAllRequestsTo.Protocol))
+	if protocol == "" {
+		protocol = "https"
+	}
+	if protocol != "http" && protocol != "https" {
+		return "", errors.New("Protocol must be http or https")
+	}
+	host := strings.TrimRight(strings.TrimSpace(cfg.RedirectAllRequestsTo.HostName), "/")
+	if host == "" {
+		return "", errors.New("HostName required")
+	}
+	base := protocol + "://" + host
+	parsed, err := url.Parse(base)
+	if err != nil || parsed.Host == "" || parsed.User != nil || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", errors.New("invalid HostName")
+	}
+	return base, nil
+}
+
+// websiteConfigurationFromBase is the inverse of parseWebsiteConfiguration.
+func websiteConfigurationFromBase(base string) websiteConfiguration {
+	protocol, host, _ := strings.Cut(base, "://")
+	return websiteConfiguration{
+		Xmlns: versioningXMLNamespace,
+		RedirectAllRequestsTo: &websiteRedirectAllTo{
+			HostName: host,
+			Protocol: protocol,
+		},
+	}
+}
+
+func (h *Handler) handleGetBucketWebsite(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	exists, err := h.Meta.BucketExists(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if !exists {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+		return
+	}
+	cfg, err := h.Meta.GetBucketWebsite(ctx, bucket)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchWebsiteConfiguration", "", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(websiteConfigurationFromBase(cfg.RedirectBaseURL))
+}
+
+func (h *Handler) handlePutBucketWebsite(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	var req websiteConfiguration
+	h.limitControlBody(w, r)
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		if h.writeBodyTooLarge(w, err, requestID, r.URL.Path) {
+			return
+		}
+		writeErrorWithResource(w, http.StatusBadRequest, "MalformedXML", "invalid xml", requestID, r.URL.Path)
+		return
+	}
+	base, err := parseWebsiteConfiguration(req)
+	if err != nil {
+		writeErrorWithResource(w, http.StatusBadRequest, "InvalidArgument", err.Error(), requestID, r.URL.Path)
+		return
+	}
+	if err := h.Meta.SetBucketWebsite(ctx, bucket, base); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+			return
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) handleDeleteBucketWebsite(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, requestID string) {
+	if h.Meta == nil {
+		writeErrorWithResource(w, http.StatusInternalServerError, "InternalError", "meta not initialized", requestID, r.URL.Path)
+		return
+	}
+	exists, err := h.Meta.BucketExists(ctx, bucket)
+	if err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	if !exists {
+		writeErrorWithResource(w, http.StatusNotFound, "NoSuchBucket", "bucket not found", requestID, r.URL.Path)
+		return
+	}
+	if err := h.Meta.DeleteBucketWebsite(ctx, bucket); err != nil {
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// websiteRedirect answers an anonymous GET on a bucket with a redirect base
+// URL by pointing the client at the same key behind that URL, without reading
+// the object. Signed, presigned, versioned and range requests are served
+// directly. It returns true when the response has been written.
+func (h *Handler) websiteRedirect(ctx context.Context, w http.ResponseWriter, r *http.Request, bucket, key, requestID string) bool {
+	if h.Meta == nil || !isUnsignedRequest(r) {
+		return false
+	}
+	if r.Header.Get("Range") != "" || r.URL.Query().Has("versionId") || r.URL.Query().Has("partNumber") {
+		return false
+	}
+	cfg, err := h.Meta.GetBucketWebsite(ctx, bucket)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false
+		}
+		h.writeInternalError(w, err, requestID, r.URL.Path)
+		return true
+	}
+	target := cfg.RedirectBaseURL + "/" + (&url.URL{Path: key}).EscapedPath()
+	w.Header().Set("Location", target)
+	w.Header().Set("Cache-Control", websiteRedirectCacheControl)
+	w.WriteHeader(http.StatusFound)
+	return true
+}
@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBucketWebsiteConfiguration(t *testing.T) {
+	h := newTestHandler(t)
+	if err := h.Meta.CreateBucket(context.Background(), "bucket"); err != nil {
+		t.Fatalf("CreateBucket: %v", err)
+	}
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/bucket?website", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, ""); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "NoSuchWebsiteConfiguration") {
+		t.Fatalf("expected NoSuchWebsiteConfiguration, got %d %s", w.Code, w.Body.String())
+	}
+	cases := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"index document unsupported", `<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument></WebsiteConfiguration>`, http.StatusBadRequest},
+		{"missing host", `<WebsiteConfiguration><RedirectAllRequestsTo><HostName></HostName></RedirectAllRequestsTo></WebsiteConfiguration>`, http.StatusBadRequest},
+		{"bad protocol", `<WebsiteConfiguration><RedirectAllRequestsTo><HostName>cdn.example.com</HostName><Protocol>ftp</Protocol></RedirectAllRequestsTo></WebsiteConfiguration>`, http.StatusBadRequest},
+		{"query in host", `<WebsiteConfiguration><RedirectAllRequestsTo><HostName>cdn.example.com/?x=1</HostName></RedirectAllRequestsTo></WebsiteConfiguration>`, http.StatusBadRequest},
+		{"redirect", `<WebsiteConfiguration><RedirectAllRequestsTo><HostName>cdn.example.com/assets/</HostName></RedirectAllRequestsTo></WebsiteConfiguration>`, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if w := do(http.MethodPut, tc.body); w.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d %s", tc.name, tc.code, w.Code, w.Body.String())
+		}
+	}
+	w := do(http.MethodGet, "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<HostName>cdn.example.com/assets</HostName>") || !strings.Contains(w.Body.String(), "<Protocol>https</Protocol>") {
+		t.Fatalf("unexpected website: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodDelete, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete website: %d", w.Code)
+	}
+	if w := do(http.MethodGet, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected website removed, got %d", w.Code)
+	}
+}
+
+func TestWebsiteRedirectForAnonymousGet(t *testing.T) {
+	h := newTestHandler(t)
+	putObject(t, h, "bucket", "dir/a%20b.txt", "hello")
+	if err := h.Meta.SetBucketWebsite(context.Background(), "bucket", "https://cdn.example.com/assets"); err != nil {
+		t.Fatalf("SetBucketWebsite: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/bucket/dir/a%20b.txt", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Location"); got != "https://cdn.example.com/assets/dir/a%20b.txt" {
+		t.Fatalf("location=%q", got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != websiteRedirectCacheControl {
+		t.Fatalf("cache-control=%q", got)
+	}
+
+	direct := []struct {
+		name   string
+		method string
+		target string
+		header map[string]string
+	}{
+		{"range", http.MethodGet, "/bucket/dir/a%20b.txt", map[string]string{"Range": "bytes=0-1"}},
+		{"signed", http.MethodGet, "/bucket/dir/a%20b.txt", map[string]string{"Authorization": "AWS4-HMAC-SHA256 Credential=x"}},
+		{"version", http.MethodGet, "/bucket/dir/a%20b.txt?versionId=null", nil},
+		{"head", http.MethodHead, "/bucket/dir/a%20b.txt", nil},
+	}
+	for _, tc := range direct {
+		req := httptest.NewRequest(tc.method, tc.target, nil)
+		for k, v := range tc.header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code == http.StatusFound || w.Header().Get("Location") != "" {
+			t.Fatalf("%s: redirected (status=%d)", tc.name, w.Code)
+		}
+	}
+}
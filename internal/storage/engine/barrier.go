@@ -3,10 +3,30 @@ package engine
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"sync"
 	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+const (
+	defaultBarrierInterval = 100 * time.Millisecond
+	defaultBarrierMaxBytes = 128 << 20
 )
 
+// BarrierStats reports write barrier thresholds and activity since the engine started.
+type BarrierStats struct {
+	Interval      time.Duration
+	MaxBytes      int64
+	Barriers      int64
+	Ops           int64
+	Bytes         int64
+	LastBytes     int64
+	LastOps       int64
+	LastBarrierAt time.Time
+}
+
 type writeBarrier struct {
 	engine       *Engine
 	interval     time.Duration
@@ -18,14 +38,21 @@ type writeBarrier struct {
 	commits      []func(tx *sql.Tx) error
 	timer        *time.Timer
 	flushRunning bool
+
+	barriers      int64
+	totalOps      int64
+	totalBytes    int64
+	lastBytes     int64
+	lastOps       int64
+	lastBarrierAt time.Time
 }
 
 func newWriteBarrier(engine *Engine, interval time.Duration, maxBytes int64) *writeBarrier {
 	if interval <= 0 {
-		interval = 100 * time.Millisecond
+		interval = defaultBarrierInterval
 	}
 	if maxBytes <= 0 {
-		maxBytes = 128 << 20
+		maxBytes = defaultBarrierMaxBytes
 	}
 	return &writeBarrier{
 		engine:   engine,
@@ -86,6 +113,8 @@ func (b *writeBarrier) flush() {
 	}
 	waiters := b.waiters
 	commits := b.commits
+	flushedBytes := b.pendingBytes
+	flushedOps := int64(b.pendingOps)
 	b.waiters = nil
 	b.commits = nil
 	b.pendingBytes = 0
@@ -99,6 +128,14 @@ func (b *writeBarrier) flush() {
 
 	b.mu.Lock()
 	b.flushRunning = false
+	if len(waiters) > 0 || len(commits) > 0 {
+		b.barriers++
+		b.totalOps += flushedOps
+		b.totalBytes += flushedBytes
+		b.lastBytes = flushedBytes
+		b.lastOps = flushedOps
+		b.lastBarrierAt = b.engine.clock.Now().UTC()
+	}
 	b.mu.Unlock()
 
 	for _, ch := range waiters {
@@ -106,3 +143,55 @@ func (b *writeBarrier) flush() {
 		close(ch)
 	}
 }
+
+func (b *writeBarrier) stats() BarrierStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BarrierStats{
+		Interval:      b.interval,
+		MaxBytes:      b.maxBytes,
+		Barriers:      b.barriers,
+		Ops:           b.totalOps,
+		Bytes:         b.totalBytes,
+		LastBytes:     b.lastBytes,
+		LastOps:       b.lastOps,
+		LastBarrierAt: b.lastBarrierAt,
+	}
+}
+
+// setThresholds swaps the flush thresholds. A pending timer keeps its old
+// deadline; the new interval applies from the next barrier.
+func (b *writeBarrier) setThresholds(interval time.Duration, maxBytes int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if interval > 0 {
+		b.interval = interval
+	}
+	if maxBytes > 0 {
+		b.maxBytes = maxBytes
+	}
+	if b.pendingBytes >= b.maxBytes && len(b.waiters) > 0 && !b.flushRunning {
+		go b.flush()
+	}
+}
+
+// BarrierStats returns write barrier thresholds and counters.
+func (e *Engine) BarrierStats() BarrierStats {
+	return e.barrier.stats()
+}
+
+// SetBarrierThresholds changes the write barrier interval and byte threshold
+// at runtime. Zero or negative values keep the current setting. The interval
+// is validated against the meta durability settings like Options.BarrierInterval.
+func (e *Engine) SetBarrierThresholds(interval time.Duration, maxBytes int64) error {
+	if interval <= 0 && maxBytes <= 0 {
+		return errors.New("engine: barrier interval or max bytes required")
+	}
+	if interval > 0 && e.metaStore != nil {
+		if err := meta.ValidateDurability(e.metaStore.Durability(), e.metaStore.SyncWindow(), interval); err != nil {
+			return err
+		}
+	}
+	e.barrier.setThresholds(interval, maxBytes)
+	return nil
+}
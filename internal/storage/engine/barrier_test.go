@@ -3,6 +3,8 @@ package engine
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -85,3 +87,145 @@ func TestBarrierWaitContextCanceled(t *testing.T) {
 		t.Fatalf("expected context.Canceled, got %v", err)
 	}
 }
+
+func TestNewRejectsDurabilityWindowBelowBarrier(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.OpenWithOptions(dir+"/meta.db", meta.OpenOptions{
+		Durability: meta.DurabilityBalanced,
+		SyncWindow: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("meta.OpenWithOptions: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	if _, err := New(Options{
+		Layout:          fs.NewLayout(dir + "/data"),
+		MetaStore:       store,
+		BarrierInterval: 100 * time.Millisecond,
+	}); err == nil {
+		t.Fatalf("expected durability window validation error")
+	}
+}
+
+func TestBarrierStatsAndThresholds(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(dir + "/meta.db")
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	engine, err := New(Options{
+		Layout:          fs.NewLayout(dir + "/data"),
+		MetaStore:       store,
+		BarrierInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if stats := engine.BarrierStats(); stats.Interval != 5*time.Millisecond || stats.MaxBytes != defaultBarrierMaxBytes || stats.Barriers != 0 {
+		t.Fatalf("initial stats=%+v", stats)
+	}
+	payload := bytes.Repeat([]byte("x"), 64)
+	if _, _, err := engine.PutObject(context.Background(), "b", "k1", "", bytes.NewReader(payload)); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	stats := engine.BarrierStats()
+	if stats.Barriers != 1 || stats.Ops != 1 || stats.Bytes != 64 || stats.LastBytes != 64 || stats.LastOps != 1 || stats.LastBarrierAt.IsZero() {
+		t.Fatalf("stats after put=%+v", stats)
+	}
+
+	if err := engine.SetBarrierThresholds(0, 0); err == nil {
+		t.Fatalf("expected error for empty thresholds")
+	}
+	if err := engine.SetBarrierThresholds(0, 1); err != nil {
+		t.Fatalf("SetBarrierThresholds: %v", err)
+	}
+	// A put larger than max bytes flushes without waiting for the interval.
+	if err := engine.SetBarrierThresholds(time.Hour, 0); err != nil {
+		t.Fatalf("SetBarrierThresholds: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := engine.PutObject(context.Background(), "b", "k2", "", bytes.NewReader(payload))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("PutObject: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("put waited for the barrier interval")
+	}
+	stats = engine.BarrierStats()
+	if stats.Interval != time.Hour || stats.MaxBytes != 1 || stats.Barriers != 2 || stats.Bytes != 128 {
+		t.Fatalf("stats after set=%+v", stats)
+	}
+}
+
+func TestSetBarrierThresholdsValidatesDurability(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.OpenWithOptions(dir+"/meta.db", meta.OpenOptions{Durability: meta.DurabilityBalanced, SyncWindow: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("meta.OpenWithOptions: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	engine, err := New(Options{
+		Layout:          fs.NewLayout(dir + "/data"),
+		MetaStore:       store,
+		BarrierInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := engine.SetBarrierThresholds(time.Second, 0); err == nil {
+		t.Fatalf("expected interval longer than sync window to be rejected")
+	}
+	if stats := engine.BarrierStats(); stats.Interval != 10*time.Millisecond {
+		t.Fatalf("interval changed to %s", stats.Interval)
+	}
+}
+
+func TestCommitMetaWithCheckRejectsOnlyItsCommit(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(dir + "/meta.db")
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	engine, err := New(Options{
+		Layout:          fs.NewLayout(dir + "/data"),
+		MetaStore:       store,
+		BarrierInterval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, _, err := engine.PutObject(context.Background(), "b", "k", "", bytes.NewReader([]byte("data"))); err != nil {
+			t.Errorf("PutObject: %v", err)
+		}
+	}()
+	committed := false
+	err = engine.CommitMetaWithCheck(context.Background(), func(*sql.Tx) error {
+		return ErrPreconditionFailed
+	}, func(*sql.Tx) error {
+		committed = true
+		return nil
+	})
+	wg.Wait()
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", err)
+	}
+	if committed {
+		t.Fatalf("rejected commit must not run")
+	}
+	if _, err := store.CurrentVersion(context.Background(), "b", "k"); err != nil {
+		t.Fatalf("batched put must still commit: %v", err)
+	}
+}
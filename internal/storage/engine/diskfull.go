@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/kk-code-lab/seglake/internal/storage/chunk"
+)
+
+// ErrInsufficientStorage reports that a write failed because the objects
+// volume is full (ENOSPC).
+var ErrInsufficientStorage = errors.New("engine: insufficient storage")
+
+// DiskFull reports whether the most recent write failed with ENOSPC. It is
+// cleared by the next successful write or ProbeDiskSpace.
+func (e *Engine) DiskFull() bool {
+	return e.diskFull.Load()
+}
+
+// noteWriteResult tracks disk-full state and wraps ENOSPC failures in
+// ErrInsufficientStorage; other errors are returned unchanged.
+func (e *Engine) noteWriteResult(err error) error {
+	if err == nil {
+		e.diskFull.Store(false)
+		return nil
+	}
+	if errors.Is(err, syscall.ENOSPC) {
+		e.diskFull.Store(true)
+		return fmt.Errorf("%w: %w", ErrInsufficientStorage, err)
+	}
+	return err
+}
+
+// ProbeDiskSpace retries the volume while DiskFull is set: it writes and
+// syncs one chunk where open segments go and clears the flag on success.
+// Readiness checks call it, because a node reported unready gets no client
+// writes that could clear the flag. It returns DiskFull after the probe.
+func (e *Engine) ProbeDiskSpace() bool {
+	if !e.diskFull.Load() || e.readOnly {
+		return e.diskFull.Load()
+	}
+	// Concurrent probes would only compete for the space being measured.
+	if !e.probing.CompareAndSwap(false, true) {
+		return true
+	}
+	defer e.probing.Store(false)
+	file, err := os.CreateTemp(filepath.Dir(e.layout.StagingPath("probe")), ".space-probe-*")
+	if err != nil {
+		return true
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	_, err = file.Write(make([]byte, chunk.DefaultSize))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return true
+	}
+	e.diskFull.Store(false)
+	return false
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+)
+
+type fullDiskCodec struct {
+	manifest.BinaryCodec
+}
+
+func (*fullDiskCodec) Encode(w io.Writer, _ *manifest.Manifest) error {
+	_, _ = w.Write([]byte("partial"))
+	return fmt.Errorf("write manifest: %w", syscall.ENOSPC)
+}
+
+func TestPutReportsInsufficientStorage(t *testing.T) {
+	dir := t.TempDir()
+	eng, err := New(Options{
+		Layout:        fs.NewLayout(filepath.Join(dir, "data")),
+		ManifestCodec: &fullDiskCodec{},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, _, err = eng.Put(context.Background(), bytes.NewReader([]byte("payload")))
+	if !errors.Is(err, ErrInsufficientStorage) || !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("expected ErrInsufficientStorage wrapping ENOSPC, got %v", err)
+	}
+	if !eng.DiskFull() {
+		t.Fatalf("expected DiskFull after ENOSPC")
+	}
+	entries, err := os.ReadDir(eng.Layout().ManifestsDir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected partial manifest removed, found %d entries", len(entries))
+	}
+
+	eng.manifestCodec = &manifest.BinaryCodec{}
+	if _, _, err := eng.Put(context.Background(), bytes.NewReader([]byte("payload"))); err != nil {
+		t.Fatalf("Put after space freed: %v", err)
+	}
+	if eng.DiskFull() {
+		t.Fatalf("expected DiskFull cleared after successful write")
+	}
+}
+
+func TestProbeDiskSpaceClearsDiskFull(t *testing.T) {
+	dir := t.TempDir()
+	eng, err := New(Options{
+		Layout:        fs.NewLayout(filepath.Join(dir, "data")),
+		ManifestCodec: &fullDiskCodec{},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if eng.ProbeDiskSpace() {
+		t.Fatalf("expected no disk-full state before a failed write")
+	}
+	if _, _, err := eng.Put(context.Background(), bytes.NewReader([]byte("payload"))); !errors.Is(err, ErrInsufficientStorage) {
+		t.Fatalf("expected ErrInsufficientStorage, got %v", err)
+	}
+	if eng.ProbeDiskSpace() || eng.DiskFull() {
+		t.Fatalf("expected the probe to clear DiskFull once space is available")
+	}
+	entries, err := os.ReadDir(eng.Layout().SegmentsDir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".space-probe-") {
+			t.Fatalf("probe file left behind: %s", entry.Name())
+		}
+	}
+}
@@ -14,7 +14,10 @@ import (
 	iofs "io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kk-code-lab/seglake/internal/clock"
@@ -45,8 +48,30 @@ type Options struct {
 	SegmentMaxAge   time.Duration
 	BarrierInterval time.Duration
 	BarrierMaxBytes int64
+	// ReadParallelism enables concurrent chunk prefetch on GET (<= 1 = serial).
+	ReadParallelism int
+	// ReadAheadChunks bounds chunks buffered ahead of the client (0 = 2x parallelism).
+	ReadAheadChunks int
+	// ReadOnly opens the layout without creating directories or sealing open
+	// segments; writes fail with ErrReadOnly.
+	ReadOnly bool
+	// VersionIDs generates version ids (nil = random). It is also installed
+	// on MetaStore so delete markers use the same generator.
+	VersionIDs meta.VersionIDGenerator
+	// SyncMode is SyncPerWrite (default) or SyncPerBarrier.
+	SyncMode string
+	// StagingDir holds open segments, e.g. on faster storage than the data
+	// dir; sealed segments are moved into the layout's SegmentsDir (empty =
+	// open segments are written there directly).
+	StagingDir string
 }
 
+// ErrReadOnly is returned by write methods of an engine opened with ReadOnly.
+var ErrReadOnly = errors.New("engine: read-only")
+
+// ErrPreconditionFailed is wrapped by PutObjectWithCheck checks that reject a write.
+var ErrPreconditionFailed = errors.New("engine: precondition failed")
+
 // Engine owns the storage read/write path.
 type Engine struct {
 	layout         fs.Layout
@@ -57,6 +82,20 @@ type Engine struct {
 	clock          clock.Clock
 	segments       *segmentManager
 	barrier        *writeBarrier
+	readParallel   int
+	readAhead      int
+	pins           *segmentPins
+	diskFull       atomic.Bool
+	probing        atomic.Bool
+	readOnly       bool
+	versionIDs     meta.VersionIDGenerator
+	syncMode       string
+	writes         writeCounters
+	// promotions tracks segments being moved out of the staging directory.
+	promotions sync.WaitGroup
+	// pendingManifests holds manifests of the current barrier batch whose
+	// fsync is deferred (SyncPerBarrier); only the flush goroutine uses it.
+	pendingManifests []string
 }
 
 // Layout returns the engine storage layout.
@@ -64,6 +103,13 @@ func (e *Engine) Layout() fs.Layout {
 	return e.layout
 }
 
+// Close waits for segments still being moved out of the staging directory;
+// call it before closing the meta store, which records their final paths.
+func (e *Engine) Close() error {
+	e.promotions.Wait()
+	return nil
+}
+
 // MissingChunk describes a missing segment range for replication.
 type MissingChunk struct {
 	SegmentID string
@@ -88,6 +134,30 @@ func New(opts Options) (*Engine, error) {
 	if opts.Clock == nil {
 		opts.Clock = clock.RealClock{}
 	}
+	if opts.VersionIDs == nil {
+		opts.VersionIDs = meta.RandomVersionIDs{}
+	} else if opts.MetaStore != nil {
+		opts.MetaStore.SetVersionIDGenerator(opts.VersionIDs)
+	}
+	if opts.MetaStore != nil {
+		interval := opts.BarrierInterval
+		if interval <= 0 {
+			interval = defaultBarrierInterval
+		}
+		if err := meta.ValidateDurability(opts.MetaStore.Durability(), opts.MetaStore.SyncWindow(), interval); err != nil {
+			return nil, err
+		}
+	}
+	syncMode, err := validateSyncMode(opts.SyncMode)
+	if err != nil {
+		return nil, err
+	}
+	if opts.StagingDir != "" {
+		opts.Layout.StagingDir = opts.StagingDir
+	}
+	if opts.ReadParallelism > 1 && opts.ReadAheadChunks <= 0 {
+		opts.ReadAheadChunks = opts.ReadParallelism * defaultReadAheadFactor
+	}
 	engine := &Engine{
 		layout:         opts.Layout,
 		segmentVersion: opts.SegmentVersion,
@@ -96,14 +166,30 @@ func New(opts Options) (*Engine, error) {
 		metaStore:      opts.MetaStore,
 		clock:          opts.Clock,
 		segments:       newSegmentManager(opts.Layout, opts.SegmentVersion, opts.MetaStore, opts.SegmentMaxBytes, opts.SegmentMaxAge, opts.Clock),
+		readParallel:   opts.ReadParallelism,
+		readAhead:      opts.ReadAheadChunks,
+		pins:           newSegmentPins(),
+		readOnly:       opts.ReadOnly,
+		versionIDs:     opts.VersionIDs,
+		syncMode:       syncMode,
+	}
+	engine.segments.writes = &engine.writes
+	if opts.Layout.StagingDir != "" {
+		engine.segments.onSeal = engine.promoteAsync
 	}
 	engine.barrier = newWriteBarrier(engine, opts.BarrierInterval, opts.BarrierMaxBytes)
+	if engine.readOnly {
+		return engine, nil
+	}
 	if err := engine.ensureDirs(); err != nil {
 		return nil, err
 	}
 	if err := engine.recoverOpenSegments(context.Background()); err != nil {
 		return nil, err
 	}
+	if err := engine.recoverStagedSegments(context.Background()); err != nil {
+		return nil, err
+	}
 	return engine, nil
 }
 
@@ -122,6 +208,9 @@ func (e *Engine) CommitMeta(ctx context.Context, commit func(tx *sql.Tx) error)
 	if commit == nil {
 		return nil
 	}
+	if e.readOnly {
+		return ErrReadOnly
+	}
 	if e.metaStore == nil {
 		return fmt.Errorf("engine: meta store not configured")
 	}
@@ -131,12 +220,223 @@ func (e *Engine) CommitMeta(ctx context.Context, commit func(tx *sql.Tx) error)
 	return e.barrier.wait(ctx)
 }
 
+// CommitMetaWithCheck is CommitMeta with check run in the barrier transaction
+// before commit. A check error wrapping ErrPreconditionFailed drops only this
+// commit and is returned; the rest of the barrier batch still commits.
+func (e *Engine) CommitMetaWithCheck(ctx context.Context, check func(tx *sql.Tx) error, commit func(tx *sql.Tx) error) error {
+	if check == nil {
+		return e.CommitMeta(ctx, commit)
+	}
+	// rejected is set by the flush goroutine and read after the barrier returns.
+	var rejected error
+	err := e.CommitMeta(ctx, func(tx *sql.Tx) error {
+		// Reset in case the meta store retries the transaction.
+		rejected = nil
+		if err := check(tx); err != nil {
+			if errors.Is(err, ErrPreconditionFailed) {
+				rejected = err
+				return nil
+			}
+			return err
+		}
+		return commit(tx)
+	})
+	if err != nil {
+		return err
+	}
+	return rejected
+}
+
 // PutObjectWithCommit stores an object stream and runs an optional meta commit in the barrier transaction.
+// A write that fails because the volume is full returns ErrInsufficientStorage.
 func (e *Engine) PutObjectWithCommit(ctx context.Context, bucket, key, contentType string, r io.Reader, extraCommit func(tx *sql.Tx, result *PutResult, manifestPath string) error) (*manifest.Manifest, *PutResult, error) {
+	return e.PutObjectWithCheck(ctx, bucket, key, contentType, r, nil, extraCommit)
+}
+
+// PutObjectWithCheck is PutObjectWithCommit with a check run in the commit
+// transaction before the version is recorded, e.g. to re-verify a
+// precondition against concurrently applied oplog entries. When the check
+// returns an error wrapping ErrPreconditionFailed only this write is dropped;
+// the rest of the barrier batch still commits.
+func (e *Engine) PutObjectWithCheck(ctx context.Context, bucket, key, contentType string, r io.Reader, check func(tx *sql.Tx) error, extraCommit func(tx *sql.Tx, result *PutResult, manifestPath string) error) (*manifest.Manifest, *PutResult, error) {
+	if e.readOnly {
+		return nil, nil, ErrReadOnly
+	}
+	man, result, err := e.putObjectWithCommit(ctx, bucket, key, contentType, nil, r, check, extraCommit)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return nil, nil, err
+	}
+	if err = e.noteWriteResult(err); err != nil {
+		return nil, nil, err
+	}
+	return man, result, nil
+}
+
+// AppendObject stores r as a new version of bucket/key whose manifest lists
+// the chunks of base followed by the appended ones; base data is not read or
+// rewritten. The ETag chains baseETag with the MD5 of the appended bytes (see
+// AppendETag). check and extraCommit behave as in PutObjectWithCheck; check
+// should verify that base is still the current version.
+func (e *Engine) AppendObject(ctx context.Context, bucket, key, contentType string, base *manifest.Manifest, baseETag string, r io.Reader, check func(tx *sql.Tx) error, extraCommit func(tx *sql.Tx, result *PutResult, manifestPath string) error) (*manifest.Manifest, *PutResult, error) {
+	if e.readOnly {
+		return nil, nil, ErrReadOnly
+	}
+	if bucket == "" || key == "" || base == nil {
+		return nil, nil, errors.New("engine: bucket, key and base manifest required")
+	}
+	kept := &keptChunks{head: base.Chunks, etag: func(sum []byte) string { return AppendETag(baseETag, sum) }}
+	man, result, err := e.putObjectWithCommit(ctx, bucket, key, contentType, kept, r, check, extraCommit)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return nil, nil, err
+	}
+	if err = e.noteWriteResult(err); err != nil {
+		return nil, nil, err
+	}
+	return man, result, nil
+}
+
+// OverwriteObject stores a new version of bucket/key that is base with the
+// length bytes at offset replaced by r, which must supply exactly length
+// bytes. The range must lie within base. Chunks of base outside the range are
+// reused; a hole the range cuts is shortened, while a data chunk it cuts is
+// read back and rewritten together with the new bytes. The ETag chains
+// baseETag with the MD5 of the rewritten bytes (see AppendETag). check and
+// extraCommit behave as in PutObjectWithCheck; check should verify that base
+// is still the current version.
+func (e *Engine) OverwriteObject(ctx context.Context, bucket, key, contentType string, base *manifest.Manifest, baseETag string, offset, length int64, r io.Reader, check func(tx *sql.Tx) error, extraCommit func(tx *sql.Tx, result *PutResult, manifestPath string) error) (*manifest.Manifest, *PutResult, error) {
+	if e.readOnly {
+		return nil, nil, ErrReadOnly
+	}
+	if bucket == "" || key == "" || base == nil {
+		return nil, nil, errors.New("engine: bucket, key and base manifest required")
+	}
+	if offset < 0 || length <= 0 || offset > base.Size-length {
+		return nil, nil, errors.New("engine: overwrite range out of bounds")
+	}
+	end := offset + length
+	kept := &keptChunks{etag: func(sum []byte) string { return AppendETag(baseETag, sum) }}
+	// [readStart, offset) and [end, readEnd) are base bytes of cut data
+	// chunks that are written again around r.
+	readStart, readEnd := offset, end
+	var pos int64
+	for _, ch := range base.Chunks {
+		chStart, chEnd := pos, pos+int64(ch.Len)
+		pos = chEnd
+		switch {
+		case chEnd <= offset:
+			kept.head = append(kept.head, ch)
+		case chStart >= end:
+			kept.tail = append(kept.tail, ch)
+		default:
+			if chStart < offset {
+				if ch.IsHole() {
+					kept.head = append(kept.head, manifest.ChunkRef{Len: uint32(offset - chStart)})
+				} else {
+					readStart = chStart
+				}
+			}
+			if chEnd > end {
+				if ch.IsHole() {
+					kept.tail = append(kept.tail, manifest.ChunkRef{Len: uint32(chEnd - end)})
+				} else {
+					readEnd = chEnd
+				}
+			}
+		}
+	}
+	e.pins.resolve.RLock()
+	pinned := e.pins.pin(base)
+	e.pins.resolve.RUnlock()
+	defer e.pins.unpin(pinned)
+	body := &exactReader{r: r, remaining: length}
+	readers := make([]io.Reader, 0, 3)
+	if readStart < offset {
+		head, err := newRangeReader(e.layout, base, readStart, offset-readStart)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = head.Close() }()
+		readers = append(readers, head)
+	}
+	readers = append(readers, body)
+	if readEnd > end {
+		tail, err := newRangeReader(e.layout, base, end, readEnd-end)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = tail.Close() }()
+		readers = append(readers, tail)
+	}
+	man, result, err := e.putObjectWithCommit(ctx, bucket, key, contentType, kept, io.MultiReader(readers...), check, extraCommit)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return nil, nil, err
+	}
+	if err = e.noteWriteResult(err); err != nil {
+		return nil, nil, err
+	}
+	return man, result, nil
+}
+
+// exactReader reads r, failing if it has fewer or more than remaining bytes.
+type exactReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (r *exactReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		var extra [1]byte
+		if n, _ := io.ReadFull(r.r, extra[:]); n > 0 {
+			return 0, errors.New("engine: overwrite data longer than its range")
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+	if errors.Is(err, io.EOF) {
+		if r.remaining > 0 {
+			return n, errors.New("engine: overwrite data shorter than its range")
+		}
+		err = nil
+	}
+	return n, err
+}
+
+// keptChunks are the chunks a new version keeps from an earlier one: head
+// before the written data and tail after it. etag derives the ETag of the
+// version from the MD5 of the written bytes.
+type keptChunks struct {
+	head []manifest.ChunkRef
+	tail []manifest.ChunkRef
+	etag func(sum []byte) string
+}
+
+// AppendETag returns the ETag of an object after appending bytes with MD5
+// sum to an object with ETag baseETag: the MD5 of the base digest followed by
+// sum, suffixed with the number of writes like a multipart ETag. It is not an
+// MD5 of the content.
+func AppendETag(baseETag string, sum []byte) string {
+	digest, count, _ := strings.Cut(baseETag, "-")
+	writes, err := strconv.Atoi(count)
+	if err != nil || writes < 1 {
+		writes = 1
+	}
+	h := md5.New()
+	if raw, err := hex.DecodeString(digest); err == nil {
+		h.Write(raw)
+	}
+	h.Write(sum)
+	return hex.EncodeToString(h.Sum(nil)) + "-" + strconv.Itoa(writes+1)
+}
+
+func (e *Engine) putObjectWithCommit(ctx context.Context, bucket, key, contentType string, kept *keptChunks, r io.Reader, check func(tx *sql.Tx) error, extraCommit func(tx *sql.Tx, result *PutResult, manifestPath string) error) (*manifest.Manifest, *PutResult, error) {
 	if err := e.ensureDirs(); err != nil {
 		return nil, nil, err
 	}
-	versionID, err := newID()
+	versionID, err := e.nextVersionID(bucket, key)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -147,6 +447,17 @@ func (e *Engine) PutObjectWithCommit(ctx context.Context, bucket, key, contentTy
 		VersionID: versionID,
 	}
 	var size int64
+	keep := func(chunks []manifest.ChunkRef) {
+		for _, ch := range chunks {
+			ch.Index = len(man.Chunks)
+			man.Chunks = append(man.Chunks, ch)
+			size += int64(ch.Len)
+		}
+	}
+	if kept != nil {
+		man.Chunks = make([]manifest.ChunkRef, 0, len(kept.head)+len(kept.tail))
+		keep(kept.head)
+	}
 	hasher := md5.New()
 	splitErr := e.splitter.Split(io.TeeReader(r, hasher), func(ch chunk.Chunk) error {
 		select {
@@ -159,8 +470,9 @@ func (e *Engine) PutObjectWithCommit(ctx context.Context, bucket, key, contentTy
 			return err
 		}
 		e.barrier.addBytes(int64(len(ch.Data)))
+		e.writes.logical.Add(int64(len(ch.Data)))
 		man.Chunks = append(man.Chunks, manifest.ChunkRef{
-			Index:     ch.Index,
+			Index:     len(man.Chunks),
 			Hash:      ch.Hash,
 			SegmentID: segmentID,
 			Offset:    offset,
@@ -172,6 +484,9 @@ func (e *Engine) PutObjectWithCommit(ctx context.Context, bucket, key, contentTy
 	if splitErr != nil {
 		return nil, nil, splitErr
 	}
+	if kept != nil {
+		keep(kept.tail)
+	}
 	man.Size = size
 
 	result := &PutResult{
@@ -179,9 +494,25 @@ func (e *Engine) PutObjectWithCommit(ctx context.Context, bucket, key, contentTy
 		ETag:      hex.EncodeToString(hasher.Sum(nil)),
 		Size:      size,
 	}
+	if kept != nil {
+		result.ETag = kept.etag(hasher.Sum(nil))
+	}
 	manifestPath := e.layout.ManifestPath(formatManifestName(bucket, key, versionID))
+	// rejected is set by the flush goroutine and read after barrier.wait returns.
+	var rejected error
 	commit := func(tx *sql.Tx) error {
-		if err := writeManifestFile(manifestPath, e.manifestCodec, man); err != nil {
+		// Reset in case the meta store retries the transaction.
+		rejected = nil
+		if check != nil {
+			if err := check(tx); err != nil {
+				if errors.Is(err, ErrPreconditionFailed) {
+					rejected = err
+					return nil
+				}
+				return err
+			}
+		}
+		if err := e.writeManifest(manifestPath, man, true); err != nil {
 			return err
 		}
 		if e.metaStore != nil {
@@ -205,15 +536,27 @@ func (e *Engine) PutObjectWithCommit(ctx context.Context, bucket, key, contentTy
 		}
 		return nil
 	}
-	if err := e.barrier.register(commit); err != nil {
-		return nil, nil, err
+	wait := func() error {
+		if err := e.barrier.register(commit); err != nil {
+			return err
+		}
+		if e.syncMode == SyncPerWrite {
+			if err := e.segments.sync(); err != nil {
+				return err
+			}
+		}
+		return e.barrier.wait(ctx)
 	}
-	if err := e.segments.sync(); err != nil {
-		return nil, nil, err
+	var keptRefs []manifest.ChunkRef
+	if kept != nil {
+		keptRefs = append(append(keptRefs, kept.head...), kept.tail...)
 	}
-	if err := e.barrier.wait(ctx); err != nil {
+	if err := e.referenceSegments(keptRefs, wait); err != nil {
 		return nil, nil, err
 	}
+	if rejected != nil {
+		return nil, nil, rejected
+	}
 	result.CommittedAt = e.clock.Now().UTC()
 	return man, result, nil
 }
@@ -221,13 +564,88 @@ func (e *Engine) PutObjectWithCommit(ctx context.Context, bucket, key, contentTy
 // PutManifestWithCommit stores a manifest and runs an optional meta commit in the barrier transaction.
 // This is used for virtual manifests that reference existing chunks without rewriting data.
 func (e *Engine) PutManifestWithCommit(ctx context.Context, bucket, key, contentType string, size int64, etag string, chunks []manifest.ChunkRef, extraCommit func(tx *sql.Tx, result *PutResult, manifestPath string) error) (*manifest.Manifest, *PutResult, error) {
+	return e.PutManifestWithCheck(ctx, bucket, key, contentType, size, etag, chunks, nil, extraCommit)
+}
+
+// PutManifestWithCheck is PutManifestWithCommit with a check that runs first in
+// the commit transaction; see PutObjectWithCheck.
+func (e *Engine) PutManifestWithCheck(ctx context.Context, bucket, key, contentType string, size int64, etag string, chunks []manifest.ChunkRef, check func(tx *sql.Tx) error, extraCommit func(tx *sql.Tx, result *PutResult, manifestPath string) error) (*manifest.Manifest, *PutResult, error) {
+	if e.readOnly {
+		return nil, nil, ErrReadOnly
+	}
+	man, result, err := e.putManifestWithCommit(ctx, bucket, key, contentType, size, etag, chunks, check, extraCommit)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return nil, nil, err
+	}
+	if err = e.noteWriteResult(err); err != nil {
+		return nil, nil, err
+	}
+	return man, result, nil
+}
+
+// MaxAllocateSize caps AllocateObject; its manifest holds one hole chunk per
+// chunk.DefaultSize bytes.
+const MaxAllocateSize int64 = 1 << 40
+
+// zeroChunkMD5 is the MD5 of a hole chunk of chunk.DefaultSize bytes.
+var zeroChunkMD5 = sync.OnceValue(func() []byte {
+	sum := md5.Sum(make([]byte, chunk.DefaultSize))
+	return sum[:]
+})
+
+// AllocateObject stores a sparse object of size zero bytes as a new version
+// of bucket/key. Its manifest lists hole chunks of at most chunk.DefaultSize
+// bytes, so no data is written; reads return zeros, appends (AppendObject)
+// keep the holes and OverwriteObject fills them. size is capped by
+// MaxAllocateSize. The zeros are not hashed: the ETag is the MD5 of the chunk
+// MD5s suffixed with the chunk count, like a multipart ETag with one part per
+// chunk (a bare MD5 for an empty object). check and extraCommit behave as in
+// PutObjectWithCheck.
+func (e *Engine) AllocateObject(ctx context.Context, bucket, key, contentType string, size int64, check func(tx *sql.Tx) error, extraCommit func(tx *sql.Tx, result *PutResult, manifestPath string) error) (*manifest.Manifest, *PutResult, error) {
+	if size < 0 {
+		return nil, nil, errors.New("engine: negative allocation size")
+	}
+	if size > MaxAllocateSize {
+		return nil, nil, fmt.Errorf("engine: allocation of %d bytes exceeds %d", size, MaxAllocateSize)
+	}
+	chunks := make([]manifest.ChunkRef, 0, (size+chunk.DefaultSize-1)/chunk.DefaultSize)
+	hasher := md5.New()
+	for remaining := size; remaining > 0; {
+		n := min(remaining, int64(chunk.DefaultSize))
+		if n == chunk.DefaultSize {
+			hasher.Write(zeroChunkMD5())
+		} else {
+			sum := md5.Sum(make([]byte, n))
+			hasher.Write(sum[:])
+		}
+		chunks = append(chunks, manifest.ChunkRef{Index: len(chunks), Len: uint32(n)})
+		remaining -= n
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	etag := hex.EncodeToString(hasher.Sum(nil))
+	if len(chunks) > 0 {
+		etag += "-" + strconv.Itoa(len(chunks))
+	}
+	return e.PutManifestWithCheck(ctx, bucket, key, contentType, size, etag, chunks, check, extraCommit)
+}
+
+func (e *Engine) putManifestWithCommit(ctx context.Context, bucket, key, contentType string, size int64, etag string, chunks []manifest.ChunkRef, check func(tx *sql.Tx) error, extraCommit func(tx *sql.Tx, result *PutResult, manifestPath string) error) (*manifest.Manifest, *PutResult, error) {
 	if err := e.ensureDirs(); err != nil {
 		return nil, nil, err
 	}
 	if bucket == "" || key == "" {
 		return nil, nil, errors.New("engine: bucket and key required")
 	}
-	versionID, err := newID()
+	var chunkBytes int64
+	for _, ch := range chunks {
+		chunkBytes += int64(ch.Len)
+	}
+	if chunkBytes != size {
+		return nil, nil, fmt.Errorf("engine: manifest chunks total %d bytes, size is %d", chunkBytes, size)
+	}
+	versionID, err := e.nextVersionID(bucket, key)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -244,8 +662,20 @@ func (e *Engine) PutManifestWithCommit(ctx context.Context, bucket, key, content
 		Size:      size,
 	}
 	manifestPath := e.layout.ManifestPath(formatManifestName(bucket, key, versionID))
+	var rejected error
 	commit := func(tx *sql.Tx) error {
-		if err := writeManifestFile(manifestPath, e.manifestCodec, man); err != nil {
+		// Reset in case the meta store retries the transaction.
+		rejected = nil
+		if check != nil {
+			if err := check(tx); err != nil {
+				if errors.Is(err, ErrPreconditionFailed) {
+					rejected = err
+					return nil
+				}
+				return err
+			}
+		}
+		if err := e.writeManifest(manifestPath, man, true); err != nil {
 			return err
 		}
 		if e.metaStore != nil {
@@ -263,11 +693,17 @@ func (e *Engine) PutManifestWithCommit(ctx context.Context, bucket, key, content
 		}
 		return nil
 	}
-	if err := e.barrier.register(commit); err != nil {
+	err = e.referenceSegments(chunks, func() error {
+		if err := e.barrier.register(commit); err != nil {
+			return err
+		}
+		return e.barrier.wait(ctx)
+	})
+	if err != nil {
 		return nil, nil, err
 	}
-	if err := e.barrier.wait(ctx); err != nil {
-		return nil, nil, err
+	if rejected != nil {
+		return nil, nil, rejected
 	}
 	result.CommittedAt = e.clock.Now().UTC()
 	return man, result, nil
@@ -278,39 +714,75 @@ func (e *Engine) Get(ctx context.Context, versionID string) (io.ReadCloser, *man
 	if err := e.ensureDirs(); err != nil {
 		return nil, nil, err
 	}
-	file, man, err := e.openManifestByVersion(ctx, versionID)
+	man, pinned, err := e.openPinnedManifest(ctx, versionID)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer func() { _ = file.Close() }()
+	if e.readParallel > 1 {
+		return e.pinnedReader(newPrefetchReader(ctx, e.layout, manifestPieces(man), e.readParallel, e.readAhead), pinned), man, nil
+	}
 	reader := newManifestReader(e.layout, man)
 	if ctx != nil {
 		reader.ctx = ctx
 	}
-	return reader, man, nil
+	return e.pinnedReader(reader, pinned), man, nil
 }
 
-// GetRange retrieves a byte range for a version id.
-func (e *Engine) GetRange(ctx context.Context, versionID string, start, length int64) (io.ReadCloser, *manifest.Manifest, error) {
+// GetRange retrieves a byte range for a version id. A range running past the
+// end of the object is clamped; the returned start and length describe the
+// bytes the reader actually serves.
+func (e *Engine) GetRange(ctx context.Context, versionID string, start, length int64) (io.ReadCloser, *manifest.Manifest, int64, int64, error) {
 	if err := e.ensureDirs(); err != nil {
-		return nil, nil, err
+		return nil, nil, 0, 0, err
 	}
 	if length <= 0 {
-		return nil, nil, errors.New("engine: invalid range length")
+		return nil, nil, 0, 0, errors.New("engine: invalid range length")
 	}
-	file, man, err := e.openManifestByVersion(ctx, versionID)
+	man, pinned, err := e.openPinnedManifest(ctx, versionID)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, 0, err
+	}
+	if start < 0 || start >= man.Size {
+		e.pins.unpin(pinned)
+		return nil, nil, 0, 0, errors.New("engine: range out of bounds")
+	}
+	if start+length > man.Size {
+		length = man.Size - start
+	}
+	if e.readParallel > 1 {
+		pieces, err := rangePieces(man, start, length)
+		if err != nil {
+			e.pins.unpin(pinned)
+			return nil, nil, 0, 0, err
+		}
+		return e.pinnedReader(newPrefetchReader(ctx, e.layout, pieces, e.readParallel, e.readAhead), pinned), man, start, length, nil
 	}
-	defer func() { _ = file.Close() }()
 	reader, err := newRangeReader(e.layout, man, start, length)
 	if err != nil {
-		return nil, nil, err
+		e.pins.unpin(pinned)
+		return nil, nil, 0, 0, err
 	}
 	if ctx != nil {
 		reader.ctx = ctx
 	}
-	return reader, man, nil
+	return e.pinnedReader(reader, pinned), man, start, length, nil
+}
+
+// openPinnedManifest resolves a manifest and pins its segments until the
+// returned ids are released.
+func (e *Engine) openPinnedManifest(ctx context.Context, versionID string) (*manifest.Manifest, []string, error) {
+	e.pins.resolve.RLock()
+	defer e.pins.resolve.RUnlock()
+	file, man, err := e.openManifestByVersion(ctx, versionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	_ = file.Close()
+	return man, e.pins.pin(man), nil
+}
+
+func (e *Engine) pinnedReader(reader io.ReadCloser, ids []string) io.ReadCloser {
+	return &pinnedReader{ReadCloser: reader, pins: e.pins, ids: ids}
 }
 
 func (e *Engine) openManifestByVersion(ctx context.Context, versionID string) (*os.File, *manifest.Manifest, error) {
@@ -443,8 +915,9 @@ func (e *Engine) ReadSegmentRange(segmentID string, offset, length int64) ([]byt
 	if offset < 0 || length <= 0 {
 		return nil, errors.New("engine: invalid segment range")
 	}
-	path := e.layout.SegmentPath(segmentID)
-	file, err := os.Open(path)
+	e.pins.resolve.RLock()
+	defer e.pins.resolve.RUnlock()
+	file, err := e.layout.OpenSegment(segmentID)
 	if err != nil {
 		return nil, err
 	}
@@ -467,8 +940,10 @@ func (e *Engine) MissingChunks(man *manifest.Manifest) ([]MissingChunk, error) {
 	}
 	missing := make([]MissingChunk, 0)
 	for _, ch := range man.Chunks {
-		path := e.layout.SegmentPath(ch.SegmentID)
-		info, err := os.Stat(path)
+		if ch.IsHole() {
+			continue
+		}
+		info, err := e.layout.StatSegment(ch.SegmentID)
 		if err != nil {
 			if os.IsNotExist(err) {
 				missing = append(missing, MissingChunk{
@@ -488,7 +963,7 @@ func (e *Engine) MissingChunks(man *manifest.Manifest) ([]MissingChunk, error) {
 			})
 			continue
 		}
-		file, err := os.Open(path)
+		file, err := e.layout.OpenSegment(ch.SegmentID)
 		if err != nil {
 			return nil, err
 		}
@@ -511,6 +986,9 @@ func (e *Engine) MissingChunks(man *manifest.Manifest) ([]MissingChunk, error) {
 
 // StoreManifestBytes decodes and stores a manifest blob on disk and in metadata.
 func (e *Engine) StoreManifestBytes(ctx context.Context, data []byte) (*manifest.Manifest, error) {
+	if e.readOnly {
+		return nil, ErrReadOnly
+	}
 	if len(data) == 0 {
 		return nil, errors.New("engine: manifest bytes required")
 	}
@@ -522,19 +1000,27 @@ func (e *Engine) StoreManifestBytes(ctx context.Context, data []byte) (*manifest
 		return nil, err
 	}
 	manifestPath := e.layout.ManifestPath(formatManifestName(man.Bucket, man.Key, man.VersionID))
-	if err := writeManifestFile(manifestPath, e.manifestCodec, man); err != nil {
-		return nil, err
-	}
-	if e.metaStore != nil {
-		if err := e.metaStore.RecordManifest(ctx, man.VersionID, manifestPath); err != nil {
-			return nil, err
+	err = e.referenceSegments(man.Chunks, func() error {
+		if err := e.writeManifest(manifestPath, man, false); err != nil {
+			return e.noteWriteResult(err)
+		}
+		e.diskFull.Store(false)
+		if e.metaStore != nil {
+			return e.metaStore.RecordManifest(ctx, man.VersionID, manifestPath)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return man, nil
 }
 
 // WriteSegmentRange writes raw bytes into a segment file at the given offset.
 func (e *Engine) WriteSegmentRange(ctx context.Context, segmentID string, offset int64, data []byte) error {
+	if e.readOnly {
+		return ErrReadOnly
+	}
 	if segmentID == "" {
 		return fmt.Errorf("engine: segment id required")
 	}
@@ -551,11 +1037,15 @@ func (e *Engine) WriteSegmentRange(ctx context.Context, segmentID string, offset
 	}
 	defer func() { _ = file.Close() }()
 	if _, err := file.WriteAt(data, offset); err != nil {
-		return err
+		return e.noteWriteResult(err)
 	}
+	e.writes.logical.Add(int64(len(data)))
+	e.writes.segment.Add(int64(len(data)))
 	if err := file.Sync(); err != nil {
-		return err
+		return e.noteWriteResult(err)
 	}
+	e.diskFull.Store(false)
+	e.writes.segmentSyncs.Add(1)
 	info, err := file.Stat()
 	if err != nil {
 		return err
@@ -569,16 +1059,34 @@ func (e *Engine) WriteSegmentRange(ctx context.Context, segmentID string, offset
 }
 
 func (e *Engine) ensureDirs() error {
+	if e.readOnly {
+		return nil
+	}
 	if err := os.MkdirAll(e.layout.SegmentsDir, 0o755); err != nil {
 		return err
 	}
 	if err := os.MkdirAll(e.layout.ManifestsDir, 0o755); err != nil {
 		return err
 	}
+	if e.layout.StagingDir != "" {
+		if err := os.MkdirAll(e.layout.StagingDir, 0o755); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (e *Engine) flushMeta(commits []func(tx *sql.Tx) error) error {
+	if e.syncMode == SyncPerBarrier {
+		// Drop leftovers of a batch whose transaction failed.
+		e.pendingManifests = nil
+		// One fsync covers the chunks of every write in the batch; sealed
+		// segments were synced when they rotated.
+		if err := e.segments.sync(); err != nil {
+			return err
+		}
+		commits = append(commits, func(*sql.Tx) error { return e.syncPendingManifests() })
+	}
 	if e.metaStore == nil {
 		for _, commit := range commits {
 			if err := commit(nil); err != nil {
@@ -590,19 +1098,48 @@ func (e *Engine) flushMeta(commits []func(tx *sql.Tx) error) error {
 	return e.metaStore.FlushWith(commits)
 }
 
-func writeManifestFile(path string, codec manifest.Codec, man *manifest.Manifest) error {
+// writeManifest writes man to path. inBarrier marks writes from a barrier
+// commit, whose fsync SyncPerBarrier defers to the end of the batch.
+func (e *Engine) writeManifest(path string, man *manifest.Manifest, inBarrier bool) (err error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
 	}
+	var buf bytes.Buffer
+	if err := e.manifestCodec.Encode(&buf, man); err != nil {
+		return err
+	}
 	file, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = file.Close() }()
-	if err := codec.Encode(file, man); err != nil {
+	defer func() {
+		_ = file.Close()
+		if err != nil {
+			// Never leave a truncated manifest behind (e.g. on ENOSPC).
+			_ = os.Remove(path)
+		}
+	}()
+	if _, err := file.Write(buf.Bytes()); err != nil {
 		return err
 	}
-	return file.Sync()
+	e.writes.manifest.Add(int64(buf.Len()))
+	if inBarrier && e.syncMode == SyncPerBarrier {
+		e.pendingManifests = append(e.pendingManifests, path)
+		return nil
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	e.writes.manifestSyncs.Add(1)
+	return nil
+}
+
+func (e *Engine) nextVersionID(bucket, key string) (string, error) {
+	id := e.versionIDs.Next(bucket, key)
+	if id == "" {
+		return "", errors.New("engine: version id generator returned empty id")
+	}
+	return id, nil
 }
 
 func newID() (string, error) {
@@ -619,5 +1156,13 @@ func formatManifestName(bucket, key, versionID string) string {
 	}
 	b := base64.RawURLEncoding.EncodeToString([]byte(bucket))
 	k := base64.RawURLEncoding.EncodeToString([]byte(key))
-	return b + "__" + k + "__" + versionID
+	name := b + "__" + k + "__" + versionID
+	if len(name) > maxManifestNameBytes {
+		// Long keys exceed filesystem name limits; the manifest still records bucket/key.
+		return versionID
+	}
+	return name
 }
+
+// maxManifestNameBytes is the common filesystem limit on a single path element.
+const maxManifestNameBytes = 255
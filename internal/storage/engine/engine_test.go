@@ -3,11 +3,15 @@ package engine
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kk-code-lab/seglake/internal/meta"
 	"github.com/kk-code-lab/seglake/internal/storage/fs"
@@ -96,6 +100,41 @@ func TestEnginePutObjectRecordsMetadata(t *testing.T) {
 	}
 }
 
+func TestEngineVersionIDGenerator(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open meta: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	engine, err := New(Options{
+		Layout:     fs.NewLayout(filepath.Join(dir, "data")),
+		MetaStore:  store,
+		VersionIDs: &meta.SequentialVersionIDs{Prefix: "v-"},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+	for _, want := range []string{"v-00000001", "v-00000002"} {
+		_, result, err := engine.PutObject(ctx, "bucket1", "key1", "", bytes.NewReader([]byte(want)))
+		if err != nil {
+			t.Fatalf("PutObject: %v", err)
+		}
+		if result.VersionID != want {
+			t.Fatalf("version id=%s want %s", result.VersionID, want)
+		}
+	}
+	marker, err := store.CreateDeleteMarker(ctx, "bucket1", "key1")
+	if err != nil {
+		t.Fatalf("CreateDeleteMarker: %v", err)
+	}
+	if marker != "v-00000003" {
+		t.Fatalf("delete marker id=%s", marker)
+	}
+}
+
 func TestEngineGetRange(t *testing.T) {
 	dir := t.TempDir()
 	engine, err := New(Options{
@@ -111,7 +150,7 @@ func TestEngineGetRange(t *testing.T) {
 		t.Fatalf("Put: %v", err)
 	}
 
-	reader, _, err := engine.GetRange(context.Background(), result.VersionID, 3, 7)
+	reader, _, _, _, err := engine.GetRange(context.Background(), result.VersionID, 3, 7)
 	if err != nil {
 		t.Fatalf("GetRange: %v", err)
 	}
@@ -125,6 +164,41 @@ func TestEngineGetRange(t *testing.T) {
 	}
 }
 
+func TestEngineGetRangeClampsPastEOF(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := New(Options{
+		Layout: fs.NewLayout(filepath.Join(dir, "data")),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	input := bytes.Repeat([]byte("abcd"), 8)
+	_, result, err := engine.Put(context.Background(), bytes.NewReader(input))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reader, _, start, length, err := engine.GetRange(context.Background(), result.VersionID, 30, 100)
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+	if start != 30 || length != 2 {
+		t.Fatalf("expected clamped range 30+2, got %d+%d", start, length)
+	}
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if int64(len(got)) != length || string(got) != string(input[30:]) {
+		t.Fatalf("range mismatch: %q", string(got))
+	}
+	if _, _, _, _, err := engine.GetRange(context.Background(), result.VersionID, 32, 1); err == nil {
+		t.Fatalf("expected error for range starting at EOF")
+	}
+}
+
 func TestEngineGetManifestFallbackToWalk(t *testing.T) {
 	dir := t.TempDir()
 	store, err := meta.Open(filepath.Join(dir, "meta.db"))
@@ -151,8 +225,8 @@ func TestEngineGetManifestFallbackToWalk(t *testing.T) {
 	if err := os.MkdirAll(filepath.Dir(manifestPath), 0o755); err != nil {
 		t.Fatalf("MkdirAll: %v", err)
 	}
-	if err := writeManifestFile(manifestPath, &manifest.BinaryCodec{}, man); err != nil {
-		t.Fatalf("writeManifestFile: %v", err)
+	if err := engine.writeManifest(manifestPath, man, false); err != nil {
+		t.Fatalf("writeManifest: %v", err)
 	}
 	stalePath := layout.ManifestPath("missing-" + man.VersionID)
 	if err := store.RecordManifest(context.Background(), man.VersionID, stalePath); err != nil {
@@ -207,6 +281,9 @@ func TestEngineManifestNameEscapesKey(t *testing.T) {
 	if filepath.Dir(path) != layout.ManifestsDir {
 		t.Fatalf("manifest path escaped manifests dir: %q", path)
 	}
+	if long := formatManifestName("bucket", strings.Repeat("k", 1024), "v2"); long != "v2" {
+		t.Fatalf("expected long key to fall back to version id, got %d bytes", len(long))
+	}
 }
 
 func TestMissingChunksDetectsHashMismatch(t *testing.T) {
@@ -250,3 +327,307 @@ func TestMissingChunksDetectsHashMismatch(t *testing.T) {
 		t.Fatalf("expected missing chunk after corruption")
 	}
 }
+
+func TestEngineRemoveSegmentRespectsOpenReaders(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := New(Options{
+		Layout: fs.NewLayout(filepath.Join(dir, "data")),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	man, result, err := engine.Put(context.Background(), strings.NewReader("pinned"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	segID := man.Chunks[0].SegmentID
+	reader, _, err := engine.Get(context.Background(), result.VersionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	removed, err := engine.RemoveSegmentIfUnpinned(segID, func() error { return nil })
+	if err != nil || removed {
+		t.Fatalf("expected pinned segment to be kept, removed=%v err=%v", removed, err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if engine.SegmentPinned(segID) {
+		t.Fatalf("expected segment unpinned after close")
+	}
+	removed, err = engine.RemoveSegmentIfUnpinned(segID, func() error { return nil })
+	if err != nil || !removed {
+		t.Fatalf("expected unpinned segment removal, removed=%v err=%v", removed, err)
+	}
+}
+
+func TestEngineRetireWaitsForCommitsWithoutBlockingReaders(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := New(Options{
+		Layout: fs.NewLayout(filepath.Join(dir, "data")),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	man, result, err := engine.Put(context.Background(), strings.NewReader("retired"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	segID := man.Chunks[0].SegmentID
+
+	committing := make(chan struct{})
+	release := make(chan struct{})
+	commitDone := make(chan error, 1)
+	go func() {
+		commitDone <- engine.referenceSegments(man.Chunks, func() error {
+			close(committing)
+			<-release
+			return nil
+		})
+	}()
+	<-committing
+	retireDone := make(chan struct{})
+	go func() {
+		engine.RetireSegments([]string{segID})
+		close(retireDone)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		engine.pins.mu.Lock()
+		_, retired := engine.pins.retired[segID]
+		engine.pins.mu.Unlock()
+		if retired {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("segment not retired")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	got := make(chan error, 1)
+	go func() {
+		reader, _, err := engine.Get(context.Background(), result.VersionID)
+		if err == nil {
+			err = reader.Close()
+		}
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Get blocked behind a pending retirement")
+	}
+	select {
+	case <-retireDone:
+		t.Fatalf("RetireSegments returned before the in-flight commit")
+	default:
+	}
+
+	close(release)
+	if err := <-commitDone; err != nil {
+		t.Fatalf("referenceSegments: %v", err)
+	}
+	<-retireDone
+	err = engine.referenceSegments(man.Chunks, func() error { return nil })
+	if !errors.Is(err, ErrSegmentRetired) {
+		t.Fatalf("expected ErrSegmentRetired, got %v", err)
+	}
+}
+
+func TestEngineAppendObjectReusesBaseChunks(t *testing.T) {
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("Open meta: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	engine, err := New(Options{
+		Layout:    fs.NewLayout(filepath.Join(dir, "data")),
+		MetaStore: store,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+	base, baseResult, err := engine.PutObject(ctx, "bucket", "log", "text/plain", strings.NewReader("line1\n"))
+	if err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	man, result, err := engine.AppendObject(ctx, "bucket", "log", "text/plain", base, baseResult.ETag, strings.NewReader("line2\n"), nil, nil)
+	if err != nil {
+		t.Fatalf("AppendObject: %v", err)
+	}
+	if result.Size != 12 || man.Size != 12 {
+		t.Fatalf("unexpected size: result=%d manifest=%d", result.Size, man.Size)
+	}
+	if len(man.Chunks) != len(base.Chunks)+1 || man.Chunks[0].SegmentID != base.Chunks[0].SegmentID || man.Chunks[0].Offset != base.Chunks[0].Offset {
+		t.Fatalf("expected base chunks to be reused: %+v", man.Chunks)
+	}
+	for i, ch := range man.Chunks {
+		if ch.Index != i {
+			t.Fatalf("chunk %d has index %d", i, ch.Index)
+		}
+	}
+	if !strings.HasSuffix(result.ETag, "-2") || result.ETag != AppendETag(baseResult.ETag, md5Sum("line2\n")) {
+		t.Fatalf("unexpected ETag %q", result.ETag)
+	}
+	objMeta, err := store.GetObjectMeta(ctx, "bucket", "log")
+	if err != nil {
+		t.Fatalf("GetObjectMeta: %v", err)
+	}
+	if objMeta.VersionID != result.VersionID || objMeta.Size != 12 || objMeta.ETag != result.ETag {
+		t.Fatalf("unexpected current version: %+v", objMeta)
+	}
+	reader, _, err := engine.Get(ctx, result.VersionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(got) != "line1\nline2\n" {
+		t.Fatalf("unexpected content %q", got)
+	}
+	if next := AppendETag(result.ETag, md5Sum("line3\n")); !strings.HasSuffix(next, "-3") {
+		t.Fatalf("expected write count to grow, got %q", next)
+	}
+}
+
+func md5Sum(s string) []byte {
+	sum := md5.Sum([]byte(s))
+	return sum[:]
+}
+
+func TestAllocateObjectRejectsOversize(t *testing.T) {
+	engine, err := New(Options{Layout: fs.NewLayout(filepath.Join(t.TempDir(), "data"))})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, _, err := engine.AllocateObject(context.Background(), "bucket", "huge", "", 1<<62, nil, nil); err == nil {
+		t.Fatalf("expected oversize allocation to fail")
+	}
+}
+
+func TestOverwriteObjectFillsHolesAndData(t *testing.T) {
+	const size = 4<<20 + 10
+	dir := t.TempDir()
+	engine, err := New(Options{Layout: fs.NewLayout(filepath.Join(dir, "data"))})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+	man, result, err := engine.AllocateObject(ctx, "bucket", "sparse", "", size, nil, nil)
+	if err != nil {
+		t.Fatalf("AllocateObject: %v", err)
+	}
+	want := make([]byte, size)
+	for _, w := range []struct {
+		offset int64
+		data   string
+	}{
+		{offset: 100, data: "inside a hole"},
+		{offset: 4<<20 - 2, data: "across holes"},
+		{offset: 105, data: "inside data"},
+	} {
+		man, result, err = engine.OverwriteObject(ctx, "bucket", "sparse", "", man, result.ETag, w.offset, int64(len(w.data)), strings.NewReader(w.data), nil, nil)
+		if err != nil {
+			t.Fatalf("OverwriteObject %q: %v", w.data, err)
+		}
+		copy(want[w.offset:], w.data)
+		reader, _, err := engine.Get(ctx, result.VersionID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		got, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("content mismatch after %q: %v", w.data, err)
+		}
+	}
+	var data int64
+	for _, ch := range man.Chunks {
+		if !ch.IsHole() {
+			data += int64(ch.Len)
+		}
+	}
+	if data >= size/2 {
+		t.Fatalf("overwrite rewrote holes: %d data bytes", data)
+	}
+	if result.Size != size || !strings.HasSuffix(result.ETag, "-5") {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	for _, tc := range []struct {
+		name           string
+		offset, length int64
+		body           string
+	}{
+		{name: "past end", offset: size - 1, length: 2, body: "ab"},
+		{name: "short body", offset: 0, length: 3, body: "ab"},
+		{name: "long body", offset: 0, length: 1, body: "ab"},
+	} {
+		if _, _, err := engine.OverwriteObject(ctx, "bucket", "sparse", "", man, result.ETag, tc.offset, tc.length, strings.NewReader(tc.body), nil, nil); err == nil {
+			t.Fatalf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestAllocateObjectReadsZerosAndAppends(t *testing.T) {
+	const size = 4<<20 + 10
+	for _, parallelism := range []int{1, 4} {
+		dir := t.TempDir()
+		engine, err := New(Options{Layout: fs.NewLayout(filepath.Join(dir, "data")), ReadParallelism: parallelism})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		ctx := context.Background()
+		man, result, err := engine.AllocateObject(ctx, "bucket", "sparse", "", size, nil, nil)
+		if err != nil {
+			t.Fatalf("AllocateObject: %v", err)
+		}
+		zeros := make([]byte, size)
+		full, tail := md5.Sum(zeros[:4<<20]), md5.Sum(zeros[:10])
+		if sum := md5.Sum(append(full[:], tail[:]...)); result.ETag != hex.EncodeToString(sum[:])+"-2" || result.Size != size {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		if len(man.Chunks) != 2 || !man.Chunks[0].IsHole() || !man.Chunks[1].IsHole() {
+			t.Fatalf("expected two hole chunks, got %+v", man.Chunks)
+		}
+		if missing, err := engine.MissingChunks(man); err != nil || len(missing) != 0 {
+			t.Fatalf("MissingChunks: %v %v", missing, err)
+		}
+
+		_, appended, err := engine.AppendObject(ctx, "bucket", "sparse", "", man, result.ETag, strings.NewReader("tail"), nil, nil)
+		if err != nil {
+			t.Fatalf("AppendObject: %v", err)
+		}
+		reader, _, err := engine.Get(ctx, appended.VersionID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		got, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			t.Fatalf("ReadAll: %v", err)
+		}
+		if want := append(zeros, "tail"...); !bytes.Equal(got, want) {
+			t.Fatalf("parallelism %d: content mismatch (len %d)", parallelism, len(got))
+		}
+
+		reader, _, _, _, err = engine.GetRange(ctx, appended.VersionID, size-2, 6)
+		if err != nil {
+			t.Fatalf("GetRange: %v", err)
+		}
+		got, err = io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil || string(got) != "\x00\x00tail" {
+			t.Fatalf("parallelism %d: range %q %v", parallelism, got, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+)
+
+// RangeSet serves several byte ranges of one version from a single manifest
+// resolution: its segments stay pinned and segment files stay open until
+// Close, so a multipart/byteranges response does not re-resolve the manifest
+// and re-open segments for every range.
+type RangeSet struct {
+	ctx    context.Context
+	man    *manifest.Manifest
+	pins   *segmentPins
+	pinned []string
+	files  *segmentFiles
+	once   sync.Once
+}
+
+// OpenRanges resolves versionID once for serving ranges with RangeSet.Range.
+// The caller must Close the set, also when a later range fails.
+func (e *Engine) OpenRanges(ctx context.Context, versionID string) (*RangeSet, error) {
+	if err := e.ensureDirs(); err != nil {
+		return nil, err
+	}
+	man, pinned, err := e.openPinnedManifest(ctx, versionID)
+	if err != nil {
+		return nil, err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &RangeSet{
+		ctx:    ctx,
+		man:    man,
+		pins:   e.pins,
+		pinned: pinned,
+		files:  &segmentFiles{layout: e.layout, files: make(map[string]*os.File)},
+	}, nil
+}
+
+// Manifest returns the manifest the ranges are served from.
+func (s *RangeSet) Manifest() *manifest.Manifest {
+	return s.man
+}
+
+// Range returns a reader for a byte range, clamped like GetRange; the
+// returned start and length describe the bytes it serves. Readers share the
+// set's open files, so read them one at a time and not after Close.
+func (s *RangeSet) Range(start, length int64) (io.Reader, int64, int64, error) {
+	if length <= 0 {
+		return nil, 0, 0, errors.New("engine: invalid range length")
+	}
+	if start < 0 || start >= s.man.Size {
+		return nil, 0, 0, errors.New("engine: range out of bounds")
+	}
+	if start+length > s.man.Size {
+		length = s.man.Size - start
+	}
+	reader, err := newRangeReader(s.files.layout, s.man, start, length)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	reader.ctx = s.ctx
+	reader.shared = s.files
+	return reader, start, length, nil
+}
+
+// Close closes every segment file opened by the set's readers and unpins its
+// segments. It is safe to call more than once.
+func (s *RangeSet) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = s.files.close()
+		s.pins.unpin(s.pinned)
+	})
+	return err
+}
+
+// segmentFiles keeps segment files open by id for readers that revisit them.
+type segmentFiles struct {
+	layout fs.Layout
+	files  map[string]*os.File
+}
+
+func (f *segmentFiles) open(segmentID string) (*os.File, error) {
+	if file, ok := f.files[segmentID]; ok {
+		return file, nil
+	}
+	file, err := f.layout.OpenSegment(segmentID)
+	if err != nil {
+		return nil, err
+	}
+	f.files[segmentID] = file
+	return file, nil
+}
+
+func (f *segmentFiles) close() error {
+	var errs []error
+	for id, file := range f.files {
+		errs = append(errs, file.Close())
+		delete(f.files, id)
+	}
+	return errors.Join(errs...)
+}
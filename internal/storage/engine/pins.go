@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+)
+
+// ErrSegmentRetired is returned by a write that would reference a segment
+// online compaction is removing or has removed. Retrying re-reads the
+// rewritten manifests.
+var ErrSegmentRetired = errors.New("engine: segment retired by compaction")
+
+// segmentPins tracks segments referenced by open readers so online
+// compaction never removes a segment that is still being streamed, and
+// segments retired by compaction so no new manifest references them.
+type segmentPins struct {
+	// resolve is held shared while a reader resolves its manifest and pins
+	// segments, and exclusively while a segment is removed.
+	resolve sync.RWMutex
+	mu      sync.Mutex
+	counts  map[string]int
+	retired map[string]struct{}
+	// refs counts writes committing references to a segment; released is
+	// broadcast when one finishes so RetireSegments can stop waiting.
+	refs     map[string]int
+	released *sync.Cond
+}
+
+func newSegmentPins() *segmentPins {
+	p := &segmentPins{counts: make(map[string]int), retired: make(map[string]struct{}), refs: make(map[string]int)}
+	p.released = sync.NewCond(&p.mu)
+	return p
+}
+
+// chunkSegmentIDs returns the distinct segments chunks reference.
+func chunkSegmentIDs(chunks []manifest.ChunkRef) []string {
+	seen := make(map[string]struct{}, len(chunks))
+	ids := make([]string, 0, len(chunks))
+	for _, ch := range chunks {
+		if ch.IsHole() {
+			continue
+		}
+		if _, ok := seen[ch.SegmentID]; ok {
+			continue
+		}
+		seen[ch.SegmentID] = struct{}{}
+		ids = append(ids, ch.SegmentID)
+	}
+	return ids
+}
+
+func (p *segmentPins) pin(man *manifest.Manifest) []string {
+	if man == nil {
+		return nil
+	}
+	ids := chunkSegmentIDs(man.Chunks)
+	p.mu.Lock()
+	for _, id := range ids {
+		p.counts[id]++
+	}
+	p.mu.Unlock()
+	return ids
+}
+
+func (p *segmentPins) unpin(ids []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		if p.counts[id] <= 1 {
+			delete(p.counts, id)
+			continue
+		}
+		p.counts[id]--
+	}
+}
+
+func (p *segmentPins) pinned(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.counts[id] > 0
+}
+
+// reference records a write committing references to ids, unless one of
+// them is retired; it returns that segment instead.
+func (p *segmentPins) reference(ids []string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := p.retired[id]; ok {
+			return id
+		}
+	}
+	for _, id := range ids {
+		p.refs[id]++
+	}
+	return ""
+}
+
+func (p *segmentPins) dereference(ids []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		if p.refs[id] <= 1 {
+			delete(p.refs, id)
+			continue
+		}
+		p.refs[id]--
+	}
+	p.released.Broadcast()
+}
+
+func (p *segmentPins) referenced(ids []string) bool {
+	for _, id := range ids {
+		if p.refs[id] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type pinnedReader struct {
+	io.ReadCloser
+	pins *segmentPins
+	ids  []string
+	once sync.Once
+}
+
+func (r *pinnedReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() { r.pins.unpin(r.ids) })
+	return err
+}
+
+// SegmentPinned reports whether an open reader references the segment.
+func (e *Engine) SegmentPinned(segmentID string) bool {
+	return e.pins.pinned(segmentID)
+}
+
+// RetireSegments makes writes that would reference segmentIDs fail with
+// ErrSegmentRetired. It waits for writes already committing references to
+// them, so a manifest scan started afterwards sees every remaining reference.
+// Removed segments stay retired; ReleaseSegments undoes it for kept ones.
+func (e *Engine) RetireSegments(segmentIDs []string) {
+	e.pins.mu.Lock()
+	defer e.pins.mu.Unlock()
+	for _, id := range segmentIDs {
+		e.pins.retired[id] = struct{}{}
+	}
+	for e.pins.referenced(segmentIDs) {
+		e.pins.released.Wait()
+	}
+}
+
+// ReleaseSegments lets new writes reference segmentIDs again.
+func (e *Engine) ReleaseSegments(segmentIDs []string) {
+	e.pins.mu.Lock()
+	defer e.pins.mu.Unlock()
+	for _, id := range segmentIDs {
+		delete(e.pins.retired, id)
+	}
+}
+
+// referenceSegments runs commit, which records a manifest listing chunks, so
+// that no segment of chunks can be retired until it returns. It fails with
+// ErrSegmentRetired when one already is. It holds no lock while commit waits
+// on the barrier, so readers never queue behind a pending retirement.
+func (e *Engine) referenceSegments(chunks []manifest.ChunkRef, commit func() error) error {
+	ids := chunkSegmentIDs(chunks)
+	if len(ids) == 0 {
+		return commit()
+	}
+	if id := e.pins.reference(ids); id != "" {
+		return fmt.Errorf("%w: %s", ErrSegmentRetired, id)
+	}
+	defer e.pins.dereference(ids)
+	return commit()
+}
+
+// RemoveSegmentIfUnpinned runs remove only when no reader references the
+// segment; new readers are blocked from pinning while remove runs.
+func (e *Engine) RemoveSegmentIfUnpinned(segmentID string, remove func() error) (bool, error) {
+	e.pins.resolve.Lock()
+	defer e.pins.resolve.Unlock()
+	if e.pins.pinned(segmentID) {
+		return false, nil
+	}
+	if err := remove(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
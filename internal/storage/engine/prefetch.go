@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+)
+
+const defaultReadAheadFactor = 2
+
+type prefetchResult struct {
+	buf []byte
+	err error
+}
+
+// prefetchReader reads upcoming pieces concurrently while the caller streams
+// the current one. Results are delivered strictly in manifest order.
+type prefetchReader struct {
+	layout fs.Layout
+	pieces []rangePiece
+	ctx    context.Context
+	cancel context.CancelFunc
+	order  chan chan prefetchResult
+	wg     sync.WaitGroup
+
+	buf    []byte
+	bufOff int
+	err    error
+
+	filesMu sync.Mutex
+	files   map[string]*os.File
+
+	closeOnce sync.Once
+}
+
+func manifestPieces(man *manifest.Manifest) []rangePiece {
+	pieces := make([]rangePiece, 0, len(man.Chunks))
+	for _, ch := range man.Chunks {
+		pieces = append(pieces, rangePiece{
+			segmentID: ch.SegmentID,
+			offset:    ch.Offset,
+			length:    int64(ch.Len),
+		})
+	}
+	return pieces
+}
+
+// newPrefetchReader starts reading pieces with up to parallelism concurrent
+// reads and at most readAhead pieces buffered ahead of the consumer.
+func newPrefetchReader(ctx context.Context, layout fs.Layout, pieces []rangePiece, parallelism, readAhead int) *prefetchReader {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	if readAhead < parallelism {
+		readAhead = parallelism
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	r := &prefetchReader{
+		layout: layout,
+		pieces: pieces,
+		ctx:    ctx,
+		cancel: cancel,
+		order:  make(chan chan prefetchResult, readAhead),
+		files:  make(map[string]*os.File),
+	}
+	r.wg.Add(1)
+	go r.dispatch(parallelism)
+	return r
+}
+
+func (r *prefetchReader) dispatch(parallelism int) {
+	defer r.wg.Done()
+	defer close(r.order)
+	sem := make(chan struct{}, parallelism)
+	for _, piece := range r.pieces {
+		result := make(chan prefetchResult, 1)
+		select {
+		case r.order <- result:
+		case <-r.ctx.Done():
+			return
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-r.ctx.Done():
+			result <- prefetchResult{err: r.ctx.Err()}
+			return
+		}
+		r.wg.Add(1)
+		go func(piece rangePiece) {
+			defer r.wg.Done()
+			defer func() { <-sem }()
+			buf, err := r.readPiece(piece)
+			result <- prefetchResult{buf: buf, err: err}
+		}(piece)
+	}
+}
+
+func (r *prefetchReader) readPiece(piece rangePiece) ([]byte, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if piece.length == 0 {
+		return nil, errors.New("engine: zero-length chunk")
+	}
+	if piece.segmentID == "" {
+		return make([]byte, piece.length), nil
+	}
+	file, err := r.segmentFile(piece.segmentID)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, piece.length)
+	n, err := file.ReadAt(buf, piece.offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n != int(piece.length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf, nil
+}
+
+func (r *prefetchReader) segmentFile(segmentID string) (*os.File, error) {
+	r.filesMu.Lock()
+	defer r.filesMu.Unlock()
+	if file, ok := r.files[segmentID]; ok {
+		return file, nil
+	}
+	if r.files == nil {
+		return nil, os.ErrClosed
+	}
+	file, err := r.layout.OpenSegment(segmentID)
+	if err != nil {
+		return nil, err
+	}
+	r.files[segmentID] = file
+	return file, nil
+}
+
+func (r *prefetchReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	n := 0
+	for n < len(p) {
+		if r.buf == nil || r.bufOff >= len(r.buf) {
+			if err := r.next(); err != nil {
+				r.err = err
+				if errors.Is(err, io.EOF) && n > 0 {
+					return n, nil
+				}
+				return n, err
+			}
+		}
+		copied := copy(p[n:], r.buf[r.bufOff:])
+		n += copied
+		r.bufOff += copied
+	}
+	return n, nil
+}
+
+func (r *prefetchReader) next() error {
+	var result chan prefetchResult
+	var ok bool
+	select {
+	case result, ok = <-r.order:
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	}
+	if !ok {
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	var res prefetchResult
+	select {
+	case res = <-result:
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	}
+	if res.err != nil {
+		return res.err
+	}
+	r.buf = res.buf
+	r.bufOff = 0
+	return nil
+}
+
+// Close cancels outstanding prefetches and waits for them to exit.
+func (r *prefetchReader) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		r.cancel()
+		r.wg.Wait()
+		r.filesMu.Lock()
+		defer r.filesMu.Unlock()
+		for _, file := range r.files {
+			if cerr := file.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		r.files = nil
+	})
+	return err
+}
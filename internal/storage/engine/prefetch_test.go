@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/storage/chunk"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func newPrefetchTestEngine(t testing.TB, parallelism int) *Engine {
+	t.Helper()
+	eng, err := New(Options{
+		Layout:          fs.NewLayout(filepath.Join(t.TempDir(), "data")),
+		Splitter:        chunk.NewFixedSplitter(4096),
+		ReadParallelism: parallelism,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return eng
+}
+
+func TestEnginePrefetchGetPreservesOrder(t *testing.T) {
+	eng := newPrefetchTestEngine(t, 4)
+	input := make([]byte, 4096*37+123)
+	for i := range input {
+		input[i] = byte(i * 7)
+	}
+	_, result, err := eng.Put(context.Background(), bytes.NewReader(input))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reader, _, err := eng.Get(context.Background(), result.VersionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, input) {
+		t.Fatalf("data mismatch")
+	}
+
+	rangeReader, _, _, _, err := eng.GetRange(context.Background(), result.VersionID, 4000, 9000)
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	got, err = io.ReadAll(rangeReader)
+	_ = rangeReader.Close()
+	if err != nil {
+		t.Fatalf("ReadAll range: %v", err)
+	}
+	if !bytes.Equal(got, input[4000:13000]) {
+		t.Fatalf("range mismatch")
+	}
+}
+
+func TestEnginePrefetchSurfacesReadError(t *testing.T) {
+	eng := newPrefetchTestEngine(t, 4)
+	input := bytes.Repeat([]byte("x"), 4096*8)
+	man, result, err := eng.Put(context.Background(), bytes.NewReader(input))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := os.Remove(eng.layout.SegmentPath(man.Chunks[0].SegmentID)); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	reader, _, err := eng.Get(context.Background(), result.VersionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+	if _, err := io.ReadAll(reader); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestEnginePrefetchCancelDoesNotLeak(t *testing.T) {
+	eng := newPrefetchTestEngine(t, 4)
+	input := bytes.Repeat([]byte("y"), 4096*64)
+	_, result, err := eng.Put(context.Background(), bytes.NewReader(input))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, _, err := eng.Get(ctx, result.VersionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	buf := make([]byte, 100)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	cancel()
+	if _, err := io.ReadAll(reader); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > before {
+		t.Fatalf("goroutines leaked: before=%d after=%d", before, got)
+	}
+}
+
+func BenchmarkEngineGet(b *testing.B) {
+	for _, tc := range []struct {
+		name        string
+		parallelism int
+	}{
+		{name: "serial", parallelism: 1},
+		{name: "prefetch4", parallelism: 4},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			eng, err := New(Options{
+				Layout:          fs.NewLayout(filepath.Join(b.TempDir(), "data")),
+				ReadParallelism: tc.parallelism,
+			})
+			if err != nil {
+				b.Fatalf("New: %v", err)
+			}
+			input := bytes.Repeat([]byte("benchmark"), (32<<20)/9)
+			_, result, err := eng.Put(context.Background(), bytes.NewReader(input))
+			if err != nil {
+				b.Fatalf("Put: %v", err)
+			}
+			b.SetBytes(int64(len(input)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				reader, _, err := eng.Get(context.Background(), result.VersionID)
+				if err != nil {
+					b.Fatalf("Get: %v", err)
+				}
+				if _, err := io.Copy(io.Discard, reader); err != nil {
+					b.Fatalf("Copy: %v", err)
+				}
+				_ = reader.Close()
+			}
+		})
+	}
+}
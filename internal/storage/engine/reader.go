@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 
 	"github.com/kk-code-lab/seglake/internal/storage/fs"
 	"github.com/kk-code-lab/seglake/internal/storage/manifest"
@@ -73,6 +74,12 @@ func (r *manifestReader) loadNextChunk() error {
 	if ref.Len == 0 {
 		return fmt.Errorf("engine: zero-length chunk")
 	}
+	if ref.IsHole() {
+		r.buf = make([]byte, ref.Len)
+		r.bufOff = 0
+		r.index++
+		return nil
+	}
 	if err := r.openSegment(ref.SegmentID); err != nil {
 		return err
 	}
@@ -98,8 +105,7 @@ func (r *manifestReader) openSegment(segmentID string) error {
 		_ = r.segFile.Close()
 		r.segFile = nil
 	}
-	path := r.layout.SegmentPath(segmentID)
-	file, err := os.Open(path)
+	file, err := r.layout.OpenSegment(segmentID)
 	if err != nil {
 		return err
 	}
@@ -120,6 +126,8 @@ func (r *manifestReader) checkContext() error {
 	}
 }
 
+// rangePiece is a slice of one chunk; an empty segmentID is a hole that
+// reads as zeros.
 type rangePiece struct {
 	segmentID string
 	offset    int64
@@ -135,48 +143,61 @@ type rangeReader struct {
 	segID   string
 	segFile *os.File
 	ctx     context.Context
+	// shared, when set, owns the segment files (see RangeSet).
+	shared *segmentFiles
 }
 
 func newRangeReader(layout fs.Layout, man *manifest.Manifest, start, length int64) (*rangeReader, error) {
+	pieces, err := rangePieces(man, start, length)
+	if err != nil {
+		return nil, err
+	}
+	return &rangeReader{
+		layout: layout,
+		pieces: pieces,
+		ctx:    context.Background(),
+	}, nil
+}
+
+// rangePieces maps [start, start+length) onto chunk slices. Chunk lengths vary
+// (the last chunk of every multipart part is short), so the first chunk is
+// found by binary search over cumulative chunk ends rather than by dividing by
+// a chunk size.
+func rangePieces(man *manifest.Manifest, start, length int64) ([]rangePiece, error) {
 	if start < 0 || length <= 0 {
 		return nil, errors.New("engine: invalid range")
 	}
 	if man.Size < start+length {
 		return nil, errors.New("engine: range out of bounds")
 	}
-	pieces := make([]rangePiece, 0)
+	ends := make([]int64, len(man.Chunks))
 	var pos int64
+	for i, ch := range man.Chunks {
+		pos += int64(ch.Len)
+		ends[i] = pos
+	}
 	end := start + length
-	for _, ch := range man.Chunks {
-		chStart := pos
-		chEnd := pos + int64(ch.Len)
-		if chEnd <= start {
-			pos = chEnd
-			continue
-		}
+	if pos < end {
+		return nil, fmt.Errorf("engine: manifest chunks cover %d bytes, range ends at %d", pos, end)
+	}
+	first := sort.Search(len(ends), func(i int) bool { return ends[i] > start })
+	pieces := make([]rangePiece, 0)
+	for i := first; i < len(man.Chunks); i++ {
+		ch := man.Chunks[i]
+		chEnd := ends[i]
+		chStart := chEnd - int64(ch.Len)
 		if chStart >= end {
 			break
 		}
-		readStart := start
-		if chStart > readStart {
-			readStart = chStart
-		}
-		readEnd := end
-		if chEnd < readEnd {
-			readEnd = chEnd
-		}
+		readStart := max(start, chStart)
+		readEnd := min(end, chEnd)
 		pieces = append(pieces, rangePiece{
 			segmentID: ch.SegmentID,
 			offset:    ch.Offset + (readStart - chStart),
 			length:    readEnd - readStart,
 		})
-		pos = chEnd
 	}
-	return &rangeReader{
-		layout: layout,
-		pieces: pieces,
-		ctx:    context.Background(),
-	}, nil
+	return pieces, nil
 }
 
 func (r *rangeReader) Read(p []byte) (int, error) {
@@ -207,7 +228,7 @@ func (r *rangeReader) Read(p []byte) (int, error) {
 }
 
 func (r *rangeReader) Close() error {
-	if r.segFile != nil {
+	if r.segFile != nil && r.shared == nil {
 		return r.segFile.Close()
 	}
 	return nil
@@ -218,6 +239,12 @@ func (r *rangeReader) loadNextPiece() error {
 		return io.EOF
 	}
 	piece := r.pieces[r.index]
+	if piece.segmentID == "" {
+		r.buf = make([]byte, piece.length)
+		r.bufOff = 0
+		r.index++
+		return nil
+	}
 	if err := r.openSegment(piece.segmentID); err != nil {
 		return err
 	}
@@ -239,12 +266,20 @@ func (r *rangeReader) openSegment(segmentID string) error {
 	if r.segFile != nil && r.segID == segmentID {
 		return nil
 	}
+	if r.shared != nil {
+		file, err := r.shared.open(segmentID)
+		if err != nil {
+			return err
+		}
+		r.segID = segmentID
+		r.segFile = file
+		return nil
+	}
 	if r.segFile != nil {
 		_ = r.segFile.Close()
 		r.segFile = nil
 	}
-	path := r.layout.SegmentPath(segmentID)
-	file, err := os.Open(path)
+	file, err := r.layout.OpenSegment(segmentID)
 	if err != nil {
 		return err
 	}
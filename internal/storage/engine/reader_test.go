@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/manifest"
+)
+
+// putAssembled stores each part separately and then a virtual manifest that
+// concatenates their chunks, the way CompleteMultipartUpload does.
+func putAssembled(t *testing.T, eng *Engine, parts [][]byte) string {
+	t.Helper()
+	var (
+		chunks []manifest.ChunkRef
+		size   int64
+	)
+	for _, data := range parts {
+		man, _, err := eng.Put(context.Background(), bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Put part: %v", err)
+		}
+		for _, ch := range man.Chunks {
+			ch.Index = len(chunks)
+			chunks = append(chunks, ch)
+		}
+		size += man.Size
+	}
+	_, result, err := eng.PutManifestWithCommit(context.Background(), "bucket", "key", "", size, "etag-3", chunks, nil)
+	if err != nil {
+		t.Fatalf("PutManifestWithCommit: %v", err)
+	}
+	return result.VersionID
+}
+
+func TestEngineGetRangeAcrossMultipartParts(t *testing.T) {
+	// Part sizes are not multiples of the 4096-byte chunk size, so each part
+	// ends in a short chunk and chunk offsets are not index*4096.
+	parts := [][]byte{
+		bytes.Repeat([]byte("a"), 4096*2+100),
+		bytes.Repeat([]byte("b"), 4096+7),
+		bytes.Repeat([]byte("c"), 4096*3+1),
+	}
+	whole := bytes.Join(parts, nil)
+	p1 := int64(len(parts[0]))
+	p2 := p1 + int64(len(parts[1]))
+	cases := []struct {
+		name          string
+		start, length int64
+	}{
+		{"spans all three parts", p1 - 10, p2 - p1 + 20},
+		{"starts mid part one ends mid part three", 50, int64(len(whole)) - 100},
+		{"starts at part boundary", p1, 200},
+		{"ends at part boundary", p1 - 200, 200},
+		{"straddles boundary by one byte", p2 - 1, 2},
+		{"whole object", 0, int64(len(whole))},
+	}
+	for _, parallelism := range []int{1, 4} {
+		eng := newPrefetchTestEngine(t, parallelism)
+		versionID := putAssembled(t, eng, parts)
+		for _, tc := range cases {
+			reader, _, start, length, err := eng.GetRange(context.Background(), versionID, tc.start, tc.length)
+			if err != nil {
+				t.Fatalf("parallelism=%d %s: GetRange: %v", parallelism, tc.name, err)
+			}
+			got, err := io.ReadAll(reader)
+			_ = reader.Close()
+			if err != nil {
+				t.Fatalf("parallelism=%d %s: ReadAll: %v", parallelism, tc.name, err)
+			}
+			if start != tc.start || length != tc.length {
+				t.Fatalf("parallelism=%d %s: effective range %d+%d", parallelism, tc.name, start, length)
+			}
+			if !bytes.Equal(got, whole[tc.start:tc.start+tc.length]) {
+				t.Fatalf("parallelism=%d %s: data mismatch", parallelism, tc.name)
+			}
+		}
+	}
+}
+
+func TestRangePiecesRejectsShortManifest(t *testing.T) {
+	man := &manifest.Manifest{
+		Size: 100,
+		Chunks: []manifest.ChunkRef{
+			{SegmentID: "seg-1", Offset: 0, Len: 40},
+			{SegmentID: "seg-2", Offset: 0, Len: 40},
+		},
+	}
+	if _, err := rangePieces(man, 30, 70); err == nil {
+		t.Fatalf("expected error for range past the last chunk")
+	}
+	pieces, err := rangePieces(man, 30, 20)
+	if err != nil {
+		t.Fatalf("rangePieces: %v", err)
+	}
+	want := []rangePiece{
+		{segmentID: "seg-1", offset: 30, length: 10},
+		{segmentID: "seg-2", offset: 0, length: 10},
+	}
+	if len(pieces) != len(want) {
+		t.Fatalf("pieces=%+v", pieces)
+	}
+	for i := range want {
+		if pieces[i] != want[i] {
+			t.Fatalf("piece %d: got %+v want %+v", i, pieces[i], want[i])
+		}
+	}
+}
+
+func TestRangeSetSharesSegmentFiles(t *testing.T) {
+	parts := [][]byte{
+		bytes.Repeat([]byte("a"), 4096*2+100),
+		bytes.Repeat([]byte("b"), 4096+7),
+		bytes.Repeat([]byte("c"), 4096*3+1),
+	}
+	whole := bytes.Join(parts, nil)
+	eng := newPrefetchTestEngine(t, 1)
+	versionID := putAssembled(t, eng, parts)
+
+	set, err := eng.OpenRanges(context.Background(), versionID)
+	if err != nil {
+		t.Fatalf("OpenRanges: %v", err)
+	}
+	// Served out of order and overlapping, as a Range header may ask.
+	ranges := [][2]int64{{int64(len(whole)) - 10, 100}, {0, 5000}, {4096*2 + 90, 20}, {100, 10}}
+	for _, rg := range ranges {
+		reader, start, length, err := set.Range(rg[0], rg[1])
+		if err != nil {
+			t.Fatalf("Range %v: %v", rg, err)
+		}
+		got, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("ReadAll %v: %v", rg, err)
+		}
+		if start != rg[0] || length != min(rg[1], int64(len(whole))-rg[0]) {
+			t.Fatalf("Range %v: effective range %d+%d", rg, start, length)
+		}
+		if !bytes.Equal(got, whole[start:start+length]) {
+			t.Fatalf("Range %v: data mismatch", rg)
+		}
+	}
+	if len(set.files.files) != len(set.pinned) {
+		t.Fatalf("opened %d segment files for %d segments", len(set.files.files), len(set.pinned))
+	}
+	opened := make([]*os.File, 0, len(set.files.files))
+	for _, file := range set.files.files {
+		opened = append(opened, file)
+	}
+
+	// A failing later range leaves cleanup to Close.
+	if _, _, _, err := set.Range(int64(len(whole)), 1); err == nil {
+		t.Fatalf("expected error for range past the end")
+	}
+	if err := set.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := set.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	for _, file := range opened {
+		if _, err := file.Stat(); !errors.Is(err, os.ErrClosed) {
+			t.Fatalf("segment file still open after Close: %v", err)
+		}
+	}
+	for _, id := range set.pinned {
+		if eng.SegmentPinned(id) {
+			t.Fatalf("segment %s still pinned after Close", id)
+		}
+	}
+}
+
+// BenchmarkEngineMultiRange compares a 10-range request served by GetRange
+// per range with one served from a RangeSet.
+func BenchmarkEngineMultiRange(b *testing.B) {
+	eng, err := New(Options{Layout: fs.NewLayout(filepath.Join(b.TempDir(), "data"))})
+	if err != nil {
+		b.Fatalf("New: %v", err)
+	}
+	input := bytes.Repeat([]byte("benchmark"), (8<<20)/9)
+	_, result, err := eng.Put(context.Background(), bytes.NewReader(input))
+	if err != nil {
+		b.Fatalf("Put: %v", err)
+	}
+	const ranges, rangeLen = 10, 4096
+	step := int64(len(input)) / ranges
+	b.Run("get-range", func(b *testing.B) {
+		b.SetBytes(ranges * rangeLen)
+		for i := 0; i < b.N; i++ {
+			for r := int64(0); r < ranges; r++ {
+				reader, _, _, _, err := eng.GetRange(context.Background(), result.VersionID, r*step, rangeLen)
+				if err != nil {
+					b.Fatalf("GetRange: %v", err)
+				}
+				if _, err := io.Copy(io.Discard, reader); err != nil {
+					b.Fatalf("Copy: %v", err)
+				}
+				_ = reader.Close()
+			}
+		}
+	})
+	b.Run("range-set", func(b *testing.B) {
+		b.SetBytes(ranges * rangeLen)
+		for i := 0; i < b.N; i++ {
+			set, err := eng.OpenRanges(context.Background(), result.VersionID)
+			if err != nil {
+				b.Fatalf("OpenRanges: %v", err)
+			}
+			for r := int64(0); r < ranges; r++ {
+				reader, _, _, err := set.Range(r*step, rangeLen)
+				if err != nil {
+					b.Fatalf("Range: %v", err)
+				}
+				if _, err := io.Copy(io.Discard, reader); err != nil {
+					b.Fatalf("Copy: %v", err)
+				}
+			}
+			_ = set.Close()
+		}
+	})
+}
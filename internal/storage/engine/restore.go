@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+)
+
+// RestoreObjectVersion undeletes a DELETED version after checking that its
+// manifest and chunks are still on disk. Segments stay pinned until the
+// restore commits, so online compaction cannot drop them in between.
+func (e *Engine) RestoreObjectVersion(ctx context.Context, bucket, key, versionID string) error {
+	if e.readOnly {
+		return ErrReadOnly
+	}
+	if e.metaStore == nil {
+		return fmt.Errorf("engine: meta store not configured")
+	}
+	man, pinned, err := e.openPinnedManifest(ctx, versionID)
+	if err != nil {
+		return fmt.Errorf("%w: manifest for version %s unreadable: %v", meta.ErrNotRestorable, versionID, err)
+	}
+	defer e.pins.unpin(pinned)
+	missing, err := e.MissingChunks(man)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: version %s has %d missing chunks (segment %s garbage collected)", meta.ErrNotRestorable, versionID, len(missing), missing[0].SegmentID)
+	}
+	return e.referenceSegments(man.Chunks, func() error {
+		return e.CommitMeta(ctx, func(tx *sql.Tx) error {
+			return e.metaStore.RestoreObjectVersionTx(ctx, tx, bucket, key, versionID)
+		})
+	})
+}
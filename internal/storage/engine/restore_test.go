@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func TestRestoreObjectVersionChecksChunks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatalf("meta.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	eng, err := New(Options{Layout: fs.NewLayout(filepath.Join(dir, "data")), MetaStore: store})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	man, result, err := eng.PutObject(ctx, "bucket", "key", "", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+	if _, err := store.DeleteObjectVersion(ctx, "bucket", "key", result.VersionID); err != nil {
+		t.Fatalf("DeleteObjectVersion: %v", err)
+	}
+	if err := eng.RestoreObjectVersion(ctx, "bucket", "key", result.VersionID); err != nil {
+		t.Fatalf("RestoreObjectVersion: %v", err)
+	}
+	current, err := store.GetObjectMeta(ctx, "bucket", "key")
+	if err != nil || current.VersionID != result.VersionID {
+		t.Fatalf("expected restored version current, got %+v err=%v", current, err)
+	}
+
+	if _, err := store.DeleteObjectVersion(ctx, "bucket", "key", result.VersionID); err != nil {
+		t.Fatalf("DeleteObjectVersion: %v", err)
+	}
+	if err := os.Remove(eng.Layout().SegmentPath(man.Chunks[0].SegmentID)); err != nil {
+		t.Fatalf("Remove segment: %v", err)
+	}
+	if err := eng.RestoreObjectVersion(ctx, "bucket", "key", result.VersionID); !errors.Is(err, meta.ErrNotRestorable) {
+		t.Fatalf("expected ErrNotRestorable after segment removal, got %v", err)
+	}
+}
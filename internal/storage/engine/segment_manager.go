@@ -19,10 +19,16 @@ type segmentManager struct {
 	maxBytes       int64
 	maxAge         time.Duration
 	clock          clock.Clock
+	// writes counts segment bytes and fsyncs (nil in bare tests).
+	writes *writeCounters
+	// onSeal is called with the id of each sealed segment while mu is held
+	// (nil = nothing to do after sealing).
+	onSeal func(segmentID string)
 
 	mu        sync.Mutex
 	writer    *segment.Writer
 	segmentID string
+	path      string
 	createdAt time.Time
 	size      int64
 	lastWrite time.Time
@@ -72,6 +78,7 @@ func (m *segmentManager) appendChunk(ctx context.Context, hash [32]byte, data []
 		return "", 0, err
 	}
 	m.size += int64(len(data)) + int64(segment.RecordHeaderLen())
+	m.countBytes(int64(len(data)) + int64(segment.RecordHeaderLen()))
 	m.lastWrite = m.now().UTC()
 	m.indexEntries = append(m.indexEntries, segment.IndexEntry{
 		Offset: offset,
@@ -86,7 +93,23 @@ func (m *segmentManager) sync() error {
 	if m.writer == nil {
 		return nil
 	}
-	return m.writer.Sync()
+	if err := m.writer.Sync(); err != nil {
+		return err
+	}
+	m.countSync()
+	return nil
+}
+
+func (m *segmentManager) countBytes(n int64) {
+	if m.writes != nil {
+		m.writes.segment.Add(n)
+	}
+}
+
+func (m *segmentManager) countSync() {
+	if m.writes != nil {
+		m.writes.segmentSyncs.Add(1)
+	}
 }
 
 func (m *segmentManager) sealIfIdle(ctx context.Context) error {
@@ -121,13 +144,14 @@ func (m *segmentManager) openNewSegment(ctx context.Context) error {
 		return err
 	}
 	segmentID := "seg-" + id
-	segmentPath := m.layout.SegmentPath(segmentID)
+	segmentPath := m.layout.StagingPath(segmentID)
 	writer, err := segment.NewWriter(segmentPath, m.segmentVersion)
 	if err != nil {
 		return err
 	}
 	m.writer = writer
 	m.segmentID = segmentID
+	m.path = segmentPath
 	m.createdAt = m.now().UTC()
 	m.lastWrite = m.createdAt
 	info, _ := os.Stat(segmentPath)
@@ -136,6 +160,7 @@ func (m *segmentManager) openNewSegment(ctx context.Context) error {
 	} else {
 		m.size = 0
 	}
+	m.countBytes(m.size)
 	m.indexEntries = nil
 	if m.metaStore != nil {
 		if err := m.metaStore.RecordSegment(ctx, segmentID, segmentPath, string(segment.StateOpen), m.size, nil); err != nil {
@@ -163,21 +188,26 @@ func (m *segmentManager) sealCurrentLocked(ctx context.Context) error {
 	if err := m.writer.Sync(); err != nil {
 		return err
 	}
+	m.countSync()
 	if err := m.writer.Close(); err != nil {
 		return err
 	}
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return err
+	}
+	m.countBytes(info.Size() - m.size)
 	if m.metaStore != nil {
-		path := m.layout.SegmentPath(m.segmentID)
-		info, err := os.Stat(path)
-		if err != nil {
-			return err
-		}
-		if err := m.metaStore.RecordSegment(ctx, m.segmentID, path, string(segment.StateSealed), info.Size(), footer.ChecksumHash[:]); err != nil {
+		if err := m.metaStore.RecordSegment(ctx, m.segmentID, m.path, string(segment.StateSealed), info.Size(), footer.ChecksumHash[:]); err != nil {
 			return err
 		}
 	}
+	if m.onSeal != nil {
+		m.onSeal(m.segmentID)
+	}
 	m.writer = nil
 	m.segmentID = ""
+	m.path = ""
 	m.size = 0
 	m.indexEntries = nil
 	return nil
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// promoteSuffix marks a segment being copied into SegmentsDir from a staging
+// directory on another filesystem.
+const promoteSuffix = ".promote"
+
+// promoteAsync moves a sealed segment out of the staging directory without
+// holding up writers; failures leave it staged, where reads still find it,
+// until the next start retries.
+func (e *Engine) promoteAsync(segmentID string) {
+	staged := e.layout.StagingPath(segmentID)
+	e.promotions.Add(1)
+	go func() {
+		defer e.promotions.Done()
+		if err := e.promoteSegment(context.Background(), segmentID, staged); err != nil {
+			fmt.Fprintf(os.Stderr, "staging: segment %s not moved: %v\n", segmentID, err)
+		}
+	}()
+}
+
+// promoteSegment moves a segment from staged to SegmentsDir: a rename on the
+// same filesystem, otherwise a copy to a temporary file that is renamed into
+// place before staged is removed.
+func (e *Engine) promoteSegment(ctx context.Context, segmentID, staged string) error {
+	final := e.layout.SegmentPath(segmentID)
+	_, err := e.finishPromotion(ctx, segmentID, staged, func() error {
+		return os.Rename(staged, final)
+	})
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	// The copy runs without the pin lock, so reads go on from staged.
+	tmp := final + promoteSuffix
+	if err := copySegmentFile(staged, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	moved, err := e.finishPromotion(ctx, segmentID, staged, func() error {
+		if err := os.Rename(tmp, final); err != nil {
+			return err
+		}
+		return os.Remove(staged)
+	})
+	if !moved {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+// finishPromotion runs move while segment removal is blocked and records the
+// final path. It does nothing when staged is gone, e.g. because compaction
+// removed the segment meanwhile.
+func (e *Engine) finishPromotion(ctx context.Context, segmentID, staged string, move func() error) (bool, error) {
+	e.pins.resolve.Lock()
+	defer e.pins.resolve.Unlock()
+	if _, err := os.Stat(staged); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := move(); err != nil {
+		return false, err
+	}
+	if err := syncDir(e.layout.SegmentsDir); err != nil {
+		return true, err
+	}
+	if e.metaStore == nil {
+		return true, nil
+	}
+	return true, e.metaStore.SetSegmentPath(ctx, segmentID, e.layout.SegmentPath(segmentID))
+}
+
+// recoverStagedSegments finishes promotions cut short by a crash or shutdown:
+// it drops partial copies and moves every segment left in the staging
+// directory into SegmentsDir. Run after recoverOpenSegments, so open segments
+// are sealed where they are.
+func (e *Engine) recoverStagedSegments(ctx context.Context) error {
+	if e.layout.StagingDir == "" {
+		return nil
+	}
+	partial, err := filepath.Glob(filepath.Join(e.layout.SegmentsDir, "*"+promoteSuffix))
+	if err != nil {
+		return err
+	}
+	for _, path := range partial {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	entries, err := os.ReadDir(e.layout.StagingDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		id := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(id, "seg-") {
+			continue
+		}
+		if err := e.promoteSegment(ctx, id, e.layout.StagingPath(id)); err != nil {
+			return fmt.Errorf("engine: move staged segment %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func copySegmentFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	return d.Sync()
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+	"github.com/kk-code-lab/seglake/internal/storage/segment"
+)
+
+// stagingDirs returns staging directories on the data dir's filesystem and,
+// when /dev/shm is available, on another one.
+func stagingDirs(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	dirs := map[string]string{"same-fs": filepath.Join(dir, "staging")}
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		shm, err := os.MkdirTemp("/dev/shm", "seglake-staging-")
+		if err == nil {
+			t.Cleanup(func() { _ = os.RemoveAll(shm) })
+			dirs["cross-fs"] = shm
+		}
+	}
+	return dirs
+}
+
+func readObject(t *testing.T, eng *Engine, versionID string) []byte {
+	t.Helper()
+	reader, _, err := eng.Get(context.Background(), versionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	return data
+}
+
+func TestStagingDirMovesSealedSegments(t *testing.T) {
+	root := t.TempDir()
+	for name, staging := range stagingDirs(t, root) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			store, err := meta.Open(filepath.Join(dir, "meta.db"))
+			if err != nil {
+				t.Fatalf("meta.Open: %v", err)
+			}
+			t.Cleanup(func() { _ = store.Close() })
+			layout := fs.NewLayout(filepath.Join(dir, "objects"))
+			eng, err := New(Options{Layout: layout, MetaStore: store, StagingDir: staging, SegmentMaxBytes: 64})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			first := bytes.Repeat([]byte("a"), 40)
+			man, res, err := eng.PutObject(ctx, "bucket", "first", "", bytes.NewReader(first))
+			if err != nil {
+				t.Fatalf("PutObject: %v", err)
+			}
+			segmentID := man.Chunks[0].SegmentID
+			if _, err := os.Stat(filepath.Join(staging, segmentID)); err != nil {
+				t.Fatalf("open segment not staged: %v", err)
+			}
+			if _, err := os.Stat(layout.SegmentPath(segmentID)); !os.IsNotExist(err) {
+				t.Fatalf("open segment already in data dir: %v", err)
+			}
+			if got := readObject(t, eng, res.VersionID); !bytes.Equal(got, first) {
+				t.Fatalf("read from staging mismatch")
+			}
+
+			// The second put does not fit, so the first segment is sealed.
+			if _, _, err := eng.PutObject(ctx, "bucket", "second", "", bytes.NewReader(first)); err != nil {
+				t.Fatalf("PutObject: %v", err)
+			}
+			_ = eng.Close()
+			if _, err := os.Stat(filepath.Join(staging, segmentID)); !os.IsNotExist(err) {
+				t.Fatalf("sealed segment left in staging: %v", err)
+			}
+			if _, err := segment.NewReader(layout.SegmentPath(segmentID)); err != nil {
+				t.Fatalf("sealed segment not in data dir: %v", err)
+			}
+			if _, err := os.Stat(layout.SegmentPath(segmentID) + promoteSuffix); !os.IsNotExist(err) {
+				t.Fatalf("partial copy left behind: %v", err)
+			}
+			seg, err := store.GetSegment(ctx, segmentID)
+			if err != nil {
+				t.Fatalf("GetSegment: %v", err)
+			}
+			if seg.Path != layout.SegmentPath(segmentID) || seg.State != string(segment.StateSealed) {
+				t.Fatalf("segment meta path=%s state=%s", seg.Path, seg.State)
+			}
+			if got := readObject(t, eng, res.VersionID); !bytes.Equal(got, first) {
+				t.Fatalf("read after move mismatch")
+			}
+		})
+	}
+}
+
+func TestStagingDirRecoversAfterCrash(t *testing.T) {
+	root := t.TempDir()
+	for name, staging := range stagingDirs(t, root) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			store, err := meta.Open(filepath.Join(dir, "meta.db"))
+			if err != nil {
+				t.Fatalf("meta.Open: %v", err)
+			}
+			t.Cleanup(func() { _ = store.Close() })
+			layout := fs.NewLayout(filepath.Join(dir, "objects"))
+			eng, err := New(Options{Layout: layout, MetaStore: store, StagingDir: staging})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			data := []byte("staged before crash")
+			man, res, err := eng.PutObject(ctx, "bucket", "key", "", bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("PutObject: %v", err)
+			}
+			segmentID := man.Chunks[0].SegmentID
+			// A crash mid-copy leaves a partial file next to the segments.
+			partial := layout.SegmentPath(segmentID) + promoteSuffix
+			if err := os.WriteFile(partial, []byte("partial"), 0o644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			// Restart without sealing: the open segment is sealed and moved.
+			eng, err = New(Options{Layout: layout, MetaStore: store, StagingDir: staging})
+			if err != nil {
+				t.Fatalf("New after crash: %v", err)
+			}
+			if _, err := os.Stat(partial); !os.IsNotExist(err) {
+				t.Fatalf("partial copy not removed: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(staging, segmentID)); !os.IsNotExist(err) {
+				t.Fatalf("segment left in staging: %v", err)
+			}
+			seg, err := store.GetSegment(ctx, segmentID)
+			if err != nil {
+				t.Fatalf("GetSegment: %v", err)
+			}
+			if seg.Path != layout.SegmentPath(segmentID) || seg.State != string(segment.StateSealed) {
+				t.Fatalf("segment meta path=%s state=%s", seg.Path, seg.State)
+			}
+			if got := readObject(t, eng, res.VersionID); !bytes.Equal(got, data) {
+				t.Fatalf("read after recovery mismatch")
+			}
+		})
+	}
+}
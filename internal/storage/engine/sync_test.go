@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kk-code-lab/seglake/internal/meta"
+	"github.com/kk-code-lab/seglake/internal/storage/fs"
+)
+
+func newSyncModeEngine(tb testing.TB, mode string, interval time.Duration) (*Engine, *meta.Store) {
+	tb.Helper()
+	dir := tb.TempDir()
+	store, err := meta.Open(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		tb.Fatalf("meta.Open: %v", err)
+	}
+	tb.Cleanup(func() { _ = store.Close() })
+	eng, err := New(Options{
+		Layout:          fs.NewLayout(filepath.Join(dir, "data")),
+		MetaStore:       store,
+		BarrierInterval: interval,
+		SyncMode:        mode,
+	})
+	if err != nil {
+		tb.Fatalf("New: %v", err)
+	}
+	return eng, store
+}
+
+func putConcurrently(tb testing.TB, eng *Engine, puts int, payload []byte) {
+	tb.Helper()
+	var wg sync.WaitGroup
+	for i := 0; i < puts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := eng.PutObject(context.Background(), "b", fmt.Sprintf("k%d", i), "", bytes.NewReader(payload)); err != nil {
+				tb.Errorf("PutObject: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSyncPerBarrierSharesFsyncs(t *testing.T) {
+	const puts = 8
+	payload := bytes.Repeat([]byte("x"), 1000)
+	for _, mode := range []string{SyncPerWrite, SyncPerBarrier} {
+		t.Run(mode, func(t *testing.T) {
+			eng, store := newSyncModeEngine(t, mode, 50*time.Millisecond)
+			putConcurrently(t, eng, puts, payload)
+			for i := 0; i < puts; i++ {
+				if _, err := store.CurrentVersion(context.Background(), "b", fmt.Sprintf("k%d", i)); err != nil {
+					t.Fatalf("CurrentVersion k%d: %v", i, err)
+				}
+			}
+			stats := eng.WriteStats()
+			barriers := eng.BarrierStats().Barriers
+			if stats.SyncMode != mode || stats.ManifestSyncs != puts {
+				t.Fatalf("unexpected stats: %+v", stats)
+			}
+			switch mode {
+			case SyncPerWrite:
+				if stats.SegmentSyncs != puts {
+					t.Fatalf("expected one segment fsync per put, got %d", stats.SegmentSyncs)
+				}
+			case SyncPerBarrier:
+				if stats.SegmentSyncs != barriers || barriers >= puts {
+					t.Fatalf("expected one segment fsync per barrier, got %d fsyncs for %d barriers", stats.SegmentSyncs, barriers)
+				}
+			}
+			if stats.LogicalBytes != puts*int64(len(payload)) {
+				t.Fatalf("logical bytes: %d", stats.LogicalBytes)
+			}
+			if amp := stats.Amplification(); amp <= 1 {
+				t.Fatalf("expected amplification > 1 (record headers, manifests), got %f", amp)
+			}
+		})
+	}
+}
+
+func TestNewRejectsUnknownSyncMode(t *testing.T) {
+	if _, err := New(Options{Layout: fs.NewLayout(t.TempDir()), SyncMode: "never"}); err == nil {
+		t.Fatalf("expected error for unknown sync mode")
+	}
+}
+
+func BenchmarkEnginePutSyncMode(b *testing.B) {
+	payload := bytes.Repeat([]byte("benchmark"), (64<<10)/9)
+	for _, mode := range []string{SyncPerWrite, SyncPerBarrier} {
+		b.Run(mode, func(b *testing.B) {
+			eng, _ := newSyncModeEngine(b, mode, 5*time.Millisecond)
+			b.SetBytes(int64(len(payload)))
+			b.SetParallelism(8)
+			var seq atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					key := fmt.Sprintf("k%d", seq.Add(1))
+					if _, _, err := eng.PutObject(context.Background(), "b", key, "", bytes.NewReader(payload)); err != nil {
+						b.Errorf("PutObject: %v", err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+			stats := eng.WriteStats()
+			b.ReportMetric(float64(stats.SegmentSyncs+stats.ManifestSyncs)/float64(b.N), "fsyncs/op")
+			b.ReportMetric(stats.Amplification(), "write-amp")
+		})
+	}
+}
@@ -1,12 +1,19 @@
 package fs
 
-import "path/filepath"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
 
 // Layout defines on-disk directory layout for storage data.
 type Layout struct {
 	Root         string
 	SegmentsDir  string
 	ManifestsDir string
+	// StagingDir holds open segments until they are sealed and moved to
+	// SegmentsDir (empty = open segments are written in SegmentsDir).
+	StagingDir string
 }
 
 // NewLayout builds a default layout under the given root.
@@ -22,6 +29,51 @@ func (l Layout) SegmentPath(segmentID string) string {
 	return filepath.Join(l.SegmentsDir, segmentID)
 }
 
+// StagingPath is where an open segment is written; it is SegmentPath when
+// there is no StagingDir.
+func (l Layout) StagingPath(segmentID string) string {
+	if l.StagingDir == "" {
+		return l.SegmentPath(segmentID)
+	}
+	return filepath.Join(l.StagingDir, segmentID)
+}
+
 func (l Layout) ManifestPath(versionID string) string {
 	return filepath.Join(l.ManifestsDir, versionID)
 }
+
+// OpenSegment opens a segment from SegmentsDir, or from StagingDir while it
+// is still staged. A segment moved out of staging between the two lookups is
+// found on the final retry.
+func (l Layout) OpenSegment(segmentID string) (*os.File, error) {
+	file, err := os.Open(l.SegmentPath(segmentID))
+	if l.StagingDir == "" || !errors.Is(err, os.ErrNotExist) {
+		return file, err
+	}
+	if file, err := os.Open(l.StagingPath(segmentID)); !errors.Is(err, os.ErrNotExist) {
+		return file, err
+	}
+	return os.Open(l.SegmentPath(segmentID))
+}
+
+// StatSegment is os.Stat of the file OpenSegment would open.
+func (l Layout) StatSegment(segmentID string) (os.FileInfo, error) {
+	_, info, err := l.LocateSegment(segmentID)
+	return info, err
+}
+
+// LocateSegment returns the path and os.Stat of the file OpenSegment would
+// open, for callers that need a path rather than an open file.
+func (l Layout) LocateSegment(segmentID string) (string, os.FileInfo, error) {
+	path := l.SegmentPath(segmentID)
+	info, err := os.Stat(path)
+	if l.StagingDir == "" || !errors.Is(err, os.ErrNotExist) {
+		return path, info, err
+	}
+	staged := l.StagingPath(segmentID)
+	if info, err := os.Stat(staged); !errors.Is(err, os.ErrNotExist) {
+		return staged, info, err
+	}
+	info, err = os.Stat(path)
+	return path, info, err
+}
@@ -17,6 +17,9 @@ const (
 	checksumLen = 32
 )
 
+// errHoleWithData rejects a hole chunk (no segment) that carries an offset or hash.
+var errHoleWithData = errors.New("manifest: hole chunk with segment data")
+
 // Codec serializes and deserializes manifests.
 type Codec interface {
 	Encode(w io.Writer, m *Manifest) error
@@ -55,6 +58,9 @@ func (c *BinaryCodec) Encode(w io.Writer, m *Manifest) error {
 	buf = appendU64(buf, uint64(m.Size))
 	buf = appendU32(buf, uint32(len(m.Chunks)))
 	for _, ch := range m.Chunks {
+		if ch.IsHole() && (ch.Offset != 0 || ch.Hash != [32]byte{}) {
+			return errHoleWithData
+		}
 		buf = appendU32(buf, uint32(ch.Index))
 		buf = append(buf, ch.Hash[:]...)
 		buf, err = appendString(buf, ch.SegmentID)
@@ -145,6 +151,9 @@ func (c *BinaryCodec) Decode(r io.Reader) (*Manifest, error) {
 		offset += 8
 		length := binary.LittleEndian.Uint32(body[offset:])
 		offset += 4
+		if segmentID == "" && (off != 0 || hash != [32]byte{}) {
+			return nil, errHoleWithData
+		}
 		chunks = append(chunks, ChunkRef{
 			Index:     index,
 			Hash:      hash,
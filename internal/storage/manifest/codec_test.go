@@ -69,3 +69,24 @@ func TestBinaryCodecChecksumMismatch(t *testing.T) {
 		t.Fatalf("expected checksum error")
 	}
 }
+
+func TestBinaryCodecHoleChunks(t *testing.T) {
+	c := &BinaryCodec{}
+	manifest := &Manifest{VersionID: "v1", Size: 8, Chunks: []ChunkRef{{Index: 0, Len: 8}}}
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, manifest); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got, err := c.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(got.Chunks) != 1 || !got.Chunks[0].IsHole() || got.Chunks[0].Len != 8 {
+		t.Fatalf("unexpected chunks: %+v", got.Chunks)
+	}
+
+	manifest.Chunks[0].Offset = 64
+	if err := c.Encode(&buf, manifest); err == nil {
+		t.Fatalf("expected error for hole chunk with an offset")
+	}
+}
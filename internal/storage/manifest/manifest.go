@@ -9,6 +9,12 @@ type ChunkRef struct {
 	Len       uint32
 }
 
+// IsHole reports whether the chunk is an unwritten range of a sparse object:
+// it has no segment data and reads as Len zero bytes.
+func (c ChunkRef) IsHole() bool {
+	return c.SegmentID == ""
+}
+
 // Manifest describes the layout of an object version.
 type Manifest struct {
 	Bucket    string
@@ -56,9 +56,12 @@ func (w *Writer) AppendRecord(header ChunkRecordHeader, data []byte) (int64, err
 		return 0, err
 	}
 	if err := EncodeHeader(w.file, header); err != nil {
+		_ = w.file.Truncate(pos)
 		return 0, err
 	}
 	if _, err := w.file.Write(data); err != nil {
+		// Drop the partial record so the next append starts on a record boundary.
+		_ = w.file.Truncate(pos)
 		return 0, err
 	}
 	return pos + headerLen, nil